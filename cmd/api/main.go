@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/spectra-red/recon/internal/api"
-	"github.com/surrealdb/surrealdb.go"
+	"github.com/spectra-red/recon/internal/db"
+	"github.com/spectra-red/recon/internal/logging"
+	"github.com/spectra-red/recon/internal/tracing"
 	"go.uber.org/zap"
 )
 
@@ -23,8 +25,8 @@ const (
 )
 
 func main() {
-	// Initialize structured logger
-	logger, err := zap.NewProduction()
+	// Initialize structured logger (LOG_LEVEL, LOG_FORMAT)
+	logger, err := logging.FromEnv()
 	if err != nil {
 		panic("failed to initialize logger: " + err.Error())
 	}
@@ -34,6 +36,21 @@ func main() {
 		zap.String("version", ServerVersion),
 		zap.String("port", ServerPort))
 
+	// Export traces when an OTLP endpoint is configured; otherwise spans are no-ops
+	shutdownTracing, err := tracing.Setup(context.Background(), "spectra-api", logger)
+	if err != nil {
+		logger.Warn("failed to initialize tracing, continuing without it",
+			zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("failed to flush traces",
+				zap.Error(err))
+		}
+	}()
+
 	// Get database configuration from environment
 	surrealURL := getEnv("SURREALDB_URL", "ws://localhost:8000/rpc")
 	surrealUser := getEnv("SURREALDB_USER", "root")
@@ -41,37 +58,36 @@ func main() {
 	surrealNS := getEnv("SURREALDB_NAMESPACE", "spectra")
 	surrealDB := getEnv("SURREALDB_DATABASE", "intel_mesh")
 
-	// Connect to SurrealDB
-	db, err := surrealdb.New(surrealURL)
+	// Connect to SurrealDB; the pool health-checks the connection and
+	// reconnects with backoff if it drops
+	pool, err := db.NewPool(context.Background(), db.PoolConfig{
+		URL:                 surrealURL,
+		Username:            surrealUser,
+		Password:            surrealPass,
+		Namespace:           surrealNS,
+		Database:            surrealDB,
+		HealthCheckInterval: getDurationEnv(logger, "SURREALDB_HEALTH_CHECK_INTERVAL", db.DefaultHealthCheckInterval),
+		MaxBackoff:          getDurationEnv(logger, "SURREALDB_RECONNECT_MAX_BACKOFF", db.DefaultReconnectMaxBackoff),
+	}, logger)
 	if err != nil {
 		logger.Fatal("failed to connect to SurrealDB",
 			zap.Error(err),
-			zap.String("url", surrealURL))
-	}
-	defer db.Close(context.Background())
-
-	// Authenticate and use namespace/database
-	if _, err := db.SignIn(context.Background(), surrealdb.Auth{
-		Username: surrealUser,
-		Password: surrealPass,
-	}); err != nil {
-		logger.Fatal("failed to authenticate with SurrealDB",
-			zap.Error(err))
-	}
-
-	if err := db.Use(context.Background(), surrealNS, surrealDB); err != nil {
-		logger.Fatal("failed to use namespace/database",
-			zap.Error(err),
+			zap.String("url", surrealURL),
 			zap.String("namespace", surrealNS),
 			zap.String("database", surrealDB))
 	}
+	defer pool.Close(context.Background())
 
 	logger.Info("connected to SurrealDB successfully",
 		zap.String("namespace", surrealNS),
 		zap.String("database", surrealDB))
 
-	// Setup routes with middleware
-	router := api.SetupRoutes(logger, db)
+	// Setup routes with middleware; the ingest in-flight limiter is drained on
+	// shutdown, and the routes' background pollers stop once the server has
+	ingestInFlight := api.NewIngestInFlightLimiter(logger)
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	router := api.SetupRoutesWithInFlight(background, logger, pool.DB(), ingestInFlight)
 
 	// Configure HTTP server
 	srv := &http.Server{
@@ -114,6 +130,15 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
 		defer cancel()
 
+		// Drain first: /readyz fails and new ingests get 503 while accepted
+		// scans finish being handed off to the workflow service
+		logger.Info("draining in-flight ingest jobs",
+			zap.Int("in_flight", ingestInFlight.Depth()))
+		if err := ingestInFlight.Drain(ctx); err != nil {
+			logger.Warn("drain did not complete before the shutdown timeout",
+				zap.Error(err))
+		}
+
 		// Attempt graceful shutdown
 		logger.Info("shutting down server gracefully",
 			zap.Duration("timeout", ShutdownTimeout))
@@ -124,6 +149,7 @@ func main() {
 			// Force close
 			srv.Close()
 		}
+		stopBackground()
 
 		logger.Info("server stopped")
 	}
@@ -136,3 +162,16 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDurationEnv parses a positive duration from the environment, warning and
+// falling back to defaultValue if it is malformed
+func getDurationEnv(logger *zap.Logger, key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil || value <= 0 {
+		logger.Warn("invalid "+key+", using default",
+			zap.String("value", os.Getenv(key)),
+			zap.Duration("default", defaultValue))
+		return defaultValue
+	}
+	return value
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -14,7 +13,8 @@ var rootCmd = &cobra.Command{
 	Long: `Spectra-Red is a community-driven security intelligence mesh.
 
 Use this CLI to scan targets, query the mesh, and contribute to the community.`,
-	Version: "0.1.0",
+	Version:       "0.1.0",
+	SilenceErrors: true,
 }
 
 func init() {
@@ -29,6 +29,7 @@ func init() {
 	rootCmd.AddCommand(jobsCmd)
 	rootCmd.AddCommand(cli.NewIngestCommand())
 	rootCmd.AddCommand(cli.QueryCmd)
+	cli.AddErrorFlags(rootCmd)
 
 	// Future commands will be added here
 	// rootCmd.AddCommand(scanCmd)
@@ -38,7 +39,7 @@ func init() {
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		cli.ReportError(os.Stderr, err)
+		os.Exit(cli.ExitCode(err))
 	}
 }
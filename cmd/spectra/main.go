@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/spectra-red/recon/internal/cli"
@@ -22,7 +21,7 @@ func main() {
 
 	// Execute the root command
 	if err := cli.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		cli.ReportError(os.Stderr, err)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -3,28 +3,52 @@ package main
 import (
 	"context"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	restate "github.com/restatedev/sdk-go"
 	"github.com/restatedev/sdk-go/server"
+	"github.com/spectra-red/recon/internal/api/handlers"
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/logging"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/schedule"
+	"github.com/spectra-red/recon/internal/tracing"
+	"github.com/spectra-red/recon/internal/webhook"
 	"github.com/spectra-red/recon/internal/workflows"
-	"github.com/surrealdb/surrealdb.go"
 	"go.uber.org/zap"
 )
 
 func main() {
-	// Initialize logger
-	logger, err := zap.NewProduction()
+	// Initialize logger (LOG_LEVEL, LOG_FORMAT)
+	logger, err := logging.FromEnv()
 	if err != nil {
 		log.Fatalf("failed to initialize logger: %v", err)
 	}
 	defer logger.Sync()
 
+	// Export traces when an OTLP endpoint is configured; otherwise spans are no-ops
+	shutdownTracing, err := tracing.Setup(context.Background(), "spectra-workflows", logger)
+	if err != nil {
+		logger.Warn("failed to initialize tracing, continuing without it",
+			zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("failed to flush traces",
+				zap.Error(err))
+		}
+	}()
+
 	// Get configuration from environment
 	surrealURL := getEnv("SURREALDB_URL", "ws://localhost:8000/rpc")
 	surrealUser := getEnv("SURREALDB_USER", "root")
@@ -37,52 +61,107 @@ func main() {
 		zap.String("port", port),
 		zap.String("surrealdb_url", surrealURL))
 
-	// Connect to SurrealDB
-	db, err := surrealdb.New(surrealURL)
+	// Connect to SurrealDB; the pool health-checks the connection and
+	// reconnects with backoff if it drops
+	pool, err := db.NewPool(context.Background(), db.PoolConfig{
+		URL:                 surrealURL,
+		Username:            surrealUser,
+		Password:            surrealPass,
+		Namespace:           surrealNS,
+		Database:            surrealDB,
+		HealthCheckInterval: getDurationEnv(logger, "SURREALDB_HEALTH_CHECK_INTERVAL", db.DefaultHealthCheckInterval),
+		MaxBackoff:          getDurationEnv(logger, "SURREALDB_RECONNECT_MAX_BACKOFF", db.DefaultReconnectMaxBackoff),
+	}, logger)
 	if err != nil {
 		logger.Fatal("failed to connect to SurrealDB",
 			zap.Error(err),
-			zap.String("url", surrealURL))
-	}
-	defer db.Close(context.Background())
-
-	// Authenticate and use namespace/database
-	if _, err := db.SignIn(context.Background(), surrealdb.Auth{
-		Username: surrealUser,
-		Password: surrealPass,
-	}); err != nil {
-		logger.Fatal("failed to authenticate with SurrealDB",
-			zap.Error(err))
-	}
-
-	if err := db.Use(context.Background(), surrealNS, surrealDB); err != nil {
-		logger.Fatal("failed to use namespace/database",
-			zap.Error(err),
+			zap.String("url", surrealURL),
 			zap.String("namespace", surrealNS),
 			zap.String("database", surrealDB))
 	}
+	defer pool.Close(context.Background())
+	dbClient := pool.DB()
 
 	logger.Info("connected to SurrealDB successfully",
 		zap.String("namespace", surrealNS),
 		zap.String("database", surrealDB))
 
+	// Host IDs are namespaced by address family once migration 10 has rewritten
+	// the database's hosts, and legacy until then. Workflows read the applied
+	// schema before each write, so they follow a migration without a restart;
+	// HOST_ID_SCHEME pins the scheme instead.
+	var hostIDSchemes workflows.HostIDSchemeSource = db.NewMigrator(dbClient, logger)
+	if value := os.Getenv("HOST_ID_SCHEME"); value != "" {
+		if scheme, err := models.ParseHostIDScheme(value); err != nil {
+			logger.Warn("invalid HOST_ID_SCHEME, following the applied schema",
+				zap.String("value", value))
+		} else {
+			hostIDSchemes = workflows.FixedHostIDScheme(scheme)
+			logger.Info("host ID scheme pinned",
+				zap.String("scheme", string(scheme)))
+		}
+	}
+
 	// Initialize ASN client
 	// Get ASN client configuration from environment
 	asnRateLimit := 100                // Default: 100 req/min
 	asnCacheTTL := 24 * time.Hour      // Default: 24 hours
-	asnClient := enrichment.NewTeamCymruClient(asnRateLimit, asnCacheTTL)
+	asnCacheMaxEntries, err := strconv.Atoi(getEnv("ASN_CACHE_MAX_ENTRIES", strconv.Itoa(enrichment.DefaultASNCacheMaxEntries)))
+	if err != nil || asnCacheMaxEntries <= 0 {
+		logger.Warn("invalid ASN_CACHE_MAX_ENTRIES, using default",
+			zap.String("value", os.Getenv("ASN_CACHE_MAX_ENTRIES")),
+			zap.Int("default", enrichment.DefaultASNCacheMaxEntries))
+		asnCacheMaxEntries = enrichment.DefaultASNCacheMaxEntries
+	}
+	// Circuit breakers around the NVD and Team Cymru calls fail fast once an upstream is down
+	breakerThreshold, err := strconv.Atoi(getEnv("ENRICHMENT_BREAKER_FAILURE_THRESHOLD", strconv.Itoa(enrichment.DefaultBreakerFailureThreshold)))
+	if err != nil || breakerThreshold <= 0 {
+		logger.Warn("invalid ENRICHMENT_BREAKER_FAILURE_THRESHOLD, using default",
+			zap.String("value", os.Getenv("ENRICHMENT_BREAKER_FAILURE_THRESHOLD")),
+			zap.Int("default", enrichment.DefaultBreakerFailureThreshold))
+		breakerThreshold = enrichment.DefaultBreakerFailureThreshold
+	}
+	breakerConfig := enrichment.BreakerConfig{
+		FailureThreshold: breakerThreshold,
+		OpenDuration:     getDurationEnv(logger, "ENRICHMENT_BREAKER_OPEN_DURATION", enrichment.DefaultBreakerOpenDuration),
+	}
+
+	asnClient := enrichment.NewTeamCymruClientWithConfig(enrichment.TeamCymruConfig{
+		RateLimit:       asnRateLimit,
+		CacheTTL:        asnCacheTTL,
+		CacheMaxEntries: asnCacheMaxEntries,
+		Breaker:         breakerConfig,
+	})
 
 	logger.Info("initialized ASN client",
 		zap.Int("rate_limit_per_min", asnRateLimit),
-		zap.Duration("cache_ttl", asnCacheTTL))
+		zap.Duration("cache_ttl", asnCacheTTL),
+		zap.Int("cache_max_entries", asnCacheMaxEntries))
 
 	// Initialize GeoIP client
 	geoipMMDBPath := getEnv("GEOIP_MMDB_PATH", "/var/lib/GeoIP/GeoLite2-City.mmdb")
 	geoipAPIKey := getEnv("GEOIP_API_KEY", "")
+	geoipConcurrency, err := strconv.Atoi(getEnv("GEOIP_CONCURRENCY", strconv.Itoa(enrichment.DefaultGeoIPConcurrency)))
+	if err != nil || geoipConcurrency <= 0 {
+		logger.Warn("invalid GEOIP_CONCURRENCY, using default",
+			zap.String("value", os.Getenv("GEOIP_CONCURRENCY")),
+			zap.Int("default", enrichment.DefaultGeoIPConcurrency))
+		geoipConcurrency = enrichment.DefaultGeoIPConcurrency
+	}
+
+	// Results less precise than this place a host in its region and country only
+	geoipMaxCityAccuracyKm, err := strconv.Atoi(getEnv("GEOIP_MAX_CITY_ACCURACY_KM", strconv.Itoa(workflows.DefaultMaxCityAccuracyKm)))
+	if err != nil || geoipMaxCityAccuracyKm <= 0 {
+		logger.Warn("invalid GEOIP_MAX_CITY_ACCURACY_KM, using default",
+			zap.String("value", os.Getenv("GEOIP_MAX_CITY_ACCURACY_KM")),
+			zap.Int("default", workflows.DefaultMaxCityAccuracyKm))
+		geoipMaxCityAccuracyKm = workflows.DefaultMaxCityAccuracyKm
+	}
 
 	geoClient, err := enrichment.NewGeoIPClient(enrichment.GeoIPConfig{
-		MMDBPath: geoipMMDBPath,
-		APIKey:   geoipAPIKey,
+		MMDBPath:    geoipMMDBPath,
+		APIKey:      geoipAPIKey,
+		Concurrency: geoipConcurrency,
 	})
 	if err != nil {
 		logger.Warn("GeoIP client initialization had warnings",
@@ -92,7 +171,8 @@ func main() {
 	if geoClient != nil {
 		defer geoClient.Close()
 		logger.Info("GeoIP client initialized",
-			zap.String("mmdb_path", geoipMMDBPath))
+			zap.String("mmdb_path", geoipMMDBPath),
+			zap.Int("concurrency", geoipConcurrency))
 	}
 
 	// Get NVD API key from environment
@@ -100,15 +180,199 @@ func main() {
 	if nvdAPIKey == "" {
 		logger.Warn("NVD_API_KEY not set, using public rate limit (5 req/30s)")
 	}
+	nvdCacheMaxEntries, err := strconv.Atoi(getEnv("NVD_CACHE_MAX_ENTRIES", strconv.Itoa(enrichment.DefaultNVDCacheMaxEntries)))
+	if err != nil || nvdCacheMaxEntries <= 0 {
+		logger.Warn("invalid NVD_CACHE_MAX_ENTRIES, using default",
+			zap.String("value", os.Getenv("NVD_CACHE_MAX_ENTRIES")),
+			zap.Int("default", enrichment.DefaultNVDCacheMaxEntries))
+		nvdCacheMaxEntries = enrichment.DefaultNVDCacheMaxEntries
+	}
+	// Responses holding CRITICAL CVEs can be refreshed sooner than LOW-only ones,
+	// e.g. NVD_CACHE_TTL_BY_SEVERITY=CRITICAL=1h,HIGH=6h
+	nvdCacheTTLs, err := enrichment.ParseSeverityTTLs(getEnv("NVD_CACHE_TTL_BY_SEVERITY", ""))
+	if err != nil {
+		logger.Warn("invalid NVD_CACHE_TTL_BY_SEVERITY, using the default TTL for every severity",
+			zap.String("value", os.Getenv("NVD_CACHE_TTL_BY_SEVERITY")),
+			zap.Error(err))
+		nvdCacheTTLs = nil
+	}
+	nvdMaxResults, err := strconv.Atoi(getEnv("NVD_MAX_RESULTS", strconv.Itoa(enrichment.DefaultNVDMaxResults)))
+	if err != nil || nvdMaxResults <= 0 {
+		logger.Warn("invalid NVD_MAX_RESULTS, using default",
+			zap.String("value", os.Getenv("NVD_MAX_RESULTS")),
+			zap.Int("default", enrichment.DefaultNVDMaxResults))
+		nvdMaxResults = enrichment.DefaultNVDMaxResults
+	}
+	nvdResultsPerPage, err := strconv.Atoi(getEnv("NVD_RESULTS_PER_PAGE", strconv.Itoa(enrichment.DefaultNVDResultsPerPage)))
+	if err != nil || nvdResultsPerPage <= 0 || nvdResultsPerPage > enrichment.NVDMaxResultsPerPage {
+		logger.Warn("invalid NVD_RESULTS_PER_PAGE, using default",
+			zap.String("value", os.Getenv("NVD_RESULTS_PER_PAGE")),
+			zap.Int("default", enrichment.DefaultNVDResultsPerPage))
+		nvdResultsPerPage = enrichment.DefaultNVDResultsPerPage
+	}
+
+	// Optional product-to-vendor overrides for products the built-in CPE map doesn't know
+	if vendorMapPath := getEnv("CPE_VENDOR_MAP_PATH", ""); vendorMapPath != "" {
+		loaded, err := enrichment.LoadVendorMap(vendorMapPath)
+		if err != nil {
+			logger.Fatal("failed to load CPE vendor map",
+				zap.Error(err),
+				zap.String("path", vendorMapPath))
+		}
+		logger.Info("loaded CPE vendor map",
+			zap.String("path", vendorMapPath),
+			zap.Int("mappings", loaded))
+	}
+
+	// Optional NVD CPE dictionary for snapping product names to canonical CPEs
+	if dictionaryPath := getEnv("CPE_DICTIONARY_PATH", ""); dictionaryPath != "" {
+		dictionary, err := enrichment.LoadCPEDictionary(dictionaryPath)
+		if err != nil {
+			logger.Fatal("failed to load CPE dictionary",
+				zap.Error(err),
+				zap.String("path", dictionaryPath))
+		}
+		enrichment.UseCPEDictionary(dictionary)
+		logger.Info("loaded CPE dictionary",
+			zap.String("path", dictionaryPath),
+			zap.Int("products", dictionary.Len()))
+	}
+
+	// Only vulnerabilities at or above this severity get AFFECTED_BY edges
+	cpeMinSeverity, err := enrichment.ParseSeverity(getEnv("CPE_MIN_SEVERITY", enrichment.DefaultMinSeverity.String()))
+	if err != nil {
+		logger.Warn("invalid CPE_MIN_SEVERITY, using default",
+			zap.Error(err),
+			zap.String("default", enrichment.DefaultMinSeverity.String()))
+		cpeMinSeverity = enrichment.DefaultMinSeverity
+	}
+
+	// Public meshes reject private/reserved IPs; internal deployments keep them
+	rejectPrivateIPs := getEnv("INGEST_REJECT_PRIVATE_IPS", "false") == "true"
+
+	// Ports a scan identified nothing on can be tagged with the service
+	// conventionally run on them, stored with low confidence; off by default
+	guessServiceNames := getEnv("INGEST_GUESS_SERVICE_NAMES", "false") == "true"
+	if portMapPath := getEnv("INGEST_PORT_SERVICE_MAP_PATH", ""); portMapPath != "" {
+		loaded, err := enrichment.LoadPortServiceMap(portMapPath)
+		if err != nil {
+			logger.Fatal("failed to load port service map",
+				zap.Error(err),
+				zap.String("path", portMapPath))
+		}
+		logger.Info("loaded port service map",
+			zap.String("path", portMapPath),
+			zap.Int("mappings", loaded))
+	}
+
+	// Completion callbacks need both an allowlist and a signing secret
+	var callbackNotifier *webhook.Notifier
+	callbackAllowlist := webhook.ParseAllowlist(getEnv("INGEST_CALLBACK_ALLOWED_HOSTS", ""))
+	callbackSecret := getEnv("INGEST_CALLBACK_SECRET", "")
+	switch {
+	case callbackAllowlist.Enabled() && callbackSecret == "":
+		logger.Warn("INGEST_CALLBACK_ALLOWED_HOSTS set without INGEST_CALLBACK_SECRET, job callbacks disabled")
+	case callbackAllowlist.Enabled():
+		callbackNotifier = webhook.NewNotifier(callbackAllowlist, []byte(callbackSecret), nil)
+	}
+
+	// Ingest queues the services it writes for CPE enrichment, which a background
+	// worker drains in bounded chunks; CPE_QUEUE_ENABLED=false leaves them to backfills
+	var cpeQueue workflows.CPEQueue
+	cpeQueueEnabled := getEnv("CPE_QUEUE_ENABLED", "true") != "false"
+	if cpeQueueEnabled {
+		cpeQueue = db.NewCPEQueueStore(dbClient, getDurationEnv(logger, "CPE_QUEUE_LEASE", db.DefaultCPEQueueLease), logger)
+	}
 
 	// Initialize workflows
-	ingestWorkflow := workflows.NewIngestWorkflow(db)
-	enrichASNWorkflow := workflows.NewEnrichASNWorkflow(db, asnClient)
-	enrichGeoWorkflow := workflows.NewEnrichGeoWorkflow(db, geoClient, logger)
-	enrichCPEWorkflow := workflows.NewEnrichCPEWorkflow(db, nvdAPIKey)
+	ingestWorkflow := workflows.NewIngestWorkflowWithConfig(dbClient, workflows.IngestConfig{
+		RejectPrivateIPs:  rejectPrivateIPs,
+		Notifier:          callbackNotifier,
+		GuessServiceNames: guessServiceNames,
+		CPEQueue:          cpeQueue,
+		HostIDSchemes:     hostIDSchemes,
+	})
+	// One Team Cymru lookup per announced prefix; ASN_COALESCE_PREFIXES=false looks up every IP
+	asnCoalescePrefixes := getEnv("ASN_COALESCE_PREFIXES", "true") != "false"
+	// Hosts ASN/GeoIP-enriched within this window are skipped unless a run forces a refresh
+	enrichmentFreshFor := getDurationEnv(logger, "ENRICHMENT_FRESHNESS_WINDOW", workflows.DefaultEnrichmentFreshness)
+	enrichASNWorkflow := workflows.NewEnrichASNWorkflowWithConfig(dbClient, asnClient, workflows.EnrichASNConfig{
+		CoalescePrefixes: asnCoalescePrefixes,
+		FreshFor:         enrichmentFreshFor,
+		Logger:           logger,
+		HostIDSchemes:    hostIDSchemes,
+	})
+	enrichGeoWorkflow := workflows.NewEnrichGeoWorkflowWithConfig(dbClient, geoClient, logger, workflows.EnrichGeoConfig{
+		MaxCityAccuracyKm: geoipMaxCityAccuracyKm,
+		FreshFor:          enrichmentFreshFor,
+		HostIDSchemes:     hostIDSchemes,
+	})
+	nvdClient := enrichment.NewNVDClientWithConfig(enrichment.NVDConfig{
+		APIKey:             nvdAPIKey,
+		CacheMaxEntries:    nvdCacheMaxEntries,
+		MaxResults:         nvdMaxResults,
+		ResultsPerPage:     nvdResultsPerPage,
+		Breaker:            breakerConfig,
+		CacheTTLBySeverity: nvdCacheTTLs,
+	})
+	enrichCPEWorkflow := workflows.NewEnrichCPEWorkflowWithConfig(dbClient, workflows.EnrichCPEConfig{
+		MinSeverity: cpeMinSeverity,
+		NVDClient:   nvdClient,
+	})
 
 	logger.Info("workflows initialized",
-		zap.Bool("nvd_api_key_configured", nvdAPIKey != ""))
+		zap.Bool("nvd_api_key_configured", nvdAPIKey != ""),
+		zap.Int("nvd_cache_max_entries", nvdCacheMaxEntries),
+		zap.Int("nvd_max_results", nvdMaxResults),
+		zap.String("cpe_min_severity", cpeMinSeverity.String()),
+		zap.Bool("asn_coalesce_prefixes", asnCoalescePrefixes),
+		zap.Duration("enrichment_freshness_window", enrichmentFreshFor),
+		zap.Bool("reject_private_ips", rejectPrivateIPs),
+		zap.Bool("job_callbacks_enabled", callbackNotifier != nil))
+
+	// Periodically decay AFFECTED_BY edges that CPE enrichment has not re-confirmed
+	decayInterval := getDurationEnv(logger, "AFFECTED_BY_DECAY_INTERVAL", db.DefaultDecayInterval)
+	decayer := db.NewConfidenceDecayerWithConfig(dbClient, logger, db.DecayConfig{
+		StaleAfter: getDurationEnv(logger, "AFFECTED_BY_STALE_AFTER", db.DefaultDecayStaleAfter),
+	})
+	// Spread decay runs so replicas don't decay in lockstep (0 disables)
+	scheduleJitter, err := strconv.ParseFloat(getEnv("SCHEDULE_JITTER", strconv.FormatFloat(schedule.DefaultJitter, 'f', -1, 64)), 64)
+	if err != nil || scheduleJitter < 0 || scheduleJitter >= 1 {
+		logger.Warn("invalid SCHEDULE_JITTER, using default",
+			zap.String("value", os.Getenv("SCHEDULE_JITTER")),
+			zap.Float64("default", schedule.DefaultJitter))
+		scheduleJitter = schedule.DefaultJitter
+	}
+	decayer.StartWithJitter(decayInterval, scheduleJitter)
+	defer decayer.Stop()
+
+	logger.Info("AFFECTED_BY confidence decay scheduled",
+		zap.Duration("interval", decayInterval),
+		zap.Float64("jitter", scheduleJitter),
+		zap.Duration("stale_after", decayer.Config().StaleAfter),
+		zap.Float64("factor", decayer.Config().Factor),
+		zap.Float64("floor", decayer.Config().Floor))
+
+	if cpeQueueEnabled {
+		cpeQueueChunkSize, err := strconv.Atoi(getEnv("CPE_QUEUE_CHUNK_SIZE", strconv.Itoa(workflows.DefaultCPEQueueChunkSize)))
+		if err != nil || cpeQueueChunkSize <= 0 {
+			logger.Warn("invalid CPE_QUEUE_CHUNK_SIZE, using default",
+				zap.String("value", os.Getenv("CPE_QUEUE_CHUNK_SIZE")),
+				zap.Int("default", workflows.DefaultCPEQueueChunkSize))
+			cpeQueueChunkSize = workflows.DefaultCPEQueueChunkSize
+		}
+		cpeQueueWorker := workflows.NewCPEQueueWorker(cpeQueue, enrichCPEWorkflow, logger, workflows.CPEQueueWorkerConfig{
+			ChunkSize: cpeQueueChunkSize,
+			Interval:  getDurationEnv(logger, "CPE_QUEUE_INTERVAL", workflows.DefaultCPEQueueInterval),
+			Jitter:    scheduleJitter,
+		})
+		cpeQueueWorker.Start()
+		defer cpeQueueWorker.Stop()
+
+		logger.Info("CPE enrichment queue worker started",
+			zap.Int("chunk_size", cpeQueueWorker.Config().ChunkSize),
+			zap.Duration("interval", cpeQueueWorker.Config().Interval))
+	}
 
 	// Create Restate server and register workflows
 	restateServer := server.NewRestate().
@@ -124,10 +388,24 @@ func main() {
 			zap.Error(err))
 	}
 
+	// Count Restate invocations in flight so shutdown can wait for them;
+	// /readyz fails and new invocations get 503 while draining
+	inFlight := middleware.NewInFlightLimiter(math.MaxInt, 5*time.Second)
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", handlers.ReadyHandler(inFlight, logger))
+	mux.Handle("/metrics", handlers.MetricsHandler(map[string]handlers.CacheStatsSource{
+		"asn": asnClient.Stats,
+		"nvd": nvdClient.CacheStats,
+	}, map[string]handlers.BreakerStatsSource{
+		"asn": asnClient.BreakerStats,
+		"nvd": nvdClient.BreakerStats,
+	}, logger))
+	mux.Handle("/", middleware.DrainMiddleware(inFlight)(handler))
+
 	// Setup HTTP server
 	httpServer := &http.Server{
 		Addr:         ":" + port,
-		Handler:      handler,
+		Handler:      mux,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -155,6 +433,14 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Let running workflow steps finish before closing the listener
+	logger.Info("draining in-flight invocations",
+		zap.Int("in_flight", inFlight.Depth()))
+	if err := inFlight.Drain(ctx); err != nil {
+		logger.Warn("drain did not complete before the shutdown timeout",
+			zap.Error(err))
+	}
+
 	if err := httpServer.Shutdown(ctx); err != nil {
 		logger.Error("server forced to shutdown",
 			zap.Error(err))
@@ -170,3 +456,16 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDurationEnv parses a positive duration from the environment, warning and
+// falling back to defaultValue if it is malformed
+func getDurationEnv(logger *zap.Logger, key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil || value <= 0 {
+		logger.Warn("invalid "+key+", using default",
+			zap.String("value", os.Getenv(key)),
+			zap.Duration("default", defaultValue))
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// AuditRecorder appends entries to the audit trail
+type AuditRecorder interface {
+	Record(ctx context.Context, entry models.AuditEntry) error
+}
+
+// AuditStore lists entries from the audit trail
+type AuditStore interface {
+	List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// recordAudit writes entry with the request's source IP. A nil recorder skips
+// auditing, and a failed write is logged rather than failing the request the
+// entry describes, which has already taken effect.
+func recordAudit(ctx context.Context, audit AuditRecorder, r *http.Request, entry models.AuditEntry, logger *zap.Logger) {
+	if audit == nil {
+		return
+	}
+	entry.SourceIP = middleware.RequestClientIP(r)
+	if err := audit.Record(ctx, entry); err != nil {
+		logger.Error("failed to write audit entry",
+			zap.Error(err),
+			zap.String("action", entry.Action),
+			zap.String("job_id", entry.JobID))
+	}
+}
+
+// adminActor returns the audit actor for an admin request: AuditActorAdmin only
+// when it presented the admin token
+func adminActor(r *http.Request) string {
+	if middleware.IsAdmin(r) {
+		return models.AuditActorAdmin
+	}
+	return models.AuditActorAnonymous
+}
+
+// countScanHosts returns the number of distinct hosts in a JSON lines scan
+// Lines that do not parse or name no host are skipped, as the ingest workflow does.
+func countScanHosts(data []byte) int {
+	hosts := make(map[string]struct{})
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var record struct {
+			Host string `json:"host"`
+		}
+		if json.Unmarshal(bytes.TrimSpace(line), &record) == nil && record.Host != "" {
+			hosts[record.Host] = struct{}{}
+		}
+	}
+	return len(hosts)
+}
+
+// AuditHandler handles GET /v1/admin/audit
+// Query params: ?since=RFC3339&until=RFC3339&actor=admin&scanner_key=xyz&action=ingest&limit=100
+// scanner_key is hashed the way entries store it and is an alternative to actor.
+func AuditHandler(store AuditStore, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		query := r.URL.Query()
+		filter := models.AuditFilter{
+			Actor:  query.Get("actor"),
+			Action: query.Get("action"),
+			Limit:  models.DefaultAuditLimit,
+		}
+
+		for _, bound := range []struct {
+			name string
+			dst  *time.Time
+		}{{"since", &filter.Since}, {"until", &filter.Until}} {
+			value := query.Get(bound.name)
+			if value == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				jobErrorResponse(w, "invalid_parameter", bound.name+" must be an RFC 3339 timestamp, e.g. 2024-01-02T15:04:05Z", http.StatusBadRequest)
+				return
+			}
+			*bound.dst = parsed
+		}
+
+		if scannerKey := query.Get("scanner_key"); scannerKey != "" {
+			if filter.Actor != "" {
+				jobErrorResponse(w, "invalid_parameter", "actor and scanner_key cannot be combined", http.StatusBadRequest)
+				return
+			}
+			filter.Actor = models.ScannerActor(scannerKey)
+		}
+
+		if limitStr := query.Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed < 1 || parsed > models.MaxAuditLimit {
+				jobErrorResponse(w, "invalid_parameter", fmt.Sprintf("limit must be an integer between 1 and %d", models.MaxAuditLimit), http.StatusBadRequest)
+				return
+			}
+			filter.Limit = parsed
+		}
+
+		entries, err := store.List(ctx, filter)
+		if err != nil {
+			logger.Error("failed to list audit entries",
+				zap.Error(err))
+			jobErrorResponse(w, "internal_error", "Failed to list audit entries", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(models.AuditListResponse{
+			Entries: entries,
+			Total:   len(entries),
+			Limit:   filter.Limit,
+		}); err != nil {
+			logger.Error("failed to encode audit list",
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeAudit collects recorded entries and serves them back from List
+type fakeAudit struct {
+	entries   []models.AuditEntry
+	err       error
+	gotFilter models.AuditFilter
+}
+
+func (a *fakeAudit) Record(ctx context.Context, entry models.AuditEntry) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *fakeAudit) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	a.gotFilter = filter
+	return a.entries, a.err
+}
+
+func TestAuditHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFilter models.AuditFilter
+	}{
+		{name: "no filters", wantStatus: http.StatusOK, wantFilter: models.AuditFilter{Limit: models.DefaultAuditLimit}},
+		{
+			name:       "time window and actor",
+			query:      "?since=2026-01-01T00:00:00Z&until=2026-01-02T00:00:00Z&actor=admin&action=redact&limit=5",
+			wantStatus: http.StatusOK,
+			wantFilter: models.AuditFilter{
+				Since:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				Until:  time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+				Actor:  "admin",
+				Action: "redact",
+				Limit:  5,
+			},
+		},
+		{
+			name:       "scanner key is hashed",
+			query:      "?scanner_key=scanner-key",
+			wantStatus: http.StatusOK,
+			wantFilter: models.AuditFilter{Actor: models.ScannerActor("scanner-key"), Limit: models.DefaultAuditLimit},
+		},
+		{name: "bad since", query: "?since=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "actor and scanner key", query: "?actor=admin&scanner_key=k", wantStatus: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=5000", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeAudit{entries: []models.AuditEntry{{Action: models.AuditActionIngest, Actor: models.ScannerActor("k")}}}
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+			AuditHandler(store, zap.NewNop()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tt.wantFilter, store.gotFilter)
+
+			var resp models.AuditListResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, 1, resp.Total)
+			assert.Equal(t, tt.wantFilter.Limit, resp.Limit)
+		})
+	}
+}
+
+func TestAuditHandler_StoreError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/audit", nil)
+	w := httptest.NewRecorder()
+	AuditHandler(&fakeAudit{err: errors.New("connection refused")}, zap.NewNop()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCountScanHosts(t *testing.T) {
+	scan := []byte(`{"host":"10.0.0.5","port":22}
+{"host":"10.0.0.5","port":443}
+not json
+{"port":80}
+
+{"host":"10.0.0.6","port":80}`)
+	assert.Equal(t, 2, countScanHosts(scan))
+	assert.Zero(t, countScanHosts(nil))
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+const (
+	// DefaultIngestMaxBodyBytes is the default request body cap for scan submissions (10MB)
+	DefaultIngestMaxBodyBytes int64 = 10 << 20
+
+	// DefaultQueryMaxBodyBytes is the default request body cap for query endpoints (64KB)
+	DefaultQueryMaxBodyBytes int64 = 64 << 10
+)
+
+// isBodyTooLarge reports whether err was caused by reading past an http.MaxBytesReader limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// decodeJSONBody reads at most maxBodyBytes of the request body and unmarshals it into v
+// The whole body is read so oversized requests are rejected even when they start with valid JSON.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, maxBodyBytes int64, v interface{}) error {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spectra-red/recon/internal/db"
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// DeadLetterStore lists, fetches and clears dead-lettered enrichment items
+type DeadLetterStore interface {
+	List(ctx context.Context, stage string, limit int) ([]models.DeadLetter, error)
+	Get(ctx context.Context, ip, stage string) (*models.DeadLetter, error)
+	Delete(ctx context.Context, ip, stage string) error
+}
+
+// maxDeadLetterLimit bounds the page size of the dead-letter listing
+const maxDeadLetterLimit = 500
+
+// enrichmentServices maps dead-letter stages to the Restate service that re-runs them
+var enrichmentServices = map[string]string{
+	models.DeadLetterStageASN: "EnrichASNWorkflow",
+	models.DeadLetterStageGeo: "EnrichGeoWorkflow",
+}
+
+// DeadLetterHandler serves the dead-letter admin endpoints
+type DeadLetterHandler struct {
+	store   DeadLetterStore
+	invoker WorkflowInvoker
+	audit   AuditRecorder
+	logger  *zap.Logger
+}
+
+// NewDeadLetterHandler creates a dead-letter handler that requeues items through Restate at restateURL
+// audit, if non-nil, records each requeue that reached the enrichment workflow.
+func NewDeadLetterHandler(store DeadLetterStore, restateURL string, audit AuditRecorder, logger *zap.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		store:   store,
+		invoker: NewRestateInvoker(restateURL),
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+// HandleList handles GET /v1/admin/dead-letters
+// Query params: ?stage=asn|geo&limit=50
+func (h *DeadLetterHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	stage := r.URL.Query().Get("stage")
+	if stage != "" && !models.IsValidDeadLetterStage(stage) {
+		jobErrorResponse(w, "invalid_parameter", "stage must be one of: asn, geo", http.StatusBadRequest)
+		return
+	}
+
+	limit := db.DefaultDeadLetterLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxDeadLetterLimit {
+			jobErrorResponse(w, "invalid_parameter", fmt.Sprintf("limit must be an integer between 1 and %d", maxDeadLetterLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deadLetters, err := h.store.List(ctx, stage, limit)
+	if err != nil {
+		h.logger.Error("failed to list dead letters",
+			zap.Error(err))
+		jobErrorResponse(w, "internal_error", "Failed to list dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(models.DeadLetterListResponse{
+		DeadLetters: deadLetters,
+		Total:       len(deadLetters),
+		Limit:       limit,
+	}); err != nil {
+		h.logger.Error("failed to encode dead letter list",
+			zap.Error(err))
+	}
+}
+
+// HandleRequeue handles POST /v1/admin/dead-letters/requeue
+// Re-submits the IP to its enrichment workflow, then clears the dead letter.
+// If the workflow can't be reached, the dead letter is kept.
+func (h *DeadLetterHandler) HandleRequeue(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	var req models.RequeueDeadLetterRequest
+	if err := decodeJSONBody(w, r, DefaultQueryMaxBodyBytes, &req); err != nil {
+		jobErrorResponse(w, "invalid_json", "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if req.IP == "" {
+		jobErrorResponse(w, "missing_parameter", "ip is required", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidDeadLetterStage(req.Stage) {
+		jobErrorResponse(w, "invalid_parameter", "stage must be one of: asn, geo", http.StatusBadRequest)
+		return
+	}
+
+	deadLetter, err := h.store.Get(ctx, req.IP, req.Stage)
+	if err != nil {
+		h.logger.Error("failed to get dead letter",
+			zap.Error(err),
+			zap.String("ip", req.IP),
+			zap.String("stage", req.Stage))
+		jobErrorResponse(w, "internal_error", "Failed to get dead letter", http.StatusInternalServerError)
+		return
+	}
+	if deadLetter == nil {
+		jobErrorResponse(w, "not_found", "Dead letter not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.submitEnrichment(ctx, req.Stage, req.IP); err != nil {
+		h.logger.Error("failed to requeue dead letter",
+			zap.Error(err),
+			zap.String("ip", req.IP),
+			zap.String("stage", req.Stage))
+		jobErrorResponse(w, "service_unavailable", "Failed to re-submit for enrichment", http.StatusServiceUnavailable)
+		return
+	}
+
+	recordAudit(ctx, h.audit, r, models.AuditEntry{
+		Action: models.AuditActionRequeue,
+		Actor:  adminActor(r),
+		Details: map[string]interface{}{
+			"ip":       req.IP,
+			"stage":    req.Stage,
+			"attempts": deadLetter.Attempts,
+		},
+	}, h.logger)
+
+	if err := h.store.Delete(ctx, req.IP, req.Stage); err != nil {
+		// The item was re-submitted; a stale record is only cosmetic and is overwritten on the next failure
+		h.logger.Warn("requeued dead letter but failed to clear it",
+			zap.Error(err),
+			zap.String("ip", req.IP),
+			zap.String("stage", req.Stage))
+	}
+
+	h.logger.Info("dead letter requeued",
+		zap.String("ip", req.IP),
+		zap.String("stage", req.Stage),
+		zap.Int("attempts", deadLetter.Attempts))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(deadLetter); err != nil {
+		h.logger.Error("failed to encode requeue response",
+			zap.Error(err))
+	}
+}
+
+// submitEnrichment sends a single-IP enrichment request to Restate without waiting for the result
+func (h *DeadLetterHandler) submitEnrichment(ctx context.Context, stage, ip string) error {
+	payload := map[string]interface{}{
+		"ips": []string{ip},
+	}
+	if stage == models.DeadLetterStageASN {
+		// Skip the "already enriched" filter so the IP is looked up again
+		payload["force_refresh"] = true
+	}
+	return h.invoker.Send(ctx, enrichmentServices[stage], payload)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeDeadLetterStore keeps dead letters in memory, keyed by stage and IP
+type fakeDeadLetterStore struct {
+	records map[string]models.DeadLetter
+}
+
+func newFakeDeadLetterStore(letters ...models.DeadLetter) *fakeDeadLetterStore {
+	store := &fakeDeadLetterStore{records: make(map[string]models.DeadLetter)}
+	for _, dl := range letters {
+		store.records[dl.Stage+":"+dl.IP] = dl
+	}
+	return store
+}
+
+func (f *fakeDeadLetterStore) List(ctx context.Context, stage string, limit int) ([]models.DeadLetter, error) {
+	out := make([]models.DeadLetter, 0)
+	for _, dl := range f.records {
+		if stage != "" && dl.Stage != stage {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, dl)
+	}
+	return out, nil
+}
+
+func (f *fakeDeadLetterStore) Get(ctx context.Context, ip, stage string) (*models.DeadLetter, error) {
+	dl, ok := f.records[stage+":"+ip]
+	if !ok {
+		return nil, nil
+	}
+	return &dl, nil
+}
+
+func (f *fakeDeadLetterStore) Delete(ctx context.Context, ip, stage string) error {
+	delete(f.records, stage+":"+ip)
+	return nil
+}
+
+func testDeadLetter(ip, stage string) models.DeadLetter {
+	return models.DeadLetter{
+		IP:          ip,
+		Stage:       stage,
+		Reason:      "no data returned after retries",
+		Attempts:    3,
+		LastAttempt: time.Now().UTC(),
+	}
+}
+
+func TestDeadLetterHandler_HandleList(t *testing.T) {
+	store := newFakeDeadLetterStore(
+		testDeadLetter("192.0.2.1", models.DeadLetterStageASN),
+		testDeadLetter("192.0.2.2", models.DeadLetterStageGeo),
+	)
+	handler := NewDeadLetterHandler(store, "http://unused", nil, zap.NewNop())
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTotal  int
+	}{
+		{"all stages", "", http.StatusOK, 2},
+		{"filter by stage", "?stage=geo", http.StatusOK, 1},
+		{"limit", "?limit=1", http.StatusOK, 1},
+		{"invalid stage", "?stage=dns", http.StatusBadRequest, 0},
+		{"limit too large", "?limit=501", http.StatusBadRequest, 0},
+		{"limit not a number", "?limit=abc", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/dead-letters"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.HandleList(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp models.DeadLetterListResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, tt.wantTotal, resp.Total)
+			assert.Len(t, resp.DeadLetters, tt.wantTotal)
+		})
+	}
+}
+
+func TestDeadLetterHandler_HandleRequeue(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]interface{}
+	restate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer restate.Close()
+
+	store := newFakeDeadLetterStore(testDeadLetter("192.0.2.1", models.DeadLetterStageASN))
+	audit := &fakeAudit{}
+	handler := NewDeadLetterHandler(store, restate.URL, audit, zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/dead-letters/requeue",
+		strings.NewReader(`{"ip":"192.0.2.1","stage":"asn"}`))
+	req.Header.Set("Authorization", "Bearer admin-token")
+	w := httptest.NewRecorder()
+
+	middleware.AdminAuth("admin-token", zap.NewNop())(http.HandlerFunc(handler.HandleRequeue)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/EnrichASNWorkflow/Run/send", gotPath)
+	assert.Equal(t, []interface{}{"192.0.2.1"}, gotBody["ips"])
+	assert.Equal(t, true, gotBody["force_refresh"])
+	assert.Empty(t, store.records, "requeue should clear the dead letter")
+
+	var resp models.DeadLetter
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "192.0.2.1", resp.IP)
+	assert.Equal(t, 3, resp.Attempts)
+
+	require.Len(t, audit.entries, 1)
+	assert.Equal(t, models.AuditActionRequeue, audit.entries[0].Action)
+	assert.Equal(t, models.AuditActorAdmin, audit.entries[0].Actor)
+	assert.Equal(t, "192.0.2.1", audit.entries[0].Details["ip"])
+	assert.Equal(t, models.DeadLetterStageASN, audit.entries[0].Details["stage"])
+}
+
+func TestDeadLetterHandler_HandleRequeue_RestateFailureKeepsRecord(t *testing.T) {
+	restate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer restate.Close()
+
+	store := newFakeDeadLetterStore(testDeadLetter("192.0.2.2", models.DeadLetterStageGeo))
+	audit := &fakeAudit{}
+	handler := NewDeadLetterHandler(store, restate.URL, audit, zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/dead-letters/requeue",
+		strings.NewReader(`{"ip":"192.0.2.2","stage":"geo"}`))
+	w := httptest.NewRecorder()
+
+	handler.HandleRequeue(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, store.records, "geo:192.0.2.2")
+	assert.Empty(t, audit.entries, "a requeue that never reached the workflow is not audited")
+}
+
+func TestDeadLetterHandler_HandleRequeue_Validation(t *testing.T) {
+	store := newFakeDeadLetterStore()
+	handler := NewDeadLetterHandler(store, "http://unused", nil, zap.NewNop())
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest},
+		{"missing ip", `{"stage":"asn"}`, http.StatusBadRequest},
+		{"invalid stage", `{"ip":"192.0.2.1","stage":"dns"}`, http.StatusBadRequest},
+		{"not found", `{"ip":"192.0.2.1","stage":"asn"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/dead-letters/requeue", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.HandleRequeue(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
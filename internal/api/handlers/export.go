@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// exportFlushEvery is how many records an export writes between flushes
+const exportFlushEvery = 100
+
+// GraphExporter streams graph records in batches
+type GraphExporter interface {
+	Export(ctx context.Context, tables []string, emit func(models.ExportRecord) error) (int, error)
+}
+
+// ExportHandler handles GET /v1/admin/export
+// The response is JSON lines, one models.ExportRecord per node or edge, nodes
+// before edges. ?tables=host,HAS limits it to those tables. Records are written
+// as they are read, so the status is sent before the export can fail; the
+// X-Export-Records and X-Export-Error trailers report how it ended.
+// audit, if non-nil, records each export with the tables and record count.
+func ExportHandler(exporter GraphExporter, audit AuditRecorder, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			jobErrorResponse(w, "streaming_unsupported", "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		var selected []string
+		if raw := r.URL.Query().Get("tables"); raw != "" {
+			selected = strings.Split(raw, ",")
+		}
+		tables, err := models.ResolveExportTables(selected)
+		if err != nil {
+			jobErrorResponse(w, "invalid_request", err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The export outlives the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.Header().Set("Trailer", models.ExportRecordsTrailer+", "+models.ExportErrorTrailer)
+		w.WriteHeader(http.StatusOK)
+
+		encoder := json.NewEncoder(w)
+		written := 0
+		exported, err := exporter.Export(r.Context(), tables, func(record models.ExportRecord) error {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+			written++
+			if written%exportFlushEvery == 0 {
+				flusher.Flush()
+			}
+			return nil
+		})
+
+		w.Header().Set(models.ExportRecordsTrailer, strconv.Itoa(exported))
+		complete := err == nil
+		if err != nil {
+			// Most often the client went away; the status has already been sent
+			logger.Error("graph export failed",
+				zap.Error(err),
+				zap.Strings("tables", tables),
+				zap.Int("records", exported))
+			w.Header().Set(models.ExportErrorTrailer, "export failed after "+strconv.Itoa(exported)+" records")
+		} else {
+			logger.Info("graph export finished",
+				zap.Strings("tables", tables),
+				zap.Int("records", exported))
+		}
+		flusher.Flush()
+
+		// The request context may be gone if the client disconnected
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+		defer cancel()
+		recordAudit(ctx, audit, r, models.AuditEntry{
+			Action: models.AuditActionExport,
+			Actor:  models.AuditActorAdmin,
+			Details: map[string]interface{}{
+				"tables":   tables,
+				"records":  exported,
+				"complete": complete,
+			},
+		}, logger)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeExporter emits a fixed set of records, then returns err
+type fakeExporter struct {
+	records   []models.ExportRecord
+	err       error
+	gotTables []string
+}
+
+func (e *fakeExporter) Export(ctx context.Context, tables []string, emit func(models.ExportRecord) error) (int, error) {
+	e.gotTables = tables
+	exported := 0
+	for _, record := range e.records {
+		if err := emit(record); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+	return exported, e.err
+}
+
+func TestExportHandler(t *testing.T) {
+	records := []models.ExportRecord{
+		{Table: "host", Kind: models.ExportKindNode, ID: "host:a", Data: map[string]interface{}{"ip": "192.0.2.1"}},
+		{Table: "port", Kind: models.ExportKindNode, ID: "port:port_22_tcp", Data: map[string]interface{}{"number": float64(22)}},
+		{Table: "HAS", Kind: models.ExportKindEdge, ID: "HAS:e1", In: "host:a", Out: "port:port_22_tcp", Data: map[string]interface{}{}},
+	}
+
+	t.Run("streams records as JSON lines", func(t *testing.T) {
+		exporter := &fakeExporter{records: records}
+		audit := &fakeAudit{}
+		handler := ExportHandler(exporter, audit, zap.NewNop())
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/export?tables=HAS,port,host", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		assert.Equal(t, []string{"host", "port", "HAS"}, exporter.gotTables)
+
+		var got []models.ExportRecord
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var record models.ExportRecord
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+			got = append(got, record)
+		}
+		assert.Equal(t, records, got)
+
+		assert.Equal(t, "3", resp.Trailer.Get(models.ExportRecordsTrailer))
+		assert.Empty(t, resp.Trailer.Get(models.ExportErrorTrailer))
+
+		require.Len(t, audit.entries, 1)
+		assert.Equal(t, models.AuditActionExport, audit.entries[0].Action)
+		assert.Equal(t, models.AuditActorAdmin, audit.entries[0].Actor)
+		assert.Equal(t, 3, audit.entries[0].Details["records"])
+		assert.Equal(t, true, audit.entries[0].Details["complete"])
+	})
+
+	t.Run("no tables exports every table", func(t *testing.T) {
+		exporter := &fakeExporter{}
+		w := httptest.NewRecorder()
+		ExportHandler(exporter, nil, zap.NewNop()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/export", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, exporter.gotTables, len(models.ExportNodeTables)+len(models.ExportEdgeTables))
+		assert.Equal(t, "0", w.Result().Trailer.Get(models.ExportRecordsTrailer))
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		exporter := &fakeExporter{records: records}
+		w := httptest.NewRecorder()
+		ExportHandler(exporter, nil, zap.NewNop()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/export?tables=host,job", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "job")
+		assert.Nil(t, exporter.gotTables)
+	})
+
+	t.Run("failure midway is reported in the trailer", func(t *testing.T) {
+		exporter := &fakeExporter{records: records[:1], err: errors.New("connection reset")}
+		audit := &fakeAudit{}
+		w := httptest.NewRecorder()
+		ExportHandler(exporter, audit, zap.NewNop()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/export", nil))
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "the status is sent before the export fails")
+		assert.Equal(t, "1", resp.Trailer.Get(models.ExportRecordsTrailer))
+		assert.NotEmpty(t, resp.Trailer.Get(models.ExportErrorTrailer))
+
+		require.Len(t, audit.entries, 1)
+		assert.Equal(t, false, audit.entries[0].Details["complete"])
+	})
+}
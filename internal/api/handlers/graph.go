@@ -3,7 +3,11 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spectra-red/recon/internal/db"
@@ -12,54 +16,79 @@ import (
 	"go.uber.org/zap"
 )
 
+// GraphExecutor executes graph traversal and aggregation queries
+type GraphExecutor interface {
+	ExecuteGraphQuery(ctx context.Context, req models.GraphQueryRequest) (*models.GraphQueryResponse, error)
+	QueryAggregateByASN(ctx context.Context, limit int) (*models.ASNAggregateResponse, error)
+	QueryByCPE(ctx context.Context, cpe string, limit int) (*models.CPELookupResponse, error)
+	QueryByCPEPrefix(ctx context.Context, prefix string, limit int) (*models.CPEPrefixResponse, error)
+	QueryExposure(ctx context.Context, ports []int, limit int) (*models.ExposureResponse, error)
+	QueryUnidentifiedServices(ctx context.Context, limit int) (*models.UnidentifiedServicesResponse, error)
+	QueryDiff(ctx context.Context, req models.DiffRequest) (*models.DiffResponse, error)
+}
+
 // GraphQueryHandler handles graph traversal queries
 type GraphQueryHandler struct {
-	executor *db.GraphQueryExecutor
-	logger   *zap.Logger
+	executor     GraphExecutor
+	maxBodyBytes int64
+	riskyPorts   []int // ports the exposure report looks for unless the request names some
+	logger       *zap.Logger
 }
 
-// NewGraphQueryHandler creates a new graph query handler
-func NewGraphQueryHandler(logger *zap.Logger) (*GraphQueryHandler, error) {
-	// Create database connection
-	dbConn, err := surrealdb.New("ws://localhost:8000/rpc")
-	if err != nil {
-		return nil, err
-	}
+// NewGraphQueryHandler creates a new graph query handler on the shared database
+// connection whose queries are cut off after maxQueryDuration (db.DefaultMaxQueryDuration if non-positive)
+func NewGraphQueryHandler(dbClient *surrealdb.DB, logger *zap.Logger, maxQueryDuration time.Duration) *GraphQueryHandler {
+	return NewGraphQueryHandlerWithCache(dbClient, logger, maxQueryDuration, nil)
+}
 
-	ctx := context.Background()
+// NewGraphQueryHandlerWithCache creates a graph query handler that serves repeated
+// queries from cache; a nil cache disables caching
+func NewGraphQueryHandlerWithCache(dbClient *surrealdb.DB, logger *zap.Logger, maxQueryDuration time.Duration, cache *db.GraphQueryCache) *GraphQueryHandler {
+	executor := db.NewGraphQueryExecutorWithTimeout(dbClient, logger, maxQueryDuration).WithCache(cache)
 
-	// Sign in with credentials
-	if _, err := dbConn.SignIn(ctx, map[string]interface{}{
-		"user": "root",
-		"pass": "root",
-	}); err != nil {
-		dbConn.Close(ctx)
-		return nil, err
-	}
+	return NewGraphQueryHandlerWithExecutor(executor, logger)
+}
 
-	// Use namespace and database
-	if err := dbConn.Use(ctx, "spectra", "intel"); err != nil {
-		dbConn.Close(ctx)
-		return nil, err
+// NewGraphQueryHandlerWithExecutor creates a graph query handler backed by the given executor
+func NewGraphQueryHandlerWithExecutor(executor GraphExecutor, logger *zap.Logger) *GraphQueryHandler {
+	return &GraphQueryHandler{
+		executor:     executor,
+		maxBodyBytes: DefaultQueryMaxBodyBytes,
+		logger:       logger,
 	}
+}
 
-	executor := db.NewGraphQueryExecutor(dbConn, logger)
+// WithMaxBodyBytes caps the request bodies of graph queries and diffs; a
+// non-positive value keeps DefaultQueryMaxBodyBytes
+func (h *GraphQueryHandler) WithMaxBodyBytes(maxBodyBytes int64) *GraphQueryHandler {
+	if maxBodyBytes > 0 {
+		h.maxBodyBytes = maxBodyBytes
+	}
+	return h
+}
 
-	return &GraphQueryHandler{
-		executor: executor,
-		logger:   logger,
-	}, nil
+// WithRiskyPorts sets the ports the exposure report looks for when a request
+// names none; empty uses models.DefaultRiskyPorts
+func (h *GraphQueryHandler) WithRiskyPorts(riskyPorts []int) *GraphQueryHandler {
+	h.riskyPorts = riskyPorts
+	return h
 }
 
 // HandleGraphQuery handles POST /v1/query/graph requests
 func (h *GraphQueryHandler) HandleGraphQuery(w http.ResponseWriter, r *http.Request) {
-	// Create context with timeout protection (5 seconds max)
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
+	// The executor applies the server-side query deadline
+	ctx := r.Context()
 
 	// Parse request body
 	var req models.GraphQueryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(w, r, h.maxBodyBytes, &req); err != nil {
+		if isBodyTooLarge(err) {
+			h.logger.Warn("graph query request body too large",
+				zap.Int64("max_body_bytes", h.maxBodyBytes),
+				zap.String("remote_addr", r.RemoteAddr))
+			h.respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", h.maxBodyBytes), nil)
+			return
+		}
 		h.logger.Warn("failed to decode graph query request",
 			zap.Error(err),
 			zap.String("remote_addr", r.RemoteAddr))
@@ -74,27 +103,28 @@ func (h *GraphQueryHandler) HandleGraphQuery(w http.ResponseWriter, r *http.Requ
 		zap.String("city", req.City),
 		zap.String("cve", req.CVE),
 		zap.String("product", req.Product),
+		zap.String("seed_ip", req.SeedIP),
 		zap.Int("limit", req.Limit),
 		zap.Int("offset", req.Offset))
 
-	// Execute query with timeout protection
 	resp, err := h.executor.ExecuteGraphQuery(ctx, req)
 	if err != nil {
 		// Check if error was due to timeout
-		if ctx.Err() == context.DeadlineExceeded {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
 			h.logger.Warn("graph query timeout",
-				zap.String("query_type", string(req.QueryType)),
-				zap.Duration("timeout", 5*time.Second))
-			h.respondWithError(w, http.StatusRequestTimeout, "query timeout exceeded", err)
+				zap.String("query_type", string(req.QueryType)))
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
 			return
 		}
 
-		// Check for validation errors
-		if validationErr, ok := err.(*models.ValidationError); ok {
+		// Check for validation errors (the executor wraps them)
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
 			h.logger.Warn("graph query validation error",
 				zap.String("field", validationErr.Field),
+				zap.String("rule", validationErr.Rule),
 				zap.String("message", validationErr.Message))
-			h.respondWithError(w, http.StatusBadRequest, validationErr.Message, err)
+			h.respondWithValidationError(w, validationErr)
 			return
 		}
 
@@ -122,11 +152,330 @@ func (h *GraphQueryHandler) HandleGraphQuery(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// HandleAggregateByASN handles GET /v1/query/aggregate/asn requests
+func (h *GraphQueryHandler) HandleAggregateByASN(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse optional limit parameter (top-N)
+	limit := models.DefaultAggregateLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > models.MaxLimit {
+			h.logger.Warn("invalid aggregate limit parameter",
+				zap.String("limit", limitParam))
+			h.respondWithError(w, http.StatusBadRequest, "limit must be an integer between 1 and 1000", err)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	resp, err := h.executor.QueryAggregateByASN(ctx, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			h.logger.Warn("ASN aggregate query timeout")
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
+			return
+		}
+
+		h.logger.Error("ASN aggregate query failed",
+			zap.Error(err))
+		h.respondWithError(w, http.StatusInternalServerError, "query execution failed", err)
+		return
+	}
+
+	h.logger.Info("ASN aggregate query completed",
+		zap.Int("result_count", len(resp.Results)),
+		zap.Float64("query_time_ms", resp.QueryTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode ASN aggregate response",
+			zap.Error(err))
+	}
+}
+
+// HandleCPELookup handles GET /v1/query/cpe requests
+// Query params: ?cpe=cpe:2.3:a:vendor:product:version:...&limit=100
+func (h *GraphQueryHandler) HandleCPELookup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	cpe := strings.TrimSpace(r.URL.Query().Get("cpe"))
+	if !strings.HasPrefix(cpe, "cpe:") {
+		h.respondWithError(w, http.StatusBadRequest, "cpe must be a CPE string such as cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*", nil)
+		return
+	}
+
+	limit := models.DefaultLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > models.MaxLimit {
+			h.logger.Warn("invalid CPE lookup limit parameter",
+				zap.String("limit", limitParam))
+			h.respondWithError(w, http.StatusBadRequest, "limit must be an integer between 1 and 1000", err)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	resp, err := h.executor.QueryByCPE(ctx, cpe, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			h.logger.Warn("CPE lookup timeout",
+				zap.String("cpe", cpe))
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
+			return
+		}
+
+		h.logger.Error("CPE lookup failed",
+			zap.Error(err),
+			zap.String("cpe", cpe))
+		h.respondWithError(w, http.StatusInternalServerError, "query execution failed", err)
+		return
+	}
+
+	h.logger.Info("CPE lookup completed",
+		zap.String("cpe", cpe),
+		zap.Int("service_count", len(resp.Services)),
+		zap.Float64("query_time_ms", resp.QueryTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode CPE lookup response",
+			zap.Error(err))
+	}
+}
+
+// HandleCPEPrefix handles GET /v1/query/cpe/prefix requests
+// Query params: ?prefix=cpe:2.3:a:apache:*&limit=100
+func (h *GraphQueryHandler) HandleCPEPrefix(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	prefix := strings.TrimSpace(r.URL.Query().Get("prefix"))
+
+	limit := models.DefaultLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > models.MaxLimit {
+			h.logger.Warn("invalid CPE prefix lookup limit parameter",
+				zap.String("limit", limitParam))
+			h.respondWithError(w, http.StatusBadRequest, "limit must be an integer between 1 and 1000", err)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	resp, err := h.executor.QueryByCPEPrefix(ctx, prefix, limit)
+	if err != nil {
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			h.logger.Warn("CPE prefix validation error",
+				zap.String("prefix", prefix),
+				zap.String("message", validationErr.Message))
+			h.respondWithValidationError(w, validationErr)
+			return
+		}
+
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			h.logger.Warn("CPE prefix lookup timeout",
+				zap.String("prefix", prefix))
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
+			return
+		}
+
+		h.logger.Error("CPE prefix lookup failed",
+			zap.Error(err),
+			zap.String("prefix", prefix))
+		h.respondWithError(w, http.StatusInternalServerError, "query execution failed", err)
+		return
+	}
+
+	h.logger.Info("CPE prefix lookup completed",
+		zap.String("prefix", resp.Prefix),
+		zap.Int("service_count", len(resp.Services)),
+		zap.Int("host_count", len(resp.Hosts)),
+		zap.Float64("query_time_ms", resp.QueryTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode CPE prefix lookup response",
+			zap.Error(err))
+	}
+}
+
+// HandleExposure handles GET /v1/query/exposure requests
+// Query params: ?ports=22,3389&limit=100 (hosts listed per port, max 1000)
+func (h *GraphQueryHandler) HandleExposure(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ports := h.riskyPorts
+	if portsParam := r.URL.Query().Get("ports"); portsParam != "" {
+		parsedPorts, err := models.ParsePortList(portsParam)
+		if err != nil || len(parsedPorts) == 0 {
+			h.logger.Warn("invalid exposure ports parameter",
+				zap.String("ports", portsParam))
+			h.respondWithError(w, http.StatusBadRequest, "ports must be a comma-separated list of port numbers", err)
+			return
+		}
+		ports = parsedPorts
+	}
+
+	limit := models.DefaultLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > models.MaxLimit {
+			h.logger.Warn("invalid exposure limit parameter",
+				zap.String("limit", limitParam))
+			h.respondWithError(w, http.StatusBadRequest, "limit must be an integer between 1 and 1000", err)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	resp, err := h.executor.QueryExposure(ctx, ports, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			h.logger.Warn("exposure query timeout")
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
+			return
+		}
+
+		h.logger.Error("exposure query failed",
+			zap.Error(err))
+		h.respondWithError(w, http.StatusInternalServerError, "query execution failed", err)
+		return
+	}
+
+	h.logger.Info("exposure query completed",
+		zap.Int("port_count", len(resp.Results)),
+		zap.Float64("query_time_ms", resp.QueryTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode exposure response",
+			zap.Error(err))
+	}
+}
+
+// HandleDiff handles POST /v1/query/diff requests
+func (h *GraphQueryHandler) HandleDiff(w http.ResponseWriter, r *http.Request) {
+	// The executor applies the server-side query deadline
+	ctx := r.Context()
+
+	var req models.DiffRequest
+	if err := decodeJSONBody(w, r, h.maxBodyBytes, &req); err != nil {
+		if isBodyTooLarge(err) {
+			h.logger.Warn("diff request body too large",
+				zap.Int64("max_body_bytes", h.maxBodyBytes),
+				zap.String("remote_addr", r.RemoteAddr))
+			h.respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", h.maxBodyBytes), nil)
+			return
+		}
+		h.logger.Warn("failed to decode diff request",
+			zap.Error(err),
+			zap.String("remote_addr", r.RemoteAddr))
+		h.respondWithError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	resp, err := h.executor.QueryDiff(ctx, req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			h.logger.Warn("diff query timeout")
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
+			return
+		}
+
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			h.logger.Warn("diff query validation error",
+				zap.String("field", validationErr.Field),
+				zap.String("rule", validationErr.Rule),
+				zap.String("message", validationErr.Message))
+			h.respondWithValidationError(w, validationErr)
+			return
+		}
+
+		h.logger.Error("diff query failed",
+			zap.Error(err))
+		h.respondWithError(w, http.StatusInternalServerError, "query execution failed", err)
+		return
+	}
+
+	h.logger.Info("diff query completed",
+		zap.String("cidr", req.CIDR),
+		zap.Any("asn", req.ASN),
+		zap.Int("hosts_added", resp.Summary.HostsAdded),
+		zap.Int("hosts_removed", resp.Summary.HostsRemoved),
+		zap.Int("hosts_changed", resp.Summary.HostsChanged),
+		zap.Float64("query_time_ms", resp.QueryTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode diff response",
+			zap.Error(err))
+	}
+}
+
+// HandleUnidentifiedServices handles GET /v1/admin/unidentified-services requests
+// Query params: ?limit=20 (top-N banners, max 1000)
+func (h *GraphQueryHandler) HandleUnidentifiedServices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := models.DefaultAggregateLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 1 || parsedLimit > models.MaxLimit {
+			h.logger.Warn("invalid unidentified services limit parameter",
+				zap.String("limit", limitParam))
+			h.respondWithError(w, http.StatusBadRequest, "limit must be an integer between 1 and 1000", err)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	resp, err := h.executor.QueryUnidentifiedServices(ctx, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+			h.logger.Warn("unidentified services query timeout")
+			h.respondWithError(w, http.StatusGatewayTimeout, "query exceeded the server deadline", err)
+			return
+		}
+
+		h.logger.Error("unidentified services query failed",
+			zap.Error(err))
+		h.respondWithError(w, http.StatusInternalServerError, "query execution failed", err)
+		return
+	}
+
+	h.logger.Info("unidentified services query completed",
+		zap.Int("result_count", len(resp.Results)),
+		zap.Float64("query_time_ms", resp.QueryTime))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode unidentified services response",
+			zap.Error(err))
+	}
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Error   string                   `json:"error"`
+	Message string                   `json:"message"`
+	Details string                   `json:"details,omitempty"`
+	Errors  []models.ValidationError `json:"errors,omitempty"`
 }
 
 // respondWithError sends an error response
@@ -149,22 +498,20 @@ func (h *GraphQueryHandler) respondWithError(w http.ResponseWriter, statusCode i
 	}
 }
 
-// GraphQueryHandlerFunc returns a handler function that can be used with chi router
-func GraphQueryHandlerFunc(logger *zap.Logger) http.HandlerFunc {
-	handler, err := NewGraphQueryHandler(logger)
-	if err != nil {
-		logger.Error("failed to create graph query handler",
-			zap.Error(err))
-		// Return a handler that always returns 503
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(ErrorResponse{
-				Error:   "Service Unavailable",
-				Message: "database connection unavailable",
-			})
-		}
+// respondWithValidationError sends a 400 response with field-level validation details
+func (h *GraphQueryHandler) respondWithValidationError(w http.ResponseWriter, validationErr *models.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+
+	errResp := ErrorResponse{
+		Error:   http.StatusText(http.StatusBadRequest),
+		Message: validationErr.Message,
+		Details: validationErr.Error(),
+		Errors:  []models.ValidationError{*validationErr},
 	}
 
-	return handler.HandleGraphQuery
+	if err := json.NewEncoder(w).Encode(errResp); err != nil {
+		h.logger.Error("failed to encode error response",
+			zap.Error(err))
+	}
 }
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,8 +24,11 @@ import (
 func setupTestGraphDB(t *testing.T) *surrealdb.DB {
 	ctx := context.Background()
 
+	// Integration tests need a local SurrealDB; without one they are skipped
 	db, err := surrealdb.New("ws://localhost:8000/rpc")
-	require.NoError(t, err, "failed to connect to SurrealDB")
+	if err != nil {
+		t.Skipf("SurrealDB not available at ws://localhost:8000/rpc: %v", err)
+	}
 
 	// Sign in
 	_, err = db.SignIn(ctx, map[string]interface{}{
@@ -45,7 +52,7 @@ func cleanupTestGraphDB(t *testing.T, db *surrealdb.DB) {
 	ctx := context.Background()
 
 	// Delete all test data
-	_, err := db.Query(ctx, "DELETE host; DELETE port; DELETE service; DELETE vuln;", nil)
+	_, err := surrealdb.Query[interface{}](ctx, db, "DELETE host; DELETE port; DELETE service; DELETE vuln;", nil)
 	if err != nil {
 		t.Logf("cleanup error (non-fatal): %v", err)
 	}
@@ -78,7 +85,7 @@ func seedTestGraphData(t *testing.T, db *surrealdb.DB) {
 	}
 
 	for _, query := range queries {
-		_, err := db.Query(ctx, query, nil)
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
 		require.NoError(t, err, "failed to seed test data: %s", query)
 	}
 }
@@ -89,8 +96,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ByASN(t *testing.T) {
 	defer cleanupTestGraphDB(t, db)
 
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandler(db, logger, 0)
 
 	// Prepare request
 	asn := 15169
@@ -130,8 +136,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ByLocation(t *testing.T) {
 	defer cleanupTestGraphDB(t, db)
 
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandler(db, logger, 0)
 
 	tests := []struct {
 		name      string
@@ -196,8 +201,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ByVuln(t *testing.T) {
 	defer cleanupTestGraphDB(t, db)
 
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandler(db, logger, 0)
 
 	// Prepare request
 	reqBody := models.GraphQueryRequest{
@@ -235,8 +239,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ByService(t *testing.T) {
 	defer cleanupTestGraphDB(t, db)
 
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandler(db, logger, 0)
 
 	tests := []struct {
 		name      string
@@ -291,15 +294,57 @@ func TestGraphQueryHandler_HandleGraphQuery_ByService(t *testing.T) {
 	}
 }
 
-func TestGraphQueryHandler_HandleGraphQuery_Pagination(t *testing.T) {
+func TestGraphQueryHandler_HandleGraphQuery_ByKEV(t *testing.T) {
 	// Setup
 	db := setupTestGraphDB(t)
 	defer cleanupTestGraphDB(t, db)
 
+	// CVE-2023-1234 (nginx, host:test1) stays non-KEV; redis gets a KEV-listed CVE
+	ctx := context.Background()
+	for _, query := range []string{
+		`CREATE vuln:cve_2024_0001 SET cve = "CVE-2024-0001", title = "Redis KEV Vulnerability", cvss = 8.8, kev_flag = true;`,
+		`RELATE service:redis->AFFECTED_BY->vuln:cve_2024_0001;`,
+	} {
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
+		require.NoError(t, err, "failed to seed KEV data: %s", query)
+	}
+
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
+	handler := NewGraphQueryHandler(db, logger, 0)
+
+	// Prepare request
+	body, err := json.Marshal(models.GraphQueryRequest{QueryType: models.QueryByKEV, Limit: 10})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+
+	// Execute
+	handler.HandleGraphQuery(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.GraphQueryResponse
+	err = json.NewDecoder(w.Body).Decode(&resp)
 	require.NoError(t, err)
 
+	// Only the redis host is affected by a KEV-listed CVE
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "10.0.0.1", resp.Results[0].IP)
+	assert.Equal(t, 8.8, resp.Results[0].MaxCVSS)
+}
+
+func TestGraphQueryHandler_HandleGraphQuery_Pagination(t *testing.T) {
+	// Setup
+	db := setupTestGraphDB(t)
+	defer cleanupTestGraphDB(t, db)
+
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandler(db, logger, 0)
+
 	// First page
 	asn := 15169
 	reqBody1 := models.GraphQueryRequest{
@@ -356,13 +401,14 @@ func TestGraphQueryHandler_HandleGraphQuery_Pagination(t *testing.T) {
 
 func TestGraphQueryHandler_HandleGraphQuery_ValidationErrors(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	// The executor validates requests before querying, so no database is needed
+	handler := NewGraphQueryHandler(nil, logger, 0)
 
 	tests := []struct {
 		name       string
 		reqBody    models.GraphQueryRequest
 		wantStatus int
+		wantField  string
 	}{
 		{
 			name: "missing ASN for by_asn query",
@@ -371,6 +417,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ValidationErrors(t *testing.T) {
 				Limit:     10,
 			},
 			wantStatus: http.StatusBadRequest,
+			wantField:  "asn",
 		},
 		{
 			name: "missing location for by_location query",
@@ -379,6 +426,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ValidationErrors(t *testing.T) {
 				Limit:     10,
 			},
 			wantStatus: http.StatusBadRequest,
+			wantField:  "location",
 		},
 		{
 			name: "missing CVE for by_vuln query",
@@ -387,6 +435,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ValidationErrors(t *testing.T) {
 				Limit:     10,
 			},
 			wantStatus: http.StatusBadRequest,
+			wantField:  "cve",
 		},
 		{
 			name: "missing service for by_service query",
@@ -395,6 +444,7 @@ func TestGraphQueryHandler_HandleGraphQuery_ValidationErrors(t *testing.T) {
 				Limit:     10,
 			},
 			wantStatus: http.StatusBadRequest,
+			wantField:  "service",
 		},
 	}
 
@@ -415,14 +465,17 @@ func TestGraphQueryHandler_HandleGraphQuery_ValidationErrors(t *testing.T) {
 			err = json.NewDecoder(w.Body).Decode(&errResp)
 			require.NoError(t, err)
 			assert.NotEmpty(t, errResp.Message)
+
+			require.Len(t, errResp.Errors, 1)
+			assert.Equal(t, tt.wantField, errResp.Errors[0].Field)
+			assert.Equal(t, models.RuleRequired, errResp.Errors[0].Rule)
 		})
 	}
 }
 
 func TestGraphQueryHandler_HandleGraphQuery_InvalidJSON(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, logger)
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
@@ -433,15 +486,271 @@ func TestGraphQueryHandler_HandleGraphQuery_InvalidJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
 	var errResp ErrorResponse
-	err = json.NewDecoder(w.Body).Decode(&errResp)
+	err := json.NewDecoder(w.Body).Decode(&errResp)
 	require.NoError(t, err)
 	assert.Contains(t, errResp.Message, "invalid request body")
 }
 
+// stubGraphExecutor is a GraphExecutor that returns a fixed error or blocks until the context is done
+type stubGraphExecutor struct {
+	err   error
+	block bool
+
+	// services are returned by QueryByCPE and QueryByCPEPrefix for matching CPEs
+	services []models.CPEServiceMatch
+
+	// banners are returned by QueryUnidentifiedServices, truncated to the limit
+	banners []models.UnidentifiedBanner
+
+	// exposure is returned by QueryExposure for the ports it asks for, which are recorded in exposurePorts
+	exposure      []models.ExposureGroup
+	exposurePorts []int
+
+	// diff is returned by QueryDiff, which records the validated request in diffRequest
+	diff        models.DiffResponse
+	diffRequest models.DiffRequest
+}
+
+func (s *stubGraphExecutor) ExecuteGraphQuery(ctx context.Context, req models.GraphQueryRequest) (*models.GraphQueryResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	return nil, s.err
+}
+
+func (s *stubGraphExecutor) QueryAggregateByASN(ctx context.Context, limit int) (*models.ASNAggregateResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	return nil, s.err
+}
+
+func (s *stubGraphExecutor) QueryUnidentifiedServices(ctx context.Context, limit int) (*models.UnidentifiedServicesResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	banners := s.banners
+	if len(banners) > limit {
+		banners = banners[:limit]
+	}
+	return &models.UnidentifiedServicesResponse{Results: banners, Limit: limit}, nil
+}
+
+func (s *stubGraphExecutor) QueryByCPE(ctx context.Context, cpe string, limit int) (*models.CPELookupResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	resp := &models.CPELookupResponse{CPE: cpe, Services: []models.CPEServiceMatch{}, Limit: limit}
+	for _, service := range s.services {
+		for _, assigned := range service.CPEs {
+			if assigned == cpe {
+				resp.Services = append(resp.Services, service)
+				break
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (s *stubGraphExecutor) QueryByCPEPrefix(ctx context.Context, prefix string, limit int) (*models.CPEPrefixResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	pattern, err := models.ParseCPEPrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	resp := &models.CPEPrefixResponse{Prefix: pattern.Prefix, Services: []models.CPEServiceMatch{}, Hosts: []string{}, Limit: limit}
+	for _, service := range s.services {
+		for _, assigned := range service.CPEs {
+			if pattern.Matches(assigned) {
+				resp.Services = append(resp.Services, service)
+				resp.Hosts = append(resp.Hosts, service.Hosts...)
+				break
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (s *stubGraphExecutor) QueryExposure(ctx context.Context, ports []int, limit int) (*models.ExposureResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.exposurePorts = ports
+	resp := &models.ExposureResponse{Results: []models.ExposureGroup{}, Ports: ports, Limit: limit}
+	for _, group := range s.exposure {
+		for _, port := range ports {
+			if group.Port == port {
+				resp.Results = append(resp.Results, group)
+				break
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (s *stubGraphExecutor) QueryDiff(ctx context.Context, req models.DiffRequest) (*models.DiffResponse, error) {
+	if s.block {
+		<-ctx.Done()
+		return nil, fmt.Errorf("query failed: %w", ctx.Err())
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	s.diffRequest = req
+	resp := s.diff
+	return &resp, nil
+}
+
 func TestGraphQueryHandler_HandleGraphQuery_Timeout(t *testing.T) {
-	// This test is challenging to implement without mocking
-	// In a real scenario, we would mock the executor to simulate a slow query
-	t.Skip("Timeout testing requires mocking or extremely slow queries")
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{block: true}, logger)
+
+	asn := 15169
+	body, err := json.Marshal(models.GraphQueryRequest{QueryType: models.QueryByASN, ASN: &asn})
+	require.NoError(t, err)
+
+	// A context that expires almost immediately stands in for a slow query
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.HandleGraphQuery(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	var errResp ErrorResponse
+	err = json.NewDecoder(w.Body).Decode(&errResp)
+	require.NoError(t, err)
+	assert.Contains(t, errResp.Message, "deadline")
+}
+
+func TestGraphQueryHandler_HandleGraphQuery_WrappedDeadlineError(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{
+		err: fmt.Errorf("failed to query hosts: %w", context.DeadlineExceeded),
+	}, logger)
+
+	asn := 15169
+	body, err := json.Marshal(models.GraphQueryRequest{QueryType: models.QueryByASN, ASN: &asn})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleGraphQuery(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestGraphQueryHandler_HandleGraphQuery_WrappedValidationError(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{
+		err: fmt.Errorf("validation error: %w", models.ErrMissingASN),
+	}, logger)
+
+	body, err := json.Marshal(models.GraphQueryRequest{QueryType: models.QueryByASN})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleGraphQuery(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errResp ErrorResponse
+	err = json.NewDecoder(w.Body).Decode(&errResp)
+	require.NoError(t, err)
+	require.Len(t, errResp.Errors, 1)
+	assert.Equal(t, "asn", errResp.Errors[0].Field)
+	assert.Equal(t, models.RuleRequired, errResp.Errors[0].Rule)
+}
+
+func TestGraphQueryHandler_HandleGraphQuery_OtherErrorsStay500(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{
+		err: errors.New("connection reset"),
+	}, logger)
+
+	asn := 15169
+	body, err := json.Marshal(models.GraphQueryRequest{QueryType: models.QueryByASN, ASN: &asn})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleGraphQuery(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestGraphQueryHandler_HandleGraphQuery_MaxBodyBytes(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	const limit = 256
+
+	asn := 15169
+	body, err := json.Marshal(models.GraphQueryRequest{QueryType: models.QueryByASN, ASN: &asn})
+	require.NoError(t, err)
+	padTo := func(size int) []byte {
+		require.LessOrEqual(t, len(body), size)
+		return append(append([]byte{}, body...), bytes.Repeat([]byte(" "), size-len(body))...)
+	}
+
+	tests := []struct {
+		name       string
+		size       int
+		wantStatus int
+	}{
+		{name: "just under", size: limit - 1, wantStatus: http.StatusInternalServerError},
+		{name: "just over", size: limit + 1, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Bodies within the limit reach the executor, which fails
+			handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{err: errors.New("boom")}, logger).WithMaxBodyBytes(limit)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/query/graph", bytes.NewReader(padTo(tt.size)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.HandleGraphQuery(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestGraphQueryHandler_DefaultMaxBodyBytes(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, zaptest.NewLogger(t))
+	assert.Equal(t, DefaultQueryMaxBodyBytes, handler.maxBodyBytes)
+
+	// A non-positive limit keeps the default
+	handler.WithMaxBodyBytes(0)
+	assert.Equal(t, DefaultQueryMaxBodyBytes, handler.maxBodyBytes)
 }
 
 func TestGraphQueryHandler_HandleGraphQuery_DefaultsAndLimits(t *testing.T) {
@@ -450,8 +759,7 @@ func TestGraphQueryHandler_HandleGraphQuery_DefaultsAndLimits(t *testing.T) {
 	defer cleanupTestGraphDB(t, db)
 
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandler(db, logger, 0)
 
 	tests := []struct {
 		name       string
@@ -510,8 +818,7 @@ func TestGraphQueryHandler_HandleGraphQuery_QueryTimeReported(t *testing.T) {
 	defer cleanupTestGraphDB(t, db)
 
 	logger := zaptest.NewLogger(t)
-	handler, err := NewGraphQueryHandler(logger)
-	require.NoError(t, err)
+	handler := NewGraphQueryHandler(db, logger, 0)
 
 	asn := 15169
 	reqBody := models.GraphQueryRequest{
@@ -542,3 +849,375 @@ func TestGraphQueryHandler_HandleGraphQuery_QueryTimeReported(t *testing.T) {
 	assert.Greater(t, resp.QueryTime, 0.0)
 	assert.Less(t, resp.QueryTime, float64(elapsed)+100) // Within 100ms of wall clock time
 }
+
+func TestGraphQueryHandler_HandleAggregateByASN(t *testing.T) {
+	db := setupTestGraphDB(t)
+	defer cleanupTestGraphDB(t, db)
+
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandler(db, logger, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/query/aggregate/asn?limit=5", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandleAggregateByASN(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.ASNAggregateResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, resp.Limit)
+	assert.LessOrEqual(t, len(resp.Results), 5)
+	for i := 1; i < len(resp.Results); i++ {
+		assert.GreaterOrEqual(t, resp.Results[i-1].HostCount, resp.Results[i].HostCount)
+	}
+}
+
+func TestGraphQueryHandler_HandleAggregateByASN_InvalidLimit(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, logger)
+
+	for _, limit := range []string{"abc", "0", "1001"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/aggregate/asn?limit="+limit, nil)
+		w := httptest.NewRecorder()
+
+		handler.HandleAggregateByASN(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "limit=%s", limit)
+	}
+}
+
+func TestGraphQueryHandler_HandleCPELookup(t *testing.T) {
+	const cpe = "cpe:2.3:a:openbsd:openssh:9.0p1:*:*:*:*:*:*:*"
+	executor := &stubGraphExecutor{services: []models.CPEServiceMatch{
+		{
+			ServiceID: "ssh_9_0p1",
+			Name:      "ssh",
+			CPEs:      []string{cpe},
+			Hosts:     []string{"203.0.113.5"},
+			Vulns:     []models.CPEVulnLink{{CVEID: "CVE-2023-38408", CVSS: 9.8, Severity: "CRITICAL", Confidence: 0.75}},
+		},
+		{
+			ServiceID: "nginx_1_24_0",
+			Name:      "http",
+			CPEs:      []string{"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"},
+		},
+	}}
+	handler := NewGraphQueryHandlerWithExecutor(executor, zaptest.NewLogger(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/query/cpe?cpe="+url.QueryEscape(cpe), nil)
+	w := httptest.NewRecorder()
+	handler.HandleCPELookup(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.CPELookupResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, cpe, resp.CPE)
+	assert.Equal(t, models.DefaultLimit, resp.Limit)
+	require.Len(t, resp.Services, 1)
+	assert.Equal(t, "ssh_9_0p1", resp.Services[0].ServiceID)
+	assert.Equal(t, []string{"203.0.113.5"}, resp.Services[0].Hosts)
+	require.Len(t, resp.Services[0].Vulns, 1)
+	assert.Equal(t, "CVE-2023-38408", resp.Services[0].Vulns[0].CVEID)
+}
+
+func TestGraphQueryHandler_HandleCPELookup_InvalidParams(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, zaptest.NewLogger(t))
+
+	for _, query := range []string{
+		"",
+		"?cpe=nginx",
+		"?cpe=cpe:2.3:a:nginx:nginx:1.24.0&limit=0",
+		"?cpe=cpe:2.3:a:nginx:nginx:1.24.0&limit=abc",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/cpe"+query, nil)
+		w := httptest.NewRecorder()
+		handler.HandleCPELookup(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "query %q", query)
+	}
+}
+
+func TestGraphQueryHandler_HandleCPELookup_Timeout(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{block: true}, zaptest.NewLogger(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/query/cpe?cpe=cpe:2.3:a:nginx:nginx:1.24.0", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.HandleCPELookup(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestGraphQueryHandler_HandleCPEPrefix(t *testing.T) {
+	executor := &stubGraphExecutor{services: []models.CPEServiceMatch{
+		{ServiceID: "httpd", CPEs: []string{"cpe:2.3:a:apache:http_server:2.4.57:*:*:*:*:*:*:*"}, Hosts: []string{"203.0.113.10"}},
+		{ServiceID: "tomcat", CPEs: []string{"cpe:2.3:a:apache:tomcat:9.0.80:*:*:*:*:*:*:*"}, Hosts: []string{"203.0.113.11"}},
+		{ServiceID: "nginx", CPEs: []string{"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"}, Hosts: []string{"203.0.113.13"}},
+	}}
+	handler := NewGraphQueryHandlerWithExecutor(executor, zaptest.NewLogger(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/query/cpe/prefix?prefix="+url.QueryEscape("cpe:2.3:a:apache:*"), nil)
+	w := httptest.NewRecorder()
+	handler.HandleCPEPrefix(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.CPEPrefixResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "cpe:2.3:a:apache:", resp.Prefix)
+	assert.Equal(t, models.DefaultLimit, resp.Limit)
+	require.Len(t, resp.Services, 2)
+	assert.Equal(t, "httpd", resp.Services[0].ServiceID)
+	assert.Equal(t, "tomcat", resp.Services[1].ServiceID)
+	assert.Equal(t, []string{"203.0.113.10", "203.0.113.11"}, resp.Hosts)
+}
+
+func TestGraphQueryHandler_HandleCPEPrefix_InvalidParams(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, zaptest.NewLogger(t))
+
+	for query, field := range map[string]string{
+		"":                                   "prefix",
+		"?prefix=apache":                     "prefix",
+		"?prefix=cpe:2.3:*":                  "prefix",
+		"?prefix=cpe:2.3:*:apache":           "prefix",
+		"?prefix=cpe:2.3:a:apache&limit=0":   "",
+		"?prefix=cpe:2.3:a:apache&limit=abc": "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/cpe/prefix"+query, nil)
+		w := httptest.NewRecorder()
+		handler.HandleCPEPrefix(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code, "query %q", query)
+		if field != "" {
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+			require.Len(t, errResp.Errors, 1, "query %q", query)
+			assert.Equal(t, field, errResp.Errors[0].Field)
+		}
+	}
+}
+
+func TestGraphQueryHandler_HandleCPEPrefix_Timeout(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{block: true}, zaptest.NewLogger(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/query/cpe/prefix?prefix=cpe:2.3:a:apache:*", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.HandleCPEPrefix(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestGraphQueryHandler_HandleExposure(t *testing.T) {
+	executor := &stubGraphExecutor{exposure: []models.ExposureGroup{
+		{Port: 22, Service: "ssh", HostCount: 2, Hosts: []string{"198.51.100.4", "203.0.113.5"}},
+		{Port: 6379, Service: "redis", HostCount: 1, Hosts: []string{"203.0.113.9"}},
+	}}
+	handler := NewGraphQueryHandlerWithExecutor(executor, zaptest.NewLogger(t))
+
+	t.Run("default risky ports", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/exposure", nil)
+		w := httptest.NewRecorder()
+		handler.HandleExposure(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, executor.exposurePorts, "the executor falls back to the default risky ports")
+
+		var resp models.ExposureResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, models.DefaultLimit, resp.Limit)
+	})
+
+	t.Run("ports named by the request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/exposure?ports=6379,3389,6379&limit=10", nil)
+		w := httptest.NewRecorder()
+		handler.HandleExposure(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int{3389, 6379}, executor.exposurePorts)
+
+		var resp models.ExposureResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, 10, resp.Limit)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "redis", resp.Results[0].Service)
+		assert.Equal(t, []string{"203.0.113.9"}, resp.Results[0].Hosts)
+	})
+
+	t.Run("configured risky ports", func(t *testing.T) {
+		configured := NewGraphQueryHandlerWithExecutor(executor, zaptest.NewLogger(t)).WithRiskyPorts([]int{22})
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/exposure", nil)
+		w := httptest.NewRecorder()
+		configured.HandleExposure(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int{22}, executor.exposurePorts)
+	})
+}
+
+func TestGraphQueryHandler_HandleExposure_InvalidParams(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, zaptest.NewLogger(t))
+
+	for _, query := range []string{
+		"?ports=ssh",
+		"?ports=0",
+		"?ports=70000",
+		"?ports=,",
+		"?limit=0",
+		"?limit=abc",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/query/exposure"+query, nil)
+		w := httptest.NewRecorder()
+		handler.HandleExposure(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "query %q", query)
+	}
+}
+
+func TestGraphQueryHandler_HandleUnidentifiedServices(t *testing.T) {
+	executor := &stubGraphExecutor{banners: []models.UnidentifiedBanner{
+		{Banner: "SSH-2.0-dropbear_2022.83", ServiceCount: 12},
+		{Banner: "220 acme FTP ready", ServiceCount: 4},
+		{Banner: "", ServiceCount: 2},
+	}}
+	handler := NewGraphQueryHandlerWithExecutor(executor, zaptest.NewLogger(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/unidentified-services?limit=2", nil)
+	w := httptest.NewRecorder()
+	handler.HandleUnidentifiedServices(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.UnidentifiedServicesResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 2, resp.Limit)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, models.UnidentifiedBanner{Banner: "SSH-2.0-dropbear_2022.83", ServiceCount: 12}, resp.Results[0])
+}
+
+func TestGraphQueryHandler_HandleUnidentifiedServices_Errors(t *testing.T) {
+	handler := NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{}, zaptest.NewLogger(t))
+	for _, limit := range []string{"0", "1001", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/unidentified-services?limit="+limit, nil)
+		w := httptest.NewRecorder()
+		handler.HandleUnidentifiedServices(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "limit=%s", limit)
+	}
+
+	handler = NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{err: errors.New("connection reset")}, zaptest.NewLogger(t))
+	w := httptest.NewRecorder()
+	handler.HandleUnidentifiedServices(w, httptest.NewRequest(http.MethodGet, "/v1/admin/unidentified-services", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	handler = NewGraphQueryHandlerWithExecutor(&stubGraphExecutor{block: true}, zaptest.NewLogger(t))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	w = httptest.NewRecorder()
+	handler.HandleUnidentifiedServices(w, httptest.NewRequest(http.MethodGet, "/v1/admin/unidentified-services", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestGraphQueryHandler_HandleDiff(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	stub := &stubGraphExecutor{diff: models.DiffResponse{
+		Summary: models.DiffSummary{HostsAdded: 1, PortsAdded: 1},
+		Added: []models.HostDiff{{
+			IP:         "192.0.2.7",
+			AddedPorts: []models.DiffPort{{Number: 443, Protocol: "tcp"}},
+		}},
+		Removed: []models.HostDiff{},
+		Changed: []models.HostDiff{},
+	}}
+	handler := NewGraphQueryHandlerWithExecutor(stub, logger)
+
+	body := `{"cidr": "192.0.2.0/24", "from": "2026-10-15T00:00:00Z", "to": "2026-10-16T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/diff", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.HandleDiff(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "192.0.2.0/24", stub.diffRequest.CIDR)
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), stub.diffRequest.To)
+	assert.Equal(t, models.DefaultLimit, stub.diffRequest.Limit)
+
+	var resp models.DiffResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 1, resp.Summary.HostsAdded)
+	require.Len(t, resp.Added, 1)
+	assert.Equal(t, "192.0.2.7", resp.Added[0].IP)
+}
+
+func TestGraphQueryHandler_HandleDiff_Errors(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		name       string
+		stub       *stubGraphExecutor
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{
+			name:       "malformed body",
+			stub:       &stubGraphExecutor{},
+			body:       `{"cidr": `,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no scope",
+			stub:       &stubGraphExecutor{},
+			body:       `{"from": "2026-10-15T00:00:00Z", "to": "2026-10-16T00:00:00Z"}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "scope",
+		},
+		{
+			name:       "to before from",
+			stub:       &stubGraphExecutor{},
+			body:       `{"asn": 64500, "from": "2026-10-16T00:00:00Z", "to": "2026-10-15T00:00:00Z"}`,
+			wantStatus: http.StatusBadRequest,
+			wantField:  "to",
+		},
+		{
+			name:       "deadline",
+			stub:       &stubGraphExecutor{err: fmt.Errorf("failed to query diff: %w", context.DeadlineExceeded)},
+			body:       `{"asn": 64500, "from": "2026-10-15T00:00:00Z", "to": "2026-10-16T00:00:00Z"}`,
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "query failure",
+			stub:       &stubGraphExecutor{err: errors.New("connection reset")},
+			body:       `{"asn": 64500, "from": "2026-10-15T00:00:00Z", "to": "2026-10-16T00:00:00Z"}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewGraphQueryHandlerWithExecutor(tt.stub, logger)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/query/diff", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandleDiff(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantField != "" {
+				var errResp ErrorResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+				require.Len(t, errResp.Errors, 1)
+				assert.Equal(t, tt.wantField, errResp.Errors[0].Field)
+			}
+		})
+	}
+}
@@ -17,8 +17,8 @@ type HealthResponse struct {
 	Services  map[string]string `json:"services"`
 }
 
-// HealthHandler creates a health check handler with database connectivity check
-func HealthHandler(logger *zap.Logger) http.HandlerFunc {
+// HealthHandler creates a health check handler that checks the shared database connection
+func HealthHandler(dbClient *surrealdb.DB, logger *zap.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 		defer cancel()
@@ -27,7 +27,7 @@ func HealthHandler(logger *zap.Logger) http.HandlerFunc {
 		services["api"] = "ok"
 
 		// Check SurrealDB connectivity
-		dbStatus := checkDatabaseConnection(ctx, logger)
+		dbStatus := checkDatabaseConnection(ctx, dbClient, logger)
 		services["database"] = dbStatus
 
 		// Determine overall health status
@@ -56,40 +56,16 @@ func HealthHandler(logger *zap.Logger) http.HandlerFunc {
 	}
 }
 
-// checkDatabaseConnection attempts to connect to SurrealDB and returns status
-func checkDatabaseConnection(ctx context.Context, logger *zap.Logger) string {
-	// Create database connection
-	db, err := surrealdb.New("ws://localhost:8000/rpc")
-	if err != nil {
+// checkDatabaseConnection asks SurrealDB for its version over the shared connection and returns status
+func checkDatabaseConnection(ctx context.Context, dbClient *surrealdb.DB, logger *zap.Logger) string {
+	if dbClient == nil {
 		logger.Debug("database connection failed",
-			zap.Error(err),
-			zap.String("reason", "connection_error"))
-		return "unavailable"
-	}
-	defer db.Close(ctx)
-
-	// Sign in with credentials
-	if _, err := db.SignIn(ctx, map[string]interface{}{
-		"user": "root",
-		"pass": "root",
-	}); err != nil {
-		logger.Debug("database authentication failed",
-			zap.Error(err),
-			zap.String("reason", "auth_error"))
-		return "unavailable"
-	}
-
-	// Use namespace and database
-	if err := db.Use(ctx, "spectra", "intel"); err != nil {
-		logger.Debug("database use failed",
-			zap.Error(err),
-			zap.String("reason", "use_error"))
+			zap.String("reason", "no_connection"))
 		return "unavailable"
 	}
 
 	// Verify connection with version check
-	_, err = db.Version(ctx)
-	if err != nil {
+	if _, err := dbClient.Version(ctx); err != nil {
 		logger.Debug("database version check failed",
 			zap.Error(err),
 			zap.String("reason", "version_error"))
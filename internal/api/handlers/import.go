@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// GraphImporter loads a graph export
+type GraphImporter interface {
+	Import(ctx context.Context, r io.Reader) (models.ImportResponse, error)
+}
+
+// ImportHandler handles POST /v1/admin/import
+// The body is a graph export as written by GET /v1/admin/export, read as it
+// arrives and upserted in batches. Malformed lines are skipped and reported in
+// the response; a body with nothing importable is rejected. Importing the same
+// export again is harmless, so a failed import can simply be retried.
+// audit, if non-nil, records each import with its counts.
+func ImportHandler(importer GraphImporter, audit AuditRecorder, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		// An import of a large export outlives the server's read and write timeouts
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		resp, err := importer.Import(r.Context(), r.Body)
+
+		// The request context may be gone if the client disconnected
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+		defer cancel()
+		recordAudit(ctx, audit, r, models.AuditEntry{
+			Action: models.AuditActionImport,
+			Actor:  models.AuditActorAdmin,
+			Details: map[string]interface{}{
+				"nodes":    resp.Nodes,
+				"edges":    resp.Edges,
+				"skipped":  resp.Skipped,
+				"complete": err == nil,
+			},
+		}, logger)
+
+		if err != nil {
+			// Counts cover the batches written before the failure
+			logger.Error("graph import failed",
+				zap.Error(err),
+				zap.Int("nodes", resp.Nodes),
+				zap.Int("edges", resp.Edges),
+				zap.Int("skipped", resp.Skipped))
+			jobErrorResponse(w, "internal_error", "Import failed; it is safe to retry", http.StatusInternalServerError)
+			return
+		}
+
+		if resp.Nodes+resp.Edges == 0 {
+			msg := "Import contains no records"
+			if len(resp.Errors) > 0 {
+				msg = fmt.Sprintf("Import contains no valid records (%d skipped; %s)", resp.Skipped, resp.Errors[0])
+			}
+			jobErrorResponse(w, "missing_data", msg, http.StatusBadRequest)
+			return
+		}
+
+		logger.Info("graph import finished",
+			zap.Int("nodes", resp.Nodes),
+			zap.Int("edges", resp.Edges),
+			zap.Int("skipped", resp.Skipped))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Error("failed to encode import response",
+				zap.Error(err))
+		}
+	}
+}
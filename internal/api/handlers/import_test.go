@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeImporter returns a fixed result and records the body it was given
+type fakeImporter struct {
+	resp    models.ImportResponse
+	err     error
+	gotBody string
+}
+
+func (i *fakeImporter) Import(ctx context.Context, r io.Reader) (models.ImportResponse, error) {
+	body, _ := io.ReadAll(r)
+	i.gotBody = string(body)
+	return i.resp, i.err
+}
+
+func TestImportHandler(t *testing.T) {
+	const dump = `{"table":"host","kind":"node","id":"host:a","data":{"ip":"192.0.2.1"}}` + "\n"
+
+	tests := []struct {
+		name         string
+		resp         models.ImportResponse
+		err          error
+		wantStatus   int
+		wantMessage  string
+		wantComplete bool
+	}{
+		{name: "imported", resp: models.ImportResponse{Nodes: 1, Skipped: 1, Errors: []string{"line 2: invalid JSON"}}, wantStatus: http.StatusOK, wantComplete: true},
+		{name: "nothing valid", resp: models.ImportResponse{Skipped: 2, Errors: []string{"line 1: invalid JSON"}}, wantStatus: http.StatusBadRequest,
+			wantMessage: "2 skipped; line 1: invalid JSON", wantComplete: true},
+		{name: "empty", wantStatus: http.StatusBadRequest, wantMessage: "no records", wantComplete: true},
+		{name: "write failed", resp: models.ImportResponse{Nodes: 500}, err: errors.New("connection reset"), wantStatus: http.StatusInternalServerError,
+			wantMessage: "safe to retry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importer := &fakeImporter{resp: tt.resp, err: tt.err}
+			audit := &fakeAudit{}
+			handler := ImportHandler(importer, audit, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/import", strings.NewReader(dump))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Equal(t, dump, importer.gotBody)
+			if tt.wantMessage != "" {
+				assert.Contains(t, w.Body.String(), tt.wantMessage)
+			}
+
+			require.Len(t, audit.entries, 1)
+			entry := audit.entries[0]
+			assert.Equal(t, models.AuditActionImport, entry.Action)
+			assert.Equal(t, models.AuditActorAdmin, entry.Actor)
+			assert.Equal(t, tt.resp.Nodes, entry.Details["nodes"])
+			assert.Equal(t, tt.wantComplete, entry.Details["complete"])
+
+			if tt.wantStatus == http.StatusOK {
+				var resp models.ImportResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, tt.resp, resp)
+			}
+		})
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/spectra-red/recon/internal/api/middleware"
 	"github.com/spectra-red/recon/internal/auth"
 	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/webhook"
 	"github.com/surrealdb/surrealdb.go"
 	"go.uber.org/zap"
 )
@@ -20,6 +23,20 @@ import (
 // IngestRequest represents the incoming scan submission request
 type IngestRequest struct {
 	auth.ScanEnvelope
+
+	// CallbackURL, if set, is POSTed a signed completion payload when the job finishes.
+	// It must be an https URL on a host in the server's callback allowlist.
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// Source names the tool that produced the scan, e.g. naabu, nmap or masscan, and
+	// is recorded on every port it reports; absent, it is recorded as unknown.
+	// Like CallbackURL it is not covered by the signature.
+	Source string `json:"source,omitempty"`
+
+	// SchemaVersion declares the format of Data, naabu/v1 or structured/v1; data
+	// that doesn't match is rejected before a job is created. Absent, the data is
+	// not validated up front. It is not covered by the signature.
+	SchemaVersion string `json:"schema_version,omitempty"`
 }
 
 // IngestResponse represents the response returned after accepting a scan
@@ -30,16 +47,52 @@ type IngestResponse struct {
 	Timestamp string `json:"timestamp"`
 }
 
+// CodedErrorResponse is the error body returned by the ingest, job and admin endpoints
+type CodedErrorResponse struct {
+	Error     string `json:"error"`     // Machine-readable error code, e.g. invalid_parameter
+	Message   string `json:"message"`   // Human-readable description
+	Timestamp string `json:"timestamp"` // RFC 3339
+
+	// Line and Path locate the offending record and field when scan data fails
+	// validation against its declared schema
+	Line int    `json:"line,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
 // IngestHandler creates an HTTP handler for the /v1/mesh/ingest endpoint
-// It validates Ed25519 signatures, creates a job record, and triggers the Restate workflow
-func IngestHandler(logger *zap.Logger, dbClient *surrealdb.DB, restateURL string) http.HandlerFunc {
+// It validates envelope signatures, creates a job record, and triggers the Restate workflow.
+// maxSkew bounds how far envelope timestamps may drift from server time; a non-positive
+// value uses auth.TimestampWindow. inFlight, if non-nil, bounds the number of workflows
+// handed off to Restate that have not yet completed; beyond it the handler returns 503.
+// maxBodyBytes caps the request body (413 beyond it); a non-positive value uses
+// DefaultIngestMaxBodyBytes. callbacks lists the hosts a submission's callback_url may
+// target; a nil or empty allowlist rejects any submission that sets one. audit, if
+// non-nil, records each accepted scan under the hash of its scanner key.
+func IngestHandler(logger *zap.Logger, dbClient *surrealdb.DB, restateURL string, maxSkew time.Duration, inFlight *middleware.InFlightLimiter, maxBodyBytes int64, callbacks *webhook.Allowlist, audit AuditRecorder) http.HandlerFunc {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultIngestMaxBodyBytes
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
+		// Refuse new scans while the server drains for shutdown
+		if inFlight != nil && inFlight.Draining() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(inFlight.RetryAfter().Seconds())))
+			ingestErrorResponse(w, "shutting_down", "Server is shutting down, retry later", http.StatusServiceUnavailable)
+			return
+		}
+
 		// Parse request body
-		body, err := io.ReadAll(io.LimitReader(r.Body, 10*1024*1024)) // 10MB max
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
 		if err != nil {
+			if isBodyTooLarge(err) {
+				logger.Warn("request body too large",
+					zap.Int64("max_body_bytes", maxBodyBytes))
+				ingestErrorResponse(w, "request_too_large", fmt.Sprintf("Request body exceeds %d bytes", maxBodyBytes), http.StatusRequestEntityTooLarge)
+				return
+			}
 			logger.Warn("failed to read request body",
 				zap.Error(err))
 			ingestErrorResponse(w, "invalid_request", "Failed to read request body", http.StatusBadRequest)
@@ -55,18 +108,62 @@ func IngestHandler(logger *zap.Logger, dbClient *surrealdb.DB, restateURL string
 			return
 		}
 
-		// Validate Ed25519 signature
-		if err := auth.VerifyEnvelope(req.ScanEnvelope); err != nil {
+		// Validate envelope signature
+		if err := auth.VerifyEnvelopeWithConfig(req.ScanEnvelope, maxSkew); err != nil {
 			logger.Warn("signature verification failed",
 				zap.Error(err),
+				zap.String("algorithm", req.Algorithm),
 				zap.String("public_key", maskPublicKey(req.PublicKey)))
 			ingestErrorResponse(w, "invalid_signature", "Signature verification failed", http.StatusUnauthorized)
 			return
 		}
 
+		source, err := models.NormalizeScanSource(req.Source)
+		if err != nil {
+			logger.Warn("scan source rejected",
+				zap.Error(err),
+				zap.String("public_key", maskPublicKey(req.PublicKey)))
+			ingestErrorResponse(w, "invalid_source", err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := ValidateScanSchema(req.SchemaVersion, req.Data); err != nil {
+			logger.Warn("scan data rejected by schema validation",
+				zap.Error(err),
+				zap.String("schema_version", req.SchemaVersion),
+				zap.String("public_key", maskPublicKey(req.PublicKey)))
+			scanSchemaErrorResponse(w, err)
+			return
+		}
+
+		// Reject callback URLs outside the allowlist before doing any work
+		if req.CallbackURL != "" {
+			if err := callbacks.Validate(req.CallbackURL); err != nil {
+				logger.Warn("callback URL rejected",
+					zap.Error(err),
+					zap.String("public_key", maskPublicKey(req.PublicKey)))
+				ingestErrorResponse(w, "invalid_callback_url", err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Apply backpressure when the workflow backend is saturated
+		if inFlight != nil && !inFlight.TryAcquire() {
+			logger.Warn("ingest backpressure: too many in-flight jobs",
+				zap.Int("in_flight", inFlight.Depth()),
+				zap.Int("max_in_flight", inFlight.Max()),
+				zap.String("public_key", maskPublicKey(req.PublicKey)))
+			w.Header().Set("Retry-After", strconv.Itoa(int(inFlight.RetryAfter().Seconds())))
+			ingestErrorResponse(w, "service_unavailable", "Ingest queue is full, retry later", http.StatusServiceUnavailable)
+			return
+		}
+
 		// Create job record in database
 		job, err := db.CreateJob(ctx, dbClient, logger, req.PublicKey)
 		if err != nil {
+			if inFlight != nil {
+				inFlight.Release()
+			}
 			logger.Error("failed to create job",
 				zap.Error(err),
 				zap.String("public_key", maskPublicKey(req.PublicKey)))
@@ -78,17 +175,30 @@ func IngestHandler(logger *zap.Logger, dbClient *surrealdb.DB, restateURL string
 			zap.String("job_id", job.ID),
 			zap.String("public_key", maskPublicKey(req.PublicKey)),
 			zap.Int64("timestamp", req.Timestamp),
+			zap.String("source", source),
 			zap.Int("data_size", len(req.Data)))
 
+		recordAudit(ctx, audit, r, models.AuditEntry{
+			Action:    models.AuditActionIngest,
+			Actor:     models.ScannerActor(req.PublicKey),
+			JobID:     job.ID,
+			HostCount: countScanHosts(req.Data),
+		}, logger)
+
 		// Trigger Restate workflow asynchronously
 		workflowReq := models.IngestWorkflowRequest{
-			JobID:      job.ID,
-			ScannerKey: req.PublicKey,
-			ScanData:   req.Data,
+			JobID:       job.ID,
+			ScannerKey:  req.PublicKey,
+			ScanData:    req.Data,
+			CallbackURL: req.CallbackURL,
+			Source:      source,
 		}
 
 		// Send to Restate (fire-and-forget)
 		go func() {
+			if inFlight != nil {
+				defer inFlight.Release()
+			}
 			if err := triggerRestateWorkflow(context.Background(), restateURL, job.ID, workflowReq, logger); err != nil {
 				logger.Error("failed to trigger workflow",
 					zap.Error(err),
@@ -168,15 +278,15 @@ func generateJobID() string {
 
 // ingestErrorResponse writes a consistent error response for ingest endpoint
 func ingestErrorResponse(w http.ResponseWriter, errorCode, message string, statusCode int) {
-	response := struct {
-		Error     string `json:"error"`
-		Message   string `json:"message"`
-		Timestamp string `json:"timestamp"`
-	}{
-		Error:     errorCode,
-		Message:   message,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}
+	writeCodedError(w, CodedErrorResponse{
+		Error:   errorCode,
+		Message: message,
+	}, statusCode)
+}
+
+// writeCodedError writes a coded error response, stamping it with the current time
+func writeCodedError(w http.ResponseWriter, response CodedErrorResponse, statusCode int) {
+	response.Timestamp = time.Now().UTC().Format(time.RFC3339)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
@@ -16,16 +17,46 @@ import (
 	"github.com/spectra-red/recon/internal/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
 
+// testIngestHandler returns an ingest handler that records jobs in a local
+// SurrealDB and hands workflows to a stub Restate, skipping the test when there
+// is no database. The workflow hand-off outlives the request, so the handler
+// logs nowhere rather than to a test that may have finished.
+func testIngestHandler(tb testing.TB) http.HandlerFunc {
+	tb.Helper()
+	ctx := context.Background()
+
+	db, err := surrealdb.New("ws://localhost:8000/rpc")
+	if err != nil {
+		tb.Skipf("SurrealDB not available at ws://localhost:8000/rpc: %v", err)
+	}
+	_, err = db.SignIn(ctx, map[string]interface{}{
+		"user": "root",
+		"pass": "root",
+	})
+	require.NoError(tb, err, "failed to sign in")
+	require.NoError(tb, db.Use(ctx, "test", "ingest_handler_test"), "failed to use test database")
+	tb.Cleanup(func() { db.Close(ctx) })
+
+	restate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	tb.Cleanup(restate.Close)
+
+	return IngestHandler(zap.NewNop(), db, restate.URL, 0, nil, 0, nil, nil)
+}
+
 // TestIngestEndpoint_FullIntegration tests the complete ingest flow with all middleware
 func TestIngestEndpoint_FullIntegration(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 
 	// Setup middleware chain: rate limiter -> handler
 	rateLimiter := middleware.NewRateLimiter(60, logger)
-	handler := middleware.RateLimitMiddleware(rateLimiter)(IngestHandler(logger))
+	handler := middleware.RateLimitMiddleware(rateLimiter)(testIngestHandler(t))
 
 	// Generate test keypair
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
@@ -61,10 +92,11 @@ func TestIngestEndpoint_FullIntegration(t *testing.T) {
 	}`)
 
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -109,7 +141,7 @@ func TestIngestEndpoint_RateLimitEnforcement(t *testing.T) {
 
 	// Setup with LOW rate limit for testing (5 requests per minute)
 	rateLimiter := middleware.NewRateLimiter(5, logger)
-	handler := middleware.RateLimitMiddleware(rateLimiter)(IngestHandler(logger))
+	handler := middleware.RateLimitMiddleware(rateLimiter)(testIngestHandler(t))
 
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(t, err)
@@ -117,10 +149,11 @@ func TestIngestEndpoint_RateLimitEnforcement(t *testing.T) {
 	createRequest := func() *http.Request {
 		scanData := json.RawMessage(`{"test": "data"}`)
 		timestamp := time.Now().Unix()
-		message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+		message := auth.SigningMessage(timestamp, scanData)
 		signature := ed25519.Sign(privKey, message)
 
 		envelope := auth.ScanEnvelope{
+			Version:   auth.EnvelopeVersion,
 			Data:      scanData,
 			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 			Signature: base64.StdEncoding.EncodeToString(signature),
@@ -158,15 +191,16 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 
 	t.Run("AC1: POST /v1/mesh/ingest endpoint accepts scan results", func(t *testing.T) {
-		handler := IngestHandler(logger)
+		handler := testIngestHandler(t)
 		pubKey, privKey, _ := ed25519.GenerateKey(nil)
 
 		scanData := json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`)
 		timestamp := time.Now().Unix()
-		message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+		message := auth.SigningMessage(timestamp, scanData)
 		signature := ed25519.Sign(privKey, message)
 
 		envelope := auth.ScanEnvelope{
+			Version:   auth.EnvelopeVersion,
 			Data:      scanData,
 			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 			Signature: base64.StdEncoding.EncodeToString(signature),
@@ -183,11 +217,12 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 	})
 
 	t.Run("AC2: Validates Ed25519 signature from header", func(t *testing.T) {
-		handler := IngestHandler(logger)
-		pubKey, _ := ed25519.GenerateKey(nil)
+		handler := IngestHandler(zaptest.NewLogger(t), nil, "", 0, nil, 0, nil, nil)
+		pubKey, _, _ := ed25519.GenerateKey(nil)
 
 		// Create envelope with INVALID signature
 		envelope := auth.ScanEnvelope{
+			Version:   auth.EnvelopeVersion,
 			Data:      json.RawMessage(`{"hosts":[]}`),
 			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 			Signature: base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize)),
@@ -204,15 +239,16 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 	})
 
 	t.Run("AC3: Returns 202 Accepted with job ID", func(t *testing.T) {
-		handler := IngestHandler(logger)
+		handler := testIngestHandler(t)
 		pubKey, privKey, _ := ed25519.GenerateKey(nil)
 
 		scanData := json.RawMessage(`{"test":"data"}`)
 		timestamp := time.Now().Unix()
-		message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+		message := auth.SigningMessage(timestamp, scanData)
 		signature := ed25519.Sign(privKey, message)
 
 		envelope := auth.ScanEnvelope{
+			Version:   auth.EnvelopeVersion,
 			Data:      scanData,
 			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 			Signature: base64.StdEncoding.EncodeToString(signature),
@@ -237,7 +273,7 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 
 	t.Run("AC4: Implements rate limiting (60 req/min per scanner)", func(t *testing.T) {
 		rateLimiter := middleware.NewRateLimiter(60, logger)
-		handler := middleware.RateLimitMiddleware(rateLimiter)(IngestHandler(logger))
+		handler := middleware.RateLimitMiddleware(rateLimiter)(testIngestHandler(t))
 
 		pubKey, privKey, _ := ed25519.GenerateKey(nil)
 
@@ -245,10 +281,11 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 		for i := 0; i < 60; i++ {
 			scanData := json.RawMessage(fmt.Sprintf(`{"req":%d}`, i))
 			timestamp := time.Now().Unix()
-			message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+			message := auth.SigningMessage(timestamp, scanData)
 			signature := ed25519.Sign(privKey, message)
 
 			envelope := auth.ScanEnvelope{
+				Version:   auth.EnvelopeVersion,
 				Data:      scanData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -267,10 +304,11 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 		// 61st request should be rate limited
 		scanData := json.RawMessage(`{"req":61}`)
 		timestamp := time.Now().Unix()
-		message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+		message := auth.SigningMessage(timestamp, scanData)
 		signature := ed25519.Sign(privKey, message)
 
 		envelope := auth.ScanEnvelope{
+			Version:   auth.EnvelopeVersion,
 			Data:      scanData,
 			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 			Signature: base64.StdEncoding.EncodeToString(signature),
@@ -289,15 +327,16 @@ func TestIngestEndpoint_AcceptanceCriteria(t *testing.T) {
 	t.Run("AC5: Logs ingest requests with structured logging", func(t *testing.T) {
 		// This is implicitly tested by using zaptest.NewLogger
 		// The logger captures all log output for inspection
-		handler := IngestHandler(logger)
+		handler := testIngestHandler(t)
 		pubKey, privKey, _ := ed25519.GenerateKey(nil)
 
 		scanData := json.RawMessage(`{"test":"data"}`)
 		timestamp := time.Now().Unix()
-		message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+		message := auth.SigningMessage(timestamp, scanData)
 		signature := ed25519.Sign(privKey, message)
 
 		envelope := auth.ScanEnvelope{
+			Version:   auth.EnvelopeVersion,
 			Data:      scanData,
 			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 			Signature: base64.StdEncoding.EncodeToString(signature),
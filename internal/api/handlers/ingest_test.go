@@ -5,7 +5,6 @@ import (
 	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -13,15 +12,16 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/spectra-red/recon/internal/api/middleware"
 	"github.com/spectra-red/recon/internal/auth"
+	"github.com/spectra-red/recon/internal/webhook"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 )
 
 func TestIngestHandler_Success(t *testing.T) {
-	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := testIngestHandler(t)
 
 	// Generate test keypair
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
@@ -36,10 +36,11 @@ func TestIngestHandler_Success(t *testing.T) {
 	}`)
 
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -73,7 +74,7 @@ func TestIngestHandler_Success(t *testing.T) {
 
 func TestIngestHandler_InvalidJSON(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := IngestHandler(logger, nil, "", 0, nil, 0, nil, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader([]byte("invalid json")))
 	req.Header.Set("Content-Type", "application/json")
@@ -93,7 +94,7 @@ func TestIngestHandler_InvalidJSON(t *testing.T) {
 
 func TestIngestHandler_InvalidSignature(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := IngestHandler(logger, nil, "", 0, nil, 0, nil, nil)
 
 	// Create envelope with invalid signature
 	pubKey, _, err := ed25519.GenerateKey(nil)
@@ -103,6 +104,7 @@ func TestIngestHandler_InvalidSignature(t *testing.T) {
 	invalidSignature := make([]byte, ed25519.SignatureSize)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(invalidSignature),
@@ -128,17 +130,18 @@ func TestIngestHandler_InvalidSignature(t *testing.T) {
 
 func TestIngestHandler_ExpiredTimestamp(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := IngestHandler(logger, nil, "", 0, nil, 0, nil, nil)
 
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(t, err)
 
 	scanData := json.RawMessage(`{"test": "data"}`)
 	oldTimestamp := time.Now().Add(-10 * time.Minute).Unix()
-	message := append([]byte(fmt.Sprintf("%d", oldTimestamp)), scanData...)
+	message := auth.SigningMessage(oldTimestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -164,7 +167,7 @@ func TestIngestHandler_ExpiredTimestamp(t *testing.T) {
 
 func TestIngestHandler_MissingData(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := IngestHandler(logger, nil, "", 0, nil, 0, nil, nil)
 
 	tests := []struct {
 		name     string
@@ -173,6 +176,7 @@ func TestIngestHandler_MissingData(t *testing.T) {
 		{
 			name: "missing data",
 			envelope: auth.ScanEnvelope{
+				Version:   auth.EnvelopeVersion,
 				Data:      nil,
 				PublicKey: "dGVzdA==",
 				Signature: "dGVzdA==",
@@ -182,6 +186,7 @@ func TestIngestHandler_MissingData(t *testing.T) {
 		{
 			name: "missing public key",
 			envelope: auth.ScanEnvelope{
+				Version:   auth.EnvelopeVersion,
 				Data:      json.RawMessage(`{"test": "data"}`),
 				PublicKey: "",
 				Signature: "dGVzdA==",
@@ -191,6 +196,7 @@ func TestIngestHandler_MissingData(t *testing.T) {
 		{
 			name: "missing signature",
 			envelope: auth.ScanEnvelope{
+				Version:   auth.EnvelopeVersion,
 				Data:      json.RawMessage(`{"test": "data"}`),
 				PublicKey: "dGVzdA==",
 				Signature: "",
@@ -200,6 +206,7 @@ func TestIngestHandler_MissingData(t *testing.T) {
 		{
 			name: "missing timestamp",
 			envelope: auth.ScanEnvelope{
+				Version:   auth.EnvelopeVersion,
 				Data:      json.RawMessage(`{"test": "data"}`),
 				PublicKey: "dGVzdA==",
 				Signature: "dGVzdA==",
@@ -225,32 +232,84 @@ func TestIngestHandler_MissingData(t *testing.T) {
 
 func TestIngestHandler_RequestBodyTooLarge(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := IngestHandler(logger, nil, "", 0, nil, 0, nil, nil)
 
-	// Create a 15MB payload (exceeds 10MB limit)
+	// Create a 15MB payload (exceeds the 10MB default limit)
 	largeData := make([]byte, 15*1024*1024)
 	req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(largeData))
 	w := httptest.NewRecorder()
 
 	handler.ServeHTTP(w, req)
 
-	// Should fail to parse JSON since we hit the limit
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var errResp map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&errResp)
+	require.NoError(t, err)
+	assert.Equal(t, "request_too_large", errResp["error"])
 }
 
-func TestIngestHandler_ContentTypeHandling(t *testing.T) {
+func TestIngestHandler_MaxBodyBytes(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	const limit = 4096
+
+	_, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	// A valid envelope padded with trailing whitespace to an exact size
+	envelope := auth.SignEnvelope(privKey, json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`), time.Now().Unix())
+	envelopeJSON, err := json.Marshal(envelope)
+	require.NoError(t, err)
+	padTo := func(size int) []byte {
+		require.LessOrEqual(t, len(envelopeJSON), size)
+		return append(append([]byte{}, envelopeJSON...), bytes.Repeat([]byte(" "), size-len(envelopeJSON))...)
+	}
+
+	tests := []struct {
+		name     string
+		size     int
+		tooLarge bool
+	}{
+		{name: "just under", size: limit - 1},
+		{name: "exactly at limit", size: limit},
+		{name: "just over", size: limit + 1, tooLarge: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A saturated limiter stops accepted bodies before they reach the database
+			inFlight := middleware.NewInFlightLimiter(1, time.Second)
+			require.True(t, inFlight.TryAcquire())
+			handler := IngestHandler(logger, nil, "", 0, inFlight, limit, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(padTo(tt.size)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if tt.tooLarge {
+				assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			} else {
+				assert.Equal(t, http.StatusServiceUnavailable, w.Code, "body within the limit should be parsed and verified")
+			}
+		})
+	}
+}
+
+func TestIngestHandler_ContentTypeHandling(t *testing.T) {
+	handler := testIngestHandler(t)
 
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(t, err)
 
 	scanData := json.RawMessage(`{"test": "data"}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -272,18 +331,18 @@ func TestIngestHandler_ContentTypeHandling(t *testing.T) {
 
 func TestIngestHandler_MultipleRequests(t *testing.T) {
 	// Test that handler can process multiple requests (idempotency check)
-	logger := zaptest.NewLogger(t)
-	handler := IngestHandler(logger)
+	handler := testIngestHandler(t)
 
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(t, err)
 
 	scanData := json.RawMessage(`{"test": "data"}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -335,7 +394,7 @@ func TestGenerateJobID(t *testing.T) {
 	assert.Len(t, ids, 100)
 }
 
-func TestWriteErrorResponse(t *testing.T) {
+func TestIngestErrorResponse(t *testing.T) {
 	tests := []struct {
 		name       string
 		errorCode  string
@@ -365,12 +424,12 @@ func TestWriteErrorResponse(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
-			writeErrorResponse(w, tt.errorCode, tt.message, tt.statusCode)
+			ingestErrorResponse(w, tt.errorCode, tt.message, tt.statusCode)
 
 			assert.Equal(t, tt.statusCode, w.Code)
 			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 
-			var response ErrorResponse
+			var response CodedErrorResponse
 			err := json.NewDecoder(w.Body).Decode(&response)
 			require.NoError(t, err)
 
@@ -423,18 +482,18 @@ func TestMaskPublicKey(t *testing.T) {
 
 // Benchmark tests
 func BenchmarkIngestHandler(b *testing.B) {
-	logger := zaptest.NewLogger(b)
-	handler := IngestHandler(logger)
+	handler := testIngestHandler(b)
 
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(b, err)
 
 	scanData := json.RawMessage(`{"hosts":[{"ip":"1.2.3.4","ports":[{"number":80}]}]}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -453,18 +512,18 @@ func BenchmarkIngestHandler(b *testing.B) {
 }
 
 func BenchmarkIngestHandler_Parallel(b *testing.B) {
-	logger := zaptest.NewLogger(b)
-	handler := IngestHandler(logger)
+	handler := testIngestHandler(b)
 
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(b, err)
 
 	scanData := json.RawMessage(`{"hosts":[{"ip":"1.2.3.4","ports":[{"number":80}]}]}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 	signature := ed25519.Sign(privKey, message)
 
 	envelope := auth.ScanEnvelope{
+		Version:   auth.EnvelopeVersion,
 		Data:      scanData,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -490,3 +549,150 @@ func readBody(t *testing.T, r io.Reader) []byte {
 	require.NoError(t, err)
 	return body
 }
+
+func TestIngestHandler_Backpressure(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	// Saturate the in-flight limiter before the request arrives
+	inFlight := middleware.NewInFlightLimiter(2, 15*time.Second)
+	require.True(t, inFlight.TryAcquire())
+	require.True(t, inFlight.TryAcquire())
+
+	handler := IngestHandler(logger, nil, "", 0, inFlight, 0, nil, nil)
+
+	_, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	envelope := auth.SignEnvelope(privKey, json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`), time.Now().Unix())
+	body, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "15", w.Header().Get("Retry-After"))
+	assert.Equal(t, 2, inFlight.Depth(), "rejected request must not hold a slot")
+
+	var errResp map[string]interface{}
+	err = json.NewDecoder(w.Body).Decode(&errResp)
+	require.NoError(t, err)
+	assert.Equal(t, "service_unavailable", errResp["error"])
+}
+
+func TestIngestHandler_CallbackURL(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	_, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		allowlist   *webhook.Allowlist
+		callbackURL string
+		wantStatus  int
+	}{
+		{name: "callbacks disabled", allowlist: nil, callbackURL: "https://hooks.example.com/", wantStatus: http.StatusBadRequest},
+		{name: "host not allowlisted", allowlist: webhook.ParseAllowlist("hooks.example.com"), callbackURL: "https://169.254.169.254/", wantStatus: http.StatusBadRequest},
+		{name: "http scheme", allowlist: webhook.ParseAllowlist("hooks.example.com"), callbackURL: "http://hooks.example.com/", wantStatus: http.StatusBadRequest},
+		// A saturated limiter stops accepted submissions before they reach the database
+		{name: "allowlisted", allowlist: webhook.ParseAllowlist("hooks.example.com"), callbackURL: "https://hooks.example.com/", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inFlight := middleware.NewInFlightLimiter(1, time.Second)
+			require.True(t, inFlight.TryAcquire())
+			handler := IngestHandler(logger, nil, "", 0, inFlight, 0, tt.allowlist, nil)
+
+			body, err := json.Marshal(IngestRequest{
+				ScanEnvelope: auth.SignEnvelope(privKey, json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`), time.Now().Unix()),
+				CallbackURL:  tt.callbackURL,
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusBadRequest {
+				var errResp map[string]interface{}
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+				assert.Equal(t, "invalid_callback_url", errResp["error"])
+			}
+		})
+	}
+}
+
+func TestIngestHandler_Source(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	_, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		source     string
+		wantStatus int
+	}{
+		{name: "invalid source", source: "not a tool!", wantStatus: http.StatusBadRequest},
+		// A saturated limiter stops accepted submissions before they reach the database
+		{name: "named tool", source: "nmap", wantStatus: http.StatusServiceUnavailable},
+		{name: "absent", source: "", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inFlight := middleware.NewInFlightLimiter(1, time.Second)
+			require.True(t, inFlight.TryAcquire())
+			handler := IngestHandler(logger, nil, "", 0, inFlight, 0, nil, nil)
+
+			body, err := json.Marshal(IngestRequest{
+				ScanEnvelope: auth.SignEnvelope(privKey, json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`), time.Now().Unix()),
+				Source:       tt.source,
+			})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusBadRequest {
+				var errResp map[string]interface{}
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+				assert.Equal(t, "invalid_source", errResp["error"])
+			}
+		})
+	}
+}
+
+func TestStatsHandler_ReportsInFlightDepth(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	inFlight := middleware.NewInFlightLimiter(10, time.Second)
+	require.True(t, inFlight.TryAcquire())
+	require.True(t, inFlight.TryAcquire())
+	require.True(t, inFlight.TryAcquire())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+	w := httptest.NewRecorder()
+
+	StatsHandler(inFlight, nil, logger).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp StatsResponse
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	require.NoError(t, err)
+	assert.Equal(t, 3, resp.Ingest.InFlight)
+	assert.Equal(t, 10, resp.Ingest.MaxInFlight)
+}
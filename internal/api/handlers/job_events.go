@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/db"
+	"github.com/spectra-red/recon/internal/events"
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// jobEventsKeepAlive is how often an idle stream sends a comment so proxies keep it open
+const jobEventsKeepAlive = 15 * time.Second
+
+// JobEventSource replays persisted job events to reconnecting clients
+type JobEventSource interface {
+	Since(ctx context.Context, afterID string, limit int) ([]models.JobEvent, error)
+}
+
+// JobEventsHandler creates an HTTP handler for GET /v1/jobs/events
+// Streams job state transitions as Server-Sent Events. Clients resume after a
+// disconnect by sending the Last-Event-ID header (or ?last_event_id=); events
+// after that ID are replayed from source before live events. ?scanner_key=
+// restricts the stream to one scanner's jobs.
+func JobEventsHandler(broker *events.Broker, source JobEventSource, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			jobErrorResponse(w, "streaming_unsupported", "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		scannerKey := r.URL.Query().Get("scanner_key")
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+
+		// Subscribe before replaying so nothing published in between is missed;
+		// live events already covered by the replay are skipped below
+		sub := broker.Subscribe()
+		defer sub.Close()
+
+		var backlog []models.JobEvent
+		if lastEventID != "" && source != nil {
+			var err error
+			backlog, err = replayJobEvents(r.Context(), source, lastEventID)
+			if err != nil {
+				logger.Error("failed to replay job events",
+					zap.Error(err),
+					zap.String("last_event_id", lastEventID))
+				jobErrorResponse(w, "internal_error", "Failed to replay job events", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// The stream outlives the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		sent := lastEventID
+		send := func(event models.JobEvent) bool {
+			if event.ID <= sent {
+				return true
+			}
+			sent = event.ID
+			if scannerKey != "" && event.ScannerKey != scannerKey {
+				return true
+			}
+			if err := writeJobEvent(w, event); err != nil {
+				logger.Debug("job event stream closed",
+					zap.Error(err))
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+
+		for _, event := range backlog {
+			if !send(event) {
+				return
+			}
+		}
+
+		keepAlive := time.NewTicker(jobEventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-sub.C:
+				if !ok {
+					// Dropped for falling behind; the client reconnects with Last-Event-ID
+					return
+				}
+				if !send(event) {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// replayJobEvents loads every event after lastEventID
+func replayJobEvents(ctx context.Context, source JobEventSource, lastEventID string) ([]models.JobEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	backlog := make([]models.JobEvent, 0)
+	cursor := lastEventID
+	for {
+		batch, err := source.Since(ctx, cursor, db.DefaultJobEventLimit)
+		if err != nil {
+			return nil, err
+		}
+		backlog = append(backlog, batch...)
+		if len(batch) < db.DefaultJobEventLimit {
+			return backlog, nil
+		}
+		cursor = batch[len(batch)-1].ID
+	}
+}
+
+// writeJobEvent writes a single SSE frame
+func writeJobEvent(w http.ResponseWriter, event models.JobEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, models.JobEventType, data)
+	return err
+}
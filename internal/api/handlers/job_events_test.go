@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/events"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// memoryJobEvents is an in-memory job event store
+type memoryJobEvents struct {
+	mu     sync.Mutex
+	events []models.JobEvent
+}
+
+func (m *memoryJobEvents) Publish(ctx context.Context, event models.JobEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memoryJobEvents) Since(ctx context.Context, afterID string, limit int) ([]models.JobEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]models.JobEvent, 0)
+	for _, event := range m.events {
+		if event.ID > afterID && len(out) < limit {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryJobEvents) LatestID(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.events) == 0 {
+		return "", nil
+	}
+	return m.events[len(m.events)-1].ID, nil
+}
+
+// sseFrame is one parsed Server-Sent Event
+type sseFrame struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readFrames parses SSE frames from body onto a channel, skipping comments
+func readFrames(body *bufio.Reader) <-chan sseFrame {
+	frames := make(chan sseFrame)
+	go func() {
+		defer close(frames)
+		var frame sseFrame
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				if frame.Data != "" {
+					frames <- frame
+				}
+				frame = sseFrame{}
+			case strings.HasPrefix(line, "id: "):
+				frame.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				frame.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				frame.Data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return frames
+}
+
+func nextFrame(t *testing.T, frames <-chan sseFrame) models.JobEvent {
+	t.Helper()
+	select {
+	case frame, ok := <-frames:
+		require.True(t, ok, "stream closed")
+		assert.Equal(t, models.JobEventType, frame.Event)
+		var event models.JobEvent
+		require.NoError(t, json.Unmarshal([]byte(frame.Data), &event))
+		assert.Equal(t, frame.ID, event.ID)
+		return event
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for job event")
+		return models.JobEvent{}
+	}
+}
+
+// openJobEvents starts the handler and opens a stream against it
+func openJobEvents(t *testing.T, broker *events.Broker, store *memoryJobEvents, query, lastEventID string) <-chan sseFrame {
+	t.Helper()
+	server := httptest.NewServer(JobEventsHandler(broker, store, zaptest.NewLogger(t)))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/jobs/events"+query, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	return readFrames(bufio.NewReader(resp.Body))
+}
+
+func TestJobEventsHandler_StreamsStateChanges(t *testing.T) {
+	store := &memoryJobEvents{}
+	broker := events.NewBroker(0, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Poll(ctx, store, 10*time.Millisecond)
+
+	frames := openJobEvents(t, broker, store, "", "")
+
+	// The workflow publishes a transition; the poller relays it to the stream
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, store.Publish(ctx, models.JobEvent{
+		ID:         "0190a000-0000-7000-8000-000000000001",
+		JobID:      "job-1",
+		ScannerKey: "scanner-a",
+		State:      models.JobStateCompleted,
+		HostCount:  3,
+		PortCount:  7,
+	}))
+
+	event := nextFrame(t, frames)
+	assert.Equal(t, "job-1", event.JobID)
+	assert.Equal(t, models.JobStateCompleted, event.State)
+	assert.Equal(t, 3, event.HostCount)
+	assert.Equal(t, 7, event.PortCount)
+}
+
+func TestJobEventsHandler_ReplaysAfterLastEventID(t *testing.T) {
+	store := &memoryJobEvents{}
+	ctx := context.Background()
+	require.NoError(t, store.Publish(ctx, models.JobEvent{ID: "01", JobID: "job-1", State: models.JobStatePending}))
+	require.NoError(t, store.Publish(ctx, models.JobEvent{ID: "02", JobID: "job-1", State: models.JobStateProcessing}))
+	require.NoError(t, store.Publish(ctx, models.JobEvent{ID: "03", JobID: "job-1", State: models.JobStateCompleted}))
+
+	broker := events.NewBroker(0, nil)
+	frames := openJobEvents(t, broker, store, "", "01")
+
+	assert.Equal(t, "02", nextFrame(t, frames).ID)
+	assert.Equal(t, "03", nextFrame(t, frames).ID)
+
+	// A live event already covered by the replay is not sent twice
+	broker.Publish(models.JobEvent{ID: "03", JobID: "job-1", State: models.JobStateCompleted})
+	broker.Publish(models.JobEvent{ID: "04", JobID: "job-2", State: models.JobStatePending})
+	assert.Equal(t, "04", nextFrame(t, frames).ID)
+}
+
+func TestJobEventsHandler_FiltersByScannerKey(t *testing.T) {
+	store := &memoryJobEvents{}
+	broker := events.NewBroker(0, nil)
+	frames := openJobEvents(t, broker, store, "?scanner_key=scanner-b", "")
+
+	broker.Publish(models.JobEvent{ID: "01", JobID: "job-a", ScannerKey: "scanner-a", State: models.JobStateProcessing})
+	broker.Publish(models.JobEvent{ID: "02", JobID: "job-b", ScannerKey: "scanner-b", State: models.JobStateProcessing})
+
+	event := nextFrame(t, frames)
+	assert.Equal(t, "job-b", event.JobID)
+	assert.Equal(t, "scanner-b", event.ScannerKey)
+}
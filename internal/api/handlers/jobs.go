@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -155,13 +156,52 @@ func ListJobsHandler(dbClient *surrealdb.DB, logger *zap.Logger) http.HandlerFun
 	}
 }
 
+// CancelJobHandler creates an HTTP handler for POST /v1/jobs/{job_id}/cancel
+// Cancels a pending or processing job; finished jobs are rejected with 409 Conflict
+func CancelJobHandler(dbClient *surrealdb.DB, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		jobID := chi.URLParam(r, "job_id")
+		if jobID == "" {
+			logger.Warn("missing job_id parameter")
+			jobErrorResponse(w, "missing_parameter", "job_id is required", http.StatusBadRequest)
+			return
+		}
+
+		job, err := db.CancelJob(ctx, dbClient, logger, jobID)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidTransition) {
+				jobErrorResponse(w, "invalid_state", "Job has already finished and cannot be cancelled", http.StatusConflict)
+				return
+			}
+			logger.Error("failed to cancel job",
+				zap.Error(err),
+				zap.String("job_id", jobID))
+			jobErrorResponse(w, "internal_error", "Failed to cancel job", http.StatusInternalServerError)
+			return
+		}
+
+		if job == nil {
+			jobErrorResponse(w, "not_found", "Job not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(job); err != nil {
+			logger.Error("failed to encode job response",
+				zap.Error(err),
+				zap.String("job_id", jobID))
+		}
+	}
+}
+
 // jobErrorResponse writes a consistent error response for job endpoints
 func jobErrorResponse(w http.ResponseWriter, errorCode, message string, statusCode int) {
-	response := struct {
-		Error     string `json:"error"`
-		Message   string `json:"message"`
-		Timestamp string `json:"timestamp"`
-	}{
+	response := CodedErrorResponse{
 		Error:     errorCode,
 		Message:   message,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
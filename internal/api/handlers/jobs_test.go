@@ -8,6 +8,7 @@ import (
 
 	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
 )
 
 func TestGetJobHandler_MissingJobID(t *testing.T) {
@@ -121,6 +122,22 @@ func TestJobStateTransitions_ValidScenarios(t *testing.T) {
 	}
 }
 
+func TestCancelJobHandler_MissingJobID(t *testing.T) {
+	handler := CancelJobHandler(nil, zaptest.NewLogger(t))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/jobs//cancel", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "missing_parameter", response["error"])
+}
+
 func TestErrorResponseFormat(t *testing.T) {
 	w := httptest.NewRecorder()
 
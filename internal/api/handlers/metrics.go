@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/enrichment"
+	"go.uber.org/zap"
+)
+
+// CacheStatsSource reports the statistics of an enrichment cache
+type CacheStatsSource func() enrichment.CacheStats
+
+// BreakerStatsSource reports the state of an upstream circuit breaker
+type BreakerStatsSource func() enrichment.BreakerStats
+
+// CacheMetrics is one cache's entry in the metrics response
+type CacheMetrics struct {
+	enrichment.CacheStats
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// MetricsResponse represents the /metrics response
+type MetricsResponse struct {
+	Caches    map[string]CacheMetrics            `json:"caches"`
+	Breakers  map[string]enrichment.BreakerStats `json:"breakers"`
+	Timestamp string                             `json:"timestamp"`
+}
+
+// MetricsHandler creates the /metrics handler, reporting size, hit/miss and
+// eviction counts for each named cache so operators can judge whether the
+// caches are effective and size them, and the state of each named upstream
+// circuit breaker
+func MetricsHandler(caches map[string]CacheStatsSource, breakers map[string]BreakerStatsSource, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := MetricsResponse{
+			Caches:    make(map[string]CacheMetrics, len(caches)),
+			Breakers:  make(map[string]enrichment.BreakerStats, len(breakers)),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		for name, source := range caches {
+			stats := source()
+			response.Caches[name] = CacheMetrics{CacheStats: stats, HitRatio: stats.HitRatio()}
+		}
+		for name, source := range breakers {
+			response.Breakers[name] = source()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode metrics response",
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	handler := MetricsHandler(map[string]CacheStatsSource{
+		"asn": func() enrichment.CacheStats {
+			return enrichment.CacheStats{Size: 2, Hits: 3, Misses: 1, Evictions: 4}
+		},
+		"nvd": func() enrichment.CacheStats {
+			return enrichment.CacheStats{}
+		},
+	}, map[string]BreakerStatsSource{
+		"nvd": func() enrichment.BreakerStats {
+			return enrichment.BreakerStats{State: enrichment.BreakerOpen, ConsecutiveFailures: 5, Opens: 1}
+		},
+	}, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var resp MetricsResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Caches, 2)
+
+	asn := resp.Caches["asn"]
+	assert.Equal(t, 2, asn.Size)
+	assert.Equal(t, uint64(3), asn.Hits)
+	assert.Equal(t, uint64(1), asn.Misses)
+	assert.Equal(t, uint64(4), asn.Evictions)
+	assert.InDelta(t, 0.75, asn.HitRatio, 1e-9)
+
+	nvd := resp.Caches["nvd"]
+	assert.Zero(t, nvd.Hits)
+	assert.Zero(t, nvd.HitRatio)
+	assert.True(t, nvd.OldestEntry.IsZero())
+
+	require.Len(t, resp.Breakers, 1)
+	assert.Equal(t, enrichment.BreakerOpen, resp.Breakers["nvd"].State)
+	assert.Equal(t, 5, resp.Breakers["nvd"].ConsecutiveFailures)
+	assert.Equal(t, uint64(1), resp.Breakers["nvd"].Opens)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// SchemaMigrator brings the graph schema up to date
+type SchemaMigrator interface {
+	Migrate(ctx context.Context, dryRun bool) (models.MigrateResponse, error)
+}
+
+// MigrateHandler handles POST /v1/admin/migrate
+// Applies the schema migrations the database has not seen yet; re-running it is a no-op.
+// audit, if non-nil, records each run, including dry runs and failed ones.
+func MigrateHandler(migrator SchemaMigrator, audit AuditRecorder, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Building indexes over existing records can take a while
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+		defer cancel()
+
+		var req models.MigrateRequest
+		if r.ContentLength != 0 {
+			if err := decodeJSONBody(w, r, DefaultQueryMaxBodyBytes, &req); err != nil {
+				jobErrorResponse(w, "invalid_json", "Invalid JSON format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		resp, err := migrator.Migrate(ctx, req.DryRun)
+		recordAudit(ctx, audit, r, models.AuditEntry{
+			Action:  models.AuditActionMigrate,
+			Actor:   adminActor(r),
+			Details: migrateAuditDetails(req, resp, err == nil),
+		}, logger)
+		if err != nil {
+			logger.Error("schema migration failed",
+				zap.Error(err),
+				zap.Int("current_version", resp.CurrentVersion),
+				zap.Int("applied", len(resp.Applied)))
+			jobErrorResponse(w, "internal_error", "Schema migration failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		logger.Info("schema migration finished",
+			zap.Int("current_version", resp.CurrentVersion),
+			zap.Int("latest_version", resp.LatestVersion),
+			zap.Int("applied", len(resp.Applied)),
+			zap.Bool("dry_run", resp.DryRun))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Error("failed to encode migrate response",
+				zap.Error(err))
+		}
+	}
+}
+
+// migrateAuditDetails summarizes a migration run for the audit trail: the
+// migrations it applied, which on failure may be only some of them
+func migrateAuditDetails(req models.MigrateRequest, resp models.MigrateResponse, complete bool) map[string]interface{} {
+	applied := make([]int, 0, len(resp.Applied))
+	for _, migration := range resp.Applied {
+		applied = append(applied, migration.Version)
+	}
+	return map[string]interface{}{
+		"dry_run":         req.DryRun,
+		"current_version": resp.CurrentVersion,
+		"applied":         applied,
+		"complete":        complete,
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeMigrator returns a fixed result and records whether it was asked for a dry run
+type fakeMigrator struct {
+	resp      models.MigrateResponse
+	err       error
+	gotDryRun bool
+}
+
+func (m *fakeMigrator) Migrate(ctx context.Context, dryRun bool) (models.MigrateResponse, error) {
+	m.gotDryRun = dryRun
+	resp := m.resp
+	resp.DryRun = dryRun
+	return resp, m.err
+}
+
+func TestMigrateHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+		wantDryRun bool
+	}{
+		{"no body", "", nil, http.StatusOK, false},
+		{"dry run", `{"dry_run":true}`, nil, http.StatusOK, true},
+		{"migration fails", `{}`, errors.New("failed to apply migration 2 (affected_by_indexes)"), http.StatusInternalServerError, false},
+		{"invalid json", `{"dry_run":`, nil, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrator := &fakeMigrator{
+				resp: models.MigrateResponse{
+					CurrentVersion: 2,
+					LatestVersion:  2,
+					Applied:        []models.MigrationInfo{{Version: 2, Name: "affected_by_indexes"}},
+				},
+				err: tt.err,
+			}
+			audit := &fakeAudit{}
+			handler := middleware.AdminAuth("admin-token", zap.NewNop())(MigrateHandler(migrator, audit, zap.NewNop()))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/migrate", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer admin-token")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusBadRequest {
+				assert.Empty(t, audit.entries)
+			} else {
+				require.Len(t, audit.entries, 1)
+				entry := audit.entries[0]
+				assert.Equal(t, models.AuditActionMigrate, entry.Action)
+				assert.Equal(t, models.AuditActorAdmin, entry.Actor)
+				assert.Equal(t, tt.wantDryRun, entry.Details["dry_run"])
+				assert.Equal(t, []int{2}, entry.Details["applied"])
+				assert.Equal(t, tt.err == nil, entry.Details["complete"])
+			}
+			if tt.wantStatus == http.StatusInternalServerError {
+				assert.Contains(t, w.Body.String(), "migration 2")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tt.wantDryRun, migrator.gotDryRun)
+			var resp models.MigrateResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, 2, resp.CurrentVersion)
+			assert.Equal(t, tt.wantDryRun, resp.DryRun)
+			require.Len(t, resp.Applied, 1)
+			assert.Equal(t, "affected_by_indexes", resp.Applied[0].Name)
+		})
+	}
+}
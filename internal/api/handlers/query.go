@@ -3,8 +3,10 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,8 +16,8 @@ import (
 	"go.uber.org/zap"
 )
 
-// QueryHandler creates a handler for querying host information by IP
-func QueryHandler(logger *zap.Logger) http.HandlerFunc {
+// QueryHandler creates a handler for querying host information by IP on the shared database connection
+func QueryHandler(dbClient *surrealdb.DB, logger *zap.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
@@ -29,44 +31,22 @@ func QueryHandler(logger *zap.Logger) http.HandlerFunc {
 		}
 
 		// Parse optional depth parameter (default: 2)
-		depth := int(models.DefaultDepth())
-		if depthParam := r.URL.Query().Get("depth"); depthParam != "" {
-			parsedDepth, err := strconv.Atoi(depthParam)
-			if err != nil {
-				logger.Warn("invalid depth parameter",
-					zap.String("depth", depthParam),
-					zap.Error(err))
-				writeErrorResponse(w, "invalid depth parameter: must be an integer", http.StatusBadRequest)
-				return
-			}
-
-			if !models.ValidateDepth(parsedDepth) {
-				logger.Warn("depth out of range",
-					zap.Int("depth", parsedDepth))
-				writeErrorResponse(w, "depth must be between 0 and 5", http.StatusBadRequest)
-				return
-			}
-
-			depth = parsedDepth
+		depthParam := r.URL.Query().Get("depth")
+		depth, err := parseHostDepth(depthParam)
+		if err != nil {
+			logger.Warn("invalid depth parameter",
+				zap.String("depth", depthParam),
+				zap.Error(err))
+			writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		logger.Info("querying host",
 			zap.String("ip", ip),
 			zap.Int("depth", depth))
 
-		// Create database connection
-		dbConn, err := createDBConnection(ctx, logger)
-		if err != nil {
-			logger.Error("database connection failed",
-				zap.Error(err),
-				zap.String("ip", ip))
-			writeErrorResponse(w, "database connection error", http.StatusInternalServerError)
-			return
-		}
-		defer dbConn.Close(ctx)
-
 		// Query the host
-		result, err := db.QueryHost(ctx, dbConn, logger, ip, depth)
+		result, err := db.QueryHost(ctx, dbClient, logger, ip, depth)
 		if err != nil {
 			logger.Error("host query failed",
 				zap.Error(err),
@@ -84,6 +64,11 @@ func QueryHandler(logger *zap.Logger) http.HandlerFunc {
 			return
 		}
 
+		if depth >= int(models.DepthWithVulns) {
+			summary := summarizeVulns(result.Vulns)
+			result.VulnSummary = &summary
+		}
+
 		// Return successful response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -105,30 +90,55 @@ func QueryHandler(logger *zap.Logger) http.HandlerFunc {
 	}
 }
 
-// createDBConnection establishes a connection to SurrealDB
-func createDBConnection(ctx context.Context, logger *zap.Logger) (*surrealdb.DB, error) {
-	// Create database connection
-	db, err := surrealdb.New("ws://localhost:8000/rpc")
-	if err != nil {
-		return nil, err
-	}
+// summarizeVulns counts vulnerabilities by severity and KEV listing
+// The stored severity is used when present; otherwise it is derived from the CVSS score.
+func summarizeVulns(vulns []models.VulnDetail) models.VulnSummary {
+	var summary models.VulnSummary
+	for _, vuln := range vulns {
+		severity := strings.ToLower(vuln.Severity)
+		if severity == "" {
+			severity = cvssSeverity(vuln.CVSS)
+		}
 
-	// Sign in with credentials
-	if _, err := db.SignIn(ctx, map[string]interface{}{
-		"user": "root",
-		"pass": "root",
-	}); err != nil {
-		db.Close(ctx)
-		return nil, err
+		switch severity {
+		case "critical":
+			summary.Critical++
+		case "high":
+			summary.High++
+		case "medium":
+			summary.Medium++
+		case "low":
+			summary.Low++
+		}
+		if vuln.KEVFlag {
+			summary.KEV++
+		}
 	}
+	return summary
+}
 
-	// Use namespace and database
-	if err := db.Use(ctx, "spectra", "intel"); err != nil {
-		db.Close(ctx)
-		return nil, err
+// cvssSeverity returns the lower-case CVSS v3 severity rating for a base score,
+// or "" when no score was reported
+func cvssSeverity(cvss float64) string {
+	switch {
+	case cvss >= 9.0:
+		return "critical"
+	case cvss >= 7.0:
+		return "high"
+	case cvss >= 4.0:
+		return "medium"
+	case cvss > 0:
+		return "low"
+	default:
+		return ""
 	}
+}
 
-	return db, nil
+// QueryErrorResponse is the error body returned by the host query endpoint
+type QueryErrorResponse struct {
+	Error   string `json:"error"`   // HTTP status text
+	Message string `json:"message"` // Human-readable description
+	Code    int    `json:"code"`    // HTTP status code
 }
 
 // writeErrorResponse writes a standard JSON error response
@@ -136,11 +146,29 @@ func writeErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	errorResp := map[string]interface{}{
-		"error":   http.StatusText(statusCode),
-		"message": message,
-		"code":    statusCode,
+	errorResp := QueryErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+		Code:    statusCode,
 	}
 
 	json.NewEncoder(w).Encode(errorResp)
 }
+
+// parseHostDepth parses the depth query parameter, defaulting to DefaultDepth
+// Depths above DepthMaximum are clamped to it rather than rejected, since
+// clients pass arbitrary integers; negative depths are an error.
+func parseHostDepth(param string) (int, error) {
+	if param == "" {
+		return int(models.DefaultDepth()), nil
+	}
+
+	depth, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, fmt.Errorf("invalid depth parameter: must be an integer")
+	}
+	if depth < 0 {
+		return 0, fmt.Errorf("depth must be between 0 and %d", models.DepthMaximum)
+	}
+	return models.ClampDepth(depth), nil
+}
@@ -107,7 +107,7 @@ func TestQueryHandlerIntegration(t *testing.T) {
 	}
 
 	logger := zap.NewNop()
-	handler := QueryHandler(logger)
+	handler := QueryHandler(db, logger)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -213,7 +213,7 @@ func BenchmarkQueryHandler(b *testing.B) {
 	}
 
 	logger := zap.NewNop()
-	handler := QueryHandler(logger)
+	handler := QueryHandler(db, logger)
 
 	b.ResetTimer()
 
@@ -251,7 +251,7 @@ func TestQueryResponse_DepthLevels(t *testing.T) {
 	depths := []int{0, 1, 2, 3}
 
 	logger := zap.NewNop()
-	handler := QueryHandler(logger)
+	handler := QueryHandler(db, logger)
 
 	for _, depth := range depths {
 		t.Run(fmt.Sprintf("depth_%d", depth), func(t *testing.T) {
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -14,7 +15,7 @@ import (
 
 func TestQueryHandler_MissingIP(t *testing.T) {
 	logger := zap.NewNop()
-	handler := QueryHandler(logger)
+	handler := QueryHandler(nil, logger)
 
 	req := httptest.NewRequest(http.MethodGet, "/v1/query/host/", nil)
 	w := httptest.NewRecorder()
@@ -45,17 +46,12 @@ func TestQueryHandler_InvalidDepth(t *testing.T) {
 			depth:       "-1",
 			expectedMsg: "depth must be between 0 and 5",
 		},
-		{
-			name:        "depth too large",
-			depth:       "10",
-			expectedMsg: "depth must be between 0 and 5",
-		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logger := zap.NewNop()
-			handler := QueryHandler(logger)
+			handler := QueryHandler(nil, logger)
 
 			req := httptest.NewRequest(http.MethodGet, "/v1/query/host/1.2.3.4?depth="+tt.depth, nil)
 			w := httptest.NewRecorder()
@@ -63,7 +59,7 @@ func TestQueryHandler_InvalidDepth(t *testing.T) {
 			// Add chi URL params
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("ip", "1.2.3.4")
-			req = req.WithContext(chi.NewRouteContext().WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 			handler(w, req)
 
@@ -124,6 +120,35 @@ func TestQueryHandler_ValidDepthParsing(t *testing.T) {
 	}
 }
 
+func TestParseHostDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		param     string
+		wantDepth int
+		wantErr   bool
+	}{
+		{name: "default", param: "", wantDepth: int(models.DefaultDepth())},
+		{name: "in range", param: "3", wantDepth: 3},
+		{name: "maximum", param: "5", wantDepth: int(models.DepthMaximum)},
+		{name: "clamped", param: "10", wantDepth: int(models.DepthMaximum)},
+		{name: "far out of range", param: "99", wantDepth: int(models.DepthMaximum)},
+		{name: "negative", param: "-1", wantErr: true},
+		{name: "non-numeric", param: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth, err := parseHostDepth(tt.param)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDepth, depth)
+		})
+	}
+}
+
 func TestWriteErrorResponse(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -199,3 +224,27 @@ func TestDefaultDepth(t *testing.T) {
 	assert.Equal(t, models.DepthWithServices, depth)
 	assert.Equal(t, 2, int(depth))
 }
+
+func TestSummarizeVulns(t *testing.T) {
+	vulns := []models.VulnDetail{
+		{CVEID: "CVE-2024-0001", CVSS: 9.8, Severity: "CRITICAL", KEVFlag: true},
+		{CVEID: "CVE-2024-0002", CVSS: 9.1, Severity: "critical"},
+		{CVEID: "CVE-2024-0003", CVSS: 7.5, Severity: "High", KEVFlag: true},
+		{CVEID: "CVE-2024-0004", CVSS: 5.3, Severity: "medium"},
+		{CVEID: "CVE-2024-0005", CVSS: 2.1, Severity: "low"},
+		{CVEID: "CVE-2024-0006", CVSS: 8.2},               // severity derived from CVSS
+		{CVEID: "CVE-2024-0007", KEVFlag: true},           // no severity or CVSS
+		{CVEID: "CVE-2024-0008", CVSS: 4.0, Severity: ""}, // boundary: medium
+	}
+
+	summary := summarizeVulns(vulns)
+
+	assert.Equal(t, models.VulnSummary{
+		Critical: 2,
+		High:     2,
+		Medium:   2,
+		Low:      1,
+		KEV:      3,
+	}, summary)
+	assert.Equal(t, models.VulnSummary{}, summarizeVulns(nil))
+}
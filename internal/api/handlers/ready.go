@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"go.uber.org/zap"
+)
+
+// ReadyResponse represents the readiness check response
+type ReadyResponse struct {
+	Status    string `json:"status"` // "ready" or "draining"
+	InFlight  int    `json:"in_flight"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ReadyHandler creates the /readyz handler: 200 while the server accepts new
+// work, 503 once inFlight has started draining for shutdown, so load balancers
+// stop routing to it
+func ReadyHandler(inFlight *middleware.InFlightLimiter, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := ReadyResponse{
+			Status:    "ready",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		statusCode := http.StatusOK
+
+		if inFlight != nil {
+			response.InFlight = inFlight.Depth()
+			if inFlight.Draining() {
+				response.Status = "draining"
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode readiness response",
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestReadyHandler(t *testing.T) {
+	inFlight := middleware.NewInFlightLimiter(10, time.Second)
+	require.True(t, inFlight.TryAcquire())
+	handler := ReadyHandler(inFlight, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp ReadyResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, 1, resp.InFlight)
+
+	// Draining fails readiness while the accepted job is still running
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = inFlight.Drain(ctx)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "draining", resp.Status)
+	assert.Equal(t, 1, resp.InFlight)
+}
+
+func TestIngestHandler_RejectsWhileDraining(t *testing.T) {
+	inFlight := middleware.NewInFlightLimiter(10, 30*time.Second)
+	require.NoError(t, inFlight.Drain(context.Background()))
+
+	handler := IngestHandler(zap.NewNop(), nil, "http://restate.invalid", time.Minute, inFlight, 0, nil, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", strings.NewReader(`{}`)))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	var resp CodedErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "shutting_down", resp.Error)
+}
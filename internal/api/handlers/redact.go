@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// HostRedactor removes hosts and their relationships from the graph
+type HostRedactor interface {
+	DeleteHost(ctx context.Context, ip string) (models.RedactResponse, error)
+	DeleteNetwork(ctx context.Context, cidr string) (models.RedactResponse, error)
+}
+
+// RedactHandler handles POST /v1/admin/redact
+// The body names one IP or one CIDR; every matching host is removed with its
+// edges and any ports no other host has. onRedacted, if set, runs after hosts
+// were removed, e.g. to drop cached query results that still contain them.
+// audit, if non-nil, records each redaction with its counts but not its target.
+func RedactHandler(redactor HostRedactor, onRedacted func(), audit AuditRecorder, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+		defer cancel()
+
+		var req models.RedactRequest
+		if err := decodeJSONBody(w, r, DefaultQueryMaxBodyBytes, &req); err != nil {
+			jobErrorResponse(w, "invalid_json", "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+
+		var (
+			resp models.RedactResponse
+			err  error
+		)
+		switch {
+		case (req.IP == "") == (req.CIDR == ""):
+			jobErrorResponse(w, "invalid_request", "Exactly one of ip and cidr is required", http.StatusBadRequest)
+			return
+		case req.IP != "":
+			if _, perr := netip.ParseAddr(req.IP); perr != nil {
+				jobErrorResponse(w, "invalid_request", "ip is not a valid IP address", http.StatusBadRequest)
+				return
+			}
+			resp, err = redactor.DeleteHost(ctx, req.IP)
+		default:
+			if _, perr := netip.ParsePrefix(req.CIDR); perr != nil {
+				jobErrorResponse(w, "invalid_request", "cidr is not a valid network, e.g. 192.0.2.0/24", http.StatusBadRequest)
+				return
+			}
+			resp, err = redactor.DeleteNetwork(ctx, req.CIDR)
+		}
+		if resp != (models.RedactResponse{}) && onRedacted != nil {
+			onRedacted()
+		}
+		recordAudit(ctx, audit, r, models.AuditEntry{
+			Action:    models.AuditActionRedact,
+			Actor:     models.AuditActorAdmin,
+			HostCount: resp.Hosts,
+			Details: map[string]interface{}{
+				"network":  req.CIDR != "",
+				"ports":    resp.Ports,
+				"edges":    resp.Edges,
+				"complete": err == nil,
+			},
+		}, logger)
+		if err != nil {
+			// Counts cover whatever was removed before the failure
+			logger.Error("redaction failed",
+				zap.Error(err),
+				zap.Int("hosts", resp.Hosts),
+				zap.Int("ports", resp.Ports),
+				zap.Int("edges", resp.Edges))
+			jobErrorResponse(w, "internal_error", "Redaction failed", http.StatusInternalServerError)
+			return
+		}
+
+		// The target itself is not logged; it is the data being removed
+		logger.Info("redaction finished",
+			zap.Bool("network", req.CIDR != ""),
+			zap.Int("hosts", resp.Hosts),
+			zap.Int("ports", resp.Ports),
+			zap.Int("edges", resp.Edges))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.Error("failed to encode redact response",
+				zap.Error(err))
+		}
+	}
+}
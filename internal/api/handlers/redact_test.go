@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRedactor returns a fixed result and records what it was asked to remove
+type fakeRedactor struct {
+	resp    models.RedactResponse
+	err     error
+	gotIP   string
+	gotCIDR string
+}
+
+func (r *fakeRedactor) DeleteHost(ctx context.Context, ip string) (models.RedactResponse, error) {
+	r.gotIP = ip
+	return r.resp, r.err
+}
+
+func (r *fakeRedactor) DeleteNetwork(ctx context.Context, cidr string) (models.RedactResponse, error) {
+	r.gotCIDR = cidr
+	return r.resp, r.err
+}
+
+func TestRedactHandler(t *testing.T) {
+	removed := models.RedactResponse{Hosts: 1, Ports: 2, Edges: 5}
+
+	tests := []struct {
+		name         string
+		body         string
+		resp         models.RedactResponse
+		err          error
+		wantStatus   int
+		wantIP       string
+		wantCIDR     string
+		wantCallback bool
+	}{
+		{name: "host", body: `{"ip":"192.0.2.1"}`, resp: removed, wantStatus: http.StatusOK, wantIP: "192.0.2.1", wantCallback: true},
+		{name: "network", body: `{"cidr":"192.0.2.0/24"}`, resp: removed, wantStatus: http.StatusOK, wantCIDR: "192.0.2.0/24", wantCallback: true},
+		{name: "nothing matched", body: `{"ip":"192.0.2.1"}`, wantStatus: http.StatusOK, wantIP: "192.0.2.1"},
+		{name: "both targets", body: `{"ip":"192.0.2.1","cidr":"192.0.2.0/24"}`, wantStatus: http.StatusBadRequest},
+		{name: "no target", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid ip", body: `{"ip":"192.0.2"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid cidr", body: `{"cidr":"192.0.2.0/40"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid json", body: `{"ip":`, wantStatus: http.StatusBadRequest},
+		{name: "partial failure", body: `{"ip":"192.0.2.1"}`, resp: models.RedactResponse{Edges: 2}, err: errors.New("connection reset"),
+			wantStatus: http.StatusInternalServerError, wantIP: "192.0.2.1", wantCallback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor := &fakeRedactor{resp: tt.resp, err: tt.err}
+			called := false
+			audit := &fakeAudit{}
+			handler := RedactHandler(redactor, func() { called = true }, audit, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/redact", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantIP, redactor.gotIP)
+			assert.Equal(t, tt.wantCIDR, redactor.gotCIDR)
+			assert.Equal(t, tt.wantCallback, called)
+
+			// Every redaction that reached the redactor is audited, without its target
+			if tt.wantIP == "" && tt.wantCIDR == "" {
+				assert.Empty(t, audit.entries)
+			} else {
+				require.Len(t, audit.entries, 1)
+				entry := audit.entries[0]
+				assert.Equal(t, models.AuditActionRedact, entry.Action)
+				assert.Equal(t, models.AuditActorAdmin, entry.Actor)
+				assert.Equal(t, "192.0.2.1", entry.SourceIP, "httptest requests come from 192.0.2.1:1234")
+				assert.Equal(t, tt.resp.Hosts, entry.HostCount)
+				assert.Equal(t, tt.err == nil, entry.Details["complete"])
+				assert.NotContains(t, fmt.Sprint(entry.Details), "192.0.2.0/24")
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				var resp models.RedactResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, tt.resp, resp)
+			}
+		})
+	}
+}
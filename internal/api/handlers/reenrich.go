@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// ReenrichSource selects the hosts and services a bulk re-enrichment covers
+type ReenrichSource interface {
+	HostIPs(ctx context.Context, req models.ReenrichRequest, limit int) ([]string, error)
+	Services(ctx context.Context, req models.ReenrichRequest, limit int) ([]models.ReenrichService, error)
+}
+
+// reenrichBatchSize is how many IPs or services go to one workflow invocation
+// It matches the largest batch EnrichASNWorkflow accepts.
+const reenrichBatchSize = 100
+
+// ReenrichHandler serves the bulk re-enrichment admin endpoint
+type ReenrichHandler struct {
+	source  ReenrichSource
+	invoker WorkflowInvoker
+	audit   AuditRecorder
+	logger  *zap.Logger
+}
+
+// NewReenrichHandler creates a handler that queues the selected targets through invoker
+// audit, if non-nil, records each request that reached the enrichment workflows.
+func NewReenrichHandler(source ReenrichSource, invoker WorkflowInvoker, audit AuditRecorder, logger *zap.Logger) *ReenrichHandler {
+	return &ReenrichHandler{
+		source:  source,
+		invoker: invoker,
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+// HandleReenrich handles POST /v1/admin/reenrich
+// With only asn/country filters, the matching hosts are queued for ASN and GeoIP
+// enrichment. With missing_cpe or stale_before, the matching services on those
+// hosts are queued for CPE enrichment instead. At least one filter is required.
+func (h *ReenrichHandler) HandleReenrich(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	var req models.ReenrichRequest
+	if err := decodeJSONBody(w, r, DefaultQueryMaxBodyBytes, &req); err != nil {
+		jobErrorResponse(w, "invalid_json", "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if req.ASN < 0 {
+		jobErrorResponse(w, "invalid_parameter", "asn must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if !req.HasHostFilter() && !req.HasServiceFilter() {
+		jobErrorResponse(w, "missing_parameter", "at least one filter is required: asn, country, missing_cpe, stale_before", http.StatusBadRequest)
+		return
+	}
+	if req.Limit < 0 || req.Limit > models.MaxReenrichLimit {
+		jobErrorResponse(w, "invalid_parameter", fmt.Sprintf("limit must be between 1 and %d", models.MaxReenrichLimit), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.dispatch(ctx, req)
+	recordAudit(ctx, h.audit, r, models.AuditEntry{
+		Action:  models.AuditActionReenrich,
+		Actor:   adminActor(r),
+		Details: reenrichAuditDetails(req, resp, err == nil),
+	}, h.logger)
+	if err != nil {
+		h.logger.Error("failed to queue re-enrichment",
+			zap.Error(err),
+			zap.Int("hosts_queued", resp.HostsQueued),
+			zap.Int("services_queued", resp.ServicesQueued))
+		jobErrorResponse(w, "service_unavailable", "Failed to queue re-enrichment", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.logger.Info("re-enrichment queued",
+		zap.Int("asn", req.ASN),
+		zap.String("country", req.Country),
+		zap.Bool("missing_cpe", req.MissingCPE),
+		zap.Int("hosts_queued", resp.HostsQueued),
+		zap.Int("services_queued", resp.ServicesQueued),
+		zap.Int("batches", resp.Batches))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error("failed to encode re-enrichment response",
+			zap.Error(err))
+	}
+}
+
+// reenrichAuditDetails describes a re-enrichment for the audit trail: the filters
+// that were set and what was queued, which on failure may be only part of it
+func reenrichAuditDetails(req models.ReenrichRequest, resp models.ReenrichResponse, complete bool) map[string]interface{} {
+	details := map[string]interface{}{
+		"hosts_queued":    resp.HostsQueued,
+		"services_queued": resp.ServicesQueued,
+		"complete":        complete,
+	}
+	if req.ASN > 0 {
+		details["asn"] = req.ASN
+	}
+	if req.Country != "" {
+		details["country"] = req.Country
+	}
+	if req.MissingCPE {
+		details["missing_cpe"] = true
+	}
+	if req.StaleBefore != nil {
+		details["stale_before"] = req.StaleBefore.UTC().Format(time.RFC3339)
+	}
+	return details
+}
+
+// dispatch collects the request's targets and sends them to the enrichment
+// workflows in batches. On error the response counts what was already queued.
+func (h *ReenrichHandler) dispatch(ctx context.Context, req models.ReenrichRequest) (models.ReenrichResponse, error) {
+	var resp models.ReenrichResponse
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = models.DefaultReenrichLimit
+	}
+
+	// Service filters narrow to CPE enrichment; host filters alone re-run host enrichment
+	if !req.HasServiceFilter() {
+		ips, err := h.source.HostIPs(ctx, req, limit)
+		if err != nil {
+			return resp, err
+		}
+		for start := 0; start < len(ips); start += reenrichBatchSize {
+			batch := ips[start:min(start+reenrichBatchSize, len(ips))]
+
+			// Skip the "already enriched" filter so every IP is looked up again
+			if err := h.invoker.Send(ctx, "EnrichASNWorkflow", map[string]interface{}{
+				"ips":           batch,
+				"force_refresh": true,
+			}); err != nil {
+				return resp, fmt.Errorf("failed to queue ASN enrichment: %w", err)
+			}
+			resp.Batches++
+			if err := h.invoker.Send(ctx, "EnrichGeoWorkflow", map[string]interface{}{
+				"ips":           batch,
+				"force_refresh": true,
+			}); err != nil {
+				return resp, fmt.Errorf("failed to queue GeoIP enrichment: %w", err)
+			}
+			resp.Batches++
+			resp.HostsQueued += len(batch)
+		}
+		return resp, nil
+	}
+
+	services, err := h.source.Services(ctx, req, limit)
+	if err != nil {
+		return resp, err
+	}
+	for start := 0; start < len(services); start += reenrichBatchSize {
+		batch := services[start:min(start+reenrichBatchSize, len(services))]
+
+		if err := h.invoker.Send(ctx, "EnrichCPEWorkflow", map[string]interface{}{
+			"services":      batch,
+			"force_refresh": true,
+		}); err != nil {
+			return resp, fmt.Errorf("failed to queue CPE enrichment: %w", err)
+		}
+		resp.Batches++
+		resp.ServicesQueued += len(batch)
+	}
+	return resp, nil
+}
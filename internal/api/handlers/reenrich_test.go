@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeReenrichSource returns fixed targets and records the request it was asked for
+type fakeReenrichSource struct {
+	ips      []string
+	services []models.ReenrichService
+	gotReq   models.ReenrichRequest
+	gotLimit int
+}
+
+func (s *fakeReenrichSource) HostIPs(ctx context.Context, req models.ReenrichRequest, limit int) ([]string, error) {
+	s.gotReq, s.gotLimit = req, limit
+	return s.ips, nil
+}
+
+func (s *fakeReenrichSource) Services(ctx context.Context, req models.ReenrichRequest, limit int) ([]models.ReenrichService, error) {
+	s.gotReq, s.gotLimit = req, limit
+	return s.services, nil
+}
+
+// invocation is one workflow request seen by mockInvoker
+type invocation struct {
+	service string
+	payload map[string]interface{}
+}
+
+// mockInvoker records workflow requests, failing after failAfter successful sends when set
+type mockInvoker struct {
+	calls     []invocation
+	failAfter int
+}
+
+func (m *mockInvoker) Send(ctx context.Context, service string, payload interface{}) error {
+	if m.failAfter > 0 && len(m.calls) >= m.failAfter {
+		return errors.New("restate unavailable")
+	}
+	m.calls = append(m.calls, invocation{service: service, payload: payload.(map[string]interface{})})
+	return nil
+}
+
+func testIPs(n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+	}
+	return ips
+}
+
+func postReenrich(t *testing.T, handler *ReenrichHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/reenrich", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleReenrich(w, req)
+	return w
+}
+
+func TestReenrichHandler_HostFiltersQueueASNAndGeo(t *testing.T) {
+	source := &fakeReenrichSource{ips: testIPs(250)}
+	invoker := &mockInvoker{}
+	handler := NewReenrichHandler(source, invoker, nil, zap.NewNop())
+
+	w := postReenrich(t, handler, `{"asn":64500,"country":"US"}`)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var resp models.ReenrichResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, models.ReenrichResponse{HostsQueued: 250, Batches: 6}, resp)
+
+	assert.Equal(t, 64500, source.gotReq.ASN)
+	assert.Equal(t, "US", source.gotReq.Country)
+	assert.Equal(t, models.DefaultReenrichLimit, source.gotLimit)
+
+	require.Len(t, invoker.calls, 6)
+	wantSizes := []int{100, 100, 50}
+	for i, size := range wantSizes {
+		asn, geo := invoker.calls[2*i], invoker.calls[2*i+1]
+		assert.Equal(t, "EnrichASNWorkflow", asn.service)
+		assert.Len(t, asn.payload["ips"], size)
+		assert.Equal(t, true, asn.payload["force_refresh"])
+		assert.Equal(t, "EnrichGeoWorkflow", geo.service)
+		assert.Equal(t, true, geo.payload["force_refresh"])
+		assert.Equal(t, asn.payload["ips"], geo.payload["ips"])
+	}
+}
+
+func TestReenrichHandler_ServiceFiltersQueueCPE(t *testing.T) {
+	services := make([]models.ReenrichService, 120)
+	for i := range services {
+		services[i] = models.ReenrichService{ID: fmt.Sprintf("service:s%d", i), Name: "http", Product: "nginx"}
+	}
+	source := &fakeReenrichSource{services: services}
+	invoker := &mockInvoker{}
+	handler := NewReenrichHandler(source, invoker, nil, zap.NewNop())
+
+	w := postReenrich(t, handler, `{"asn":64500,"missing_cpe":true,"stale_before":"2026-01-01T00:00:00Z","limit":500}`)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var resp models.ReenrichResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, models.ReenrichResponse{ServicesQueued: 120, Batches: 2}, resp)
+
+	assert.True(t, source.gotReq.MissingCPE)
+	require.NotNil(t, source.gotReq.StaleBefore)
+	assert.Equal(t, 2026, source.gotReq.StaleBefore.Year())
+	assert.Equal(t, 500, source.gotLimit)
+
+	require.Len(t, invoker.calls, 2)
+	for _, call := range invoker.calls {
+		assert.Equal(t, "EnrichCPEWorkflow", call.service)
+	}
+	assert.Len(t, invoker.calls[0].payload["services"], 100)
+	assert.Len(t, invoker.calls[1].payload["services"], 20)
+}
+
+func TestReenrichHandler_InvokerFailure(t *testing.T) {
+	source := &fakeReenrichSource{ips: testIPs(10)}
+	invoker := &mockInvoker{failAfter: 1}
+	handler := NewReenrichHandler(source, invoker, nil, zap.NewNop())
+
+	w := postReenrich(t, handler, `{"country":"DE"}`)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Len(t, invoker.calls, 1)
+}
+
+func TestReenrichHandler_AuditActor(t *testing.T) {
+	tests := []struct {
+		name      string
+		admin     bool
+		wantActor string
+	}{
+		{name: "admin token", admin: true, wantActor: models.AuditActorAdmin},
+		{name: "no admin token", wantActor: models.AuditActorAnonymous},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &fakeAudit{}
+			handler := NewReenrichHandler(&fakeReenrichSource{ips: testIPs(1)}, &mockInvoker{}, audit, zap.NewNop())
+
+			var h http.Handler = http.HandlerFunc(handler.HandleReenrich)
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/reenrich", strings.NewReader(`{"asn":64500}`))
+			req.RemoteAddr = "192.0.2.10:4000"
+			if tt.admin {
+				h = middleware.AdminAuth("admin-token", zap.NewNop())(h)
+				req.Header.Set("Authorization", "Bearer admin-token")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusAccepted, w.Code)
+			require.Len(t, audit.entries, 1)
+			assert.Equal(t, tt.wantActor, audit.entries[0].Actor)
+			assert.Equal(t, "192.0.2.10", audit.entries[0].SourceIP)
+		})
+	}
+}
+
+func TestReenrichHandler_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no filter", `{}`},
+		{"limit only", `{"limit":10}`},
+		{"negative asn", `{"asn":-1}`},
+		{"limit too large", `{"country":"US","limit":100001}`},
+		{"invalid json", `{"asn":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoker := &mockInvoker{}
+			handler := NewReenrichHandler(&fakeReenrichSource{ips: testIPs(1)}, invoker, nil, zap.NewNop())
+
+			w := postReenrich(t, handler, tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Empty(t, invoker.calls)
+		})
+	}
+}
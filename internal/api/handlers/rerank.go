@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// RerankWeights controls how each signal contributes to a result's FinalScore
+// Weights are relative; they are normalized by their sum when scoring.
+type RerankWeights struct {
+	// Similarity weights the raw vector similarity score
+	Similarity float64
+
+	// CVSS weights the CVSS base score (normalized to 0-1)
+	CVSS float64
+
+	// EPSS weights the exploit prediction score
+	EPSS float64
+
+	// KEV weights presence in the CISA Known Exploited Vulnerabilities catalog
+	KEV float64
+
+	// Recency weights how recently the vulnerability was published
+	Recency float64
+
+	// RecencyHalfLife is the age at which the recency signal drops to 0.5
+	RecencyHalfLife time.Duration
+}
+
+// DefaultRerankWeights returns the default reranking weights
+// Similarity stays dominant so reranking reorders close matches rather than
+// surfacing unrelated but severe vulnerabilities.
+func DefaultRerankWeights() RerankWeights {
+	return RerankWeights{
+		Similarity:      0.5,
+		CVSS:            0.15,
+		EPSS:            0.15,
+		KEV:             0.15,
+		Recency:         0.05,
+		RecencyHalfLife: 2 * 365 * 24 * time.Hour,
+	}
+}
+
+// rerankResults sets FinalScore on each result and sorts by it, highest first
+// Ties keep the original similarity order.
+func rerankResults(results []models.VulnResult, weights RerankWeights, now time.Time) {
+	total := weights.Similarity + weights.CVSS + weights.EPSS + weights.KEV + weights.Recency
+	if total <= 0 {
+		return
+	}
+
+	for i := range results {
+		r := &results[i]
+
+		kev := 0.0
+		if r.KEV {
+			kev = 1.0
+		}
+
+		score := weights.Similarity*clamp01(r.Score) +
+			weights.CVSS*clamp01(r.CVSS/10.0) +
+			weights.EPSS*clamp01(r.EPSS) +
+			weights.KEV*kev +
+			weights.Recency*recencyScore(r.PublishedDate, weights.RecencyHalfLife, now)
+
+		r.FinalScore = score / total
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].FinalScore > results[j].FinalScore
+	})
+}
+
+// recencyScore decays from 1.0 at publication with the given half-life
+// Unknown or unparsable dates score 0.
+func recencyScore(publishedDate string, halfLife time.Duration, now time.Time) float64 {
+	if publishedDate == "" || halfLife <= 0 {
+		return 0
+	}
+
+	published, err := time.Parse(time.RFC3339, publishedDate)
+	if err != nil {
+		return 0
+	}
+
+	age := now.Sub(published)
+	if age < 0 {
+		age = 0
+	}
+
+	return math.Pow(0.5, age.Hours()/halfLife.Hours())
+}
+
+// clamp01 limits v to the range [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// rrfK is the reciprocal-rank fusion constant; larger values flatten the
+// advantage of top-ranked documents (60 is the value from the original RRF paper)
+const rrfK = 60
+
+// fuseReciprocalRank merges ranked result lists with reciprocal-rank fusion,
+// deduplicating by CVE ID. Each document scores the sum of 1/(rrfK + rank) over
+// the lists it appears in. A duplicate keeps the copy with the higher similarity score.
+func fuseReciprocalRank(lists ...[]models.VulnResult) []models.VulnResult {
+	fusedScores := make(map[string]float64)
+	docs := make(map[string]models.VulnResult)
+	var order []string
+
+	for _, list := range lists {
+		for rank, r := range list {
+			existing, seen := docs[r.CVEID]
+			if !seen {
+				order = append(order, r.CVEID)
+				docs[r.CVEID] = r
+			} else if r.Score > existing.Score {
+				docs[r.CVEID] = r
+			}
+			fusedScores[r.CVEID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return fusedScores[order[i]] > fusedScores[order[j]]
+	})
+
+	fused := make([]models.VulnResult, 0, len(order))
+	for _, id := range order {
+		fused = append(fused, docs[id])
+	}
+
+	return fused
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRerankResults_SimilarityOnlyWeights(t *testing.T) {
+	results := []models.VulnResult{
+		{CVEID: "CVE-A", Score: 0.6, KEV: true, CVSS: 10},
+		{CVEID: "CVE-B", Score: 0.9},
+	}
+
+	rerankResults(results, RerankWeights{Similarity: 1}, time.Now())
+
+	assert.Equal(t, "CVE-B", results[0].CVEID)
+	assert.InDelta(t, 0.9, results[0].FinalScore, 1e-9)
+	assert.InDelta(t, 0.6, results[1].FinalScore, 1e-9)
+}
+
+func TestRecencyScore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	halfLife := 365 * 24 * time.Hour
+
+	assert.InDelta(t, 1.0, recencyScore(now.Format(time.RFC3339), halfLife, now), 1e-9)
+	assert.InDelta(t, 0.5, recencyScore(now.Add(-halfLife).Format(time.RFC3339), halfLife, now), 1e-9)
+	assert.Zero(t, recencyScore("", halfLife, now))
+	assert.Zero(t, recencyScore("not-a-date", halfLife, now))
+}
+
+func TestFuseReciprocalRank(t *testing.T) {
+	vector := []models.VulnResult{
+		{CVEID: "CVE-A", Score: 0.9},
+		{CVEID: "CVE-B", Score: 0.8},
+	}
+	keyword := []models.VulnResult{
+		{CVEID: "CVE-C"},
+		{CVEID: "CVE-B"},
+	}
+
+	fused := fuseReciprocalRank(vector, keyword)
+
+	require.Len(t, fused, 3)
+	assert.Equal(t, "CVE-B", fused[0].CVEID)
+	assert.Equal(t, 0.8, fused[0].Score)
+	assert.Equal(t, "CVE-A", fused[1].CVEID)
+	assert.Equal(t, "CVE-C", fused[2].CVEID)
+
+	assert.Empty(t, fuseReciprocalRank(nil, nil))
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Scan payload schema versions a submission may declare
+// Both are JSON lines with one open port per record; structured/v1 records
+// also carry the service a fingerprinting scanner identified on the port.
+const (
+	ScanSchemaNaabuV1      = "naabu/v1"
+	ScanSchemaStructuredV1 = "structured/v1"
+)
+
+// ErrUnsupportedScanSchema is returned for a schema version the server doesn't know
+var ErrUnsupportedScanSchema = errors.New("unsupported scan schema version")
+
+// ScanSchemaError reports the first place scan data departs from its declared schema
+type ScanSchemaError struct {
+	Schema string
+	Line   int    // 1-based line of the offending record
+	Path   string // JSON pointer within the record, e.g. /service/name; empty for the record itself
+	Reason string
+}
+
+func (e *ScanSchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d does not match schema %s: %s", e.Line, e.Schema, e.Reason)
+	}
+	return fmt.Sprintf("line %d %s does not match schema %s: %s", e.Line, e.Path, e.Schema, e.Reason)
+}
+
+// ValidateScanSchema checks every record in JSON lines scan data against a declared
+// schema version. An empty version skips validation, for scanners that predate
+// schema versions; their records are checked by the ingest workflow as before.
+func ValidateScanSchema(version string, data []byte) error {
+	switch version {
+	case "":
+		return nil
+	case ScanSchemaNaabuV1, ScanSchemaStructuredV1:
+	default:
+		return fmt.Errorf("%w %q: expected %s or %s", ErrUnsupportedScanSchema, version, ScanSchemaNaabuV1, ScanSchemaStructuredV1)
+	}
+
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if path, reason := validateScanRecord(version, line); reason != "" {
+			return &ScanSchemaError{Schema: version, Line: i + 1, Path: path, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// validateScanRecord checks one record, returning the path and reason of the
+// first violation or an empty reason when the record is valid. Fields the
+// schema doesn't name (naabu also emits ip, tls, ...) are allowed.
+func validateScanRecord(version string, line []byte) (string, string) {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(line, &record); err != nil {
+		return "", "record must be a JSON object"
+	}
+
+	if reason := requireString(record["host"], true); reason != "" {
+		return "/host", reason
+	}
+	if reason := validatePort(record["port"]); reason != "" {
+		return "/port", reason
+	}
+	if raw, ok := record["protocol"]; ok {
+		var protocol string
+		if json.Unmarshal(raw, &protocol) != nil || (protocol != "tcp" && protocol != "udp") {
+			return "/protocol", `must be "tcp" or "udp"`
+		}
+	}
+	if raw, ok := record["timestamp"]; ok {
+		var timestamp string
+		if json.Unmarshal(raw, &timestamp) != nil {
+			return "/timestamp", "must be an RFC 3339 string"
+		}
+		if _, err := time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			return "/timestamp", "must be an RFC 3339 string"
+		}
+	}
+
+	service, hasService := record["service"]
+	switch {
+	case version == ScanSchemaNaabuV1 && hasService:
+		return "/service", fmt.Sprintf("is not part of %s; declare %s for fingerprinted services", ScanSchemaNaabuV1, ScanSchemaStructuredV1)
+	case version == ScanSchemaStructuredV1 && !hasService:
+		return "/service", "is required"
+	case version == ScanSchemaStructuredV1:
+		return validateService(service)
+	}
+	return "", ""
+}
+
+// validatePort checks that raw is an integer port number
+func validatePort(raw json.RawMessage) string {
+	if raw == nil {
+		return "is required"
+	}
+	var port float64
+	if json.Unmarshal(raw, &port) != nil || port != math.Trunc(port) || port < 1 || port > 65535 {
+		return "must be an integer between 1 and 65535"
+	}
+	return ""
+}
+
+// validateService checks a structured/v1 service object
+func validateService(raw json.RawMessage) (string, string) {
+	var service map[string]json.RawMessage
+	if json.Unmarshal(raw, &service) != nil || service == nil {
+		return "/service", "must be an object"
+	}
+	for _, field := range []string{"name", "product", "version"} {
+		if reason := requireString(service[field], false); reason != "" {
+			return "/service/" + field, reason
+		}
+	}
+
+	var named struct {
+		Name    string `json:"name"`
+		Product string `json:"product"`
+	}
+	_ = json.Unmarshal(raw, &named)
+	if named.Name == "" && named.Product == "" {
+		return "/service", "must name a service or product"
+	}
+	return "", ""
+}
+
+// requireString checks that raw, if present, is a string, non-empty when required
+func requireString(raw json.RawMessage, required bool) string {
+	if raw == nil {
+		if required {
+			return "is required"
+		}
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return "must be a string"
+	}
+	if required && s == "" {
+		return "must not be empty"
+	}
+	return ""
+}
+
+// scanSchemaErrorResponse writes the 400 for scan data that failed ValidateScanSchema
+func scanSchemaErrorResponse(w http.ResponseWriter, err error) {
+	var schemaErr *ScanSchemaError
+	if !errors.As(err, &schemaErr) {
+		ingestErrorResponse(w, "unsupported_schema_version", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeCodedError(w, CodedErrorResponse{
+		Error:   "schema_validation_failed",
+		Message: err.Error(),
+		Line:    schemaErr.Line,
+		Path:    schemaErr.Path,
+	}, http.StatusBadRequest)
+}
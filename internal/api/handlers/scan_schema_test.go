@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const structuredScan = `{"host":"198.51.100.7","port":80,"protocol":"tcp","service":{"name":"http","product":"nginx","version":"1.24.0"}}
+{"host":"198.51.100.7","port":22,"service":{"name":"ssh"}}
+`
+
+func TestValidateScanSchema_Valid(t *testing.T) {
+	naabu := `{"host":"198.51.100.7","ip":"198.51.100.7","port":443,"protocol":"tcp","tls":true,"timestamp":"2026-01-02T03:04:05.123Z"}` + "\n\n"
+
+	assert.NoError(t, ValidateScanSchema(ScanSchemaNaabuV1, []byte(naabu)), "naabu's extra fields are allowed")
+	assert.NoError(t, ValidateScanSchema(ScanSchemaStructuredV1, []byte(structuredScan)))
+	assert.NoError(t, ValidateScanSchema("", []byte("not json")), "undeclared schemas are not validated")
+}
+
+func TestValidateScanSchema_UnsupportedVersion(t *testing.T) {
+	err := ValidateScanSchema("nmap/v7", []byte(trustedScan))
+	assert.True(t, errors.Is(err, ErrUnsupportedScanSchema), err)
+}
+
+func TestValidateScanSchema_MismatchedSchema(t *testing.T) {
+	// Fingerprinted records declared as plain naabu output
+	err := ValidateScanSchema(ScanSchemaNaabuV1, []byte(structuredScan))
+
+	var schemaErr *ScanSchemaError
+	require.True(t, errors.As(err, &schemaErr), err)
+	assert.Equal(t, 1, schemaErr.Line)
+	assert.Equal(t, "/service", schemaErr.Path)
+	assert.Contains(t, schemaErr.Reason, ScanSchemaStructuredV1)
+
+	// Plain naabu output declared as structured
+	err = ValidateScanSchema(ScanSchemaStructuredV1, []byte(trustedScan))
+	require.True(t, errors.As(err, &schemaErr), err)
+	assert.Equal(t, 1, schemaErr.Line)
+	assert.Equal(t, "/service", schemaErr.Path)
+	assert.Equal(t, "is required", schemaErr.Reason)
+}
+
+func TestValidateScanSchema_Malformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		data     string
+		wantLine int
+		wantPath string
+	}{
+		{"not an object", ScanSchemaNaabuV1, `["198.51.100.7", 80]`, 1, ""},
+		{"missing host", ScanSchemaNaabuV1, `{"port":80}`, 1, "/host"},
+		{"empty host", ScanSchemaNaabuV1, `{"host":"","port":80}`, 1, "/host"},
+		{"port as string", ScanSchemaNaabuV1, `{"host":"198.51.100.7","port":80}` + "\n" + `{"host":"198.51.100.7","port":"443"}`, 2, "/port"},
+		{"fractional port", ScanSchemaNaabuV1, `{"host":"198.51.100.7","port":80.5}`, 1, "/port"},
+		{"port out of range", ScanSchemaNaabuV1, `{"host":"198.51.100.7","port":70000}`, 1, "/port"},
+		{"unknown protocol", ScanSchemaNaabuV1, `{"host":"198.51.100.7","port":80,"protocol":"sctp"}`, 1, "/protocol"},
+		{"bad timestamp", ScanSchemaNaabuV1, `{"host":"198.51.100.7","port":80,"timestamp":"yesterday"}`, 1, "/timestamp"},
+		{"service not an object", ScanSchemaStructuredV1, `{"host":"198.51.100.7","port":80,"service":"http"}`, 1, "/service"},
+		{"service version not a string", ScanSchemaStructuredV1, `{"host":"198.51.100.7","port":80,"service":{"name":"http","version":1.24}}`, 1, "/service/version"},
+		{"service names nothing", ScanSchemaStructuredV1, `{"host":"198.51.100.7","port":80,"service":{"version":"1.24.0"}}`, 1, "/service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateScanSchema(tt.schema, []byte(tt.data))
+
+			var schemaErr *ScanSchemaError
+			require.True(t, errors.As(err, &schemaErr), err)
+			assert.Equal(t, tt.wantLine, schemaErr.Line)
+			assert.Equal(t, tt.wantPath, schemaErr.Path)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// ScannerStatsSource aggregates a scanner's jobs into contribution stats
+type ScannerStatsSource interface {
+	// Stats returns nil when the scanner has no jobs
+	Stats(ctx context.Context, scannerID string) (*models.ScannerStats, error)
+}
+
+// ScannerStatsHandler handles GET /v1/scanners/{key}/stats
+// {key} is the scanner's ScannerID, the hex SHA-256 of its public key, so the
+// key itself never appears in URLs or access logs.
+func ScannerStatsHandler(source ScannerStatsSource, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		scannerID, ok := models.NormalizeScannerID(chi.URLParam(r, "key"))
+		if !ok {
+			jobErrorResponse(w, "invalid_parameter", "key must be the hex SHA-256 of a scanner public key", http.StatusBadRequest)
+			return
+		}
+
+		stats, err := source.Stats(ctx, scannerID)
+		if err != nil {
+			logger.Error("failed to get scanner stats",
+				zap.Error(err),
+				zap.String("scanner_id", scannerID))
+			jobErrorResponse(w, "internal_error", "Failed to get scanner stats", http.StatusInternalServerError)
+			return
+		}
+		if stats == nil {
+			jobErrorResponse(w, "not_found", "No jobs from this scanner", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			logger.Error("failed to encode scanner stats",
+				zap.Error(err),
+				zap.String("scanner_id", scannerID))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeScannerStats serves seeded stats keyed by scanner ID
+type fakeScannerStats struct {
+	stats map[string]models.ScannerStats
+	err   error
+}
+
+func (f *fakeScannerStats) Stats(ctx context.Context, scannerID string) (*models.ScannerStats, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	stats, ok := f.stats[scannerID]
+	if !ok {
+		return nil, nil
+	}
+	return &stats, nil
+}
+
+// serveScannerStats routes a GET for path through the scanner stats handler
+func serveScannerStats(source ScannerStatsSource, path string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/v1/scanners/{key}/stats", ScannerStatsHandler(source, zap.NewNop()))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestScannerStatsHandler(t *testing.T) {
+	alice := models.ScannerID("alice")
+	rate := 0.5
+	source := &fakeScannerStats{stats: map[string]models.ScannerStats{
+		alice: {ScannerID: alice, Jobs: 4, Completed: 1, Failed: 1, Hosts: 10, Ports: 25, SuccessRate: &rate},
+	}}
+
+	t.Run("found", func(t *testing.T) {
+		rec := serveScannerStats(source, "/v1/scanners/"+alice+"/stats")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var stats models.ScannerStats
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+		assert.Equal(t, source.stats[alice], stats)
+	})
+
+	t.Run("uppercase id", func(t *testing.T) {
+		rec := serveScannerStats(source, "/v1/scanners/"+strings.ToUpper(alice)+"/stats")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("no jobs", func(t *testing.T) {
+		rec := serveScannerStats(source, "/v1/scanners/"+models.ScannerID("bob")+"/stats")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("raw key", func(t *testing.T) {
+		rec := serveScannerStats(source, "/v1/scanners/alice/stats")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		rec := serveScannerStats(&fakeScannerStats{err: errors.New("connection refused")}, "/v1/scanners/"+alice+"/stats")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
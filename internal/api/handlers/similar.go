@@ -4,30 +4,98 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/embeddings"
 	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// EmbeddingGenerator generates embedding vectors for query text
+type EmbeddingGenerator interface {
+	GenerateEmbedding(ctx context.Context, query string) ([]float64, error)
+}
+
+// VectorSearcher performs vector similarity search over vulnerability documents
+type VectorSearcher interface {
+	VectorSearch(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error)
+}
+
+// KeywordSearcher performs keyword search over vulnerability documents
+// Vector clients that also implement it enable the keyword and hybrid modes.
+type KeywordSearcher interface {
+	KeywordSearch(ctx context.Context, params db.KeywordSearchParams) ([]models.VulnResult, error)
+}
+
+// SimilarConfig holds the request limits enforced by the similarity search handler
+type SimilarConfig struct {
+	// MaxK is the maximum number of results a request may ask for
+	MaxK int
+
+	// MaxQueryLength is the maximum query string length
+	MaxQueryLength int
+
+	// RerankWeights controls result reranking when a request sets rerank
+	RerankWeights RerankWeights
+
+	// MaxBodyBytes caps the request body size
+	MaxBodyBytes int64
+}
+
+// DefaultSimilarConfig returns the default similarity search limits
+func DefaultSimilarConfig() SimilarConfig {
+	return SimilarConfig{
+		MaxK:           models.MaxK,
+		MaxQueryLength: models.MaxQueryLength,
+		RerankWeights:  DefaultRerankWeights(),
+		MaxBodyBytes:   DefaultQueryMaxBodyBytes,
+	}
+}
+
 // SimilarHandler handles similarity search requests for vulnerability documents
 type SimilarHandler struct {
-	embeddingClient *embeddings.Client
-	vectorClient    *db.VectorSearchClient
+	embeddingClient EmbeddingGenerator
+	vectorClient    VectorSearcher
+	keywordClient   KeywordSearcher
+	config          SimilarConfig
 	logger          *zap.Logger
 }
 
-// NewSimilarHandler creates a new similarity search handler
-func NewSimilarHandler(embeddingClient *embeddings.Client, vectorClient *db.VectorSearchClient, logger *zap.Logger) *SimilarHandler {
+// NewSimilarHandler creates a new similarity search handler with the default limits
+func NewSimilarHandler(embeddingClient EmbeddingGenerator, vectorClient VectorSearcher, logger *zap.Logger) *SimilarHandler {
+	return NewSimilarHandlerWithConfig(embeddingClient, vectorClient, DefaultSimilarConfig(), logger)
+}
+
+// NewSimilarHandlerWithConfig creates a new similarity search handler with the given limits
+// Non-positive limits fall back to the defaults
+func NewSimilarHandlerWithConfig(embeddingClient EmbeddingGenerator, vectorClient VectorSearcher, config SimilarConfig, logger *zap.Logger) *SimilarHandler {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	if config.MaxK <= 0 {
+		config.MaxK = models.MaxK
+	}
+	if config.MaxQueryLength <= 0 {
+		config.MaxQueryLength = models.MaxQueryLength
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultQueryMaxBodyBytes
+	}
+	if config.RerankWeights == (RerankWeights{}) {
+		config.RerankWeights = DefaultRerankWeights()
+	}
+	keywordClient, _ := vectorClient.(KeywordSearcher)
 	return &SimilarHandler{
 		embeddingClient: embeddingClient,
 		vectorClient:    vectorClient,
+		keywordClient:   keywordClient,
+		config:          config,
 		logger:          logger,
 	}
 }
@@ -44,7 +112,13 @@ func (h *SimilarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req models.SimilarRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSONBody(w, r, h.config.MaxBodyBytes, &req); err != nil {
+		if isBodyTooLarge(err) {
+			h.logger.Warn("request body too large",
+				zap.Int64("max_body_bytes", h.config.MaxBodyBytes))
+			h.writeError(w, "request body too large", http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", h.config.MaxBodyBytes))
+			return
+		}
 		h.logger.Warn("failed to decode request",
 			zap.Error(err))
 		h.writeError(w, "invalid request body", http.StatusBadRequest, err.Error())
@@ -52,18 +126,21 @@ func (h *SimilarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate request
-	if err := req.Validate(); err != nil {
+	if err := req.ValidateWithLimits(h.config.MaxK, h.config.MaxQueryLength); err != nil {
 		h.logger.Warn("request validation failed",
 			zap.Error(err),
 			zap.String("query", req.Query))
-		h.writeError(w, "validation error", http.StatusBadRequest, err.Error())
+		h.writeValidationError(w, err)
 		return
 	}
 
 	// Log the request
 	h.logger.Info("processing similarity search",
 		zap.String("query", req.Query),
-		zap.Int("k", req.GetK()))
+		zap.Int("k", req.GetK()),
+		zap.Int("offset", req.Offset),
+		zap.String("mode", string(req.GetMode())),
+		zap.Bool("rerank", req.Rerank))
 
 	// Execute similarity search
 	results, err := h.executeSimilaritySearch(ctx, req)
@@ -72,32 +149,95 @@ func (h *SimilarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optionally blend similarity with severity, exploitation and recency signals
+	_, span := tracing.Start(ctx, "similar.format",
+		attribute.Int("results", len(results)),
+		attribute.Bool("rerank", req.Rerank))
+	results, belowMinScore := applyMinScore(results, req.MinScore)
+	if req.Rerank {
+		rerankResults(results, h.config.RerankWeights, time.Now())
+	}
+
 	// Build response
+	page, hasMore := pageResults(results, req.Offset, req.GetK())
 	response := models.SimilarResponse{
-		Query:     req.Query,
-		Results:   results,
-		Count:     len(results),
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Query:         req.Query,
+		Results:       page,
+		Count:         len(page),
+		Offset:        req.Offset,
+		HasMore:       hasMore,
+		MinScore:      req.MinScore,
+		BelowMinScore: belowMinScore,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+	}
+	if hasMore {
+		next := req.Offset + len(page)
+		response.NextOffset = &next
 	}
 
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
 		h.logger.Error("failed to encode response",
 			zap.Error(err))
 	}
+	tracing.End(span, err)
 
 	h.logger.Info("similarity search completed",
 		zap.String("query", req.Query),
-		zap.Int("results", len(results)))
+		zap.Int("results", len(page)),
+		zap.Int("below_min_score", belowMinScore),
+		zap.Bool("has_more", hasMore))
+}
+
+// applyMinScore drops results whose similarity score is below minScore and
+// returns the rest with how many were dropped. A zero minScore keeps everything.
+func applyMinScore(results []models.VulnResult, minScore float64) ([]models.VulnResult, int) {
+	if minScore <= 0 {
+		return results, 0
+	}
+	kept := make([]models.VulnResult, 0, len(results))
+	for _, r := range results {
+		if r.Score >= minScore {
+			kept = append(kept, r)
+		}
+	}
+	return kept, len(results) - len(kept)
+}
+
+// pageResults returns up to k results starting at offset, and whether any follow
+// The search window over-fetches by one, so a result past the page means more exist.
+func pageResults(results []models.VulnResult, offset, k int) ([]models.VulnResult, bool) {
+	if offset >= len(results) {
+		return []models.VulnResult{}, false
+	}
+	end := min(offset+k, len(results))
+	return results[offset:end], end < len(results)
 }
 
-// executeSimilaritySearch performs the complete similarity search workflow
+// executeSimilaritySearch runs the search strategy selected by the request mode
 func (h *SimilarHandler) executeSimilaritySearch(ctx context.Context, req models.SimilarRequest) ([]models.VulnResult, error) {
+	switch req.GetMode() {
+	case models.SearchModeKeyword:
+		return h.executeKeywordSearch(ctx, req)
+	case models.SearchModeHybrid:
+		return h.executeHybridSearch(ctx, req)
+	default:
+		return h.executeVectorSearch(ctx, req)
+	}
+}
+
+// executeVectorSearch performs the complete embedding + vector similarity search workflow
+func (h *SimilarHandler) executeVectorSearch(ctx context.Context, req models.SimilarRequest) ([]models.VulnResult, error) {
 	// Step 1: Generate embedding from query text
-	embedding, err := h.embeddingClient.GenerateEmbedding(ctx, req.Query)
+	embedCtx, span := tracing.Start(ctx, "similar.embedding",
+		attribute.Int("query_length", len(req.Query)))
+	embedding, err := h.embeddingClient.GenerateEmbedding(embedCtx, req.Query)
+	span.SetAttributes(attribute.Int("dimension", len(embedding)))
+	tracing.End(span, err)
 	if err != nil {
 		h.logger.Error("failed to generate embedding",
 			zap.Error(err),
@@ -110,11 +250,15 @@ func (h *SimilarHandler) executeSimilaritySearch(ctx context.Context, req models
 		zap.String("query", req.Query))
 
 	// Step 2: Perform vector similarity search
-	results, err := h.vectorClient.VectorSearch(ctx, db.VectorSearchParams{
+	searchCtx, span := tracing.Start(ctx, "similar.vector_search",
+		attribute.Int("k", req.SearchWindow()))
+	results, err := h.vectorClient.VectorSearch(searchCtx, db.VectorSearchParams{
 		QueryEmbedding: embedding,
-		K:              req.GetK(),
-		MinScore:       0.0, // No minimum score filter for now
+		K:              req.SearchWindow(),
+		MinScore:       0.0, // The score floor is applied by the handler so dropped results can be counted
 	})
+	span.SetAttributes(attribute.Int("results", len(results)))
+	endSearchSpan(span, err)
 
 	if err != nil {
 		if errors.Is(err, db.ErrNoResults) {
@@ -129,6 +273,67 @@ func (h *SimilarHandler) executeSimilaritySearch(ctx context.Context, req models
 	return results, nil
 }
 
+// endSearchSpan ends a search step's span; finding nothing is not an error
+func endSearchSpan(span trace.Span, err error) {
+	if errors.Is(err, db.ErrNoResults) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// executeKeywordSearch matches CVE IDs and title/summary text
+func (h *SimilarHandler) executeKeywordSearch(ctx context.Context, req models.SimilarRequest) ([]models.VulnResult, error) {
+	if h.keywordClient == nil {
+		return nil, fmt.Errorf("%w: keyword search is not supported by the search backend", db.ErrDatabaseUnavailable)
+	}
+
+	searchCtx, span := tracing.Start(ctx, "similar.keyword_search",
+		attribute.Int("k", req.SearchWindow()))
+	results, err := h.keywordClient.KeywordSearch(searchCtx, db.KeywordSearchParams{
+		Query: req.Query,
+		K:     req.SearchWindow(),
+	})
+	span.SetAttributes(attribute.Int("results", len(results)))
+	endSearchSpan(span, err)
+	if err != nil {
+		if errors.Is(err, db.ErrNoResults) {
+			h.logger.Info("no keyword matches found",
+				zap.String("query", req.Query))
+			return []models.VulnResult{}, nil
+		}
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// executeHybridSearch runs vector and keyword searches and fuses their rankings
+// If one search fails the other's results are returned; if both fail the vector error is returned.
+func (h *SimilarHandler) executeHybridSearch(ctx context.Context, req models.SimilarRequest) ([]models.VulnResult, error) {
+	vectorResults, vectorErr := h.executeVectorSearch(ctx, req)
+	keywordResults, keywordErr := h.executeKeywordSearch(ctx, req)
+
+	switch {
+	case vectorErr != nil && keywordErr != nil:
+		return nil, vectorErr
+	case vectorErr != nil:
+		h.logger.Warn("vector search failed, using keyword results only",
+			zap.Error(vectorErr),
+			zap.String("query", req.Query))
+	case keywordErr != nil:
+		h.logger.Warn("keyword search failed, using vector results only",
+			zap.Error(keywordErr),
+			zap.String("query", req.Query))
+	}
+
+	fused := fuseReciprocalRank(vectorResults, keywordResults)
+	if len(fused) > req.SearchWindow() {
+		fused = fused[:req.SearchWindow()]
+	}
+
+	return fused, nil
+}
+
 // handleSearchError handles errors from the search operation with graceful fallback
 func (h *SimilarHandler) handleSearchError(w http.ResponseWriter, err error, query string) {
 	// Check error type and provide appropriate response
@@ -207,6 +412,8 @@ func (h *SimilarHandler) writeError(w http.ResponseWriter, message string, statu
 		response.Code = "INTERNAL_ERROR"
 	case http.StatusMethodNotAllowed:
 		response.Code = "METHOD_NOT_ALLOWED"
+	case http.StatusRequestEntityTooLarge:
+		response.Code = "REQUEST_TOO_LARGE"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -218,9 +425,32 @@ func (h *SimilarHandler) writeError(w http.ResponseWriter, message string, statu
 	}
 }
 
+// writeValidationError writes a 400 response carrying field-level validation details
+func (h *SimilarHandler) writeValidationError(w http.ResponseWriter, err error) {
+	response := models.ErrorResponse{
+		Error:     "validation error",
+		Code:      "BAD_REQUEST",
+		Details:   err.Error(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		response.Errors = []models.ValidationError{*validationErr}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode error response",
+			zap.Error(err))
+	}
+}
+
 // SimilarHandlerFunc creates a handler function for similarity search
 // This is a convenience function for route registration
-func SimilarHandlerFunc(embeddingClient *embeddings.Client, vectorClient *db.VectorSearchClient, logger *zap.Logger) http.HandlerFunc {
+func SimilarHandlerFunc(embeddingClient EmbeddingGenerator, vectorClient VectorSearcher, logger *zap.Logger) http.HandlerFunc {
 	handler := NewSimilarHandler(embeddingClient, vectorClient, logger)
 	return handler.ServeHTTP
 }
@@ -5,15 +5,24 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/spectra-red/recon/internal/api/middleware"
 	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/embeddings"
 	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -36,7 +45,16 @@ func (m *MockEmbeddingClient) GenerateEmbedding(ctx context.Context, query strin
 
 // MockVectorClient mocks the vector search client for testing
 type MockVectorClient struct {
-	SearchFunc func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error)
+	SearchFunc        func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error)
+	KeywordSearchFunc func(ctx context.Context, params db.KeywordSearchParams) ([]models.VulnResult, error)
+}
+
+func (m *MockVectorClient) KeywordSearch(ctx context.Context, params db.KeywordSearchParams) ([]models.VulnResult, error) {
+	if m.KeywordSearchFunc != nil {
+		return m.KeywordSearchFunc(ctx, params)
+	}
+	// Default: no keyword matches
+	return nil, db.ErrNoResults
 }
 
 func (m *MockVectorClient) VectorSearch(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
@@ -102,23 +120,29 @@ func TestSimilarHandler_ValidationErrors(t *testing.T) {
 	handler := NewSimilarHandler(&MockEmbeddingClient{}, &MockVectorClient{}, logger)
 
 	tests := []struct {
-		name        string
-		request     models.SimilarRequest
-		expectedErr string
+		name          string
+		request       models.SimilarRequest
+		expectedErr   string
+		expectedField string
+		expectedRule  string
 	}{
 		{
 			name: "empty query",
 			request: models.SimilarRequest{
 				Query: "",
 			},
-			expectedErr: "query cannot be empty",
+			expectedErr:   "query cannot be empty",
+			expectedField: "query",
+			expectedRule:  models.RuleRequired,
 		},
 		{
 			name: "query too long",
 			request: models.SimilarRequest{
 				Query: string(make([]byte, 501)),
 			},
-			expectedErr: "query exceeds maximum length",
+			expectedErr:   "query exceeds maximum length",
+			expectedField: "query",
+			expectedRule:  models.RuleMaxLength,
 		},
 		{
 			name: "invalid K (negative)",
@@ -126,7 +150,9 @@ func TestSimilarHandler_ValidationErrors(t *testing.T) {
 				Query: "test query",
 				K:     ptr(-1),
 			},
-			expectedErr: "k must be greater than 0",
+			expectedErr:   "k must be greater than 0",
+			expectedField: "k",
+			expectedRule:  models.RuleMin,
 		},
 		{
 			name: "K too large",
@@ -134,7 +160,9 @@ func TestSimilarHandler_ValidationErrors(t *testing.T) {
 				Query: "test query",
 				K:     ptr(100),
 			},
-			expectedErr: "k exceeds maximum allowed value",
+			expectedErr:   "k exceeds maximum allowed value",
+			expectedField: "k",
+			expectedRule:  models.RuleMax,
 		},
 	}
 
@@ -152,6 +180,13 @@ func TestSimilarHandler_ValidationErrors(t *testing.T) {
 			err := json.NewDecoder(w.Body).Decode(&errResp)
 			require.NoError(t, err)
 			assert.Contains(t, errResp.Details, tt.expectedErr)
+			assert.Equal(t, "validation error", errResp.Error)
+			assert.Equal(t, "BAD_REQUEST", errResp.Code)
+
+			require.Len(t, errResp.Errors, 1)
+			assert.Equal(t, tt.expectedField, errResp.Errors[0].Field)
+			assert.Equal(t, tt.expectedRule, errResp.Errors[0].Rule)
+			assert.Equal(t, tt.expectedErr, errResp.Errors[0].Message)
 		})
 	}
 }
@@ -195,7 +230,7 @@ func TestSimilarHandler_SuccessfulSearch(t *testing.T) {
 		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
 			// Verify parameters
 			assert.Equal(t, 1536, len(params.QueryEmbedding))
-			assert.Equal(t, 10, params.K)
+			assert.Equal(t, 11, params.K) // default k plus one to detect another page
 			return mockResults, nil
 		},
 	}
@@ -234,7 +269,7 @@ func TestSimilarHandler_CustomK(t *testing.T) {
 	mockVector := &MockVectorClient{
 		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
 			// Verify K parameter
-			assert.Equal(t, 20, params.K)
+			assert.Equal(t, 21, params.K) // k plus one to detect another page
 			return []models.VulnResult{}, nil
 		},
 	}
@@ -436,7 +471,563 @@ func TestSimilarHandlerFunc(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestSimilarHandler_ConfigurableLimits(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	mockVector := &MockVectorClient{
+		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+			return []models.VulnResult{}, nil
+		},
+	}
+
+	defaultHandler := NewSimilarHandler(&MockEmbeddingClient{}, mockVector, logger)
+	researchHandler := NewSimilarHandlerWithConfig(&MockEmbeddingClient{}, mockVector, SimilarConfig{
+		MaxK:           200,
+		MaxQueryLength: 2000,
+	}, logger)
+
+	tests := []struct {
+		name       string
+		handler    *SimilarHandler
+		request    models.SimilarRequest
+		wantStatus int
+	}{
+		{
+			name:       "default rejects k=100",
+			handler:    defaultHandler,
+			request:    models.SimilarRequest{Query: "test query", K: ptr(100)},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "raised MaxK accepts k=100",
+			handler:    researchHandler,
+			request:    models.SimilarRequest{Query: "test query", K: ptr(100)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "raised MaxK still rejects k above it",
+			handler:    researchHandler,
+			request:    models.SimilarRequest{Query: "test query", K: ptr(201)},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "default rejects 1000-char query",
+			handler:    defaultHandler,
+			request:    models.SimilarRequest{Query: string(bytes.Repeat([]byte("a"), 1000))},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "raised MaxQueryLength accepts 1000-char query",
+			handler:    researchHandler,
+			request:    models.SimilarRequest{Query: string(bytes.Repeat([]byte("a"), 1000))},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.request)
+			req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestSimilarHandler_MaxBodyBytes(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	const limit = 256
+
+	body, err := json.Marshal(models.SimilarRequest{Query: "remote code execution"})
+	require.NoError(t, err)
+	padTo := func(size int) []byte {
+		require.LessOrEqual(t, len(body), size)
+		return append(append([]byte{}, body...), bytes.Repeat([]byte(" "), size-len(body))...)
+	}
+
+	mockVector := &MockVectorClient{
+		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+			return []models.VulnResult{}, nil
+		},
+	}
+	handler := NewSimilarHandlerWithConfig(&MockEmbeddingClient{}, mockVector, SimilarConfig{MaxBodyBytes: limit}, logger)
+
+	tests := []struct {
+		name       string
+		size       int
+		wantStatus int
+	}{
+		{name: "just under", size: limit - 1, wantStatus: http.StatusOK},
+		{name: "just over", size: limit + 1, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewReader(padTo(tt.size)))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusRequestEntityTooLarge {
+				var errResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+				assert.Equal(t, "REQUEST_TOO_LARGE", errResp.Code)
+			}
+		})
+	}
+}
+
+func TestNewSimilarHandlerWithConfig_Defaults(t *testing.T) {
+	handler := NewSimilarHandlerWithConfig(&MockEmbeddingClient{}, &MockVectorClient{}, SimilarConfig{}, nil)
+
+	assert.Equal(t, DefaultSimilarConfig(), handler.config)
+	assert.NotNil(t, handler.logger)
+}
+
+func TestSimilarHandler_Rerank(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	// The old, never-exploited CVE is the closer vector match
+	mockResults := []models.VulnResult{
+		{
+			CVEID:         "CVE-2012-0001",
+			Title:         "Old similar vulnerability",
+			CVSS:          3.1,
+			EPSS:          0.01,
+			PublishedDate: "2012-01-15T00:00:00Z",
+			Score:         0.95,
+		},
+		{
+			CVEID:         "CVE-2024-0002",
+			Title:         "Actively exploited vulnerability",
+			CVSS:          9.8,
+			EPSS:          0.9,
+			KEV:           true,
+			PublishedDate: time.Now().UTC().AddDate(0, -1, 0).Format(time.RFC3339),
+			Score:         0.80,
+		},
+	}
+
+	newHandler := func() *SimilarHandler {
+		return NewSimilarHandler(&MockEmbeddingClient{}, &MockVectorClient{
+			SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+				results := make([]models.VulnResult, len(mockResults))
+				copy(results, mockResults)
+				return results, nil
+			},
+		}, logger)
+	}
+
+	search := func(t *testing.T, rerank bool) models.SimilarResponse {
+		body, _ := json.Marshal(models.SimilarRequest{Query: "remote code execution", Rerank: rerank})
+		req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		newHandler().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp models.SimilarResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		require.Len(t, resp.Results, 2)
+		return resp
+	}
+
+	t.Run("disabled keeps similarity order", func(t *testing.T) {
+		resp := search(t, false)
+
+		assert.Equal(t, "CVE-2012-0001", resp.Results[0].CVEID)
+		assert.Zero(t, resp.Results[0].FinalScore)
+		assert.Zero(t, resp.Results[1].FinalScore)
+	})
+
+	t.Run("enabled moves KEV/high-EPSS result first", func(t *testing.T) {
+		resp := search(t, true)
+
+		assert.Equal(t, "CVE-2024-0002", resp.Results[0].CVEID)
+		assert.Equal(t, "CVE-2012-0001", resp.Results[1].CVEID)
+		assert.Greater(t, resp.Results[0].FinalScore, resp.Results[1].FinalScore)
+
+		// Raw similarity scores are preserved alongside the final score
+		assert.Equal(t, 0.80, resp.Results[0].Score)
+		assert.Equal(t, 0.95, resp.Results[1].Score)
+	})
+}
+
+// keywordFixture is a small document set for keyword-matching tests
+var keywordFixture = []models.VulnResult{
+	{CVEID: "CVE-2021-44228", Title: "Apache Log4j2 JNDI remote code execution", Summary: "Log4Shell", CVSS: 10.0},
+	{CVEID: "CVE-2021-45046", Title: "Apache Log4j2 Thread Context lookup", Summary: "Incomplete fix for CVE-2021-44228", CVSS: 9.0},
+	{CVEID: "CVE-2023-44487", Title: "HTTP/2 Rapid Reset", Summary: "Denial of service in HTTP/2 servers", CVSS: 7.5},
+}
+
+// matchKeywordFixture mimics the keyword search: exact CVE ID or case-insensitive title/summary match
+func matchKeywordFixture(ctx context.Context, params db.KeywordSearchParams) ([]models.VulnResult, error) {
+	term := strings.ToLower(params.Query)
+	var matches []models.VulnResult
+	for _, doc := range keywordFixture {
+		if strings.ToLower(doc.CVEID) == term ||
+			strings.Contains(strings.ToLower(doc.Title), term) ||
+			strings.Contains(strings.ToLower(doc.Summary), term) {
+			matches = append(matches, doc)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, db.ErrNoResults
+	}
+	return matches, nil
+}
+
+func TestSimilarHandler_SearchModes(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	vectorResults := []models.VulnResult{
+		{CVEID: "CVE-2022-22965", Title: "Spring4Shell", Score: 0.91},
+		{CVEID: "CVE-2021-45046", Title: "Apache Log4j2 Thread Context lookup", Score: 0.88},
+	}
+
+	newHandler := func(embedCalled *bool, embedErr error) *SimilarHandler {
+		return NewSimilarHandler(&MockEmbeddingClient{
+			GenerateFunc: func(ctx context.Context, query string) ([]float64, error) {
+				*embedCalled = true
+				if embedErr != nil {
+					return nil, embedErr
+				}
+				return []float64{0.1, 0.2}, nil
+			},
+		}, &MockVectorClient{
+			SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+				results := make([]models.VulnResult, len(vectorResults))
+				copy(results, vectorResults)
+				return results, nil
+			},
+			KeywordSearchFunc: matchKeywordFixture,
+		}, logger)
+	}
+
+	search := func(t *testing.T, handler *SimilarHandler, request models.SimilarRequest) models.SimilarResponse {
+		body, _ := json.Marshal(request)
+		req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp models.SimilarResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return resp
+	}
+
+	cveIDs := func(results []models.VulnResult) []string {
+		ids := make([]string, len(results))
+		for i, r := range results {
+			ids[i] = r.CVEID
+		}
+		return ids
+	}
+
+	t.Run("default mode is vector", func(t *testing.T) {
+		embedCalled := false
+		resp := search(t, newHandler(&embedCalled, nil), models.SimilarRequest{Query: "CVE-2021-44228"})
+
+		assert.True(t, embedCalled)
+		assert.Equal(t, []string{"CVE-2022-22965", "CVE-2021-45046"}, cveIDs(resp.Results))
+	})
+
+	t.Run("keyword mode matches exact CVE ID without embedding", func(t *testing.T) {
+		embedCalled := false
+		resp := search(t, newHandler(&embedCalled, nil), models.SimilarRequest{Query: "CVE-2023-44487", Mode: models.SearchModeKeyword})
+
+		assert.False(t, embedCalled)
+		assert.Equal(t, []string{"CVE-2023-44487"}, cveIDs(resp.Results))
+	})
+
+	t.Run("keyword mode matches title text", func(t *testing.T) {
+		embedCalled := false
+		resp := search(t, newHandler(&embedCalled, nil), models.SimilarRequest{Query: "log4j", Mode: models.SearchModeKeyword})
+
+		assert.Equal(t, []string{"CVE-2021-44228", "CVE-2021-45046"}, cveIDs(resp.Results))
+	})
+
+	t.Run("hybrid fuses and dedupes", func(t *testing.T) {
+		embedCalled := false
+		resp := search(t, newHandler(&embedCalled, nil), models.SimilarRequest{Query: "log4j", Mode: models.SearchModeHybrid})
+
+		assert.True(t, embedCalled)
+		// CVE-2021-45046 appears in both lists so it ranks first, once
+		assert.Equal(t, []string{"CVE-2021-45046", "CVE-2022-22965", "CVE-2021-44228"}, cveIDs(resp.Results))
+		// The vector copy (with a similarity score) is kept for the duplicate
+		assert.Equal(t, 0.88, resp.Results[0].Score)
+	})
+
+	t.Run("hybrid respects k", func(t *testing.T) {
+		embedCalled := false
+		resp := search(t, newHandler(&embedCalled, nil), models.SimilarRequest{Query: "log4j", Mode: models.SearchModeHybrid, K: ptr(2)})
+
+		assert.Len(t, resp.Results, 2)
+	})
+
+	t.Run("hybrid falls back to keyword when embeddings are unavailable", func(t *testing.T) {
+		embedCalled := false
+		resp := search(t, newHandler(&embedCalled, embeddings.ErrServiceUnavailable), models.SimilarRequest{Query: "log4shell", Mode: models.SearchModeHybrid})
+
+		assert.Equal(t, []string{"CVE-2021-44228"}, cveIDs(resp.Results))
+	})
+}
+
+func TestSimilarHandler_InvalidMode(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	handler := NewSimilarHandler(&MockEmbeddingClient{}, &MockVectorClient{}, logger)
+
+	body, _ := json.Marshal(models.SimilarRequest{Query: "test", Mode: "fuzzy"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var errResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+	require.Len(t, errResp.Errors, 1)
+	assert.Equal(t, "mode", errResp.Errors[0].Field)
+	assert.Equal(t, models.RuleEnum, errResp.Errors[0].Rule)
+}
+
+// rankedVectorClient returns n results with descending scores, honouring the requested K
+func rankedVectorClient(n int, gotK *int) *MockVectorClient {
+	return &MockVectorClient{
+		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+			*gotK = params.K
+			results := make([]models.VulnResult, 0, n)
+			for i := 0; i < n && i < params.K; i++ {
+				results = append(results, models.VulnResult{
+					CVEID: fmt.Sprintf("CVE-2024-%04d", i),
+					Score: 0.99 - 0.01*float64(i),
+				})
+			}
+			return results, nil
+		},
+	}
+}
+
+func postSimilar(t *testing.T, handler *SimilarHandler, reqBody models.SimilarRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestSimilarHandler_OffsetPaging(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	tests := []struct {
+		name        string
+		offset      int
+		wantK       int
+		wantFirst   string
+		wantCount   int
+		wantHasMore bool
+		wantNext    *int
+	}{
+		{"first page", 0, 6, "CVE-2024-0000", 5, true, ptr(5)},
+		{"second page", 5, 11, "CVE-2024-0005", 5, true, ptr(10)},
+		{"last partial page", 10, 16, "CVE-2024-0010", 2, false, nil},
+		{"past the end", 20, 26, "", 0, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotK int
+			handler := NewSimilarHandler(&MockEmbeddingClient{}, rankedVectorClient(12, &gotK), logger)
+
+			w := postSimilar(t, handler, models.SimilarRequest{Query: "nginx", K: ptr(5), Offset: tt.offset})
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp models.SimilarResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+
+			assert.Equal(t, tt.wantK, gotK, "search window should cover the page plus one result")
+			assert.Equal(t, tt.offset, resp.Offset)
+			assert.Equal(t, tt.wantCount, resp.Count)
+			assert.Len(t, resp.Results, tt.wantCount)
+			assert.Equal(t, tt.wantHasMore, resp.HasMore)
+			assert.Equal(t, tt.wantNext, resp.NextOffset)
+			if tt.wantFirst != "" {
+				assert.Equal(t, tt.wantFirst, resp.Results[0].CVEID)
+			}
+		})
+	}
+}
+
+// TestSimilarHandler_RerankPaging checks reranked pages come from one window, so
+// they neither repeat nor skip results
+func TestSimilarHandler_RerankPaging(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	var windows []int
+	// Later, less similar results are more severe, so reranking reverses them
+	handler := NewSimilarHandler(&MockEmbeddingClient{}, &MockVectorClient{
+		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+			windows = append(windows, params.K)
+			results := make([]models.VulnResult, 0, 12)
+			for i := 0; i < 12 && i < params.K; i++ {
+				results = append(results, models.VulnResult{
+					CVEID: fmt.Sprintf("CVE-2024-%04d", i),
+					Score: 0.99 - 0.001*float64(i),
+					CVSS:  float64(i) * 0.8,
+				})
+			}
+			return results, nil
+		},
+	}, logger)
+
+	var seen []string
+	for offset := 0; ; {
+		w := postSimilar(t, handler, models.SimilarRequest{Query: "nginx", K: ptr(5), Offset: offset, Rerank: true})
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp models.SimilarResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+
+		for _, r := range resp.Results {
+			seen = append(seen, r.CVEID)
+		}
+		if !resp.HasMore {
+			break
+		}
+		offset = *resp.NextOffset
+	}
+
+	require.Len(t, seen, 12, "every result is paged through once")
+	assert.Equal(t, "CVE-2024-0011", seen[0], "the most severe result leads the first page")
+	assert.ElementsMatch(t, []int{models.MaxSearchWindow, models.MaxSearchWindow, models.MaxSearchWindow}, windows)
+	unique := map[string]bool{}
+	for _, id := range seen {
+		unique[id] = true
+	}
+	assert.Len(t, unique, 12, "no result appears on two pages")
+}
+
+func TestSimilarHandler_MinScore(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	var gotK int
+	// Scores run 0.99, 0.98, ... 0.90; a 0.95 floor keeps the first five
+	handler := NewSimilarHandler(&MockEmbeddingClient{}, rankedVectorClient(10, &gotK), logger)
+
+	w := postSimilar(t, handler, models.SimilarRequest{Query: "nginx", K: ptr(3), MinScore: 0.95})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp models.SimilarResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+
+	assert.Equal(t, 3, resp.Count)
+	assert.True(t, resp.HasMore)
+	assert.Equal(t, 0.95, resp.MinScore)
+	assert.Zero(t, resp.BelowMinScore, "the four-result window is all above the floor")
+
+	// A later page reaches past the floor; the dropped results are reported
+	w = postSimilar(t, handler, models.SimilarRequest{Query: "nginx", K: ptr(3), Offset: 3, MinScore: 0.95})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = models.SimilarResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+
+	assert.Equal(t, 2, resp.Count)
+	assert.False(t, resp.HasMore)
+	assert.Nil(t, resp.NextOffset)
+	assert.Equal(t, 2, resp.BelowMinScore)
+	for _, r := range resp.Results {
+		assert.GreaterOrEqual(t, r.Score, 0.95)
+	}
+}
+
+func TestSimilarHandler_InvalidPaging(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	handler := NewSimilarHandler(&MockEmbeddingClient{}, &MockVectorClient{}, logger)
+
+	tests := []struct {
+		name      string
+		req       models.SimilarRequest
+		wantField string
+	}{
+		{"negative offset", models.SimilarRequest{Query: "test", Offset: -1}, "offset"},
+		{"offset too large", models.SimilarRequest{Query: "test", Offset: models.MaxOffset + 1}, "offset"},
+		{"min score above one", models.SimilarRequest{Query: "test", MinScore: 1.5}, "min_score"},
+		{"negative min score", models.SimilarRequest{Query: "test", MinScore: -0.1}, "min_score"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postSimilar(t, handler, tt.req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var errResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+			require.Len(t, errResp.Errors, 1)
+			assert.Equal(t, tt.wantField, errResp.Errors[0].Field)
+		})
+	}
+}
+
 // Helper function to create int pointer
 func ptr(i int) *int {
 	return &i
 }
+
+func TestSimilarHandler_TracingSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	var embedTrace, searchTrace trace.TraceID
+	mockEmbed := &MockEmbeddingClient{
+		GenerateFunc: func(ctx context.Context, query string) ([]float64, error) {
+			embedTrace = trace.SpanContextFromContext(ctx).TraceID()
+			return make([]float64, 1536), nil
+		},
+	}
+	mockVector := &MockVectorClient{
+		SearchFunc: func(ctx context.Context, params db.VectorSearchParams) ([]models.VulnResult, error) {
+			searchTrace = trace.SpanContextFromContext(ctx).TraceID()
+			return []models.VulnResult{{CVEID: "CVE-2021-44228", Score: 0.9}}, nil
+		},
+	}
+
+	router := chi.NewRouter()
+	router.Use(middleware.Tracing())
+	router.Post("/v1/query/similar", NewSimilarHandler(mockEmbed, mockVector, zaptest.NewLogger(t)).ServeHTTP)
+
+	body, _ := json.Marshal(models.SimilarRequest{Query: "log4j remote code execution"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/query/similar", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	parent, ok := spans["POST /v1/query/similar"]
+	require.True(t, ok, "missing request span, got %v", spans)
+	assert.Equal(t, trace.SpanKindServer, parent.SpanKind())
+
+	for _, name := range []string{"similar.embedding", "similar.vector_search", "similar.format"} {
+		child, ok := spans[name]
+		if !assert.True(t, ok, "missing %s span", name) {
+			continue
+		}
+		assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID(), "%s should be a child of the request span", name)
+		assert.Equal(t, parent.SpanContext().TraceID(), child.SpanContext().TraceID())
+	}
+
+	// The request's trace reaches the embedding and search clients
+	assert.Equal(t, parent.SpanContext().TraceID(), embedTrace)
+	assert.Equal(t, parent.SpanContext().TraceID(), searchTrace)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"go.uber.org/zap"
+)
+
+// StatsResponse represents the operational stats returned by the stats endpoint
+type StatsResponse struct {
+	Ingest    IngestStats                `json:"ingest"`
+	RateLimit *middleware.RateLimitStats `json:"rate_limit,omitempty"`
+	Timestamp string                     `json:"timestamp"`
+}
+
+// IngestStats describes the current ingest queue depth
+type IngestStats struct {
+	InFlight    int `json:"in_flight"`
+	MaxInFlight int `json:"max_in_flight"`
+}
+
+// StatsHandler creates an HTTP handler for the /v1/stats endpoint
+// rateLimiter, if non-nil, is the ingest rate limiter whose request counts are reported.
+func StatsHandler(inFlight *middleware.InFlightLimiter, rateLimiter *middleware.RateLimiter, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := StatsResponse{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+
+		if inFlight != nil {
+			response.Ingest = IngestStats{
+				InFlight:    inFlight.Depth(),
+				MaxInFlight: inFlight.Max(),
+			}
+		}
+		if rateLimiter != nil {
+			stats := rateLimiter.Stats()
+			response.RateLimit = &stats
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.Error("failed to encode stats response",
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/db"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// TrustedIngestHandler serves POST /v1/internal/ingest, which accepts raw scan
+// data without a signed envelope and attributes it to a single configured
+// scanner key. It is meant for deployments on a trusted network and answers
+// 404 unless a scanner key is configured.
+type TrustedIngestHandler struct {
+	scannerKey   string
+	inFlight     *middleware.InFlightLimiter
+	maxBodyBytes int64
+	audit        AuditRecorder
+	jobs         IngestJobStarter
+	logger       *zap.Logger
+}
+
+// IngestJobStarter records a job for an accepted scan and hands it to the ingest workflow
+type IngestJobStarter interface {
+	CreateJob(ctx context.Context, scannerKey string) (*models.Job, error)
+	StartIngest(ctx context.Context, jobID string, req models.IngestWorkflowRequest) error
+}
+
+// NewTrustedIngestHandler creates the trusted ingest handler
+// An empty scannerKey disables the endpoint. inFlight, maxBodyBytes and audit behave
+// as for IngestHandler.
+func NewTrustedIngestHandler(logger *zap.Logger, dbClient *surrealdb.DB, restateURL, scannerKey string, inFlight *middleware.InFlightLimiter, maxBodyBytes int64, audit AuditRecorder) *TrustedIngestHandler {
+	jobs := &restateIngestStarter{db: dbClient, restateURL: restateURL, logger: logger}
+	return NewTrustedIngestHandlerWithJobs(logger, jobs, scannerKey, inFlight, maxBodyBytes, audit)
+}
+
+// NewTrustedIngestHandlerWithJobs creates the trusted ingest handler on a custom
+// job starter (useful for testing)
+func NewTrustedIngestHandlerWithJobs(logger *zap.Logger, jobs IngestJobStarter, scannerKey string, inFlight *middleware.InFlightLimiter, maxBodyBytes int64, audit AuditRecorder) *TrustedIngestHandler {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultIngestMaxBodyBytes
+	}
+
+	return &TrustedIngestHandler{
+		scannerKey:   scannerKey,
+		inFlight:     inFlight,
+		maxBodyBytes: maxBodyBytes,
+		audit:        audit,
+		jobs:         jobs,
+		logger:       logger,
+	}
+}
+
+// restateIngestStarter records jobs in the database and starts the workflow through the Restate ingress
+type restateIngestStarter struct {
+	db         *surrealdb.DB
+	restateURL string
+	logger     *zap.Logger
+}
+
+// CreateJob creates a pending job owned by scannerKey
+func (s *restateIngestStarter) CreateJob(ctx context.Context, scannerKey string) (*models.Job, error) {
+	return db.CreateJob(ctx, s.db, s.logger, scannerKey)
+}
+
+// StartIngest triggers the IngestWorkflow for jobID
+func (s *restateIngestStarter) StartIngest(ctx context.Context, jobID string, req models.IngestWorkflowRequest) error {
+	return triggerRestateWorkflow(ctx, s.restateURL, jobID, req, s.logger)
+}
+
+// Enabled reports whether a scanner key is configured
+func (h *TrustedIngestHandler) Enabled() bool {
+	return h.scannerKey != ""
+}
+
+// ServeHTTP handles POST /v1/internal/ingest
+// The body is raw scan output, one JSON record per line (e.g. naabu -json). The
+// optional ?source= names the tool that produced it, and ?schema_version= the
+// schema its records are validated against before a job is created.
+func (h *TrustedIngestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.Enabled() {
+		ingestErrorResponse(w, "not_found", "Trusted ingest is not enabled on this server", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Refuse new scans while the server drains for shutdown
+	if h.inFlight != nil && h.inFlight.Draining() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(h.inFlight.RetryAfter().Seconds())))
+		ingestErrorResponse(w, "shutting_down", "Server is shutting down, retry later", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodyBytes))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			ingestErrorResponse(w, "request_too_large", fmt.Sprintf("Request body exceeds %d bytes", h.maxBodyBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		ingestErrorResponse(w, "invalid_request", "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// The body is the scan itself, in the same JSON lines format a signed
+	// envelope carries; reject anything the ingest workflow could not parse
+	records, err := countScanRecords(body)
+	if err != nil {
+		h.logger.Warn("failed to parse trusted scan JSON",
+			zap.Error(err))
+		ingestErrorResponse(w, "invalid_json", "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if records == 0 {
+		ingestErrorResponse(w, "missing_data", "Scan contains no records", http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateScanSchema(r.URL.Query().Get("schema_version"), body); err != nil {
+		h.logger.Warn("trusted scan rejected by schema validation",
+			zap.Error(err))
+		scanSchemaErrorResponse(w, err)
+		return
+	}
+
+	source, err := models.NormalizeScanSource(r.URL.Query().Get("source"))
+	if err != nil {
+		ingestErrorResponse(w, "invalid_source", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Apply backpressure when the workflow backend is saturated
+	if h.inFlight != nil && !h.inFlight.TryAcquire() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(h.inFlight.RetryAfter().Seconds())))
+		ingestErrorResponse(w, "service_unavailable", "Ingest queue is full, retry later", http.StatusServiceUnavailable)
+		return
+	}
+
+	job, err := h.jobs.CreateJob(ctx, h.scannerKey)
+	if err != nil {
+		if h.inFlight != nil {
+			h.inFlight.Release()
+		}
+		h.logger.Error("failed to create job",
+			zap.Error(err),
+			zap.String("public_key", maskPublicKey(h.scannerKey)))
+		ingestErrorResponse(w, "internal_error", "Failed to create job", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("trusted scan received, job created",
+		zap.String("job_id", job.ID),
+		zap.String("public_key", maskPublicKey(h.scannerKey)),
+		zap.Int("records", records),
+		zap.String("source", source),
+		zap.Int("data_size", len(body)))
+
+	recordAudit(ctx, h.audit, r, models.AuditEntry{
+		Action:    models.AuditActionIngest,
+		Actor:     models.ScannerActor(h.scannerKey),
+		JobID:     job.ID,
+		HostCount: countScanHosts(body),
+	}, h.logger)
+
+	workflowReq := models.IngestWorkflowRequest{
+		JobID:      job.ID,
+		ScannerKey: h.scannerKey,
+		ScanData:   body,
+		Source:     source,
+	}
+
+	// Send to Restate (fire-and-forget)
+	go func() {
+		if h.inFlight != nil {
+			defer h.inFlight.Release()
+		}
+		if err := h.jobs.StartIngest(context.Background(), job.ID, workflowReq); err != nil {
+			h.logger.Error("failed to trigger workflow",
+				zap.Error(err),
+				zap.String("job_id", job.ID))
+		}
+	}()
+
+	response := IngestResponse{
+		JobID:     job.ID,
+		Status:    "accepted",
+		Message:   "Scan submitted successfully, processing asynchronously",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode response",
+			zap.Error(err),
+			zap.String("job_id", job.ID))
+	}
+}
+
+// countScanRecords returns the number of non-empty lines in a JSON lines scan,
+// failing on the first line that is not valid JSON
+func countScanRecords(body []byte) (int, error) {
+	records := 0
+	for i, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return records, fmt.Errorf("line %d is not valid JSON", i+1)
+		}
+		records++
+	}
+	return records, nil
+}
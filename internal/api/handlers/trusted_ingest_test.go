@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const trustedScan = `{"host":"10.0.0.5","port":22,"protocol":"tcp"}
+{"host":"10.0.0.5","port":443,"protocol":"tcp"}
+`
+
+// fakeIngestJobStarter creates jobs in memory and sends each started workflow
+// request to triggered
+type fakeIngestJobStarter struct {
+	triggered chan models.IngestWorkflowRequest
+}
+
+func (f *fakeIngestJobStarter) CreateJob(ctx context.Context, scannerKey string) (*models.Job, error) {
+	return &models.Job{ID: "job-1", ScannerKey: scannerKey}, nil
+}
+
+func (f *fakeIngestJobStarter) StartIngest(ctx context.Context, jobID string, req models.IngestWorkflowRequest) error {
+	f.triggered <- req
+	return nil
+}
+
+// newTestTrustedIngestHandler returns a handler whose job store and workflow
+// trigger are stubbed, and a channel receiving each triggered workflow request
+func newTestTrustedIngestHandler(t *testing.T, scannerKey string, inFlight *middleware.InFlightLimiter) (*TrustedIngestHandler, <-chan models.IngestWorkflowRequest) {
+	jobs := &fakeIngestJobStarter{triggered: make(chan models.IngestWorkflowRequest, 1)}
+	h := NewTrustedIngestHandlerWithJobs(zaptest.NewLogger(t), jobs, scannerKey, inFlight, 0, nil)
+	return h, jobs.triggered
+}
+
+func TestTrustedIngestHandler_Disabled(t *testing.T) {
+	h, triggered := newTestTrustedIngestHandler(t, "", nil)
+	require.False(t, h.Enabled())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest", strings.NewReader(trustedScan))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, triggered)
+}
+
+func TestTrustedIngestHandler_Accepted(t *testing.T) {
+	inFlight := middleware.NewInFlightLimiter(10, time.Second)
+	h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", inFlight)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest", strings.NewReader(trustedScan))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	var resp IngestResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "job-1", resp.JobID)
+	assert.Equal(t, "accepted", resp.Status)
+
+	select {
+	case workflowReq := <-triggered:
+		assert.Equal(t, "job-1", workflowReq.JobID)
+		assert.Equal(t, "internal-scanner", workflowReq.ScannerKey)
+		assert.Equal(t, trustedScan, string(workflowReq.ScanData))
+		assert.Equal(t, models.ScanSourceUnknown, workflowReq.Source)
+	case <-time.After(time.Second):
+		t.Fatal("workflow was not triggered")
+	}
+}
+
+func TestTrustedIngestHandler_Source(t *testing.T) {
+	h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest?source=Masscan", strings.NewReader(trustedScan))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	select {
+	case workflowReq := <-triggered:
+		assert.Equal(t, "masscan", workflowReq.Source)
+	case <-time.After(time.Second):
+		t.Fatal("workflow was not triggered")
+	}
+}
+
+func TestTrustedIngestHandler_InvalidSource(t *testing.T) {
+	h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest?source=not+a+tool", strings.NewReader(trustedScan))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errResp CodedErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+	assert.Equal(t, "invalid_source", errResp.Error)
+	assert.Empty(t, triggered)
+}
+
+func TestTrustedIngestHandler_RecordsAudit(t *testing.T) {
+	h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", nil)
+	audit := &fakeAudit{}
+	h.audit = audit
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest", strings.NewReader(trustedScan))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	<-triggered
+
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	assert.Equal(t, models.AuditActionIngest, entry.Action)
+	assert.Equal(t, models.ScannerActor("internal-scanner"), entry.Actor)
+	assert.NotContains(t, entry.Actor, "internal-scanner", "the scanner key is stored hashed")
+	assert.Equal(t, "job-1", entry.JobID)
+	assert.Equal(t, 1, entry.HostCount, "both records are for 10.0.0.5")
+	assert.Equal(t, "192.0.2.1", entry.SourceIP)
+}
+
+func TestTrustedIngestHandler_AuditFailureDoesNotRejectScan(t *testing.T) {
+	h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", nil)
+	h.audit = &fakeAudit{err: errors.New("connection refused")}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest", strings.NewReader(trustedScan))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	<-triggered
+}
+
+func TestTrustedIngestHandler_InvalidScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+	}{
+		{name: "not json", body: `host: 10.0.0.5`, wantCode: "invalid_json"},
+		{name: "bad second line", body: "{\"host\":\"10.0.0.5\",\"port\":22}\n{\"host\":", wantCode: "invalid_json"},
+		{name: "empty", body: "\n  \n", wantCode: "missing_data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var errResp CodedErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&errResp))
+			assert.Equal(t, tt.wantCode, errResp.Error)
+			assert.Empty(t, triggered)
+		})
+	}
+}
+
+func TestTrustedIngestHandler_SchemaValidation(t *testing.T) {
+	h, triggered := newTestTrustedIngestHandler(t, "internal-scanner", nil)
+
+	body := trustedScan + `{"host":"10.0.0.6","port":"8080","protocol":"tcp"}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/v1/internal/ingest?schema_version=naabu/v1", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp CodedErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "schema_validation_failed", resp.Error)
+	assert.Equal(t, 3, resp.Line)
+	assert.Equal(t, "/port", resp.Path)
+	assert.Empty(t, triggered)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/internal/ingest?schema_version=naabu/v9", strings.NewReader(trustedScan))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "unsupported_schema_version", resp.Error)
+	assert.Empty(t, triggered)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// VulnStore fetches the full detail of a CVE
+type VulnStore interface {
+	// GetVulnDetail returns nil when the CVE is unknown
+	GetVulnDetail(ctx context.Context, cveID string) (*models.VulnDetailResponse, error)
+}
+
+// VulnDetailHandler handles GET /v1/vuln/{cve}
+// Returns the CVE's vuln and vuln_doc fields with the number of affected hosts.
+func VulnDetailHandler(store VulnStore, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		cveID, ok := models.NormalizeCVEID(chi.URLParam(r, "cve"))
+		if !ok {
+			jobErrorResponse(w, "invalid_parameter", "cve must look like CVE-YYYY-NNNN", http.StatusBadRequest)
+			return
+		}
+
+		detail, err := store.GetVulnDetail(ctx, cveID)
+		if err != nil {
+			logger.Error("failed to get vuln detail",
+				zap.Error(err),
+				zap.String("cve_id", cveID))
+			jobErrorResponse(w, "internal_error", "Failed to get vulnerability", http.StatusInternalServerError)
+			return
+		}
+		if detail == nil {
+			jobErrorResponse(w, "not_found", "Vulnerability not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(detail); err != nil {
+			logger.Error("failed to encode vuln detail",
+				zap.Error(err),
+				zap.String("cve_id", cveID))
+		}
+	}
+}
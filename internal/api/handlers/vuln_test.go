@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeVulnStore serves seeded vuln details keyed by CVE ID
+type fakeVulnStore struct {
+	vulns map[string]models.VulnDetailResponse
+	err   error
+}
+
+func (f *fakeVulnStore) GetVulnDetail(ctx context.Context, cveID string) (*models.VulnDetailResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	detail, ok := f.vulns[cveID]
+	if !ok {
+		return nil, nil
+	}
+	return &detail, nil
+}
+
+// serveVulnDetail routes a GET for path through the vuln detail handler
+func serveVulnDetail(store VulnStore, path string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/v1/vuln/{cve}", VulnDetailHandler(store, zap.NewNop()))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestVulnDetailHandler(t *testing.T) {
+	published := time.Date(2021, 12, 10, 0, 0, 0, 0, time.UTC)
+	log4shell := models.VulnDetailResponse{
+		CVEID:         "CVE-2021-44228",
+		Title:         "Log4Shell",
+		Summary:       "Apache Log4j2 JNDI features do not protect against attacker controlled LDAP endpoints.",
+		CVSS:          10.0,
+		CVSSVector:    "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H",
+		Severity:      "CRITICAL",
+		EPSS:          0.97,
+		KEVFlag:       true,
+		CPEs:          []string{"cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*"},
+		References:    []string{"https://logging.apache.org/log4j/2.x/security.html"},
+		AffectedHosts: 3,
+		PublishedDate: &published,
+		FirstSeen:     published,
+		LastUpdated:   published,
+	}
+	store := &fakeVulnStore{vulns: map[string]models.VulnDetailResponse{log4shell.CVEID: log4shell}}
+
+	t.Run("found", func(t *testing.T) {
+		rec := serveVulnDetail(store, "/v1/vuln/CVE-2021-44228")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var got models.VulnDetailResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+		assert.Equal(t, log4shell.CVEID, got.CVEID)
+		assert.Equal(t, log4shell.Summary, got.Summary)
+		assert.Equal(t, log4shell.CVSSVector, got.CVSSVector)
+		assert.Equal(t, 0.97, got.EPSS)
+		assert.True(t, got.KEVFlag)
+		assert.Equal(t, log4shell.References, got.References)
+		assert.Equal(t, 3, got.AffectedHosts)
+	})
+
+	t.Run("lower-case CVE is normalized", func(t *testing.T) {
+		rec := serveVulnDetail(store, "/v1/vuln/cve-2021-44228")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("unknown CVE", func(t *testing.T) {
+		rec := serveVulnDetail(store, "/v1/vuln/CVE-2020-0001")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		var resp CodedErrorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "not_found", resp.Error)
+	})
+
+	t.Run("malformed CVE", func(t *testing.T) {
+		rec := serveVulnDetail(store, "/v1/vuln/log4shell")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		rec := serveVulnDetail(&fakeVulnStore{err: errors.New("connection refused")}, "/v1/vuln/CVE-2021-44228")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WorkflowInvoker starts an enrichment workflow without waiting for its result
+type WorkflowInvoker interface {
+	Send(ctx context.Context, service string, payload interface{}) error
+}
+
+// RestateInvoker sends workflow requests through the Restate ingress
+type RestateInvoker struct {
+	restateURL string
+	httpClient *http.Client
+}
+
+// NewRestateInvoker creates an invoker for the Restate ingress at restateURL
+func NewRestateInvoker(restateURL string) *RestateInvoker {
+	return &RestateInvoker{
+		restateURL: restateURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts payload to the service's Run handler using Restate's one-way send
+func (i *RestateInvoker) Send(ctx context.Context, service string, payload interface{}) error {
+	url := fmt.Sprintf("%s/%s/Run/send", i.restateURL, service)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal enrichment request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to submit enrichment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("enrichment submission failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
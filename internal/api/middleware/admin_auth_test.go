@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAdminAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, IsAdmin(r), "requests past AdminAuth are marked as admin")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{name: "valid token", token: "s3cret", authorization: "Bearer s3cret", want: http.StatusNoContent},
+		{name: "wrong token", token: "s3cret", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "missing header", token: "s3cret", want: http.StatusUnauthorized},
+		{name: "not a bearer token", token: "s3cret", authorization: "Basic s3cret", want: http.StatusUnauthorized},
+		{name: "no token configured", authorization: "Bearer ", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/redact", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			AdminAuth(tt.token, zap.NewNop())(ok).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusUnauthorized {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestIsAdmin_WithoutAdminAuth(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/reenrich", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+
+	assert.False(t, IsAdmin(req))
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// InFlightLimiter bounds the number of ingest jobs handed off to the workflow
+// backend that have not yet completed. When the limit is reached, callers should
+// reject new work and ask clients to retry later.
+// On shutdown, Drain stops new work from being accepted and waits for the jobs
+// already in flight to finish.
+type InFlightLimiter struct {
+	current    int
+	max        int
+	retryAfter time.Duration
+	draining   bool
+	idle       chan struct{} // Closed when the last job finishes during a drain
+	mu         sync.Mutex
+}
+
+// NewInFlightLimiter creates a limiter allowing up to maxInFlight outstanding jobs
+// retryAfter is the delay suggested to clients when the limit is reached
+func NewInFlightLimiter(maxInFlight int, retryAfter time.Duration) *InFlightLimiter {
+	return &InFlightLimiter{
+		max:        maxInFlight,
+		retryAfter: retryAfter,
+	}
+}
+
+// TryAcquire reserves a slot and reports whether one was available
+// Every successful call must be paired with a call to Release
+func (l *InFlightLimiter) TryAcquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.draining || l.current >= l.max {
+		return false
+	}
+
+	l.current++
+	return true
+}
+
+// Release frees a slot reserved by TryAcquire
+func (l *InFlightLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.current > 0 {
+		l.current--
+	}
+	if l.current == 0 && l.idle != nil {
+		close(l.idle)
+		l.idle = nil
+	}
+}
+
+// Drain stops TryAcquire from granting new slots and waits until every job
+// already in flight has been released, or ctx is done
+func (l *InFlightLimiter) Drain(ctx context.Context) error {
+	l.mu.Lock()
+	l.draining = true
+	if l.current == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.idle == nil {
+		l.idle = make(chan struct{})
+	}
+	idle := l.idle
+	l.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d jobs still in flight: %w", l.Depth(), ctx.Err())
+	}
+}
+
+// Draining reports whether Drain has been called
+func (l *InFlightLimiter) Draining() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.draining
+}
+
+// Depth returns the number of jobs currently in flight
+func (l *InFlightLimiter) Depth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.current
+}
+
+// Max returns the configured in-flight limit
+func (l *InFlightLimiter) Max() int {
+	return l.max
+}
+
+// RetryAfter returns the delay suggested to clients when the limit is reached
+func (l *InFlightLimiter) RetryAfter() time.Duration {
+	return l.retryAfter
+}
+
+// DrainMiddleware counts each request as in flight on inFlight, so Drain waits
+// for it to finish. Requests that arrive while draining, or beyond the limit,
+// get 503 with a Retry-After header.
+func DrainMiddleware(inFlight *InFlightLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !inFlight.TryAcquire() {
+				w.Header().Set("Retry-After", strconv.Itoa(int(inFlight.RetryAfter().Seconds())))
+				http.Error(w, "service unavailable, retry later", http.StatusServiceUnavailable)
+				return
+			}
+			defer inFlight.Release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
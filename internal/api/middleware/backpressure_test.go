@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInFlightLimiter_Saturation(t *testing.T) {
+	limiter := NewInFlightLimiter(3, 30*time.Second)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.TryAcquire(), "slot %d should be available", i+1)
+	}
+
+	assert.Equal(t, 3, limiter.Depth())
+	assert.False(t, limiter.TryAcquire(), "limiter should be saturated")
+	assert.Equal(t, 3, limiter.Depth())
+
+	limiter.Release()
+	assert.Equal(t, 2, limiter.Depth())
+	assert.True(t, limiter.TryAcquire(), "slot should be available after release")
+}
+
+func TestInFlightLimiter_ReleaseNeverNegative(t *testing.T) {
+	limiter := NewInFlightLimiter(1, time.Second)
+
+	limiter.Release()
+	assert.Equal(t, 0, limiter.Depth())
+	assert.True(t, limiter.TryAcquire())
+}
+
+func TestInFlightLimiter_Concurrent(t *testing.T) {
+	limiter := NewInFlightLimiter(10, time.Second)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	acquired := 0
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.TryAcquire() {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 10, acquired)
+	assert.Equal(t, 10, limiter.Depth())
+}
+
+func TestInFlightLimiter_DrainWaitsForInFlight(t *testing.T) {
+	limiter := NewInFlightLimiter(10, time.Second)
+	assert.NoError(t, limiter.Drain(context.Background()), "idle limiter drains immediately")
+	assert.True(t, limiter.Draining())
+	assert.False(t, limiter.TryAcquire(), "no new work once draining")
+
+	limiter = NewInFlightLimiter(10, time.Second)
+	require.True(t, limiter.TryAcquire())
+
+	drained := make(chan error, 1)
+	go func() { drained <- limiter.Drain(context.Background()) }()
+
+	// Drain flips immediately but waits for the outstanding job
+	require.Eventually(t, limiter.Draining, time.Second, time.Millisecond)
+	select {
+	case <-drained:
+		t.Fatal("drain returned with a job still in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	limiter.Release()
+	select {
+	case err := <-drained:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after the last job was released")
+	}
+}
+
+func TestInFlightLimiter_DrainTimeout(t *testing.T) {
+	limiter := NewInFlightLimiter(10, time.Second)
+	require.True(t, limiter.TryAcquire())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := limiter.Drain(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "1 jobs still in flight")
+}
+
+func TestDrainMiddleware(t *testing.T) {
+	limiter := NewInFlightLimiter(10, 5*time.Second)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	handler := DrainMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-finish
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A request accepted before the drain starts
+	accepted := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/slow", nil))
+		accepted <- rec.Code
+	}()
+	<-started
+
+	drained := make(chan error, 1)
+	go func() { drained <- limiter.Drain(context.Background()) }()
+	require.Eventually(t, limiter.Draining, time.Second, time.Millisecond)
+
+	// A request arriving during the drain is rejected
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/fast", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+
+	// The accepted request still completes, and then the drain finishes
+	close(finish)
+	assert.Equal(t, http.StatusOK, <-accepted)
+	select {
+	case err := <-drained:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("drain did not finish after the accepted request completed")
+	}
+	assert.Equal(t, 0, limiter.Depth())
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs, such as
+// "10.0.0.0/8, 2001:db8::/32". A bare IP is trusted as a single address.
+// An empty list trusts no proxy.
+func ParseTrustedProxies(list string) ([]net.IPNet, error) {
+	var proxies []net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			proxies = append(proxies, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, *network)
+	}
+	return proxies, nil
+}
+
+// ClientIP returns the IP of the client that sent r
+// X-Forwarded-For is honored only when the request came directly from a trusted
+// proxy, since anyone else can set it to anything. Its entries are then read
+// from the right, skipping trusted proxies, and the first untrusted one is the
+// client: entries further left were written by the client itself. With no
+// trusted proxies, the socket address is always used.
+func ClientIP(r *http.Request, trusted []net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isTrustedProxy(ip, trusted) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// Nothing left of a malformed entry can be relied on; the last
+			// trusted proxy is the best we know
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop, trusted) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+// clientIPKey is the context key ResolveClientIP stores the client IP under
+type clientIPKey struct{}
+
+// ResolveClientIP stores each request's ClientIP, resolved with the trusted
+// proxies, for RequestClientIP, so handlers record the same address the rate
+// limiter keys on
+func ResolveClientIP(trusted []net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, ClientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestClientIP returns the client IP ResolveClientIP stored for r, or the
+// address of the connection when the middleware did not run
+func RequestClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return ClientIP(r, nil)
+}
+
+// isTrustedProxy reports whether ip is in one of the trusted ranges
+func isTrustedProxy(ip net.IP, trusted []net.IPNet) bool {
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
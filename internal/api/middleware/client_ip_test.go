@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 10.0.0.0/8, 192.0.2.1 ,2001:db8::/32,,::1")
+	require.NoError(t, err)
+	require.Len(t, proxies, 4)
+	assert.Equal(t, "10.0.0.0/8", proxies[0].String())
+	assert.Equal(t, "192.0.2.1/32", proxies[1].String())
+	assert.Equal(t, "2001:db8::/32", proxies[2].String())
+	assert.Equal(t, "::1/128", proxies[3].String())
+
+	proxies, err = ParseTrustedProxies("")
+	require.NoError(t, err)
+	assert.Empty(t, proxies)
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0.0/8,nope"} {
+		_, err := ParseTrustedProxies(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 2001:db8:ffff::/48")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		trusted    bool
+		remoteAddr string
+		xff        []string
+		expected   string
+	}{
+		{
+			name:       "no proxies trusted",
+			remoteAddr: "10.0.0.1:443",
+			xff:        []string{"203.0.113.9"},
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "spoofed header from untrusted source",
+			trusted:    true,
+			remoteAddr: "198.51.100.7:50000",
+			xff:        []string{"203.0.113.9"},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "trusted proxy",
+			trusted:    true,
+			remoteAddr: "10.0.0.1:443",
+			xff:        []string{"203.0.113.9"},
+			expected:   "203.0.113.9",
+		},
+		{
+			name:       "client-supplied entries left of the real client",
+			trusted:    true,
+			remoteAddr: "10.0.0.1:443",
+			xff:        []string{"1.1.1.1, 203.0.113.9, 10.0.0.2"},
+			expected:   "203.0.113.9",
+		},
+		{
+			name:       "header repeated by chained proxies",
+			trusted:    true,
+			remoteAddr: "10.0.0.1:443",
+			xff:        []string{"1.1.1.1", "203.0.113.9", "10.0.0.2"},
+			expected:   "203.0.113.9",
+		},
+		{
+			name:       "malformed entry stops at the last trusted hop",
+			trusted:    true,
+			remoteAddr: "10.0.0.1:443",
+			xff:        []string{"203.0.113.9, garbage, 10.0.0.2"},
+			expected:   "10.0.0.2",
+		},
+		{
+			name:       "trusted proxy without header",
+			trusted:    true,
+			remoteAddr: "10.0.0.1:443",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "IPv6 through a trusted proxy",
+			trusted:    true,
+			remoteAddr: "[2001:db8:ffff::1]:443",
+			xff:        []string{"2001:db8:1::7"},
+			expected:   "2001:db8:1::7",
+		},
+		{
+			name:       "IPv6 direct",
+			trusted:    true,
+			remoteAddr: "[2001:db8:1::7]:443",
+			xff:        []string{"203.0.113.9"},
+			expected:   "2001:db8:1::7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			proxies := trusted
+			if !tt.trusted {
+				proxies = nil
+			}
+			assert.Equal(t, tt.expected, ClientIP(req, proxies))
+		})
+	}
+}
+
+func TestResolveClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+
+	var got string
+	handler := ResolveClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestClientIP(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.5:443"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "203.0.113.9", got)
+
+	// Without the middleware, only the connection address is known
+	assert.Equal(t, "10.0.0.5", RequestClientIP(req))
+}
@@ -1,11 +1,19 @@
 package middleware
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/spectra-red/recon/internal/auth"
+	"github.com/spectra-red/recon/internal/schedule"
 	"go.uber.org/zap"
 )
 
@@ -33,8 +41,50 @@ func (tb *TokenBucket) Allow() bool {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
-	// Refill tokens based on elapsed time
-	now := time.Now()
+	tb.refill(time.Now())
+
+	// Check if we have tokens available
+	if tb.tokens >= 1.0 {
+		tb.tokens -= 1.0
+		return true
+	}
+
+	return false
+}
+
+// Tokens returns the number of whole tokens currently available without consuming one
+// Like Allow, it first refills the bucket for the elapsed time
+func (tb *TokenBucket) Tokens() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill(time.Now())
+	return int(math.Floor(tb.tokens))
+}
+
+// Remaining returns how many more requests the bucket will currently allow
+func (tb *TokenBucket) Remaining() int {
+	return tb.Tokens()
+}
+
+// Reset returns the time until the next whole token is added to the bucket
+// Returns 0 when the bucket is already full
+func (tb *TokenBucket) Reset() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.refill(time.Now())
+	if tb.tokens >= tb.capacity || tb.refillRate <= 0 {
+		return 0
+	}
+
+	missing := math.Floor(tb.tokens) + 1 - tb.tokens
+	return time.Duration(missing / tb.refillRate * float64(time.Second))
+}
+
+// refill adds tokens for the time elapsed since the last refill, capped at capacity
+// Callers must hold tb.mu
+func (tb *TokenBucket) refill(now time.Time) {
 	elapsed := now.Sub(tb.lastRefillTime).Seconds()
 	tb.tokens += elapsed * tb.refillRate
 
@@ -44,50 +94,181 @@ func (tb *TokenBucket) Allow() bool {
 	}
 
 	tb.lastRefillTime = now
-
-	// Check if we have tokens available
-	if tb.tokens >= 1.0 {
-		tb.tokens -= 1.0
-		return true
-	}
-
-	return false
 }
 
+// DefaultExemptPeekBytes is how much of a request body is read to find the scanner
+// key of a signed envelope (10MB), the default ingest body cap: the handler reads
+// that much of the body anyway, so any scan it would accept can be exempted
+const DefaultExemptPeekBytes int64 = 10 << 20
+
 // RateLimiter manages rate limits per scanner key
 type RateLimiter struct {
-	buckets  map[string]*TokenBucket
-	capacity float64
-	rate     float64 // refill rate in tokens per second
-	mu       sync.RWMutex
-	logger   *zap.Logger
+	buckets        map[string]*TokenBucket
+	capacity       float64
+	rate           float64 // refill rate in tokens per second
+	trustedProxies []net.IPNet
+	exemptNetworks []net.IPNet
+	exemptKeys     map[string]bool
+	exemptPeek     int64
+	maxSkew        time.Duration
+	signatures     map[string]time.Time // exempting envelope signatures, until they expire
+	allowed        atomic.Uint64
+	exempted       atomic.Uint64
+	rejected       atomic.Uint64
+	mu             sync.RWMutex
+	logger         *zap.Logger
 }
 
-// NewRateLimiter creates a new rate limiter
+// RateLimiterConfig configures a RateLimiter
+type RateLimiterConfig struct {
+	// RequestsPerMinute is the maximum requests allowed per minute per client
+	RequestsPerMinute int
+	// TrustedProxies are the ranges whose X-Forwarded-For is believed; empty
+	// trusts none, so clients are keyed by their socket address
+	TrustedProxies []net.IPNet
+	// ExemptNetworks are client ranges that bypass the limit, e.g. our own scanner fleet
+	ExemptNetworks []net.IPNet
+	// ExemptKeys are scanner public keys that bypass the limit. A request is exempt
+	// only when its body is a signed envelope from one of them whose signature verifies,
+	// so the key alone, being public, doesn't grant the exemption, and only the first
+	// time that envelope is seen, so a captured one can't be replayed past the limit.
+	// Bodies are only read for requests the limit would otherwise reject.
+	ExemptKeys []string
+	// ExemptPeekBytes caps the body read to check ExemptKeys and should match the
+	// ingest body cap (INGEST_MAX_BODY_BYTES); larger bodies are limited as usual.
+	// Default: DefaultExemptPeekBytes
+	ExemptPeekBytes int64
+	// MaxSkew bounds envelope timestamp drift when checking ExemptKeys.
+	// Default: auth.TimestampWindow
+	MaxSkew time.Duration
+}
+
+// RateLimitStats counts the requests a RateLimiter has seen since it was created
+type RateLimitStats struct {
+	Allowed  uint64 `json:"allowed"`
+	Exempted uint64 `json:"exempted"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// NewRateLimiter creates a new rate limiter that trusts no proxy
 // requestsPerMinute: maximum requests allowed per minute
 func NewRateLimiter(requestsPerMinute int, logger *zap.Logger) *RateLimiter {
+	return NewRateLimiterWithConfig(RateLimiterConfig{RequestsPerMinute: requestsPerMinute}, logger)
+}
+
+// NewRateLimiterWithConfig creates a new rate limiter with custom configuration
+func NewRateLimiterWithConfig(cfg RateLimiterConfig, logger *zap.Logger) *RateLimiter {
+	if cfg.ExemptPeekBytes <= 0 {
+		cfg.ExemptPeekBytes = DefaultExemptPeekBytes
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = auth.TimestampWindow
+	}
+
+	exemptKeys := make(map[string]bool, len(cfg.ExemptKeys))
+	for _, key := range cfg.ExemptKeys {
+		if key != "" {
+			exemptKeys[key] = true
+		}
+	}
+
 	return &RateLimiter{
-		buckets:  make(map[string]*TokenBucket),
-		capacity: float64(requestsPerMinute),
-		rate:     float64(requestsPerMinute) / 60.0, // convert to tokens per second
-		logger:   logger,
+		buckets:        make(map[string]*TokenBucket),
+		capacity:       float64(cfg.RequestsPerMinute),
+		rate:           float64(cfg.RequestsPerMinute) / 60.0, // convert to tokens per second
+		trustedProxies: cfg.TrustedProxies,
+		exemptNetworks: cfg.ExemptNetworks,
+		exemptKeys:     exemptKeys,
+		exemptPeek:     cfg.ExemptPeekBytes,
+		maxSkew:        cfg.MaxSkew,
+		signatures:     make(map[string]time.Time),
+		logger:         logger,
+	}
+}
+
+// Stats returns how many requests the middleware has allowed, exempted and rejected
+func (rl *RateLimiter) Stats() RateLimitStats {
+	return RateLimitStats{
+		Allowed:  rl.allowed.Load(),
+		Exempted: rl.exempted.Load(),
+		Rejected: rl.rejected.Load(),
+	}
+}
+
+// exemptNetwork reports whether a request from clientIP bypasses the limit by its network
+func (rl *RateLimiter) exemptNetwork(clientIP string) bool {
+	if ip := net.ParseIP(clientIP); ip != nil {
+		for _, network := range rl.exemptNetworks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exemptKey reports whether a request bypasses the limit by the verified scanner
+// key of the envelope in its body. Reading and verifying the body is the costly
+// part, so it is only done for requests the bucket has already rejected.
+func (rl *RateLimiter) exemptKey(r *http.Request) bool {
+	if len(rl.exemptKeys) == 0 || r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+
+	// Read the envelope, then restore the body for the handler
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, rl.exemptPeek+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+	if err != nil || int64(len(peeked)) > rl.exemptPeek {
+		return false
+	}
+
+	var env auth.ScanEnvelope
+	if err := json.Unmarshal(peeked, &env); err != nil || !rl.exemptKeys[env.PublicKey] {
+		return false
+	}
+	if auth.VerifyEnvelopeWithConfig(env, rl.maxSkew) != nil {
+		return false
+	}
+	return rl.claimSignature(env.Signature, time.Unix(env.Timestamp, 0).Add(rl.maxSkew))
+}
+
+// claimSignature records the signature of a verified envelope until expires, when
+// its timestamp leaves the skew window, and reports whether it was new. A replayed
+// envelope verifies as well as the original, so only its first use is exempt.
+func (rl *RateLimiter) claimSignature(signature string, expires time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if _, seen := rl.signatures[signature]; seen {
+		return false
 	}
+	rl.signatures[signature] = expires
+	return true
 }
 
 // Allow checks if a request from the given key can proceed
 func (rl *RateLimiter) Allow(key string) bool {
+	return rl.bucket(key).Allow()
+}
+
+// bucket returns the token bucket for the given key, creating it if needed
+func (rl *RateLimiter) bucket(key string) *TokenBucket {
 	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
 	bucket, exists := rl.buckets[key]
 	if !exists {
 		bucket = NewTokenBucket(rl.capacity, rl.rate)
 		rl.buckets[key] = bucket
 	}
-	rl.mu.Unlock()
-
-	return bucket.Allow()
+	return bucket
 }
 
 // CleanupStale removes buckets that haven't been used recently (memory optimization)
+// and the signatures of envelopes too old to verify again
 func (rl *RateLimiter) CleanupStale(maxAge time.Duration) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -100,6 +281,11 @@ func (rl *RateLimiter) CleanupStale(maxAge time.Duration) {
 		}
 		bucket.mu.Unlock()
 	}
+	for signature, expires := range rl.signatures {
+		if now.After(expires) {
+			delete(rl.signatures, signature)
+		}
+	}
 }
 
 // RateLimitMiddleware creates a middleware that enforces rate limiting per scanner
@@ -109,7 +295,7 @@ func RateLimitMiddleware(limiter *RateLimiter) func(next http.Handler) http.Hand
 			// Extract scanner key from request
 			// For now, we use the public key from the request body
 			// In a production system, this might come from a header after auth
-			scannerKey := extractScannerKey(r)
+			scannerKey := extractScannerKey(r, limiter.trustedProxies)
 
 			if scannerKey == "" {
 				// If we can't extract a key, allow the request
@@ -118,9 +304,30 @@ func RateLimitMiddleware(limiter *RateLimiter) func(next http.Handler) http.Hand
 				return
 			}
 
-			if !limiter.Allow(scannerKey) {
+			// Allowlisted networks skip the token bucket but are still counted
+			if limiter.exemptNetwork(scannerKey) {
+				limiter.exempted.Add(1)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bucket := limiter.bucket(scannerKey)
+			allowed := bucket.Allow()
+
+			// Allowlisted keys are checked only once the bucket is empty
+			if !allowed && limiter.exemptKey(r) {
+				limiter.exempted.Add(1)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Let clients pace themselves before they are rejected
+			setRateLimitStatusHeaders(w, bucket)
+
+			if !allowed {
+				limiter.rejected.Add(1)
 				limiter.logger.Warn("rate limit exceeded",
-					zap.String("scanner_key", maskKey(scannerKey)),
+					zap.String("scanner_key", MaskKey(scannerKey)),
 					zap.String("path", r.URL.Path),
 					zap.String("remote_addr", r.RemoteAddr))
 
@@ -138,24 +345,28 @@ func RateLimitMiddleware(limiter *RateLimiter) func(next http.Handler) http.Hand
 				return
 			}
 
+			limiter.allowed.Add(1)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// setRateLimitStatusHeaders sets the remaining token count and the seconds until the next token
+func setRateLimitStatusHeaders(w http.ResponseWriter, bucket *TokenBucket) {
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(bucket.Tokens()))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(bucket.Reset().Seconds()))))
+}
+
 // extractScannerKey extracts a unique identifier for rate limiting
-// For the ingest endpoint, we use the client IP as a basic identifier
+// For the ingest endpoint, we use the client IP as a basic identifier, taken
+// from X-Forwarded-For only when the request came through a trusted proxy
 // In production, this would be enhanced to use the authenticated scanner ID
-func extractScannerKey(r *http.Request) string {
-	// Use X-Forwarded-For if behind a proxy, otherwise use RemoteAddr
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
-	}
-	return r.RemoteAddr
+func extractScannerKey(r *http.Request, trustedProxies []net.IPNet) string {
+	return ClientIP(r, trustedProxies)
 }
 
-// maskKey masks a key for safe logging
-func maskKey(key string) string {
+// MaskKey masks a key for safe logging, keeping only its first eight characters
+func MaskKey(key string) string {
 	if len(key) <= 8 {
 		return key
 	}
@@ -164,7 +375,14 @@ func maskKey(key string) string {
 
 // StartCleanupRoutine starts a background goroutine to clean up stale buckets
 func (rl *RateLimiter) StartCleanupRoutine(interval, maxAge time.Duration) {
-	ticker := time.NewTicker(interval)
+	rl.StartCleanupRoutineWithJitter(interval, maxAge, 0)
+}
+
+// StartCleanupRoutineWithJitter starts the cleanup goroutine with each run
+// spread by up to jitter (a fraction of interval) either side of interval, so
+// replicas started together don't clean up in lockstep
+func (rl *RateLimiter) StartCleanupRoutineWithJitter(interval, maxAge time.Duration, jitter float64) {
+	ticker := schedule.NewTicker(interval, jitter)
 	go func() {
 		for range ticker.C {
 			rl.CleanupStale(maxAge)
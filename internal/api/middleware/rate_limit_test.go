@@ -1,12 +1,18 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/spectra-red/recon/internal/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
@@ -34,6 +40,67 @@ func TestTokenBucket_Allow(t *testing.T) {
 	assert.False(t, bucket.Allow(), "should deny after consuming refilled token")
 }
 
+func TestTokenBucket_Remaining(t *testing.T) {
+	// Refill slowly enough that no token is added during the test
+	bucket := NewTokenBucket(3, 0.001)
+
+	assert.Equal(t, 3, bucket.Remaining())
+
+	for want := 2; want >= 0; want-- {
+		require.True(t, bucket.Allow())
+		assert.Equal(t, want, bucket.Remaining())
+	}
+
+	// Remaining does not consume tokens
+	assert.False(t, bucket.Allow())
+	assert.Equal(t, 0, bucket.Remaining())
+}
+
+func TestTokenBucket_Tokens(t *testing.T) {
+	// Refill slowly enough that no token is added during the test
+	bucket := NewTokenBucket(2, 0.001)
+
+	// Peeking repeatedly must not drain the bucket
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, 2, bucket.Tokens())
+	}
+
+	// Tokens followed by Allow stays consistent
+	require.True(t, bucket.Allow())
+	assert.Equal(t, 1, bucket.Tokens())
+	require.True(t, bucket.Allow())
+	assert.Equal(t, 0, bucket.Tokens())
+	assert.False(t, bucket.Allow())
+	assert.Equal(t, 0, bucket.Tokens())
+}
+
+func TestTokenBucket_TokensRefills(t *testing.T) {
+	// 10 tokens per second
+	bucket := NewTokenBucket(2, 10)
+
+	require.True(t, bucket.Allow())
+	require.True(t, bucket.Allow())
+	assert.Equal(t, 0, bucket.Tokens())
+
+	// Tokens refills based on elapsed time, like Allow
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 1, bucket.Tokens())
+	assert.True(t, bucket.Allow())
+}
+
+func TestTokenBucket_Reset(t *testing.T) {
+	// 1 token per second
+	bucket := NewTokenBucket(2, 1)
+
+	// A full bucket has nothing to wait for
+	assert.Equal(t, time.Duration(0), bucket.Reset())
+
+	require.True(t, bucket.Allow())
+	reset := bucket.Reset()
+	assert.Greater(t, reset, 900*time.Millisecond)
+	assert.LessOrEqual(t, reset, time.Second)
+}
+
 func TestTokenBucket_Refill(t *testing.T) {
 	// Create a bucket with 10 tokens, refilling at 10 tokens/second
 	bucket := NewTokenBucket(10, 10)
@@ -177,6 +244,8 @@ func TestRateLimitMiddleware_Exceeded(t *testing.T) {
 	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 	assert.Equal(t, "60", w.Header().Get("X-RateLimit-Limit"))
 	assert.Equal(t, "1m", w.Header().Get("X-RateLimit-Window"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
 
 	var response map[string]interface{}
 	err := json.NewDecoder(w.Body).Decode(&response)
@@ -186,6 +255,36 @@ func TestRateLimitMiddleware_Exceeded(t *testing.T) {
 	assert.Contains(t, response["message"], "Rate limit exceeded")
 }
 
+func TestRateLimitMiddleware_RemainingHeaders(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	limiter := NewRateLimiter(5, logger)
+	middleware := RateLimitMiddleware(limiter)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrappedHandler := middleware(handler)
+
+	// Remaining should decrement across successive allowed requests
+	for want := 4; want >= 0; want-- {
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		w := httptest.NewRecorder()
+
+		wrappedHandler.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, strconv.Itoa(want), w.Header().Get("X-RateLimit-Remaining"))
+
+		// 5 requests per minute refills one token every 12 seconds
+		reset, err := strconv.Atoi(w.Header().Get("X-RateLimit-Reset"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, reset, 1)
+		assert.LessOrEqual(t, reset, 12)
+	}
+}
+
 func TestRateLimitMiddleware_DifferentIPs(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	limiter := NewRateLimiter(5, logger)
@@ -223,7 +322,9 @@ func TestRateLimitMiddleware_DifferentIPs(t *testing.T) {
 
 func TestRateLimitMiddleware_XForwardedFor(t *testing.T) {
 	logger := zaptest.NewLogger(t)
-	limiter := NewRateLimiter(5, logger)
+	trusted, err := ParseTrustedProxies("192.168.1.0/24")
+	require.NoError(t, err)
+	limiter := NewRateLimiterWithConfig(RateLimiterConfig{RequestsPerMinute: 5, TrustedProxies: trusted}, logger)
 	middleware := RateLimitMiddleware(limiter)
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -232,7 +333,7 @@ func TestRateLimitMiddleware_XForwardedFor(t *testing.T) {
 
 	wrappedHandler := middleware(handler)
 
-	// Test with X-Forwarded-For header (simulates proxy)
+	// Test with X-Forwarded-For header from a trusted proxy
 	for i := 0; i < 5; i++ {
 		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
 		req.Header.Set("X-Forwarded-For", "10.0.0.1")
@@ -251,26 +352,264 @@ func TestRateLimitMiddleware_XForwardedFor(t *testing.T) {
 
 	wrappedHandler.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// The same proxy forwarding another client is limited separately
+	req = httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.2")
+	req.RemoteAddr = "192.168.1.1:12345"
+	w = httptest.NewRecorder()
+
+	wrappedHandler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimitMiddleware_IgnoresSpoofedXForwardedFor(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	limiter := NewRateLimiter(5, logger)
+	middleware := RateLimitMiddleware(limiter)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	wrappedHandler := middleware(handler)
+
+	// A client rotating X-Forwarded-For on every request still shares one bucket
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+		req.Header.Set("X-Forwarded-For", "10.0.0."+strconv.Itoa(i))
+		req.RemoteAddr = "198.51.100.7:" + strconv.Itoa(40000+i)
+		w := httptest.NewRecorder()
+
+		wrappedHandler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.99")
+	req.RemoteAddr = "198.51.100.7:40099"
+	w := httptest.NewRecorder()
+
+	wrappedHandler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestRateLimitMiddleware_ExemptNetwork(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	exempt, err := ParseTrustedProxies("10.20.0.0/16")
+	require.NoError(t, err)
+	limiter := NewRateLimiterWithConfig(RateLimiterConfig{RequestsPerMinute: 5, ExemptNetworks: exempt}, logger)
+
+	wrappedHandler := RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A fleet scanner goes well past the limit
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+		req.RemoteAddr = "10.20.3.4:12345"
+		w := httptest.NewRecorder()
+
+		wrappedHandler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, "exempt request %d", i+1)
+	}
+
+	// Everyone else keeps the limit
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+		req.RemoteAddr = "10.21.3.4:12345"
+		w := httptest.NewRecorder()
+
+		wrappedHandler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", nil)
+	req.RemoteAddr = "10.21.3.4:12345"
+	w := httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	assert.Equal(t, RateLimitStats{Allowed: 5, Exempted: 20, Rejected: 1}, limiter.Stats())
+}
+
+func TestRateLimitMiddleware_ExemptKey(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	fleetPub, fleetPriv, err := auth.GenerateTestKey()
+	require.NoError(t, err)
+	_, otherPriv, err := auth.GenerateTestKey()
+	require.NoError(t, err)
+	fleetKey := base64.StdEncoding.EncodeToString(fleetPub)
+
+	limiter := NewRateLimiterWithConfig(RateLimiterConfig{RequestsPerMinute: 5, ExemptKeys: []string{fleetKey}}, logger)
+
+	var bodies []string
+	wrappedHandler := RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(env auth.ScanEnvelope, remoteAddr string) int {
+		body, err := json.Marshal(env)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	fleetEnvelope := func(port int) auth.ScanEnvelope {
+		data := json.RawMessage(`{"host":"192.0.2.1","port":` + strconv.Itoa(port) + `}`)
+		return auth.SignEnvelope(fleetPriv, data, time.Now().Unix())
+	}
+
+	// The fleet scanner spends the address's tokens, then goes well past the limit
+	var fleet []auth.ScanEnvelope
+	for i := 0; i < 20; i++ {
+		fleet = append(fleet, fleetEnvelope(i))
+		require.Equal(t, http.StatusOK, send(fleet[i], "198.51.100.1:12345"), "fleet request %d", i+1)
+	}
+
+	// The handler still reads the whole body after the middleware peeked at it
+	wantBody, err := json.Marshal(fleet[5])
+	require.NoError(t, err)
+	assert.Equal(t, string(wantBody), bodies[5])
+
+	// Another scanner from the same address is limited as usual
+	other := auth.SignEnvelope(otherPriv, json.RawMessage(`{"host":"192.0.2.1","port":22}`), time.Now().Unix())
+	assert.Equal(t, http.StatusTooManyRequests, send(other, "198.51.100.1:12345"))
+
+	// Claiming the fleet's public key without its signature earns no exemption
+	forged := other
+	forged.PublicKey = fleetKey
+	assert.Equal(t, http.StatusTooManyRequests, send(forged, "198.51.100.1:12345"))
+
+	// Nor does replaying an envelope the fleet already sent
+	assert.Equal(t, http.StatusTooManyRequests, send(fleet[10], "198.51.100.1:12345"))
+
+	assert.Equal(t, RateLimitStats{Allowed: 5, Exempted: 15, Rejected: 3}, limiter.Stats())
+}
+
+// readTracker is a request body that records whether it was read
+type readTracker struct {
+	io.Reader
+	read bool
+}
+
+func (rt *readTracker) Read(p []byte) (int, error) {
+	rt.read = true
+	return rt.Reader.Read(p)
+}
+
+func TestRateLimitMiddleware_ExemptKeyPeeksOnlyOverLimit(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	fleetPub, fleetPriv, err := auth.GenerateTestKey()
+	require.NoError(t, err)
+	limiter := NewRateLimiterWithConfig(RateLimiterConfig{
+		RequestsPerMinute: 2,
+		ExemptKeys:        []string{base64.StdEncoding.EncodeToString(fleetPub)},
+		ExemptPeekBytes:   1024,
+	}, logger)
+
+	wrappedHandler := RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(data string) (int, bool) {
+		body, err := json.Marshal(auth.SignEnvelope(fleetPriv, json.RawMessage(data), time.Now().Unix()))
+		require.NoError(t, err)
+		tracker := &readTracker{Reader: bytes.NewReader(body)}
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", tracker)
+		req.RemoteAddr = "198.51.100.1:12345"
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+		return w.Code, tracker.read
+	}
+
+	// Requests within the limit are passed on without reading their bodies
+	for i := 0; i < 2; i++ {
+		code, read := send(`{"port":` + strconv.Itoa(i) + `}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.False(t, read, "request %d within the limit was peeked", i+1)
+	}
+
+	code, read := send(`{"port":2}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, read, "a request over the limit is checked for an exempt key")
+
+	// An envelope larger than the peek is limited as usual
+	code, _ = send(`{"banner":"` + strings.Repeat("a", 2048) + `"}`)
+	assert.Equal(t, http.StatusTooManyRequests, code)
+}
+
+func TestRateLimitMiddleware_ExemptKeyLargeScan(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	fleetPub, fleetPriv, err := auth.GenerateTestKey()
+	require.NoError(t, err)
+	limiter := NewRateLimiterWithConfig(RateLimiterConfig{
+		RequestsPerMinute: 1,
+		ExemptKeys:        []string{base64.StdEncoding.EncodeToString(fleetPub)},
+	}, logger)
+
+	var received int
+	wrappedHandler := RateLimitMiddleware(limiter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = len(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(data string) (int, int) {
+		body, err := json.Marshal(auth.SignEnvelope(fleetPriv, json.RawMessage(data), time.Now().Unix()))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/mesh/ingest", bytes.NewReader(body))
+		req.RemoteAddr = "198.51.100.1:12345"
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+		return w.Code, len(body)
+	}
+
+	code, _ := send(`{"port":1}`)
+	require.Equal(t, http.StatusOK, code)
+
+	// A fleet scan well past 64KB is still exempt, and reaches the handler whole
+	code, size := send(`{"banner":"` + strings.Repeat("a", 1<<20) + `"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Greater(t, size, 64<<10)
+	assert.Equal(t, size, received)
+
+	assert.Equal(t, RateLimitStats{Allowed: 1, Exempted: 1}, limiter.Stats())
 }
 
 func TestExtractScannerKey(t *testing.T) {
+	trusted, err := ParseTrustedProxies("127.0.0.1")
+	require.NoError(t, err)
+
 	tests := []struct {
-		name           string
-		remoteAddr     string
-		xForwardedFor  string
-		expectedPrefix string
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		expected      string
 	}{
 		{
-			name:           "direct connection",
-			remoteAddr:     "192.168.1.1:12345",
-			xForwardedFor:  "",
-			expectedPrefix: "192.168.1.1",
+			name:          "direct connection",
+			remoteAddr:    "192.168.1.1:12345",
+			xForwardedFor: "",
+			expected:      "192.168.1.1",
+		},
+		{
+			name:          "behind proxy",
+			remoteAddr:    "127.0.0.1:12345",
+			xForwardedFor: "203.0.113.42",
+			expected:      "203.0.113.42",
 		},
 		{
-			name:           "behind proxy",
-			remoteAddr:     "127.0.0.1:12345",
-			xForwardedFor:  "203.0.113.42",
-			expectedPrefix: "203.0.113.42",
+			name:          "spoofed header",
+			remoteAddr:    "192.168.1.1:12345",
+			xForwardedFor: "203.0.113.42",
+			expected:      "192.168.1.1",
 		},
 	}
 
@@ -282,8 +621,8 @@ func TestExtractScannerKey(t *testing.T) {
 				req.Header.Set("X-Forwarded-For", tt.xForwardedFor)
 			}
 
-			key := extractScannerKey(req)
-			assert.Contains(t, key, tt.expectedPrefix)
+			key := extractScannerKey(req, trusted)
+			assert.Equal(t, tt.expected, key)
 		})
 	}
 }
@@ -313,7 +652,7 @@ func TestMaskKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := maskKey(tt.key)
+			result := MaskKey(tt.key)
 			if len(tt.key) > 8 {
 				assert.Contains(t, result, "...")
 				assert.Contains(t, result, tt.expected)
@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// swaggerUIVersion pins the Swagger UI assets loaded by the docs page
+const swaggerUIVersion = "5.17.14"
+
+// docsPage renders Swagger UI against /openapi.json
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Spectra-Red Recon API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// SpecHandler creates an HTTP handler for GET /openapi.json
+// The document is built once, when the handler is created.
+func SpecHandler(logger *zap.Logger) http.HandlerFunc {
+	spec, err := JSON()
+	if err != nil {
+		logger.Error("failed to build OpenAPI document", zap.Error(err))
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "OpenAPI document unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(spec)
+	}
+}
+
+// DocsHandler creates an HTTP handler for GET /docs serving Swagger UI
+func DocsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(docsPage))
+	}
+}
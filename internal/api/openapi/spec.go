@@ -0,0 +1,396 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/invopop/jsonschema"
+	"github.com/spectra-red/recon/internal/api/handlers"
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// schemaRefPrefix is where reflected schemas live in the document
+const schemaRefPrefix = "#/components/schemas/"
+
+// reflectedRefPrefix is the prefix the reflector uses for its own definitions
+const reflectedRefPrefix = "#/$defs/"
+
+// schemaNames renames reflected types whose names collide across packages
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(handlers.ErrorResponse{}): "GraphErrorResponse",
+}
+
+// Spec builds the OpenAPI document for the public /v1 API
+func Spec() *Document {
+	b := newBuilder()
+
+	return &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "Spectra-Red Recon API",
+			Description: "Submit signed scan results to the mesh, track ingest jobs, and query the host graph and vulnerability corpus.",
+			Version:     "v1",
+		},
+		Servers: []Server{
+			{URL: "http://localhost:3000", Description: "Local development"},
+		},
+		Paths: map[string]*PathItem{
+			"/v1/mesh/ingest": {
+				Post: &Operation{
+					OperationID: "ingestScan",
+					Summary:     "Submit a signed scan",
+					Description: "Verifies the envelope signature, creates a job and processes the scan asynchronously. Poll /v1/jobs/{job_id} or subscribe to /v1/jobs/events for progress.",
+					Tags:        []string{"ingest"},
+					RequestBody: b.jsonBody(handlers.IngestRequest{}),
+					Responses: map[string]*Response{
+						"202": b.jsonResponse("Scan accepted for processing", handlers.IngestResponse{}),
+						"400": b.jsonResponse("Malformed body, data not matching schema_version, disallowed callback_url or invalid source", handlers.CodedErrorResponse{}),
+						"401": b.jsonResponse("Signature verification failed", handlers.CodedErrorResponse{}),
+						"413": b.jsonResponse("Request body too large", handlers.CodedErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Job could not be created", handlers.CodedErrorResponse{}),
+						"503": b.jsonResponse("Ingest queue is full", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/host/{ip}": {
+				Get: &Operation{
+					OperationID: "queryHost",
+					Summary:     "Look up a host by IP",
+					Tags:        []string{"query"},
+					Parameters: []Parameter{
+						pathParam("ip", "IPv4 or IPv6 address"),
+						{
+							Name:        "depth",
+							In:          "query",
+							Description: "Traversal depth: 0 host only, 1 ports, 2 services, 3+ vulnerabilities; depths above 5 are clamped to 5",
+							Schema:      &jsonschema.Schema{Type: "integer", Minimum: "0", Default: 2},
+						},
+					},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Host and its related graph records", models.HostQueryResponse{}),
+						"400": b.jsonResponse("Invalid IP or depth", handlers.QueryErrorResponse{}),
+						"404": b.jsonResponse("Host not found", handlers.QueryErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Query failed", handlers.QueryErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/graph": {
+				Post: &Operation{
+					OperationID: "queryGraph",
+					Summary:     "Run a graph traversal query",
+					Description: "query_type selects the traversal; each type requires its own parameters (asn for by_asn, cve for by_vuln, seed_ip for related, and so on).",
+					Tags:        []string{"query"},
+					RequestBody: b.jsonBody(models.GraphQueryRequest{}),
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Matching hosts", models.GraphQueryResponse{}),
+						"400": b.jsonResponse("Invalid query", handlers.ErrorResponse{}),
+						"413": b.jsonResponse("Request body too large", handlers.ErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Query failed", handlers.ErrorResponse{}),
+						"504": b.jsonResponse("Query exceeded the server deadline", handlers.ErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/diff": {
+				Post: &Operation{
+					OperationID: "queryDiff",
+					Summary:     "Compare two scan snapshots of a network",
+					Description: "Lists the hosts added, removed and changed in a CIDR or ASN between the scans starting at from and to, with the ports opened or closed, services changed and CVEs newly affecting each host.",
+					Tags:        []string{"query"},
+					RequestBody: b.jsonBody(models.DiffRequest{}),
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Changes between the snapshots", models.DiffResponse{}),
+						"400": b.jsonResponse("Invalid scope or time range", handlers.ErrorResponse{}),
+						"413": b.jsonResponse("Request body too large", handlers.ErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Query failed", handlers.ErrorResponse{}),
+						"504": b.jsonResponse("Query exceeded the server deadline", handlers.ErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/similar": {
+				Post: &Operation{
+					OperationID: "querySimilar",
+					Summary:     "Search vulnerabilities by natural language",
+					Tags:        []string{"query"},
+					RequestBody: b.jsonBody(models.SimilarRequest{}),
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Most similar vulnerability documents", models.SimilarResponse{}),
+						"400": b.jsonResponse("Invalid request", models.ErrorResponse{}),
+						"413": b.jsonResponse("Request body too large", models.ErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Search failed", models.ErrorResponse{}),
+						"503": b.jsonResponse("Embedding or vector search service unavailable", models.ErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/cpe": {
+				Get: &Operation{
+					OperationID: "queryCPE",
+					Summary:     "Find the services a CPE was assigned to",
+					Description: "Lists services whose cpe array contains the CPE, the hosts running them and the CVEs linked through AFFECTED_BY, for debugging CPE-based matches.",
+					Tags:        []string{"query"},
+					Parameters: []Parameter{
+						{Name: "cpe", In: "query", Required: true, Description: "Full CPE 2.3 string", Schema: &jsonschema.Schema{Type: "string"}},
+						{Name: "limit", In: "query", Schema: &jsonschema.Schema{Type: "integer", Minimum: "1", Maximum: "1000", Default: 100}},
+					},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Matching services", models.CPELookupResponse{}),
+						"400": b.jsonResponse("Missing CPE or invalid limit", handlers.ErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Query failed", handlers.ErrorResponse{}),
+						"504": b.jsonResponse("Query exceeded the server deadline", handlers.ErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/cpe/prefix": {
+				Get: &Operation{
+					OperationID: "queryCPEPrefix",
+					Summary:     "Find the services and hosts matching a CPE prefix",
+					Description: "Lists services with a CPE matching the prefix, such as every apache product for cpe:2.3:a:apache:*, the hosts running them and the CVEs linked through AFFECTED_BY. Components match whole; a * component matches any value and a * ending the last component matches values starting with the rest of it.",
+					Tags:        []string{"query"},
+					Parameters: []Parameter{
+						{Name: "prefix", In: "query", Required: true, Description: "CPE 2.3 prefix, e.g. cpe:2.3:a:apache:* or cpe:2.3:a:*:log4j", Schema: &jsonschema.Schema{Type: "string"}},
+						{Name: "limit", In: "query", Schema: &jsonschema.Schema{Type: "integer", Minimum: "1", Maximum: "1000", Default: 100}},
+					},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Matching services and hosts", models.CPEPrefixResponse{}),
+						"400": b.jsonResponse("Invalid prefix or limit", handlers.ErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Query failed", handlers.ErrorResponse{}),
+						"504": b.jsonResponse("Query exceeded the server deadline", handlers.ErrorResponse{}),
+					},
+				},
+			},
+			"/v1/query/exposure": {
+				Get: &Operation{
+					OperationID: "queryExposure",
+					Summary:     "List public hosts exposing risky management ports",
+					Description: "Groups hosts with a public IP by the risky port (SSH, RDP, Redis, MongoDB and similar) they have open, the most exposed port first. Hosts with private or non-routable IPs are left out.",
+					Tags:        []string{"query"},
+					Parameters: []Parameter{
+						{Name: "ports", In: "query", Description: "Comma-separated ports to look for; defaults to the server's risky-port set", Schema: &jsonschema.Schema{Type: "string"}},
+						{Name: "limit", In: "query", Description: "Most hosts listed per port", Schema: &jsonschema.Schema{Type: "integer", Minimum: "1", Maximum: "1000", Default: 100}},
+					},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("Exposed hosts by port", models.ExposureResponse{}),
+						"400": b.jsonResponse("Invalid ports or limit", handlers.ErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Query failed", handlers.ErrorResponse{}),
+						"504": b.jsonResponse("Query exceeded the server deadline", handlers.ErrorResponse{}),
+					},
+				},
+			},
+			"/v1/vuln/{cve}": {
+				Get: &Operation{
+					OperationID: "getVuln",
+					Summary:     "Get a vulnerability's full detail",
+					Description: "Combines the CVE's vuln and vuln_doc records (summary, CVSS, EPSS, KEV, references) with the number of hosts running an affected service.",
+					Tags:        []string{"query"},
+					Parameters:  []Parameter{pathParam("cve", "CVE identifier, e.g. CVE-2021-44228")},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("The vulnerability", models.VulnDetailResponse{}),
+						"400": b.jsonResponse("Malformed CVE identifier", handlers.CodedErrorResponse{}),
+						"404": b.jsonResponse("Vulnerability not found", handlers.CodedErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Vulnerability could not be retrieved", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+			"/v1/scanners/{key}/stats": {
+				Get: &Operation{
+					OperationID: "getScannerStats",
+					Summary:     "Get a scanner's contribution stats",
+					Description: "Aggregates every job submitted with the scanner's key: job counts by outcome, hosts and ports processed, success rate and first and last contribution time.",
+					Tags:        []string{"scanners"},
+					Parameters:  []Parameter{pathParam("key", "Hex SHA-256 of the scanner's public key")},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("The scanner's stats", models.ScannerStats{}),
+						"400": b.jsonResponse("key is not a hex SHA-256", handlers.CodedErrorResponse{}),
+						"404": b.jsonResponse("No jobs from this scanner", handlers.CodedErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Stats could not be retrieved", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+			"/v1/jobs": {
+				Get: &Operation{
+					OperationID: "listJobs",
+					Summary:     "List ingest jobs",
+					Tags:        []string{"jobs"},
+					Parameters: []Parameter{
+						{Name: "limit", In: "query", Schema: &jsonschema.Schema{Type: "integer", Minimum: "1", Maximum: "500", Default: 50}},
+						{Name: "offset", In: "query", Schema: &jsonschema.Schema{Type: "integer", Minimum: "0", Default: 0}},
+						{Name: "state", In: "query", Schema: jobStateSchema()},
+						{Name: "scanner_key", In: "query", Description: "Only jobs submitted with this public key", Schema: &jsonschema.Schema{Type: "string"}},
+						{Name: "order_by", In: "query", Schema: &jsonschema.Schema{Type: "string", Enum: []any{"created_at", "updated_at"}, Default: "created_at"}},
+						{Name: "order_desc", In: "query", Schema: &jsonschema.Schema{Type: "boolean", Default: true}},
+					},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("A page of jobs", models.JobListResponse{}),
+						"400": b.jsonResponse("Invalid filter or pagination parameter", handlers.CodedErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Jobs could not be listed", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+			"/v1/jobs/events": {
+				Get: &Operation{
+					OperationID: "streamJobEvents",
+					Summary:     "Stream job state changes",
+					Description: "Server-Sent Events stream; each event is named job_state and carries a JobEvent as data. Reconnect with Last-Event-ID to resume without gaps.",
+					Tags:        []string{"jobs"},
+					Parameters: []Parameter{
+						{Name: "scanner_key", In: "query", Description: "Only events for jobs submitted with this public key", Schema: &jsonschema.Schema{Type: "string"}},
+						{Name: "last_event_id", In: "query", Description: "Replay events after this ID; alternative to the Last-Event-ID header", Schema: &jsonschema.Schema{Type: "string"}},
+						{Name: "Last-Event-ID", In: "header", Description: "Replay events after this ID", Schema: &jsonschema.Schema{Type: "string"}},
+					},
+					Responses: map[string]*Response{
+						"200": {
+							Description: "Event stream",
+							Content: map[string]*MediaType{
+								"text/event-stream": {Schema: b.schema(models.JobEvent{})},
+							},
+						},
+						"429": rateLimited(),
+						"500": b.jsonResponse("Events could not be replayed", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+			"/v1/jobs/{job_id}": {
+				Get: &Operation{
+					OperationID: "getJob",
+					Summary:     "Get an ingest job",
+					Tags:        []string{"jobs"},
+					Parameters:  []Parameter{pathParam("job_id", "Job ID returned by /v1/mesh/ingest")},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("The job", models.Job{}),
+						"404": b.jsonResponse("Job not found", handlers.CodedErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Job could not be retrieved", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+			"/v1/jobs/{job_id}/cancel": {
+				Post: &Operation{
+					OperationID: "cancelJob",
+					Summary:     "Cancel a pending or processing job",
+					Tags:        []string{"jobs"},
+					Parameters:  []Parameter{pathParam("job_id", "Job ID returned by /v1/mesh/ingest")},
+					Responses: map[string]*Response{
+						"200": b.jsonResponse("The cancelled job", models.Job{}),
+						"404": b.jsonResponse("Job not found", handlers.CodedErrorResponse{}),
+						"409": b.jsonResponse("Job has already finished", handlers.CodedErrorResponse{}),
+						"429": rateLimited(),
+						"500": b.jsonResponse("Job could not be cancelled", handlers.CodedErrorResponse{}),
+					},
+				},
+			},
+		},
+		Components: Components{Schemas: b.schemas},
+	}
+}
+
+// JSON renders the OpenAPI document as indented JSON
+func JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(Spec(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// The reflector points nested references at its own $defs; they live under components instead
+	return bytes.ReplaceAll(data, []byte(`"`+reflectedRefPrefix), []byte(`"`+schemaRefPrefix)), nil
+}
+
+// builder reflects Go types into shared component schemas
+type builder struct {
+	reflector *jsonschema.Reflector
+	schemas   map[string]*jsonschema.Schema
+}
+
+func newBuilder() *builder {
+	return &builder{
+		reflector: &jsonschema.Reflector{
+			Anonymous:                 true,
+			AllowAdditionalProperties: true,
+			Namer: func(t reflect.Type) string {
+				return schemaNames[t]
+			},
+			Mapper: enumSchema,
+		},
+		schemas: make(map[string]*jsonschema.Schema),
+	}
+}
+
+// schema registers v's type (and every type it references) as components and
+// returns a reference to it
+func (b *builder) schema(v any) *jsonschema.Schema {
+	reflected := b.reflector.Reflect(v)
+	for name, def := range reflected.Definitions {
+		b.schemas[name] = def
+	}
+	return &jsonschema.Schema{Ref: reflected.Ref}
+}
+
+// jsonBody describes a required JSON request body of v's type
+func (b *builder) jsonBody(v any) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: b.schema(v)}},
+	}
+}
+
+// jsonResponse describes a JSON response of v's type
+func (b *builder) jsonResponse(description string, v any) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: b.schema(v)}},
+	}
+}
+
+// rateLimited describes the response from the per-client rate limiter
+func rateLimited() *Response {
+	return &Response{Description: "Rate limit exceeded; retry after the Retry-After header"}
+}
+
+// pathParam describes a required string path parameter
+func pathParam(name, description string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &jsonschema.Schema{Type: "string"},
+	}
+}
+
+// enumSchema maps the string enum types in models to schemas listing their values
+func enumSchema(t reflect.Type) *jsonschema.Schema {
+	switch t {
+	case reflect.TypeOf(models.JobState("")):
+		return jobStateSchema()
+	case reflect.TypeOf(models.GraphQueryType("")):
+		return stringEnum(models.QueryByASN, models.QueryByLocation, models.QueryByVuln, models.QueryByService, models.QueryByKEV, models.QueryRelated)
+	case reflect.TypeOf(models.RelationKind("")):
+		return stringEnum(models.AllRelationKinds...)
+	case reflect.TypeOf(models.SearchMode("")):
+		return stringEnum(models.SearchModeVector, models.SearchModeKeyword, models.SearchModeHybrid)
+	}
+	return nil
+}
+
+// jobStateSchema lists the job lifecycle states
+func jobStateSchema() *jsonschema.Schema {
+	return stringEnum(models.JobStatePending, models.JobStateProcessing, models.JobStateCompleted, models.JobStateFailed, models.JobStateCancelled)
+}
+
+// stringEnum builds a string schema restricted to values
+func stringEnum[T ~string](values ...T) *jsonschema.Schema {
+	enum := make([]any, len(values))
+	for i, value := range values {
+		enum[i] = string(value)
+	}
+	return &jsonschema.Schema{Type: "string", Enum: enum}
+}
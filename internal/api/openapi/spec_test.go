@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// specDoc is the generic JSON form of the rendered document
+type specDoc map[string]interface{}
+
+func loadSpec(t *testing.T) specDoc {
+	t.Helper()
+	data, err := JSON()
+	require.NoError(t, err)
+
+	var doc specDoc
+	require.NoError(t, json.Unmarshal(data, &doc))
+	return doc
+}
+
+// collectRefs returns every $ref value in v
+func collectRefs(v interface{}, refs *[]string) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				*refs = append(*refs, ref)
+				continue
+			}
+			collectRefs(child, refs)
+		}
+	case []interface{}:
+		for _, child := range node {
+			collectRefs(child, refs)
+		}
+	}
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// validateSpec checks the structural rules of an OpenAPI 3.1 document that
+// the generator could plausibly get wrong
+func validateSpec(t *testing.T, doc specDoc) {
+	t.Helper()
+
+	assert.Equal(t, Version, doc["openapi"])
+	info, ok := doc["info"].(map[string]interface{})
+	require.True(t, ok, "info is required")
+	assert.NotEmpty(t, info["title"])
+	assert.NotEmpty(t, info["version"])
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	// Every reference resolves to a component schema
+	var refs []string
+	collectRefs(map[string]interface{}(doc), &refs)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		require.True(t, strings.HasPrefix(ref, schemaRefPrefix), "unexpected ref %s", ref)
+		_, ok := schemas[strings.TrimPrefix(ref, schemaRefPrefix)]
+		assert.True(t, ok, "unresolved ref %s", ref)
+	}
+
+	operationIDs := make(map[string]bool)
+	for path, item := range doc["paths"].(map[string]interface{}) {
+		assert.True(t, strings.HasPrefix(path, "/"), "path %s must start with /", path)
+
+		for method, op := range item.(map[string]interface{}) {
+			operation := op.(map[string]interface{})
+			name := method + " " + path
+
+			id, _ := operation["operationId"].(string)
+			assert.NotEmpty(t, id, "%s has no operationId", name)
+			assert.False(t, operationIDs[id], "duplicate operationId %s", id)
+			operationIDs[id] = true
+
+			responses, ok := operation["responses"].(map[string]interface{})
+			require.True(t, ok, "%s has no responses", name)
+			assert.NotEmpty(t, responses)
+			for status, resp := range responses {
+				assert.Regexp(t, `^[1-5]\d\d$`, status, "%s: bad status", name)
+				assert.NotEmpty(t, resp.(map[string]interface{})["description"], "%s %s needs a description", name, status)
+			}
+
+			// Every templated segment is a required path parameter, and vice versa
+			declared := make(map[string]bool)
+			params, _ := operation["parameters"].([]interface{})
+			for _, p := range params {
+				param := p.(map[string]interface{})
+				assert.Contains(t, []string{"path", "query", "header"}, param["in"], "%s: bad parameter location", name)
+				assert.NotNil(t, param["schema"], "%s: parameter %s needs a schema", name, param["name"])
+				if param["in"] == "path" {
+					declared[param["name"].(string)] = true
+					assert.Equal(t, true, param["required"], "%s: path parameter %s must be required", name, param["name"])
+				}
+			}
+			templated := make(map[string]bool)
+			for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+				templated[match[1]] = true
+			}
+			assert.Equal(t, templated, declared, "%s: path parameters", name)
+		}
+	}
+}
+
+func TestSpec_Validates(t *testing.T) {
+	validateSpec(t, loadSpec(t))
+}
+
+func TestSpec_ListsExpectedPaths(t *testing.T) {
+	paths := loadSpec(t)["paths"].(map[string]interface{})
+
+	expected := map[string][]string{
+		"/v1/mesh/ingest":          {"post"},
+		"/v1/query/graph":          {"post"},
+		"/v1/query/similar":        {"post"},
+		"/v1/query/host/{ip}":      {"get"},
+		"/v1/vuln/{cve}":           {"get"},
+		"/v1/query/cpe":            {"get"},
+		"/v1/query/cpe/prefix":     {"get"},
+		"/v1/query/exposure":       {"get"},
+		"/v1/query/diff":           {"post"},
+		"/v1/jobs":                 {"get"},
+		"/v1/jobs/events":          {"get"},
+		"/v1/jobs/{job_id}":        {"get"},
+		"/v1/jobs/{job_id}/cancel": {"post"},
+		"/v1/scanners/{key}/stats": {"get"},
+	}
+	for path, methods := range expected {
+		item, ok := paths[path].(map[string]interface{})
+		if !assert.True(t, ok, "missing path %s", path) {
+			continue
+		}
+		for _, method := range methods {
+			assert.Contains(t, item, method, "%s is missing %s", path, method)
+		}
+	}
+}
+
+func TestSpec_SchemasFollowModels(t *testing.T) {
+	schemas := loadSpec(t)["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	// Field names come from the json tags and required fields from omitempty
+	ingest := schemas["IngestRequest"].(map[string]interface{})
+	properties := ingest["properties"].(map[string]interface{})
+	for _, field := range []string{"version", "data", "public_key", "signature", "timestamp", "callback_url"} {
+		assert.Contains(t, properties, field)
+	}
+	assert.ElementsMatch(t, []interface{}{"version", "data", "public_key", "signature", "timestamp"}, ingest["required"])
+
+	// String enums list their values
+	graph := schemas["GraphQueryRequest"].(map[string]interface{})
+	queryType := graph["properties"].(map[string]interface{})["query_type"].(map[string]interface{})
+	assert.Contains(t, queryType["enum"], "by_kev")
+
+	job := schemas["Job"].(map[string]interface{})
+	createdAt := job["properties"].(map[string]interface{})["created_at"].(map[string]interface{})
+	assert.Equal(t, "date-time", createdAt["format"])
+
+	// Types sharing a name across packages get distinct components
+	assert.Contains(t, schemas, "ErrorResponse")
+	assert.Contains(t, schemas, "GraphErrorResponse")
+}
+
+func TestSpecHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	SpecHandler(zaptest.NewLogger(t))(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var doc specDoc
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, Version, doc["openapi"])
+}
+
+func TestDocsHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	DocsHandler()(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+}
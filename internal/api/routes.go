@@ -4,55 +4,205 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/spectra-red/recon/internal/api/handlers"
 	"github.com/spectra-red/recon/internal/api/middleware"
+	"github.com/spectra-red/recon/internal/api/openapi"
+	"github.com/spectra-red/recon/internal/auth"
 	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/embeddings"
+	"github.com/spectra-red/recon/internal/events"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/schedule"
+	"github.com/spectra-red/recon/internal/webhook"
 	"github.com/surrealdb/surrealdb.go"
 	"go.uber.org/zap"
 )
 
 // SetupRoutes configures all routes and middleware for the API server
 func SetupRoutes(logger *zap.Logger, dbClient *surrealdb.DB) *chi.Mux {
+	return SetupRoutesWithInFlight(context.Background(), logger, dbClient, NewIngestInFlightLimiter(logger))
+}
+
+// SetupRoutesWithInFlight configures the routes around an ingest in-flight limiter
+// owned by the caller, which drains it on shutdown. Background work the routes
+// depend on, such as job event polling, stops when ctx is cancelled.
+func SetupRoutesWithInFlight(ctx context.Context, logger *zap.Logger, dbClient *surrealdb.DB, ingestInFlight *middleware.InFlightLimiter) *chi.Mux {
 	r := chi.NewRouter()
 
+	// Proxies whose X-Forwarded-For identifies the client for rate limiting
+	// and audit entries (empty trusts none)
+	trustedProxies, err := middleware.ParseTrustedProxies(getEnv("TRUSTED_PROXY_CIDRS", ""))
+	if err != nil {
+		logger.Warn("invalid TRUSTED_PROXY_CIDRS, trusting no proxies",
+			zap.String("value", os.Getenv("TRUSTED_PROXY_CIDRS")),
+			zap.Error(err))
+		trustedProxies = nil
+	}
+
 	// Middleware chain - order matters!
 	// 1. Request ID - must be first to ensure all logs have request IDs
 	r.Use(middleware.RequestID())
 
-	// 2. Logger - logs all requests with request IDs
+	// 2. Tracing - starts a server span, continuing the caller's trace if one is propagated
+	r.Use(middleware.Tracing())
+
+	// 3. Logger - logs all requests with request IDs
 	r.Use(middleware.Logger(logger))
 
-	// 3. Recoverer - recovers from panics
+	// 4. Recoverer - recovers from panics
 	r.Use(chimiddleware.Recoverer)
 
+	// 5. Client IP - resolves the caller's address through trusted proxies for audit entries
+	r.Use(middleware.ResolveClientIP(trustedProxies))
+
 	// Health check endpoint (no authentication required)
-	r.Get("/health", handlers.HealthHandler(logger))
+	r.Get("/health", handlers.HealthHandler(dbClient, logger))
 
-	// Initialize rate limiter for ingest endpoint (60 requests per minute per scanner)
-	ingestRateLimiter := middleware.NewRateLimiter(60, logger)
-	// Start background cleanup of stale rate limit buckets (every 10 minutes, remove buckets older than 1 hour)
-	ingestRateLimiter.StartCleanupRoutine(10*time.Minute, 1*time.Hour)
+	// Readiness check: fails once the server starts draining for shutdown
+	r.Get("/readyz", handlers.ReadyHandler(ingestInFlight, logger))
 
-	// Initialize rate limiter for query endpoints (30 requests per minute per user)
-	queryRateLimiter := middleware.NewRateLimiter(30, logger)
-	queryRateLimiter.StartCleanupRoutine(10*time.Minute, 1*time.Hour)
+	// API description (no authentication required)
+	// GET /openapi.json - OpenAPI 3.1 document; GET /docs - Swagger UI for it
+	r.Get("/openapi.json", openapi.SpecHandler(logger))
+	r.Get("/docs", openapi.DocsHandler())
+
+	// Spread background cleanup so replicas don't run it in lockstep (0 disables)
+	cleanupJitter, err := strconv.ParseFloat(getEnv("SCHEDULE_JITTER", strconv.FormatFloat(schedule.DefaultJitter, 'f', -1, 64)), 64)
+	if err != nil || cleanupJitter < 0 || cleanupJitter >= 1 {
+		logger.Warn("invalid SCHEDULE_JITTER, using default",
+			zap.String("value", os.Getenv("SCHEDULE_JITTER")),
+			zap.Float64("default", schedule.DefaultJitter))
+		cleanupJitter = schedule.DefaultJitter
+	}
 
 	// Get Restate URL from environment (for workflow triggering)
 	restateURL := getEnv("RESTATE_URL", "http://localhost:8080")
 
+	// Get the accepted clock skew for signed envelopes (e.g. "10m" for scanners with unreliable clocks)
+	timestampWindow, err := time.ParseDuration(getEnv("INGEST_TIMESTAMP_WINDOW", auth.TimestampWindow.String()))
+	if err != nil || timestampWindow <= 0 {
+		logger.Warn("invalid INGEST_TIMESTAMP_WINDOW, using default",
+			zap.String("value", os.Getenv("INGEST_TIMESTAMP_WINDOW")),
+			zap.Duration("default", auth.TimestampWindow))
+		timestampWindow = auth.TimestampWindow
+	}
+
+	// Request body caps: scan submissions can be large, query bodies are tiny
+	ingestMaxBodyBytes := parseByteLimit(logger, "INGEST_MAX_BODY_BYTES", handlers.DefaultIngestMaxBodyBytes)
+	queryMaxBodyBytes := parseByteLimit(logger, "QUERY_MAX_BODY_BYTES", handlers.DefaultQueryMaxBodyBytes)
+
+	// Our own scanner fleet, by network or public key, bypasses the ingest rate limit
+	exemptNetworks, err := middleware.ParseTrustedProxies(getEnv("RATE_LIMIT_EXEMPT_CIDRS", ""))
+	if err != nil {
+		logger.Warn("invalid RATE_LIMIT_EXEMPT_CIDRS, exempting no networks",
+			zap.String("value", os.Getenv("RATE_LIMIT_EXEMPT_CIDRS")),
+			zap.Error(err))
+		exemptNetworks = nil
+	}
+	var exemptKeys []string
+	for _, key := range strings.Split(getEnv("RATE_LIMIT_EXEMPT_KEYS", ""), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			exemptKeys = append(exemptKeys, key)
+		}
+	}
+
+	// Initialize rate limiter for ingest endpoint (60 requests per minute per scanner)
+	ingestRateLimiter := middleware.NewRateLimiterWithConfig(middleware.RateLimiterConfig{
+		RequestsPerMinute: 60,
+		TrustedProxies:    trustedProxies,
+		ExemptNetworks:    exemptNetworks,
+		ExemptKeys:        exemptKeys,
+		ExemptPeekBytes:   ingestMaxBodyBytes,
+		MaxSkew:           timestampWindow,
+	}, logger)
+	// Start background cleanup of stale rate limit buckets (about every 10 minutes, remove buckets older than 1 hour)
+	ingestRateLimiter.StartCleanupRoutineWithJitter(10*time.Minute, 1*time.Hour, cleanupJitter)
+
+	// Initialize rate limiter for query endpoints (30 requests per minute per user)
+	queryRateLimiter := middleware.NewRateLimiterWithConfig(middleware.RateLimiterConfig{
+		RequestsPerMinute: 30,
+		TrustedProxies:    trustedProxies,
+	}, logger)
+	queryRateLimiter.StartCleanupRoutineWithJitter(10*time.Minute, 1*time.Hour, cleanupJitter)
+
+	// Server-side cap on graph query duration, independent of the client's own deadline
+	graphMaxQueryDuration, err := time.ParseDuration(getEnv("GRAPH_MAX_QUERY_DURATION", db.DefaultMaxQueryDuration.String()))
+	if err != nil || graphMaxQueryDuration <= 0 {
+		logger.Warn("invalid GRAPH_MAX_QUERY_DURATION, using default",
+			zap.String("value", os.Getenv("GRAPH_MAX_QUERY_DURATION")),
+			zap.Duration("default", db.DefaultMaxQueryDuration))
+		graphMaxQueryDuration = db.DefaultMaxQueryDuration
+	}
+
+	// Hosts that ingest completion callbacks may target; empty disables callbacks
+	callbackAllowlist := webhook.ParseAllowlist(getEnv("INGEST_CALLBACK_ALLOWED_HOSTS", ""))
+
+	// Ingests and admin actions are recorded to the audit table
+	auditLogger := db.NewAuditLogger(dbClient, logger)
+
+	// Trusted ingest accepts unsigned scans attributed to this scanner key; empty disables it
+	trustedIngest := handlers.NewTrustedIngestHandler(logger, dbClient, restateURL, getEnv("INGEST_TRUSTED_SCANNER_KEY", ""), ingestInFlight, ingestMaxBodyBytes, auditLogger)
+	if trustedIngest.Enabled() {
+		logger.Warn("trusted ingest enabled: POST /v1/internal/ingest accepts unsigned scans without authentication",
+			zap.String("scanner_key", middleware.MaskKey(os.Getenv("INGEST_TRUSTED_SCANNER_KEY"))))
+	}
+
+	// Destructive admin endpoints require this bearer token; empty disables them
+	adminToken := getEnv("ADMIN_API_TOKEN", "")
+
+	// Ports the exposure report looks for unless a request names some (empty uses models.DefaultRiskyPorts)
+	exposurePorts, err := models.ParsePortList(getEnv("EXPOSURE_RISKY_PORTS", ""))
+	if err != nil {
+		logger.Warn("invalid EXPOSURE_RISKY_PORTS, using default",
+			zap.String("value", os.Getenv("EXPOSURE_RISKY_PORTS")),
+			zap.Error(err))
+		exposurePorts = nil
+	}
+
+	// Job events are written by the workflow service; poll them into a broker for SSE subscribers
+	jobEventsPollInterval, err := time.ParseDuration(getEnv("JOB_EVENTS_POLL_INTERVAL", events.DefaultPollInterval.String()))
+	if err != nil || jobEventsPollInterval <= 0 {
+		logger.Warn("invalid JOB_EVENTS_POLL_INTERVAL, using default",
+			zap.String("value", os.Getenv("JOB_EVENTS_POLL_INTERVAL")),
+			zap.Duration("default", events.DefaultPollInterval))
+		jobEventsPollInterval = events.DefaultPollInterval
+	}
+	jobEventStore := db.NewJobEventStore(dbClient, logger)
+	jobEvents := events.NewBroker(events.DefaultSubscriberBuffer, logger)
+	go jobEvents.Poll(ctx, jobEventStore, jobEventsPollInterval)
+
+	// Optional graph query result cache; off unless GRAPH_CACHE_TTL is set.
+	// Completed ingests invalidate it, so the TTL only bounds staleness from enrichment.
+	graphCache := setupGraphCache(ctx, logger, jobEvents)
+
+	// Every graph endpoint shares one executor on the shared database connection
+	graphQueries := handlers.NewGraphQueryHandlerWithCache(dbClient, logger, graphMaxQueryDuration, graphCache).
+		WithMaxBodyBytes(queryMaxBodyBytes).
+		WithRiskyPorts(exposurePorts)
+
 	// API routes under /v1 prefix
 	r.Route("/v1", func(r chi.Router) {
 		// Mesh ingest endpoint with rate limiting
 		r.Route("/mesh", func(r chi.Router) {
 			r.With(middleware.RateLimitMiddleware(ingestRateLimiter)).
-				Post("/ingest", handlers.IngestHandler(logger, dbClient, restateURL))
+				Post("/ingest", handlers.IngestHandler(logger, dbClient, restateURL, timestampWindow, ingestInFlight, ingestMaxBodyBytes, callbackAllowlist, auditLogger))
+		})
+
+		// POST /v1/internal/ingest - Raw ScanData without a signed envelope (404 unless INGEST_TRUSTED_SCANNER_KEY is set)
+		r.Route("/internal", func(r chi.Router) {
+			r.With(middleware.RateLimitMiddleware(ingestRateLimiter)).
+				Post("/ingest", trustedIngest.ServeHTTP)
 		})
 
+		// GET /v1/stats - Operational stats (ingest queue depth)
+		r.Get("/stats", handlers.StatsHandler(ingestInFlight, ingestRateLimiter, logger))
+
 		// Job tracking endpoints
 		r.Route("/jobs", func(r chi.Router) {
 			// Apply rate limiting to job endpoints
@@ -62,10 +212,86 @@ func SetupRoutes(logger *zap.Logger, dbClient *surrealdb.DB) *chi.Mux {
 			// Query params: ?limit=50&offset=0&state=pending&scanner_key=xyz&order_by=created_at&order_desc=true
 			r.Get("/", handlers.ListJobsHandler(dbClient, logger))
 
+			// GET /v1/jobs/events - Server-Sent Events stream of job state changes
+			// Query params: ?scanner_key=xyz; resume with the Last-Event-ID header
+			r.Get("/events", handlers.JobEventsHandler(jobEvents, jobEventStore, logger))
+
 			// GET /v1/jobs/{job_id} - Get job status by ID
 			r.Get("/{job_id}", handlers.GetJobHandler(dbClient, logger))
+
+			// POST /v1/jobs/{job_id}/cancel - Cancel a pending or processing job
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Post("/{job_id}/cancel", handlers.CancelJobHandler(dbClient, logger))
 		})
 
+		// Admin endpoints
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.RateLimitMiddleware(queryRateLimiter))
+
+			deadLetters := handlers.NewDeadLetterHandler(db.NewDeadLetterStore(dbClient, logger), restateURL, auditLogger, logger)
+
+			// GET /v1/admin/dead-letters - Enrichment items that exhausted their retries
+			// Query params: ?stage=asn|geo&limit=50
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Get("/dead-letters", deadLetters.HandleList)
+
+			// POST /v1/admin/dead-letters/requeue - Re-submit a dead-lettered IP and clear its record
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Post("/dead-letters/requeue", deadLetters.HandleRequeue)
+
+			// POST /v1/admin/reenrich - Queue hosts (asn, country) or services (missing_cpe, stale_before) for enrichment again
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Post("/reenrich", handlers.NewReenrichHandler(db.NewReenrichStore(dbClient, logger), handlers.NewRestateInvoker(restateURL), auditLogger, logger).HandleReenrich)
+
+			// GET /v1/admin/unidentified-services - Raw banners of services with no product or CPE, most common first
+			// Query params: ?limit=20
+			r.Get("/unidentified-services", graphQueries.HandleUnidentifiedServices)
+
+			// POST /v1/admin/migrate - Apply pending schema migrations; body {"dry_run": true} only lists them
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Post("/migrate", handlers.MigrateHandler(db.NewMigrator(dbClient, logger), auditLogger, logger))
+
+			// POST /v1/admin/redact - Remove a host ({"ip"}) or every host in a network ({"cidr"}) with its edges and orphaned ports
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Post("/redact", handlers.RedactHandler(db.NewHostRedactor(dbClient, logger), func() {
+					if graphCache != nil {
+						graphCache.BumpEpoch()
+					}
+				}, auditLogger, logger))
+
+			// GET /v1/admin/audit - Ingests and admin actions, most recent first
+			// Query params: ?since=&until=&actor=admin|scanner_key=xyz&action=ingest&limit=100
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Get("/audit", handlers.AuditHandler(auditLogger, logger))
+
+			// GET /v1/admin/export - Stream the graph as JSON lines, one node or edge per line, nodes first
+			// Query params: ?tables=host,port,HAS (default: every node and edge table)
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Get("/export", handlers.ExportHandler(db.NewGraphExporter(dbClient, logger), auditLogger, logger))
+
+			// POST /v1/admin/import - Upsert the JSON lines of an export; malformed lines are skipped and reported
+			// Requires "Authorization: Bearer $ADMIN_API_TOKEN" (404 unless ADMIN_API_TOKEN is set)
+			r.With(middleware.AdminAuth(adminToken, logger)).
+				Post("/import", handlers.ImportHandler(db.NewGraphImporter(dbClient, logger), auditLogger, logger))
+		})
+
+		// GET /v1/scanners/{key}/stats - Jobs, hosts, ports and success rate of one scanner
+		// {key} is the hex SHA-256 of the scanner's public key
+		r.With(middleware.RateLimitMiddleware(queryRateLimiter)).
+			Get("/scanners/{key}/stats", handlers.ScannerStatsHandler(db.NewScannerStatsStore(dbClient, logger), logger))
+
+		// GET /v1/vuln/{cve} - Full detail of a single CVE with its affected host count
+		r.With(middleware.RateLimitMiddleware(queryRateLimiter)).
+			Get("/vuln/{cve}", handlers.VulnDetailHandler(db.NewVulnStore(dbClient, logger), logger))
+
 		// Query endpoints
 		r.Route("/query", func(r chi.Router) {
 			// Apply rate limiting to all query endpoints
@@ -73,15 +299,35 @@ func SetupRoutes(logger *zap.Logger, dbClient *surrealdb.DB) *chi.Mux {
 
 			// GET /v1/query/host/{ip} - Query host by IP with optional depth parameter
 			// Query params: ?depth=0-5 (default: 2)
-			r.Get("/host/{ip}", handlers.QueryHandler(logger))
+			r.Get("/host/{ip}", handlers.QueryHandler(dbClient, logger))
 
 			// POST /v1/query/graph - Advanced graph traversal queries
-			// Supports: by_asn, by_location, by_vuln, by_service
-			r.Post("/graph", handlers.GraphQueryHandlerFunc(logger))
+			// Supports: by_asn, by_location, by_vuln, by_service, by_kev, related
+			r.Post("/graph", graphQueries.HandleGraphQuery)
+
+			// GET /v1/query/aggregate/asn - Host and port counts per ASN, sorted descending
+			// Query params: ?limit=20 (top-N, max 1000)
+			r.Get("/aggregate/asn", graphQueries.HandleAggregateByASN)
+
+			// GET /v1/query/cpe - Services assigned a CPE and the CVEs it matched them to
+			// Query params: ?cpe=cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*&limit=100
+			r.Get("/cpe", graphQueries.HandleCPELookup)
+
+			// GET /v1/query/cpe/prefix - Services with a CPE matching a prefix and the hosts running them
+			// Query params: ?prefix=cpe:2.3:a:apache:*&limit=100
+			r.Get("/cpe/prefix", graphQueries.HandleCPEPrefix)
+
+			// GET /v1/query/exposure - Public hosts exposing risky management ports, grouped by port
+			// Query params: ?ports=22,3389&limit=100 (hosts listed per port, max 1000)
+			r.Get("/exposure", graphQueries.HandleExposure)
+
+			// POST /v1/query/diff - Hosts, ports, services and CVEs that changed between two scan snapshots
+			// Scoped to a CIDR or an ASN
+			r.Post("/diff", graphQueries.HandleDiff)
 
 			// POST /v1/query/similar - Vector similarity search for vulnerabilities
 			// Accepts natural language query, returns top K similar vulnerability documents
-			r.Post("/similar", setupSimilarityHandler(logger))
+			r.Post("/similar", setupSimilarityHandler(dbClient, logger, queryMaxBodyBytes))
 		})
 	})
 
@@ -106,10 +352,66 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// parseByteLimit reads a positive byte count from the environment, falling back to defaultValue
+func parseByteLimit(logger *zap.Logger, key string, defaultValue int64) int64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 {
+		logger.Warn("invalid "+key+", using default",
+			zap.String("value", value),
+			zap.Int64("default", defaultValue))
+		return defaultValue
+	}
+	return limit
+}
+
+// setupGraphCache builds the graph query cache from GRAPH_CACHE_TTL and
+// GRAPH_CACHE_MAX_ENTRIES, returning nil (caching off) when no TTL is set.
+// Every completed ingest bumps the cache epoch so new hosts are visible immediately,
+// until ctx is cancelled.
+func setupGraphCache(ctx context.Context, logger *zap.Logger, jobEvents *events.Broker) *db.GraphQueryCache {
+	value := getEnv("GRAPH_CACHE_TTL", "")
+	if value == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		logger.Warn("invalid GRAPH_CACHE_TTL, graph query caching disabled",
+			zap.String("value", value))
+		return nil
+	}
+
+	maxEntries, err := strconv.Atoi(getEnv("GRAPH_CACHE_MAX_ENTRIES", strconv.Itoa(db.DefaultGraphCacheMaxEntries)))
+	if err != nil || maxEntries <= 0 {
+		logger.Warn("invalid GRAPH_CACHE_MAX_ENTRIES, using default",
+			zap.String("value", os.Getenv("GRAPH_CACHE_MAX_ENTRIES")),
+			zap.Int("default", db.DefaultGraphCacheMaxEntries))
+		maxEntries = db.DefaultGraphCacheMaxEntries
+	}
+
+	cache := db.NewGraphQueryCache(ttl, maxEntries)
+	go jobEvents.Watch(ctx, func(event models.JobEvent) {
+		if event.State == models.JobStateCompleted {
+			cache.BumpEpoch()
+		}
+	}, func() {
+		// Completions may have been missed while the watcher was lagging
+		cache.BumpEpoch()
+	})
+
+	logger.Info("graph query cache enabled",
+		zap.Duration("ttl", ttl),
+		zap.Int("max_entries", maxEntries))
+	return cache
+}
+
 // setupSimilarityHandler initializes and returns the similarity search handler
 // This function handles the initialization of dependencies (embedding client, vector search client)
 // and returns a configured handler function with graceful degradation if services are unavailable
-func setupSimilarityHandler(logger *zap.Logger) http.HandlerFunc {
+func setupSimilarityHandler(dbClient *surrealdb.DB, logger *zap.Logger, maxBodyBytes int64) http.HandlerFunc {
 	// Initialize embedding client from environment
 	embeddingClient, err := embeddings.NewClientFromEnv(logger)
 	if err != nil {
@@ -125,26 +427,43 @@ func setupSimilarityHandler(logger *zap.Logger) http.HandlerFunc {
 		}
 	}
 
-	// Initialize vector search client
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	vectorClient, err := db.CreateVectorSearchClient(ctx, logger)
-	if err != nil {
-		logger.Warn("failed to initialize vector search client",
-			zap.Error(err),
-			zap.String("hint", "similarity search will return errors until database is available"))
+	// Vector search runs on the shared database connection
+	vectorClient := db.NewVectorSearchClient(dbClient, logger)
 
-		// Return a handler that always returns an error about database unavailability
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte(`{"error":"database service not available","code":"SERVICE_UNAVAILABLE","details":"The vector search database is not available. Please ensure SurrealDB is running and accessible.","timestamp":"` + time.Now().UTC().Format(time.RFC3339) + `"}`))
-		}
+	// Request limits are configurable per deployment (e.g. research instances allow larger K)
+	similarConfig := handlers.DefaultSimilarConfig()
+	similarConfig.MaxBodyBytes = maxBodyBytes
+	if maxK, err := strconv.Atoi(getEnv("SIMILAR_MAX_K", "")); err == nil && maxK > 0 {
+		similarConfig.MaxK = maxK
+	}
+	if maxQueryLength, err := strconv.Atoi(getEnv("SIMILAR_MAX_QUERY_LENGTH", "")); err == nil && maxQueryLength > 0 {
+		similarConfig.MaxQueryLength = maxQueryLength
 	}
 
-	logger.Info("similarity search endpoint initialized successfully")
+	logger.Info("similarity search endpoint initialized successfully",
+		zap.Int("max_k", similarConfig.MaxK),
+		zap.Int("max_query_length", similarConfig.MaxQueryLength))
 
 	// Return the configured handler
-	return handlers.SimilarHandlerFunc(embeddingClient, vectorClient, logger)
+	return handlers.NewSimilarHandlerWithConfig(embeddingClient, vectorClient, similarConfig, logger).ServeHTTP
+}
+
+// NewIngestInFlightLimiter bounds the number of ingest workflows in flight from
+// INGEST_MAX_IN_FLIGHT and INGEST_RETRY_AFTER; beyond it the ingest endpoint returns 503
+func NewIngestInFlightLimiter(logger *zap.Logger) *middleware.InFlightLimiter {
+	maxInFlight, err := strconv.Atoi(getEnv("INGEST_MAX_IN_FLIGHT", "1000"))
+	if err != nil || maxInFlight <= 0 {
+		logger.Warn("invalid INGEST_MAX_IN_FLIGHT, using default",
+			zap.String("value", os.Getenv("INGEST_MAX_IN_FLIGHT")),
+			zap.Int("default", 1000))
+		maxInFlight = 1000
+	}
+	retryAfter, err := time.ParseDuration(getEnv("INGEST_RETRY_AFTER", "30s"))
+	if err != nil || retryAfter < time.Second {
+		logger.Warn("invalid INGEST_RETRY_AFTER, using default",
+			zap.String("value", os.Getenv("INGEST_RETRY_AFTER")),
+			zap.Duration("default", 30*time.Second))
+		retryAfter = 30 * time.Second
+	}
+	return middleware.NewInFlightLimiter(maxInFlight, retryAfter)
 }
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// ECDSAP256PublicKeySize is the size of an uncompressed SEC 1 encoded P-256 public key
+	ECDSAP256PublicKeySize = 65
+	// ECDSAP256SignatureSize is the size of a fixed-width r || s P-256 signature
+	ECDSAP256SignatureSize = 64
+)
+
+// VerifyECDSAP256Signature verifies an ECDSA P-256 signature over the SHA-256 digest of message
+// The public key must be an uncompressed SEC 1 point and the signature a fixed-width r || s pair
+func VerifyECDSAP256Signature(publicKey, message, signature []byte) error {
+	if len(publicKey) != ECDSAP256PublicKeySize {
+		return fmt.Errorf("%w: public key must be %d bytes, got %d",
+			ErrInvalidPublicKey, ECDSAP256PublicKeySize, len(publicKey))
+	}
+
+	if len(signature) != ECDSAP256SignatureSize {
+		return fmt.Errorf("%w: signature must be %d bytes, got %d",
+			ErrInvalidSignature, ECDSAP256SignatureSize, len(signature))
+	}
+
+	pubKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), publicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	digest := sha256.Sum256(message)
+
+	if !ecdsa.Verify(pubKey, digest[:], r, s) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// GenerateTestECDSAKey generates an ECDSA P-256 keypair for testing purposes
+func GenerateTestECDSAKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// SignEnvelopeECDSAP256 builds a signed envelope using an ECDSA P-256 key
+func SignEnvelopeECDSAP256(privKey *ecdsa.PrivateKey, data json.RawMessage, timestamp int64) (ScanEnvelope, error) {
+	digest := sha256.Sum256(SigningMessage(timestamp, data))
+
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, digest[:])
+	if err != nil {
+		return ScanEnvelope{}, fmt.Errorf("failed to sign envelope: %w", err)
+	}
+
+	signature := make([]byte, ECDSAP256SignatureSize)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	publicKey, err := privKey.PublicKey.Bytes()
+	if err != nil {
+		return ScanEnvelope{}, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	return ScanEnvelope{
+		Version:   EnvelopeVersion,
+		Algorithm: AlgorithmECDSAP256,
+		Data:      data,
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		Signature: base64.StdEncoding.EncodeToString(signature),
+		Timestamp: timestamp,
+	}, nil
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEnvelope_Algorithms(t *testing.T) {
+	data := json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`)
+	timestamp := time.Now().Unix()
+
+	_, edPriv, err := GenerateTestKey()
+	require.NoError(t, err)
+
+	ecPriv, err := GenerateTestECDSAKey()
+	require.NoError(t, err)
+
+	edEnv := SignEnvelope(edPriv, data, timestamp)
+
+	ecEnv, err := SignEnvelopeECDSAP256(ecPriv, data, timestamp)
+	require.NoError(t, err)
+
+	t.Run("ed25519 without algorithm field", func(t *testing.T) {
+		env := edEnv
+		env.Algorithm = ""
+		assert.NoError(t, VerifyEnvelope(env))
+	})
+
+	t.Run("ed25519 explicit", func(t *testing.T) {
+		env := edEnv
+		env.Algorithm = AlgorithmEd25519
+		assert.NoError(t, VerifyEnvelope(env))
+	})
+
+	t.Run("ecdsa-p256", func(t *testing.T) {
+		assert.Equal(t, AlgorithmECDSAP256, ecEnv.Algorithm)
+		assert.NoError(t, VerifyEnvelope(ecEnv))
+	})
+
+	t.Run("ecdsa-p256 tampered data", func(t *testing.T) {
+		env := ecEnv
+		env.Data = json.RawMessage(`{"hosts":[{"ip":"5.6.7.8"}]}`)
+		assert.ErrorIs(t, VerifyEnvelope(env), ErrInvalidSignature)
+	})
+
+	t.Run("ed25519 key declared as ecdsa-p256", func(t *testing.T) {
+		env := edEnv
+		env.Algorithm = AlgorithmECDSAP256
+		assert.ErrorIs(t, VerifyEnvelope(env), ErrInvalidPublicKey)
+	})
+
+	t.Run("ecdsa-p256 key declared as ed25519", func(t *testing.T) {
+		env := ecEnv
+		env.Algorithm = AlgorithmEd25519
+		assert.ErrorIs(t, VerifyEnvelope(env), ErrInvalidPublicKey)
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		env := edEnv
+		env.Algorithm = "rsa-2048"
+		assert.ErrorIs(t, VerifyEnvelope(env), ErrUnsupportedAlgorithm)
+	})
+}
+
+func TestVerifyECDSAP256Signature(t *testing.T) {
+	privKey, err := GenerateTestECDSAKey()
+	require.NoError(t, err)
+
+	env, err := SignEnvelopeECDSAP256(privKey, json.RawMessage(`{"test":"data"}`), time.Now().Unix())
+	require.NoError(t, err)
+
+	pubKey, err := base64.StdEncoding.DecodeString(env.PublicKey)
+	require.NoError(t, err)
+	signature, err := base64.StdEncoding.DecodeString(env.Signature)
+	require.NoError(t, err)
+	message := SigningMessage(env.Timestamp, env.Data)
+
+	tests := []struct {
+		name      string
+		publicKey []byte
+		message   []byte
+		signature []byte
+		wantErr   error
+	}{
+		{
+			name:      "valid signature",
+			publicKey: pubKey,
+			message:   message,
+			signature: signature,
+		},
+		{
+			name:      "invalid public key length",
+			publicKey: make([]byte, ed25519.PublicKeySize),
+			message:   message,
+			signature: signature,
+			wantErr:   ErrInvalidPublicKey,
+		},
+		{
+			name:      "public key not on curve",
+			publicKey: append([]byte{0x04}, make([]byte, 64)...),
+			message:   message,
+			signature: signature,
+			wantErr:   ErrInvalidPublicKey,
+		},
+		{
+			name:      "invalid signature length",
+			publicKey: pubKey,
+			message:   message,
+			signature: []byte("short"),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "tampered message",
+			publicKey: pubKey,
+			message:   []byte("different message"),
+			signature: signature,
+			wantErr:   ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyECDSAP256Signature(tt.publicKey, tt.message, tt.signature)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package auth
 import (
 	"crypto/ed25519"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -18,26 +20,82 @@ var (
 	ErrExpiredTimestamp = errors.New("timestamp outside acceptable window")
 	// ErrMissingData is returned when required envelope data is missing
 	ErrMissingData = errors.New("missing required envelope data")
+	// ErrUnsupportedVersion is returned when the envelope version is not recognized
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+	// ErrUnsupportedAlgorithm is returned when the envelope signature algorithm is not recognized
+	ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
 )
 
+// Supported envelope signature algorithms
+const (
+	AlgorithmEd25519   = "ed25519"
+	AlgorithmECDSAP256 = "ecdsa-p256"
+)
+
+// signatureVerifiers maps each supported algorithm to its verifier
+// Each verifier enforces the public key and signature lengths for its scheme
+var signatureVerifiers = map[string]func(publicKey, message, signature []byte) error{
+	AlgorithmEd25519:   VerifySignature,
+	AlgorithmECDSAP256: VerifyECDSAP256Signature,
+}
+
 // TimestampWindow defines the acceptable time window for request timestamps (±5 minutes)
 const TimestampWindow = 5 * time.Minute
 
+// EnvelopeVersion is the current envelope format version
+// Version 1 signs the length-prefixed preimage built by SigningMessage
+const EnvelopeVersion = 1
+
+// SigningDomain separates envelope signatures from signatures made with the
+// same key for any other purpose
+const SigningDomain = "spectra-v1"
+
 // ScanEnvelope represents a signed scan submission
 type ScanEnvelope struct {
+	Version   int             `json:"version"`
+	Algorithm string          `json:"algorithm,omitempty"` // defaults to ed25519
 	Data      json.RawMessage `json:"data"`
 	PublicKey string          `json:"public_key"`
 	Signature string          `json:"signature"`
 	Timestamp int64           `json:"timestamp"`
 }
 
-// VerifyEnvelope validates the Ed25519 signature on a scan envelope
+// SigningMessage builds the preimage that is signed for an envelope
+// Format: domain || len(ts) || ts || len(data) || data, where lengths are
+// 4-byte big-endian and ts is the decimal timestamp. Length prefixes make the
+// split between timestamp and data unambiguous.
+func SigningMessage(timestamp int64, data []byte) []byte {
+	ts := strconv.FormatInt(timestamp, 10)
+
+	message := make([]byte, 0, len(SigningDomain)+4+len(ts)+4+len(data))
+	message = append(message, SigningDomain...)
+	message = binary.BigEndian.AppendUint32(message, uint32(len(ts)))
+	message = append(message, ts...)
+	message = binary.BigEndian.AppendUint32(message, uint32(len(data)))
+	message = append(message, data...)
+
+	return message
+}
+
+// VerifyEnvelope validates the signature on a scan envelope
 // It performs the following checks:
-// 1. Timestamp freshness (±5 minutes from current time)
-// 2. Public key format validation
-// 3. Signature format validation
-// 4. Cryptographic signature verification
+// 1. Envelope version and signature algorithm
+// 2. Timestamp freshness (±5 minutes from current time)
+// 3. Public key format validation
+// 4. Signature format validation
+// 5. Cryptographic signature verification
 func VerifyEnvelope(env ScanEnvelope) error {
+	return VerifyEnvelopeWithConfig(env, TimestampWindow)
+}
+
+// VerifyEnvelopeWithConfig validates a scan envelope like VerifyEnvelope, but accepts
+// timestamps up to maxSkew from the current time. A non-positive maxSkew falls back
+// to TimestampWindow.
+func VerifyEnvelopeWithConfig(env ScanEnvelope, maxSkew time.Duration) error {
+	if maxSkew <= 0 {
+		maxSkew = TimestampWindow
+	}
+
 	// Validate required fields
 	if len(env.Data) == 0 {
 		return fmt.Errorf("%w: data is empty", ErrMissingData)
@@ -52,14 +110,29 @@ func VerifyEnvelope(env ScanEnvelope) error {
 		return fmt.Errorf("%w: timestamp is zero", ErrMissingData)
 	}
 
+	if env.Version != EnvelopeVersion {
+		return fmt.Errorf("%w: got %d, expected %d",
+			ErrUnsupportedVersion, env.Version, EnvelopeVersion)
+	}
+
+	algorithm := env.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmEd25519
+	}
+
+	verify, ok := signatureVerifiers[algorithm]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, env.Algorithm)
+	}
+
 	// Check timestamp freshness
 	requestTime := time.Unix(env.Timestamp, 0)
 	now := time.Now()
 	timeDiff := now.Sub(requestTime).Abs()
 
-	if timeDiff > TimestampWindow {
+	if timeDiff > maxSkew {
 		return fmt.Errorf("%w: timestamp %v is %v from current time (max %v)",
-			ErrExpiredTimestamp, requestTime, timeDiff, TimestampWindow)
+			ErrExpiredTimestamp, requestTime, timeDiff, maxSkew)
 	}
 
 	// Decode base64-encoded public key
@@ -68,32 +141,17 @@ func VerifyEnvelope(env ScanEnvelope) error {
 		return fmt.Errorf("%w: failed to decode public key: %v", ErrInvalidPublicKey, err)
 	}
 
-	if len(pubKeyBytes) != ed25519.PublicKeySize {
-		return fmt.Errorf("%w: public key must be %d bytes, got %d",
-			ErrInvalidPublicKey, ed25519.PublicKeySize, len(pubKeyBytes))
-	}
-
 	// Decode base64-encoded signature
 	sigBytes, err := base64.StdEncoding.DecodeString(env.Signature)
 	if err != nil {
 		return fmt.Errorf("%w: failed to decode signature: %v", ErrInvalidSignature, err)
 	}
 
-	if len(sigBytes) != ed25519.SignatureSize {
-		return fmt.Errorf("%w: signature must be %d bytes, got %d",
-			ErrInvalidSignature, ed25519.SignatureSize, len(sigBytes))
-	}
-
-	// Construct the message that was signed
-	// Format: timestamp + data (this ensures timestamp binding)
-	message := append([]byte(fmt.Sprintf("%d", env.Timestamp)), env.Data...)
+	// Construct the message that was signed (binds the timestamp to the data)
+	message := SigningMessage(env.Timestamp, env.Data)
 
-	// Verify the signature
-	if !ed25519.Verify(pubKeyBytes, message, sigBytes) {
-		return ErrInvalidSignature
-	}
-
-	return nil
+	// Verify the signature with the envelope's algorithm
+	return verify(pubKeyBytes, message, sigBytes)
 }
 
 // VerifySignature is a lower-level function that verifies an Ed25519 signature
@@ -121,3 +179,17 @@ func VerifySignature(publicKey, message, signature []byte) error {
 func GenerateTestKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	return ed25519.GenerateKey(nil)
 }
+
+// SignEnvelope builds a signed envelope for the given data and timestamp
+// This is the signing counterpart of VerifyEnvelope, used by clients and tests
+func SignEnvelope(privKey ed25519.PrivateKey, data json.RawMessage, timestamp int64) ScanEnvelope {
+	signature := ed25519.Sign(privKey, SigningMessage(timestamp, data))
+
+	return ScanEnvelope{
+		Version:   EnvelopeVersion,
+		Data:      data,
+		PublicKey: base64.StdEncoding.EncodeToString(privKey.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(signature),
+		Timestamp: timestamp,
+	}
+}
@@ -1,10 +1,11 @@
 package auth
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -22,7 +23,7 @@ func TestVerifyEnvelope(t *testing.T) {
 	validTimestamp := time.Now().Unix()
 
 	// Sign the data
-	message := append([]byte(fmt.Sprintf("%d", validTimestamp)), validData...)
+	message := SigningMessage(validTimestamp, validData)
 	signature := ed25519.Sign(privKey, message)
 
 	tests := []struct {
@@ -33,6 +34,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "valid envelope",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -43,6 +45,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "empty data",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      nil,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -53,6 +56,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "empty public key",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: "",
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -63,6 +67,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "empty signature",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: "",
@@ -73,6 +78,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "zero timestamp",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -83,6 +89,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "expired timestamp - too old",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -93,6 +100,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "expired timestamp - too far in future",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -103,6 +111,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "invalid public key - not base64",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: "not-valid-base64!@#$",
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -113,6 +122,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "invalid public key - wrong length",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString([]byte("short")),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -123,6 +133,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "invalid signature - not base64",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: "not-valid-base64!@#$",
@@ -133,6 +144,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "invalid signature - wrong length",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString([]byte("short")),
@@ -143,6 +155,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "tampered data",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      json.RawMessage(`{"hosts":[{"ip":"5.6.7.8"}]}`), // Different data
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -153,6 +166,7 @@ func TestVerifyEnvelope(t *testing.T) {
 		{
 			name: "wrong signature",
 			env: ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize)),
@@ -212,10 +226,11 @@ func TestVerifyEnvelope_TimestampBoundary(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			timestamp := tt.timestamp.Unix()
-			message := append([]byte(fmt.Sprintf("%d", timestamp)), validData...)
+			message := SigningMessage(timestamp, validData)
 			signature := ed25519.Sign(privKey, message)
 
 			env := ScanEnvelope{
+				Version:   EnvelopeVersion,
 				Data:      validData,
 				PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 				Signature: base64.StdEncoding.EncodeToString(signature),
@@ -232,6 +247,57 @@ func TestVerifyEnvelope_TimestampBoundary(t *testing.T) {
 	}
 }
 
+func TestVerifyEnvelopeWithConfig_Skew(t *testing.T) {
+	_, privKey, err := GenerateTestKey()
+	require.NoError(t, err)
+
+	data := json.RawMessage(`{"test":"data"}`)
+
+	tests := []struct {
+		name    string
+		maxSkew time.Duration
+		offset  time.Duration
+		wantErr bool
+	}{
+		{name: "default window via zero skew, 4m old", maxSkew: 0, offset: -4 * time.Minute},
+		{name: "default window via zero skew, 6m old", maxSkew: 0, offset: -6 * time.Minute, wantErr: true},
+		{name: "wide window, 20m old", maxSkew: 30 * time.Minute, offset: -20 * time.Minute},
+		{name: "wide window, 20m in future", maxSkew: 30 * time.Minute, offset: 20 * time.Minute},
+		{name: "wide window, 40m old", maxSkew: 30 * time.Minute, offset: -40 * time.Minute, wantErr: true},
+		{name: "tight window, 10s old", maxSkew: 30 * time.Second, offset: -10 * time.Second},
+		{name: "tight window, 2m old", maxSkew: 30 * time.Second, offset: -2 * time.Minute, wantErr: true},
+		{name: "tight window, 2m in future", maxSkew: 30 * time.Second, offset: 2 * time.Minute, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := SignEnvelope(privKey, data, time.Now().Add(tt.offset).Unix())
+
+			err := VerifyEnvelopeWithConfig(env, tt.maxSkew)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrExpiredTimestamp)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerifyEnvelopeWithConfig_WideSkewKeepsOtherChecks(t *testing.T) {
+	_, privKey, err := GenerateTestKey()
+	require.NoError(t, err)
+
+	env := SignEnvelope(privKey, json.RawMessage(`{"test":"data"}`), time.Now().Unix())
+
+	missing := env
+	missing.Signature = ""
+	assert.ErrorIs(t, VerifyEnvelopeWithConfig(missing, 24*time.Hour), ErrMissingData)
+
+	tampered := env
+	tampered.Data = json.RawMessage(`{"test":"other"}`)
+	assert.ErrorIs(t, VerifyEnvelopeWithConfig(tampered, 24*time.Hour), ErrInvalidSignature)
+}
+
 func TestVerifySignature(t *testing.T) {
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
 	require.NoError(t, err)
@@ -339,10 +405,11 @@ func TestVerifyEnvelope_RealWorldScenario(t *testing.T) {
 	require.NoError(t, err)
 
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), data...)
+	message := SigningMessage(timestamp, data)
 	signature := ed25519.Sign(privKey, message)
 
 	env := ScanEnvelope{
+		Version:   EnvelopeVersion,
 		Data:      data,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -353,6 +420,71 @@ func TestVerifyEnvelope_RealWorldScenario(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSigningMessage_LegacyPreimageDoesNotCrossValidate(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	data := json.RawMessage(`{"hosts":[{"ip":"1.2.3.4"}]}`)
+	timestamp := time.Now().Unix()
+
+	legacyMessage := append([]byte(strconv.FormatInt(timestamp, 10)), data...)
+	newMessage := SigningMessage(timestamp, data)
+
+	t.Run("legacy signature rejected by VerifyEnvelope", func(t *testing.T) {
+		env := ScanEnvelope{
+			Version:   EnvelopeVersion,
+			Data:      data,
+			PublicKey: base64.StdEncoding.EncodeToString(pubKey),
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, legacyMessage)),
+			Timestamp: timestamp,
+		}
+
+		err := VerifyEnvelope(env)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("new signature does not verify against legacy preimage", func(t *testing.T) {
+		signature := ed25519.Sign(privKey, newMessage)
+		assert.False(t, ed25519.Verify(pubKey, legacyMessage, signature))
+		assert.True(t, ed25519.Verify(pubKey, newMessage, signature))
+	})
+
+	t.Run("unversioned envelope rejected", func(t *testing.T) {
+		env := SignEnvelope(privKey, data, timestamp)
+		env.Version = 0
+
+		err := VerifyEnvelope(env)
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	})
+}
+
+func TestSigningMessage_Unambiguous(t *testing.T) {
+	// With plain concatenation these two splits produce the same preimage:
+	// "170000000" + "0{}" == "1700000000" + "{}"
+	a := SigningMessage(170000000, []byte(`0{}`))
+	b := SigningMessage(1700000000, []byte(`{}`))
+
+	assert.Equal(t,
+		append([]byte("170000000"), `0{}`...),
+		append([]byte("1700000000"), `{}`...))
+	assert.NotEqual(t, a, b)
+
+	assert.True(t, bytes.HasPrefix(a, []byte(SigningDomain)))
+}
+
+func TestSignEnvelope(t *testing.T) {
+	_, privKey, err := GenerateTestKey()
+	require.NoError(t, err)
+
+	env := SignEnvelope(privKey, json.RawMessage(`{"test":"data"}`), time.Now().Unix())
+	assert.Equal(t, EnvelopeVersion, env.Version)
+	assert.NoError(t, VerifyEnvelope(env))
+
+	// Tampering with the data invalidates the signature
+	env.Data = json.RawMessage(`{"test":"other"}`)
+	assert.ErrorIs(t, VerifyEnvelope(env), ErrInvalidSignature)
+}
+
 // Benchmark tests
 func BenchmarkVerifyEnvelope(b *testing.B) {
 	pubKey, privKey, err := ed25519.GenerateKey(nil)
@@ -360,10 +492,11 @@ func BenchmarkVerifyEnvelope(b *testing.B) {
 
 	data := json.RawMessage(`{"hosts":[{"ip":"1.2.3.4","ports":[{"number":80}]}]}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), data...)
+	message := SigningMessage(timestamp, data)
 	signature := ed25519.Sign(privKey, message)
 
 	env := ScanEnvelope{
+		Version:   EnvelopeVersion,
 		Data:      data,
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -0,0 +1,743 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deadLetterStage   string
+	deadLetterLimit   int
+	deadLetterNoColor bool
+
+	unidentifiedLimit   int
+	unidentifiedNoColor bool
+)
+
+// NewAdminCommand creates the admin command with subcommands
+func NewAdminCommand() *cobra.Command {
+	adminCmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Operator commands for the Spectra-Red API",
+		Long: `Operator commands for inspecting and repairing the Spectra-Red pipeline.
+
+Enrichment workflows record IPs they could not resolve after retrying as
+dead letters, so they can be inspected and re-submitted later.`,
+		Example: `  # List dead-lettered enrichment items
+  spectra admin dead-letters
+
+  # Re-submit an IP that failed ASN enrichment
+  spectra admin dead-letters requeue 1.2.3.4 --stage asn
+
+  # Show the most common banners no pattern could identify
+  spectra admin unidentified
+
+  # Re-run CPE enrichment for services in an ASN that have no CPEs
+  spectra admin reenrich --asn 13335 --missing-cpe
+
+  # Create or upgrade the database schema
+  spectra admin migrate
+
+  # Show yesterday's redactions
+  spectra admin audit --action redact --since 24h
+
+  # Dump the whole graph as JSON lines
+  spectra admin export --file graph.jsonl
+
+  # Load it into another instance
+  spectra admin import --file graph.jsonl`,
+	}
+
+	adminCmd.AddCommand(NewDeadLettersCommand())
+	adminCmd.AddCommand(NewUnidentifiedCommand())
+	adminCmd.AddCommand(NewReenrichCommand())
+	adminCmd.AddCommand(NewMigrateCommand())
+	adminCmd.AddCommand(NewAuditCommand())
+	adminCmd.AddCommand(NewExportCommand())
+	adminCmd.AddCommand(NewImportCommand())
+
+	return adminCmd
+}
+
+// NewDeadLettersCommand creates the admin dead-letters subcommand
+func NewDeadLettersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dead-letters",
+		Short: "List enrichment items that exhausted their retries",
+		Long: `List IPs that permanently failed an enrichment stage (asn, geo).
+
+Each dead letter records the stage, the reason, how many workflow runs failed
+the IP, and when it last failed.`,
+		Example: `  # List all dead letters
+  spectra admin dead-letters
+
+  # List only GeoIP failures as JSON
+  spectra admin dead-letters --stage geo --output json`,
+		RunE: runDeadLettersList,
+	}
+
+	cmd.Flags().StringVar(&deadLetterStage, "stage", "", "Filter by enrichment stage (asn, geo)")
+	cmd.Flags().IntVar(&deadLetterLimit, "limit", 50, "Maximum number of results (max: 500)")
+	cmd.Flags().BoolVar(&deadLetterNoColor, "no-color", false, "Disable colored output")
+
+	cmd.AddCommand(NewDeadLettersRequeueCommand())
+
+	return cmd
+}
+
+// NewDeadLettersRequeueCommand creates the dead-letters requeue subcommand
+func NewDeadLettersRequeueCommand() *cobra.Command {
+	var stage string
+
+	cmd := &cobra.Command{
+		Use:   "requeue <ip>",
+		Short: "Re-submit a dead-lettered IP for enrichment",
+		Long: `Re-submit a dead-lettered IP to its enrichment workflow and clear the record.
+
+If the IP fails again, a new dead letter is recorded.`,
+		Example: `  spectra admin dead-letters requeue 1.2.3.4 --stage asn`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !models.IsValidDeadLetterStage(stage) {
+				return fmt.Errorf("invalid stage: %q (must be one of: asn, geo)", stage)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+			defer cancel()
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+			deadLetter, err := apiClient.RequeueDeadLetter(ctx, stage, args[0])
+			if err != nil {
+				return fmt.Errorf("failed to requeue dead letter: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Requeued %s for %s enrichment (previous attempts: %d)\n", deadLetter.IP, deadLetter.Stage, deadLetter.Attempts)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&stage, "stage", "", "Enrichment stage to re-run (asn, geo)")
+	_ = cmd.MarkFlagRequired("stage")
+
+	return cmd
+}
+
+// NewUnidentifiedCommand creates the admin unidentified subcommand
+func NewUnidentifiedCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unidentified",
+		Short: "List the banners of services that could not be identified",
+		Long: `List the raw banners of services with no product and no CPE, grouped with
+how many services share each one, most common first.
+
+These are blind spots in banner parsing: the banners at the top of the list
+are the patterns most worth adding.`,
+		Example: `  # Show the 20 most common unidentified banners
+  spectra admin unidentified
+
+  # Show the top 100 as JSON
+  spectra admin unidentified --limit 100 --output json`,
+		RunE: runUnidentifiedList,
+	}
+
+	cmd.Flags().IntVar(&unidentifiedLimit, "limit", models.DefaultAggregateLimit, "Maximum number of banners (max: 1000)")
+	cmd.Flags().BoolVar(&unidentifiedNoColor, "no-color", false, "Disable colored output")
+
+	return cmd
+}
+
+// NewReenrichCommand creates the admin reenrich subcommand
+func NewReenrichCommand() *cobra.Command {
+	var (
+		asn        int
+		country    string
+		missingCPE bool
+		stale      time.Duration
+		limit      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reenrich",
+		Short: "Queue hosts or services for enrichment again",
+		Long: `Queue the hosts or services matching a filter for enrichment again.
+
+With only --asn or --country, the matching hosts are re-run through ASN and
+GeoIP enrichment. With --missing-cpe or --stale, the matching services on those
+hosts are re-run through CPE enrichment instead. At least one filter is required.`,
+		Example: `  # Refresh ASN and location data for every host in a country
+  spectra admin reenrich --country NL
+
+  # Re-match services in an ASN that have no CPEs
+  spectra admin reenrich --asn 13335 --missing-cpe
+
+  # Re-match services whose CPEs are more than 30 days old
+  spectra admin reenrich --stale 720h --limit 5000`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := buildReenrichRequest(asn, country, missingCPE, stale, limit, time.Now())
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+			defer cancel()
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+			resp, err := apiClient.Reenrich(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to queue re-enrichment: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Queued %d hosts and %d services in %d workflow batches\n", resp.HostsQueued, resp.ServicesQueued, resp.Batches)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&asn, "asn", 0, "Only hosts announced by this ASN")
+	cmd.Flags().StringVar(&country, "country", "", "Only hosts in this country (ISO code)")
+	cmd.Flags().BoolVar(&missingCPE, "missing-cpe", false, "Re-match services that have no CPEs")
+	cmd.Flags().DurationVar(&stale, "stale", 0, "Re-match services whose CPEs are older than this (e.g. 720h)")
+	cmd.Flags().IntVar(&limit, "limit", models.DefaultReenrichLimit, fmt.Sprintf("Maximum hosts or services to queue (max: %d)", models.MaxReenrichLimit))
+
+	return cmd
+}
+
+// buildReenrichRequest validates the reenrich flags and turns them into a request
+func buildReenrichRequest(asn int, country string, missingCPE bool, stale time.Duration, limit int, now time.Time) (models.ReenrichRequest, error) {
+	req := models.ReenrichRequest{
+		ASN:        asn,
+		Country:    strings.ToUpper(country),
+		MissingCPE: missingCPE,
+		Limit:      limit,
+	}
+
+	if asn < 0 {
+		return req, fmt.Errorf("invalid asn: %d (must be positive)", asn)
+	}
+	if stale < 0 {
+		return req, fmt.Errorf("invalid stale duration: %s (must be positive)", stale)
+	}
+	if stale > 0 {
+		staleBefore := now.Add(-stale).UTC()
+		req.StaleBefore = &staleBefore
+	}
+	if limit < 1 || limit > models.MaxReenrichLimit {
+		return req, fmt.Errorf("invalid limit: %d (must be between 1 and %d)", limit, models.MaxReenrichLimit)
+	}
+	if !req.HasHostFilter() && !req.HasServiceFilter() {
+		return req, fmt.Errorf("at least one filter is required: --asn, --country, --missing-cpe, --stale")
+	}
+
+	return req, nil
+}
+
+// NewMigrateCommand creates the admin migrate subcommand
+func NewMigrateCommand() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Create or upgrade the database schema",
+		Long: `Apply the schema migrations the database has not seen yet: table and field
+definitions, indexes (including the vuln_doc vector index) and analyzers.
+
+Applied versions are recorded in the database, so running migrate again only
+applies migrations added since the last run. Building indexes over a large
+existing graph can take several minutes; raise --timeout if needed.
+
+The endpoint requires the server's admin token, read from api.admin_token or
+SPECTRA_API_ADMIN_TOKEN.`,
+		Example: `  # Bootstrap a fresh database or apply new migrations
+  spectra admin migrate
+
+  # List pending migrations without applying them
+  spectra admin migrate --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+			defer cancel()
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+			resp, err := apiClient.Migrate(ctx, dryRun)
+			if err != nil {
+				return fmt.Errorf("failed to migrate schema: %w", err)
+			}
+
+			formatMigrateResult(cmd.OutOrStdout(), resp)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List pending migrations without applying them")
+
+	return cmd
+}
+
+// NewAuditCommand creates the admin audit subcommand
+func NewAuditCommand() *cobra.Command {
+	var (
+		since      string
+		until      string
+		actor      string
+		scannerKey string
+		action     string
+		limit      int
+		noColor    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "List ingests and admin actions from the audit trail",
+		Long: `List audit entries, most recent first. Every accepted scan is recorded with
+its job, host count and source address, and every redaction, re-enrichment,
+export and import with its counts.
+
+Scanner keys are stored hashed; --scanner-key hashes the key it is given the
+same way, so entries can be found for a known key. The endpoint requires the
+server's admin token, read from api.admin_token or SPECTRA_API_ADMIN_TOKEN.`,
+		Example: `  # Everything in the last day
+  spectra admin audit --since 24h
+
+  # Scans one scanner submitted in January
+  spectra admin audit --scanner-key <public-key> --action ingest --since 2026-01-01T00:00:00Z --until 2026-02-01T00:00:00Z
+
+  # Admin actions only
+  spectra admin audit --actor admin`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := buildAuditFilter(since, until, actor, scannerKey, action, limit, time.Now())
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+			defer cancel()
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+			resp, err := apiClient.ListAudit(ctx, filter)
+			if err != nil {
+				return fmt.Errorf("failed to list audit entries: %w", err)
+			}
+
+			outputOpts := NewOutputOptions(GetOutputFormat(), noColor)
+
+			switch outputOpts.Format {
+			case FormatJSON:
+				return writeJSON(outputOpts, resp)
+			case FormatYAML:
+				return writeYAML(outputOpts, resp)
+			case FormatTable:
+				return formatAuditTable(outputOpts, resp)
+			default:
+				return fmt.Errorf("unsupported output format: %s", outputOpts.Format)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only entries at or after this time (RFC 3339, or a duration ago such as 24h)")
+	cmd.Flags().StringVar(&until, "until", "", "Only entries before this time (RFC 3339, or a duration ago such as 1h)")
+	cmd.Flags().StringVar(&actor, "actor", "", "Only entries by this actor (admin, or scanner:<sha256>)")
+	cmd.Flags().StringVar(&scannerKey, "scanner-key", "", "Only entries by the scanner with this public key")
+	cmd.Flags().StringVar(&action, "action", "", "Only entries for this action (ingest, redact, reenrich, requeue, migrate, export, import)")
+	cmd.Flags().IntVar(&limit, "limit", models.DefaultAuditLimit, fmt.Sprintf("Maximum number of entries (max: %d)", models.MaxAuditLimit))
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return cmd
+}
+
+// buildAuditFilter validates the audit flags and turns them into a filter
+func buildAuditFilter(since, until, actor, scannerKey, action string, limit int, now time.Time) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Actor:  actor,
+		Action: action,
+		Limit:  limit,
+	}
+
+	var err error
+	if filter.Since, err = parseAuditTime(since, now); err != nil {
+		return filter, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseAuditTime(until, now); err != nil {
+		return filter, fmt.Errorf("invalid until: %w", err)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, fmt.Errorf("invalid time range: --since must be before --until")
+	}
+	if scannerKey != "" {
+		if actor != "" {
+			return filter, fmt.Errorf("--actor and --scanner-key cannot be combined")
+		}
+		filter.Actor = models.ScannerActor(scannerKey)
+	}
+	if limit < 1 || limit > models.MaxAuditLimit {
+		return filter, fmt.Errorf("invalid limit: %d (must be between 1 and %d)", limit, models.MaxAuditLimit)
+	}
+
+	return filter, nil
+}
+
+// parseAuditTime reads an RFC 3339 timestamp, or a duration counted back from now
+// An empty value is the zero time, i.e. no bound.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("%q (durations count back from now and must be positive)", value)
+		}
+		return now.Add(-d).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q (expected RFC 3339, e.g. 2024-01-02T15:04:05Z, or a duration such as 24h)", value)
+	}
+	return t, nil
+}
+
+// formatAuditTable renders audit entries as a table
+func formatAuditTable(opts *OutputOptions, resp *models.AuditListResponse) error {
+	if len(resp.Entries) == 0 {
+		fmt.Fprintln(opts.Writer, "No audit entries found")
+		return nil
+	}
+
+	if !opts.NoColor && opts.IsTerminal {
+		color.New(color.FgCyan, color.Bold).Fprintf(opts.Writer, "\nAudit Trail\n\n")
+	} else {
+		fmt.Fprintf(opts.Writer, "\nAudit Trail\n\n")
+	}
+
+	table := tablewriter.NewWriter(opts.Writer)
+	table.SetHeader([]string{"Time", "Action", "Actor", "Source IP", "Job", "Hosts"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, entry := range resp.Entries {
+		table.Append([]string{
+			formatTime(entry.CreatedAt),
+			entry.Action,
+			truncate(entry.Actor, 24),
+			entry.SourceIP,
+			entry.JobID,
+			strconv.Itoa(entry.HostCount),
+		})
+	}
+
+	table.Render()
+
+	fmt.Fprintf(opts.Writer, "\nShowing %d audit entries\n", len(resp.Entries))
+
+	return nil
+}
+
+// formatMigrateResult prints the migrations a run applied, or would apply on a dry run
+func formatMigrateResult(w io.Writer, resp *models.MigrateResponse) {
+	if len(resp.Applied) == 0 {
+		fmt.Fprintf(w, "Schema is up to date (version %d)\n", resp.CurrentVersion)
+		return
+	}
+
+	verb := "Applied"
+	if resp.DryRun {
+		verb = "Pending"
+	}
+	for _, migration := range resp.Applied {
+		fmt.Fprintf(w, "%s migration %d: %s\n", verb, migration.Version, migration.Name)
+	}
+	if resp.DryRun {
+		fmt.Fprintf(w, "Schema is at version %d of %d\n", resp.CurrentVersion, resp.LatestVersion)
+		return
+	}
+	fmt.Fprintf(w, "Schema is now at version %d\n", resp.CurrentVersion)
+}
+
+func runDeadLettersList(cmd *cobra.Command, args []string) error {
+	format := GetOutputFormat()
+
+	if deadLetterStage != "" && !models.IsValidDeadLetterStage(deadLetterStage) {
+		return fmt.Errorf("invalid stage: %q (must be one of: asn, geo)", deadLetterStage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+	defer cancel()
+
+	apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+	resp, err := apiClient.ListDeadLetters(ctx, deadLetterStage, deadLetterLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	outputOpts := NewOutputOptions(format, deadLetterNoColor)
+
+	switch outputOpts.Format {
+	case FormatJSON:
+		return writeJSON(outputOpts, resp)
+	case FormatYAML:
+		return writeYAML(outputOpts, resp)
+	case FormatTable:
+		return formatDeadLettersTable(outputOpts, resp)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func formatDeadLettersTable(opts *OutputOptions, resp *models.DeadLetterListResponse) error {
+	if len(resp.DeadLetters) == 0 {
+		fmt.Fprintln(opts.Writer, "No dead letters found")
+		return nil
+	}
+
+	if !opts.NoColor && opts.IsTerminal {
+		color.New(color.FgCyan, color.Bold).Fprintf(opts.Writer, "\nDead-Lettered Enrichment Items\n\n")
+	} else {
+		fmt.Fprintf(opts.Writer, "\nDead-Lettered Enrichment Items\n\n")
+	}
+
+	table := tablewriter.NewWriter(opts.Writer)
+	table.SetHeader([]string{"IP", "Stage", "Attempts", "Last Attempt", "Reason"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, dl := range resp.DeadLetters {
+		table.Append([]string{
+			dl.IP,
+			dl.Stage,
+			strconv.Itoa(dl.Attempts),
+			formatTime(dl.LastAttempt),
+			truncate(dl.Reason, 60),
+		})
+	}
+
+	table.Render()
+
+	fmt.Fprintf(opts.Writer, "\nShowing %d dead letters\n", len(resp.DeadLetters))
+	fmt.Fprintln(opts.Writer, "Use 'spectra admin dead-letters requeue <ip> --stage <stage>' to re-submit one")
+
+	return nil
+}
+
+func runUnidentifiedList(cmd *cobra.Command, args []string) error {
+	format := GetOutputFormat()
+
+	ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+	defer cancel()
+
+	apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig())
+	resp, err := apiClient.ListUnidentifiedServices(ctx, unidentifiedLimit)
+	if err != nil {
+		return fmt.Errorf("failed to list unidentified services: %w", err)
+	}
+
+	outputOpts := NewOutputOptions(format, unidentifiedNoColor)
+
+	switch outputOpts.Format {
+	case FormatJSON:
+		return writeJSON(outputOpts, resp)
+	case FormatYAML:
+		return writeYAML(outputOpts, resp)
+	case FormatTable:
+		return formatUnidentifiedTable(outputOpts, resp)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func formatUnidentifiedTable(opts *OutputOptions, resp *models.UnidentifiedServicesResponse) error {
+	if len(resp.Results) == 0 {
+		fmt.Fprintln(opts.Writer, "No unidentified services found")
+		return nil
+	}
+
+	if !opts.NoColor && opts.IsTerminal {
+		color.New(color.FgCyan, color.Bold).Fprintf(opts.Writer, "\nUnidentified Service Banners\n\n")
+	} else {
+		fmt.Fprintf(opts.Writer, "\nUnidentified Service Banners\n\n")
+	}
+
+	table := tablewriter.NewWriter(opts.Writer)
+	table.SetHeader([]string{"Services", "Banner"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, result := range resp.Results {
+		banner := strconv.Quote(truncate(result.Banner, 80))
+		if result.Banner == "" {
+			banner = "(no banner)"
+		}
+		table.Append([]string{
+			strconv.Itoa(result.ServiceCount),
+			banner,
+		})
+	}
+
+	table.Render()
+
+	fmt.Fprintf(opts.Writer, "\nShowing %d banners\n", len(resp.Results))
+
+	return nil
+}
+
+// NewExportCommand creates the admin export subcommand
+func NewExportCommand() *cobra.Command {
+	var (
+		tables     []string
+		outputFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Dump the graph as JSON lines",
+		Long: `Stream the graph as JSON lines, one node or edge per line, for backups, offline
+analysis or seeding another instance.
+
+Each line has the record's table, kind (node or edge), id, the in and out
+record ids of an edge, and its other fields under data. Every node table is
+written before any edge table. The server reads the graph in batches, so an
+export of any size streams without being held in memory on either side.
+
+The endpoint requires the server's admin token, read from api.admin_token or
+SPECTRA_API_ADMIN_TOKEN. No timeout applies; interrupt the command to stop it.`,
+		Example: `  # Everything, to stdout
+  spectra admin export > graph.jsonl
+
+  # Hosts, ports and the edges between them, to a file
+  spectra admin export --tables host,port,HAS --file hosts.jsonl`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Fail before connecting on a table the server would reject
+			if _, err := models.ResolveExportTables(tables); err != nil {
+				return err
+			}
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(0).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+
+			if outputFile == "" {
+				_, err := apiClient.ExportGraph(context.Background(), tables, cmd.OutOrStdout())
+				if err != nil {
+					return fmt.Errorf("failed to export graph: %w", err)
+				}
+				return nil
+			}
+
+			records, err := exportToFile(apiClient, tables, outputFile)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d records to %s\n", records, outputFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&tables, "tables", nil, fmt.Sprintf("Only these tables, comma separated (default: all of %s, %s)",
+		strings.Join(models.ExportNodeTables, ", "), strings.Join(models.ExportEdgeTables, ", ")))
+	cmd.Flags().StringVarP(&outputFile, "file", "f", "", "Write the export to this file instead of stdout")
+
+	return cmd
+}
+
+// exportToFile streams an export into path and returns the record count
+// An export that fails partway removes the file rather than leave one that
+// looks complete.
+func exportToFile(apiClient *client.Client, tables []string, path string) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	w := bufio.NewWriter(f)
+	records, err := apiClient.ExportGraph(context.Background(), tables, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, fmt.Errorf("failed to export graph: %w", err)
+	}
+	return records, nil
+}
+
+// NewImportCommand creates the admin import subcommand
+func NewImportCommand() *cobra.Command {
+	var inputFile string
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Load a JSON lines graph export",
+		Long: `Upload a graph export written by 'spectra admin export' to seed a fresh
+instance or restore a backup.
+
+Records are upserted: nodes and edges that already exist have their fields
+replaced, except that first_seen only moves earlier and last_seen only later,
+so importing an old backup never shrinks a host's observation window.
+Importing the same export twice is harmless, so a failed import can be rerun.
+Malformed lines are skipped and reported.
+
+The endpoint requires the server's admin token, read from api.admin_token or
+SPECTRA_API_ADMIN_TOKEN. No timeout applies.`,
+		Example: `  # Restore a backup
+  spectra admin import --file graph.jsonl
+
+  # Read a compressed export from stdin
+  gunzip -c graph.jsonl.gz | spectra admin import`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if inputFile != "" {
+				f, err := os.Open(inputFile)
+				if err != nil {
+					return fmt.Errorf("failed to open %s: %w", inputFile, err)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(0).WithTLSConfig(GetTLSConfig()).WithAPIKey(GetAdminToken())
+			resp, err := apiClient.ImportGraph(context.Background(), in)
+			if err != nil {
+				return fmt.Errorf("failed to import graph: %w", err)
+			}
+
+			formatImportResult(cmd.OutOrStdout(), resp)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&inputFile, "file", "f", "", "Read the export from this file instead of stdin")
+
+	return cmd
+}
+
+// formatImportResult prints import counts and the lines the server skipped
+func formatImportResult(w io.Writer, resp *models.ImportResponse) {
+	fmt.Fprintf(w, "Imported %d nodes and %d edges\n", resp.Nodes, resp.Edges)
+	if resp.Skipped == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "Skipped %d malformed lines:\n", resp.Skipped)
+	for _, msg := range resp.Errors {
+		fmt.Fprintf(w, "  %s\n", msg)
+	}
+	if more := resp.Skipped - len(resp.Errors); more > 0 {
+		fmt.Fprintf(w, "  ... and %d more\n", more)
+	}
+}
@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminCommand(t *testing.T) {
+	cmd := NewAdminCommand()
+
+	assert.Equal(t, "admin", cmd.Use)
+	assert.NotEmpty(t, cmd.Short)
+
+	deadLetters, _, err := cmd.Find([]string{"dead-letters"})
+	require.NoError(t, err)
+	assert.Equal(t, "dead-letters", deadLetters.Use)
+	assert.NotNil(t, deadLetters.Flags().Lookup("stage"))
+	assert.NotNil(t, deadLetters.Flags().Lookup("limit"))
+	assert.NotNil(t, deadLetters.Flags().Lookup("no-color"))
+
+	requeue, _, err := cmd.Find([]string{"dead-letters", "requeue"})
+	require.NoError(t, err)
+	assert.Contains(t, requeue.Use, "requeue")
+	assert.NotNil(t, requeue.Flags().Lookup("stage"))
+	assert.Error(t, requeue.Args(requeue, []string{}))
+	assert.NoError(t, requeue.Args(requeue, []string{"192.0.2.1"}))
+
+	unidentified, _, err := cmd.Find([]string{"unidentified"})
+	require.NoError(t, err)
+	assert.Equal(t, "unidentified", unidentified.Use)
+	assert.NotNil(t, unidentified.Flags().Lookup("limit"))
+	assert.NotNil(t, unidentified.Flags().Lookup("no-color"))
+
+	reenrich, _, err := cmd.Find([]string{"reenrich"})
+	require.NoError(t, err)
+	assert.Equal(t, "reenrich", reenrich.Use)
+	for _, flag := range []string{"asn", "country", "missing-cpe", "stale", "limit"} {
+		assert.NotNil(t, reenrich.Flags().Lookup(flag), flag)
+	}
+
+	migrate, _, err := cmd.Find([]string{"migrate"})
+	require.NoError(t, err)
+	assert.Equal(t, "migrate", migrate.Use)
+	assert.NotNil(t, migrate.Flags().Lookup("dry-run"))
+
+	audit, _, err := cmd.Find([]string{"audit"})
+	require.NoError(t, err)
+	assert.Equal(t, "audit", audit.Use)
+	for _, flag := range []string{"since", "until", "actor", "scanner-key", "action", "limit", "no-color"} {
+		assert.NotNil(t, audit.Flags().Lookup(flag), flag)
+	}
+
+	export, _, err := cmd.Find([]string{"export"})
+	require.NoError(t, err)
+	assert.Equal(t, "export", export.Use)
+	assert.NotNil(t, export.Flags().Lookup("tables"))
+	assert.NotNil(t, export.Flags().Lookup("file"))
+	assert.Error(t, export.Args(export, []string{"graph.jsonl"}))
+
+	importCmd, _, err := cmd.Find([]string{"import"})
+	require.NoError(t, err)
+	assert.Equal(t, "import", importCmd.Use)
+	assert.NotNil(t, importCmd.Flags().Lookup("file"))
+}
+
+func TestExportToFile(t *testing.T) {
+	const body = `{"table":"host","kind":"node","id":"host:a","data":{"ip":"192.0.2.1"}}
+`
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", models.ExportRecordsTrailer+", "+models.ExportErrorTrailer)
+		_, _ = w.Write([]byte(body))
+		w.Header().Set(models.ExportRecordsTrailer, "1")
+		if failed {
+			w.Header().Set(models.ExportErrorTrailer, "export failed after 1 records")
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "graph.jsonl")
+
+	records, err := exportToFile(client.NewClient(server.URL), nil, path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, records)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(data))
+
+	// A failed export leaves no file behind
+	failed = true
+	_, err = exportToFile(client.NewClient(server.URL), nil, path)
+	assert.ErrorContains(t, err, "export failed after 1 records")
+	assert.NoFileExists(t, path)
+}
+
+func TestFormatMigrateResult(t *testing.T) {
+	tests := []struct {
+		name string
+		resp models.MigrateResponse
+		want []string
+	}{
+		{
+			name: "applied",
+			resp: models.MigrateResponse{
+				CurrentVersion: 2,
+				LatestVersion:  2,
+				Applied:        []models.MigrationInfo{{Version: 1, Name: "baseline"}, {Version: 2, Name: "affected_by_indexes"}},
+			},
+			want: []string{"Applied migration 1: baseline", "Applied migration 2: affected_by_indexes", "Schema is now at version 2"},
+		},
+		{
+			name: "dry run",
+			resp: models.MigrateResponse{
+				CurrentVersion: 1,
+				LatestVersion:  2,
+				Applied:        []models.MigrationInfo{{Version: 2, Name: "affected_by_indexes"}},
+				DryRun:         true,
+			},
+			want: []string{"Pending migration 2: affected_by_indexes", "Schema is at version 1 of 2"},
+		},
+		{
+			name: "up to date",
+			resp: models.MigrateResponse{CurrentVersion: 2, LatestVersion: 2},
+			want: []string{"Schema is up to date (version 2)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			formatMigrateResult(&buf, &tt.resp)
+			for _, want := range tt.want {
+				assert.Contains(t, buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestBuildReenrichRequest(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	req, err := buildReenrichRequest(64500, "nl", true, 48*time.Hour, 200, now)
+	require.NoError(t, err)
+	assert.Equal(t, 64500, req.ASN)
+	assert.Equal(t, "NL", req.Country)
+	assert.True(t, req.MissingCPE)
+	require.NotNil(t, req.StaleBefore)
+	assert.Equal(t, now.Add(-48*time.Hour), *req.StaleBefore)
+	assert.Equal(t, 200, req.Limit)
+
+	req, err = buildReenrichRequest(0, "US", false, 0, 10, now)
+	require.NoError(t, err)
+	assert.Nil(t, req.StaleBefore)
+
+	tests := []struct {
+		name        string
+		asn         int
+		stale       time.Duration
+		limit       int
+		errContains string
+	}{
+		{"no filter", 0, 0, 10, "at least one filter"},
+		{"negative asn", -1, 0, 10, "invalid asn"},
+		{"negative stale", 0, -time.Hour, 10, "invalid stale"},
+		{"limit too large", 64500, 0, models.MaxReenrichLimit + 1, "invalid limit"},
+		{"zero limit", 64500, 0, 0, "invalid limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildReenrichRequest(tt.asn, "", false, tt.stale, tt.limit, now)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestBuildAuditFilter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	filter, err := buildAuditFilter("24h", "2026-03-01T06:00:00Z", "", "scanner-key", "ingest", 20, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-24*time.Hour), filter.Since)
+	assert.Equal(t, time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC), filter.Until)
+	assert.Equal(t, models.ScannerActor("scanner-key"), filter.Actor, "scanner keys are hashed before they are sent")
+	assert.Equal(t, "ingest", filter.Action)
+	assert.Equal(t, 20, filter.Limit)
+
+	filter, err = buildAuditFilter("", "", "admin", "", "", 10, now)
+	require.NoError(t, err)
+	assert.True(t, filter.Since.IsZero())
+	assert.True(t, filter.Until.IsZero())
+	assert.Equal(t, "admin", filter.Actor)
+
+	tests := []struct {
+		name        string
+		since       string
+		until       string
+		actor       string
+		scannerKey  string
+		limit       int
+		errContains string
+	}{
+		{name: "bad since", since: "yesterday", limit: 10, errContains: "invalid since"},
+		{name: "negative duration", until: "-1h", limit: 10, errContains: "invalid until"},
+		{name: "empty range", since: "1h", until: "2h", limit: 10, errContains: "invalid time range"},
+		{name: "actor and scanner key", actor: "admin", scannerKey: "k", limit: 10, errContains: "cannot be combined"},
+		{name: "limit too large", limit: models.MaxAuditLimit + 1, errContains: "invalid limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildAuditFilter(tt.since, tt.until, tt.actor, tt.scannerKey, "", tt.limit, now)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestFormatAuditTable(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	err := formatAuditTable(opts, &models.AuditListResponse{
+		Entries: []models.AuditEntry{
+			{Action: models.AuditActionIngest, Actor: models.ScannerActor("k"), SourceIP: "192.0.2.10", JobID: "job-1", HostCount: 3, CreatedAt: time.Now()},
+		},
+		Total: 1,
+		Limit: 100,
+	})
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "192.0.2.10")
+	assert.Contains(t, output, "job-1")
+	assert.Contains(t, output, "Showing 1 audit entries")
+
+	buf.Reset()
+	require.NoError(t, formatAuditTable(opts, &models.AuditListResponse{}))
+	assert.Contains(t, buf.String(), "No audit entries found")
+}
+
+func TestFormatDeadLettersTable(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	err := formatDeadLettersTable(opts, &models.DeadLetterListResponse{
+		DeadLetters: []models.DeadLetter{
+			{IP: "192.0.2.1", Stage: "geo", Reason: "no GeoIP data for address", Attempts: 4, LastAttempt: time.Now()},
+		},
+		Total: 1,
+		Limit: 50,
+	})
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "192.0.2.1")
+	assert.Contains(t, output, "no GeoIP data for address")
+	assert.Contains(t, output, "Showing 1 dead letters")
+
+	buf.Reset()
+	require.NoError(t, formatDeadLettersTable(opts, &models.DeadLetterListResponse{}))
+	assert.Contains(t, buf.String(), "No dead letters found")
+}
+
+func TestFormatUnidentifiedTable(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	err := formatUnidentifiedTable(opts, &models.UnidentifiedServicesResponse{
+		Results: []models.UnidentifiedBanner{
+			{Banner: "SSH-2.0-dropbear_2022.83", ServiceCount: 12},
+			{Banner: "", ServiceCount: 3},
+		},
+		Limit: 20,
+	})
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, `"SSH-2.0-dropbear_2022.83"`)
+	assert.Contains(t, output, "12")
+	assert.Contains(t, output, "(no banner)")
+	assert.Contains(t, output, "Showing 2 banners")
+
+	buf.Reset()
+	require.NoError(t, formatUnidentifiedTable(opts, &models.UnidentifiedServicesResponse{}))
+	assert.Contains(t, buf.String(), "No unidentified services found")
+}
+
+func TestFormatImportResult(t *testing.T) {
+	var buf bytes.Buffer
+	formatImportResult(&buf, &models.ImportResponse{Nodes: 3, Edges: 2})
+	assert.Equal(t, "Imported 3 nodes and 2 edges\n", buf.String())
+
+	buf.Reset()
+	formatImportResult(&buf, &models.ImportResponse{Nodes: 3, Skipped: 25, Errors: []string{"line 4: invalid JSON"}})
+	assert.Contains(t, buf.String(), "Skipped 25 malformed lines:\n  line 4: invalid JSON\n  ... and 24 more\n")
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spectra-red/recon/internal/api/openapi"
+	"github.com/spf13/cobra"
+)
+
+// NewAPICommand creates the api command with subcommands
+func NewAPICommand() *cobra.Command {
+	apiCmd := &cobra.Command{
+		Use:   "api",
+		Short: "Describe the Spectra-Red API",
+		Long:  `Commands for working with the Spectra-Red API description.`,
+	}
+
+	apiCmd.AddCommand(NewAPISpecCommand())
+
+	return apiCmd
+}
+
+// NewAPISpecCommand creates the api spec subcommand
+func NewAPISpecCommand() *cobra.Command {
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Print the OpenAPI document for the API",
+		Long: `Print the OpenAPI 3.1 document describing the /v1 API.
+
+This is the same document the server serves at /openapi.json, generated from
+this build rather than fetched, so it works without a running server.`,
+		Example: `  # Print the spec
+  spectra api spec
+
+  # Write it to a file for a client generator
+  spectra api spec --file openapi.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := openapi.JSON()
+			if err != nil {
+				return fmt.Errorf("failed to build OpenAPI document: %w", err)
+			}
+			spec = append(spec, '\n')
+
+			if outputFile == "" {
+				_, err = cmd.OutOrStdout().Write(spec)
+				return err
+			}
+			if err := os.WriteFile(outputFile, spec, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", outputFile, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Wrote OpenAPI document to %s\n", outputFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "file", "f", "", "Write the document to this file instead of stdout")
+
+	return cmd
+}
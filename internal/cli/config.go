@@ -2,26 +2,35 @@ package cli
 
 import (
 	"crypto/ed25519"
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/spectra-red/recon/internal/client"
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the CLI
 type Config struct {
-	API      APIConfig      `mapstructure:"api"`
-	Scanner  ScannerConfig  `mapstructure:"scanner"`
-	Output   OutputConfig   `mapstructure:"output"`
+	API        APIConfig        `mapstructure:"api"`
+	Scanner    ScannerConfig    `mapstructure:"scanner"`
+	Output     OutputConfig     `mapstructure:"output"`
+	Federation FederationConfig `mapstructure:"federation"`
 }
 
 // APIConfig holds API-related configuration
 type APIConfig struct {
-	URL     string        `mapstructure:"url"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	URL      string        `mapstructure:"url"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	CACert   string        `mapstructure:"ca_cert"`  // PEM bundle to trust for HTTPS endpoints
+	Insecure bool          `mapstructure:"insecure"` // Skip TLS certificate verification
+
+	// AdminToken is sent as a bearer token by admin commands whose endpoints
+	// require the server's ADMIN_API_TOKEN
+	AdminToken string `mapstructure:"admin_token"`
 }
 
 // ScannerConfig holds scanner authentication configuration
@@ -36,6 +45,20 @@ type OutputConfig struct {
 	Color  bool   `mapstructure:"color"`
 }
 
+// FederationConfig lists trusted peer meshes that host and graph queries fall
+// back to when the local API has no data. Federation is off without peers.
+type FederationConfig struct {
+	Peers   []PeerConfig  `mapstructure:"peers"`
+	Timeout time.Duration `mapstructure:"timeout"` // bound on forwarding one query to all peers
+}
+
+// PeerConfig holds one federation peer
+type PeerConfig struct {
+	Name   string `mapstructure:"name"` // tags the peer's results; defaults to the URL
+	URL    string `mapstructure:"url"`
+	APIKey string `mapstructure:"api_key"`
+}
+
 // InitConfig initializes configuration from file, environment variables, and flags
 // Configuration precedence: flags > env vars > config file > defaults
 func InitConfig(cfgFile string) (*Config, error) {
@@ -68,6 +91,9 @@ func InitConfig(cfgFile string) (*Config, error) {
 	// Bind environment variables to config keys explicitly
 	viper.BindEnv("api.url", "SPECTRA_API_URL")
 	viper.BindEnv("api.timeout", "SPECTRA_API_TIMEOUT")
+	viper.BindEnv("api.ca_cert", "SPECTRA_API_CA_CERT")
+	viper.BindEnv("api.insecure", "SPECTRA_API_INSECURE")
+	viper.BindEnv("api.admin_token", "SPECTRA_API_ADMIN_TOKEN")
 	viper.BindEnv("output.format", "SPECTRA_OUTPUT_FORMAT")
 	viper.BindEnv("output.color", "SPECTRA_OUTPUT_COLOR")
 	viper.BindEnv("scanner.public_key", "SPECTRA_SCANNER_PUBLIC_KEY")
@@ -95,6 +121,9 @@ func setDefaults() {
 	// API defaults
 	viper.SetDefault("api.url", "http://localhost:3000")
 	viper.SetDefault("api.timeout", "30s")
+	viper.SetDefault("api.ca_cert", "")
+	viper.SetDefault("api.insecure", false)
+	viper.SetDefault("api.admin_token", "")
 
 	// Scanner defaults
 	viper.SetDefault("scanner.public_key", "")
@@ -115,6 +144,32 @@ func GetAPITimeout() time.Duration {
 	return viper.GetDuration("api.timeout")
 }
 
+// GetAdminToken returns the configured admin API token
+func GetAdminToken() string {
+	return viper.GetString("api.admin_token")
+}
+
+// GetTLSConfig returns the TLS settings for API clients, or nil for the defaults
+// It is loaded from api.ca_cert and api.insecure when the root command starts.
+func GetTLSConfig() *tls.Config {
+	return apiTLSConfig
+}
+
+// GetFederationConfig returns the configured federation peers and timeout
+// Peers are verified with the same TLS settings as the API.
+func GetFederationConfig() client.FederationConfig {
+	var cfg FederationConfig
+	if err := viper.UnmarshalKey("federation", &cfg); err != nil {
+		return client.FederationConfig{}
+	}
+
+	federation := client.FederationConfig{Timeout: cfg.Timeout, TLSConfig: GetTLSConfig()}
+	for _, peer := range cfg.Peers {
+		federation.Peers = append(federation.Peers, client.Peer{Name: peer.Name, URL: peer.URL, APIKey: peer.APIKey})
+	}
+	return federation
+}
+
 // GetOutputFormat returns the configured output format
 func GetOutputFormat() string {
 	return viper.GetString("output.format")
@@ -157,6 +212,13 @@ func ValidateConfig(cfg *Config) error {
 		return fmt.Errorf("invalid output format: %s (must be json, yaml, or table)", cfg.Output.Format)
 	}
 
+	// Validate federation peers
+	for i, peer := range cfg.Federation.Peers {
+		if peer.URL == "" {
+			return fmt.Errorf("federation.peers[%d].url cannot be empty", i)
+		}
+	}
+
 	return nil
 }
 
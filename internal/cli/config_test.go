@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestInitConfig_Defaults(t *testing.T) {
@@ -151,6 +153,47 @@ func TestValidateConfig_InvalidOutputFormat(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid output format")
 }
 
+func TestInitConfig_Federation(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	cfgFile := filepath.Join(t.TempDir(), ".spectra.yaml")
+	configContent := `
+federation:
+  timeout: 3s
+  peers:
+    - name: upstream
+      url: https://mesh.example.com
+      api_key: peer-key
+    - url: https://other.example.com
+`
+	require.NoError(t, os.WriteFile(cfgFile, []byte(configContent), 0644))
+
+	cfg, err := InitConfig(cfgFile)
+	require.NoError(t, err)
+	require.NoError(t, ValidateConfig(cfg))
+
+	federation := GetFederationConfig()
+	assert.Equal(t, 3*time.Second, federation.Timeout)
+	require.Len(t, federation.Peers, 2)
+	assert.Equal(t, "upstream", federation.Peers[0].Name)
+	assert.Equal(t, "https://mesh.example.com", federation.Peers[0].URL)
+	assert.Equal(t, "peer-key", federation.Peers[0].APIKey)
+	assert.Equal(t, "https://other.example.com", federation.Peers[1].URL)
+}
+
+func TestValidateConfig_FederationPeerWithoutURL(t *testing.T) {
+	cfg := &Config{
+		API:        APIConfig{URL: "http://localhost:3000", Timeout: 30 * time.Second},
+		Output:     OutputConfig{Format: "json"},
+		Federation: FederationConfig{Peers: []PeerConfig{{Name: "upstream"}}},
+	}
+
+	err := ValidateConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "federation.peers[0].url")
+}
+
 func TestGetterFunctions(t *testing.T) {
 	// Reset viper between tests
 	viper.Reset()
@@ -201,3 +244,70 @@ api:
 	// File value should be used where no env var is set
 	assert.Equal(t, 60*time.Second, cfg.API.Timeout)
 }
+
+func TestTLSFlags(t *testing.T) {
+	t.Run("secure by default", func(t *testing.T) {
+		viper.Reset()
+		t.Cleanup(viper.Reset)
+
+		var stderr bytes.Buffer
+		rootCmd := NewRootCommand()
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&stderr)
+		rootCmd.SetArgs([]string{"version"})
+		require.NoError(t, rootCmd.Execute())
+
+		assert.Nil(t, GetTLSConfig())
+		assert.NotContains(t, stderr.String(), "WARNING")
+	})
+
+	t.Run("insecure warns", func(t *testing.T) {
+		viper.Reset()
+		t.Cleanup(viper.Reset)
+
+		var stderr bytes.Buffer
+		rootCmd := NewRootCommand()
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&stderr)
+		rootCmd.SetArgs([]string{"--insecure", "version"})
+		require.NoError(t, rootCmd.Execute())
+
+		require.NotNil(t, GetTLSConfig())
+		assert.True(t, GetTLSConfig().InsecureSkipVerify)
+		assert.Contains(t, stderr.String(), "WARNING: TLS certificate verification is disabled")
+	})
+
+	t.Run("unreadable CA certificate", func(t *testing.T) {
+		viper.Reset()
+		t.Cleanup(viper.Reset)
+
+		rootCmd := NewRootCommand()
+		rootCmd.SetOut(&bytes.Buffer{})
+		rootCmd.SetErr(&bytes.Buffer{})
+		rootCmd.SetArgs([]string{"--ca-cert", filepath.Join(t.TempDir(), "missing.pem"), "version"})
+
+		err := rootCmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid TLS configuration")
+	})
+}
+
+func TestLogLevelFlag(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"--log-level", "debug", "version"})
+	require.NoError(t, rootCmd.Execute())
+	assert.True(t, Logger().Core().Enabled(zap.DebugLevel))
+
+	rootCmd = NewRootCommand()
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"--log-level", "chatty", "version"})
+	err := rootCmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --log-level")
+}
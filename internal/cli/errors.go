@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// Error codes reported in --json-errors output
+const (
+	ErrCodeInvalidInput = "invalid_input" // bad arguments or flags
+	ErrCodeNotFound     = "not_found"     // the API answered 404
+	ErrCodeAuth         = "unauthorized"  // the API answered 401 or 403
+	ErrCodeAPI          = "api_error"     // any other 4xx answer
+	ErrCodeServer       = "server_error"  // a 5xx answer
+	ErrCodeTimeout      = "timeout"       // the request ran past --timeout
+	ErrCodeNetwork      = "network_error" // the API could not be reached
+	ErrCodeInternal     = "error"         // anything else
+)
+
+// jsonErrors prints failures as JSON on stderr instead of plain text
+var jsonErrors bool
+
+// AddErrorFlags registers the --json-errors flag on cmd and its subcommands
+func AddErrorFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false, "print errors to stderr as JSON ({error, code, details})")
+}
+
+// ErrorOutput is the JSON form of a failure written with --json-errors
+type ErrorOutput struct {
+	Error   string                 `json:"error"`
+	Code    string                 `json:"code"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// InputError marks a failure caused by the command's arguments or flags
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// requestTimeoutError reports a request that ran past the configured timeout
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *requestTimeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %s (use --timeout to allow longer)", e.timeout)
+}
+
+// newErrorOutput classifies err and builds its structured form
+// A non-empty message is prefixed to the error text as in the plain output.
+func newErrorOutput(err error, message string) ErrorOutput {
+	out := ErrorOutput{Error: err.Error(), Code: ErrCodeInternal}
+	if message != "" {
+		out.Error = message + ": " + out.Error
+	}
+
+	var (
+		inputErr   *InputError
+		timeoutErr *requestTimeoutError
+		apiErr     *client.APIError
+		httpErr    *client.HTTPError
+		urlErr     *url.Error
+		netErr     net.Error
+	)
+	switch {
+	case errors.As(err, &inputErr):
+		out.Code = ErrCodeInvalidInput
+	case errors.As(err, &timeoutErr):
+		out.Code = ErrCodeTimeout
+		out.Details = map[string]interface{}{"timeout": timeoutErr.timeout.String()}
+	case errors.As(err, &apiErr):
+		out.Code = statusErrorCode(apiErr.StatusCode)
+		out.Details = map[string]interface{}{
+			"status":    apiErr.StatusCode,
+			"api_error": apiErr.ErrorCode,
+			"message":   apiErr.Message,
+		}
+	case errors.As(err, &httpErr):
+		out.Code = statusErrorCode(httpErr.StatusCode)
+		out.Details = map[string]interface{}{
+			"status": httpErr.StatusCode,
+			"body":   httpErr.Body,
+		}
+	case errors.As(err, &urlErr):
+		out.Code = ErrCodeNetwork
+		out.Details = map[string]interface{}{"url": urlErr.URL}
+	case errors.As(err, &netErr):
+		out.Code = ErrCodeNetwork
+	}
+
+	return out
+}
+
+// statusErrorCode maps an HTTP error status to an error code
+func statusErrorCode(status int) string {
+	switch {
+	case status == 404:
+		return ErrCodeNotFound
+	case status == 401 || status == 403:
+		return ErrCodeAuth
+	case status >= 500:
+		return ErrCodeServer
+	default:
+		return ErrCodeAPI
+	}
+}
+
+// writeError writes err to w, as an ErrorOutput object when asJSON is set and
+// as an "Error: ..." line otherwise
+func writeError(w io.Writer, err error, message string, asJSON bool) {
+	out := newErrorOutput(err, message)
+	if asJSON {
+		if encErr := json.NewEncoder(w).Encode(out); encErr == nil {
+			return
+		}
+	}
+	fmt.Fprintf(w, "Error: %s\n", out.Error)
+}
+
+// ReportError writes a command's error to w in the format chosen by --json-errors
+func ReportError(w io.Writer, err error) {
+	writeError(w, err, "", jsonErrors)
+}
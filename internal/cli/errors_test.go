@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notFoundError returns the error a host query gets from a server answering 404
+func notFoundError(t *testing.T) error {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("host not found"))
+	}))
+	defer server.Close()
+
+	_, err := client.NewQueryClient(server.URL).QueryHost(context.Background(), "192.0.2.1", 0)
+	require.Error(t, err)
+	return err
+}
+
+func TestWriteError_Text(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		var stderr bytes.Buffer
+		writeError(&stderr, notFoundError(t), "failed to query host", false)
+		assert.Equal(t, "Error: failed to query host: HTTP 404: host not found\n", stderr.String())
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		var stderr bytes.Buffer
+		writeError(&stderr, &InputError{Err: fmt.Errorf("invalid IP address: %s", "nope")}, "", false)
+		assert.Equal(t, "Error: invalid IP address: nope\n", stderr.String())
+	})
+}
+
+func TestWriteError_JSON(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		var stderr bytes.Buffer
+		writeError(&stderr, notFoundError(t), "failed to query host", true)
+
+		var out ErrorOutput
+		require.NoError(t, json.Unmarshal(stderr.Bytes(), &out), stderr.String())
+		assert.Equal(t, ErrCodeNotFound, out.Code)
+		assert.Equal(t, "failed to query host: HTTP 404: host not found", out.Error)
+		assert.Equal(t, float64(http.StatusNotFound), out.Details["status"])
+		assert.Equal(t, "host not found", out.Details["body"])
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		var stderr bytes.Buffer
+		writeError(&stderr, &InputError{Err: fmt.Errorf("invalid IP address: %s", "nope")}, "", true)
+
+		var out ErrorOutput
+		require.NoError(t, json.Unmarshal(stderr.Bytes(), &out), stderr.String())
+		assert.Equal(t, ErrCodeInvalidInput, out.Code)
+		assert.Equal(t, "invalid IP address: nope", out.Error)
+		assert.Empty(t, out.Details)
+	})
+}
+
+func TestNewErrorOutput_Codes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"api 4xx", &client.APIError{StatusCode: http.StatusBadRequest, ErrorCode: "invalid_request", Message: "bad"}, ErrCodeAPI},
+		{"api 5xx", &client.APIError{StatusCode: http.StatusServiceUnavailable, ErrorCode: "unavailable", Message: "down"}, ErrCodeServer},
+		{"http 500", fmt.Errorf("wrapped: %w", &client.HTTPError{StatusCode: http.StatusInternalServerError, Body: "boom"}), ErrCodeServer},
+		{"timeout", &requestTimeoutError{timeout: time.Second}, ErrCodeTimeout},
+		{"other", fmt.Errorf("something broke"), ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, newErrorOutput(tt.err, "").Code)
+		})
+	}
+
+	t.Run("unreachable server", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		_, err := client.NewQueryClient(url).QueryHost(context.Background(), "192.0.2.1", 0)
+		require.Error(t, err)
+		assert.Equal(t, ErrCodeNetwork, newErrorOutput(err, "").Code)
+	})
+}
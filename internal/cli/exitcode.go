@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by the spectra commands
+const (
+	ExitOK        = 0
+	ExitError     = 1 // any failure not covered below
+	ExitUsage     = 2 // invalid arguments, flags or configuration
+	ExitNotFound  = 3 // the requested host, job or CVE does not exist
+	ExitNoResults = 4 // a search succeeded but matched nothing
+	ExitServer    = 5 // the API failed, timed out or could not be reached
+	ExitAuth      = 6 // the API rejected the request's credentials
+)
+
+// osExit ends the process; tests replace it to observe exit codes
+var osExit = os.Exit
+
+// ExitCode returns the process exit code for a command's error
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	switch newErrorOutput(err, "").Code {
+	case ErrCodeInvalidInput:
+		return ExitUsage
+	case ErrCodeNotFound:
+		return ExitNotFound
+	case ErrCodeAuth:
+		return ExitAuth
+	case ErrCodeServer, ErrCodeTimeout, ErrCodeNetwork:
+		return ExitServer
+	default:
+		return ExitError
+	}
+}
+
+// markUsageErrors makes cobra's flag and argument errors for cmd and its
+// subcommands InputErrors, so they exit with ExitUsage
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return &InputError{Err: err}
+	})
+	markArgsErrors(cmd)
+}
+
+// markArgsErrors wraps the Args validators of cmd and its subcommands
+func markArgsErrors(cmd *cobra.Command) {
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			err := validate(c, args)
+			var inputErr *InputError
+			if err == nil || errors.As(err, &inputErr) {
+				return err
+			}
+			return &InputError{Err: err}
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markArgsErrors(sub)
+	}
+}
+
+// usageError marks cobra's unknown command error, which has no hook of its own,
+// as an InputError. Other errors are returned unchanged.
+func usageError(err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), "unknown command ") {
+		return &InputError{Err: err}
+	}
+	return err
+}
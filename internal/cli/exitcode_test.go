@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// exitStatus is raised by the osExit stub to stop a command where it would exit
+type exitStatus int
+
+// runCLI runs the spectra root command with args and returns the exit code
+// the process would end with
+func runCLI(t *testing.T, args ...string) (code int) {
+	viper.Reset()
+	osExit = func(code int) { panic(exitStatus(code)) }
+	t.Cleanup(func() {
+		viper.Reset()
+		osExit = os.Exit
+		queryAPIURL = ""
+		outputFormat = "table"
+	})
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	rootCmd.SetArgs(args)
+
+	defer func() {
+		if r := recover(); r != nil {
+			status, ok := r.(exitStatus)
+			if !ok {
+				panic(r)
+			}
+			code = int(status)
+		}
+	}()
+	return ExitCode(usageError(rootCmd.Execute()))
+}
+
+// statusServer answers every request with status and body
+func statusServer(t *testing.T, status int, body interface{}) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestExitCodes_Query(t *testing.T) {
+	emptyGraph := models.GraphQueryResponse{Results: []models.HostResult{}}
+	errBody := client.ErrorResponse{Error: "error", Message: "failed"}
+
+	tests := []struct {
+		name   string
+		status int
+		body   interface{}
+		args   []string
+		want   int
+	}{
+		{"host found", http.StatusOK, models.HostQueryResponse{IP: "192.0.2.1"}, []string{"query", "host", "192.0.2.1"}, ExitOK},
+		{"host not found", http.StatusNotFound, errBody, []string{"query", "host", "192.0.2.1"}, ExitNotFound},
+		{"no results", http.StatusOK, emptyGraph, []string{"query", "graph", "--type", "by_kev"}, ExitNoResults},
+		{"server error", http.StatusInternalServerError, errBody, []string{"query", "host", "192.0.2.1"}, ExitServer},
+		{"unauthorized", http.StatusUnauthorized, errBody, []string{"query", "host", "192.0.2.1"}, ExitAuth},
+		{"invalid ip", http.StatusOK, nil, []string{"query", "host", "not-an-ip"}, ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := statusServer(t, tt.status, tt.body)
+			args := append(tt.args, "--api-url", url, "--output", "json")
+			assert.Equal(t, tt.want, runCLI(t, args...))
+		})
+	}
+}
+
+func TestExitCodes_Jobs(t *testing.T) {
+	url := statusServer(t, http.StatusNotFound, client.ErrorResponse{Error: "not_found", Message: "job not found"})
+	assert.Equal(t, ExitNotFound, runCLI(t, "--api-url", url, "jobs", "get", "job-1"))
+
+	url = statusServer(t, http.StatusServiceUnavailable, client.ErrorResponse{Error: "unavailable", Message: "down"})
+	assert.Equal(t, ExitServer, runCLI(t, "--api-url", url, "jobs", "get", "job-1"))
+}
+
+func TestExitCodes_Usage(t *testing.T) {
+	assert.Equal(t, ExitUsage, runCLI(t, "query", "host"), "missing argument")
+	assert.Equal(t, ExitUsage, runCLI(t, "query", "host", "192.0.2.1", "--no-such-flag"), "unknown flag")
+	assert.Equal(t, ExitUsage, runCLI(t, "no-such-command"), "unknown command")
+	assert.Equal(t, ExitUsage, runCLI(t, "--timeout", "soon", "version"), "invalid global flag")
+}
+
+func TestExitCodes_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	assert.Equal(t, ExitServer, runCLI(t, "query", "host", "192.0.2.1", "--api-url", url, "--output", "json"))
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitOK, ExitCode(nil))
+	assert.Equal(t, ExitError, ExitCode(errors.New("failed")))
+	assert.Equal(t, ExitUsage, ExitCode(fmt.Errorf("wrapped: %w", &InputError{Err: errors.New("bad flag")})))
+	assert.Equal(t, ExitAuth, ExitCode(&client.APIError{StatusCode: http.StatusForbidden}))
+	assert.Equal(t, ExitError, ExitCode(&client.APIError{StatusCode: http.StatusConflict}))
+}
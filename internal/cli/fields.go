@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// tableColumn is a column that --fields can select for table output
+type tableColumn[T any] struct {
+	name   string // Field name accepted by --fields
+	header string
+	value  func(T) string
+}
+
+// tableColumns is an ordered set of selectable columns and the ones shown by default
+type tableColumns[T any] struct {
+	all      []tableColumn[T]
+	defaults []string
+}
+
+// graphColumns are the columns of the graph query results table
+var graphColumns = tableColumns[models.HostResult]{
+	all: []tableColumn[models.HostResult]{
+		{"ip", "IP", func(h models.HostResult) string { return h.IP }},
+		{"asn", "ASN", func(h models.HostResult) string { return fmt.Sprintf("%d", h.ASN) }},
+		{"city", "City", func(h models.HostResult) string { return h.City }},
+		{"region", "Region", func(h models.HostResult) string { return h.Region }},
+		{"country", "Country", func(h models.HostResult) string { return h.Country }},
+		{"ports", "Ports", func(h models.HostResult) string { return fmt.Sprintf("%d", len(h.Ports)) }},
+		{"services", "Services", func(h models.HostResult) string { return fmt.Sprintf("%d", len(h.Services)) }},
+		{"score", "Score", func(h models.HostResult) string { return fmt.Sprintf("%.2f", h.Score) }},
+		{"first_seen", "First Seen", func(h models.HostResult) string { return formatTime(h.FirstSeen) }},
+		{"last_seen", "Last Seen", func(h models.HostResult) string { return formatTime(h.LastSeen) }},
+		{"source", "Source", func(h models.HostResult) string { return h.Source }},
+	},
+	defaults: []string{"ip", "asn", "city", "country", "ports", "services", "last_seen"},
+}
+
+// hostPortColumns are the columns of the host query ports table
+var hostPortColumns = tableColumns[models.PortDetail]{
+	all: []tableColumn[models.PortDetail]{
+		{"port", "Port", func(p models.PortDetail) string { return fmt.Sprintf("%d", p.Number) }},
+		{"protocol", "Protocol", func(p models.PortDetail) string { return p.Protocol }},
+		{"service", "Service", func(p models.PortDetail) string { return firstService(p).Name }},
+		{"product", "Product", func(p models.PortDetail) string { return firstService(p).Product }},
+		{"version", "Version", func(p models.PortDetail) string { return firstService(p).Version }},
+		{"first_seen", "First Seen", func(p models.PortDetail) string { return formatTime(p.FirstSeen) }},
+		{"last_seen", "Last Seen", func(p models.PortDetail) string { return formatTime(p.LastSeen) }},
+		{"discovered_by", "Discovered By", func(p models.PortDetail) string { return p.DiscoveredBy }},
+	},
+	defaults: []string{"port", "protocol", "service", "product", "version"},
+}
+
+// firstService returns the first service detected on a port, or an empty one
+func firstService(p models.PortDetail) models.ServiceDetail {
+	if len(p.Services) > 0 {
+		return p.Services[0]
+	}
+	return models.ServiceDetail{}
+}
+
+// names lists every selectable field name
+func (c tableColumns[T]) names() []string {
+	names := make([]string, len(c.all))
+	for i, col := range c.all {
+		names[i] = col.name
+	}
+	return names
+}
+
+// parse validates a comma-separated --fields value and returns the field names
+// in the order given; an empty value returns nil, meaning the default columns
+func (c tableColumns[T]) parse(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, raw := range strings.Split(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(raw))
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := c.lookup(name); !ok {
+			return nil, fmt.Errorf("unknown field %q (valid fields: %s)", name, strings.Join(c.names(), ", "))
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// lookup returns the column with the given field name
+func (c tableColumns[T]) lookup(name string) (tableColumn[T], bool) {
+	for _, col := range c.all {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return tableColumn[T]{}, false
+}
+
+// selectColumns returns the columns for fields, or the defaults when fields is empty
+// Unknown names are skipped; parse has already rejected them for user input.
+func (c tableColumns[T]) selectColumns(fields []string) []tableColumn[T] {
+	if len(fields) == 0 {
+		fields = c.defaults
+	}
+	columns := make([]tableColumn[T], 0, len(fields))
+	for _, name := range fields {
+		if col, ok := c.lookup(name); ok {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// headers returns the header row for columns
+func headers[T any](columns []tableColumn[T]) []string {
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = col.header
+	}
+	return row
+}
+
+// row renders item as a table row for columns
+func row[T any](columns []tableColumn[T], item T) []string {
+	cells := make([]string, len(columns))
+	for i, col := range columns {
+		cells[i] = col.value(item)
+	}
+	return cells
+}
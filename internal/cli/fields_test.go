@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{name: "unset uses defaults", value: "", expected: nil},
+		{name: "subset keeps order", value: "country,ip", expected: []string{"country", "ip"}},
+		{name: "spaces and case", value: " IP , First_Seen ", expected: []string{"ip", "first_seen"}},
+		{name: "duplicates dropped", value: "ip,ip,ports", expected: []string{"ip", "ports"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := graphColumns.parse(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestParseFields_UnknownField(t *testing.T) {
+	_, err := graphColumns.parse("ip,hostname")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "hostname"`)
+	assert.Contains(t, err.Error(), "first_seen")
+
+	// Ports table columns are not graph columns
+	_, err = graphColumns.parse("protocol")
+	assert.Error(t, err)
+}
+
+func TestFormatGraphTable_Fields(t *testing.T) {
+	result := &models.GraphQueryResponse{
+		Results: []models.HostResult{
+			{
+				IP:        "1.2.3.4",
+				ASN:       15169,
+				City:      "Paris",
+				Country:   "France",
+				FirstSeen: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				LastSeen:  time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+				Ports:     []models.Port{{Number: 80, Protocol: "tcp"}},
+			},
+		},
+		QueryTime: 1.5,
+	}
+
+	fields, err := graphColumns.parse("ip,country,first_seen")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf, Fields: fields}
+	require.NoError(t, formatGraphTable(opts, result))
+
+	output := buf.String()
+	assert.Contains(t, output, "FIRST SEEN")
+	assert.Contains(t, output, "1.2.3.4")
+	assert.Contains(t, output, "France")
+	assert.Contains(t, output, "2024-03-01")
+	assert.NotContains(t, output, "ASN")
+	assert.NotContains(t, output, "15169")
+	assert.NotContains(t, output, "Paris")
+	assert.NotContains(t, output, "LAST SEEN")
+}
+
+func TestFormatHostTable_Fields(t *testing.T) {
+	result := &models.HostQueryResponse{
+		IP: "1.2.3.4",
+		Ports: []models.PortDetail{
+			{
+				Number:   443,
+				Protocol: "tcp",
+				Services: []models.ServiceDetail{{Name: "https", Product: "nginx", Version: "1.25.1"}},
+			},
+		},
+	}
+
+	fields, err := hostPortColumns.parse("port,service")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf, Fields: fields}
+	require.NoError(t, formatHostTable(opts, result))
+
+	output := buf.String()
+	assert.Contains(t, output, "443")
+	assert.Contains(t, output, "https")
+	assert.NotContains(t, output, "PROTOCOL")
+	assert.NotContains(t, output, "nginx")
+	assert.NotContains(t, output, "1.25.1")
+}
+
+func TestFormatHostTable_DiscoveredBy(t *testing.T) {
+	result := &models.HostQueryResponse{
+		IP:    "1.2.3.4",
+		Ports: []models.PortDetail{{Number: 22, Protocol: "tcp", DiscoveredBy: "masscan"}},
+	}
+
+	fields, err := hostPortColumns.parse("port,discovered_by")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf, Fields: fields}
+	require.NoError(t, formatHostTable(opts, result))
+
+	assert.Contains(t, buf.String(), "masscan")
+}
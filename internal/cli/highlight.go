@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// ANSI escape sequences used to highlight JSON and YAML output
+// Raw codes rather than fatih/color, whose global NoColor flag tracks stdout
+// and not the writer being highlighted.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiKey     = "\x1b[1;34m" // bold blue
+	ansiString  = "\x1b[32m"   // green
+	ansiNumber  = "\x1b[36m"   // cyan
+	ansiBool    = "\x1b[33m"   // yellow
+	ansiNull    = "\x1b[90m"   // grey
+	ansiComment = "\x1b[90m"   // grey
+)
+
+// colorize reports whether structured output should be syntax highlighted
+func (opts *OutputOptions) colorize() bool {
+	return opts.IsTerminal && !opts.NoColor
+}
+
+// paint wraps s in an ANSI color
+func paint(code, s string) string {
+	return code + s + ansiReset
+}
+
+// highlightJSON adds ANSI colors to encoded JSON without changing its layout
+func highlightJSON(data []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data) * 2)
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(data) {
+				end++
+			}
+			token := string(data[i:end])
+
+			// A string followed by a colon is an object key
+			next := end
+			for next < len(data) && (data[next] == ' ' || data[next] == '\t') {
+				next++
+			}
+			if next < len(data) && data[next] == ':' {
+				out.WriteString(paint(ansiKey, token))
+			} else {
+				out.WriteString(paint(ansiString, token))
+			}
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(data) && strings.IndexByte("0123456789+-.eE", data[end]) >= 0 {
+				end++
+			}
+			out.WriteString(paint(ansiNumber, string(data[i:end])))
+			i = end
+		case bytes.HasPrefix(data[i:], []byte("true")):
+			out.WriteString(paint(ansiBool, "true"))
+			i += len("true")
+		case bytes.HasPrefix(data[i:], []byte("false")):
+			out.WriteString(paint(ansiBool, "false"))
+			i += len("false")
+		case bytes.HasPrefix(data[i:], []byte("null")):
+			out.WriteString(paint(ansiNull, "null"))
+			i += len("null")
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+var (
+	// yamlKeyLine splits a YAML line into indent, optional list marker, key and the rest
+	yamlKeyLine = regexp.MustCompile(`^(\s*)(- )?("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s'"#:][^:#]*?)(:)(\s.*|)$`)
+	// yamlListItem matches a bare list item with a scalar value
+	yamlListItem = regexp.MustCompile(`^(\s*)(- )(.*)$`)
+	// yamlNumber matches YAML integer and float scalars
+	yamlNumber = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+)
+
+// highlightYAML adds ANSI colors to encoded YAML, line by line
+// Block scalar bodies (after | or >) are colored as strings.
+func highlightYAML(data []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data) * 2)
+
+	lines := strings.SplitAfter(string(data), "\n")
+	blockIndent := -1 // indent of the line that opened a block scalar, -1 if none
+
+	for _, line := range lines {
+		body := strings.TrimRight(line, "\n")
+		newline := line[len(body):]
+		indent := len(body) - len(strings.TrimLeft(body, " "))
+
+		if blockIndent >= 0 {
+			if strings.TrimSpace(body) == "" || indent > blockIndent {
+				if body != "" {
+					out.WriteString(paint(ansiString, body))
+				}
+				out.WriteString(newline)
+				continue
+			}
+			blockIndent = -1
+		}
+
+		switch {
+		case strings.TrimSpace(body) == "":
+			out.WriteString(body)
+		case strings.HasPrefix(strings.TrimSpace(body), "#"):
+			out.WriteString(paint(ansiComment, body))
+		case yamlKeyLine.MatchString(body):
+			m := yamlKeyLine.FindStringSubmatch(body)
+			out.WriteString(m[1] + m[2] + paint(ansiKey, m[3]) + m[4])
+			value := strings.TrimLeft(m[5], " ")
+			out.WriteString(m[5][:len(m[5])-len(value)])
+			out.WriteString(highlightYAMLScalar(value))
+			if isBlockScalarHeader(value) {
+				blockIndent = indent
+			}
+		case yamlListItem.MatchString(body):
+			m := yamlListItem.FindStringSubmatch(body)
+			out.WriteString(m[1] + m[2] + highlightYAMLScalar(m[3]))
+			if isBlockScalarHeader(m[3]) {
+				blockIndent = indent
+			}
+		default:
+			out.WriteString(body)
+		}
+		out.WriteString(newline)
+	}
+
+	return out.Bytes()
+}
+
+// highlightYAMLScalar colors a single YAML value by type
+func highlightYAMLScalar(value string) string {
+	switch {
+	case value == "" || value == "[]" || value == "{}" || isBlockScalarHeader(value):
+		return value
+	case value == "null" || value == "~":
+		return paint(ansiNull, value)
+	case value == "true" || value == "false":
+		return paint(ansiBool, value)
+	case yamlNumber.MatchString(value):
+		return paint(ansiNumber, value)
+	default:
+		return paint(ansiString, value)
+	}
+}
+
+// isBlockScalarHeader reports whether a value starts a literal or folded block
+func isBlockScalarHeader(value string) bool {
+	if value == "" || (value[0] != '|' && value[0] != '>') {
+		return false
+	}
+	return strings.Trim(value[1:], "+-0123456789") == ""
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func sampleGraphResponse() *models.GraphQueryResponse {
+	return &models.GraphQueryResponse{
+		Results: []models.HostResult{
+			{ID: "host:1", IP: "192.0.2.1", ASN: 64500, City: "Berlin", Score: 1.5},
+		},
+		Pagination: models.PaginationMetadata{Limit: 100, Total: 1, HasMore: false},
+		QueryTime:  12.5,
+	}
+}
+
+func TestFormatGraphQuery_ColorOnlyOnTerminal(t *testing.T) {
+	formatter := NewFormatter()
+
+	tests := []struct {
+		name       string
+		format     OutputFormat
+		isTerminal bool
+		noColor    bool
+		wantColor  bool
+	}{
+		{name: "json to terminal", format: FormatJSON, isTerminal: true, wantColor: true},
+		{name: "yaml to terminal", format: FormatYAML, isTerminal: true, wantColor: true},
+		{name: "json piped", format: FormatJSON},
+		{name: "yaml piped", format: FormatYAML},
+		{name: "json with --no-color", format: FormatJSON, isTerminal: true, noColor: true},
+		{name: "yaml with --no-color", format: FormatYAML, isTerminal: true, noColor: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := &OutputOptions{Format: tt.format, Writer: &buf, IsTerminal: tt.isTerminal, NoColor: tt.noColor}
+
+			require.NoError(t, formatter.FormatGraphQuery(opts, sampleGraphResponse()))
+
+			out := buf.String()
+			if tt.wantColor {
+				assert.Contains(t, out, ansiKey)
+				assert.Contains(t, out, ansiString)
+				assert.Contains(t, out, ansiNumber)
+				assert.Contains(t, out, ansiBool)
+			} else {
+				assert.NotContains(t, out, "\x1b[")
+			}
+		})
+	}
+}
+
+func TestHighlightJSON_PreservesContent(t *testing.T) {
+	var plain bytes.Buffer
+	require.NoError(t, formatJSON(&plain, map[string]interface{}{
+		"key":     "value with \"quotes\": and colon",
+		"count":   -1.5e3,
+		"enabled": true,
+		"missing": nil,
+		"list":    []int{1, 2},
+	}))
+
+	colored := highlightJSON(plain.Bytes())
+	assert.Equal(t, plain.String(), ansiEscape.ReplaceAllString(string(colored), ""))
+
+	assert.Contains(t, string(colored), ansiKey+`"key"`+ansiReset)
+	assert.Contains(t, string(colored), ansiString+`"value with \"quotes\": and colon"`+ansiReset)
+	assert.Contains(t, string(colored), ansiNumber+"-1500"+ansiReset)
+	assert.Contains(t, string(colored), ansiBool+"true"+ansiReset)
+	assert.Contains(t, string(colored), ansiNull+"null"+ansiReset)
+
+	// Still valid JSON once the colors are stripped
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(ansiEscape.ReplaceAllString(string(colored), "")), &decoded))
+}
+
+func TestHighlightYAML_PreservesContent(t *testing.T) {
+	var plain bytes.Buffer
+	require.NoError(t, formatYAML(&plain, map[string]interface{}{
+		"name":    "redis",
+		"port":    6379,
+		"tls":     false,
+		"banner":  nil,
+		"summary": "first line\nsecond: line",
+		"cves":    []string{"CVE-2024-0001", "123"},
+	}))
+
+	colored := string(highlightYAML(plain.Bytes()))
+	assert.Equal(t, plain.String(), ansiEscape.ReplaceAllString(colored, ""))
+
+	assert.Contains(t, colored, ansiKey+"name"+ansiReset+": "+ansiString+"redis"+ansiReset)
+	assert.Contains(t, colored, ansiKey+"port"+ansiReset+": "+ansiNumber+"6379"+ansiReset)
+	assert.Contains(t, colored, ansiKey+"tls"+ansiReset+": "+ansiBool+"false"+ansiReset)
+	assert.Contains(t, colored, ansiKey+"banner"+ansiReset+": "+ansiNull+"null"+ansiReset)
+	assert.Contains(t, colored, "- "+ansiString+"CVE-2024-0001"+ansiReset)
+
+	// The body of a block scalar is a string, even where it looks like a key
+	assert.Contains(t, colored, ansiString+"  second: line"+ansiReset)
+	assert.False(t, strings.Contains(colored, ansiKey+"second"), "block scalar body must not be treated as a key")
+
+	var decoded map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(ansiEscape.ReplaceAllString(colored, "")), &decoded))
+}
@@ -8,15 +8,24 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
+	"github.com/spectra-red/recon/internal/auth"
 	"github.com/spectra-red/recon/internal/client"
+	"go.uber.org/zap"
 )
 
 // NewIngestCommand creates the ingest command
 func NewIngestCommand() *cobra.Command {
 	var filePath string
+	var callbackURL string
+	var source string
+	var chunkBytes int
+	var quiet bool
+	var skipSelfCheck bool
 
 	ingestCmd := &cobra.Command{
 		Use:   "ingest [file]",
@@ -34,7 +43,16 @@ Examples:
   spectra ingest scan-results.json
 
   # Ingest with explicit file flag
-  spectra ingest --file scan-results.json`,
+  spectra ingest --file scan-results.json
+
+  # Get notified when processing finishes (host must be allowlisted by the server)
+  spectra ingest scan-results.json --callback-url https://hooks.example.com/spectra
+
+  # Record which tool found the ports
+  naabu -host example.com -json | spectra ingest - --source naabu
+
+Scans larger than --chunk-size that are a JSON array are split into several
+signed submissions, one job each; the job IDs are listed at the end.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Determine input source: flag, positional arg, or stdin
@@ -46,26 +64,28 @@ Examples:
 				inputPath = "-" // default to stdin
 			}
 
-			return runIngest(inputPath)
+			return runIngest(inputPath, callbackURL, source, chunkBytes, quiet, skipSelfCheck)
 		},
 	}
 
 	ingestCmd.Flags().StringVarP(&filePath, "file", "f", "", "Input file containing scan results (use '-' for stdin)")
+	ingestCmd.Flags().StringVar(&callbackURL, "callback-url", "", "HTTPS URL to notify when the job finishes")
+	ingestCmd.Flags().StringVar(&source, "source", "", "Tool that produced the scan (e.g. naabu, nmap, masscan); recorded as unknown if unset")
+	ingestCmd.Flags().IntVar(&chunkBytes, "chunk-size", client.DefaultChunkBytes, "Split scans larger than this many bytes into several submissions")
+	ingestCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Don't show submission progress")
+	ingestCmd.Flags().BoolVar(&skipSelfCheck, "skip-self-check", false, "Send envelopes without verifying their signature locally (for testing malformed submissions)")
 
 	return ingestCmd
 }
 
 // runIngest executes the ingest command
-func runIngest(filePath string) error {
+func runIngest(filePath, callbackURL, source string, chunkBytes int, quiet, skipSelfCheck bool) error {
 	// Get private key from config
 	privKey, err := GetPrivateKey()
 	if err != nil {
 		return fmt.Errorf("failed to get private key: %w\n\nHint: Run 'spectra keys generate' to create a keypair", err)
 	}
 
-	// Derive public key from private key
-	pubKey := privKey.Public().(ed25519.PublicKey)
-
 	// Read scan data
 	scanData, err := readScanData(filePath)
 	if err != nil {
@@ -77,16 +97,19 @@ func runIngest(filePath string) error {
 		return fmt.Errorf("invalid JSON in scan data")
 	}
 
-	// Show progress for large files (>1MB)
-	if len(scanData) > 1024*1024 {
-		fmt.Fprintf(os.Stderr, "Submitting %d bytes of scan data...\n", len(scanData))
-	}
-
-	// Sign the scan data
-	timestamp := time.Now().Unix()
-	signature, err := signScanData(scanData, timestamp, privKey)
+	// Split large scans into several envelopes
+	chunks, err := client.SplitScanData(scanData, chunkBytes)
 	if err != nil {
-		return fmt.Errorf("failed to sign scan data: %w", err)
+		return fmt.Errorf("failed to split scan data: %w", err)
+	}
+	Logger().Debug("prepared scan submission",
+		zap.Int("bytes", len(scanData)),
+		zap.Int("chunks", len(chunks)))
+
+	// Show progress for large files (>1MB) unless output isn't a terminal
+	showProgress := !quiet && isatty.IsTerminal(os.Stderr.Fd())
+	if showProgress && len(scanData) > 1024*1024 {
+		fmt.Fprintf(os.Stderr, "Submitting %d bytes of scan data in %d chunk(s)...\n", len(scanData), len(chunks))
 	}
 
 	// Get config values
@@ -95,23 +118,83 @@ func runIngest(filePath string) error {
 	outputFormat := GetOutputFormat()
 
 	// Create ingest client
-	ingestClient := client.NewIngestClient(apiURL, int(timeout.Seconds()))
-
-	// Submit to API
-	req := client.IngestRequest{
-		Data:      json.RawMessage(scanData),
-		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
-		Signature: base64.StdEncoding.EncodeToString(signature),
-		Timestamp: timestamp,
+	ingestClient := client.NewIngestClient(apiURL, int(timeout.Seconds())).WithTLSConfig(GetTLSConfig())
+	if skipSelfCheck {
+		ingestClient = ingestClient.SkipSelfCheck()
 	}
 
-	resp, err := ingestClient.Submit(req)
+	progress := newProgressBar(os.Stderr, "Submitting", len(chunks), showProgress && len(chunks) > 1)
+	resps, err := submitChunks(ingestClient, chunks, privKey, callbackURL, source, progress)
 	if err != nil {
-		return fmt.Errorf("failed to submit scan: %w", err)
+		return err
 	}
 
 	// Display response
-	return displayIngestResponse(resp, outputFormat)
+	if len(resps) == 1 {
+		return displayIngestResponse(resps[0], outputFormat)
+	}
+	return displayChunkedIngestResponses(resps, outputFormat)
+}
+
+// submitChunks signs and submits each chunk in order as its own envelope,
+// advancing progress after every accepted chunk. Submission stops at the
+// first failure; the error names the chunk and the jobs already created.
+func submitChunks(ingestClient *client.IngestClient, chunks []json.RawMessage, privKey ed25519.PrivateKey, callbackURL, source string, progress *progressBar) ([]*client.IngestResponse, error) {
+	// Derive public key from private key
+	pubKey := privKey.Public().(ed25519.PublicKey)
+
+	resps := make([]*client.IngestResponse, 0, len(chunks))
+	defer progress.Finish()
+
+	for i, chunk := range chunks {
+		// Sign the scan data
+		timestamp := time.Now().Unix()
+		signature, err := signScanData(chunk, timestamp, privKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign scan data: %w", err)
+		}
+
+		// Submit to API
+		req := client.IngestRequest{
+			Version:     auth.EnvelopeVersion,
+			Data:        chunk,
+			PublicKey:   base64.StdEncoding.EncodeToString(pubKey),
+			Signature:   base64.StdEncoding.EncodeToString(signature),
+			Timestamp:   timestamp,
+			CallbackURL: callbackURL,
+			Source:      source,
+		}
+
+		resp, err := ingestClient.Submit(req)
+		if err != nil {
+			if len(chunks) == 1 {
+				return nil, fmt.Errorf("failed to submit scan: %w", err)
+			}
+			if len(resps) == 0 {
+				return nil, fmt.Errorf("failed to submit chunk %d of %d: %w", i+1, len(chunks), err)
+			}
+			return nil, fmt.Errorf("failed to submit chunk %d of %d (jobs already created: %s): %w",
+				i+1, len(chunks), strings.Join(jobIDs(resps), ", "), err)
+		}
+
+		Logger().Debug("submitted scan chunk",
+			zap.Int("chunk", i+1),
+			zap.Int("chunks", len(chunks)),
+			zap.String("job_id", resp.JobID))
+		resps = append(resps, resp)
+		progress.Add(1)
+	}
+
+	return resps, nil
+}
+
+// jobIDs returns the job ID of each response
+func jobIDs(resps []*client.IngestResponse) []string {
+	ids := make([]string, len(resps))
+	for i, resp := range resps {
+		ids[i] = resp.JobID
+	}
+	return ids
 }
 
 // readScanData reads scan data from a file or stdin
@@ -145,11 +228,11 @@ func readScanData(filePath string) ([]byte, error) {
 }
 
 // signScanData creates an Ed25519 signature for the scan data
-// The signature covers: timestamp + scan_data
+// The signature covers the length-prefixed preimage built by auth.SigningMessage
 func signScanData(scanData []byte, timestamp int64, privKey ed25519.PrivateKey) ([]byte, error) {
-	// Construct the message: timestamp + data
+	// Construct the message from timestamp and data
 	// This binds the timestamp to the data, preventing replay attacks
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
+	message := auth.SigningMessage(timestamp, scanData)
 
 	// Sign the message
 	signature := ed25519.Sign(privKey, message)
@@ -212,3 +295,34 @@ func displayTable(resp *client.IngestResponse) error {
 	fmt.Println()
 	return nil
 }
+
+// displayChunkedIngestResponses displays the jobs created by a chunked submission
+func displayChunkedIngestResponses(resps []*client.IngestResponse, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resps)
+	case "yaml":
+		var buf bytes.Buffer
+		buf.WriteString("---\njob_ids:\n")
+		for _, id := range jobIDs(resps) {
+			buf.WriteString(fmt.Sprintf("  - %s\n", id))
+		}
+		fmt.Print(buf.String())
+		return nil
+	case "table", "":
+		fmt.Println()
+		fmt.Printf("✓ Scan submitted successfully in %d chunks\n", len(resps))
+		fmt.Println()
+		for _, resp := range resps {
+			fmt.Printf("  Job ID:    %s (%s)\n", resp.JobID, resp.Status)
+		}
+		fmt.Println()
+		fmt.Println("Track job status with: spectra jobs get <job-id>")
+		fmt.Println()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
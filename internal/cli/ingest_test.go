@@ -1,14 +1,21 @@
 package cli
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/spectra-red/recon/internal/auth"
 	"github.com/spectra-red/recon/internal/client"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -66,7 +73,7 @@ func TestSignScanData(t *testing.T) {
 	assert.Len(t, signature, ed25519.SignatureSize)
 
 	// Verify the signature is valid
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), testData...)
+	message := auth.SigningMessage(timestamp, testData)
 	valid := ed25519.Verify(pubKey, message, signature)
 	assert.True(t, valid, "Signature should be valid")
 }
@@ -172,6 +179,82 @@ func TestDisplayIngestResponse_InvalidFormat(t *testing.T) {
 	assert.Contains(t, err.Error(), "unsupported output format")
 }
 
+func TestSubmitChunks_OneEnvelopePerChunk(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	var submissions int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&submissions, 1)
+
+		// Every chunk is an independently signed envelope
+		var req client.IngestRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		signature, err := base64.StdEncoding.DecodeString(req.Signature)
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(pubKey, auth.SigningMessage(req.Timestamp, req.Data), signature))
+		assert.Equal(t, "naabu", req.Source)
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(client.IngestResponse{JobID: fmt.Sprintf("job_%d", n), Status: "accepted"})
+	}))
+	defer server.Close()
+
+	entries := make([]string, 50)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"host":"192.0.2.%d","port":80}`, i+1)
+	}
+	chunks, err := client.SplitScanData([]byte("["+strings.Join(entries, ",")+"]"), 400)
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 1)
+
+	var progressOut bytes.Buffer
+	progress := newProgressBar(&progressOut, "Submitting", len(chunks), true)
+
+	resps, err := submitChunks(client.NewIngestClient(server.URL, 5), chunks, privKey, "", "naabu", progress)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(len(chunks)), atomic.LoadInt32(&submissions))
+	require.Len(t, resps, len(chunks))
+	assert.Equal(t, "job_1", resps[0].JobID)
+	assert.Equal(t, fmt.Sprintf("job_%d", len(chunks)), resps[len(resps)-1].JobID)
+	assert.Contains(t, progressOut.String(), fmt.Sprintf("%d/%d", len(chunks), len(chunks)))
+}
+
+func TestSubmitChunks_StopsAtFirstFailure(t *testing.T) {
+	_, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	var submissions int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&submissions, 1) == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(client.IngestErrorResponse{Error: "invalid_data", Message: "bad chunk"})
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(client.IngestResponse{JobID: "job_1", Status: "accepted"})
+	}))
+	defer server.Close()
+
+	chunks := []json.RawMessage{
+		json.RawMessage(`[{"host":"192.0.2.1","port":80}]`),
+		json.RawMessage(`[{"host":"192.0.2.2","port":80}]`),
+		json.RawMessage(`[{"host":"192.0.2.3","port":80}]`),
+	}
+
+	// A disabled bar writes nothing
+	var progressOut bytes.Buffer
+	progress := newProgressBar(&progressOut, "Submitting", len(chunks), false)
+
+	_, err = submitChunks(client.NewIngestClient(server.URL, 5), chunks, privKey, "", "", progress)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chunk 2 of 3")
+	assert.Contains(t, err.Error(), "job_1")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&submissions))
+	assert.Empty(t, progressOut.String())
+}
+
 // Benchmark tests
 func BenchmarkSignScanData(b *testing.B) {
 	_, privKey, err := ed25519.GenerateKey(nil)
@@ -214,7 +297,7 @@ func TestSignatureCompatibility(t *testing.T) {
 	require.NoError(t, err)
 
 	// Manually verify using the same message construction
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), testData...)
+	message := auth.SigningMessage(timestamp, testData)
 	valid := ed25519.Verify(pubKey, message, signature)
 	assert.True(t, valid, "Signature should be valid with manual verification")
 
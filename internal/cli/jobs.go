@@ -19,7 +19,8 @@ Jobs have the following states:
   - pending: Job is queued and waiting to be processed
   - processing: Job is currently being processed
   - completed: Job has completed successfully
-  - failed: Job encountered an error during processing`,
+  - failed: Job encountered an error during processing
+  - cancelled: Job was cancelled before it finished`,
 		Example: `  # List all jobs
   spectra jobs list
 
@@ -3,6 +3,8 @@ package cli
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/fatih/color"
@@ -67,7 +69,7 @@ func runJobsGet(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create client
-	apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout())
+	apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig())
 
 	// If watch mode, continuously poll until terminal state
 	if getWatch {
@@ -136,11 +138,14 @@ func watchJob(apiClient *client.Client, jobID string, interval time.Duration, fo
 					fmt.Printf("  Error: %s\n", *job.ErrorMessage)
 				}
 				fmt.Println()
+			} else if job.State == models.JobStateCancelled {
+				color.New(color.FgMagenta, color.Bold).Println("Job cancelled.")
+				fmt.Println()
 			}
 		}
 
 		// Check if we've reached a terminal state
-		if job.State == models.JobStateCompleted || job.State == models.JobStateFailed {
+		if job.State.IsTerminal() {
 			return formatJob(job, format)
 		}
 
@@ -154,9 +159,9 @@ func formatJob(job *models.Job, format string) error {
 
 	switch outputOpts.Format {
 	case FormatJSON:
-		return formatJSON(outputOpts.Writer, job)
+		return writeJSON(outputOpts, job)
 	case FormatYAML:
-		return formatYAML(outputOpts.Writer, job)
+		return writeYAML(outputOpts, job)
 	case FormatTable:
 		return formatJobDetail(outputOpts, job)
 	default:
@@ -215,6 +220,25 @@ func formatJobDetail(opts *OutputOptions, job *models.Job) error {
 	}
 	fmt.Fprintf(opts.Writer, "Hosts:        %d\n", job.HostCount)
 	fmt.Fprintf(opts.Writer, "Ports:        %d\n", job.PortCount)
+	if job.Progress != nil {
+		fmt.Fprintf(opts.Writer, "Progress:     %d/%d hosts (%.0f%%)\n", job.Progress.HostsDone, job.Progress.HostsTotal, job.Progress.Percent())
+	}
+
+	// Per-step timings recorded by the ingest workflow
+	if len(job.StepDurations) > 0 {
+		fmt.Fprintln(opts.Writer)
+		if !opts.NoColor && opts.IsTerminal {
+			headerColor.Fprintln(opts.Writer, "Step Timings")
+			fmt.Fprintln(opts.Writer, "------------")
+		} else {
+			fmt.Fprintln(opts.Writer, "Step Timings")
+			fmt.Fprintln(opts.Writer, "------------")
+		}
+		for _, step := range orderedSteps(job.StepDurations) {
+			label := step + ":"
+			fmt.Fprintf(opts.Writer, "%-14s%s\n", label, formatDuration(time.Duration(job.StepDurations[step])*time.Millisecond))
+		}
+	}
 
 	// Error message if present
 	if job.ErrorMessage != nil {
@@ -234,6 +258,27 @@ func formatJobDetail(opts *OutputOptions, job *models.Job) error {
 	return nil
 }
 
+// orderedSteps returns step names in workflow order, followed by any unknown steps alphabetically
+func orderedSteps(durations map[string]int64) []string {
+	known := []string{models.JobStepParse, models.JobStepPersist, models.JobStepEnrich}
+	steps := make([]string, 0, len(durations))
+	for _, step := range known {
+		if _, ok := durations[step]; ok {
+			steps = append(steps, step)
+		}
+	}
+
+	var other []string
+	for step := range durations {
+		if !slices.Contains(known, step) {
+			other = append(other, step)
+		}
+	}
+	sort.Strings(other)
+
+	return append(steps, other...)
+}
+
 // formatDuration formats a duration for display
 func formatDuration(d time.Duration) string {
 	if d == 0 {
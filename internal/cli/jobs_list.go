@@ -52,7 +52,7 @@ YAML, or a table (default).`,
 
 	// Add flags
 	cmd.Flags().StringVar(&listScannerKey, "scanner", "", "Filter by scanner public key")
-	cmd.Flags().StringVar(&listState, "state", "", "Filter by job state (pending, processing, completed, failed)")
+	cmd.Flags().StringVar(&listState, "state", "", "Filter by job state (pending, processing, completed, failed, cancelled)")
 	cmd.Flags().IntVar(&listLimit, "limit", 50, "Maximum number of results (max: 500)")
 	cmd.Flags().IntVar(&listOffset, "offset", 0, "Offset for pagination")
 	cmd.Flags().StringVar(&listOrderBy, "order-by", "created_at", "Order by field (created_at, updated_at)")
@@ -82,7 +82,7 @@ func runJobsList(cmd *cobra.Command, args []string) error {
 	if listState != "" {
 		state := models.JobState(listState)
 		if !state.IsValid() {
-			return fmt.Errorf("invalid state: %s (must be one of: pending, processing, completed, failed)", listState)
+			return fmt.Errorf("invalid state: %s (must be one of: pending, processing, completed, failed, cancelled)", listState)
 		}
 		opts.State = &state
 	}
@@ -91,7 +91,7 @@ func runJobsList(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
 	defer cancel()
 
-	apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout())
+	apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig())
 	resp, err := apiClient.ListJobs(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to list jobs: %w", err)
@@ -102,9 +102,9 @@ func runJobsList(cmd *cobra.Command, args []string) error {
 
 	switch outputOpts.Format {
 	case FormatJSON:
-		return formatJSON(outputOpts.Writer, resp)
+		return writeJSON(outputOpts, resp)
 	case FormatYAML:
-		return formatYAML(outputOpts.Writer, resp)
+		return writeYAML(outputOpts, resp)
 	case FormatTable:
 		return formatJobsListTable(outputOpts, resp)
 	default:
@@ -179,6 +179,8 @@ func colorizeJobState(state models.JobState) string {
 		return color.YellowString(state.String())
 	case models.JobStatePending:
 		return color.CyanString(state.String())
+	case models.JobStateCancelled:
+		return color.MagentaString(state.String())
 	default:
 		return state.String()
 	}
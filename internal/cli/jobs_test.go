@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -107,6 +109,38 @@ func TestFormatDuration(t *testing.T) {
 	}
 }
 
+func TestFormatJobDetail_ProgressAndTimings(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	job := &models.Job{
+		ID:         "job-123",
+		State:      models.JobStateProcessing,
+		ScannerKey: "scanner-key",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		HostCount:  0,
+		Progress:   &models.JobProgress{HostsDone: 100, HostsTotal: 400},
+		StepDurations: map[string]int64{
+			models.JobStepEnrich:  40,
+			models.JobStepPersist: 2500,
+			models.JobStepParse:   120,
+		},
+	}
+
+	err := formatJobDetail(opts, job)
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "Progress:     100/400 hosts (25%)")
+	assert.Contains(t, out, "Step Timings")
+	assert.Contains(t, out, "parse:        120ms")
+	assert.Contains(t, out, "persist:      2s")
+	assert.Less(t, strings.Index(out, "parse:"), strings.Index(out, "persist:"), "steps are listed in workflow order")
+	assert.Contains(t, out, "enrich:       40ms")
+	assert.Less(t, strings.Index(out, "persist:"), strings.Index(out, "enrich:"), "steps are listed in workflow order")
+}
+
 func TestJobsCommand(t *testing.T) {
 	cmd := NewJobsCommand()
 
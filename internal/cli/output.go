@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -30,6 +32,7 @@ type OutputOptions struct {
 	NoColor    bool
 	Writer     io.Writer
 	IsTerminal bool
+	Fields     []string // Table columns selected with --fields; empty means the defaults
 }
 
 // NewOutputOptions creates output options with sensible defaults
@@ -86,9 +89,9 @@ func NewFormatter() OutputFormatter {
 func (f *DefaultFormatter) FormatHostQuery(opts *OutputOptions, result *models.HostQueryResponse) error {
 	switch opts.Format {
 	case FormatJSON:
-		return formatJSON(opts.Writer, result)
+		return writeJSON(opts, result)
 	case FormatYAML:
-		return formatYAML(opts.Writer, result)
+		return writeYAML(opts, result)
 	case FormatTable:
 		return formatHostTable(opts, result)
 	default:
@@ -100,9 +103,9 @@ func (f *DefaultFormatter) FormatHostQuery(opts *OutputOptions, result *models.H
 func (f *DefaultFormatter) FormatGraphQuery(opts *OutputOptions, result *models.GraphQueryResponse) error {
 	switch opts.Format {
 	case FormatJSON:
-		return formatJSON(opts.Writer, result)
+		return writeJSON(opts, result)
 	case FormatYAML:
-		return formatYAML(opts.Writer, result)
+		return writeYAML(opts, result)
 	case FormatTable:
 		return formatGraphTable(opts, result)
 	default:
@@ -114,9 +117,9 @@ func (f *DefaultFormatter) FormatGraphQuery(opts *OutputOptions, result *models.
 func (f *DefaultFormatter) FormatSimilarQuery(opts *OutputOptions, result *models.SimilarResponse) error {
 	switch opts.Format {
 	case FormatJSON:
-		return formatJSON(opts.Writer, result)
+		return writeJSON(opts, result)
 	case FormatYAML:
-		return formatYAML(opts.Writer, result)
+		return writeYAML(opts, result)
 	case FormatTable:
 		return formatSimilarTable(opts, result)
 	default:
@@ -139,6 +142,32 @@ func formatYAML(w io.Writer, data interface{}) error {
 	return encoder.Encode(data)
 }
 
+// writeJSON outputs data as JSON, syntax highlighted when writing to a color terminal
+func writeJSON(opts *OutputOptions, data interface{}) error {
+	if !opts.colorize() {
+		return formatJSON(opts.Writer, data)
+	}
+	var buf bytes.Buffer
+	if err := formatJSON(&buf, data); err != nil {
+		return err
+	}
+	_, err := opts.Writer.Write(highlightJSON(buf.Bytes()))
+	return err
+}
+
+// writeYAML outputs data as YAML, syntax highlighted when writing to a color terminal
+func writeYAML(opts *OutputOptions, data interface{}) error {
+	if !opts.colorize() {
+		return formatYAML(opts.Writer, data)
+	}
+	var buf bytes.Buffer
+	if err := formatYAML(&buf, data); err != nil {
+		return err
+	}
+	_, err := opts.Writer.Write(highlightYAML(buf.Bytes()))
+	return err
+}
+
 // formatHostTable formats host query results as a table
 func formatHostTable(opts *OutputOptions, result *models.HostQueryResponse) error {
 	// Header information
@@ -159,28 +188,13 @@ func formatHostTable(opts *OutputOptions, result *models.HostQueryResponse) erro
 
 	// Ports table
 	if len(result.Ports) > 0 {
+		columns := hostPortColumns.selectColumns(opts.Fields)
 		table := tablewriter.NewWriter(opts.Writer)
-		table.SetHeader([]string{"Port", "Protocol", "Service", "Product", "Version"})
+		table.SetHeader(headers(columns))
 		table.SetBorder(true)
 
 		for _, port := range result.Ports {
-			serviceName := ""
-			product := ""
-			version := ""
-
-			if len(port.Services) > 0 {
-				serviceName = port.Services[0].Name
-				product = port.Services[0].Product
-				version = port.Services[0].Version
-			}
-
-			table.Append([]string{
-				fmt.Sprintf("%d", port.Number),
-				port.Protocol,
-				serviceName,
-				product,
-				version,
-			})
+			table.Append(row(columns, port))
 		}
 
 		table.Render()
@@ -194,6 +208,10 @@ func formatHostTable(opts *OutputOptions, result *models.HostQueryResponse) erro
 		} else {
 			fmt.Fprintln(opts.Writer, "Vulnerabilities:")
 		}
+		if s := result.VulnSummary; s != nil {
+			fmt.Fprintf(opts.Writer, "Critical %d | High %d | Medium %d | Low %d | KEV-listed %d\n",
+				s.Critical, s.High, s.Medium, s.Low, s.KEV)
+		}
 
 		table := tablewriter.NewWriter(opts.Writer)
 		table.SetHeader([]string{"CVE ID", "CVSS", "Severity", "KEV", "First Detected"})
@@ -246,23 +264,13 @@ func formatGraphTable(opts *OutputOptions, result *models.GraphQueryResponse) er
 	}
 
 	// Results table
+	columns := graphColumns.selectColumns(opts.Fields)
 	table := tablewriter.NewWriter(opts.Writer)
-	table.SetHeader([]string{"IP", "ASN", "City", "Country", "Ports", "Services", "Last Seen"})
+	table.SetHeader(headers(columns))
 	table.SetBorder(true)
 
 	for _, host := range result.Results {
-		portCount := len(host.Ports)
-		serviceCount := len(host.Services)
-
-		table.Append([]string{
-			host.IP,
-			fmt.Sprintf("%d", host.ASN),
-			host.City,
-			host.Country,
-			fmt.Sprintf("%d", portCount),
-			fmt.Sprintf("%d", serviceCount),
-			formatTime(host.LastSeen),
-		})
+		table.Append(row(columns, host))
 	}
 
 	table.Render()
@@ -301,7 +309,8 @@ func formatSimilarTable(opts *OutputOptions, result *models.SimilarResponse) err
 	table.SetAutoWrapText(true)
 	table.SetColWidth(60)
 
-	for _, vuln := range result.Results {
+	results := sortedByScore(result.Results)
+	for _, vuln := range results {
 		score := fmt.Sprintf("%.3f", vuln.Score)
 		if !opts.NoColor && opts.IsTerminal {
 			score = colorScore(vuln.Score)
@@ -317,9 +326,88 @@ func formatSimilarTable(opts *OutputOptions, result *models.SimilarResponse) err
 
 	table.Render()
 
+	// Summary footer
+	fmt.Fprintf(opts.Writer, "\nSeverity: %s\n", severitySummary(results))
+	fmt.Fprintf(opts.Writer, "KEV-listed: %d of %d\n", countKEV(results), len(results))
+	if result.BelowMinScore > 0 {
+		fmt.Fprintf(opts.Writer, "Below minimum score: %d hidden\n", result.BelowMinScore)
+	}
+	if result.HasMore && result.NextOffset != nil {
+		fmt.Fprintf(opts.Writer, "More results available: rerun with --offset %d\n", *result.NextOffset)
+	}
+
 	return nil
 }
 
+// sortedByScore returns a copy of results ordered by descending similarity score
+// Hybrid search may return results unordered; ties keep their original order.
+func sortedByScore(results []models.VulnResult) []models.VulnResult {
+	sorted := append([]models.VulnResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+	return sorted
+}
+
+// filterByMinScore returns the results whose similarity score is at least minScore
+func filterByMinScore(results []models.VulnResult, minScore float64) []models.VulnResult {
+	filtered := []models.VulnResult{}
+	for _, vuln := range results {
+		if vuln.Score >= minScore {
+			filtered = append(filtered, vuln)
+		}
+	}
+	return filtered
+}
+
+// severityLevels lists the CVSS severity ratings in the order they are summarized
+var severityLevels = []string{"Critical", "High", "Medium", "Low", "Unknown"}
+
+// cvssSeverity returns the CVSS v3 severity rating for a base score
+// A zero score means the CVSS was not reported.
+func cvssSeverity(cvss float64) string {
+	switch {
+	case cvss >= 9.0:
+		return "Critical"
+	case cvss >= 7.0:
+		return "High"
+	case cvss >= 4.0:
+		return "Medium"
+	case cvss > 0:
+		return "Low"
+	default:
+		return "Unknown"
+	}
+}
+
+// severitySummary counts results by severity, e.g. "Critical 2 | High 1"
+// Severities with no results are left out.
+func severitySummary(results []models.VulnResult) string {
+	counts := make(map[string]int)
+	for _, vuln := range results {
+		counts[cvssSeverity(vuln.CVSS)]++
+	}
+
+	var parts []string
+	for _, level := range severityLevels {
+		if counts[level] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", level, counts[level]))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// countKEV returns how many results are listed in the CISA KEV catalog
+func countKEV(results []models.VulnResult) int {
+	count := 0
+	for _, vuln := range results {
+		if vuln.KEV {
+			count++
+		}
+	}
+	return count
+}
+
 // Helper functions
 
 // formatTime formats a time.Time for display
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// progressBarWidth is the number of cells in the rendered bar
+const progressBarWidth = 30
+
+// progressBar renders a single-line progress bar, redrawn in place with \r
+// A disabled bar (not a terminal, or --quiet) writes nothing.
+type progressBar struct {
+	w       io.Writer
+	enabled bool
+	label   string
+	total   int
+	done    int
+}
+
+// newProgressBar creates a bar counting up to total
+func newProgressBar(w io.Writer, label string, total int, enabled bool) *progressBar {
+	return &progressBar{w: w, enabled: enabled, label: label, total: total}
+}
+
+// Add advances the bar by n and redraws it
+func (p *progressBar) Add(n int) {
+	p.done += n
+	if p.done > p.total {
+		p.done = p.total
+	}
+	p.render()
+}
+
+// Finish ends the bar's line so later output starts on a fresh one
+func (p *progressBar) Finish() {
+	if p.enabled {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// render draws the bar at its current position
+func (p *progressBar) render() {
+	if !p.enabled || p.total <= 0 {
+		return
+	}
+	filled := p.done * progressBarWidth / p.total
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(p.w, "\r%s [%s] %d/%d", p.label, bar, p.done, p.total)
+}
@@ -1,9 +1,14 @@
 package cli
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"net"
 	"os"
+	"time"
 
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spectra-red/recon/internal/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -15,14 +20,16 @@ var QueryCmd = &cobra.Command{
 	Long: `Query the Spectra-Red intelligence mesh for threat intelligence data.
 
 Available subcommands:
-  host    - Query host information by IP address
-  graph   - Execute advanced graph traversal queries
-  similar - Search for similar vulnerabilities using vector similarity
+  host     - Query host information by IP address
+  graph    - Execute advanced graph traversal queries
+  similar  - Search for similar vulnerabilities using vector similarity
+  exposure - List public hosts exposing risky management ports
 
 Examples:
   spectra query host 1.2.3.4
   spectra query graph --type by_asn --value 16509
-  spectra query similar "nginx remote code execution"`,
+  spectra query similar "nginx remote code execution"
+  spectra query exposure --ports 22,3389`,
 }
 
 var (
@@ -47,6 +54,7 @@ func init() {
 	QueryCmd.AddCommand(hostQueryCmd)
 	QueryCmd.AddCommand(graphQueryCmd)
 	QueryCmd.AddCommand(similarQueryCmd)
+	QueryCmd.AddCommand(exposureQueryCmd)
 }
 
 // NewQueryCommand creates the query command with subcommands (for compatibility)
@@ -74,6 +82,53 @@ func getAPIURL() string {
 	return "http://localhost:3000"
 }
 
+// defaultQueryTimeout is used when no api.timeout is configured
+const defaultQueryTimeout = 30 * time.Second
+
+// getQueryTimeout returns the request timeout set by --timeout or api.timeout
+func getQueryTimeout() time.Duration {
+	if d := GetAPITimeout(); d > 0 {
+		return d
+	}
+	return defaultQueryTimeout
+}
+
+// newQueryClient creates a query client using the configured API URL and timeout
+func newQueryClient() *client.QueryClient {
+	return client.NewQueryClientWithTimeout(getAPIURL(), getQueryTimeout()).WithTLSConfig(GetTLSConfig())
+}
+
+// hostGraphQuerier runs host and graph queries
+type hostGraphQuerier interface {
+	QueryHost(ctx context.Context, ip string, depth int) (*models.HostQueryResponse, error)
+	GraphQuery(ctx context.Context, req *models.GraphQueryRequest) (*models.GraphQueryResponse, error)
+}
+
+// newHostGraphQuerier returns the query client, federated to the configured
+// peers when there are any
+func newHostGraphQuerier() hostGraphQuerier {
+	federation := GetFederationConfig()
+	if len(federation.Peers) == 0 {
+		return newQueryClient()
+	}
+	return client.NewFederatedQueryClient(newQueryClient(), federation)
+}
+
+// newQueryContext returns a context that expires after the configured timeout
+func newQueryContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), getQueryTimeout())
+}
+
+// timeoutError replaces a deadline or client timeout error with a clearer message
+// Other errors are returned unchanged.
+func timeoutError(err error, timeout time.Duration) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &requestTimeoutError{timeout: timeout}
+	}
+	return err
+}
+
 // getOutputOptions returns output options based on flags
 func getOutputOptions() *OutputOptions {
 	format := outputFormat
@@ -92,12 +147,14 @@ func getOutputOptions() *OutputOptions {
 	return NewOutputOptions(format, nc)
 }
 
-// handleError prints an error message and exits
+// handleError prints an error message and exits with the error's ExitCode
+// With --json-errors the message is printed as an ErrorOutput object.
 func handleError(err error, message string) {
-	if message != "" {
-		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", message, err)
-	} else {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-	}
-	os.Exit(1)
+	writeError(os.Stderr, err, message, jsonErrors)
+	osExit(ExitCode(err))
+}
+
+// handleInputError reports an invalid argument or flag and exits
+func handleInputError(err error, message string) {
+	handleError(&InputError{Err: err}, message)
 }
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exposurePorts []int
+	exposureLimit int
+)
+
+var exposureQueryCmd = &cobra.Command{
+	Use:   "exposure",
+	Short: "List public hosts exposing risky management ports",
+	Long: `List hosts exposing management and data-store services to the internet,
+grouped by the port they expose, the most exposed port first.
+
+By default the server's risky-port set is used (SSH, RDP, Telnet, SMB, VNC,
+Redis, MongoDB and similar). Hosts with private or otherwise non-routable
+addresses are not counted as exposed.
+
+Examples:
+  # Default risky ports
+  spectra query exposure
+
+  # Only SSH and RDP, listing up to 20 hosts per port
+  spectra query exposure --ports 22,3389 --limit 20
+
+  # Output as JSON
+  spectra query exposure --output json`,
+	Args: cobra.NoArgs,
+	Run:  runExposureQuery,
+}
+
+func init() {
+	exposureQueryCmd.Flags().IntSliceVar(&exposurePorts, "ports", nil, "Comma-separated ports to look for (default: the server's risky ports)")
+	exposureQueryCmd.Flags().IntVarP(&exposureLimit, "limit", "l", models.DefaultLimit, "Maximum hosts listed per port (1-1000)")
+}
+
+func runExposureQuery(cmd *cobra.Command, args []string) {
+	for _, port := range exposurePorts {
+		if port < 1 || port > 65535 {
+			handleInputError(fmt.Errorf("port must be between 1 and 65535, got %d", port), "")
+		}
+	}
+	if exposureLimit < 1 || exposureLimit > models.MaxLimit {
+		handleInputError(fmt.Errorf("limit must be between 1 and %d, got %d", models.MaxLimit, exposureLimit), "")
+	}
+
+	ctx, cancel := newQueryContext()
+	defer cancel()
+
+	result, err := newQueryClient().Exposure(ctx, exposurePorts, exposureLimit)
+	if err != nil {
+		handleError(timeoutError(err, getQueryTimeout()), "failed to query exposure")
+	}
+
+	opts := getOutputOptions()
+	switch opts.Format {
+	case FormatJSON:
+		err = writeJSON(opts, result)
+	case FormatYAML:
+		err = writeYAML(opts, result)
+	default:
+		err = formatExposureTable(opts, result)
+	}
+	if err != nil {
+		handleError(err, "failed to format output")
+	}
+}
+
+// formatExposureTable formats the exposure report as one table row per port
+func formatExposureTable(opts *OutputOptions, result *models.ExposureResponse) error {
+	headerColor := color.New(color.FgCyan, color.Bold)
+
+	if !opts.NoColor && opts.IsTerminal {
+		headerColor.Fprintf(opts.Writer, "\nInternet Exposure\n")
+	} else {
+		fmt.Fprintf(opts.Writer, "\nInternet Exposure\n")
+	}
+
+	fmt.Fprintf(opts.Writer, "Exposed ports: %d | Query Time: %.2f ms\n\n",
+		len(result.Results), result.QueryTime)
+
+	if len(result.Results) == 0 {
+		fmt.Fprintln(opts.Writer, "No exposed hosts found.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(opts.Writer)
+	table.SetHeader([]string{"Port", "Service", "Hosts", "IPs"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+
+	truncated := false
+	for _, group := range result.Results {
+		service := group.Service
+		if service == "" {
+			service = "-"
+		}
+		ips := strings.Join(group.Hosts, ", ")
+		if len(group.Hosts) < group.HostCount {
+			ips += fmt.Sprintf(", ... (+%d)", group.HostCount-len(group.Hosts))
+			truncated = true
+		}
+		table.Append([]string{fmt.Sprintf("%d", group.Port), service, fmt.Sprintf("%d", group.HostCount), ips})
+	}
+
+	table.Render()
+
+	if truncated {
+		fmt.Fprintf(opts.Writer, "\nAt most %d hosts are listed per port. Use --limit to list more.\n", result.Limit)
+	}
+
+	return nil
+}
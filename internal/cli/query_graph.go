@@ -1,9 +1,9 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -20,7 +20,14 @@ var (
 	graphRegion  string
 	graphCountry string
 	graphProduct string
+	graphVersion string
 	graphService string
+	graphFields  string
+	graphStable  bool
+
+	graphFirstSeenAfter string
+	graphLastSeenAfter  string
+	graphLastSeenBefore string
 )
 
 var graphQueryCmd = &cobra.Command{
@@ -33,6 +40,7 @@ Query Types:
   by_location - Find hosts by geographic location
   by_vuln     - Find hosts affected by a specific CVE
   by_service  - Find hosts running a specific service
+  by_kev      - Find hosts affected by any CISA KEV-listed CVE, highest CVSS first
 
 Examples:
   # Query by ASN
@@ -50,19 +58,40 @@ Examples:
   # Query by service product
   spectra query graph --type by_service --product nginx
 
+  # Query by service product and version range
+  spectra query graph --type by_service --product nginx --version-constraint '<1.25.0'
+
+  # Query hosts with known-exploited vulnerabilities
+  spectra query graph --type by_kev
+
   # With pagination
   spectra query graph --type by_asn --value 16509 --limit 50 --offset 50
 
+  # Page through an export in a fixed order (by host id)
+  spectra query graph --type by_asn --value 16509 --limit 1000 --offset 1000 --stable
+
+  # Hosts running nginx first observed in the last 24 hours
+  spectra query graph --type by_service --product nginx --first-seen-after 24h
+
+  # Show only some table columns
+  spectra query graph --type by_asn --value 16509 --fields ip,country,first_seen
+
   # Output as JSON
   spectra query graph --type by_vuln --value CVE-2024-1234 --output json`,
 	Run: runGraphQuery,
 }
 
 func init() {
-	graphQueryCmd.Flags().StringVar(&graphType, "type", "", "Query type (by_asn, by_location, by_vuln, by_service)")
+	graphQueryCmd.Flags().StringVar(&graphType, "type", "", "Query type (by_asn, by_location, by_vuln, by_service, by_kev)")
 	graphQueryCmd.Flags().StringVar(&graphValue, "value", "", "Query value (ASN number or CVE ID)")
 	graphQueryCmd.Flags().IntVar(&graphLimit, "limit", 100, "Maximum number of results (1-1000)")
 	graphQueryCmd.Flags().IntVar(&graphOffset, "offset", 0, "Offset for pagination")
+	graphQueryCmd.Flags().BoolVar(&graphStable, "stable", false, "Order results by host id so repeated paging is reproducible (slower on large result sets)")
+
+	// Time window flags, applied to every query type
+	graphQueryCmd.Flags().StringVar(&graphFirstSeenAfter, "first-seen-after", "", "Only hosts first seen at or after this time (RFC 3339, or a duration ago such as 24h)")
+	graphQueryCmd.Flags().StringVar(&graphLastSeenAfter, "last-seen-after", "", "Only hosts last seen at or after this time (RFC 3339, or a duration ago such as 24h)")
+	graphQueryCmd.Flags().StringVar(&graphLastSeenBefore, "last-seen-before", "", "Only hosts last seen before this time (RFC 3339, or a duration ago such as 168h)")
 
 	// Location-specific flags
 	graphQueryCmd.Flags().StringVar(&graphCity, "city", "", "City name for location queries")
@@ -72,6 +101,9 @@ func init() {
 	// Service-specific flags
 	graphQueryCmd.Flags().StringVar(&graphProduct, "product", "", "Product name for service queries (e.g., 'nginx')")
 	graphQueryCmd.Flags().StringVar(&graphService, "service", "", "Service name for service queries (e.g., 'http')")
+	graphQueryCmd.Flags().StringVar(&graphVersion, "version-constraint", "", "Version constraint for service queries (e.g., '<1.25.0')")
+
+	graphQueryCmd.Flags().StringVar(&graphFields, "fields", "", "Comma-separated table columns ("+strings.Join(graphColumns.names(), ", ")+")")
 
 	graphQueryCmd.MarkFlagRequired("type")
 }
@@ -88,13 +120,27 @@ func runGraphQuery(cmd *cobra.Command, args []string) {
 		queryType = models.QueryByVuln
 	case "by_service":
 		queryType = models.QueryByService
+	case "by_kev":
+		queryType = models.QueryByKEV
 	default:
-		handleError(fmt.Errorf("invalid query type: %s", graphType), "must be one of: by_asn, by_location, by_vuln, by_service")
+		handleInputError(fmt.Errorf("invalid query type: %s", graphType), "must be one of: by_asn, by_location, by_vuln, by_service, by_kev")
 	}
 
 	// Validate limit
 	if graphLimit < 1 || graphLimit > 1000 {
-		handleError(fmt.Errorf("limit must be between 1 and 1000, got %d", graphLimit), "")
+		handleInputError(fmt.Errorf("limit must be between 1 and 1000, got %d", graphLimit), "")
+	}
+
+	// Validate table columns
+	fields, err := graphColumns.parse(graphFields)
+	if err != nil {
+		handleInputError(err, "")
+	}
+
+	// Validate the time window
+	window, err := parseGraphWindow(graphFirstSeenAfter, graphLastSeenAfter, graphLastSeenBefore, time.Now())
+	if err != nil {
+		handleInputError(err, "")
 	}
 
 	// Build request based on query type
@@ -103,54 +149,98 @@ func runGraphQuery(cmd *cobra.Command, args []string) {
 	switch queryType {
 	case models.QueryByASN:
 		if graphValue == "" {
-			handleError(fmt.Errorf("--value is required for by_asn queries"), "")
+			handleInputError(fmt.Errorf("--value is required for by_asn queries"), "")
 		}
 		asn, err := strconv.Atoi(graphValue)
 		if err != nil {
-			handleError(fmt.Errorf("invalid ASN: %s", graphValue), "ASN must be a number")
+			handleInputError(fmt.Errorf("invalid ASN: %s", graphValue), "ASN must be a number")
 		}
 		req = client.GraphQueryByASN(asn, graphLimit, graphOffset)
 
 	case models.QueryByLocation:
 		if graphCity == "" && graphRegion == "" && graphCountry == "" {
-			handleError(fmt.Errorf("at least one of --city, --region, or --country is required for by_location queries"), "")
+			handleInputError(fmt.Errorf("at least one of --city, --region, or --country is required for by_location queries"), "")
 		}
 		req = client.GraphQueryByLocation(graphCity, graphRegion, graphCountry, graphLimit, graphOffset)
 
 	case models.QueryByVuln:
 		if graphValue == "" {
-			handleError(fmt.Errorf("--value is required for by_vuln queries"), "CVE ID required")
+			handleInputError(fmt.Errorf("--value is required for by_vuln queries"), "CVE ID required")
 		}
 		req = client.GraphQueryByVuln(graphValue, graphLimit, graphOffset)
 
 	case models.QueryByService:
 		if graphProduct == "" && graphService == "" {
-			handleError(fmt.Errorf("at least one of --product or --service is required for by_service queries"), "")
+			handleInputError(fmt.Errorf("at least one of --product or --service is required for by_service queries"), "")
 		}
 		req = client.GraphQueryByService(graphProduct, graphService, graphLimit, graphOffset)
-	}
+		req.VersionConstraint = graphVersion
 
-	// Get API URL
-	baseURL := getAPIURL()
+	case models.QueryByKEV:
+		req = client.GraphQueryByKEV(graphLimit, graphOffset)
+	}
+	req.Stable = graphStable
+	req.FirstSeenAfter = window.FirstSeenAfter
+	req.LastSeenAfter = window.LastSeenAfter
+	req.LastSeenBefore = window.LastSeenBefore
 
 	// Create client
-	queryClient := client.NewQueryClient(baseURL)
+	queryClient := newHostGraphQuerier()
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := newQueryContext()
 	defer cancel()
 
 	// Execute query
 	result, err := queryClient.GraphQuery(ctx, req)
 	if err != nil {
-		handleError(err, "failed to execute graph query")
+		handleError(timeoutError(err, getQueryTimeout()), "failed to execute graph query")
 	}
 
 	// Format and output result
 	opts := getOutputOptions()
+	opts.Fields = fields
 	formatter := NewFormatter()
 
 	if err := formatter.FormatGraphQuery(opts, result); err != nil {
 		handleError(err, "failed to format output")
 	}
+
+	if len(result.Results) == 0 {
+		osExit(ExitNoResults)
+	}
+}
+
+// parseGraphWindow reads the time window flags into the bounds of a graph query
+// request; empty flags leave their bound unset
+func parseGraphWindow(firstSeenAfter, lastSeenAfter, lastSeenBefore string, now time.Time) (models.GraphQueryRequest, error) {
+	var window models.GraphQueryRequest
+	flags := []struct {
+		name  string
+		value string
+		bound **time.Time
+	}{
+		{"--first-seen-after", firstSeenAfter, &window.FirstSeenAfter},
+		{"--last-seen-after", lastSeenAfter, &window.LastSeenAfter},
+		{"--last-seen-before", lastSeenBefore, &window.LastSeenBefore},
+	}
+	for _, flag := range flags {
+		t, err := parseAuditTime(flag.value, now)
+		if err != nil {
+			return window, fmt.Errorf("invalid %s: %w", flag.name, err)
+		}
+		if !t.IsZero() {
+			*flag.bound = &t
+		}
+	}
+
+	if window.LastSeenBefore != nil {
+		if window.LastSeenAfter != nil && !window.LastSeenAfter.Before(*window.LastSeenBefore) {
+			return window, fmt.Errorf("invalid time range: --last-seen-after must be before --last-seen-before")
+		}
+		if window.FirstSeenAfter != nil && !window.FirstSeenAfter.Before(*window.LastSeenBefore) {
+			return window, fmt.Errorf("invalid time range: --first-seen-after must be before --last-seen-before")
+		}
+	}
+	return window, nil
 }
@@ -1,18 +1,17 @@
 package cli
 
 import (
-	"context"
 	"fmt"
 	"net"
-	"time"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spectra-red/recon/internal/client"
 	"github.com/spectra-red/recon/internal/models"
 )
 
 var (
-	hostDepth int
+	hostDepth  int
+	hostFields string
 )
 
 var hostQueryCmd = &cobra.Command{
@@ -34,6 +33,9 @@ Examples:
   # Query with full vulnerability information
   spectra query host 1.2.3.4 --depth 3
 
+  # Show only some ports table columns
+  spectra query host 1.2.3.4 --fields port,service,last_seen
+
   # Output as JSON
   spectra query host 1.2.3.4 --output json
 
@@ -45,6 +47,7 @@ Examples:
 
 func init() {
 	hostQueryCmd.Flags().IntVarP(&hostDepth, "depth", "d", int(models.DefaultDepth()), "Query depth (0-5)")
+	hostQueryCmd.Flags().StringVar(&hostFields, "fields", "", "Comma-separated ports table columns ("+strings.Join(hostPortColumns.names(), ", ")+")")
 }
 
 func runHostQuery(cmd *cobra.Command, args []string) {
@@ -52,32 +55,36 @@ func runHostQuery(cmd *cobra.Command, args []string) {
 
 	// Validate IP address
 	if net.ParseIP(ip) == nil {
-		handleError(fmt.Errorf("invalid IP address: %s", ip), "")
+		handleInputError(fmt.Errorf("invalid IP address: %s", ip), "")
 	}
 
 	// Validate depth
 	if !models.ValidateDepth(hostDepth) {
-		handleError(fmt.Errorf("depth must be between 0 and 5, got %d", hostDepth), "")
+		handleInputError(fmt.Errorf("depth must be between 0 and 5, got %d", hostDepth), "")
 	}
 
-	// Get API URL
-	baseURL := getAPIURL()
+	// Validate table columns
+	fields, err := hostPortColumns.parse(hostFields)
+	if err != nil {
+		handleInputError(err, "")
+	}
 
 	// Create client
-	queryClient := client.NewQueryClient(baseURL)
+	queryClient := newHostGraphQuerier()
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := newQueryContext()
 	defer cancel()
 
 	// Execute query
 	result, err := queryClient.QueryHost(ctx, ip, hostDepth)
 	if err != nil {
-		handleError(err, "failed to query host")
+		handleError(timeoutError(err, getQueryTimeout()), "failed to query host")
 	}
 
 	// Format and output result
 	opts := getOutputOptions()
+	opts.Fields = fields
 	formatter := NewFormatter()
 
 	if err := formatter.FormatHostQuery(opts, result); err != nil {
@@ -1,9 +1,7 @@
 package cli
 
 import (
-	"context"
 	"fmt"
-	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spectra-red/recon/internal/client"
@@ -11,7 +9,9 @@ import (
 )
 
 var (
-	similarK int
+	similarK        int
+	similarMinScore float64
+	similarOffset   int
 )
 
 var similarQueryCmd = &cobra.Command{
@@ -38,6 +38,12 @@ Examples:
   # Get more results
   spectra query similar "SQL injection" --k 20
 
+  # Only show close matches
+  spectra query similar "SQL injection" --min-score 0.8
+
+  # Show the next page of results
+  spectra query similar "SQL injection" --offset 10
+
   # Output as JSON
   spectra query similar "XSS vulnerability" --output json
 
@@ -49,6 +55,8 @@ Examples:
 
 func init() {
 	similarQueryCmd.Flags().IntVarP(&similarK, "k", "k", models.DefaultK, fmt.Sprintf("Number of results to return (1-%d)", models.MaxK))
+	similarQueryCmd.Flags().Float64Var(&similarMinScore, "min-score", 0, "Hide results with a similarity score below this value (0.0-1.0)")
+	similarQueryCmd.Flags().IntVar(&similarOffset, "offset", 0, fmt.Sprintf("Skip this many results, to page through matches (0-%d)", models.MaxOffset))
 }
 
 func runSimilarQuery(cmd *cobra.Command, args []string) {
@@ -63,31 +71,41 @@ func runSimilarQuery(cmd *cobra.Command, args []string) {
 
 	// Validate K
 	if similarK < 1 || similarK > models.MaxK {
-		handleError(fmt.Errorf("k must be between 1 and %d, got %d", models.MaxK, similarK), "")
+		handleInputError(fmt.Errorf("k must be between 1 and %d, got %d", models.MaxK, similarK), "")
+	}
+
+	// Validate minimum score
+	if similarMinScore < 0 || similarMinScore > 1 {
+		handleInputError(fmt.Errorf("min-score must be between 0.0 and 1.0, got %g", similarMinScore), "")
 	}
 
 	// Create request
 	req := client.NewSimilarRequest(queryText, similarK)
+	req.Offset = similarOffset
+	req.MinScore = similarMinScore
 
 	// Validate request
 	if err := req.Validate(); err != nil {
-		handleError(err, "invalid request")
+		handleInputError(err, "invalid request")
 	}
 
-	// Get API URL
-	baseURL := getAPIURL()
-
 	// Create client
-	queryClient := client.NewQueryClient(baseURL)
+	queryClient := newQueryClient()
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := newQueryContext()
 	defer cancel()
 
 	// Execute query
 	result, err := queryClient.SimilarQuery(ctx, req)
 	if err != nil {
-		handleError(err, "failed to execute similarity search")
+		handleError(timeoutError(err, getQueryTimeout()), "failed to execute similarity search")
+	}
+
+	// Also filter client-side; older servers ignore min_score
+	if similarMinScore > 0 {
+		result.Results = filterByMinScore(result.Results, similarMinScore)
+		result.Count = len(result.Results)
 	}
 
 	// Format and output result
@@ -97,4 +115,8 @@ func runSimilarQuery(cmd *cobra.Command, args []string) {
 	if err := formatter.FormatSimilarQuery(opts, result); err != nil {
 		handleError(err, "failed to format output")
 	}
+
+	if len(result.Results) == 0 {
+		osExit(ExitNoResults)
+	}
 }
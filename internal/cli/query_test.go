@@ -2,11 +2,18 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/spectra-red/recon/internal/client"
 	"github.com/spectra-red/recon/internal/models"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -138,6 +145,7 @@ func TestFormatHostTable(t *testing.T) {
 				FirstSeen: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
 			},
 		},
+		VulnSummary: &models.VulnSummary{Critical: 1, KEV: 1},
 	}
 
 	var buf bytes.Buffer
@@ -161,6 +169,7 @@ func TestFormatHostTable(t *testing.T) {
 	assert.Contains(t, output, "CVE-2024-1234")
 	assert.Contains(t, output, "9.8")
 	assert.Contains(t, output, "Critical")
+	assert.Contains(t, output, "Critical 1 | High 0 | Medium 0 | Low 0 | KEV-listed 1")
 }
 
 func TestFormatGraphTable(t *testing.T) {
@@ -282,6 +291,37 @@ func TestFormatGraphTable_WithPagination(t *testing.T) {
 	assert.Contains(t, output, "offset 50")
 }
 
+func TestFormatExposureTable(t *testing.T) {
+	result := &models.ExposureResponse{
+		Results: []models.ExposureGroup{
+			{Port: 22, Service: "ssh", HostCount: 3, Hosts: []string{"198.51.100.4", "203.0.113.5"}},
+			{Port: 8443, HostCount: 1, Hosts: []string{"203.0.113.9"}},
+		},
+		Ports: []int{22, 8443},
+		Limit: 2,
+	}
+
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	require.NoError(t, formatExposureTable(opts, result))
+
+	output := buf.String()
+	assert.Contains(t, output, "Exposed ports: 2")
+	assert.Contains(t, output, "ssh")
+	assert.Contains(t, output, "198.51.100.4, 203.0.113.5, ... (+1)")
+	assert.Contains(t, output, "8443")
+	assert.Contains(t, output, "At most 2 hosts are listed per port")
+}
+
+func TestFormatExposureTable_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	require.NoError(t, formatExposureTable(opts, &models.ExposureResponse{Results: []models.ExposureGroup{}}))
+	assert.Contains(t, buf.String(), "No exposed hosts found.")
+}
+
 func TestFormatSimilarTable(t *testing.T) {
 	result := &models.SimilarResponse{
 		Query: "nginx remote code execution",
@@ -349,6 +389,77 @@ func TestFormatSimilarTable_Empty(t *testing.T) {
 	assert.Contains(t, output, "No similar vulnerabilities found")
 }
 
+func TestFormatSimilarTable_SortedWithSummary(t *testing.T) {
+	result := &models.SimilarResponse{
+		Query: "remote code execution",
+		Results: []models.VulnResult{
+			{CVEID: "CVE-2024-0003", Title: "Medium issue", CVSS: 5.3, Score: 0.71},
+			{CVEID: "CVE-2024-0001", Title: "Critical issue", CVSS: 9.8, Score: 0.93, KEV: true},
+			{CVEID: "CVE-2024-0004", Title: "Unscored issue", Score: 0.65},
+			{CVEID: "CVE-2024-0002", Title: "Another critical issue", CVSS: 9.1, Score: 0.88, KEV: true},
+		},
+		Count:     4,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	err := formatSimilarTable(opts, result)
+	require.NoError(t, err)
+
+	output := buf.String()
+	first := strings.Index(output, "CVE-2024-0001")
+	second := strings.Index(output, "CVE-2024-0002")
+	third := strings.Index(output, "CVE-2024-0003")
+	fourth := strings.Index(output, "CVE-2024-0004")
+	assert.True(t, first < second && second < third && third < fourth, "results should be ordered by descending score")
+
+	assert.Contains(t, output, "Severity: Critical 2 | Medium 1 | Unknown 1")
+	assert.Contains(t, output, "KEV-listed: 2 of 4")
+
+	// The response itself is left in the order it arrived
+	assert.Equal(t, "CVE-2024-0003", result.Results[0].CVEID)
+}
+
+func TestFormatSimilarTable_Paging(t *testing.T) {
+	next := 10
+	result := &models.SimilarResponse{
+		Query:         "sql injection",
+		Results:       []models.VulnResult{{CVEID: "CVE-2024-0001", Title: "Issue", Score: 0.9}},
+		Count:         1,
+		HasMore:       true,
+		NextOffset:    &next,
+		MinScore:      0.8,
+		BelowMinScore: 3,
+		Timestamp:     time.Now().Format(time.RFC3339),
+	}
+
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+
+	require.NoError(t, formatSimilarTable(opts, result))
+
+	output := buf.String()
+	assert.Contains(t, output, "Below minimum score: 3 hidden")
+	assert.Contains(t, output, "rerun with --offset 10")
+}
+
+func TestFilterByMinScore(t *testing.T) {
+	results := []models.VulnResult{
+		{CVEID: "CVE-2024-0001", Score: 0.93},
+		{CVEID: "CVE-2024-0002", Score: 0.8},
+		{CVEID: "CVE-2024-0003", Score: 0.71},
+	}
+
+	filtered := filterByMinScore(results, 0.8)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "CVE-2024-0001", filtered[0].CVEID)
+	assert.Equal(t, "CVE-2024-0002", filtered[1].CVEID)
+
+	assert.Empty(t, filterByMinScore(results, 0.95))
+}
+
 func TestFormatTime(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -460,6 +571,73 @@ func TestGetAPIURL(t *testing.T) {
 	}
 }
 
+func TestTimeoutFlag_SetsQueryClientTimeout(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	assert.Equal(t, defaultQueryTimeout, newQueryClient().Timeout())
+
+	var out bytes.Buffer
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"--timeout", "2m", "version"})
+	require.NoError(t, rootCmd.Execute())
+
+	assert.Equal(t, 2*time.Minute, getQueryTimeout())
+	assert.Equal(t, 2*time.Minute, newQueryClient().Timeout())
+}
+
+func TestTimeoutFlag_RejectsInvalidDuration(t *testing.T) {
+	for _, value := range []string{"soon", "30", "0s", "-5s"} {
+		t.Run(value, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			rootCmd := NewRootCommand()
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+			rootCmd.SetArgs([]string{"--timeout", value, "version"})
+
+			err := rootCmd.Execute()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid --timeout")
+		})
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.NewQueryClient(server.URL).QueryHost(ctx, "192.0.2.1", 0)
+		require.Error(t, err)
+		assert.EqualError(t, timeoutError(err, 20*time.Millisecond),
+			"request timed out after 20ms (use --timeout to allow longer)")
+	})
+
+	t.Run("client timeout", func(t *testing.T) {
+		queryClient := client.NewQueryClientWithTimeout(server.URL, 20*time.Millisecond)
+
+		_, err := queryClient.QueryHost(context.Background(), "192.0.2.1", 0)
+		require.Error(t, err)
+		assert.Contains(t, timeoutError(err, queryClient.Timeout()).Error(), "timed out after 20ms")
+	})
+
+	t.Run("other errors unchanged", func(t *testing.T) {
+		err := errors.New("API returned status 500")
+		assert.Same(t, err, timeoutError(err, time.Second))
+	})
+}
+
 func TestDefaultFormatter(t *testing.T) {
 	formatter := NewFormatter()
 	assert.NotNil(t, formatter)
@@ -515,3 +693,30 @@ func TestDefaultFormatter(t *testing.T) {
 		assert.Contains(t, buf.String(), "test")
 	})
 }
+
+func TestParseGraphWindow(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	window, err := parseGraphWindow("24h", "", "2026-03-01T06:00:00Z", now)
+	require.NoError(t, err)
+	require.NotNil(t, window.FirstSeenAfter)
+	assert.Equal(t, now.Add(-24*time.Hour), *window.FirstSeenAfter)
+	assert.Nil(t, window.LastSeenAfter)
+	require.NotNil(t, window.LastSeenBefore)
+	assert.Equal(t, time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC), *window.LastSeenBefore)
+
+	window, err = parseGraphWindow("", "", "", now)
+	require.NoError(t, err)
+	assert.Nil(t, window.FirstSeenAfter)
+	assert.Nil(t, window.LastSeenAfter)
+	assert.Nil(t, window.LastSeenBefore)
+
+	_, err = parseGraphWindow("last week", "", "", now)
+	assert.ErrorContains(t, err, "invalid --first-seen-after")
+
+	_, err = parseGraphWindow("", "1h", "2h", now)
+	assert.ErrorContains(t, err, "--last-seen-after must be before --last-seen-before")
+
+	_, err = parseGraphWindow("1h", "", "2h", now)
+	assert.ErrorContains(t, err, "--first-seen-after must be before --last-seen-before")
+}
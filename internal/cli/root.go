@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spectra-red/recon/internal/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 var (
@@ -15,9 +20,19 @@ var (
 	BuildDate = "unknown"
 
 	// Global flags
-	cfgFile string
-	apiURL  string
-	verbose bool
+	cfgFile  string
+	apiURL   string
+	timeout  string
+	caCert   string
+	insecure bool
+	logLevel string
+	verbose  bool
+
+	// apiTLSConfig is loaded from --ca-cert/--insecure before each command runs
+	apiTLSConfig *tls.Config
+
+	// cliLogger is built from --log-level before each command runs
+	cliLogger *zap.Logger
 )
 
 // NewRootCommand creates and returns the root command
@@ -37,31 +52,78 @@ Configuration precedence: flags > environment variables > config file > defaults
 
 Environment Variables:
   SPECTRA_API_URL      API endpoint URL
+  SPECTRA_API_TIMEOUT  Request timeout (e.g. 30s, 2m)
+  SPECTRA_API_CA_CERT  PEM CA certificate to trust for an HTTPS API
   SPECTRA_CONFIG       Path to config file
   SPECTRA_OUTPUT_FORMAT Output format (json, yaml, table)
 
 For more information, visit: https://github.com/spectra-red/recon`,
+		// main reports the error itself, honoring --json-errors
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Keep stderr machine-readable when errors are JSON
+			if jsonErrors {
+				cmd.SilenceUsage = true
+			}
+
+			// CLI logs are human-readable and go to stderr
+			logger, err := logging.New(logLevel, logging.FormatConsole)
+			if err != nil {
+				return &InputError{Err: fmt.Errorf("invalid --log-level: %w", err)}
+			}
+			cliLogger = logger
+
 			// Initialize configuration
 			cfg, err := InitConfig(cfgFile)
 			if err != nil {
-				return fmt.Errorf("failed to load configuration: %w", err)
+				return &InputError{Err: fmt.Errorf("failed to load configuration: %w", err)}
 			}
 
 			// Override with flags if provided
 			if cmd.Flags().Changed("api-url") {
 				viper.Set("api.url", apiURL)
 			}
+			if cmd.Flags().Changed("timeout") {
+				d, err := parseTimeout(timeout)
+				if err != nil {
+					return &InputError{Err: err}
+				}
+				viper.Set("api.timeout", d)
+			}
+			if cmd.Flags().Changed("ca-cert") {
+				viper.Set("api.ca_cert", caCert)
+			}
+			if cmd.Flags().Changed("insecure") {
+				viper.Set("api.insecure", insecure)
+			}
+
+			// Load TLS settings once so a bad CA file fails before any request
+			tlsConfig, err := client.LoadTLSConfig(viper.GetString("api.ca_cert"), viper.GetBool("api.insecure"))
+			if err != nil {
+				return &InputError{Err: fmt.Errorf("invalid TLS configuration: %w", err)}
+			}
+			apiTLSConfig = tlsConfig
+			if viper.GetBool("api.insecure") {
+				fmt.Fprintln(cmd.ErrOrStderr(), "WARNING: TLS certificate verification is disabled (--insecure). "+
+					"Anyone on the network path can impersonate the API and read or alter your data.")
+			}
 
 			// Validate configuration
 			if err := ValidateConfig(cfg); err != nil {
-				return fmt.Errorf("invalid configuration: %w", err)
+				return &InputError{Err: fmt.Errorf("invalid configuration: %w", err)}
 			}
 
+			cliLogger.Debug("configuration loaded",
+				zap.String("config_file", viper.ConfigFileUsed()),
+				zap.String("api_url", GetAPIURL()),
+				zap.Duration("api_timeout", GetAPITimeout()),
+				zap.Bool("custom_tls", apiTLSConfig != nil))
+
 			// Set verbose mode
 			if verbose {
 				fmt.Fprintf(os.Stderr, "Config file: %s\n", viper.ConfigFileUsed())
 				fmt.Fprintf(os.Stderr, "API URL: %s\n", GetAPIURL())
+				fmt.Fprintf(os.Stderr, "API timeout: %s\n", GetAPITimeout())
 			}
 
 			return nil
@@ -71,7 +133,12 @@ For more information, visit: https://github.com/spectra-red/recon`,
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./.spectra.yaml, ~/.spectra/.spectra.yaml, or /etc/spectra/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "API endpoint URL (default: http://localhost:3000)")
+	rootCmd.PersistentFlags().StringVar(&timeout, "timeout", "", "request timeout, e.g. 45s or 2m (default: 30s)")
+	rootCmd.PersistentFlags().StringVar(&caCert, "ca-cert", "", "PEM CA certificate to trust for an HTTPS API endpoint")
+	rootCmd.PersistentFlags().BoolVar(&insecure, "insecure", false, "skip TLS certificate verification (unsafe; for testing only)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	AddErrorFlags(rootCmd)
 
 	// Bind flags to viper
 	viper.BindPFlag("api.url", rootCmd.PersistentFlags().Lookup("api-url"))
@@ -81,12 +148,35 @@ For more information, visit: https://github.com/spectra-red/recon`,
 	rootCmd.AddCommand(NewIngestCommand())
 	rootCmd.AddCommand(NewQueryCommand())
 	rootCmd.AddCommand(NewJobsCommand())
+	rootCmd.AddCommand(NewScannersCommand())
+	rootCmd.AddCommand(NewAdminCommand())
+	rootCmd.AddCommand(NewAPICommand())
+
+	markUsageErrors(rootCmd)
 
 	return rootCmd
 }
 
+// Logger returns the CLI's logger, or a no-op logger before the root command has run
+func Logger() *zap.Logger {
+	if cliLogger == nil {
+		return zap.NewNop()
+	}
+	return cliLogger
+}
+
+// parseTimeout parses the --timeout flag, which must be a positive duration
+func parseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --timeout %q: must be a positive duration such as 30s or 2m", value)
+	}
+	return d, nil
+}
+
 // Execute runs the root command
+// Pass the error to ExitCode for the process exit status.
 func Execute() error {
 	rootCmd := NewRootCommand()
-	return rootCmd.Execute()
+	return usageError(rootCmd.Execute())
 }
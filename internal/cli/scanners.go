@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spectra-red/recon/internal/client"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// NewScannersCommand creates the scanners command with subcommands
+func NewScannersCommand() *cobra.Command {
+	scannersCmd := &cobra.Command{
+		Use:   "scanners",
+		Short: "Inspect mesh contributors",
+		Long: `Inspect the scanners contributing to the mesh.
+
+Scanners are identified by the hex SHA-256 of their public key, so the key
+itself never has to be sent or shown.`,
+		Example: `  # Your own contribution stats, from the configured scanner key
+  spectra scanners stats
+
+  # Another scanner's stats
+  spectra scanners stats <public-key>`,
+	}
+
+	scannersCmd.AddCommand(NewScannersStatsCommand())
+
+	return scannersCmd
+}
+
+// NewScannersStatsCommand creates the scanners stats subcommand
+func NewScannersStatsCommand() *cobra.Command {
+	var noColor bool
+
+	cmd := &cobra.Command{
+		Use:   "stats [public-key | scanner-id]",
+		Short: "Show a scanner's contribution stats",
+		Long: `Show how many jobs a scanner submitted, how they ended, how many hosts and
+ports they covered, and when it last contributed.
+
+The scanner is given as its base64 public key, which is hashed locally before
+it is sent, or as the 64-character hex scanner ID shown by 'spectra admin
+audit'. Without an argument the configured scanner key is used.`,
+		Example: `  # Stats for the configured scanner key
+  spectra scanners stats
+
+  # Stats for a public key
+  spectra scanners stats <public-key>
+
+  # As JSON
+  spectra scanners stats --output json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			arg := ""
+			if len(args) > 0 {
+				arg = args[0]
+			}
+			scannerID, err := resolveScannerID(arg, configuredScannerKey)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), GetAPITimeout())
+			defer cancel()
+
+			apiClient := client.NewClient(GetAPIURL()).WithTimeout(GetAPITimeout()).WithTLSConfig(GetTLSConfig())
+			stats, err := apiClient.GetScannerStats(ctx, scannerID)
+			if err != nil {
+				return fmt.Errorf("failed to get scanner stats: %w", err)
+			}
+
+			outputOpts := NewOutputOptions(GetOutputFormat(), noColor)
+
+			switch outputOpts.Format {
+			case FormatJSON:
+				return writeJSON(outputOpts, stats)
+			case FormatYAML:
+				return writeYAML(outputOpts, stats)
+			case FormatTable:
+				return formatScannerStats(outputOpts, stats)
+			default:
+				return fmt.Errorf("unsupported output format: %s", outputOpts.Format)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+
+	return cmd
+}
+
+// resolveScannerID turns the stats argument into a scanner ID: a scanner ID
+// as is, a public key hashed, or, with no argument, the configured key hashed
+func resolveScannerID(arg string, ownKey func() (string, error)) (string, error) {
+	if arg == "" {
+		key, err := ownKey()
+		if err != nil {
+			return "", fmt.Errorf("no scanner given and no scanner key configured: %w", err)
+		}
+		return models.ScannerID(key), nil
+	}
+	if id, ok := models.NormalizeScannerID(arg); ok {
+		return id, nil
+	}
+	return models.ScannerID(arg), nil
+}
+
+// configuredScannerKey returns the public key ingest submits with: the
+// base64 encoding of the configured private key's public half
+func configuredScannerKey() (string, error) {
+	privKey, err := GetPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(privKey.Public().(ed25519.PublicKey)), nil
+}
+
+// formatScannerStats renders a scanner's stats as labelled lines
+func formatScannerStats(opts *OutputOptions, stats *models.ScannerStats) error {
+	if !opts.NoColor && opts.IsTerminal {
+		color.New(color.FgCyan, color.Bold).Fprintln(opts.Writer, "\nScanner Stats")
+	} else {
+		fmt.Fprintln(opts.Writer, "\nScanner Stats")
+	}
+	fmt.Fprintln(opts.Writer, "=============")
+	fmt.Fprintln(opts.Writer)
+
+	fmt.Fprintf(opts.Writer, "Scanner ID:   %s\n", stats.ScannerID)
+	fmt.Fprintf(opts.Writer, "Jobs:         %d (%d completed, %d failed, %d cancelled)\n", stats.Jobs, stats.Completed, stats.Failed, stats.Cancelled)
+	if stats.SuccessRate != nil {
+		fmt.Fprintf(opts.Writer, "Success rate: %.1f%%\n", *stats.SuccessRate*100)
+	} else {
+		fmt.Fprintln(opts.Writer, "Success rate: N/A")
+	}
+	fmt.Fprintf(opts.Writer, "Hosts:        %d\n", stats.Hosts)
+	fmt.Fprintf(opts.Writer, "Ports:        %d\n", stats.Ports)
+	if stats.FirstContributed != nil {
+		fmt.Fprintf(opts.Writer, "First:        %s\n", formatTime(*stats.FirstContributed))
+	}
+	if stats.LastContributed != nil {
+		fmt.Fprintf(opts.Writer, "Last:         %s\n", formatTime(*stats.LastContributed))
+	}
+
+	fmt.Fprintln(opts.Writer)
+	return nil
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScannersCommand(t *testing.T) {
+	cmd := NewScannersCommand()
+	assert.Equal(t, "scanners", cmd.Use)
+
+	stats, _, err := cmd.Find([]string{"stats"})
+	require.NoError(t, err)
+	assert.Contains(t, stats.Use, "stats")
+	assert.NotNil(t, stats.Flags().Lookup("no-color"))
+	assert.NoError(t, stats.Args(stats, []string{}))
+	assert.Error(t, stats.Args(stats, []string{"a", "b"}))
+}
+
+func TestResolveScannerID(t *testing.T) {
+	ownKey := func() (string, error) { return "own-key", nil }
+	noKey := func() (string, error) { return "", errors.New("scanner private key not configured") }
+
+	tests := []struct {
+		name    string
+		arg     string
+		ownKey  func() (string, error)
+		want    string
+		wantErr string
+	}{
+		{name: "public key is hashed", arg: "c29tZS1rZXk=", ownKey: noKey, want: models.ScannerID("c29tZS1rZXk=")},
+		{name: "scanner id is kept", arg: strings.ToUpper(models.ScannerID("x")), ownKey: noKey, want: models.ScannerID("x")},
+		{name: "configured key", ownKey: ownKey, want: models.ScannerID("own-key")},
+		{name: "nothing configured", ownKey: noKey, wantErr: "no scanner key configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveScannerID(tt.arg, tt.ownKey)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatScannerStats(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &OutputOptions{Format: FormatTable, NoColor: true, Writer: &buf}
+	rate := 0.75
+	last := time.Date(2026, 5, 2, 10, 30, 0, 0, time.UTC)
+
+	require.NoError(t, formatScannerStats(opts, &models.ScannerStats{
+		ScannerID: models.ScannerID("k"), Jobs: 5, Completed: 3, Failed: 1, Cancelled: 1,
+		Hosts: 40, Ports: 90, SuccessRate: &rate, LastContributed: &last,
+	}))
+
+	out := buf.String()
+	assert.Contains(t, out, "Jobs:         5 (3 completed, 1 failed, 1 cancelled)")
+	assert.Contains(t, out, "Success rate: 75.0%")
+	assert.Contains(t, out, "Hosts:        40")
+	assert.Contains(t, out, "Last:         2026-05-02 10:30")
+	assert.NotContains(t, out, "First:")
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// ListDeadLetters retrieves enrichment items that exhausted their retries
+// An empty stage lists all stages; a non-positive limit uses the server default
+func (c *Client) ListDeadLetters(ctx context.Context, stage string, limit int) (*models.DeadLetterListResponse, error) {
+	params := url.Values{}
+	if stage != "" {
+		params.Set("stage", stage)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	path := "/v1/admin/dead-letters"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var listResp models.DeadLetterListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to parse dead letter response: %w", err)
+	}
+
+	return &listResp, nil
+}
+
+// RequeueDeadLetter re-submits a dead-lettered IP for enrichment and clears its record
+func (c *Client) RequeueDeadLetter(ctx context.Context, stage, ip string) (*models.DeadLetter, error) {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/v1/admin/dead-letters/requeue", models.RequeueDeadLetterRequest{
+		IP:    ip,
+		Stage: stage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("no %s dead letter for %s", stage, ip)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var deadLetter models.DeadLetter
+	if err := json.Unmarshal(body, &deadLetter); err != nil {
+		return nil, fmt.Errorf("failed to parse requeue response: %w", err)
+	}
+
+	return &deadLetter, nil
+}
+
+// ListUnidentifiedServices retrieves the raw banners of services with no product or CPE,
+// most common first; a non-positive limit uses the server default
+func (c *Client) ListUnidentifiedServices(ctx context.Context, limit int) (*models.UnidentifiedServicesResponse, error) {
+	path := "/v1/admin/unidentified-services"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var listResp models.UnidentifiedServicesResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to parse unidentified services response: %w", err)
+	}
+
+	return &listResp, nil
+}
+
+// Reenrich queues the hosts or services matching req for enrichment again
+func (c *Client) Reenrich(ctx context.Context, req models.ReenrichRequest) (*models.ReenrichResponse, error) {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/v1/admin/reenrich", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var reenrichResp models.ReenrichResponse
+	if err := json.Unmarshal(body, &reenrichResp); err != nil {
+		return nil, fmt.Errorf("failed to parse re-enrichment response: %w", err)
+	}
+
+	return &reenrichResp, nil
+}
+
+// Migrate applies the graph schema migrations the database has not seen yet,
+// or with dryRun lists them without applying anything
+func (c *Client) Migrate(ctx context.Context, dryRun bool) (*models.MigrateResponse, error) {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/v1/admin/migrate", models.MigrateRequest{DryRun: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var migrateResp models.MigrateResponse
+	if err := json.Unmarshal(body, &migrateResp); err != nil {
+		return nil, fmt.Errorf("failed to parse migrate response: %w", err)
+	}
+
+	return &migrateResp, nil
+}
+
+// ListAudit retrieves audit entries matching filter, most recent first
+// The endpoint requires the admin token, set with WithAPIKey.
+func (c *Client) ListAudit(ctx context.Context, filter models.AuditFilter) (*models.AuditListResponse, error) {
+	params := url.Values{}
+	if !filter.Since.IsZero() {
+		params.Set("since", filter.Since.UTC().Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		params.Set("until", filter.Until.UTC().Format(time.RFC3339))
+	}
+	if filter.Actor != "" {
+		params.Set("actor", filter.Actor)
+	}
+	if filter.Action != "" {
+		params.Set("action", filter.Action)
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	path := "/v1/admin/audit"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var listResp models.AuditListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, fmt.Errorf("failed to parse audit response: %w", err)
+	}
+
+	return &listResp, nil
+}
+
+// ExportGraph streams a JSON lines export of tables (every graph table if
+// empty) to w and returns the number of records the server reported. An export
+// the server did not finish is an error, though w already holds what arrived.
+// The endpoint requires the admin token, set with WithAPIKey; a graph export
+// can take far longer than the default timeout, so callers usually disable it
+// with WithTimeout(0).
+func (c *Client) ExportGraph(ctx context.Context, tables []string, w io.Writer) (int, error) {
+	path := "/v1/admin/export"
+	if len(tables) > 0 {
+		path += "?" + url.Values{"tables": {strings.Join(tables, ",")}}.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, handleErrorResponse(resp)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return 0, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	// Trailers are only available once the body has been read to the end
+	if msg := resp.Trailer.Get(models.ExportErrorTrailer); msg != "" {
+		return 0, fmt.Errorf("export incomplete: %s", msg)
+	}
+	records, err := strconv.Atoi(resp.Trailer.Get(models.ExportRecordsTrailer))
+	if err != nil {
+		return 0, fmt.Errorf("export incomplete: server did not report a record count")
+	}
+	return records, nil
+}
+
+// ImportGraph uploads a JSON lines export read from r, streaming it as it is
+// read, and returns what the server imported and skipped. The endpoint requires
+// the admin token, set with WithAPIKey; like ExportGraph, callers usually
+// disable the timeout with WithTimeout(0).
+func (c *Client) ImportGraph(ctx context.Context, r io.Reader) (*models.ImportResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/admin/import", r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var importResp models.ImportResponse
+	if err := json.Unmarshal(body, &importResp); err != nil {
+		return nil, fmt.Errorf("failed to parse import response: %w", err)
+	}
+
+	return &importResp, nil
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/admin/dead-letters", r.URL.Path)
+		assert.Equal(t, "asn", r.URL.Query().Get("stage"))
+		assert.Equal(t, "10", r.URL.Query().Get("limit"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.DeadLetterListResponse{
+			DeadLetters: []models.DeadLetter{
+				{IP: "192.0.2.1", Stage: "asn", Reason: "no ASN data", Attempts: 2, LastAttempt: time.Now()},
+			},
+			Total: 1,
+			Limit: 10,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.ListDeadLetters(context.Background(), "asn", 10)
+
+	require.NoError(t, err)
+	require.Len(t, resp.DeadLetters, 1)
+	assert.Equal(t, "192.0.2.1", resp.DeadLetters[0].IP)
+	assert.Equal(t, 2, resp.DeadLetters[0].Attempts)
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverStatus int
+		wantErr      bool
+		errContains  string
+	}{
+		{"accepted", http.StatusAccepted, false, ""},
+		{"not found", http.StatusNotFound, true, "no asn dead letter for 192.0.2.1"},
+		{"restate unavailable", http.StatusServiceUnavailable, true, "HTTP 503"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/v1/admin/dead-letters/requeue", r.URL.Path)
+
+				var req models.RequeueDeadLetterRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "192.0.2.1", req.IP)
+				assert.Equal(t, "asn", req.Stage)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.serverStatus)
+				if tt.serverStatus == http.StatusAccepted {
+					_ = json.NewEncoder(w).Encode(models.DeadLetter{IP: req.IP, Stage: req.Stage, Attempts: 3})
+				}
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			deadLetter, err := client.RequeueDeadLetter(context.Background(), "asn", "192.0.2.1")
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 3, deadLetter.Attempts)
+		})
+	}
+}
+
+func TestListUnidentifiedServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/admin/unidentified-services", r.URL.Path)
+		assert.Equal(t, "5", r.URL.Query().Get("limit"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.UnidentifiedServicesResponse{
+			Results: []models.UnidentifiedBanner{
+				{Banner: "SSH-2.0-dropbear_2022.83", ServiceCount: 12},
+			},
+			Limit: 5,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.ListUnidentifiedServices(context.Background(), 5)
+
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, "SSH-2.0-dropbear_2022.83", resp.Results[0].Banner)
+	assert.Equal(t, 12, resp.Results[0].ServiceCount)
+}
+
+func TestReenrich(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/admin/reenrich", r.URL.Path)
+
+		var req models.ReenrichRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, 64500, req.ASN)
+		assert.True(t, req.MissingCPE)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(models.ReenrichResponse{ServicesQueued: 42, Batches: 1})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.Reenrich(context.Background(), models.ReenrichRequest{ASN: 64500, MissingCPE: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, 42, resp.ServicesQueued)
+	assert.Equal(t, 1, resp.Batches)
+}
+
+func TestMigrate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/admin/migrate", r.URL.Path)
+
+		var req models.MigrateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.DryRun)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.MigrateResponse{
+			CurrentVersion: 1,
+			LatestVersion:  2,
+			Applied:        []models.MigrationInfo{{Version: 2, Name: "affected_by_indexes"}},
+			DryRun:         true,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	resp, err := client.Migrate(context.Background(), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.CurrentVersion)
+	require.Len(t, resp.Applied, 1)
+	assert.Equal(t, 2, resp.Applied[0].Version)
+}
+
+func TestListAudit(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/admin/audit", r.URL.Path)
+		assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+		assert.Equal(t, "2026-01-01T00:00:00Z", r.URL.Query().Get("since"))
+		assert.Empty(t, r.URL.Query().Get("until"))
+		assert.Equal(t, "admin", r.URL.Query().Get("actor"))
+		assert.Equal(t, "redact", r.URL.Query().Get("action"))
+		assert.Equal(t, "20", r.URL.Query().Get("limit"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.AuditListResponse{
+			Entries: []models.AuditEntry{
+				{Action: models.AuditActionRedact, Actor: models.AuditActorAdmin, SourceIP: "192.0.2.10", HostCount: 2, CreatedAt: since},
+			},
+			Total: 1,
+			Limit: 20,
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL).WithAPIKey("s3cret")
+	resp, err := client.ListAudit(context.Background(), models.AuditFilter{
+		Since:  since,
+		Actor:  models.AuditActorAdmin,
+		Action: models.AuditActionRedact,
+		Limit:  20,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Entries, 1)
+	assert.Equal(t, "192.0.2.10", resp.Entries[0].SourceIP)
+	assert.Equal(t, 2, resp.Entries[0].HostCount)
+}
+
+func TestExportGraph(t *testing.T) {
+	const body = `{"table":"host","kind":"node","id":"host:a","data":{"ip":"192.0.2.1"}}
+{"table":"HAS","kind":"edge","id":"HAS:e1","in":"host:a","out":"port:port_22_tcp","data":{}}
+`
+
+	tests := []struct {
+		name    string
+		trailer map[string]string
+		want    int
+		wantErr string
+	}{
+		{name: "complete", trailer: map[string]string{models.ExportRecordsTrailer: "2"}, want: 2},
+		{name: "failed midway", trailer: map[string]string{models.ExportRecordsTrailer: "2", models.ExportErrorTrailer: "export failed after 2 records"}, wantErr: "export failed after 2 records"},
+		{name: "no trailer", wantErr: "did not report a record count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/admin/export", r.URL.Path)
+				assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+				assert.Equal(t, "host,HAS", r.URL.Query().Get("tables"))
+
+				w.Header().Set("Trailer", models.ExportRecordsTrailer+", "+models.ExportErrorTrailer)
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				_, _ = w.Write([]byte(body))
+				for key, value := range tt.trailer {
+					w.Header().Set(key, value)
+				}
+			}))
+			defer server.Close()
+
+			var out strings.Builder
+			client := NewClient(server.URL).WithAPIKey("s3cret")
+			records, err := client.ExportGraph(context.Background(), []string{"host", "HAS"}, &out)
+
+			assert.Equal(t, body, out.String(), "what arrived is written either way")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, records)
+		})
+	}
+}
+
+func TestExportGraphRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_request","message":"unknown table \"job\""}`))
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	_, err := NewClient(server.URL).ExportGraph(context.Background(), []string{"job"}, &out)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Empty(t, out.String())
+}
+
+func TestImportGraph(t *testing.T) {
+	const dump = `{"table":"host","kind":"node","id":"host:a","data":{"ip":"192.0.2.1"}}
+not json
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/admin/import", r.URL.Path)
+		assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, dump, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.ImportResponse{Nodes: 1, Skipped: 1, Errors: []string{"line 2: invalid JSON"}})
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(server.URL).WithAPIKey("s3cret").ImportGraph(context.Background(), strings.NewReader(dump))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Nodes)
+	assert.Equal(t, 1, resp.Skipped)
+	assert.Equal(t, []string{"line 2: invalid JSON"}, resp.Errors)
+}
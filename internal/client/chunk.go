@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultChunkBytes is the largest scan payload sent in a single envelope,
+// comfortably under the API's default 10MB ingest body limit
+const DefaultChunkBytes = 4 << 20
+
+// SplitScanData splits scan data into chunks of at most maxBytes each so a large
+// scan can be submitted as several signed envelopes.
+// Only a top-level JSON array can be split; its elements are regrouped into
+// smaller arrays. Anything else, or data already within maxBytes, is returned as
+// a single chunk. A lone element larger than maxBytes gets a chunk of its own.
+func SplitScanData(data []byte, maxBytes int) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(data)
+	if maxBytes <= 0 || len(trimmed) <= maxBytes || len(trimmed) == 0 || trimmed[0] != '[' {
+		return []json.RawMessage{json.RawMessage(data)}, nil
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(trimmed, &elements); err != nil {
+		return nil, fmt.Errorf("failed to parse scan data array: %w", err)
+	}
+
+	var chunks []json.RawMessage
+	var current bytes.Buffer
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		current.WriteByte(']')
+		chunks = append(chunks, json.RawMessage(append([]byte(nil), current.Bytes()...)))
+		current.Reset()
+	}
+
+	for _, element := range elements {
+		// +2 for the comma or opening bracket and the closing bracket
+		if current.Len() > 0 && current.Len()+len(element)+2 > maxBytes {
+			flush()
+		}
+		if current.Len() == 0 {
+			current.WriteByte('[')
+		} else {
+			current.WriteByte(',')
+		}
+		current.Write(element)
+	}
+	flush()
+
+	if len(chunks) == 0 {
+		return []json.RawMessage{json.RawMessage(data)}, nil
+	}
+	return chunks, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// naabuArray builds a JSON array of n Naabu results
+func naabuArray(n int) []byte {
+	entries := make([]string, n)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"host":"192.0.2.%d","port":443,"protocol":"tcp"}`, i%250+1)
+	}
+	return []byte("[" + strings.Join(entries, ",") + "]")
+}
+
+func TestSplitScanData_SmallDataIsOneChunk(t *testing.T) {
+	data := naabuArray(3)
+
+	chunks, err := SplitScanData(data, DefaultChunkBytes)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, json.RawMessage(data), chunks[0])
+}
+
+func TestSplitScanData_SplitsArray(t *testing.T) {
+	data := naabuArray(100)
+
+	chunks, err := SplitScanData(data, 1000)
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 1)
+
+	total := 0
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len(chunk), 1000)
+
+		var entries []map[string]interface{}
+		require.NoError(t, json.Unmarshal(chunk, &entries), "each chunk must be a valid JSON array")
+		total += len(entries)
+	}
+	assert.Equal(t, 100, total, "no entries lost or duplicated")
+}
+
+func TestSplitScanData_ObjectIsNotSplit(t *testing.T) {
+	data := []byte(`{"hosts":[{"ip":"192.0.2.1"},{"ip":"192.0.2.2"}]}`)
+
+	chunks, err := SplitScanData(data, 10)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, json.RawMessage(data), chunks[0])
+}
+
+func TestSplitScanData_OversizedElementGetsOwnChunk(t *testing.T) {
+	big := `{"host":"192.0.2.1","banner":"` + strings.Repeat("x", 200) + `"}`
+	data := []byte(`[{"host":"192.0.2.2"},` + big + `,{"host":"192.0.2.3"}]`)
+
+	chunks, err := SplitScanData(data, 100)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "["+big+"]", string(chunks[1]))
+}
@@ -88,8 +88,8 @@ func handleErrorResponse(resp *http.Response) error {
 
 	var errResp ErrorResponse
 	if err := json.Unmarshal(body, &errResp); err != nil {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	return fmt.Errorf("API error (%s): %s", errResp.Error, errResp.Message)
+	return &APIError{StatusCode: resp.StatusCode, ErrorCode: errResp.Error, Message: errResp.Message}
 }
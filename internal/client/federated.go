@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+const (
+	// DefaultFederationTimeout bounds the forwarding of one query to all peers
+	DefaultFederationTimeout = 5 * time.Second
+
+	// LocalSource tags results answered by the local mesh
+	LocalSource = "local"
+)
+
+// Peer is a trusted upstream mesh that queries may be forwarded to
+type Peer struct {
+	Name   string // tags results from this peer; defaults to URL
+	URL    string
+	APIKey string // sent as a bearer token; may be empty
+}
+
+// FederationConfig configures a FederatedQueryClient
+// A zero Timeout uses DefaultFederationTimeout, and a nil TLSConfig the
+// default transport.
+type FederationConfig struct {
+	Peers     []Peer
+	Timeout   time.Duration
+	TLSConfig *tls.Config // used to verify the peers' certificates
+}
+
+// peerClient is a query client for one peer
+type peerClient struct {
+	name   string
+	client *QueryClient
+}
+
+// FederatedQueryClient runs host and graph queries against the local API and,
+// when the local mesh has no data, forwards them to the configured peers.
+// A host query is forwarded when the local API answers 404 and a graph query
+// when it matches no hosts at all. Peers are asked concurrently within the
+// federation timeout; peers that fail or time out are left out of the answer.
+// Every result is tagged with the mesh that returned it.
+type FederatedQueryClient struct {
+	local   *QueryClient
+	peers   []peerClient
+	timeout time.Duration
+}
+
+// NewFederatedQueryClient creates a client that queries local first and then
+// the peers in config
+func NewFederatedQueryClient(local *QueryClient, config FederationConfig) *FederatedQueryClient {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultFederationTimeout
+	}
+
+	peers := make([]peerClient, 0, len(config.Peers))
+	for _, peer := range config.Peers {
+		name := peer.Name
+		if name == "" {
+			name = peer.URL
+		}
+		peers = append(peers, peerClient{
+			name:   name,
+			client: NewQueryClientWithTimeout(peer.URL, config.Timeout).WithAPIKey(peer.APIKey).WithTLSConfig(config.TLSConfig),
+		})
+	}
+
+	return &FederatedQueryClient{
+		local:   local,
+		peers:   peers,
+		timeout: config.Timeout,
+	}
+}
+
+// QueryHost queries host information locally, falling back to the first peer
+// (in configured order) that knows the host. The local 404 is returned if no
+// peer does.
+func (c *FederatedQueryClient) QueryHost(ctx context.Context, ip string, depth int) (*models.HostQueryResponse, error) {
+	result, err := c.local.QueryHost(ctx, ip, depth)
+	if err == nil {
+		result.Source = LocalSource
+		return result, nil
+	}
+	if !isNotFound(err) {
+		return nil, err
+	}
+
+	answers := make([]*models.HostQueryResponse, len(c.peers))
+	c.forward(ctx, func(ctx context.Context, i int, peer peerClient) {
+		if answer, err := peer.client.QueryHost(ctx, ip, depth); err == nil {
+			answer.Source = peer.name
+			answers[i] = answer
+		}
+	})
+
+	for _, answer := range answers {
+		if answer != nil {
+			return answer, nil
+		}
+	}
+	return nil, err
+}
+
+// GraphQuery executes a graph query locally and, if the local mesh matches no
+// hosts, merges the peers' results. An empty page past the end of local matches
+// is returned as is. A host returned by more than one peer is kept once, from
+// the peer listed first; the merged results are capped at the request's limit.
+func (c *FederatedQueryClient) GraphQuery(ctx context.Context, req *models.GraphQueryRequest) (*models.GraphQueryResponse, error) {
+	result, err := c.local.GraphQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Results) > 0 || result.Pagination.Total > 0 {
+		for i := range result.Results {
+			result.Results[i].Source = LocalSource
+		}
+		return result, nil
+	}
+
+	answers := make([][]models.HostResult, len(c.peers))
+	c.forward(ctx, func(ctx context.Context, i int, peer peerClient) {
+		if answer, err := peer.client.GraphQuery(ctx, req); err == nil {
+			answers[i] = answer.Results
+		}
+	})
+
+	seen := make(map[string]bool)
+	merged := make([]models.HostResult, 0)
+	for i, hosts := range answers {
+		for _, host := range hosts {
+			if seen[host.IP] {
+				continue
+			}
+			seen[host.IP] = true
+			host.Source = c.peers[i].name
+			merged = append(merged, host)
+		}
+	}
+	if req.Limit > 0 && len(merged) > req.Limit {
+		merged = merged[:req.Limit]
+	}
+
+	result.Results = merged
+	result.Pagination.Total = len(merged)
+	result.Pagination.HasMore = false
+	result.Pagination.NextOffset = 0
+	return result, nil
+}
+
+// forward calls query for every peer concurrently and waits for them all,
+// bounding the calls by the federation timeout
+func (c *FederatedQueryClient) forward(ctx context.Context, query func(ctx context.Context, i int, peer peerClient)) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, peer := range c.peers {
+		wg.Add(1)
+		go func(i int, peer peerClient) {
+			defer wg.Done()
+			query(ctx, i, peer)
+		}(i, peer)
+	}
+	wg.Wait()
+}
+
+// isNotFound reports whether err is an API 404
+func isNotFound(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusNotFound
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// meshServer is a mock API that answers host and graph queries from fixed data
+type meshServer struct {
+	*httptest.Server
+	hosts    map[string]models.HostQueryResponse
+	graph    []models.HostResult
+	apiKey   string        // required bearer token, if set
+	delay    time.Duration // added before every answer
+	requests atomic.Int32
+}
+
+func newMeshServer(t *testing.T, hosts map[string]models.HostQueryResponse, graph []models.HostResult) *meshServer {
+	m := &meshServer{hosts: hosts, graph: graph}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *meshServer) serve(w http.ResponseWriter, r *http.Request) {
+	m.requests.Add(1)
+	if m.delay > 0 {
+		time.Sleep(m.delay)
+	}
+	if m.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+m.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/v1/query/graph":
+		var req models.GraphQueryRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		page := []models.HostResult{}
+		if req.Offset < len(m.graph) {
+			page = append(page, m.graph[req.Offset:]...)
+		}
+		json.NewEncoder(w).Encode(models.GraphQueryResponse{
+			Results:    page,
+			Pagination: models.PaginationMetadata{Offset: req.Offset, Total: len(m.graph)},
+		})
+	default:
+		ip := r.URL.Path[len("/v1/query/host/"):]
+		host, ok := m.hosts[ip]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "not_found", Message: "host not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(host)
+	}
+}
+
+func TestFederatedQueryClient_QueryHost(t *testing.T) {
+	local := newMeshServer(t, map[string]models.HostQueryResponse{"192.0.2.1": {IP: "192.0.2.1", ASN: 1}}, nil)
+	peerA := newMeshServer(t, nil, nil)
+	peerB := newMeshServer(t, map[string]models.HostQueryResponse{
+		"192.0.2.1": {IP: "192.0.2.1", ASN: 2},
+		"192.0.2.9": {IP: "192.0.2.9", ASN: 64500},
+	}, nil)
+	peerB.apiKey = "peer-b-key"
+
+	c := NewFederatedQueryClient(NewQueryClient(local.URL), FederationConfig{
+		Peers: []Peer{
+			{Name: "peer-a", URL: peerA.URL},
+			{Name: "peer-b", URL: peerB.URL, APIKey: "peer-b-key"},
+		},
+		Timeout: time.Second,
+	})
+
+	t.Run("local hit is not forwarded", func(t *testing.T) {
+		result, err := c.QueryHost(context.Background(), "192.0.2.1", 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.ASN)
+		assert.Equal(t, LocalSource, result.Source)
+		assert.Zero(t, peerA.requests.Load())
+		assert.Zero(t, peerB.requests.Load())
+	})
+
+	t.Run("local miss is forwarded", func(t *testing.T) {
+		result, err := c.QueryHost(context.Background(), "192.0.2.9", 0)
+		require.NoError(t, err)
+		assert.Equal(t, 64500, result.ASN)
+		assert.Equal(t, "peer-b", result.Source)
+		assert.Equal(t, int32(1), peerA.requests.Load())
+	})
+
+	t.Run("miss everywhere returns the local 404", func(t *testing.T) {
+		_, err := c.QueryHost(context.Background(), "192.0.2.200", 0)
+		require.Error(t, err)
+		assert.True(t, isNotFound(err), err.Error())
+	})
+}
+
+func TestFederatedQueryClient_GraphQuery(t *testing.T) {
+	local := newMeshServer(t, nil, nil)
+	peerA := newMeshServer(t, nil, []models.HostResult{{IP: "192.0.2.1", ASN: 1}, {IP: "192.0.2.2", ASN: 1}})
+	peerB := newMeshServer(t, nil, []models.HostResult{{IP: "192.0.2.2", ASN: 2}, {IP: "192.0.2.3", ASN: 2}})
+
+	c := NewFederatedQueryClient(NewQueryClient(local.URL), FederationConfig{
+		Peers: []Peer{{Name: "peer-a", URL: peerA.URL}, {Name: "peer-b", URL: peerB.URL}},
+	})
+
+	result, err := c.GraphQuery(context.Background(), GraphQueryByASN(1, 100, 0))
+	require.NoError(t, err)
+	require.Len(t, result.Results, 3)
+
+	sources := map[string]string{}
+	for _, host := range result.Results {
+		sources[host.IP] = host.Source
+	}
+	assert.Equal(t, map[string]string{
+		"192.0.2.1": "peer-a",
+		"192.0.2.2": "peer-a", // returned by both; the first peer wins
+		"192.0.2.3": "peer-b",
+	}, sources)
+	assert.Equal(t, 3, result.Pagination.Total)
+	assert.False(t, result.Pagination.HasMore)
+
+	t.Run("limit caps merged results", func(t *testing.T) {
+		result, err := c.GraphQuery(context.Background(), GraphQueryByASN(1, 2, 0))
+		require.NoError(t, err)
+		assert.Len(t, result.Results, 2)
+	})
+}
+
+func TestFederatedQueryClient_GraphQueryLocalResults(t *testing.T) {
+	local := newMeshServer(t, nil, []models.HostResult{{IP: "192.0.2.1"}})
+	peer := newMeshServer(t, nil, []models.HostResult{{IP: "192.0.2.2"}})
+
+	c := NewFederatedQueryClient(NewQueryClient(local.URL), FederationConfig{Peers: []Peer{{URL: peer.URL}}})
+
+	result, err := c.GraphQuery(context.Background(), GraphQueryByASN(1, 100, 0))
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, LocalSource, result.Results[0].Source)
+	assert.Zero(t, peer.requests.Load())
+}
+
+func TestFederatedQueryClient_GraphQueryPastLocalEnd(t *testing.T) {
+	local := newMeshServer(t, nil, []models.HostResult{{IP: "192.0.2.1"}})
+	peer := newMeshServer(t, nil, []models.HostResult{{IP: "192.0.2.2"}})
+
+	c := NewFederatedQueryClient(NewQueryClient(local.URL), FederationConfig{Peers: []Peer{{URL: peer.URL}}})
+
+	// The local mesh has matches, just none at this offset
+	result, err := c.GraphQuery(context.Background(), GraphQueryByASN(1, 100, 10))
+	require.NoError(t, err)
+	assert.Empty(t, result.Results)
+	assert.Equal(t, 1, result.Pagination.Total)
+	assert.Zero(t, peer.requests.Load())
+}
+
+func TestFederatedQueryClient_PeerTLS(t *testing.T) {
+	local := newMeshServer(t, nil, nil)
+	peer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GraphQueryResponse{Results: []models.HostResult{{IP: "192.0.2.2"}}})
+	}))
+	t.Cleanup(peer.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(peer.Certificate())
+
+	c := NewFederatedQueryClient(NewQueryClient(local.URL), FederationConfig{
+		Peers:     []Peer{{Name: "tls", URL: peer.URL}},
+		TLSConfig: &tls.Config{RootCAs: pool},
+	})
+
+	result, err := c.GraphQuery(context.Background(), GraphQueryByASN(1, 100, 0))
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1, "the peer's certificate is verified with the configured CA")
+	assert.Equal(t, "tls", result.Results[0].Source)
+}
+
+func TestFederatedQueryClient_SlowPeerIsSkipped(t *testing.T) {
+	local := newMeshServer(t, nil, nil)
+	slow := newMeshServer(t, map[string]models.HostQueryResponse{"192.0.2.9": {IP: "192.0.2.9", ASN: 1}}, nil)
+	slow.delay = 500 * time.Millisecond
+	fast := newMeshServer(t, map[string]models.HostQueryResponse{"192.0.2.9": {IP: "192.0.2.9", ASN: 2}}, nil)
+
+	c := NewFederatedQueryClient(NewQueryClient(local.URL), FederationConfig{
+		Peers:   []Peer{{Name: "slow", URL: slow.URL}, {Name: "fast", URL: fast.URL}},
+		Timeout: 50 * time.Millisecond,
+	})
+
+	start := time.Now()
+	result, err := c.QueryHost(context.Background(), "192.0.2.9", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "fast", result.Source)
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+}
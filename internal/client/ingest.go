@@ -3,24 +3,43 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/spectra-red/recon/internal/auth"
 )
 
 // IngestClient handles API requests to the /v1/mesh/ingest endpoint
 type IngestClient struct {
 	baseURL    string
 	httpClient *http.Client
+
+	// skipSelfCheck sends envelopes without verifying them first
+	skipSelfCheck bool
 }
 
 // IngestRequest represents the request body for submitting scans
 type IngestRequest struct {
+	Version   int             `json:"version"`
+	Algorithm string          `json:"algorithm,omitempty"`
 	Data      json.RawMessage `json:"data"`
 	PublicKey string          `json:"public_key"`
 	Signature string          `json:"signature"`
 	Timestamp int64           `json:"timestamp"`
+
+	// CallbackURL is notified when the job finishes; it is not covered by the signature
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// Source names the tool that produced the scan, e.g. naabu; it is not covered by
+	// the signature
+	Source string `json:"source,omitempty"`
+
+	// SchemaVersion declares the format of Data (naabu/v1 or structured/v1) for the
+	// server to validate it against; it is not covered by the signature
+	SchemaVersion string `json:"schema_version,omitempty"`
 }
 
 // IngestResponse represents the response from the ingest endpoint
@@ -48,8 +67,24 @@ func NewIngestClient(baseURL string, timeoutSeconds int) *IngestClient {
 	}
 }
 
+// SkipSelfCheck makes Submit send envelopes without verifying them first
+// Only useful for testing how the server handles malformed submissions.
+func (c *IngestClient) SkipSelfCheck() *IngestClient {
+	c.skipSelfCheck = true
+	return c
+}
+
 // Submit submits scan results to the mesh
+// The envelope is verified against its own public key before it is sent, so a
+// bad signature or timestamp fails locally with a *SelfCheckError instead of
+// an opaque 401 from the server.
 func (c *IngestClient) Submit(req IngestRequest) (*IngestResponse, error) {
+	if !c.skipSelfCheck {
+		if err := selfCheck(req); err != nil {
+			return nil, err
+		}
+	}
+
 	// Marshal request body
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -99,6 +134,39 @@ func (c *IngestClient) Submit(req IngestRequest) (*IngestResponse, error) {
 	return &resp, nil
 }
 
+// selfCheck verifies the envelope the way the server will
+func selfCheck(req IngestRequest) error {
+	err := auth.VerifyEnvelope(auth.ScanEnvelope{
+		Version:   req.Version,
+		Algorithm: req.Algorithm,
+		Data:      req.Data,
+		PublicKey: req.PublicKey,
+		Signature: req.Signature,
+		Timestamp: req.Timestamp,
+	})
+	if err != nil {
+		return &SelfCheckError{Err: err}
+	}
+	return nil
+}
+
+// SelfCheckError reports an envelope that failed verification before it was sent
+type SelfCheckError struct {
+	Err error
+}
+
+func (e *SelfCheckError) Error() string {
+	msg := fmt.Sprintf("envelope failed local verification and was not submitted: %v", e.Err)
+	if errors.Is(e.Err, auth.ErrExpiredTimestamp) {
+		msg += " (check the system clock)"
+	}
+	return msg
+}
+
+func (e *SelfCheckError) Unwrap() error {
+	return e.Err
+}
+
 // HTTPError represents an HTTP error response
 type HTTPError struct {
 	StatusCode int
@@ -154,6 +222,11 @@ func (c *IngestClient) SubmitWithRetry(req IngestRequest, maxRetries int) (*Inge
 
 // isClientError checks if an error is a client error (4xx)
 func isClientError(err error) bool {
+	// An envelope that fails the self-check would be rejected on every attempt
+	if _, ok := err.(*SelfCheckError); ok {
+		return true
+	}
+
 	// Check if it's an APIError with 4xx status code
 	if apiErr, ok := err.(*APIError); ok {
 		return apiErr.IsClientError()
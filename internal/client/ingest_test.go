@@ -4,12 +4,12 @@ import (
 	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/spectra-red/recon/internal/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -66,11 +66,11 @@ func TestIngestClient_Submit_Success(t *testing.T) {
 
 	scanData := []byte(`{"hosts":[{"ip":"1.2.3.4"}]}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
-	signature := ed25519.Sign(privKey, message)
+	signature := ed25519.Sign(privKey, auth.SigningMessage(timestamp, scanData))
 
 	// Submit request
 	req := IngestRequest{
+		Version:   auth.EnvelopeVersion,
 		Data:      json.RawMessage(scanData),
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -101,7 +101,7 @@ func TestIngestClient_Submit_InvalidSignature(t *testing.T) {
 	defer server.Close()
 
 	// Create client
-	client := NewIngestClient(server.URL, 10)
+	client := NewIngestClient(server.URL, 10).SkipSelfCheck()
 
 	// Create request with invalid signature
 	req := IngestRequest{
@@ -131,7 +131,7 @@ func TestIngestClient_Submit_ServerError(t *testing.T) {
 	defer server.Close()
 
 	// Create client
-	client := NewIngestClient(server.URL, 10)
+	client := NewIngestClient(server.URL, 10).SkipSelfCheck()
 
 	// Create request
 	req := IngestRequest{
@@ -157,7 +157,7 @@ func TestIngestClient_Submit_MalformedResponse(t *testing.T) {
 	defer server.Close()
 
 	// Create client
-	client := NewIngestClient(server.URL, 10)
+	client := NewIngestClient(server.URL, 10).SkipSelfCheck()
 
 	// Create request
 	req := IngestRequest{
@@ -175,7 +175,7 @@ func TestIngestClient_Submit_MalformedResponse(t *testing.T) {
 
 func TestIngestClient_Submit_NetworkError(t *testing.T) {
 	// Create client with invalid URL
-	client := NewIngestClient("http://localhost:99999", 1)
+	client := NewIngestClient("http://localhost:99999", 1).SkipSelfCheck()
 
 	// Create request
 	req := IngestRequest{
@@ -224,10 +224,10 @@ func TestIngestClient_SubmitWithRetry_Success(t *testing.T) {
 
 	scanData := []byte(`{"hosts":[{"ip":"1.2.3.4"}]}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
-	signature := ed25519.Sign(privKey, message)
+	signature := ed25519.Sign(privKey, auth.SigningMessage(timestamp, scanData))
 
 	req := IngestRequest{
+		Version:   auth.EnvelopeVersion,
 		Data:      json.RawMessage(scanData),
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -258,7 +258,7 @@ func TestIngestClient_SubmitWithRetry_NoRetryOn4xx(t *testing.T) {
 	defer server.Close()
 
 	// Create client
-	client := NewIngestClient(server.URL, 10)
+	client := NewIngestClient(server.URL, 10).SkipSelfCheck()
 
 	// Create request
 	req := IngestRequest{
@@ -286,7 +286,7 @@ func TestIngestClient_SubmitWithRetry_MaxRetriesExceeded(t *testing.T) {
 	defer server.Close()
 
 	// Create client
-	client := NewIngestClient(server.URL, 1)
+	client := NewIngestClient(server.URL, 1).SkipSelfCheck()
 
 	// Create request
 	req := IngestRequest{
@@ -305,6 +305,113 @@ func TestIngestClient_SubmitWithRetry_MaxRetriesExceeded(t *testing.T) {
 	assert.Equal(t, 3, attemptCount)
 }
 
+// signedTestRequest builds a correctly signed envelope around data
+func signedTestRequest(t *testing.T, data []byte, timestamp int64) IngestRequest {
+	t.Helper()
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	return IngestRequest{
+		Version:   auth.EnvelopeVersion,
+		Data:      json.RawMessage(data),
+		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, auth.SigningMessage(timestamp, data))),
+		Timestamp: timestamp,
+	}
+}
+
+func TestIngestClient_Submit_SelfCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		mutate      func(req *IngestRequest)
+		errContains string
+	}{
+		{
+			name:   "good envelope",
+			mutate: func(req *IngestRequest) {},
+		},
+		{
+			name: "tampered data",
+			mutate: func(req *IngestRequest) {
+				req.Data = json.RawMessage(`{"hosts":[{"ip":"5.6.7.8"}]}`)
+			},
+			errContains: "invalid signature",
+		},
+		{
+			name: "stale timestamp",
+			mutate: func(req *IngestRequest) {
+				req.Timestamp -= 3600
+			},
+			errContains: "check the system clock",
+		},
+		{
+			name: "missing version",
+			mutate: func(req *IngestRequest) {
+				req.Version = 0
+			},
+			errContains: "unsupported envelope version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(IngestResponse{JobID: "job_abc123"})
+			}))
+			defer server.Close()
+
+			req := signedTestRequest(t, []byte(`{"hosts":[{"ip":"1.2.3.4"}]}`), time.Now().Unix())
+			tt.mutate(&req)
+
+			resp, err := NewIngestClient(server.URL, 10).Submit(req)
+
+			if tt.errContains == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "job_abc123", resp.JobID)
+				assert.Equal(t, 1, requests)
+				return
+			}
+
+			require.Error(t, err)
+			var selfCheckErr *SelfCheckError
+			assert.ErrorAs(t, err, &selfCheckErr)
+			assert.Contains(t, err.Error(), tt.errContains)
+			assert.Zero(t, requests, "a failed self-check must not reach the server")
+		})
+	}
+}
+
+func TestIngestClient_SkipSelfCheck(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(IngestErrorResponse{Error: "invalid_signature"})
+	}))
+	defer server.Close()
+
+	req := signedTestRequest(t, []byte(`{"test":"data"}`), time.Now().Unix())
+	req.Signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+
+	_, err := NewIngestClient(server.URL, 10).SkipSelfCheck().Submit(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_signature")
+	assert.Equal(t, 1, requests)
+}
+
+func TestIngestClient_SubmitWithRetry_NoRetryOnSelfCheck(t *testing.T) {
+	req := signedTestRequest(t, []byte(`{"test":"data"}`), time.Now().Unix())
+	req.Data = json.RawMessage(`{"test":"tampered"}`)
+
+	// The server is never reached, so an unroutable URL is fine
+	_, err := NewIngestClient("http://localhost:99999", 1).SubmitWithRetry(req, 3)
+	var selfCheckErr *SelfCheckError
+	assert.ErrorAs(t, err, &selfCheckErr)
+}
+
 // Benchmark tests
 func BenchmarkIngestClient_Submit(b *testing.B) {
 	// Create a mock server
@@ -329,10 +436,10 @@ func BenchmarkIngestClient_Submit(b *testing.B) {
 
 	scanData := []byte(`{"hosts":[{"ip":"1.2.3.4"}]}`)
 	timestamp := time.Now().Unix()
-	message := append([]byte(fmt.Sprintf("%d", timestamp)), scanData...)
-	signature := ed25519.Sign(privKey, message)
+	signature := ed25519.Sign(privKey, auth.SigningMessage(timestamp, scanData))
 
 	req := IngestRequest{
+		Version:   auth.EnvelopeVersion,
 		Data:      json.RawMessage(scanData),
 		PublicKey: base64.StdEncoding.EncodeToString(pubKey),
 		Signature: base64.StdEncoding.EncodeToString(signature),
@@ -21,7 +21,7 @@ func (c *Client) GetJob(ctx context.Context, jobID string) (*models.Job, error)
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("job not found: %s", jobID)
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: fmt.Sprintf("job not found: %s", jobID)}
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spectra-red/recon/internal/models"
@@ -15,6 +18,7 @@ import (
 // QueryClient handles API queries to the Spectra-Red backend
 type QueryClient struct {
 	baseURL    string
+	apiKey     string
 	httpClient *http.Client
 }
 
@@ -38,6 +42,25 @@ func NewQueryClientWithTimeout(baseURL string, timeout time.Duration) *QueryClie
 	}
 }
 
+// WithAPIKey sends apiKey as a bearer token with every request
+func (c *QueryClient) WithAPIKey(apiKey string) *QueryClient {
+	c.apiKey = apiKey
+	return c
+}
+
+// do sends req, adding the API key if one is set
+func (c *QueryClient) do(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return c.httpClient.Do(req)
+}
+
+// Timeout returns the HTTP timeout applied to each request
+func (c *QueryClient) Timeout() time.Duration {
+	return c.httpClient.Timeout
+}
+
 // QueryHost queries host information by IP address
 func (c *QueryClient) QueryHost(ctx context.Context, ip string, depth int) (*models.HostQueryResponse, error) {
 	url := fmt.Sprintf("%s/v1/query/host/%s?depth=%d", c.baseURL, ip, depth)
@@ -49,7 +72,7 @@ func (c *QueryClient) QueryHost(ctx context.Context, ip string, depth int) (*mod
 
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -57,7 +80,7 @@ func (c *QueryClient) QueryHost(ctx context.Context, ip string, depth int) (*mod
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result models.HostQueryResponse
@@ -91,7 +114,7 @@ func (c *QueryClient) GraphQuery(ctx context.Context, req *models.GraphQueryRequ
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -99,7 +122,7 @@ func (c *QueryClient) GraphQuery(ctx context.Context, req *models.GraphQueryRequ
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
 	var result models.GraphQueryResponse
@@ -110,6 +133,46 @@ func (c *QueryClient) GraphQuery(ctx context.Context, req *models.GraphQueryRequ
 	return &result, nil
 }
 
+// Diff compares two scan snapshots of a network
+func (c *QueryClient) Diff(ctx context.Context, req *models.DiffRequest) (*models.DiffResponse, error) {
+	url := fmt.Sprintf("%s/v1/query/diff", c.baseURL)
+
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var result models.DiffResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
 // SimilarQuery performs a vector similarity search
 func (c *QueryClient) SimilarQuery(ctx context.Context, req *models.SimilarRequest) (*models.SimilarResponse, error) {
 	url := fmt.Sprintf("%s/v1/query/similar", c.baseURL)
@@ -133,7 +196,7 @@ func (c *QueryClient) SimilarQuery(ctx context.Context, req *models.SimilarReque
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -141,7 +204,7 @@ func (c *QueryClient) SimilarQuery(ctx context.Context, req *models.SimilarReque
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
 
 	var result models.SimilarResponse
@@ -152,6 +215,87 @@ func (c *QueryClient) SimilarQuery(ctx context.Context, req *models.SimilarReque
 	return &result, nil
 }
 
+// GetVuln fetches the full detail of a single CVE
+func (c *QueryClient) GetVuln(ctx context.Context, cve string) (*models.VulnDetailResponse, error) {
+	url := fmt.Sprintf("%s/v1/vuln/%s", c.baseURL, cve)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: fmt.Sprintf("vulnerability not found: %s", cve)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result models.VulnDetailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// Exposure fetches the exposure report: public hosts grouped by the risky ports they
+// expose. Empty ports uses the server's risky-port set and a non-positive limit its
+// default number of hosts listed per port.
+func (c *QueryClient) Exposure(ctx context.Context, ports []int, limit int) (*models.ExposureResponse, error) {
+	params := url.Values{}
+	if len(ports) > 0 {
+		numbers := make([]string, len(ports))
+		for i, port := range ports {
+			numbers[i] = strconv.Itoa(port)
+		}
+		params.Set("ports", strings.Join(numbers, ","))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	endpoint := c.baseURL + "/v1/query/exposure"
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result models.ExposureResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
 // HostQueryOptions contains options for host queries
 type HostQueryOptions struct {
 	IP    string
@@ -201,6 +345,15 @@ func GraphQueryByService(product, service string, limit, offset int) *models.Gra
 	}
 }
 
+// GraphQueryByKEV creates a graph query for hosts affected by KEV-listed CVEs
+func GraphQueryByKEV(limit, offset int) *models.GraphQueryRequest {
+	return &models.GraphQueryRequest{
+		QueryType: models.QueryByKEV,
+		Limit:     limit,
+		Offset:    offset,
+	}
+}
+
 // NewSimilarRequest creates a similarity search request
 func NewSimilarRequest(query string, k int) *models.SimilarRequest {
 	if k <= 0 {
@@ -95,6 +95,140 @@ func TestQueryHost_NotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "404")
 }
 
+func TestGetVuln_Success(t *testing.T) {
+	mockResponse := &models.VulnDetailResponse{
+		CVEID:         "CVE-2021-44228",
+		Summary:       "Apache Log4j2 JNDI features do not protect against attacker controlled LDAP endpoints.",
+		CVSS:          10.0,
+		Severity:      "CRITICAL",
+		EPSS:          0.97,
+		KEVFlag:       true,
+		AffectedHosts: 3,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/vuln/CVE-2021-44228", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(mockResponse)
+	}))
+	defer server.Close()
+
+	client := NewQueryClient(server.URL)
+	result, err := client.GetVuln(context.Background(), "CVE-2021-44228")
+
+	require.NoError(t, err)
+	assert.Equal(t, "CVE-2021-44228", result.CVEID)
+	assert.True(t, result.KEVFlag)
+	assert.Equal(t, 0.97, result.EPSS)
+	assert.Equal(t, 3, result.AffectedHosts)
+}
+
+func TestGetVuln_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not_found"}`))
+	}))
+	defer server.Close()
+
+	client := NewQueryClient(server.URL)
+	result, err := client.GetVuln(context.Background(), "CVE-2020-0001")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "vulnerability not found")
+}
+
+func TestExposure(t *testing.T) {
+	mockResponse := &models.ExposureResponse{
+		Results: []models.ExposureGroup{
+			{Port: 22, Service: "ssh", HostCount: 2, Hosts: []string{"198.51.100.4", "203.0.113.5"}},
+		},
+		Ports: []int{22, 3389},
+		Limit: 50,
+	}
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/query/exposure", r.URL.Path)
+		gotQuery = r.URL.RawQuery
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(mockResponse)
+	}))
+	defer server.Close()
+
+	client := NewQueryClient(server.URL)
+
+	result, err := client.Exposure(context.Background(), []int{22, 3389}, 50)
+	require.NoError(t, err)
+	assert.Equal(t, "limit=50&ports=22%2C3389", gotQuery)
+	assert.Equal(t, mockResponse, result)
+
+	// The server's defaults apply when nothing is given
+	_, err = client.Exposure(context.Background(), nil, 0)
+	require.NoError(t, err)
+	assert.Empty(t, gotQuery)
+}
+
+func TestExposure_BadRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Bad Request","message":"ports must be a comma-separated list of port numbers"}`))
+	}))
+	defer server.Close()
+
+	client := NewQueryClient(server.URL)
+	result, err := client.Exposure(context.Background(), []int{0}, 0)
+
+	assert.Nil(t, result)
+	var httpErr *HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+}
+
+func TestDiff(t *testing.T) {
+	from := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	mockResponse := &models.DiffResponse{
+		CIDR:    "192.0.2.0/24",
+		From:    from,
+		To:      to,
+		Summary: models.DiffSummary{HostsRemoved: 1, PortsRemoved: 1},
+		Added:   []models.HostDiff{},
+		Removed: []models.HostDiff{{IP: "192.0.2.9", RemovedPorts: []models.DiffPort{{Number: 22, Protocol: "tcp"}}}},
+		Changed: []models.HostDiff{},
+		Limit:   100,
+	}
+
+	var got models.DiffRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/query/diff", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(mockResponse)
+	}))
+	defer server.Close()
+
+	client := NewQueryClient(server.URL)
+
+	result, err := client.Diff(context.Background(), &models.DiffRequest{CIDR: "192.0.2.0/24", From: from, To: to})
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.0/24", got.CIDR)
+	assert.Equal(t, to, got.To)
+	assert.Equal(t, mockResponse, result)
+
+	// Invalid requests never reach the server
+	_, err = client.Diff(context.Background(), &models.DiffRequest{CIDR: "192.0.2.0/24", From: to, To: from})
+	assert.ErrorIs(t, err, models.ErrInvalidDiffRange)
+}
+
 func TestGraphQuery_ByASN(t *testing.T) {
 	asn := 15169
 	mockResponse := &models.GraphQueryResponse{
@@ -393,6 +527,14 @@ func TestGraphQueryHelpers(t *testing.T) {
 		assert.Equal(t, 50, req.Offset)
 	})
 
+	t.Run("GraphQueryByKEV", func(t *testing.T) {
+		req := GraphQueryByKEV(100, 0)
+		assert.Equal(t, models.QueryByKEV, req.QueryType)
+		assert.Empty(t, req.CVE)
+		assert.Equal(t, 100, req.Limit)
+		assert.Equal(t, 0, req.Offset)
+	})
+
 	t.Run("GraphQueryByService", func(t *testing.T) {
 		req := GraphQueryByService("nginx", "http", 75, 25)
 		assert.Equal(t, models.QueryByService, req.QueryType)
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// GetScannerStats retrieves the contribution stats of the scanner with the
+// given ScannerID (the hex SHA-256 of its public key)
+func (c *Client) GetScannerStats(ctx context.Context, scannerID string) (*models.ScannerStats, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/v1/scanners/"+scannerID+"/stats", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: fmt.Sprintf("no jobs from scanner %s", scannerID)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, handleErrorResponse(resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var stats models.ScannerStats
+	if err := json.Unmarshal(body, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse scanner stats response: %w", err)
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetScannerStats(t *testing.T) {
+	scannerID := models.ScannerID("scanner-key-abc")
+	rate := 0.75
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/scanners/"+scannerID+"/stats" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(models.ScannerStats{ScannerID: scannerID, Jobs: 4, Completed: 3, Failed: 1, Hosts: 12, SuccessRate: &rate})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	stats, err := client.GetScannerStats(context.Background(), scannerID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Jobs)
+	assert.Equal(t, 12, stats.Hosts)
+	assert.Equal(t, 0.75, *stats.SuccessRate)
+
+	_, err = client.GetScannerStats(context.Background(), models.ScannerID("unknown"))
+	assert.ErrorContains(t, err, "no jobs from scanner")
+}
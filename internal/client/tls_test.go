@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSHostServer starts an HTTPS server with a self-signed certificate and
+// returns it with the path of a PEM file holding that certificate
+func newTLSHostServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.HostQueryResponse{IP: "192.0.2.1"})
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0600))
+
+	return server, caFile
+}
+
+func TestQueryClient_TLSVerification(t *testing.T) {
+	server, caFile := newTLSHostServer(t)
+
+	t.Run("default rejects self-signed certificate", func(t *testing.T) {
+		_, err := NewQueryClient(server.URL).QueryHost(context.Background(), "192.0.2.1", 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate")
+	})
+
+	t.Run("custom CA", func(t *testing.T) {
+		tlsConfig, err := LoadTLSConfig(caFile, false)
+		require.NoError(t, err)
+		assert.False(t, tlsConfig.InsecureSkipVerify)
+
+		result, err := NewQueryClient(server.URL).WithTLSConfig(tlsConfig).QueryHost(context.Background(), "192.0.2.1", 0)
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.1", result.IP)
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		tlsConfig, err := LoadTLSConfig("", true)
+		require.NoError(t, err)
+
+		result, err := NewQueryClient(server.URL).WithTLSConfig(tlsConfig).QueryHost(context.Background(), "192.0.2.1", 0)
+		require.NoError(t, err)
+		assert.Equal(t, "192.0.2.1", result.IP)
+	})
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	tlsConfig, err := LoadTLSConfig("", false)
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig, "secure by default: no custom TLS config")
+
+	_, err = LoadTLSConfig(filepath.Join(t.TempDir(), "missing.pem"), false)
+	assert.ErrorContains(t, err, "failed to read CA certificate")
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+	_, err = LoadTLSConfig(notPEM, false)
+	assert.ErrorContains(t, err, "no PEM certificates found")
+}
+
+func TestWithTLSConfig_NilKeepsDefaultTransport(t *testing.T) {
+	assert.Nil(t, NewClient("https://api.example.com").WithTLSConfig(nil).httpClient.Transport)
+	assert.Nil(t, NewIngestClient("https://api.example.com", 30).WithTLSConfig(nil).httpClient.Transport)
+}
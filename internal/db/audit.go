@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// AuditLogger writes and reads the audit trail of ingests and admin actions
+// Entries are only ever created; nothing here updates or deletes them.
+type AuditLogger struct {
+	store  AuditStore
+	logger *zap.Logger
+}
+
+// AuditStore runs the audit trail's statements
+type AuditStore interface {
+	// CreateEntry creates an audit record from content
+	CreateEntry(ctx context.Context, content map[string]interface{}) error
+	// SelectEntries runs an audit listing built by buildAuditQuery
+	SelectEntries(ctx context.Context, query string, params map[string]interface{}) ([]models.AuditEntry, error)
+}
+
+// NewAuditLogger creates an audit logger on the database
+func NewAuditLogger(db *surrealdb.DB, logger *zap.Logger) *AuditLogger {
+	return NewAuditLoggerWithStore(&surrealAuditStore{db: db}, logger)
+}
+
+// NewAuditLoggerWithStore creates an audit logger on a custom store (useful for testing)
+func NewAuditLoggerWithStore(store AuditStore, logger *zap.Logger) *AuditLogger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &AuditLogger{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Record appends an entry to the audit trail, stamped with the database's time
+// Callers hash scanner keys with models.ScannerActor before recording them.
+func (a *AuditLogger) Record(ctx context.Context, entry models.AuditEntry) error {
+	// Unset optional fields are left out rather than written as NULL
+	content := map[string]interface{}{
+		"action": entry.Action,
+		"actor":  entry.Actor,
+	}
+	if entry.SourceIP != "" {
+		content["source_ip"] = entry.SourceIP
+	}
+	if entry.JobID != "" {
+		content["job_id"] = entry.JobID
+	}
+	if entry.HostCount > 0 {
+		content["host_count"] = entry.HostCount
+	}
+	if len(entry.Details) > 0 {
+		content["details"] = entry.Details
+	}
+
+	if err := a.store.CreateEntry(ctx, content); err != nil {
+		a.logger.Error("failed to record audit entry",
+			zap.Error(err),
+			zap.String("action", entry.Action))
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns audit entries matching filter, most recent first
+func (a *AuditLogger) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query, params := buildAuditQuery(filter)
+
+	entries, err := a.store.SelectEntries(ctx, query, params)
+	if err != nil {
+		a.logger.Error("failed to list audit entries",
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// buildAuditQuery builds the SELECT for an audit listing and its parameters
+func buildAuditQuery(filter models.AuditFilter) (string, map[string]interface{}) {
+	limit := filter.Limit
+	if limit < 1 {
+		limit = models.DefaultAuditLimit
+	}
+
+	var conditions []string
+	params := map[string]interface{}{
+		"limit": limit,
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= $since")
+		params["since"] = filter.Since.UTC()
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at < $until")
+		params["until"] = filter.Until.UTC()
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = $actor")
+		params["actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = $action")
+		params["action"] = filter.Action
+	}
+
+	query := `SELECT action, actor, source_ip, job_id, host_count, details, created_at FROM audit`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC LIMIT $limit;`
+
+	return query, params
+}
+
+// surrealAuditStore keeps the audit trail in SurrealDB
+type surrealAuditStore struct {
+	db *surrealdb.DB
+}
+
+// CreateEntry creates the record, stamped with the database's time
+func (s *surrealAuditStore) CreateEntry(ctx context.Context, content map[string]interface{}) error {
+	result, err := surrealdb.Query[interface{}](ctx, s.db, `CREATE audit CONTENT $content;`, map[string]interface{}{
+		"content": content,
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil && len(*result) > 0 && (*result)[0].Error != nil {
+		return fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+	return nil
+}
+
+// SelectEntries runs the listing and surfaces its per-statement error
+func (s *surrealAuditStore) SelectEntries(ctx context.Context, query string, params map[string]interface{}) ([]models.AuditEntry, error) {
+	result, err := surrealdb.Query[[]models.AuditEntry](ctx, s.db, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.AuditEntry, 0)
+	if result != nil && len(*result) > 0 {
+		if (*result)[0].Error != nil {
+			return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+		}
+		entries = append(entries, (*result)[0].Result...)
+	}
+	return entries, nil
+}
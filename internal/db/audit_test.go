@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditStore records created entries instead of writing them
+type fakeAuditStore struct {
+	created []map[string]interface{}
+	err     error
+}
+
+func (s *fakeAuditStore) CreateEntry(ctx context.Context, content map[string]interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.created = append(s.created, content)
+	return nil
+}
+
+func (s *fakeAuditStore) SelectEntries(ctx context.Context, query string, params map[string]interface{}) ([]models.AuditEntry, error) {
+	return nil, s.err
+}
+
+func TestAuditLogger_Record(t *testing.T) {
+	store := &fakeAuditStore{}
+	a := NewAuditLoggerWithStore(store, nil)
+
+	err := a.Record(context.Background(), models.AuditEntry{
+		Action:    models.AuditActionIngest,
+		Actor:     models.ScannerActor("scanner-key"),
+		SourceIP:  "192.0.2.10",
+		JobID:     "job-1",
+		HostCount: 3,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, store.created, 1)
+	assert.Equal(t, map[string]interface{}{
+		"action":     "ingest",
+		"actor":      models.ScannerActor("scanner-key"),
+		"source_ip":  "192.0.2.10",
+		"job_id":     "job-1",
+		"host_count": 3,
+	}, store.created[0], "unset details are left out")
+}
+
+func TestAuditLogger_RecordError(t *testing.T) {
+	a := NewAuditLoggerWithStore(&fakeAuditStore{err: errors.New("connection refused")}, nil)
+
+	err := a.Record(context.Background(), models.AuditEntry{Action: models.AuditActionRedact, Actor: models.AuditActorAdmin})
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestBuildAuditQuery(t *testing.T) {
+	query, params := buildAuditQuery(models.AuditFilter{})
+	assert.NotContains(t, query, "WHERE")
+	assert.Equal(t, models.DefaultAuditLimit, params["limit"])
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	query, params = buildAuditQuery(models.AuditFilter{
+		Since:  since,
+		Until:  until,
+		Actor:  models.AuditActorAdmin,
+		Action: models.AuditActionRedact,
+		Limit:  10,
+	})
+	assert.Contains(t, query, "WHERE created_at >= $since AND created_at < $until AND actor = $actor AND action = $action")
+	assert.Contains(t, query, "ORDER BY created_at DESC")
+	assert.Equal(t, since, params["since"])
+	assert.Equal(t, until, params["until"])
+	assert.Equal(t, "admin", params["actor"])
+	assert.Equal(t, "redact", params["action"])
+	assert.Equal(t, 10, params["limit"])
+}
+
+func TestScannerActorHashesKey(t *testing.T) {
+	actor := models.ScannerActor("scanner-key")
+	assert.True(t, strings.HasPrefix(actor, "scanner:"), actor)
+	assert.NotContains(t, actor, "scanner-key")
+	assert.Len(t, actor, len("scanner:")+64)
+	assert.Equal(t, actor, models.ScannerActor("scanner-key"), "the same key always maps to the same actor")
+	assert.NotEqual(t, actor, models.ScannerActor("other-key"))
+}
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// DefaultBatchSize is the number of queued statements sent per round-trip
+const DefaultBatchSize = 100
+
+// BatchExecutor accumulates parameterized statements and sends them to
+// SurrealDB as multi-statement queries, one round-trip per batch instead of
+// one per statement. Each queued statement may itself contain several
+// SurrealQL statements (e.g. LET followed by CREATE); its parameters are
+// renamed so statements sharing a batch cannot see each other's values.
+// A BatchExecutor is not safe for concurrent use.
+type BatchExecutor struct {
+	db        *surrealdb.DB
+	batchSize int
+	logger    *zap.Logger
+	pending   []batchStatement
+}
+
+// batchStatement is a queued statement with its own parameters
+type batchStatement struct {
+	query  string
+	params map[string]interface{}
+}
+
+// NewBatchExecutor creates a batch executor that sends batchSize statements
+// per query (DefaultBatchSize if non-positive)
+func NewBatchExecutor(db *surrealdb.DB, batchSize int, logger *zap.Logger) *BatchExecutor {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &BatchExecutor{
+		db:        db,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Add queues a statement and returns its index in the slice Flush returns
+func (b *BatchExecutor) Add(query string, params map[string]interface{}) int {
+	b.pending = append(b.pending, batchStatement{query: query, params: params})
+	return len(b.pending) - 1
+}
+
+// Len returns the number of queued statements
+func (b *BatchExecutor) Len() int {
+	return len(b.pending)
+}
+
+// Flush sends every queued statement and clears the queue
+// It returns one error per statement in Add order, nil where the statement
+// succeeded. A failed round-trip fails every statement in that batch; a failed
+// statement does not stop the others, since batches do not run in a transaction.
+func (b *BatchExecutor) Flush(ctx context.Context) []error {
+	pending := b.pending
+	b.pending = nil
+
+	errs := make([]error, len(pending))
+	for start := 0; start < len(pending); start += b.batchSize {
+		end := start + b.batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		b.execute(ctx, pending[start:end], errs[start:end])
+	}
+	return errs
+}
+
+// execute sends one batch and records each statement's outcome in errs
+func (b *BatchExecutor) execute(ctx context.Context, batch []batchStatement, errs []error) {
+	var sql strings.Builder
+	vars := make(map[string]interface{})
+	counts := make([]int, len(batch))
+
+	for i, stmt := range batch {
+		query := stmt.query
+		prefix := fmt.Sprintf("b%d_", i)
+		// LET variables live for the whole query; scope them along with the
+		// parameters so a failed LET cannot leave a later statement reading
+		// an earlier one's value
+		for _, match := range letPattern.FindAllStringSubmatch(stmt.query, -1) {
+			if _, isParam := stmt.params[match[1]]; !isParam {
+				query = renameParam(query, match[1], prefix+match[1])
+			}
+		}
+		for _, name := range paramNames(stmt.params) {
+			query = renameParam(query, name, prefix+name)
+			vars[prefix+name] = stmt.params[name]
+		}
+
+		statements := splitStatements(query)
+		counts[i] = len(statements)
+		for _, s := range statements {
+			sql.WriteString(s)
+			sql.WriteString(";\n")
+		}
+	}
+
+	results, err := surrealdb.Query[interface{}](ctx, b.db, sql.String(), vars)
+	if results == nil {
+		// The batch never ran (transport or decoding failure)
+		if err == nil {
+			err = fmt.Errorf("batch query returned no results")
+		}
+		b.logger.Error("batch query failed",
+			zap.Int("statements", len(batch)),
+			zap.Error(err))
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if len(*results) != total {
+		err := fmt.Errorf("batch query returned %d results for %d statements", len(*results), total)
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+
+	// Attribute each SurrealQL result back to the statement that queued it
+	offset := 0
+	for i, n := range counts {
+		for _, res := range (*results)[offset : offset+n] {
+			if res.Error != nil {
+				errs[i] = res.Error
+				break
+			}
+		}
+		offset += n
+	}
+}
+
+// letPattern matches a LET statement's variable name
+var letPattern = regexp.MustCompile(`(?i)\bLET\s+\$(\w+)`)
+
+// paramNames returns the parameter names in a stable order
+func paramNames(params map[string]interface{}) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renameParam rewrites every $from reference in query to $to
+func renameParam(query, from, to string) string {
+	re := regexp.MustCompile(`\$` + regexp.QuoteMeta(from) + `\b`)
+	return re.ReplaceAllLiteralString(query, "$"+to)
+}
+
+// splitStatements splits a SurrealQL query on the semicolons that end its
+// statements, ignoring semicolons inside strings, record IDs and comments
+func splitStatements(query string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			current.WriteString(string(runes[i:min(end+1, len(runes))]))
+			i = end
+		case r == '⟨':
+			end := i + 1
+			for end < len(runes) && runes[end] != '⟩' {
+				end++
+			}
+			current.WriteString(string(runes[i:min(end+1, len(runes))]))
+			i = end
+		case r == '#' || ((r == '-' || r == '/') && i+1 < len(runes) && runes[i+1] == r):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			current.WriteRune(' ')
+		case r == ';':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return statements
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"github.com/surrealdb/surrealdb.go/pkg/connection"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/gorillaws"
+)
+
+// queryConn answers query requests with one result per SurrealQL statement,
+// failing THROW statements the way SurrealDB does
+type queryConn struct {
+	// Connection supplies GetUnmarshaler; it is never connected
+	connection.Connection
+
+	marshal    func(any) ([]byte, error)
+	sendErr    error
+	roundTrips atomic.Int64
+
+	mu   sync.Mutex
+	sql  []string
+	vars []map[string]any
+}
+
+func (q *queryConn) Connect(ctx context.Context) error { return nil }
+
+func (q *queryConn) Close(ctx context.Context) error { return nil }
+
+func (q *queryConn) Send(ctx context.Context, method string, params ...any) (*connection.RPCResponse[cbor.RawMessage], error) {
+	q.roundTrips.Add(1)
+	if q.sendErr != nil {
+		return nil, q.sendErr
+	}
+
+	sql := params[0].(string)
+	vars, _ := params[1].(map[string]any)
+	q.mu.Lock()
+	q.sql = append(q.sql, sql)
+	q.vars = append(q.vars, vars)
+	q.mu.Unlock()
+
+	var results []map[string]any
+	for _, stmt := range splitStatements(sql) {
+		if strings.HasPrefix(stmt, "THROW") {
+			results = append(results, map[string]any{"status": "ERR", "time": "1ms", "result": "An error occurred: boom"})
+			continue
+		}
+		results = append(results, map[string]any{"status": "OK", "time": "1ms", "result": nil})
+	}
+
+	data, err := q.marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	raw := cbor.RawMessage(data)
+	return &connection.RPCResponse[cbor.RawMessage]{Result: &raw}, nil
+}
+
+func newQueryConn(tb testing.TB) (*queryConn, *surrealdb.DB) {
+	dialer := newFakeDialer(tb)
+	conn := &queryConn{
+		Connection: gorillaws.New(dialer.conf),
+		marshal:    dialer.conf.Marshaler.Marshal,
+	}
+	client, err := surrealdb.FromConnection(context.Background(), conn)
+	require.NoError(tb, err)
+	return conn, client
+}
+
+const upsertHost = `
+	LET $host_id = type::thing('host', $ip);
+	UPSERT $host_id SET ip = $ip;
+`
+
+func TestBatchExecutor_ReportsPartialFailures(t *testing.T) {
+	conn, client := newQueryConn(t)
+	batch := NewBatchExecutor(client, 2, nil)
+
+	first := batch.Add(upsertHost, map[string]interface{}{"ip": "192.0.2.1"})
+	failing := batch.Add(`THROW $reason;`, map[string]interface{}{"reason": "boom"})
+	last := batch.Add(upsertHost, map[string]interface{}{"ip": "192.0.2.3"})
+	assert.Equal(t, 3, batch.Len())
+
+	errs := batch.Flush(context.Background())
+	require.Len(t, errs, 3)
+	assert.NoError(t, errs[first])
+	assert.ErrorContains(t, errs[failing], "boom")
+	assert.NoError(t, errs[last], "a failed statement must not fail the rest of its batch")
+
+	assert.Equal(t, int64(2), conn.roundTrips.Load(), "three statements at batch size two")
+	assert.Equal(t, 0, batch.Len(), "flush clears the queue")
+
+	// Parameters and LET variables are scoped per statement
+	assert.Equal(t, map[string]any{"b0_ip": "192.0.2.1", "b1_reason": "boom"}, conn.vars[0])
+	assert.Contains(t, conn.sql[0], "LET $b0_host_id = type::thing('host', $b0_ip)")
+	assert.Contains(t, conn.sql[0], "UPSERT $b0_host_id SET ip = $b0_ip")
+	assert.Contains(t, conn.sql[0], "THROW $b1_reason")
+}
+
+func TestBatchExecutor_RoundTripFailureFailsBatch(t *testing.T) {
+	conn, client := newQueryConn(t)
+	conn.sendErr = errors.New("connection reset")
+	batch := NewBatchExecutor(client, 0, nil)
+
+	batch.Add(upsertHost, map[string]interface{}{"ip": "192.0.2.1"})
+	batch.Add(upsertHost, map[string]interface{}{"ip": "192.0.2.2"})
+
+	errs := batch.Flush(context.Background())
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.ErrorContains(t, err, "connection reset")
+	}
+}
+
+func TestBatchExecutor_FlushEmpty(t *testing.T) {
+	conn, client := newQueryConn(t)
+	batch := NewBatchExecutor(client, 10, nil)
+
+	assert.Empty(t, batch.Flush(context.Background()))
+	assert.Zero(t, conn.roundTrips.Load())
+}
+
+func TestSplitStatements(t *testing.T) {
+	query := `
+		LET $id = type::thing('host', 'a;b'); -- trailing; comment
+		/* block; comment */ CREATE $id SET note = "x;y", tag = ⟨semi;colon⟩;
+		// line; comment
+	`
+	statements := splitStatements(query)
+	require.Len(t, statements, 2)
+	assert.Equal(t, "LET $id = type::thing('host', 'a;b')", statements[0])
+	assert.Contains(t, statements[1], `CREATE $id SET note = "x;y", tag = ⟨semi;colon⟩`)
+}
+
+func TestRenameParam(t *testing.T) {
+	assert.Equal(t, "$b0_id, $id_suffix, $b0_id",
+		renameParam("$id, $id_suffix, $id", "id", "b0_id"))
+}
+
+// BenchmarkBatchExecutor compares one query per statement with batched writes;
+// round-trips/op is what dominates against a remote SurrealDB
+func BenchmarkBatchExecutor(b *testing.B) {
+	const statements = 250
+
+	b.Run("per_statement", func(b *testing.B) {
+		conn, client := newQueryConn(b)
+		ctx := context.Background()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for n := 0; n < statements; n++ {
+				surrealdb.Query[interface{}](ctx, client, upsertHost, map[string]interface{}{
+					"ip": fmt.Sprintf("192.0.2.%d", n),
+				})
+			}
+		}
+		b.ReportMetric(float64(conn.roundTrips.Load())/float64(b.N), "round-trips/op")
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		conn, client := newQueryConn(b)
+		ctx := context.Background()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			batch := NewBatchExecutor(client, DefaultBatchSize, nil)
+			for n := 0; n < statements; n++ {
+				batch.Add(upsertHost, map[string]interface{}{
+					"ip": fmt.Sprintf("192.0.2.%d", n),
+				})
+			}
+			batch.Flush(ctx)
+		}
+		b.ReportMetric(float64(conn.roundTrips.Load())/float64(b.N), "round-trips/op")
+	})
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spectra-red/recon/internal/schedule"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// Defaults for AFFECTED_BY confidence decay
+const (
+	// DefaultDecayInterval is how often stale edges are decayed
+	DefaultDecayInterval = 24 * time.Hour
+	// DefaultDecayStaleAfter is how long an edge may go unconfirmed before it decays
+	DefaultDecayStaleAfter = 30 * 24 * time.Hour
+	// DefaultDecayFactor scales a stale edge's confidence on each run
+	DefaultDecayFactor = 0.9
+	// DefaultConfidenceFloor is the confidence below which edges are pruned
+	DefaultConfidenceFloor = 0.2
+)
+
+// DecayConfig configures a ConfidenceDecayer
+// Zero or out-of-range fields use the defaults above.
+type DecayConfig struct {
+	StaleAfter time.Duration
+	Factor     float64 // in (0, 1)
+	Floor      float64 // in (0, 1)
+}
+
+// DecayResult reports what a decay run changed
+type DecayResult struct {
+	Decayed int // edges whose confidence was lowered
+	Pruned  int // edges deleted for falling below the floor
+}
+
+// ConfidenceDecayer lowers the confidence of AFFECTED_BY edges that have not been
+// re-confirmed by CPE enrichment recently, and prunes edges that fall below a floor.
+// Each run multiplies a stale edge's confidence by the decay factor, so an edge
+// keeps losing confidence until enrichment confirms it again or it is pruned.
+type ConfidenceDecayer struct {
+	store  DecayStore
+	logger *zap.Logger
+	config DecayConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewConfidenceDecayer creates a decayer with the default configuration
+func NewConfidenceDecayer(db *surrealdb.DB, logger *zap.Logger) *ConfidenceDecayer {
+	return NewConfidenceDecayerWithConfig(db, logger, DecayConfig{})
+}
+
+// DecayStore runs the statements of a decay run
+type DecayStore interface {
+	// Exec runs a statement and returns how many records it touched
+	Exec(ctx context.Context, query string, vars map[string]interface{}) (int, error)
+}
+
+// NewConfidenceDecayerWithConfig creates a decayer with the given configuration
+func NewConfidenceDecayerWithConfig(db *surrealdb.DB, logger *zap.Logger, config DecayConfig) *ConfidenceDecayer {
+	return NewConfidenceDecayerWithStore(&surrealDecayStore{db: db}, logger, config)
+}
+
+// NewConfidenceDecayerWithStore creates a decayer on a custom store (useful for testing)
+func NewConfidenceDecayerWithStore(store DecayStore, logger *zap.Logger, config DecayConfig) *ConfidenceDecayer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = DefaultDecayStaleAfter
+	}
+	if config.Factor <= 0 || config.Factor >= 1 {
+		config.Factor = DefaultDecayFactor
+	}
+	if config.Floor <= 0 || config.Floor >= 1 {
+		config.Floor = DefaultConfidenceFloor
+	}
+
+	return &ConfidenceDecayer{
+		store:  store,
+		logger: logger,
+		config: config,
+	}
+}
+
+// Config returns the decayer's effective configuration
+func (d *ConfidenceDecayer) Config() DecayConfig {
+	return d.config
+}
+
+// Decay lowers the confidence of edges last confirmed before now minus StaleAfter,
+// then deletes every edge whose confidence is below the floor
+func (d *ConfidenceDecayer) Decay(ctx context.Context, now time.Time) (DecayResult, error) {
+	var result DecayResult
+
+	decayed, err := d.store.Exec(ctx, `
+		UPDATE AFFECTED_BY SET confidence = confidence * $factor
+		WHERE last_confirmed < $cutoff
+		RETURN id;
+	`, map[string]interface{}{
+		"factor": d.config.Factor,
+		"cutoff": now.Add(-d.config.StaleAfter).UTC(),
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to decay AFFECTED_BY confidence: %w", err)
+	}
+	result.Decayed = decayed
+
+	pruned, err := d.store.Exec(ctx, `
+		DELETE AFFECTED_BY WHERE confidence < $floor RETURN BEFORE;
+	`, map[string]interface{}{
+		"floor": d.config.Floor,
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to prune AFFECTED_BY edges: %w", err)
+	}
+	result.Pruned = pruned
+
+	return result, nil
+}
+
+// Start runs Decay every interval until Stop is called
+// A non-positive interval uses DefaultDecayInterval.
+func (d *ConfidenceDecayer) Start(interval time.Duration) {
+	d.StartWithJitter(interval, 0)
+}
+
+// StartWithJitter runs Decay about every interval, spreading each run by up to
+// jitter (a fraction of interval) either side so replicas don't decay in lockstep
+func (d *ConfidenceDecayer) StartWithJitter(interval time.Duration, jitter float64) {
+	if interval <= 0 {
+		interval = DefaultDecayInterval
+	}
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.loop(interval, jitter)
+}
+
+// Stop ends the decay loop and waits for a run in progress to finish
+func (d *ConfidenceDecayer) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+}
+
+// loop decays edges on every tick
+func (d *ConfidenceDecayer) loop(interval time.Duration, jitter float64) {
+	defer close(d.done)
+
+	ticker := schedule.NewTicker(interval, jitter)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			result, err := d.Decay(context.Background(), time.Now())
+			if err != nil {
+				d.logger.Warn("AFFECTED_BY confidence decay failed",
+					zap.Error(err))
+				continue
+			}
+			d.logger.Info("decayed AFFECTED_BY confidence",
+				zap.Int("decayed", result.Decayed),
+				zap.Int("pruned", result.Pruned))
+		}
+	}
+}
+
+// surrealDecayStore runs decay statements against SurrealDB
+type surrealDecayStore struct {
+	db *surrealdb.DB
+}
+
+// Exec runs a statement that returns the records it touched
+func (s *surrealDecayStore) Exec(ctx context.Context, query string, vars map[string]interface{}) (int, error) {
+	result, err := surrealdb.Query[[]map[string]interface{}](ctx, s.db, query, vars)
+	if err != nil {
+		return 0, err
+	}
+	if result == nil || len(*result) == 0 {
+		return 0, nil
+	}
+	return len((*result)[0].Result), nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decayCall records one statement issued by a decay run
+type decayCall struct {
+	query string
+	vars  map[string]interface{}
+}
+
+// fakeDecayStore records statements and answers each with the next of affected
+type fakeDecayStore struct {
+	affected []int
+	err      error
+	calls    []decayCall
+	ran      chan struct{} // receives once per statement when set
+}
+
+func (s *fakeDecayStore) Exec(ctx context.Context, query string, vars map[string]interface{}) (int, error) {
+	s.calls = append(s.calls, decayCall{query: query, vars: vars})
+	if s.ran != nil {
+		s.ran <- struct{}{}
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	if len(s.calls) > len(s.affected) {
+		return 0, nil
+	}
+	return s.affected[len(s.calls)-1], nil
+}
+
+func TestNewConfidenceDecayer_Defaults(t *testing.T) {
+	decayer := NewConfidenceDecayerWithConfig(nil, nil, DecayConfig{Factor: 1.5, Floor: -1})
+	assert.Equal(t, DecayConfig{
+		StaleAfter: DefaultDecayStaleAfter,
+		Factor:     DefaultDecayFactor,
+		Floor:      DefaultConfidenceFloor,
+	}, decayer.Config())
+}
+
+func TestConfidenceDecayer_Decay(t *testing.T) {
+	store := &fakeDecayStore{affected: []int{4, 1}}
+	decayer := NewConfidenceDecayerWithStore(store, nil, DecayConfig{
+		StaleAfter: 7 * 24 * time.Hour,
+		Factor:     0.5,
+		Floor:      0.3,
+	})
+
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	result, err := decayer.Decay(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, DecayResult{Decayed: 4, Pruned: 1}, result)
+
+	calls := store.calls
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[0].query, "UPDATE AFFECTED_BY")
+	assert.Equal(t, 0.5, calls[0].vars["factor"])
+	assert.Equal(t, now.Add(-7*24*time.Hour), calls[0].vars["cutoff"], "only edges unconfirmed for StaleAfter decay")
+
+	assert.True(t, strings.Contains(calls[1].query, "DELETE AFFECTED_BY"), "decay should prune after lowering confidence")
+	assert.Equal(t, 0.3, calls[1].vars["floor"])
+}
+
+func TestConfidenceDecayer_DecayError(t *testing.T) {
+	store := &fakeDecayStore{err: errors.New("connection reset")}
+	decayer := NewConfidenceDecayerWithStore(store, nil, DecayConfig{})
+
+	_, err := decayer.Decay(context.Background(), time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decay AFFECTED_BY confidence")
+	assert.Len(t, store.calls, 1, "edges are not pruned when the decay step fails")
+}
+
+func TestConfidenceDecayer_StartStop(t *testing.T) {
+	ran := make(chan struct{}, 10)
+	decayer := NewConfidenceDecayerWithStore(&fakeDecayStore{ran: ran}, nil, DecayConfig{})
+
+	decayer.Start(10 * time.Millisecond)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("decay loop never ran")
+	}
+	decayer.Stop()
+	decayer.Stop()
+}
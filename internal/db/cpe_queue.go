@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// DefaultCPEQueueLease is how long a dequeued entry stays claimed before
+// another dequeue may hand it out again
+const DefaultCPEQueueLease = 15 * time.Minute
+
+// CPEQueueStore persists services awaiting CPE enrichment in the cpe_queue table
+// Entries are keyed by service ID, so enqueueing a service that is already
+// queued leaves a single entry. Dequeue claims entries for a lease instead of
+// removing them; Ack removes them once their enrichment succeeded, and an entry
+// whose lease runs out unacknowledged is handed out again.
+type CPEQueueStore struct {
+	querier CPEQueueQuerier
+	logger  *zap.Logger
+	lease   time.Duration
+}
+
+// CPEQueueQuerier runs the cpe_queue statements
+type CPEQueueQuerier interface {
+	// QueryEntries executes a statement and returns the entries it returned
+	QueryEntries(ctx context.Context, query string, vars map[string]interface{}) ([]models.CPEQueueEntry, error)
+}
+
+// NewCPEQueueStore creates a CPE queue store; a non-positive lease uses DefaultCPEQueueLease
+func NewCPEQueueStore(db *surrealdb.DB, lease time.Duration, logger *zap.Logger) *CPEQueueStore {
+	return NewCPEQueueStoreWithQuerier(&surrealCPEQueueQuerier{db: db}, lease, logger)
+}
+
+// NewCPEQueueStoreWithQuerier creates a CPE queue store on a custom querier (useful for testing)
+func NewCPEQueueStoreWithQuerier(querier CPEQueueQuerier, lease time.Duration, logger *zap.Logger) *CPEQueueStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if lease <= 0 {
+		lease = DefaultCPEQueueLease
+	}
+	return &CPEQueueStore{
+		querier: querier,
+		logger:  logger,
+		lease:   lease,
+	}
+}
+
+// Enqueue adds services to the queue in one round-trip
+// A service already queued keeps its place and any claim on it.
+func (s *CPEQueueStore) Enqueue(ctx context.Context, entries []models.CPEQueueEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	rows := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		rows[i] = map[string]interface{}{
+			"service_id": entry.ServiceID,
+			"name":       entry.Name,
+			"product":    entry.Product,
+			"version":    entry.Version,
+		}
+	}
+
+	query := `
+		FOR $entry IN $entries {
+			UPSERT type::thing('cpe_queue', $entry.service_id) SET
+				service_id = $entry.service_id,
+				name = $entry.name,
+				product = $entry.product,
+				version = $entry.version,
+				enqueued_at = enqueued_at ?? $now;
+		};
+	`
+	if _, err := s.querier.QueryEntries(ctx, query, map[string]interface{}{
+		"entries": rows,
+		"now":     time.Now().UTC(),
+	}); err != nil {
+		s.logger.Error("failed to enqueue services for CPE enrichment",
+			zap.Error(err),
+			zap.Int("services", len(entries)))
+		return fmt.Errorf("failed to enqueue services for CPE enrichment: %w", err)
+	}
+
+	return nil
+}
+
+// Dequeue claims up to limit unclaimed (or lease-expired) entries, oldest first
+func (s *CPEQueueStore) Dequeue(ctx context.Context, limit int) ([]models.CPEQueueEntry, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("dequeue limit must be positive, got %d", limit)
+	}
+
+	now := time.Now().UTC()
+	query := `
+		UPDATE (
+			SELECT id, enqueued_at FROM cpe_queue
+			WHERE claimed_until IS NONE OR claimed_until <= $now
+			ORDER BY enqueued_at
+			LIMIT $limit
+		).id SET
+			claimed_until = $claimed_until,
+			attempts += 1
+		RETURN service_id, name, product, version, enqueued_at, attempts;
+	`
+	entries, err := s.querier.QueryEntries(ctx, query, map[string]interface{}{
+		"now":           now,
+		"limit":         limit,
+		"claimed_until": now.Add(s.lease),
+	})
+	if err != nil {
+		s.logger.Error("failed to dequeue services for CPE enrichment",
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to dequeue services for CPE enrichment: %w", err)
+	}
+
+	// UPDATE doesn't keep the subquery's order
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].EnqueuedAt.Before(entries[j].EnqueuedAt)
+	})
+	return entries, nil
+}
+
+// Ack removes entries whose enrichment succeeded
+func (s *CPEQueueStore) Ack(ctx context.Context, serviceIDs []string) error {
+	if len(serviceIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		FOR $service_id IN $service_ids {
+			DELETE type::thing('cpe_queue', $service_id);
+		};
+	`
+	if _, err := s.querier.QueryEntries(ctx, query, map[string]interface{}{
+		"service_ids": serviceIDs,
+	}); err != nil {
+		s.logger.Error("failed to acknowledge CPE queue entries",
+			zap.Error(err),
+			zap.Int("services", len(serviceIDs)))
+		return fmt.Errorf("failed to acknowledge CPE queue entries: %w", err)
+	}
+
+	return nil
+}
+
+// surrealCPEQueueQuerier runs cpe_queue statements against SurrealDB
+type surrealCPEQueueQuerier struct {
+	db *surrealdb.DB
+}
+
+// QueryEntries runs a statement and returns the entries of its result
+func (q *surrealCPEQueueQuerier) QueryEntries(ctx context.Context, query string, vars map[string]interface{}) ([]models.CPEQueueEntry, error) {
+	result, err := surrealdb.Query[[]models.CPEQueueEntry](ctx, q.db, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(*result) == 0 {
+		return nil, nil
+	}
+	if (*result)[0].Error != nil {
+		return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+	return (*result)[0].Result, nil
+}
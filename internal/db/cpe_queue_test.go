@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturingCPEQueueQuerier captures statements instead of running them
+type capturingCPEQueueQuerier struct {
+	rows    []models.CPEQueueEntry
+	err     error
+	queries []string
+	vars    []map[string]interface{}
+}
+
+func (q *capturingCPEQueueQuerier) QueryEntries(ctx context.Context, query string, vars map[string]interface{}) ([]models.CPEQueueEntry, error) {
+	q.queries = append(q.queries, query)
+	q.vars = append(q.vars, vars)
+	return q.rows, q.err
+}
+
+// stubCPEQueueStore returns a store whose statements are captured instead of run
+func stubCPEQueueStore(rows []models.CPEQueueEntry, err error) (*CPEQueueStore, *[]string, *[]map[string]interface{}) {
+	q := &capturingCPEQueueQuerier{rows: rows, err: err}
+	return NewCPEQueueStoreWithQuerier(q, 0, nil), &q.queries, &q.vars
+}
+
+func TestCPEQueueStore_Enqueue(t *testing.T) {
+	s, queries, vars := stubCPEQueueStore(nil, nil)
+
+	err := s.Enqueue(context.Background(), []models.CPEQueueEntry{
+		{ServiceID: "service:a", Name: "http", Product: "nginx", Version: "1.18.0"},
+		{ServiceID: "service:b", Name: "ssh", Product: "openssh", Version: "8.9"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, *queries, 1, "one round-trip per enqueue")
+	assert.Contains(t, (*queries)[0], "UPSERT type::thing('cpe_queue', $entry.service_id)")
+	assert.Contains(t, (*queries)[0], "enqueued_at = enqueued_at ?? $now", "re-enqueueing keeps the queue position")
+	assert.Len(t, (*vars)[0]["entries"], 2)
+
+	require.NoError(t, s.Enqueue(context.Background(), nil))
+	assert.Len(t, *queries, 1, "nothing to enqueue skips the round-trip")
+}
+
+func TestCPEQueueStore_DequeueIsBounded(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, queries, vars := stubCPEQueueStore([]models.CPEQueueEntry{
+		{ServiceID: "service:b", EnqueuedAt: older.Add(time.Minute)},
+		{ServiceID: "service:a", EnqueuedAt: older},
+	}, nil)
+
+	entries, err := s.Dequeue(context.Background(), 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, "service:a", entries[0].ServiceID, "oldest first")
+	assert.Equal(t, "service:b", entries[1].ServiceID)
+	assert.Contains(t, (*queries)[0], "LIMIT $limit")
+	assert.Contains(t, (*queries)[0], "claimed_until IS NONE OR claimed_until <= $now")
+	assert.Equal(t, 2, (*vars)[0]["limit"])
+	assert.Equal(t, DefaultCPEQueueLease, (*vars)[0]["claimed_until"].(time.Time).Sub((*vars)[0]["now"].(time.Time)))
+
+	_, err = s.Dequeue(context.Background(), 0)
+	assert.Error(t, err)
+}
+
+func TestCPEQueueStore_Ack(t *testing.T) {
+	s, queries, vars := stubCPEQueueStore(nil, nil)
+
+	require.NoError(t, s.Ack(context.Background(), []string{"service:a"}))
+	assert.Contains(t, (*queries)[0], "DELETE type::thing('cpe_queue', $service_id)")
+	assert.Equal(t, []string{"service:a"}, (*vars)[0]["service_ids"])
+}
+
+func TestCPEQueueStore_Errors(t *testing.T) {
+	s, _, _ := stubCPEQueueStore(nil, errors.New("connection refused"))
+
+	assert.ErrorContains(t, s.Enqueue(context.Background(), []models.CPEQueueEntry{{ServiceID: "service:a"}}), "connection refused")
+	_, err := s.Dequeue(context.Background(), 1)
+	assert.ErrorContains(t, err, "connection refused")
+	assert.ErrorContains(t, s.Ack(context.Background(), []string{"service:a"}), "connection refused")
+}
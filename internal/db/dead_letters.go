@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// DefaultDeadLetterLimit is the default number of dead letters returned by List
+const DefaultDeadLetterLimit = 50
+
+// DeadLetterStore persists enrichment items that exhausted their retries
+// Records are keyed by stage and IP, so repeated failures increment attempts on one record.
+type DeadLetterStore struct {
+	db     *surrealdb.DB
+	logger *zap.Logger
+}
+
+// NewDeadLetterStore creates a new dead-letter store
+func NewDeadLetterStore(db *surrealdb.DB, logger *zap.Logger) *DeadLetterStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &DeadLetterStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// deadLetterID builds the record ID for a stage and IP
+func deadLetterID(stage, ip string) string {
+	return stage + "_" + strings.NewReplacer(".", "_", ":", "_").Replace(ip)
+}
+
+// Record writes or updates the dead letter for an IP that failed the given stage
+func (s *DeadLetterStore) Record(ctx context.Context, ip, stage, reason string) error {
+	query := `
+		UPSERT type::thing('dead_letter', $id) SET
+			ip = $ip,
+			stage = $stage,
+			reason = $reason,
+			attempts = (attempts ?? 0) + 1,
+			last_attempt = $now;
+	`
+	result, err := surrealdb.Query[interface{}](ctx, s.db, query, map[string]interface{}{
+		"id":     deadLetterID(stage, ip),
+		"ip":     ip,
+		"stage":  stage,
+		"reason": reason,
+		"now":    time.Now().UTC(),
+	})
+	if err != nil {
+		s.logger.Error("failed to record dead letter",
+			zap.Error(err),
+			zap.String("ip", ip),
+			zap.String("stage", stage))
+		return fmt.Errorf("failed to record dead letter: %w", err)
+	}
+	if result != nil && len(*result) > 0 && (*result)[0].Error != nil {
+		return fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+
+	s.logger.Warn("enrichment item dead-lettered",
+		zap.String("ip", ip),
+		zap.String("stage", stage),
+		zap.String("reason", reason))
+
+	return nil
+}
+
+// List returns dead letters, most recently failed first, optionally filtered by stage
+func (s *DeadLetterStore) List(ctx context.Context, stage string, limit int) ([]models.DeadLetter, error) {
+	if limit < 1 {
+		limit = DefaultDeadLetterLimit
+	}
+
+	query := `SELECT ip, stage, reason, attempts, last_attempt FROM dead_letter`
+	params := map[string]interface{}{
+		"limit": limit,
+	}
+	if stage != "" {
+		query += ` WHERE stage = $stage`
+		params["stage"] = stage
+	}
+	query += ` ORDER BY last_attempt DESC LIMIT $limit`
+
+	result, err := surrealdb.Query[[]models.DeadLetter](ctx, s.db, query, params)
+	if err != nil {
+		s.logger.Error("failed to list dead letters",
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	deadLetters := make([]models.DeadLetter, 0)
+	if result != nil && len(*result) > 0 {
+		if (*result)[0].Error != nil {
+			return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+		}
+		deadLetters = append(deadLetters, (*result)[0].Result...)
+	}
+
+	return deadLetters, nil
+}
+
+// Get returns the dead letter for an IP and stage, or nil if there is none
+func (s *DeadLetterStore) Get(ctx context.Context, ip, stage string) (*models.DeadLetter, error) {
+	query := `SELECT ip, stage, reason, attempts, last_attempt FROM type::thing('dead_letter', $id);`
+	result, err := surrealdb.Query[[]models.DeadLetter](ctx, s.db, query, map[string]interface{}{
+		"id": deadLetterID(stage, ip),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if result == nil || len(*result) == 0 {
+		return nil, nil
+	}
+	if (*result)[0].Error != nil {
+		return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+	if len((*result)[0].Result) == 0 {
+		return nil, nil
+	}
+
+	deadLetter := (*result)[0].Result[0]
+	return &deadLetter, nil
+}
+
+// Delete removes the dead letter for an IP and stage
+func (s *DeadLetterStore) Delete(ctx context.Context, ip, stage string) error {
+	query := `DELETE type::thing('dead_letter', $id);`
+	result, err := surrealdb.Query[interface{}](ctx, s.db, query, map[string]interface{}{
+		"id": deadLetterID(stage, ip),
+	})
+	if err != nil {
+		s.logger.Error("failed to delete dead letter",
+			zap.Error(err),
+			zap.String("ip", ip),
+			zap.String("stage", stage))
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if result != nil && len(*result) > 0 && (*result)[0].Error != nil {
+		return fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,337 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// diffHostRow is a host with its ports and their services, each with the
+// timestamps of its first and last sighting
+type diffHostRow struct {
+	IP        string        `json:"ip"`
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	Ports     []diffPortRow `json:"ports"`
+}
+
+// diffPortRow is a HAS edge: an open port on the host
+type diffPortRow struct {
+	Number    int              `json:"number"`
+	Protocol  string           `json:"protocol"`
+	FirstSeen time.Time        `json:"first_seen"`
+	LastSeen  time.Time        `json:"last_seen"`
+	Services  []diffServiceRow `json:"services"`
+}
+
+// diffServiceRow is a RUNS edge: a service on the port, with the CVEs affecting it.
+// Port nodes are shared between hosts, so only the edges stamped with the host's
+// IP are read; edges ingest wrote before RUNS carried host_ip are left out.
+type diffServiceRow struct {
+	Name      string    `json:"name"`
+	Product   string    `json:"product"`
+	Version   string    `json:"version"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	CVEs      []string  `json:"cves"`
+}
+
+// QueryDiff returns what changed in a network between two scan snapshots: hosts
+// added and removed, and for every host the ports opened or closed, the services
+// that changed on ports open in both snapshots and the CVEs newly affecting it
+func (e *GraphQueryExecutor) QueryDiff(ctx context.Context, req models.DiffRequest) (*models.DiffResponse, error) {
+	startTime := time.Now()
+
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
+
+	e.logger.Debug("executing diff query",
+		zap.String("cidr", req.CIDR),
+		zap.Any("asn", req.ASN),
+		zap.Time("from", req.From),
+		zap.Time("to", req.To))
+
+	// Hosts last seen before From are in neither snapshot
+	params := map[string]interface{}{
+		"from": req.From.UTC(),
+	}
+	var network netip.Prefix
+	scope := "asn = $asn"
+	if req.ASN != nil {
+		params["asn"] = *req.ASN
+	} else {
+		// Narrow the scan with the network's leading octets, then match exactly
+		network = netip.MustParsePrefix(req.CIDR).Masked()
+		scope = "true"
+		if prefix := ipv4TextPrefix(network); prefix != "" {
+			scope = "string::starts_with(ip, $prefix)"
+			params["prefix"] = prefix
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			ip,
+			first_seen,
+			last_seen,
+			(SELECT
+				out.number AS number,
+				out.protocol AS protocol,
+				first_seen,
+				last_seen,
+				(SELECT
+					out.name AS name,
+					out.product AS product,
+					out.version AS version,
+					first_seen,
+					last_seen,
+					array::distinct(out->AFFECTED_BY->vuln.cve_id) AS cves
+				FROM out->RUNS WHERE host_ip = $parent.in.ip) AS services
+			FROM ->HAS) AS ports
+		FROM host
+		WHERE %s AND last_seen >= $from
+	`, scope)
+
+	result, err := surrealdb.Query[[]diffHostRow](ctx, e.db, query, params)
+	if err != nil {
+		e.logger.Error("failed to execute diff query", zap.Error(err))
+		return nil, fmt.Errorf("failed to query diff: %w", err)
+	}
+
+	var rows []diffHostRow
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil {
+		rows = (*result)[0].Result
+	}
+	if req.ASN == nil {
+		inNetwork := rows[:0]
+		for _, row := range rows {
+			if addr, err := netip.ParseAddr(row.IP); err == nil && network.Contains(addr) {
+				inNetwork = append(inNetwork, row)
+			}
+		}
+		rows = inNetwork
+	}
+
+	resp := diffSnapshots(rows, req.From, req.To, req.Limit)
+	resp.CIDR = req.CIDR
+	resp.ASN = req.ASN
+	resp.QueryTime = time.Since(startTime).Seconds() * 1000
+	return &resp, nil
+}
+
+// diffWindow places sightings in the two snapshots of a diff
+type diffWindow struct {
+	from, to time.Time
+}
+
+// earlier reports whether something first seen at first and last seen at last
+// was seen between from and to
+func (w diffWindow) earlier(first, last time.Time) bool {
+	return first.Before(w.to) && !last.Before(w.from)
+}
+
+// later reports whether something last seen at last has been seen since to
+func (w diffWindow) later(last time.Time) bool {
+	return !last.Before(w.to)
+}
+
+// diffSnapshots compares the earlier and later snapshots of rows, listing at most
+// limit hosts per category, each category sorted by IP
+func diffSnapshots(rows []diffHostRow, from, to time.Time, limit int) models.DiffResponse {
+	window := diffWindow{from: from, to: to}
+	resp := models.DiffResponse{
+		From:    from,
+		To:      to,
+		Added:   []models.HostDiff{},
+		Removed: []models.HostDiff{},
+		Changed: []models.HostDiff{},
+		Limit:   limit,
+	}
+
+	for _, row := range rows {
+		inEarlier := window.earlier(row.FirstSeen, row.LastSeen)
+		inLater := window.later(row.LastSeen)
+		if !inEarlier && !inLater {
+			continue
+		}
+
+		host := diffHost(row, window)
+		resp.Summary.PortsAdded += len(host.AddedPorts)
+		resp.Summary.PortsRemoved += len(host.RemovedPorts)
+		resp.Summary.ServicesChanged += len(host.ChangedServices)
+		resp.Summary.NewCVEs += len(host.NewCVEs)
+
+		switch {
+		case inLater && !inEarlier:
+			resp.Added = append(resp.Added, host)
+		case inEarlier && !inLater:
+			resp.Removed = append(resp.Removed, host)
+		case len(host.AddedPorts) > 0 || len(host.RemovedPorts) > 0 || len(host.ChangedServices) > 0 || len(host.NewCVEs) > 0:
+			resp.Changed = append(resp.Changed, host)
+		}
+	}
+
+	resp.Summary.HostsAdded = len(resp.Added)
+	resp.Summary.HostsRemoved = len(resp.Removed)
+	resp.Summary.HostsChanged = len(resp.Changed)
+	resp.Added = sortedHostDiffs(resp.Added, limit)
+	resp.Removed = sortedHostDiffs(resp.Removed, limit)
+	resp.Changed = sortedHostDiffs(resp.Changed, limit)
+	return resp
+}
+
+// diffPortKey identifies a port on a host
+type diffPortKey struct {
+	number   int
+	protocol string
+}
+
+// diffHost compares a host's ports and services between the snapshots
+func diffHost(row diffHostRow, window diffWindow) models.HostDiff {
+	host := models.HostDiff{IP: row.IP}
+
+	// A port may be listed once per HAS edge; merge its sightings
+	ports := make(map[diffPortKey]*diffPortRow)
+	var keys []diffPortKey
+	for _, port := range row.Ports {
+		key := diffPortKey{number: port.Number, protocol: port.Protocol}
+		merged, ok := ports[key]
+		if !ok {
+			p := port
+			ports[key] = &p
+			keys = append(keys, key)
+			continue
+		}
+		if port.FirstSeen.Before(merged.FirstSeen) {
+			merged.FirstSeen = port.FirstSeen
+		}
+		if port.LastSeen.After(merged.LastSeen) {
+			merged.LastSeen = port.LastSeen
+		}
+		merged.Services = append(merged.Services, port.Services...)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].number != keys[j].number {
+			return keys[i].number < keys[j].number
+		}
+		return keys[i].protocol < keys[j].protocol
+	})
+
+	earlierCVEs := make(map[string]bool)
+	laterCVEs := make(map[string]bool)
+	for _, key := range keys {
+		port := ports[key]
+		inEarlier := window.earlier(port.FirstSeen, port.LastSeen)
+		inLater := window.later(port.LastSeen)
+
+		var before, after []models.DiffService
+		for _, svc := range port.Services {
+			service := models.DiffService{Name: svc.Name, Product: svc.Product, Version: svc.Version}
+			if inEarlier && window.earlier(svc.FirstSeen, svc.LastSeen) {
+				before = append(before, service)
+				for _, cve := range svc.CVEs {
+					earlierCVEs[cve] = true
+				}
+			}
+			if inLater && window.later(svc.LastSeen) {
+				after = append(after, service)
+				for _, cve := range svc.CVEs {
+					laterCVEs[cve] = true
+				}
+			}
+		}
+		before = distinctServices(before)
+		after = distinctServices(after)
+
+		switch {
+		case inLater && !inEarlier:
+			host.AddedPorts = append(host.AddedPorts, models.DiffPort{Number: key.number, Protocol: key.protocol, Services: after})
+		case inEarlier && !inLater:
+			host.RemovedPorts = append(host.RemovedPorts, models.DiffPort{Number: key.number, Protocol: key.protocol, Services: before})
+		case inEarlier && inLater && !sameServices(before, after):
+			host.ChangedServices = append(host.ChangedServices, models.ServiceChange{
+				Number:   key.number,
+				Protocol: key.protocol,
+				Before:   emptyIfNil(before),
+				After:    emptyIfNil(after),
+			})
+		}
+	}
+
+	for cve := range laterCVEs {
+		if !earlierCVEs[cve] {
+			host.NewCVEs = append(host.NewCVEs, cve)
+		}
+	}
+	sort.Strings(host.NewCVEs)
+	return host
+}
+
+// distinctServices sorts services and drops duplicates
+func distinctServices(services []models.DiffService) []models.DiffService {
+	sort.Slice(services, func(i, j int) bool {
+		a, b := services[i], services[j]
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Version < b.Version
+	})
+	distinct := services[:0]
+	for i, svc := range services {
+		if i == 0 || svc != services[i-1] {
+			distinct = append(distinct, svc)
+		}
+	}
+	return distinct
+}
+
+// sameServices reports whether two distinct, sorted service lists are equal
+func sameServices(a, b []models.DiffService) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// emptyIfNil returns services, or an empty list for nil so it encodes as []
+func emptyIfNil(services []models.DiffService) []models.DiffService {
+	if services == nil {
+		return []models.DiffService{}
+	}
+	return services
+}
+
+// sortedHostDiffs sorts hosts by IP, numerically where they parse, and keeps at most limit
+func sortedHostDiffs(hosts []models.HostDiff, limit int) []models.HostDiff {
+	sort.Slice(hosts, func(i, j int) bool {
+		a, errA := netip.ParseAddr(hosts[i].IP)
+		b, errB := netip.ParseAddr(hosts[j].IP)
+		if errA != nil || errB != nil {
+			return hosts[i].IP < hosts[j].IP
+		}
+		return a.Less(b)
+	})
+	if limit > 0 && len(hosts) > limit {
+		hosts = hosts[:limit]
+	}
+	return hosts
+}
@@ -0,0 +1,343 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap/zaptest"
+)
+
+// Diff fixtures: yesterday's scan started at diffFrom and today's at diffTo
+var (
+	diffFrom = time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	diffTo   = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	// Sighting times in each scan, and one long before both
+	seenLongAgo   = diffFrom.Add(-30 * 24 * time.Hour)
+	seenYesterday = diffFrom.Add(time.Hour)
+	seenToday     = diffTo.Add(time.Hour)
+)
+
+func diffService(name, product, version string, first, last time.Time, cves ...string) diffServiceRow {
+	return diffServiceRow{Name: name, Product: product, Version: version, FirstSeen: first, LastSeen: last, CVEs: cves}
+}
+
+// diffFixture is a network before and after an upgrade: one host appears, one
+// disappears, one opens and closes ports and upgrades nginx, one is unchanged
+// and one was gone before either scan
+func diffFixture() []diffHostRow {
+	return []diffHostRow{
+		{
+			IP: "192.0.2.20", FirstSeen: seenToday, LastSeen: seenToday,
+			Ports: []diffPortRow{{
+				Number: 443, Protocol: "tcp", FirstSeen: seenToday, LastSeen: seenToday,
+				Services: []diffServiceRow{diffService("https", "nginx", "1.18.0", seenToday, seenToday, "CVE-2021-23017")},
+			}},
+		},
+		{
+			IP: "192.0.2.9", FirstSeen: seenLongAgo, LastSeen: seenYesterday,
+			Ports: []diffPortRow{{
+				Number: 22, Protocol: "tcp", FirstSeen: seenLongAgo, LastSeen: seenYesterday,
+				Services: []diffServiceRow{diffService("ssh", "openssh", "8.2", seenLongAgo, seenYesterday)},
+			}},
+		},
+		{
+			IP: "192.0.2.10", FirstSeen: seenLongAgo, LastSeen: seenToday,
+			Ports: []diffPortRow{
+				{
+					Number: 80, Protocol: "tcp", FirstSeen: seenLongAgo, LastSeen: seenToday,
+					Services: []diffServiceRow{
+						diffService("http", "nginx", "1.24.0", seenLongAgo, seenYesterday),
+						diffService("http", "nginx", "1.25.3", seenToday, seenToday, "CVE-2024-7347"),
+					},
+				},
+				{Number: 23, Protocol: "tcp", FirstSeen: seenLongAgo, LastSeen: seenYesterday},
+				{
+					Number: 6379, Protocol: "tcp", FirstSeen: seenToday, LastSeen: seenToday,
+					Services: []diffServiceRow{diffService("redis", "redis", "7.0.0", seenToday, seenToday)},
+				},
+			},
+		},
+		{
+			IP: "192.0.2.11", FirstSeen: seenLongAgo, LastSeen: seenToday,
+			Ports: []diffPortRow{{
+				Number: 22, Protocol: "tcp", FirstSeen: seenLongAgo, LastSeen: seenToday,
+				Services: []diffServiceRow{diffService("ssh", "openssh", "8.2", seenLongAgo, seenToday)},
+			}},
+		},
+		{
+			IP: "192.0.2.12", FirstSeen: seenLongAgo, LastSeen: seenLongAgo,
+		},
+	}
+}
+
+func TestDiffSnapshots_AddedHosts(t *testing.T) {
+	resp := diffSnapshots(diffFixture(), diffFrom, diffTo, 10)
+
+	assert.Equal(t, 1, resp.Summary.HostsAdded)
+	require.Len(t, resp.Added, 1)
+	assert.Equal(t, models.HostDiff{
+		IP: "192.0.2.20",
+		AddedPorts: []models.DiffPort{{
+			Number:   443,
+			Protocol: "tcp",
+			Services: []models.DiffService{{Name: "https", Product: "nginx", Version: "1.18.0"}},
+		}},
+		NewCVEs: []string{"CVE-2021-23017"},
+	}, resp.Added[0])
+}
+
+func TestDiffSnapshots_RemovedHosts(t *testing.T) {
+	resp := diffSnapshots(diffFixture(), diffFrom, diffTo, 10)
+
+	assert.Equal(t, 1, resp.Summary.HostsRemoved, "a host gone before either scan isn't removed by this diff")
+	require.Len(t, resp.Removed, 1)
+	assert.Equal(t, models.HostDiff{
+		IP: "192.0.2.9",
+		RemovedPorts: []models.DiffPort{{
+			Number:   22,
+			Protocol: "tcp",
+			Services: []models.DiffService{{Name: "ssh", Product: "openssh", Version: "8.2"}},
+		}},
+	}, resp.Removed[0])
+}
+
+func TestDiffSnapshots_ChangedHosts(t *testing.T) {
+	resp := diffSnapshots(diffFixture(), diffFrom, diffTo, 10)
+
+	assert.Equal(t, 1, resp.Summary.HostsChanged, "an unchanged host isn't listed")
+	require.Len(t, resp.Changed, 1)
+	host := resp.Changed[0]
+	assert.Equal(t, "192.0.2.10", host.IP)
+
+	assert.Equal(t, []models.DiffPort{{
+		Number:   6379,
+		Protocol: "tcp",
+		Services: []models.DiffService{{Name: "redis", Product: "redis", Version: "7.0.0"}},
+	}}, host.AddedPorts)
+	assert.Equal(t, []models.DiffPort{{Number: 23, Protocol: "tcp"}}, host.RemovedPorts)
+	assert.Equal(t, []models.ServiceChange{{
+		Number:   80,
+		Protocol: "tcp",
+		Before:   []models.DiffService{{Name: "http", Product: "nginx", Version: "1.24.0"}},
+		After:    []models.DiffService{{Name: "http", Product: "nginx", Version: "1.25.3"}},
+	}}, host.ChangedServices)
+	assert.Equal(t, []string{"CVE-2024-7347"}, host.NewCVEs)
+}
+
+func TestDiffSnapshots_Summary(t *testing.T) {
+	resp := diffSnapshots(diffFixture(), diffFrom, diffTo, 10)
+
+	assert.Equal(t, models.DiffSummary{
+		HostsAdded:      1,
+		HostsRemoved:    1,
+		HostsChanged:    1,
+		PortsAdded:      2,
+		PortsRemoved:    2,
+		ServicesChanged: 1,
+		NewCVEs:         2,
+	}, resp.Summary)
+	assert.Equal(t, diffFrom, resp.From)
+	assert.Equal(t, diffTo, resp.To)
+}
+
+func TestDiffSnapshots_LimitKeepsCounts(t *testing.T) {
+	rows := []diffHostRow{
+		{IP: "192.0.2.100", FirstSeen: seenToday, LastSeen: seenToday},
+		{IP: "192.0.2.9", FirstSeen: seenToday, LastSeen: seenToday},
+		{IP: "192.0.2.10", FirstSeen: seenToday, LastSeen: seenToday},
+	}
+
+	resp := diffSnapshots(rows, diffFrom, diffTo, 2)
+
+	assert.Equal(t, 3, resp.Summary.HostsAdded)
+	require.Len(t, resp.Added, 2)
+	assert.Equal(t, "192.0.2.9", resp.Added[0].IP, "hosts are ordered numerically")
+	assert.Equal(t, "192.0.2.10", resp.Added[1].IP)
+	assert.Empty(t, resp.Removed)
+	assert.NotNil(t, resp.Removed, "empty categories encode as []")
+}
+
+func TestDiffRequest_Validate(t *testing.T) {
+	asn := 64500
+	tests := []struct {
+		name    string
+		req     models.DiffRequest
+		wantErr error
+	}{
+		{name: "no scope", req: models.DiffRequest{From: diffFrom, To: diffTo}, wantErr: models.ErrMissingDiffScope},
+		{name: "both scopes", req: models.DiffRequest{CIDR: "192.0.2.0/24", ASN: &asn, From: diffFrom, To: diffTo}, wantErr: models.ErrAmbiguousDiffScope},
+		{name: "bad cidr", req: models.DiffRequest{CIDR: "192.0.2.0", From: diffFrom, To: diffTo}, wantErr: models.ErrInvalidDiffCIDR},
+		{name: "no from", req: models.DiffRequest{ASN: &asn, To: diffTo}, wantErr: models.ErrMissingDiffFrom},
+		{name: "no to", req: models.DiffRequest{ASN: &asn, From: diffFrom}, wantErr: models.ErrMissingDiffTo},
+		{name: "to before from", req: models.DiffRequest{ASN: &asn, From: diffTo, To: diffFrom}, wantErr: models.ErrInvalidDiffRange},
+		{name: "valid", req: models.DiffRequest{CIDR: "192.0.2.0/24", From: diffFrom, To: diffTo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				assert.Equal(t, models.DefaultLimit, tt.req.Limit)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+// seedDiffTestData creates the network of diffFixture relative to now: yesterday's
+// scan ran 1d ago and today's just now
+func seedDiffTestData(t *testing.T, db *surrealdb.DB) {
+	ctx := context.Background()
+
+	queries := []string{
+		`CREATE host:diff_added SET ip = "192.0.2.20", asn = 64500, first_seen = time::now(), last_seen = time::now();`,
+		`CREATE host:diff_removed SET ip = "192.0.2.9", asn = 64500, first_seen = time::now() - 30d, last_seen = time::now() - 1d;`,
+		`CREATE host:diff_changed SET ip = "192.0.2.10", asn = 64500, first_seen = time::now() - 30d, last_seen = time::now();`,
+		`CREATE host:diff_same SET ip = "192.0.2.11", asn = 64500, first_seen = time::now() - 30d, last_seen = time::now();`,
+		`CREATE host:diff_other SET ip = "198.51.100.10", asn = 64501, first_seen = time::now(), last_seen = time::now();`,
+
+		`CREATE port:diff_added_443 SET number = 443, protocol = "tcp";`,
+		`CREATE port:diff_removed_22 SET number = 22, protocol = "tcp";`,
+		`CREATE port:diff_changed_80 SET number = 80, protocol = "tcp";`,
+		`CREATE port:diff_changed_23 SET number = 23, protocol = "tcp";`,
+		`CREATE port:diff_changed_6379 SET number = 6379, protocol = "tcp";`,
+		`CREATE port:diff_same_22 SET number = 22, protocol = "tcp";`,
+		`CREATE port:diff_other_80 SET number = 80, protocol = "tcp";`,
+
+		`CREATE service:diff_nginx_old SET name = "http", product = "nginx", version = "1.24.0";`,
+		`CREATE service:diff_nginx_new SET name = "http", product = "nginx", version = "1.25.3";`,
+		`CREATE service:diff_redis SET name = "redis", product = "redis", version = "7.0.0";`,
+		`CREATE service:diff_openssh SET name = "ssh", product = "openssh", version = "8.2";`,
+		`CREATE vuln:diff_cve SET cve_id = "CVE-2024-7347", cvss = 5.7;`,
+
+		`RELATE host:diff_added->HAS->port:diff_added_443 SET first_seen = time::now(), last_seen = time::now();`,
+		`RELATE host:diff_removed->HAS->port:diff_removed_22 SET first_seen = time::now() - 30d, last_seen = time::now() - 1d;`,
+		`RELATE host:diff_changed->HAS->port:diff_changed_80 SET first_seen = time::now() - 30d, last_seen = time::now();`,
+		`RELATE host:diff_changed->HAS->port:diff_changed_23 SET first_seen = time::now() - 30d, last_seen = time::now() - 1d;`,
+		`RELATE host:diff_changed->HAS->port:diff_changed_6379 SET first_seen = time::now(), last_seen = time::now();`,
+		`RELATE host:diff_same->HAS->port:diff_same_22 SET first_seen = time::now() - 30d, last_seen = time::now();`,
+		`RELATE host:diff_other->HAS->port:diff_other_80 SET first_seen = time::now(), last_seen = time::now();`,
+
+		`RELATE port:diff_removed_22->RUNS->service:diff_openssh SET host_ip = "192.0.2.9", first_seen = time::now() - 30d, last_seen = time::now() - 1d;`,
+		`RELATE port:diff_changed_80->RUNS->service:diff_nginx_old SET host_ip = "192.0.2.10", first_seen = time::now() - 30d, last_seen = time::now() - 1d;`,
+		`RELATE port:diff_changed_80->RUNS->service:diff_nginx_new SET host_ip = "192.0.2.10", first_seen = time::now(), last_seen = time::now();`,
+		`RELATE port:diff_changed_6379->RUNS->service:diff_redis SET host_ip = "192.0.2.10", first_seen = time::now(), last_seen = time::now();`,
+		`RELATE port:diff_same_22->RUNS->service:diff_openssh SET host_ip = "192.0.2.11", first_seen = time::now() - 30d, last_seen = time::now();`,
+		`RELATE service:diff_nginx_new->AFFECTED_BY->vuln:diff_cve;`,
+	}
+
+	for _, query := range queries {
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
+		require.NoError(t, err, "failed to seed test data: %s", query)
+	}
+}
+
+// seedSharedPortDiffTestData creates two hosts with the same port open, so one
+// port node: the first upgrades nginx on it between the scans, the second runs
+// an unchanged openssh
+func seedSharedPortDiffTestData(t *testing.T, db *surrealdb.DB) {
+	ctx := context.Background()
+
+	queries := []string{
+		`CREATE host:diff_shared_a SET ip = "203.0.113.1", first_seen = time::now() - 30d, last_seen = time::now();`,
+		`CREATE host:diff_shared_b SET ip = "203.0.113.2", first_seen = time::now() - 30d, last_seen = time::now();`,
+		`CREATE port:diff_shared_443 SET number = 443, protocol = "tcp";`,
+
+		`CREATE service:diff_nginx_old SET name = "http", product = "nginx", version = "1.24.0";`,
+		`CREATE service:diff_nginx_new SET name = "http", product = "nginx", version = "1.25.3";`,
+		`CREATE service:diff_openssh SET name = "ssh", product = "openssh", version = "8.2";`,
+		`CREATE vuln:diff_cve SET cve_id = "CVE-2024-7347", cvss = 5.7;`,
+
+		`RELATE host:diff_shared_a->HAS->port:diff_shared_443 SET first_seen = time::now() - 30d, last_seen = time::now();`,
+		`RELATE host:diff_shared_b->HAS->port:diff_shared_443 SET first_seen = time::now() - 30d, last_seen = time::now();`,
+
+		`RELATE port:diff_shared_443->RUNS->service:diff_nginx_old SET host_ip = "203.0.113.1", first_seen = time::now() - 30d, last_seen = time::now() - 1d;`,
+		`RELATE port:diff_shared_443->RUNS->service:diff_nginx_new SET host_ip = "203.0.113.1", first_seen = time::now(), last_seen = time::now();`,
+		`RELATE port:diff_shared_443->RUNS->service:diff_openssh SET host_ip = "203.0.113.2", first_seen = time::now() - 30d, last_seen = time::now();`,
+		`RELATE service:diff_nginx_new->AFFECTED_BY->vuln:diff_cve;`,
+	}
+
+	for _, query := range queries {
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
+		require.NoError(t, err, "failed to seed test data: %s", query)
+	}
+}
+
+func TestGraphQueryExecutor_QueryDiff(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedDiffTestData(t, db)
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+	now := time.Now()
+	req := models.DiffRequest{
+		CIDR: "192.0.2.0/24",
+		From: now.Add(-25 * time.Hour),
+		To:   now.Add(-time.Hour),
+	}
+
+	resp, err := executor.QueryDiff(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Added, 1)
+	assert.Equal(t, "192.0.2.20", resp.Added[0].IP, "hosts outside the CIDR are left out")
+	require.Len(t, resp.Removed, 1)
+	assert.Equal(t, "192.0.2.9", resp.Removed[0].IP)
+
+	require.Len(t, resp.Changed, 1)
+	changed := resp.Changed[0]
+	assert.Equal(t, "192.0.2.10", changed.IP)
+	require.Len(t, changed.AddedPorts, 1)
+	assert.Equal(t, 6379, changed.AddedPorts[0].Number)
+	require.Len(t, changed.RemovedPorts, 1)
+	assert.Equal(t, 23, changed.RemovedPorts[0].Number)
+	require.Len(t, changed.ChangedServices, 1)
+	assert.Equal(t, "1.24.0", changed.ChangedServices[0].Before[0].Version)
+	assert.Equal(t, "1.25.3", changed.ChangedServices[0].After[0].Version)
+	assert.Equal(t, []string{"CVE-2024-7347"}, changed.NewCVEs)
+
+	asn := 64501
+	resp, err = executor.QueryDiff(context.Background(), models.DiffRequest{ASN: &asn, From: req.From, To: req.To})
+	require.NoError(t, err)
+	require.Len(t, resp.Added, 1)
+	assert.Equal(t, "198.51.100.10", resp.Added[0].IP)
+	assert.Empty(t, resp.Removed)
+	assert.Empty(t, resp.Changed)
+}
+
+func TestGraphQueryExecutor_QueryDiff_SharedPort(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedSharedPortDiffTestData(t, db)
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+	now := time.Now()
+	resp, err := executor.QueryDiff(context.Background(), models.DiffRequest{
+		CIDR: "203.0.113.0/24",
+		From: now.Add(-25 * time.Hour),
+		To:   now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	assert.Empty(t, resp.Added)
+	assert.Empty(t, resp.Removed)
+	require.Len(t, resp.Changed, 1, "the host whose services didn't change isn't listed")
+	changed := resp.Changed[0]
+	assert.Equal(t, "203.0.113.1", changed.IP)
+	assert.Equal(t, []models.ServiceChange{{
+		Number:   443,
+		Protocol: "tcp",
+		Before:   []models.DiffService{{Name: "http", Product: "nginx", Version: "1.24.0"}},
+		After:    []models.DiffService{{Name: "http", Product: "nginx", Version: "1.25.3"}},
+	}}, changed.ChangedServices, "the other host's service on the shared port is left out")
+	assert.Equal(t, []string{"CVE-2024-7347"}, changed.NewCVEs)
+	assert.Equal(t, 1, resp.Summary.NewCVEs)
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	surrealmodels "github.com/surrealdb/surrealdb.go/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultExportBatchSize is how many records GraphExporter reads per query
+const DefaultExportBatchSize = 500
+
+// GraphExporter streams graph tables out record by record
+// Each table is read in batches as record id ranges starting after the last id
+// of the previous batch, so memory stays bounded by one batch and each batch
+// costs the same whatever the size of the graph. Records written while an
+// export runs may or may not be included.
+type GraphExporter struct {
+	reader    ExportReader
+	logger    *zap.Logger
+	batchSize int
+}
+
+// ExportReader reads graph tables in record id order
+type ExportReader interface {
+	// SelectBatch reads up to limit records of table with ids after after (nil
+	// for the first batch)
+	SelectBatch(ctx context.Context, table string, after interface{}, limit int) ([]map[string]interface{}, error)
+}
+
+// NewGraphExporter creates a graph exporter with the default batch size
+func NewGraphExporter(db *surrealdb.DB, logger *zap.Logger) *GraphExporter {
+	return NewGraphExporterWithReader(&surrealExportReader{db: db}, logger)
+}
+
+// NewGraphExporterWithReader creates a graph exporter on a custom reader (useful for testing)
+func NewGraphExporterWithReader(reader ExportReader, logger *zap.Logger) *GraphExporter {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &GraphExporter{
+		reader:    reader,
+		logger:    logger,
+		batchSize: DefaultExportBatchSize,
+	}
+}
+
+// Export emits every record of tables, which must be graph tables, in order
+// It stops at the first error from the database or from emit and returns the
+// number of records emitted until then.
+func (e *GraphExporter) Export(ctx context.Context, tables []string, emit func(models.ExportRecord) error) (int, error) {
+	exported := 0
+	for _, table := range tables {
+		kind := models.ExportKind(table)
+		if kind == "" {
+			return exported, fmt.Errorf("not a graph table: %q", table)
+		}
+
+		var after interface{}
+		for {
+			rows, err := e.reader.SelectBatch(ctx, table, after, e.batchSize)
+			if err != nil {
+				return exported, fmt.Errorf("failed to read %s: %w", table, err)
+			}
+
+			for _, row := range rows {
+				if err := emit(exportRecord(table, kind, row)); err != nil {
+					return exported, err
+				}
+				exported++
+			}
+
+			if len(rows) < e.batchSize {
+				break
+			}
+			after = rows[len(rows)-1]["id"]
+		}
+
+		e.logger.Debug("exported table",
+			zap.String("table", table),
+			zap.Int("records_so_far", exported))
+	}
+
+	return exported, nil
+}
+
+// exportRecord turns a selected row into an export record, lifting the id and
+// an edge's in and out out of its data
+func exportRecord(table, kind string, row map[string]interface{}) models.ExportRecord {
+	record := models.ExportRecord{
+		Table: table,
+		Kind:  kind,
+		ID:    recordIDString(row["id"]),
+		Data:  make(map[string]interface{}, len(row)),
+	}
+	if kind == models.ExportKindEdge {
+		record.In = recordIDString(row["in"])
+		record.Out = recordIDString(row["out"])
+	}
+
+	for field, value := range row {
+		switch field {
+		case "id":
+			continue
+		case "in", "out":
+			if kind == models.ExportKindEdge {
+				continue
+			}
+		}
+		record.Data[field] = exportValue(value)
+	}
+	return record
+}
+
+// exportValue converts the database's record links inside a value to their
+// table:id strings, so every value encodes to plain JSON
+func exportValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case surrealmodels.RecordID, *surrealmodels.RecordID:
+		return recordIDString(v)
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = exportValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = exportValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// recordIDString formats a record id as table:id
+func recordIDString(value interface{}) string {
+	switch v := value.(type) {
+	case surrealmodels.RecordID:
+		return v.String()
+	case *surrealmodels.RecordID:
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// surrealExportReader reads graph tables from SurrealDB
+type surrealExportReader struct {
+	db *surrealdb.DB
+}
+
+// SelectBatch selects the next batch of a table in record id order
+// A record range is read straight off the table's keys, in id order, so the
+// batch starts at after rather than filtering and sorting every record before it.
+func (r *surrealExportReader) SelectBatch(ctx context.Context, table string, after interface{}, limit int) ([]map[string]interface{}, error) {
+	ids, err := exportRange(table, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// The table name is one of the graph tables Export checked, never user input
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT $limit;", ids)
+	result, err := surrealdb.Query[[]map[string]interface{}](ctx, r.db, query, map[string]interface{}{
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(*result) == 0 {
+		return nil, nil
+	}
+	if (*result)[0].Error != nil {
+		return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+	return (*result)[0].Result, nil
+}
+
+// exportRange returns the record range of table's ids after after, such as
+// host:⟨192.0.2.1⟩>.., or the whole table (host:..) when after is nil
+func exportRange(table string, after interface{}) (string, error) {
+	if after == nil {
+		return table + ":..", nil
+	}
+
+	var key interface{}
+	switch id := after.(type) {
+	case surrealmodels.RecordID:
+		key = id.ID
+	case *surrealmodels.RecordID:
+		if id != nil {
+			key = id.ID
+		}
+	}
+	switch key.(type) {
+	case string, int, int64, uint64:
+		id := surrealmodels.NewRecordID(table, key)
+		return id.String() + ">..", nil
+	default:
+		return "", fmt.Errorf("cannot resume %s after record %v: unsupported id type %T", table, after, key)
+	}
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	surrealmodels "github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// memoryExportReader reads from an in-memory graph of rows keyed by table
+type memoryExportReader struct {
+	graph   map[string][]map[string]interface{}
+	batches int
+}
+
+func (r *memoryExportReader) SelectBatch(ctx context.Context, table string, after interface{}, limit int) ([]map[string]interface{}, error) {
+	r.batches++
+	rows := append([]map[string]interface{}{}, r.graph[table]...)
+	sort.Slice(rows, func(i, j int) bool { return recordIDString(rows[i]["id"]) < recordIDString(rows[j]["id"]) })
+
+	var batch []map[string]interface{}
+	for _, row := range rows {
+		if after != nil && recordIDString(row["id"]) <= recordIDString(after) {
+			continue
+		}
+		if len(batch) == limit {
+			break
+		}
+		batch = append(batch, row)
+	}
+	return batch, nil
+}
+
+// stubExporter returns an exporter reading from an in-memory graph of rows
+// keyed by table, and a counter of the batches it was asked for
+func stubExporter(graph map[string][]map[string]interface{}, batchSize int) (*GraphExporter, *int) {
+	reader := &memoryExportReader{graph: graph}
+	e := NewGraphExporterWithReader(reader, nil)
+	e.batchSize = batchSize
+	return e, &reader.batches
+}
+
+func rid(table, id string) surrealmodels.RecordID {
+	return surrealmodels.NewRecordID(table, id)
+}
+
+// seededGraph is two hosts sharing a port, each with a HAS edge
+func seededGraph() map[string][]map[string]interface{} {
+	return map[string][]map[string]interface{}{
+		"host": {
+			{"id": rid("host", "b"), "ip": "192.0.2.2"},
+			{"id": rid("host", "a"), "ip": "192.0.2.1", "tags": []interface{}{"edge"}},
+			{"id": rid("host", "c"), "ip": "192.0.2.3"},
+		},
+		"port": {
+			{"id": rid("port", "port_22_tcp"), "number": 22, "protocol": "tcp"},
+		},
+		"HAS": {
+			{"id": rid("HAS", "e1"), "in": rid("host", "a"), "out": rid("port", "port_22_tcp"), "first_seen": "2026-01-01T00:00:00Z"},
+			{"id": rid("HAS", "e2"), "in": rid("host", "b"), "out": rid("port", "port_22_tcp")},
+		},
+	}
+}
+
+func TestGraphExporter_ExportRoundTrip(t *testing.T) {
+	e, batches := stubExporter(seededGraph(), 2)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	tables, err := models.ResolveExportTables([]string{"HAS", "host", "port"})
+	require.NoError(t, err)
+
+	exported, err := e.Export(context.Background(), tables, func(record models.ExportRecord) error {
+		return encoder.Encode(record)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6, exported)
+	assert.Equal(t, 2+1+2, *batches, "a full last batch needs one more read to find the end")
+
+	// Re-parse the JSON lines output
+	var records []models.ExportRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record models.ExportRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), scanner.Text())
+		records = append(records, record)
+	}
+	require.Len(t, records, 6)
+
+	var ids []string
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	assert.Equal(t, []string{"host:a", "host:b", "host:c", "port:port_22_tcp", "HAS:e1", "HAS:e2"}, ids,
+		"nodes come before edges, each table in id order")
+
+	host := records[0]
+	assert.Equal(t, models.ExportKindNode, host.Kind)
+	assert.Equal(t, map[string]interface{}{"ip": "192.0.2.1", "tags": []interface{}{"edge"}}, host.Data)
+
+	edge := records[4]
+	assert.Equal(t, models.ExportKindEdge, edge.Kind)
+	assert.Equal(t, "host:a", edge.In)
+	assert.Equal(t, "port:port_22_tcp", edge.Out)
+	assert.Equal(t, map[string]interface{}{"first_seen": "2026-01-01T00:00:00Z"}, edge.Data)
+}
+
+func TestGraphExporter_StopsOnEmitError(t *testing.T) {
+	e, _ := stubExporter(seededGraph(), 10)
+
+	errClosed := errors.New("client went away")
+	exported, err := e.Export(context.Background(), []string{"host"}, func(record models.ExportRecord) error {
+		if record.ID == "host:b" {
+			return errClosed
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errClosed)
+	assert.Equal(t, 1, exported)
+}
+
+func TestGraphExporter_RejectsNonGraphTable(t *testing.T) {
+	e, _ := stubExporter(nil, 10)
+
+	_, err := e.Export(context.Background(), []string{"job"}, func(models.ExportRecord) error { return nil })
+	assert.ErrorContains(t, err, "not a graph table")
+}
+
+func TestExportRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		after interface{}
+		want  string
+	}{
+		{name: "first batch", want: "host:.."},
+		{name: "plain key", after: rid("host", "ip4_192_0_2_1"), want: "host:ip4_192_0_2_1>.."},
+		{name: "escaped key", after: &surrealmodels.RecordID{Table: "host", ID: "192.0.2.1"}, want: "host:⟨192.0.2.1⟩>.."},
+		{name: "numeric key", after: surrealmodels.NewRecordID("host", uint64(42)), want: "host:42>.."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exportRange("host", tt.after)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := exportRange("host", surrealmodels.NewRecordID("host", []interface{}{1, 2}))
+	assert.Error(t, err, "array keys have no range form")
+}
+
+func TestResolveExportTables(t *testing.T) {
+	all, err := models.ResolveExportTables(nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(models.ExportNodeTables)+len(models.ExportEdgeTables), len(all))
+	assert.Equal(t, "host", all[0])
+
+	tables, err := models.ResolveExportTables([]string{"RUNS", " service ", "RUNS"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service", "RUNS"}, tables)
+
+	_, err = models.ResolveExportTables([]string{"host", "job"})
+	assert.ErrorContains(t, err, `unknown table "job"`)
+}
+
+func TestGraphExporter_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	_, err := surrealdb.Query[interface{}](ctx, db, `
+		DELETE host; DELETE port; DELETE HAS;
+		CREATE host:export_a SET ip = '192.0.2.1';
+		CREATE host:export_b SET ip = '192.0.2.2';
+		CREATE host:export_c SET ip = '192.0.2.3';
+		CREATE port:port_22_tcp SET number = 22, protocol = 'tcp';
+		RELATE host:export_a->HAS->port:port_22_tcp;
+		RELATE host:export_b->HAS->port:port_22_tcp;
+	`, nil)
+	require.NoError(t, err)
+
+	e := NewGraphExporter(db, nil)
+	e.batchSize = 2
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	exported, err := e.Export(ctx, []string{"host", "port", "HAS"}, func(record models.ExportRecord) error {
+		return encoder.Encode(record)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6, exported)
+
+	byTable := map[string]int{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record models.ExportRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		byTable[record.Table]++
+		if record.Table == "HAS" {
+			assert.Contains(t, record.In, "host:export_")
+			assert.Equal(t, "port:port_22_tcp", record.Out)
+		}
+	}
+	assert.Equal(t, map[string]int{"host": 3, "port": 1, "HAS": 2}, byTable)
+}
@@ -1,29 +1,92 @@
 package db
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/tracing"
 	"github.com/surrealdb/surrealdb.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// DefaultMaxQueryDuration bounds how long a single graph query may run,
+// regardless of the caller's context
+const DefaultMaxQueryDuration = 10 * time.Second
+
 // GraphQueryExecutor handles graph traversal queries against SurrealDB
 type GraphQueryExecutor struct {
-	db     *surrealdb.DB
-	logger *zap.Logger
+	db               *surrealdb.DB
+	logger           *zap.Logger
+	maxQueryDuration time.Duration
+
+	// cache, when set, serves repeated requests until their TTL or the next ingest
+	cache *GraphQueryCache
+
+	// hosts runs validated host queries
+	hosts HostQuerier
 }
 
-// NewGraphQueryExecutor creates a new graph query executor
+// HostQuerier runs a validated host query, returning a page of hosts and the total
+type HostQuerier interface {
+	QueryHosts(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error)
+}
+
+// NewGraphQueryExecutor creates a new graph query executor with the default maximum query duration
 func NewGraphQueryExecutor(db *surrealdb.DB, logger *zap.Logger) *GraphQueryExecutor {
+	return NewGraphQueryExecutorWithTimeout(db, logger, DefaultMaxQueryDuration)
+}
+
+// NewGraphQueryExecutorWithTimeout creates a new graph query executor whose queries are cut off
+// after maxQueryDuration, even if the caller's context has a later deadline or none at all.
+// A non-positive maxQueryDuration uses DefaultMaxQueryDuration.
+func NewGraphQueryExecutorWithTimeout(db *surrealdb.DB, logger *zap.Logger, maxQueryDuration time.Duration) *GraphQueryExecutor {
+	e := newGraphQueryExecutor(db, logger, maxQueryDuration)
+	e.hosts = surrealHostQuerier{executor: e}
+	return e
+}
+
+// NewGraphQueryExecutorWithQuerier creates a graph query executor whose host
+// queries go to querier instead of the database (useful for testing)
+func NewGraphQueryExecutorWithQuerier(querier HostQuerier, logger *zap.Logger, maxQueryDuration time.Duration) *GraphQueryExecutor {
+	e := newGraphQueryExecutor(nil, logger, maxQueryDuration)
+	e.hosts = querier
+	return e
+}
+
+// newGraphQueryExecutor creates an executor without a host querier
+func newGraphQueryExecutor(db *surrealdb.DB, logger *zap.Logger, maxQueryDuration time.Duration) *GraphQueryExecutor {
+	if maxQueryDuration <= 0 {
+		maxQueryDuration = DefaultMaxQueryDuration
+	}
 	return &GraphQueryExecutor{
-		db:     db,
-		logger: logger,
+		db:               db,
+		logger:           logger,
+		maxQueryDuration: maxQueryDuration,
 	}
 }
 
+// WithCache serves repeated graph queries from cache; a nil cache disables caching
+func (e *GraphQueryExecutor) WithCache(cache *GraphQueryCache) *GraphQueryExecutor {
+	e.cache = cache
+	return e
+}
+
+// MaxQueryDuration returns the server-side deadline applied to each query
+func (e *GraphQueryExecutor) MaxQueryDuration() time.Duration {
+	return e.maxQueryDuration
+}
+
 // ExecuteGraphQuery executes a graph traversal query based on the query type
 func (e *GraphQueryExecutor) ExecuteGraphQuery(ctx context.Context, req models.GraphQueryRequest) (*models.GraphQueryResponse, error) {
 	startTime := time.Now()
@@ -33,33 +96,27 @@ func (e *GraphQueryExecutor) ExecuteGraphQuery(ctx context.Context, req models.G
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
-	// Add timeout to context if not already set
-	_, hasDeadline := ctx.Deadline()
-	if !hasDeadline {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-	}
-
-	// Execute query based on type
-	var results []models.HostResult
-	var total int
-	var err error
-
-	switch req.QueryType {
-	case models.QueryByASN:
-		results, total, err = e.queryByASN(ctx, *req.ASN, req.Limit, req.Offset)
-	case models.QueryByLocation:
-		results, total, err = e.queryByLocation(ctx, req.City, req.Region, req.Country, req.Limit, req.Offset)
-	case models.QueryByVuln:
-		results, total, err = e.queryByVuln(ctx, req.CVE, req.Limit, req.Offset)
-	case models.QueryByService:
-		results, total, err = e.queryByService(ctx, req.Product, req.Service, req.Limit, req.Offset)
-	default:
-		return nil, fmt.Errorf("unsupported query type: %s", req.QueryType)
-	}
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
 
+	queryCtx, span := tracing.Start(ctx, "db.GraphQuery",
+		attribute.String("query_type", string(req.QueryType)),
+		attribute.Int("limit", req.Limit),
+		attribute.Int("offset", req.Offset))
+	results, total, err := e.cachedQuery(queryCtx, req)
+	span.SetAttributes(attribute.Int("results", len(results)), attribute.Int("total", total))
+	tracing.End(span, err)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			e.logger.Warn("graph query cut off at deadline",
+				zap.String("query_type", string(req.QueryType)),
+				zap.Duration("max_query_duration", e.maxQueryDuration),
+				zap.Duration("elapsed", time.Since(startTime)))
+			if !errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+			}
+		}
 		return nil, err
 	}
 
@@ -94,14 +151,136 @@ func (e *GraphQueryExecutor) ExecuteGraphQuery(ctx context.Context, req models.G
 	}, nil
 }
 
+// cachedQuery serves the request from the cache when possible, otherwise runs it
+// and caches the result
+func (e *GraphQueryExecutor) cachedQuery(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	if e.cache == nil {
+		return e.hosts.QueryHosts(ctx, req)
+	}
+
+	key := graphCacheKey(req)
+	if key == "" {
+		return e.hosts.QueryHosts(ctx, req)
+	}
+	if results, total, ok := e.cache.get(key); ok {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache_hit", true))
+		return results, total, nil
+	}
+
+	// Read the epoch before querying so an ingest that lands mid-query keeps
+	// the possibly stale result out of the cache
+	epoch := e.cache.Epoch()
+	results, total, err := e.hosts.QueryHosts(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	e.cache.set(key, epoch, results, total)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache_hit", false))
+	return results, total, nil
+}
+
+// surrealHostQuerier runs host queries with the executor's per-type SurrealDB queries
+type surrealHostQuerier struct {
+	executor *GraphQueryExecutor
+}
+
+// QueryHosts dispatches the request to its per-type query
+func (q surrealHostQuerier) QueryHosts(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	return q.executor.dispatchQuery(ctx, req)
+}
+
+// dispatchQuery executes the query matching the request type
+func (e *GraphQueryExecutor) dispatchQuery(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	window := windowOf(req)
+	switch req.QueryType {
+	case models.QueryByASN:
+		return e.queryByASN(ctx, *req.ASN, window, req.Limit, req.Offset, req.Stable)
+	case models.QueryByLocation:
+		return e.queryByLocation(ctx, req.City, req.Region, req.Country, window, req.Limit, req.Offset, req.Stable)
+	case models.QueryByVuln:
+		return e.queryByVuln(ctx, req.CVE, window, req.Limit, req.Offset, req.Stable)
+	case models.QueryByService:
+		if req.VersionConstraint != "" {
+			return e.queryByServiceVersion(ctx, req.Product, req.Service, req.VersionConstraint, window, req.Limit, req.Offset, req.Stable)
+		}
+		return e.queryByService(ctx, req.Product, req.Service, window, req.Limit, req.Offset, req.Stable)
+	case models.QueryByKEV:
+		return e.queryByKEV(ctx, window, req.Limit, req.Offset, req.Stable)
+	case models.QueryRelated:
+		return e.queryRelated(ctx, req.SeedIP, req.Relations, window, req.Limit, req.Offset, req.Stable)
+	default:
+		return nil, 0, fmt.Errorf("unsupported query type: %s", req.QueryType)
+	}
+}
+
+// stableOrder is the ordering a stable request imposes on every query type
+const stableOrder = "ORDER BY id"
+
+// orderClause returns stableOrder for a stable request, otherwise the query type's
+// own ordering, which may be empty
+func orderClause(stable bool, natural string) string {
+	if stable {
+		return stableOrder
+	}
+	return natural
+}
+
+// timeWindow bounds when the hosts a query returns were first and last seen;
+// nil bounds are open
+type timeWindow struct {
+	firstSeenAfter *time.Time
+	lastSeenAfter  *time.Time
+	lastSeenBefore *time.Time
+}
+
+// windowOf returns the request's time window
+func windowOf(req models.GraphQueryRequest) timeWindow {
+	return timeWindow{
+		firstSeenAfter: req.FirstSeenAfter,
+		lastSeenAfter:  req.LastSeenAfter,
+		lastSeenBefore: req.LastSeenBefore,
+	}
+}
+
+// isOpen reports whether the window leaves every host in
+func (w timeWindow) isOpen() bool {
+	return w.firstSeenAfter == nil && w.lastSeenAfter == nil && w.lastSeenBefore == nil
+}
+
+// clause returns the host predicates for the window, each prefixed with AND so
+// they can follow any WHERE clause, and adds the bounds to params. An open
+// window returns an empty clause.
+func (w timeWindow) clause(params map[string]interface{}) string {
+	var predicates []string
+	if w.firstSeenAfter != nil {
+		predicates = append(predicates, "AND first_seen >= $first_seen_after")
+		params["first_seen_after"] = w.firstSeenAfter.UTC()
+	}
+	if w.lastSeenAfter != nil {
+		predicates = append(predicates, "AND last_seen >= $last_seen_after")
+		params["last_seen_after"] = w.lastSeenAfter.UTC()
+	}
+	if w.lastSeenBefore != nil {
+		predicates = append(predicates, "AND last_seen < $last_seen_before")
+		params["last_seen_before"] = w.lastSeenBefore.UTC()
+	}
+	return strings.Join(predicates, " ")
+}
+
 // queryByASN returns all hosts in a given ASN
-func (e *GraphQueryExecutor) queryByASN(ctx context.Context, asn, limit, offset int) ([]models.HostResult, int, error) {
+func (e *GraphQueryExecutor) queryByASN(ctx context.Context, asn int, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
 	e.logger.Debug("executing ASN query",
 		zap.Int("asn", asn),
 		zap.Int("limit", limit),
 		zap.Int("offset", offset))
 
-	query := `
+	params := map[string]interface{}{
+		"asn":    asn,
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	query := fmt.Sprintf(`
 		SELECT
 			id,
 			ip,
@@ -112,17 +291,11 @@ func (e *GraphQueryExecutor) queryByASN(ctx context.Context, asn, limit, offset
 			last_seen,
 			first_seen
 		FROM host
-		WHERE asn = $asn
-		ORDER BY last_seen DESC
+		WHERE asn = $asn %s
+		%s
 		LIMIT $limit
 		START $offset
-	`
-
-	params := map[string]interface{}{
-		"asn":    asn,
-		"limit":  limit,
-		"offset": offset,
-	}
+	`, window.clause(params), orderClause(stable, "ORDER BY last_seen DESC"))
 
 	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, query, params)
 	if err != nil {
@@ -139,7 +312,7 @@ func (e *GraphQueryExecutor) queryByASN(ctx context.Context, asn, limit, offset
 }
 
 // queryByLocation returns all hosts in a given location
-func (e *GraphQueryExecutor) queryByLocation(ctx context.Context, city, region, country string, limit, offset int) ([]models.HostResult, int, error) {
+func (e *GraphQueryExecutor) queryByLocation(ctx context.Context, city, region, country string, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
 	e.logger.Debug("executing location query",
 		zap.String("city", city),
 		zap.String("region", region),
@@ -173,11 +346,11 @@ func (e *GraphQueryExecutor) queryByLocation(ctx context.Context, city, region,
 			last_seen,
 			first_seen
 		FROM host
+		%s %s
 		%s
-		ORDER BY last_seen DESC
 		LIMIT $limit
 		START $offset
-	`, whereClause)
+	`, whereClause, window.clause(params), orderClause(stable, "ORDER BY last_seen DESC"))
 
 	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, query, params)
 	if err != nil {
@@ -192,11 +365,17 @@ func (e *GraphQueryExecutor) queryByLocation(ctx context.Context, city, region,
 }
 
 // queryByVuln returns all hosts affected by a given vulnerability
-func (e *GraphQueryExecutor) queryByVuln(ctx context.Context, cve string, limit, offset int) ([]models.HostResult, int, error) {
+func (e *GraphQueryExecutor) queryByVuln(ctx context.Context, cve string, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
 	e.logger.Debug("executing vulnerability query",
 		zap.String("cve", cve))
 
-	query := `
+	params := map[string]interface{}{
+		"cve":    cve,
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	query := fmt.Sprintf(`
 		SELECT
 			id,
 			ip,
@@ -211,16 +390,11 @@ func (e *GraphQueryExecutor) queryByVuln(ctx context.Context, cve string, limit,
 			SELECT VALUE <-HAS<-port<-RUNS<-service<-AFFECTED_BY<-vuln.id
 			FROM vuln
 			WHERE cve = $cve
-		)
+		) %s
+		%s
 		LIMIT $limit
 		START $offset
-	`
-
-	params := map[string]interface{}{
-		"cve":    cve,
-		"limit":  limit,
-		"offset": offset,
-	}
+	`, window.clause(params), orderClause(stable, ""))
 
 	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, query, params)
 	if err != nil {
@@ -235,7 +409,7 @@ func (e *GraphQueryExecutor) queryByVuln(ctx context.Context, cve string, limit,
 }
 
 // queryByService returns all hosts running a given service
-func (e *GraphQueryExecutor) queryByService(ctx context.Context, product, serviceName string, limit, offset int) ([]models.HostResult, int, error) {
+func (e *GraphQueryExecutor) queryByService(ctx context.Context, product, serviceName string, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
 	e.logger.Debug("executing service query",
 		zap.String("product", product),
 		zap.String("service", serviceName))
@@ -269,10 +443,11 @@ func (e *GraphQueryExecutor) queryByService(ctx context.Context, product, servic
 			SELECT VALUE <-HAS<-port<-RUNS<-service.id
 			FROM service
 			%s
-		)
+		) %s
+		%s
 		LIMIT $limit
 		START $offset
-	`, whereClause)
+	`, whereClause, window.clause(params), orderClause(stable, ""))
 
 	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, query, params)
 	if err != nil {
@@ -286,6 +461,781 @@ func (e *GraphQueryExecutor) queryByService(ctx context.Context, product, servic
 	return hosts, total, nil
 }
 
+// serviceVersionHosts is a service's version with the IPs of the hosts running it
+type serviceVersionHosts struct {
+	Version string   `json:"version"`
+	Hosts   []string `json:"hosts"`
+}
+
+// queryByServiceVersion returns the hosts running a given service at a version
+// satisfying constraint. SurrealQL cannot compare version strings, so the
+// matching services are fetched with their hosts and filtered here.
+func (e *GraphQueryExecutor) queryByServiceVersion(ctx context.Context, product, serviceName, constraint string, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
+	e.logger.Debug("executing service version query",
+		zap.String("product", product),
+		zap.String("service", serviceName),
+		zap.String("version_constraint", constraint))
+
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	var whereClause string
+	params := map[string]interface{}{}
+	if product != "" {
+		whereClause = "WHERE product = $product"
+		params["product"] = product
+	} else {
+		whereClause = "WHERE name = $service"
+		params["service"] = serviceName
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			version,
+			array::distinct(<-RUNS<-port<-HAS<-host.ip) AS hosts
+		FROM service
+		%s AND version != NONE
+	`, whereClause)
+
+	result, err := surrealdb.Query[[]serviceVersionHosts](ctx, e.db, query, params)
+	if err != nil {
+		e.logger.Error("failed to execute service version query", zap.Error(err))
+		return nil, 0, fmt.Errorf("failed to query by service version: %w", err)
+	}
+
+	var services []serviceVersionHosts
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil {
+		services = (*result)[0].Result
+	}
+
+	ips := hostsMatchingVersion(services, constraints)
+	if !window.isOpen() && len(ips) > 0 {
+		// Narrow the matches to the window first so the total counts only hosts in it
+		if ips, err = e.hostsInWindow(ctx, ips, window); err != nil {
+			return nil, 0, fmt.Errorf("failed to query by service version: %w", err)
+		}
+	}
+	total := len(ips)
+	if offset >= total {
+		return []models.HostResult{}, total, nil
+	}
+
+	hostResult, err := surrealdb.Query[[]models.HostResult](ctx, e.db, fmt.Sprintf(`
+		SELECT
+			id,
+			ip,
+			asn,
+			city,
+			region,
+			country,
+			last_seen,
+			first_seen
+		FROM host
+		WHERE ip IN $ips
+		%s
+		LIMIT $limit
+		START $offset
+	`, orderClause(stable, "ORDER BY ip")), map[string]interface{}{
+		"ips":    ips,
+		"limit":  limit,
+		"offset": offset,
+	})
+	if err != nil {
+		e.logger.Error("failed to fetch hosts for service version query", zap.Error(err))
+		return nil, 0, fmt.Errorf("failed to query by service version: %w", err)
+	}
+
+	return extractHostResults(hostResult), total, nil
+}
+
+// hostsInWindow returns the IPs, sorted, of the hosts in ips that fall within window
+func (e *GraphQueryExecutor) hostsInWindow(ctx context.Context, ips []string, window timeWindow) ([]string, error) {
+	params := map[string]interface{}{
+		"ips": ips,
+	}
+	query := fmt.Sprintf(`
+		SELECT VALUE ip FROM host
+		WHERE ip IN $ips %s
+		ORDER BY ip
+	`, window.clause(params))
+
+	result, err := surrealdb.Query[[]string](ctx, e.db, query, params)
+	if err != nil {
+		e.logger.Error("failed to filter hosts by time window", zap.Error(err))
+		return nil, err
+	}
+	if result == nil || len(*result) == 0 || (*result)[0].Error != nil || (*result)[0].Result == nil {
+		return []string{}, nil
+	}
+	return (*result)[0].Result, nil
+}
+
+// hostsMatchingVersion returns the distinct IPs, sorted, of hosts running a service
+// whose version satisfies constraints. Trailing banner detail such as "1.18.0 (Ubuntu)"
+// is ignored; versions that still do not parse never match.
+func hostsMatchingVersion(services []serviceVersionHosts, constraints version.Constraints) []string {
+	seen := make(map[string]bool)
+	ips := []string{}
+	for _, service := range services {
+		fields := strings.Fields(service.Version)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := version.NewVersion(fields[0])
+		if err != nil || !constraints.Check(v) {
+			continue
+		}
+		for _, ip := range service.Hosts {
+			if !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// queryByKEV returns all hosts affected by at least one CVE in the CISA Known
+// Exploited Vulnerabilities catalog. Each host appears once, ordered by the
+// highest CVSS score among its KEV-listed CVEs.
+func (e *GraphQueryExecutor) queryByKEV(ctx context.Context, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
+	e.logger.Debug("executing KEV query")
+
+	params := map[string]interface{}{
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id,
+			ip,
+			asn,
+			city,
+			region,
+			country,
+			last_seen,
+			first_seen,
+			math::max(array::flatten(->HAS->port->RUNS->service->AFFECTED_BY->(vuln WHERE kev_flag = true).cvss)) AS max_cvss
+		FROM host
+		WHERE id IN array::flatten((
+			SELECT VALUE <-AFFECTED_BY<-service<-RUNS<-port<-HAS<-host
+			FROM vuln
+			WHERE kev_flag = true
+		)) %s
+		%s
+		LIMIT $limit
+		START $offset
+	`, window.clause(params), orderClause(stable, "ORDER BY max_cvss DESC"))
+
+	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, query, params)
+	if err != nil {
+		e.logger.Error("failed to execute KEV query", zap.Error(err))
+		return nil, 0, fmt.Errorf("failed to query by KEV: %w", err)
+	}
+
+	hosts := extractHostResults(result)
+	total := len(hosts)
+
+	return hosts, total, nil
+}
+
+// QueryAggregateByASN returns host and open port counts per ASN, sorted by host count descending
+func (e *GraphQueryExecutor) QueryAggregateByASN(ctx context.Context, limit int) (*models.ASNAggregateResponse, error) {
+	startTime := time.Now()
+
+	if limit <= 0 {
+		limit = models.DefaultAggregateLimit
+	}
+	if limit > models.MaxLimit {
+		limit = models.MaxLimit
+	}
+
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
+
+	e.logger.Debug("executing ASN aggregate query",
+		zap.Int("limit", limit))
+
+	query := `
+		SELECT
+			asn,
+			count() AS host_count,
+			math::sum(array::len(->HAS->port)) AS port_count
+		FROM host
+		WHERE asn != NONE AND asn > 0
+		GROUP BY asn
+		ORDER BY host_count DESC
+		LIMIT $limit
+	`
+
+	result, err := surrealdb.Query[[]models.ASNAggregate](ctx, e.db, query, map[string]interface{}{
+		"limit": limit,
+	})
+	if err != nil {
+		e.logger.Error("failed to execute ASN aggregate query", zap.Error(err))
+		return nil, fmt.Errorf("failed to aggregate by ASN: %w", err)
+	}
+
+	aggregates := []models.ASNAggregate{}
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil && (*result)[0].Result != nil {
+		aggregates = (*result)[0].Result
+	}
+
+	// Look up organization names from the asn nodes
+	if len(aggregates) > 0 {
+		numbers := make([]int, len(aggregates))
+		for i, agg := range aggregates {
+			numbers[i] = agg.ASN
+		}
+
+		orgQuery := `SELECT number, org FROM asn WHERE number IN $numbers`
+		orgResult, err := surrealdb.Query[[]struct {
+			Number int    `json:"number"`
+			Org    string `json:"org"`
+		}](ctx, e.db, orgQuery, map[string]interface{}{
+			"numbers": numbers,
+		})
+		if err != nil {
+			// Org names are informational, so return counts without them
+			e.logger.Warn("failed to look up ASN organizations", zap.Error(err))
+		} else if orgResult != nil && len(*orgResult) > 0 {
+			orgs := make(map[int]string)
+			for _, row := range (*orgResult)[0].Result {
+				orgs[row.Number] = row.Org
+			}
+			for i := range aggregates {
+				aggregates[i].Org = orgs[aggregates[i].ASN]
+			}
+		}
+	}
+
+	// Keep ordering deterministic when host counts tie
+	sort.SliceStable(aggregates, func(i, j int) bool {
+		if aggregates[i].HostCount != aggregates[j].HostCount {
+			return aggregates[i].HostCount > aggregates[j].HostCount
+		}
+		return aggregates[i].ASN < aggregates[j].ASN
+	})
+
+	return &models.ASNAggregateResponse{
+		Results:   aggregates,
+		Limit:     limit,
+		QueryTime: time.Since(startTime).Seconds() * 1000,
+	}, nil
+}
+
+// QueryUnidentifiedServices returns the raw banners of services with no product and no
+// CPE, grouped with their service counts and sorted most common first, so the banner
+// patterns worth adding can be prioritized. Banners are the ones ingest stored as the
+// services' EVIDENCED_BY evidence.
+func (e *GraphQueryExecutor) QueryUnidentifiedServices(ctx context.Context, limit int) (*models.UnidentifiedServicesResponse, error) {
+	startTime := time.Now()
+
+	if limit <= 0 {
+		limit = models.DefaultAggregateLimit
+	}
+	if limit > models.MaxLimit {
+		limit = models.MaxLimit
+	}
+
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
+
+	e.logger.Debug("executing unidentified services query",
+		zap.Int("limit", limit))
+
+	query := `
+		SELECT
+			banner,
+			count() AS service_count
+		FROM (
+			SELECT out.sample AS banner
+			FROM EVIDENCED_BY
+			WHERE evidence_type = 'banner'
+				AND (in.product = NONE OR in.product = "")
+				AND (in.cpe = NONE OR array::len(in.cpe) = 0)
+		)
+		GROUP BY banner
+		ORDER BY service_count DESC
+		LIMIT $limit
+	`
+
+	result, err := surrealdb.Query[[]models.UnidentifiedBanner](ctx, e.db, query, map[string]interface{}{
+		"limit": limit,
+	})
+	if err != nil {
+		e.logger.Error("failed to execute unidentified services query", zap.Error(err))
+		return nil, fmt.Errorf("failed to query unidentified services: %w", err)
+	}
+
+	banners := []models.UnidentifiedBanner{}
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil && (*result)[0].Result != nil {
+		banners = (*result)[0].Result
+	}
+
+	// Keep ordering deterministic when counts tie
+	sort.SliceStable(banners, func(i, j int) bool {
+		if banners[i].ServiceCount != banners[j].ServiceCount {
+			return banners[i].ServiceCount > banners[j].ServiceCount
+		}
+		return banners[i].Banner < banners[j].Banner
+	})
+
+	return &models.UnidentifiedServicesResponse{
+		Results:   banners,
+		Limit:     limit,
+		QueryTime: time.Since(startTime).Seconds() * 1000,
+	}, nil
+}
+
+// QueryByCPE returns the services whose cpe list contains cpe, with the hosts running
+// them and the CVEs linked through AFFECTED_BY. It is meant for tracing a CPE-based
+// match back to the service records (and banners) that produced it.
+func (e *GraphQueryExecutor) QueryByCPE(ctx context.Context, cpe string, limit int) (*models.CPELookupResponse, error) {
+	startTime := time.Now()
+
+	if limit <= 0 {
+		limit = models.DefaultLimit
+	}
+	if limit > models.MaxLimit {
+		limit = models.MaxLimit
+	}
+
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
+
+	e.logger.Debug("executing CPE lookup",
+		zap.String("cpe", cpe),
+		zap.Int("limit", limit))
+
+	query := `
+		SELECT
+			meta::id(id) AS service_id,
+			name,
+			product,
+			version,
+			cpe,
+			array::distinct(<-RUNS<-port<-HAS<-host.ip) AS hosts,
+			(SELECT out.cve_id AS cve_id, out.cvss AS cvss, out.severity AS severity, confidence
+				FROM ->AFFECTED_BY ORDER BY cvss DESC) AS vulnerabilities
+		FROM service
+		WHERE cpe CONTAINS $cpe
+		ORDER BY service_id
+		LIMIT $limit
+	`
+
+	result, err := surrealdb.Query[[]models.CPEServiceMatch](ctx, e.db, query, map[string]interface{}{
+		"cpe":   cpe,
+		"limit": limit,
+	})
+	if err != nil {
+		e.logger.Error("failed to execute CPE lookup", zap.Error(err))
+		return nil, fmt.Errorf("failed to query by CPE: %w", err)
+	}
+
+	services := []models.CPEServiceMatch{}
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil && (*result)[0].Result != nil {
+		services = (*result)[0].Result
+	}
+
+	return &models.CPELookupResponse{
+		CPE:       cpe,
+		Services:  services,
+		Limit:     limit,
+		QueryTime: time.Since(startTime).Seconds() * 1000,
+	}, nil
+}
+
+// QueryByCPEPrefix returns the services with a CPE matching prefix, such as every
+// apache product for cpe:2.3:a:apache:*, with the hosts running them and the CVEs
+// linked through AFFECTED_BY. Services are narrowed by the prefix's leading literal
+// components and then matched component by component, so a wildcard in the middle
+// of the prefix (cpe:2.3:a:*:log4j) works too.
+func (e *GraphQueryExecutor) QueryByCPEPrefix(ctx context.Context, prefix string, limit int) (*models.CPEPrefixResponse, error) {
+	startTime := time.Now()
+
+	pattern, err := models.ParseCPEPrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if limit <= 0 {
+		limit = models.DefaultLimit
+	}
+	if limit > models.MaxLimit {
+		limit = models.MaxLimit
+	}
+
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
+
+	e.logger.Debug("executing CPE prefix lookup",
+		zap.String("prefix", pattern.Prefix),
+		zap.Strings("components", pattern.Components),
+		zap.Int("limit", limit))
+
+	// A literal prefix matches exactly in the database, which can apply the limit;
+	// otherwise the limit waits for the component match below, and the candidates
+	// read for it are capped instead
+	query := `
+		SELECT
+			meta::id(id) AS service_id,
+			name,
+			product,
+			version,
+			cpe,
+			array::distinct(<-RUNS<-port<-HAS<-host.ip) AS hosts,
+			(SELECT out.cve_id AS cve_id, out.cvss AS cvss, out.severity AS severity, confidence
+				FROM ->AFFECTED_BY ORDER BY cvss DESC) AS vulnerabilities
+		FROM service
+		WHERE cpe != NONE AND count(cpe[WHERE string::starts_with($this, $prefix)]) > 0
+		ORDER BY service_id
+	`
+	if pattern.Literal() {
+		query += "LIMIT $limit"
+	} else {
+		query += "LIMIT $scan"
+	}
+
+	result, err := surrealdb.Query[[]models.CPEServiceMatch](ctx, e.db, query, map[string]interface{}{
+		"prefix": pattern.Prefix,
+		"limit":  limit,
+		"scan":   models.MaxCPEPrefixScan,
+	})
+	if err != nil {
+		e.logger.Error("failed to execute CPE prefix lookup", zap.Error(err))
+		return nil, fmt.Errorf("failed to query by CPE prefix: %w", err)
+	}
+
+	var rows []models.CPEServiceMatch
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil {
+		rows = (*result)[0].Result
+	}
+
+	services, hosts := matchCPEPattern(rows, pattern, limit)
+	return &models.CPEPrefixResponse{
+		Prefix:    pattern.Prefix,
+		Services:  services,
+		Hosts:     hosts,
+		Limit:     limit,
+		Truncated: !pattern.Literal() && len(rows) >= models.MaxCPEPrefixScan && len(services) < limit,
+		QueryTime: time.Since(startTime).Seconds() * 1000,
+	}, nil
+}
+
+// matchCPEPattern keeps at most limit services with a CPE matching pattern and
+// returns them with the distinct IPs of the hosts running them, in address order
+func matchCPEPattern(rows []models.CPEServiceMatch, pattern models.CPEPattern, limit int) ([]models.CPEServiceMatch, []string) {
+	services := []models.CPEServiceMatch{}
+	ips := make(map[string]net.IP)
+	for _, row := range rows {
+		if len(services) == limit {
+			break
+		}
+		if !slices.ContainsFunc(row.CPEs, pattern.Matches) {
+			continue
+		}
+		services = append(services, row)
+		for _, host := range row.Hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				ips[ip.String()] = ip
+			}
+		}
+	}
+
+	sorted := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		sorted = append(sorted, ip)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].To16(), sorted[j].To16()) < 0
+	})
+
+	hosts := make([]string, 0, len(sorted))
+	for _, ip := range sorted {
+		hosts = append(hosts, ip.String())
+	}
+	return services, hosts
+}
+
+// exposedPortRow is a port number with the public hosts that have it open
+type exposedPortRow struct {
+	Number    int      `json:"number"`
+	HostCount int      `json:"host_count"`
+	Hosts     []string `json:"hosts"`
+}
+
+// nonPublicIPPattern matches addresses that are not routable on the internet:
+// the private, loopback, link-local, multicast and unspecified ranges ingest
+// rejects, plus shared CGNAT space (100.64.0.0/10) and the documentation ranges
+// (192.0.2.0/24, 198.51.100.0/24, 203.0.113.0/24, 2001:db8::/32). IPv4 ranges
+// also match in IPv4-mapped form. It is a regex so the database can apply it.
+const nonPublicIPPattern = `(?i)^(?:` +
+	`(?:::ffff:)?(?:0|10|127|2(?:2[4-9]|3\d)|100\.(?:6[4-9]|[7-9]\d|1[01]\d|12[0-7])|169\.254|172\.(?:1[6-9]|2\d|3[01])|192\.168|192\.0\.2|198\.51\.100|203\.0\.113)\.` +
+	`|::1?$` +
+	`|f[cd][0-9a-f]{2}:|fe[89ab][0-9a-f]:|ff[0-9a-f]{2}:` +
+	`|2001:0*db8:` +
+	`)`
+
+// QueryExposure returns the public hosts exposing any of ports, grouped by port with
+// the port exposed by most hosts first. Empty ports uses models.DefaultRiskyPorts.
+// Hosts with private or otherwise non-routable IPs are not exposed to the internet
+// and are left out; limit caps the hosts listed per port, not the counts. The
+// database counts the hosts and lists at most limit of each port, in IP string order.
+func (e *GraphQueryExecutor) QueryExposure(ctx context.Context, ports []int, limit int) (*models.ExposureResponse, error) {
+	startTime := time.Now()
+
+	if len(ports) == 0 {
+		ports = models.DefaultRiskyPorts()
+	}
+	if limit <= 0 {
+		limit = models.DefaultLimit
+	}
+	if limit > models.MaxLimit {
+		limit = models.MaxLimit
+	}
+
+	// Apply the server-side deadline; a shorter caller deadline still wins
+	ctx, cancel := context.WithTimeout(ctx, e.maxQueryDuration)
+	defer cancel()
+
+	e.logger.Debug("executing exposure query",
+		zap.Ints("ports", ports),
+		zap.Int("limit", limit))
+
+	// Port records can be shared between hosts and split by protocol, so the
+	// hosts of every record with a number are merged, each listed once
+	query := `
+		SELECT
+			number,
+			array::len(ips) AS host_count,
+			array::slice(array::sort(ips), 0, $limit) AS hosts
+		FROM (
+			SELECT
+				number,
+				array::group((<-HAS<-host.ip)[WHERE !string::matches($this, $non_public)]) AS ips
+			FROM port
+			WHERE number IN $ports
+				AND (state = NONE OR state = "open")
+			GROUP BY number
+		)
+		WHERE array::len(ips) > 0
+		ORDER BY host_count DESC, number ASC;
+	`
+
+	result, err := surrealdb.Query[[]exposedPortRow](ctx, e.db, query, map[string]interface{}{
+		"ports":      ports,
+		"limit":      limit,
+		"non_public": nonPublicIPPattern,
+	})
+	if err != nil {
+		e.logger.Error("failed to execute exposure query", zap.Error(err))
+		return nil, fmt.Errorf("failed to query exposure: %w", err)
+	}
+
+	groups := make([]models.ExposureGroup, 0)
+	if result != nil && len(*result) > 0 && (*result)[0].Error == nil {
+		for _, row := range (*result)[0].Result {
+			groups = append(groups, models.ExposureGroup{
+				Port:      row.Number,
+				Service:   models.RiskyPorts[row.Number],
+				HostCount: row.HostCount,
+				Hosts:     row.Hosts,
+			})
+		}
+	}
+
+	return &models.ExposureResponse{
+		Results:   groups,
+		Ports:     ports,
+		Limit:     limit,
+		QueryTime: time.Since(startTime).Seconds() * 1000,
+	}, nil
+}
+
+// relationWeights defines how much each shared relation contributes to a related host's score
+var relationWeights = map[models.RelationKind]float64{
+	models.RelationVuln:    0.4,
+	models.RelationService: 0.3,
+	models.RelationSubnet:  0.2,
+	models.RelationASN:     0.1,
+}
+
+// maxRelatedCandidates caps the number of hosts fetched per relation kind
+const maxRelatedCandidates = models.MaxLimit
+
+// queryRelated returns hosts related to the seed host, scored by the relations they share with it
+// The time window applies to the related hosts; the seed host is found wherever it falls.
+func (e *GraphQueryExecutor) queryRelated(ctx context.Context, seedIP string, relations []models.RelationKind, window timeWindow, limit, offset int, stable bool) ([]models.HostResult, int, error) {
+	e.logger.Debug("executing related query",
+		zap.String("seed_ip", seedIP),
+		zap.Any("relations", relations))
+
+	seedQuery := `
+		SELECT
+			id,
+			ip,
+			asn,
+			city,
+			region,
+			country,
+			last_seen,
+			first_seen
+		FROM host
+		WHERE ip = $ip
+		LIMIT 1
+	`
+
+	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, seedQuery, map[string]interface{}{
+		"ip": seedIP,
+	})
+	if err != nil {
+		e.logger.Error("failed to fetch seed host", zap.Error(err), zap.String("seed_ip", seedIP))
+		return nil, 0, fmt.Errorf("failed to fetch seed host: %w", err)
+	}
+
+	seeds := extractHostResults(result)
+	if len(seeds) == 0 {
+		return []models.HostResult{}, 0, nil
+	}
+	seed := seeds[0]
+
+	// Merge the hosts found by each relation kind, accumulating their scores
+	related := make(map[string]*models.HostResult)
+	for _, kind := range relations {
+		hosts, err := e.queryRelation(ctx, seed, kind, window)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, host := range hosts {
+			if host.IP == seed.IP {
+				continue
+			}
+			entry, exists := related[host.ID]
+			if !exists {
+				h := host
+				entry = &h
+				related[host.ID] = entry
+			}
+			entry.Score += relationWeights[kind]
+			entry.Relations = append(entry.Relations, kind)
+		}
+	}
+
+	hosts := make([]models.HostResult, 0, len(related))
+	for _, host := range related {
+		hosts = append(hosts, *host)
+	}
+
+	// Highest score first, IP as a stable tie-breaker; a stable request orders by id
+	// like every other query type
+	sort.Slice(hosts, func(i, j int) bool {
+		if stable {
+			return hosts[i].ID < hosts[j].ID
+		}
+		if hosts[i].Score != hosts[j].Score {
+			return hosts[i].Score > hosts[j].Score
+		}
+		return hosts[i].IP < hosts[j].IP
+	})
+
+	total := len(hosts)
+	if offset >= total {
+		return []models.HostResult{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return hosts[offset:end], total, nil
+}
+
+// queryRelation returns the hosts related to the seed host by a single relation kind
+func (e *GraphQueryExecutor) queryRelation(ctx context.Context, seed models.HostResult, kind models.RelationKind, window timeWindow) ([]models.HostResult, error) {
+	params := map[string]interface{}{
+		"ip":    seed.IP,
+		"limit": maxRelatedCandidates,
+	}
+
+	var whereClause string
+	switch kind {
+	case models.RelationASN:
+		if seed.ASN == 0 {
+			return nil, nil
+		}
+		whereClause = "asn = $asn"
+		params["asn"] = seed.ASN
+	case models.RelationSubnet:
+		prefix := subnetPrefix(seed.IP)
+		if prefix == "" {
+			return nil, nil
+		}
+		whereClause = "string::starts_with(ip, $prefix)"
+		params["prefix"] = prefix
+	case models.RelationService:
+		whereClause = `id IN array::flatten((
+				SELECT VALUE <-RUNS<-port<-HAS<-host
+				FROM service
+				WHERE product IN array::flatten((
+					SELECT VALUE ->HAS->port->RUNS->service.product FROM host WHERE ip = $ip
+				))
+			))`
+	case models.RelationVuln:
+		whereClause = `id IN array::flatten((
+				SELECT VALUE <-AFFECTED_BY<-service<-RUNS<-port<-HAS<-host
+				FROM array::flatten((
+					SELECT VALUE ->HAS->port->RUNS->service->AFFECTED_BY->vuln FROM host WHERE ip = $ip
+				))
+			))`
+	default:
+		return nil, fmt.Errorf("unsupported relation kind: %s", kind)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id,
+			ip,
+			asn,
+			city,
+			region,
+			country,
+			last_seen,
+			first_seen
+		FROM host
+		WHERE ip != $ip AND %s %s
+		LIMIT $limit
+	`, whereClause, window.clause(params))
+
+	result, err := surrealdb.Query[[]models.HostResult](ctx, e.db, query, params)
+	if err != nil {
+		e.logger.Error("failed to execute related query",
+			zap.Error(err),
+			zap.String("relation", string(kind)))
+		return nil, fmt.Errorf("failed to query related hosts by %s: %w", kind, err)
+	}
+
+	return extractHostResults(result), nil
+}
+
+// subnetPrefix returns the /24 string prefix (e.g. "10.0.0.") for an IPv4 address,
+// or an empty string for IPv6 and invalid addresses
+func subnetPrefix(ip string) string {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d.", parsed[0], parsed[1], parsed[2])
+}
+
 // extractHostResults extracts host results from SurrealDB query response
 func extractHostResults(results *[]surrealdb.QueryResult[[]models.HostResult]) []models.HostResult {
 	if results == nil || len(*results) == 0 {
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// DefaultGraphCacheMaxEntries bounds how many graph query results are cached
+const DefaultGraphCacheMaxEntries = 1000
+
+// GraphQueryCache holds recent graph query results keyed by the normalized request
+// Entries expire after a short TTL. Bumping the epoch (done when an ingest
+// completes) invalidates every entry at once, so new hosts show up without
+// waiting for the TTL.
+type GraphQueryCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	epoch   uint64
+	entries map[string]graphCacheEntry
+
+	// now is replaced in tests to expire entries without sleeping
+	now func() time.Time
+}
+
+// graphCacheEntry is a cached page of results and the epoch it was computed in
+type graphCacheEntry struct {
+	results []models.HostResult
+	total   int
+	epoch   uint64
+	expires time.Time
+}
+
+// NewGraphQueryCache creates a cache whose entries live for ttl and which holds
+// at most maxEntries results (DefaultGraphCacheMaxEntries if non-positive)
+func NewGraphQueryCache(ttl time.Duration, maxEntries int) *GraphQueryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultGraphCacheMaxEntries
+	}
+	return &GraphQueryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]graphCacheEntry),
+		now:        time.Now,
+	}
+}
+
+// Epoch returns the current cache epoch
+func (c *GraphQueryCache) Epoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// BumpEpoch invalidates every cached result and returns the new epoch
+// Queries already in flight when the epoch moves are not cached.
+func (c *GraphQueryCache) BumpEpoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+	c.entries = make(map[string]graphCacheEntry)
+	return c.epoch
+}
+
+// Len returns the number of cached results
+func (c *GraphQueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// get returns the cached results for key if they are current
+func (c *GraphQueryCache) get(key string) ([]models.HostResult, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	if entry.epoch != c.epoch || !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, 0, false
+	}
+	return cloneHostResults(entry.results), entry.total, true
+}
+
+// set caches results for key, unless the epoch has moved on since epoch was
+// read, in which case the results may predate the latest ingest
+func (c *GraphQueryCache) set(key string, epoch uint64, results []models.HostResult, total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if epoch != c.epoch {
+		return
+	}
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = graphCacheEntry{
+		results: cloneHostResults(results),
+		total:   total,
+		epoch:   epoch,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// evict makes room for one entry: expired entries go first, otherwise the one
+// closest to expiring. Callers must hold mu.
+func (c *GraphQueryCache) evict(now time.Time) {
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expires.Before(oldest) {
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// graphCacheKey hashes a validated request, so requests that differ only in
+// relation order, time zone of the time window or omitted defaults share an
+// entry. It returns an empty key for a request that can't be cached.
+func graphCacheKey(req models.GraphQueryRequest) string {
+	if len(req.Relations) > 0 {
+		relations := append([]models.RelationKind(nil), req.Relations...)
+		sort.Slice(relations, func(i, j int) bool { return relations[i] < relations[j] })
+		req.Relations = relations
+	}
+	req.FirstSeenAfter = utcTime(req.FirstSeenAfter)
+	req.LastSeenAfter = utcTime(req.LastSeenAfter)
+	req.LastSeenBefore = utcTime(req.LastSeenBefore)
+
+	// Field order is fixed; encoding only fails for times past year 9999
+	data, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// utcTime returns a copy of t in UTC, or nil for nil
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// cloneHostResults deep-copies results, so neither the caller that cached them
+// nor one served from the cache can change the cached entry
+func cloneHostResults(results []models.HostResult) []models.HostResult {
+	cloned := make([]models.HostResult, len(results))
+	for i, host := range results {
+		host.Ports = slices.Clone(host.Ports)
+		host.Services = slices.Clone(host.Services)
+		host.Relations = slices.Clone(host.Relations)
+		cloned[i] = host
+	}
+	return cloned
+}
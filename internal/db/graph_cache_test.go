@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// countingQuerier counts host queries and answers each with one host
+type countingQuerier struct {
+	calls int
+}
+
+func (q *countingQuerier) QueryHosts(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	q.calls++
+	return []models.HostResult{{IP: "192.0.2.10"}}, 1, nil
+}
+
+// bumpingQuerier bumps cache's epoch before each query, as an ingest completing
+// while a traversal runs would
+type bumpingQuerier struct {
+	next  HostQuerier
+	cache *GraphQueryCache
+}
+
+func (q *bumpingQuerier) QueryHosts(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	q.cache.BumpEpoch()
+	return q.next.QueryHosts(ctx, req)
+}
+
+// countingExecutor returns an executor whose DB queries are counted instead of run
+func countingExecutor(cache *GraphQueryCache) (*GraphQueryExecutor, *int) {
+	querier := &countingQuerier{}
+	return NewGraphQueryExecutorWithQuerier(querier, zap.NewNop(), 0).WithCache(cache), &querier.calls
+}
+
+func redisHosts() models.GraphQueryRequest {
+	return models.GraphQueryRequest{QueryType: models.QueryByService, Product: "redis"}
+}
+
+func TestGraphQueryCache_HitSkipsDB(t *testing.T) {
+	executor, calls := countingExecutor(NewGraphQueryCache(time.Minute, 0))
+
+	first, err := executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	second, err := executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, *calls, "second identical query should be served from cache")
+	assert.Equal(t, first.Results, second.Results)
+	assert.Equal(t, first.Pagination, second.Pagination)
+
+	// Explicit defaults normalize to the same key
+	withDefaults := redisHosts()
+	withDefaults.Limit = models.DefaultLimit
+	_, err = executor.ExecuteGraphQuery(context.Background(), withDefaults)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	// A different page is a different query
+	nextPage := redisHosts()
+	nextPage.Offset = 100
+	_, err = executor.ExecuteGraphQuery(context.Background(), nextPage)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestGraphQueryCache_ResultsAreCopies(t *testing.T) {
+	cache := NewGraphQueryCache(time.Minute, 0)
+	results := []models.HostResult{{IP: "192.0.2.10", Ports: []models.Port{{Number: 22}}}}
+	cache.set("key", cache.epoch, results, 1)
+
+	// Neither the cached slice nor a served one aliases the entry
+	results[0].IP = "changed"
+	served, _, ok := cache.get("key")
+	require.True(t, ok)
+	served[0].Ports[0].Number = 80
+	served[0].IP = "changed"
+
+	again, _, ok := cache.get("key")
+	require.True(t, ok)
+	assert.Equal(t, "192.0.2.10", again[0].IP)
+	assert.Equal(t, 22, again[0].Ports[0].Number)
+}
+
+func TestGraphQueryCache_RelationOrderSharesEntry(t *testing.T) {
+	executor, calls := countingExecutor(NewGraphQueryCache(time.Minute, 0))
+
+	req := models.GraphQueryRequest{
+		QueryType: models.QueryRelated,
+		SeedIP:    "192.0.2.1",
+		Relations: []models.RelationKind{models.RelationVuln, models.RelationASN},
+	}
+	_, err := executor.ExecuteGraphQuery(context.Background(), req)
+	require.NoError(t, err)
+
+	req.Relations = []models.RelationKind{models.RelationASN, models.RelationVuln}
+	_, err = executor.ExecuteGraphQuery(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, *calls)
+}
+
+func TestGraphQueryCache_TimeWindowIsPartOfKey(t *testing.T) {
+	executor, calls := countingExecutor(NewGraphQueryCache(time.Minute, 0))
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	windowed := redisHosts()
+	windowed.FirstSeenAfter = &since
+	_, err := executor.ExecuteGraphQuery(context.Background(), windowed)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	// The same instant in another zone shares the entry
+	local := since.In(time.FixedZone("UTC+2", 2*60*60))
+	windowed.FirstSeenAfter = &local
+	_, err = executor.ExecuteGraphQuery(context.Background(), windowed)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *calls)
+
+	// Without the window it is a different query
+	_, err = executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestGraphQueryCache_IngestBumpsEpoch(t *testing.T) {
+	cache := NewGraphQueryCache(time.Minute, 0)
+	executor, calls := countingExecutor(cache)
+
+	_, err := executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	assert.Equal(t, 1, cache.Len())
+
+	// A completed ingest bumps the epoch, so the next query must go to the DB
+	assert.Equal(t, uint64(1), cache.BumpEpoch())
+	assert.Equal(t, 0, cache.Len())
+
+	_, err = executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+
+	_, err = executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls, "the refreshed result is cached under the new epoch")
+}
+
+func TestGraphQueryCache_BumpDuringQueryIsNotCached(t *testing.T) {
+	cache := NewGraphQueryCache(time.Minute, 0)
+	counting := &countingQuerier{}
+	executor := NewGraphQueryExecutorWithQuerier(&bumpingQuerier{next: counting, cache: cache}, zap.NewNop(), 0).WithCache(cache)
+
+	_, err := executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, 1, counting.calls)
+}
+
+func TestGraphQueryCache_EntriesExpire(t *testing.T) {
+	cache := NewGraphQueryCache(time.Minute, 0)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	executor, calls := countingExecutor(cache)
+
+	_, err := executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = executor.ExecuteGraphQuery(context.Background(), redisHosts())
+	require.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestGraphQueryCache_EvictsAtCapacity(t *testing.T) {
+	cache := NewGraphQueryCache(time.Minute, 2)
+	executor, _ := countingExecutor(cache)
+
+	for _, cve := range []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"} {
+		_, err := executor.ExecuteGraphQuery(context.Background(), models.GraphQueryRequest{QueryType: models.QueryByVuln, CVE: cve})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestGraphQueryExecutor_NoCacheAlwaysQueries(t *testing.T) {
+	executor, calls := countingExecutor(nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := executor.ExecuteGraphQuery(context.Background(), redisHosts())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, *calls)
+}
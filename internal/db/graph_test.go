@@ -2,9 +2,13 @@ package db
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/go-version"
 	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,8 +20,11 @@ import (
 func setupTestDB(t *testing.T) *surrealdb.DB {
 	ctx := context.Background()
 
+	// Integration tests need a local SurrealDB; without one they are skipped
 	db, err := surrealdb.New("ws://localhost:8000/rpc")
-	require.NoError(t, err, "failed to connect to SurrealDB")
+	if err != nil {
+		t.Skipf("SurrealDB not available at ws://localhost:8000/rpc: %v", err)
+	}
 
 	// Sign in
 	_, err = db.SignIn(ctx, map[string]interface{}{
@@ -38,7 +45,7 @@ func cleanupTestDB(t *testing.T, db *surrealdb.DB) {
 	ctx := context.Background()
 
 	// Delete all test data
-	_, err := db.Query(ctx, "DELETE host; DELETE port; DELETE service; DELETE vuln;", nil)
+	_, err := surrealdb.Query[interface{}](ctx, db, "DELETE host; DELETE port; DELETE service; DELETE vuln;", nil)
 	if err != nil {
 		t.Logf("cleanup error (non-fatal): %v", err)
 	}
@@ -89,7 +96,7 @@ func seedTestData(t *testing.T, db *surrealdb.DB) {
 	}
 
 	for _, query := range queries {
-		_, err := db.Query(ctx, query, nil)
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
 		require.NoError(t, err, "failed to seed test data: %s", query)
 	}
 }
@@ -417,6 +424,167 @@ func TestGraphQueryExecutor_QueryByService(t *testing.T) {
 	}
 }
 
+func TestGraphQueryExecutor_QueryByServiceVersion(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db)
+
+	// host:test1 already runs nginx 1.25.1; add three more nginx hosts at other versions
+	for _, query := range []string{
+		`CREATE host:nginx_old SET ip = "172.16.0.1", last_seen = time::now();`,
+		`CREATE host:nginx_mid SET ip = "172.16.0.2", last_seen = time::now();`,
+		`CREATE host:nginx_banner SET ip = "172.16.0.3", last_seen = time::now();`,
+		`CREATE port:nginx_old_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE port:nginx_mid_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE port:nginx_banner_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE service:nginx_old SET name = "http", product = "nginx", version = "1.18.0";`,
+		`CREATE service:nginx_mid SET name = "http", product = "nginx", version = "1.24.0";`,
+		`CREATE service:nginx_banner SET name = "http", product = "nginx", version = "1.22.1 (Ubuntu)";`,
+		`RELATE host:nginx_old->HAS->port:nginx_old_80;`,
+		`RELATE host:nginx_mid->HAS->port:nginx_mid_80;`,
+		`RELATE host:nginx_banner->HAS->port:nginx_banner_80;`,
+		`RELATE port:nginx_old_80->RUNS->service:nginx_old;`,
+		`RELATE port:nginx_mid_80->RUNS->service:nginx_mid;`,
+		`RELATE port:nginx_banner_80->RUNS->service:nginx_banner;`,
+	} {
+		_, err := surrealdb.Query[interface{}](context.Background(), db, query, nil)
+		require.NoError(t, err)
+	}
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+
+	tests := []struct {
+		name       string
+		constraint string
+		wantIPs    []string
+	}{
+		{
+			name:       "older than 1.25",
+			constraint: "<1.25.0",
+			wantIPs:    []string{"172.16.0.1", "172.16.0.2", "172.16.0.3"},
+		},
+		{
+			name:       "bounded range",
+			constraint: ">= 1.20, < 1.25",
+			wantIPs:    []string{"172.16.0.2", "172.16.0.3"},
+		},
+		{
+			name:       "at least 1.25",
+			constraint: ">=1.25",
+			wantIPs:    []string{"192.168.1.1"},
+		},
+		{
+			name:       "no match",
+			constraint: "<1.0",
+			wantIPs:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := executor.ExecuteGraphQuery(context.Background(), models.GraphQueryRequest{
+				QueryType:         models.QueryByService,
+				Product:           "nginx",
+				VersionConstraint: tt.constraint,
+			})
+			require.NoError(t, err)
+
+			ips := []string{}
+			for _, host := range resp.Results {
+				ips = append(ips, host.IP)
+			}
+			assert.Equal(t, tt.wantIPs, ips)
+			assert.Equal(t, len(tt.wantIPs), resp.Pagination.Total)
+		})
+	}
+
+	t.Run("paginates the matching subset", func(t *testing.T) {
+		resp, err := executor.ExecuteGraphQuery(context.Background(), models.GraphQueryRequest{
+			QueryType:         models.QueryByService,
+			Product:           "nginx",
+			VersionConstraint: "<1.25.0",
+			Limit:             2,
+			Offset:            2,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "172.16.0.3", resp.Results[0].IP)
+		assert.Equal(t, 3, resp.Pagination.Total)
+		assert.False(t, resp.Pagination.HasMore)
+	})
+}
+
+func TestHostsMatchingVersion(t *testing.T) {
+	services := []serviceVersionHosts{
+		{Version: "1.18.0", Hosts: []string{"10.0.0.2", "10.0.0.1"}},
+		{Version: "1.24.0", Hosts: []string{"10.0.0.1", "10.0.0.3"}},
+		{Version: "1.22.1 (Ubuntu)", Hosts: []string{"10.0.0.4"}},
+		{Version: "1.25.3", Hosts: []string{"10.0.0.5"}},
+		{Version: "unknown", Hosts: []string{"10.0.0.6"}},
+		{Version: "", Hosts: []string{"10.0.0.7"}},
+	}
+
+	constraints, err := version.NewConstraint("<1.25.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}, hostsMatchingVersion(services, constraints))
+
+	constraints, err = version.NewConstraint("~> 1.25.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.5"}, hostsMatchingVersion(services, constraints))
+
+	constraints, err = version.NewConstraint(">2.0")
+	require.NoError(t, err)
+	assert.Empty(t, hostsMatchingVersion(services, constraints))
+}
+
+// seedKEVTestData flags CVE-2023-5678 (redis, host:test3) as known exploited and
+// adds two more KEV-listed CVEs: one on redis and one on openssh (host:test2).
+// CVE-2023-1234 (nginx, host:test1) keeps the default kev_flag = false.
+func seedKEVTestData(t *testing.T, db *surrealdb.DB) {
+	ctx := context.Background()
+
+	queries := []string{
+		`UPDATE vuln:cve_2023_5678 SET kev_flag = true;`,
+		`CREATE vuln:cve_2024_0001 SET cve = "CVE-2024-0001", title = "Redis KEV Vulnerability", cvss = 10.0, kev_flag = true;`,
+		`CREATE vuln:cve_2024_0002 SET cve = "CVE-2024-0002", title = "OpenSSH KEV Vulnerability", cvss = 8.1, kev_flag = true;`,
+		`RELATE service:redis->AFFECTED_BY->vuln:cve_2024_0001;`,
+		`RELATE service:openssh->AFFECTED_BY->vuln:cve_2024_0002;`,
+	}
+
+	for _, query := range queries {
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
+		require.NoError(t, err, "failed to seed KEV test data: %s", query)
+	}
+}
+
+func TestGraphQueryExecutor_QueryByKEV(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db)
+	seedKEVTestData(t, db)
+
+	logger := zaptest.NewLogger(t)
+	executor := NewGraphQueryExecutor(db, logger)
+
+	ctx := context.Background()
+	resp, err := executor.ExecuteGraphQuery(ctx, models.GraphQueryRequest{
+		QueryType: models.QueryByKEV,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+
+	// host:test1 is only affected by a non-KEV CVE and must not appear;
+	// host:test3 has two KEV CVEs but appears once, ranked by its 10.0
+	ips := make([]string, 0, len(resp.Results))
+	for _, host := range resp.Results {
+		ips = append(ips, host.IP)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "192.168.1.2"}, ips)
+	assert.Equal(t, 10.0, resp.Results[0].MaxCVSS)
+	assert.Equal(t, 8.1, resp.Results[1].MaxCVSS)
+	assert.False(t, resp.Pagination.HasMore)
+}
+
 func TestGraphQueryExecutor_QueryTimeout(t *testing.T) {
 	db := setupTestDB(t)
 	defer cleanupTestDB(t, db)
@@ -442,6 +610,98 @@ func TestGraphQueryExecutor_QueryTimeout(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// blockingQuerier answers a host query only once its context is done, with err
+// or else the context's error
+type blockingQuerier struct {
+	err error
+}
+
+func (q blockingQuerier) QueryHosts(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	<-ctx.Done()
+	if q.err != nil {
+		return nil, 0, q.err
+	}
+	return nil, 0, ctx.Err()
+}
+
+// slowQuerier answers a host query with results after delay
+type slowQuerier struct {
+	delay   time.Duration
+	results []models.HostResult
+}
+
+func (q slowQuerier) QueryHosts(ctx context.Context, req models.GraphQueryRequest) ([]models.HostResult, int, error) {
+	time.Sleep(q.delay)
+	return q.results, len(q.results), nil
+}
+
+func TestGraphQueryExecutor_MaxQueryDuration(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	// Stub a query that only returns once its context is done
+	executor := NewGraphQueryExecutorWithQuerier(blockingQuerier{}, logger, 50*time.Millisecond)
+
+	asn := 15169
+	req := models.GraphQueryRequest{
+		QueryType: models.QueryByASN,
+		ASN:       &asn,
+		Limit:     10,
+	}
+
+	// The inbound context has no deadline; the server-side deadline must still apply
+	start := time.Now()
+	_, err := executor.ExecuteGraphQuery(context.Background(), req)
+	elapsed := time.Since(start)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+	assert.Less(t, elapsed, 2*time.Second)
+}
+
+func TestGraphQueryExecutor_MaxQueryDurationWrapsDriverError(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	// Drivers don't always surface the context error itself
+	executor := NewGraphQueryExecutorWithQuerier(blockingQuerier{err: errors.New("connection closed")}, logger, 20*time.Millisecond)
+
+	asn := 15169
+	_, err := executor.ExecuteGraphQuery(context.Background(), models.GraphQueryRequest{
+		QueryType: models.QueryByASN,
+		ASN:       &asn,
+	})
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "connection closed")
+}
+
+func TestGraphQueryExecutor_QueryTimeReportsElapsed(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	executor := NewGraphQueryExecutorWithQuerier(slowQuerier{
+		delay:   30 * time.Millisecond,
+		results: []models.HostResult{{IP: "8.8.8.8"}},
+	}, logger, time.Second)
+
+	asn := 15169
+	resp, err := executor.ExecuteGraphQuery(context.Background(), models.GraphQueryRequest{
+		QueryType: models.QueryByASN,
+		ASN:       &asn,
+	})
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, resp.QueryTime, 30.0)
+	assert.Less(t, resp.QueryTime, 1000.0)
+}
+
+func TestNewGraphQueryExecutorWithTimeout_Default(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+
+	assert.Equal(t, DefaultMaxQueryDuration, NewGraphQueryExecutor(nil, logger).MaxQueryDuration())
+	assert.Equal(t, DefaultMaxQueryDuration, NewGraphQueryExecutorWithTimeout(nil, logger, 0).MaxQueryDuration())
+	assert.Equal(t, 3*time.Second, NewGraphQueryExecutorWithTimeout(nil, logger, 3*time.Second).MaxQueryDuration())
+}
+
 func TestGraphQueryExecutor_Pagination(t *testing.T) {
 	db := setupTestDB(t)
 	defer cleanupTestDB(t, db)
@@ -484,6 +744,196 @@ func TestGraphQueryExecutor_Pagination(t *testing.T) {
 	assert.NotEqual(t, resp1.Results[0].IP, resp2.Results[0].IP)
 }
 
+func TestGraphQueryExecutor_StablePagination(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db)
+
+	logger := zaptest.NewLogger(t)
+	executor := NewGraphQueryExecutor(db, logger)
+
+	ctx := context.Background()
+	asn := 15169
+
+	// Walk every page one host at a time, collecting the host ids in order
+	export := func() []string {
+		var ids []string
+		req := models.GraphQueryRequest{
+			QueryType: models.QueryByASN,
+			ASN:       &asn,
+			Limit:     1,
+			Stable:    true,
+		}
+		for {
+			resp, err := executor.ExecuteGraphQuery(ctx, req)
+			require.NoError(t, err)
+			for _, host := range resp.Results {
+				ids = append(ids, host.ID)
+			}
+			if !resp.Pagination.HasMore {
+				return ids
+			}
+			req.Offset = resp.Pagination.NextOffset
+		}
+	}
+
+	first := export()
+	second := export()
+
+	require.Len(t, first, 2)
+	assert.Equal(t, first, second)
+	assert.Less(t, first[0], first[1], "stable pages should be ordered by id")
+}
+
+func TestOrderClause(t *testing.T) {
+	assert.Equal(t, "ORDER BY last_seen DESC", orderClause(false, "ORDER BY last_seen DESC"))
+	assert.Equal(t, "", orderClause(false, ""))
+	assert.Equal(t, "ORDER BY id", orderClause(true, "ORDER BY last_seen DESC"))
+	assert.Equal(t, "ORDER BY id", orderClause(true, ""))
+}
+
+func TestTimeWindowClause(t *testing.T) {
+	params := map[string]interface{}{}
+	assert.Empty(t, timeWindow{}.clause(params))
+	assert.Empty(t, params)
+	assert.True(t, timeWindow{}.isOpen())
+
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	before := after.Add(24 * time.Hour)
+	window := windowOf(models.GraphQueryRequest{FirstSeenAfter: &after, LastSeenBefore: &before})
+
+	clause := window.clause(params)
+	assert.False(t, window.isOpen())
+	assert.Equal(t, "AND first_seen >= $first_seen_after AND last_seen < $last_seen_before", clause)
+	assert.Equal(t, after.UTC(), params["first_seen_after"])
+	assert.Equal(t, before.UTC(), params["last_seen_before"])
+	assert.NotContains(t, params, "last_seen_after")
+}
+
+// seedTimeWindowTestData creates nginx hosts first and last seen at staggered times
+func seedTimeWindowTestData(t *testing.T, db *surrealdb.DB) {
+	ctx := context.Background()
+
+	queries := []string{
+		// new: first seen an hour ago; stale: not seen for 10 days
+		`CREATE host:new SET ip = "198.51.100.1", asn = 64500, country = "US", last_seen = time::now(), first_seen = time::now() - 1h;`,
+		`CREATE host:week SET ip = "198.51.100.2", asn = 64500, country = "US", last_seen = time::now() - 1d, first_seen = time::now() - 7d;`,
+		`CREATE host:stale SET ip = "198.51.100.3", asn = 64500, country = "US", last_seen = time::now() - 10d, first_seen = time::now() - 30d;`,
+
+		`CREATE port:new_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE port:week_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE port:stale_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE service:nginx_window SET name = "http", product = "nginx", version = "1.18.0";`,
+		`CREATE vuln:cve_2021_23017 SET cve = "CVE-2021-23017", cvss = 7.7, kev_flag = true;`,
+
+		`RELATE host:new->HAS->port:new_80;`,
+		`RELATE host:week->HAS->port:week_80;`,
+		`RELATE host:stale->HAS->port:stale_80;`,
+		`RELATE port:new_80->RUNS->service:nginx_window;`,
+		`RELATE port:week_80->RUNS->service:nginx_window;`,
+		`RELATE port:stale_80->RUNS->service:nginx_window;`,
+		`RELATE service:nginx_window->AFFECTED_BY->vuln:cve_2021_23017;`,
+	}
+
+	for _, query := range queries {
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
+		require.NoError(t, err, "failed to seed test data: %s", query)
+	}
+}
+
+func TestGraphQueryExecutor_TimeWindow(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTimeWindowTestData(t, db)
+
+	logger := zaptest.NewLogger(t)
+	executor := NewGraphQueryExecutor(db, logger)
+
+	ctx := context.Background()
+	now := time.Now()
+	halfDayAgo := now.Add(-12 * time.Hour)
+	dayAgo := now.Add(-24 * time.Hour)
+	twoDaysAgo := now.Add(-48 * time.Hour)
+	fiveDaysAgo := now.Add(-5 * 24 * time.Hour)
+	asn := 64500
+
+	queries := map[string]models.GraphQueryRequest{
+		"by_asn":             {QueryType: models.QueryByASN, ASN: &asn},
+		"by_location":        {QueryType: models.QueryByLocation, Country: "US"},
+		"by_vuln":            {QueryType: models.QueryByVuln, CVE: "CVE-2021-23017"},
+		"by_service":         {QueryType: models.QueryByService, Product: "nginx"},
+		"by_service version": {QueryType: models.QueryByService, Product: "nginx", VersionConstraint: "<1.25.0"},
+		"by_kev":             {QueryType: models.QueryByKEV},
+		"related":            {QueryType: models.QueryRelated, SeedIP: "198.51.100.2", Relations: []models.RelationKind{models.RelationASN}},
+	}
+
+	windows := []struct {
+		name string
+		set  func(req *models.GraphQueryRequest)
+		want []string
+	}{
+		{
+			name: "first seen in the last day",
+			set:  func(req *models.GraphQueryRequest) { req.FirstSeenAfter = &dayAgo },
+			want: []string{"198.51.100.1"},
+		},
+		{
+			name: "last seen in the last two days",
+			set:  func(req *models.GraphQueryRequest) { req.LastSeenAfter = &twoDaysAgo },
+			want: []string{"198.51.100.1", "198.51.100.2"},
+		},
+		{
+			name: "last seen between five days and twelve hours ago",
+			set: func(req *models.GraphQueryRequest) {
+				req.LastSeenAfter = &fiveDaysAgo
+				req.LastSeenBefore = &halfDayAgo
+			},
+			want: []string{"198.51.100.2"},
+		},
+		{
+			name: "not seen for five days",
+			set:  func(req *models.GraphQueryRequest) { req.LastSeenBefore = &fiveDaysAgo },
+			want: []string{"198.51.100.3"},
+		},
+	}
+
+	for queryName, base := range queries {
+		for _, window := range windows {
+			t.Run(queryName+"/"+window.name, func(t *testing.T) {
+				req := base
+				window.set(&req)
+
+				resp, err := executor.ExecuteGraphQuery(ctx, req)
+				require.NoError(t, err)
+
+				want := window.want
+				if req.QueryType == models.QueryRelated {
+					// The seed host is never among its own related hosts
+					want = without(want, req.SeedIP)
+				}
+
+				var ips []string
+				for _, host := range resp.Results {
+					ips = append(ips, host.IP)
+				}
+				assert.ElementsMatch(t, want, ips)
+				assert.Equal(t, len(want), resp.Pagination.Total)
+			})
+		}
+	}
+}
+
+// without returns ips less ip
+func without(ips []string, ip string) []string {
+	out := []string{}
+	for _, candidate := range ips {
+		if candidate != ip {
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
+
 func TestGraphQueryExecutor_ValidationErrors(t *testing.T) {
 	db := setupTestDB(t)
 	defer cleanupTestDB(t, db)
@@ -530,6 +980,52 @@ func TestGraphQueryExecutor_ValidationErrors(t *testing.T) {
 			},
 			wantErr: models.ErrMissingService,
 		},
+		{
+			name: "invalid version constraint for by_service query",
+			req: models.GraphQueryRequest{
+				QueryType:         models.QueryByService,
+				Product:           "nginx",
+				VersionConstraint: "older than 1.25",
+				Limit:             10,
+			},
+			wantErr: models.ErrInvalidVersionConstraint,
+		},
+		{
+			name: "missing seed IP for related query",
+			req: models.GraphQueryRequest{
+				QueryType: models.QueryRelated,
+				Limit:     10,
+			},
+			wantErr: models.ErrMissingSeedIP,
+		},
+		{
+			name: "invalid relation kind for related query",
+			req: models.GraphQueryRequest{
+				QueryType: models.QueryRelated,
+				SeedIP:    "192.168.1.1",
+				Relations: []models.RelationKind{"owner"},
+				Limit:     10,
+			},
+			wantErr: models.ErrInvalidRelation,
+		},
+		{
+			name: "last seen window ends before it starts",
+			req: models.GraphQueryRequest{
+				QueryType:      models.QueryByKEV,
+				LastSeenAfter:  timePtr(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)),
+				LastSeenBefore: timePtr(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			wantErr: models.ErrInvalidLastSeenRange,
+		},
+		{
+			name: "first seen after the last seen window ends",
+			req: models.GraphQueryRequest{
+				QueryType:      models.QueryByKEV,
+				FirstSeenAfter: timePtr(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+				LastSeenBefore: timePtr(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			wantErr: models.ErrInvalidFirstSeenRange,
+		},
 	}
 
 	for _, tt := range tests {
@@ -540,6 +1036,28 @@ func TestGraphQueryExecutor_ValidationErrors(t *testing.T) {
 	}
 }
 
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestGraphQueryRequest_ValidateRelations(t *testing.T) {
+	// Omitted relations default to a copy of every kind
+	req := models.GraphQueryRequest{QueryType: models.QueryRelated, SeedIP: "192.0.2.1"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, models.AllRelationKinds, req.Relations)
+	req.Relations[0] = models.RelationVuln
+	assert.Equal(t, models.RelationASN, models.AllRelationKinds[0], "defaults must not alias AllRelationKinds")
+
+	// Repeated kinds are scored once, in the order first named
+	req = models.GraphQueryRequest{
+		QueryType: models.QueryRelated,
+		SeedIP:    "192.0.2.1",
+		Relations: []models.RelationKind{models.RelationVuln, models.RelationASN, models.RelationVuln},
+	}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []models.RelationKind{models.RelationVuln, models.RelationASN}, req.Relations)
+}
+
 func TestGraphQueryExecutor_DefaultsAndLimits(t *testing.T) {
 	db := setupTestDB(t)
 	defer cleanupTestDB(t, db)
@@ -609,3 +1127,554 @@ func TestGraphQueryExecutor_QueryTime(t *testing.T) {
 	assert.Greater(t, resp.QueryTime, 0.0)
 	assert.Less(t, resp.QueryTime, 5000.0) // Should be less than 5 seconds
 }
+
+// seedRelatedTestData adds a host in a different ASN and subnet that shares
+// host:test1's nginx service (and therefore its vulnerability)
+func seedRelatedTestData(t *testing.T, db *surrealdb.DB) {
+	ctx := context.Background()
+
+	queries := []string{
+		`CREATE host:test4 SET ip = "172.16.0.1", asn = 16509, city = "Seattle", region = "Washington", country = "US", last_seen = time::now(), first_seen = time::now() - 3d;`,
+		`CREATE port:test4_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`RELATE host:test4->HAS->port:test4_80;`,
+		`RELATE port:test4_80->RUNS->service:nginx;`,
+	}
+
+	for _, query := range queries {
+		_, err := surrealdb.Query[interface{}](ctx, db, query, nil)
+		require.NoError(t, err, "failed to seed related test data: %s", query)
+	}
+}
+
+func TestGraphQueryExecutor_QueryRelated(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db)
+	seedRelatedTestData(t, db)
+
+	logger := zaptest.NewLogger(t)
+	executor := NewGraphQueryExecutor(db, logger)
+
+	tests := []struct {
+		name      string
+		seedIP    string
+		relations []models.RelationKind
+		limit     int
+		offset    int
+		wantIPs   []string
+		wantTotal int
+	}{
+		{
+			name:      "related by asn",
+			seedIP:    "192.168.1.1",
+			relations: []models.RelationKind{models.RelationASN},
+			limit:     10,
+			wantIPs:   []string{"192.168.1.2"},
+			wantTotal: 1,
+		},
+		{
+			name:      "related by subnet",
+			seedIP:    "192.168.1.1",
+			relations: []models.RelationKind{models.RelationSubnet},
+			limit:     10,
+			wantIPs:   []string{"192.168.1.2"},
+			wantTotal: 1,
+		},
+		{
+			name:      "related by service",
+			seedIP:    "192.168.1.1",
+			relations: []models.RelationKind{models.RelationService},
+			limit:     10,
+			wantIPs:   []string{"172.16.0.1"},
+			wantTotal: 1,
+		},
+		{
+			name:      "related by vuln",
+			seedIP:    "192.168.1.1",
+			relations: []models.RelationKind{models.RelationVuln},
+			limit:     10,
+			wantIPs:   []string{"172.16.0.1"},
+			wantTotal: 1,
+		},
+		{
+			name:      "all relations ordered by score",
+			seedIP:    "192.168.1.1",
+			limit:     10,
+			wantIPs:   []string{"172.16.0.1", "192.168.1.2"},
+			wantTotal: 2,
+		},
+		{
+			name:      "all relations with pagination",
+			seedIP:    "192.168.1.1",
+			limit:     1,
+			offset:    1,
+			wantIPs:   []string{"192.168.1.2"},
+			wantTotal: 2,
+		},
+		{
+			name:      "unknown seed host",
+			seedIP:    "203.0.113.1",
+			limit:     10,
+			wantIPs:   []string{},
+			wantTotal: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			req := models.GraphQueryRequest{
+				QueryType: models.QueryRelated,
+				SeedIP:    tt.seedIP,
+				Relations: tt.relations,
+				Limit:     tt.limit,
+				Offset:    tt.offset,
+			}
+
+			resp, err := executor.ExecuteGraphQuery(ctx, req)
+			require.NoError(t, err)
+			require.NotNil(t, resp)
+			assert.Equal(t, tt.wantTotal, resp.Pagination.Total)
+
+			ips := make([]string, 0, len(resp.Results))
+			for _, host := range resp.Results {
+				ips = append(ips, host.IP)
+				assert.Greater(t, host.Score, 0.0)
+				assert.NotEmpty(t, host.Relations)
+			}
+			assert.Equal(t, tt.wantIPs, ips)
+		})
+	}
+}
+
+func TestSubnetPrefix(t *testing.T) {
+	assert.Equal(t, "192.168.1.", subnetPrefix("192.168.1.42"))
+	assert.Equal(t, "10.0.0.", subnetPrefix("10.0.0.1"))
+	assert.Equal(t, "", subnetPrefix("2001:db8::1"))
+	assert.Equal(t, "", subnetPrefix("not-an-ip"))
+}
+
+func TestGraphQueryExecutor_QueryAggregateByASN(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db)
+	seedRelatedTestData(t, db)
+
+	_, err := surrealdb.Query[interface{}](context.Background(), db, `CREATE asn:15169 SET number = 15169, org = "GOOGLE";`, nil)
+	require.NoError(t, err)
+
+	logger := zaptest.NewLogger(t)
+	executor := NewGraphQueryExecutor(db, logger)
+
+	t.Run("all ASNs in descending order", func(t *testing.T) {
+		resp, err := executor.QueryAggregateByASN(context.Background(), 0)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 3)
+		assert.Equal(t, models.DefaultAggregateLimit, resp.Limit)
+
+		assert.Equal(t, models.ASNAggregate{ASN: 15169, Org: "GOOGLE", HostCount: 2, PortCount: 3}, resp.Results[0])
+		assert.Equal(t, models.ASNAggregate{ASN: 8075, HostCount: 1, PortCount: 1}, resp.Results[1])
+		assert.Equal(t, models.ASNAggregate{ASN: 16509, HostCount: 1, PortCount: 1}, resp.Results[2])
+
+		for i := 1; i < len(resp.Results); i++ {
+			assert.GreaterOrEqual(t, resp.Results[i-1].HostCount, resp.Results[i].HostCount)
+		}
+	})
+
+	t.Run("top-N limit", func(t *testing.T) {
+		resp, err := executor.QueryAggregateByASN(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, 15169, resp.Results[0].ASN)
+		assert.Equal(t, 2, resp.Results[0].HostCount)
+	})
+}
+
+func TestGraphQueryExecutor_QueryByCPE(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db)
+
+	const cpe = "cpe:2.3:a:openbsd:openssh:9.0p1:*:*:*:*:*:*:*"
+	for _, query := range []string{
+		`CREATE service:openssh_banner SET name = "ssh", product = "openssh", version = "9.0p1", cpe = ["` + cpe + `"];`,
+		`CREATE vuln:CVE_2023_38408 SET cve_id = "CVE-2023-38408", cvss = 9.8, severity = "CRITICAL";`,
+		`RELATE port:test2_22->RUNS->service:openssh_banner;`,
+		`RELATE service:openssh_banner->AFFECTED_BY->vuln:CVE_2023_38408 SET confidence = 0.75;`,
+	} {
+		_, err := surrealdb.Query[interface{}](context.Background(), db, query, nil)
+		require.NoError(t, err)
+	}
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+
+	t.Run("services and CVEs for a known CPE", func(t *testing.T) {
+		resp, err := executor.QueryByCPE(context.Background(), cpe, 0)
+		require.NoError(t, err)
+		assert.Equal(t, cpe, resp.CPE)
+		assert.Equal(t, models.DefaultLimit, resp.Limit)
+
+		require.Len(t, resp.Services, 1)
+		service := resp.Services[0]
+		assert.Equal(t, "openssh_banner", service.ServiceID)
+		assert.Equal(t, []string{"192.168.1.2"}, service.Hosts)
+		require.Len(t, service.Vulns, 1)
+		assert.Equal(t, models.CPEVulnLink{CVEID: "CVE-2023-38408", CVSS: 9.8, Severity: "CRITICAL", Confidence: 0.75}, service.Vulns[0])
+	})
+
+	t.Run("unknown CPE", func(t *testing.T) {
+		resp, err := executor.QueryByCPE(context.Background(), "cpe:2.3:a:acme:widget:1.0:*:*:*:*:*:*:*", 0)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Services)
+	})
+}
+
+func TestParseCPEPrefix(t *testing.T) {
+	tests := []struct {
+		prefix     string
+		wantPrefix string
+		wantErr    *models.ValidationError
+	}{
+		{"cpe:2.3:a:apache:*", "cpe:2.3:a:apache:", nil},
+		{"cpe:2.3:a:apache", "cpe:2.3:a:apache:", nil},
+		{"CPE:2.3:A:Apache:*:*:*", "cpe:2.3:a:apache:", nil},
+		{"cpe:2.3:a:apache:http*", "cpe:2.3:a:apache:http", nil},
+		{"cpe:2.3:a:*:log4j", "cpe:2.3:a:", nil},
+		{"cpe:2.3:a:apache\\:foundation:*", "cpe:2.3:a:apache\\:foundation:", nil},
+		{"cpe:2.3:*", "", models.ErrBroadCPEPrefix},
+		{"cpe:2.3:*:apache", "", models.ErrBroadCPEPrefix},
+		{"cpe:2.3:", "", models.ErrBroadCPEPrefix},
+		{"apache", "", models.ErrInvalidCPEPrefix},
+		{"cpe:/a:apache", "", models.ErrInvalidCPEPrefix},
+		{"cpe:2.3:a::nginx", "", models.ErrInvalidCPEPrefix},
+		{"cpe:2.3:a:apa*he", "", models.ErrInvalidCPEPrefix},
+		{"cpe:2.3:a:apache*:http_server", "", models.ErrInvalidCPEPrefix},
+		{"cpe:2.3:a:b:c:d:e:f:g:h:i:j:k:l", "", models.ErrInvalidCPEPrefix},
+		{"cpe:2.3:x:apache", "", models.ErrInvalidCPEPart},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			pattern, err := models.ParseCPEPrefix(tt.prefix)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPrefix, pattern.Prefix)
+		})
+	}
+}
+
+func TestCPEPattern_Matches(t *testing.T) {
+	tests := []struct {
+		prefix string
+		cpe    string
+		want   bool
+	}{
+		{"cpe:2.3:a:apache:*", "cpe:2.3:a:apache:http_server:2.4.57:*:*:*:*:*:*:*", true},
+		{"cpe:2.3:a:apache:*", "cpe:2.3:a:apache:tomcat:9.0.80:*:*:*:*:*:*:*", true},
+		{"cpe:2.3:a:apache:*", "cpe:2.3:a:apache_software:widget:1.0:*:*:*:*:*:*:*", false},
+		{"cpe:2.3:a:apache:*", "cpe:2.3:o:apache:http_server:2.4.57:*:*:*:*:*:*:*", false},
+		{"cpe:2.3:a:apache:http*", "cpe:2.3:a:apache:http_server:2.4.57:*:*:*:*:*:*:*", true},
+		{"cpe:2.3:a:apache:http*", "cpe:2.3:a:apache:tomcat:9.0.80:*:*:*:*:*:*:*", false},
+		{"cpe:2.3:a:*:log4j", "cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*", true},
+		{"cpe:2.3:a:*:log4j", "cpe:2.3:a:apache:log4j_core:2.14.1:*:*:*:*:*:*:*", false},
+		{"cpe:2.3:a:apache:http_server:2.4.57", "cpe:2.3:a:apache:http_server:2.4.57:*:*:*:*:*:*:*", true},
+		{"cpe:2.3:a:apache:http_server:2.4.57", "cpe:2.3:a:apache:http_server:2.4.5:*:*:*:*:*:*:*", false},
+		{"cpe:2.3:a:apache:http_server:2.4.57", "cpe:2.3:a:apache:http_server", false},
+		{"cpe:2.3:a:apache", "not-a-cpe", false},
+	}
+
+	for _, tt := range tests {
+		pattern, err := models.ParseCPEPrefix(tt.prefix)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, pattern.Matches(tt.cpe), "%s matches %s", tt.prefix, tt.cpe)
+		if tt.want && pattern.Literal() {
+			assert.True(t, strings.HasPrefix(tt.cpe, pattern.Prefix), "a literal pattern's matches start with its prefix")
+		}
+	}
+}
+
+func TestMatchCPEPattern(t *testing.T) {
+	pattern, err := models.ParseCPEPrefix("cpe:2.3:a:*:log4j")
+	require.NoError(t, err)
+
+	rows := []models.CPEServiceMatch{
+		{ServiceID: "a", CPEs: []string{"cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*"}, Hosts: []string{"203.0.113.10", "198.51.100.2"}},
+		{ServiceID: "b", CPEs: []string{"cpe:2.3:a:apache:tomcat:9.0.80:*:*:*:*:*:*:*"}, Hosts: []string{"192.0.2.1"}},
+		{ServiceID: "c", CPEs: []string{"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*", "cpe:2.3:a:qos:log4j:1.2.17:*:*:*:*:*:*:*"}, Hosts: []string{"198.51.100.2", "203.0.113.9"}},
+		{ServiceID: "d", CPEs: []string{"cpe:2.3:a:apache:log4j:2.17.0:*:*:*:*:*:*:*"}, Hosts: []string{"192.0.2.50"}},
+	}
+
+	services, hosts := matchCPEPattern(rows, pattern, 10)
+	assert.Equal(t, []string{"a", "c", "d"}, serviceIDs(services))
+	assert.Equal(t, []string{"192.0.2.50", "198.51.100.2", "203.0.113.9", "203.0.113.10"}, hosts, "distinct, in address order")
+
+	// The limit counts matching services, not the rows the database returned
+	services, hosts = matchCPEPattern(rows, pattern, 2)
+	assert.Equal(t, []string{"a", "c"}, serviceIDs(services))
+	assert.Equal(t, []string{"198.51.100.2", "203.0.113.9", "203.0.113.10"}, hosts)
+
+	services, hosts = matchCPEPattern(nil, pattern, 10)
+	assert.Empty(t, services)
+	assert.NotNil(t, services, "encodes as []")
+	assert.Empty(t, hosts)
+}
+
+// serviceIDs lists the IDs of services in order
+func serviceIDs(services []models.CPEServiceMatch) []string {
+	ids := make([]string, 0, len(services))
+	for _, service := range services {
+		ids = append(ids, service.ServiceID)
+	}
+	return ids
+}
+
+// seedCPEPrefixTestData creates services with varied CPEs on their own hosts:
+// apache httpd and tomcat, a log4j service under two vendors, and nginx
+func seedCPEPrefixTestData(t *testing.T, db *surrealdb.DB) {
+	for _, query := range []string{
+		`CREATE host:cpe_httpd SET ip = "203.0.113.10";`,
+		`CREATE host:cpe_tomcat SET ip = "203.0.113.11";`,
+		`CREATE host:cpe_log4j SET ip = "203.0.113.12";`,
+		`CREATE host:cpe_nginx SET ip = "203.0.113.13";`,
+		`CREATE port:cpe_httpd_443 SET number = 443, protocol = "tcp", state = "open";`,
+		`CREATE port:cpe_tomcat_8080 SET number = 8080, protocol = "tcp", state = "open";`,
+		`CREATE port:cpe_log4j_8443 SET number = 8443, protocol = "tcp", state = "open";`,
+		`CREATE port:cpe_nginx_80 SET number = 80, protocol = "tcp", state = "open";`,
+		`CREATE service:cpe_httpd SET name = "http", product = "Apache httpd", version = "2.4.57", cpe = ["cpe:2.3:a:apache:http_server:2.4.57:*:*:*:*:*:*:*"];`,
+		`CREATE service:cpe_tomcat SET name = "http", product = "Apache Tomcat", version = "9.0.80", cpe = ["cpe:2.3:a:apache:tomcat:9.0.80:*:*:*:*:*:*:*"];`,
+		`CREATE service:cpe_log4j SET name = "http", product = "log4j", version = "2.14.1", cpe = ["cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*", "cpe:2.3:a:qos:log4j:2.14.1:*:*:*:*:*:*:*"];`,
+		`CREATE service:cpe_nginx SET name = "http", product = "nginx", version = "1.24.0", cpe = ["cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"];`,
+		`CREATE service:cpe_unidentified SET name = "http";`,
+		`CREATE vuln:CVE_2021_44228 SET cve_id = "CVE-2021-44228", cvss = 10.0, severity = "CRITICAL";`,
+		`RELATE host:cpe_httpd->HAS->port:cpe_httpd_443;`,
+		`RELATE host:cpe_tomcat->HAS->port:cpe_tomcat_8080;`,
+		`RELATE host:cpe_log4j->HAS->port:cpe_log4j_8443;`,
+		`RELATE host:cpe_nginx->HAS->port:cpe_nginx_80;`,
+		`RELATE port:cpe_httpd_443->RUNS->service:cpe_httpd;`,
+		`RELATE port:cpe_tomcat_8080->RUNS->service:cpe_tomcat;`,
+		`RELATE port:cpe_log4j_8443->RUNS->service:cpe_log4j;`,
+		`RELATE port:cpe_nginx_80->RUNS->service:cpe_nginx;`,
+		`RELATE service:cpe_log4j->AFFECTED_BY->vuln:CVE_2021_44228 SET confidence = 0.75;`,
+	} {
+		_, err := surrealdb.Query[interface{}](context.Background(), db, query, nil)
+		require.NoError(t, err, "failed to seed CPE prefix test data: %s", query)
+	}
+}
+
+func TestGraphQueryExecutor_QueryByCPEPrefix(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedCPEPrefixTestData(t, db)
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+
+	tests := []struct {
+		name         string
+		prefix       string
+		limit        int
+		wantServices []string
+		wantHosts    []string
+	}{
+		{
+			name:         "vendor wildcard",
+			prefix:       "cpe:2.3:a:apache:*",
+			wantServices: []string{"cpe_httpd", "cpe_log4j", "cpe_tomcat"},
+			wantHosts:    []string{"203.0.113.10", "203.0.113.11", "203.0.113.12"},
+		},
+		{
+			name:         "vendor without a trailing wildcard",
+			prefix:       "cpe:2.3:a:nginx",
+			wantServices: []string{"cpe_nginx"},
+			wantHosts:    []string{"203.0.113.13"},
+		},
+		{
+			name:         "partial product",
+			prefix:       "cpe:2.3:a:apache:http*",
+			wantServices: []string{"cpe_httpd"},
+			wantHosts:    []string{"203.0.113.10"},
+		},
+		{
+			name:         "any vendor",
+			prefix:       "cpe:2.3:a:*:log4j:*",
+			wantServices: []string{"cpe_log4j"},
+			wantHosts:    []string{"203.0.113.12"},
+		},
+		{
+			name:         "version",
+			prefix:       "cpe:2.3:a:apache:tomcat:9.0.80",
+			wantServices: []string{"cpe_tomcat"},
+			wantHosts:    []string{"203.0.113.11"},
+		},
+		{
+			name:         "limit",
+			prefix:       "cpe:2.3:a:apache:*",
+			limit:        2,
+			wantServices: []string{"cpe_httpd", "cpe_log4j"},
+			wantHosts:    []string{"203.0.113.10", "203.0.113.12"},
+		},
+		{
+			name:         "no match",
+			prefix:       "cpe:2.3:o:apache",
+			wantServices: []string{},
+			wantHosts:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := executor.QueryByCPEPrefix(context.Background(), tt.prefix, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantServices, serviceIDs(resp.Services))
+			assert.Equal(t, tt.wantHosts, resp.Hosts)
+		})
+	}
+
+	t.Run("services carry their CVEs", func(t *testing.T) {
+		resp, err := executor.QueryByCPEPrefix(context.Background(), "cpe:2.3:a:qos:*", 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultLimit, resp.Limit)
+		require.Len(t, resp.Services, 1)
+		assert.Equal(t, []models.CPEVulnLink{{CVEID: "CVE-2021-44228", CVSS: 10.0, Severity: "CRITICAL", Confidence: 0.75}}, resp.Services[0].Vulns)
+	})
+
+	t.Run("invalid prefix", func(t *testing.T) {
+		_, err := executor.QueryByCPEPrefix(context.Background(), "cpe:2.3:*", 0)
+		assert.ErrorIs(t, err, models.ErrBroadCPEPrefix)
+
+		_, err = executor.QueryByCPEPrefix(context.Background(), "cpe:2.3:*:apache", 0)
+		assert.ErrorIs(t, err, models.ErrBroadCPEPrefix, "a wildcard part can't narrow the scan")
+	})
+}
+
+func TestGraphQueryExecutor_QueryExposure(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	for _, query := range []string{
+		`CREATE host:ssh_a SET ip = "8.8.4.4";`,
+		`CREATE host:ssh_b SET ip = "1.1.1.1";`,
+		`CREATE host:ssh_private SET ip = "10.0.0.7";`,
+		`CREATE host:ssh_cgnat SET ip = "100.64.0.7";`,
+		`CREATE host:rdp SET ip = "2606:4700::1111";`,
+		`CREATE host:web SET ip = "9.9.9.9";`,
+		`CREATE host:mongo_closed SET ip = "203.0.113.27";`,
+		`CREATE port:ssh_a_22 SET number = 22, protocol = "tcp", state = "open";`,
+		`CREATE port:ssh_b_22 SET number = 22, protocol = "tcp";`,
+		`CREATE port:ssh_private_22 SET number = 22, protocol = "tcp", state = "open";`,
+		`CREATE port:ssh_cgnat_22 SET number = 22, protocol = "tcp", state = "open";`,
+		`CREATE port:rdp_3389 SET number = 3389, protocol = "tcp", state = "open";`,
+		`CREATE port:web_443 SET number = 443, protocol = "tcp", state = "open";`,
+		`CREATE port:mongo_closed_27017 SET number = 27017, protocol = "tcp", state = "closed";`,
+		`RELATE host:ssh_a->HAS->port:ssh_a_22;`,
+		`RELATE host:ssh_b->HAS->port:ssh_b_22;`,
+		`RELATE host:ssh_private->HAS->port:ssh_private_22;`,
+		`RELATE host:ssh_cgnat->HAS->port:ssh_cgnat_22;`,
+		`RELATE host:rdp->HAS->port:rdp_3389;`,
+		`RELATE host:web->HAS->port:web_443;`,
+		`RELATE host:mongo_closed->HAS->port:mongo_closed_27017;`,
+	} {
+		_, err := surrealdb.Query[interface{}](context.Background(), db, query, nil)
+		require.NoError(t, err)
+	}
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+
+	t.Run("default risky ports", func(t *testing.T) {
+		resp, err := executor.QueryExposure(context.Background(), nil, 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultRiskyPorts(), resp.Ports)
+		assert.Equal(t, models.DefaultLimit, resp.Limit)
+
+		// The web port is benign, the private and CGNAT SSH hosts are not exposed and the MongoDB port is closed
+		assert.Equal(t, []models.ExposureGroup{
+			{Port: 22, Service: "ssh", HostCount: 2, Hosts: []string{"1.1.1.1", "8.8.4.4"}},
+			{Port: 3389, Service: "rdp", HostCount: 1, Hosts: []string{"2606:4700::1111"}},
+		}, resp.Results)
+	})
+
+	t.Run("configured ports", func(t *testing.T) {
+		resp, err := executor.QueryExposure(context.Background(), []int{443, 3389}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []models.ExposureGroup{
+			{Port: 443, HostCount: 1, Hosts: []string{"9.9.9.9"}},
+			{Port: 3389, Service: "rdp", HostCount: 1, Hosts: []string{"2606:4700::1111"}},
+		}, resp.Results)
+	})
+
+	t.Run("limit caps the hosts listed, not the count", func(t *testing.T) {
+		resp, err := executor.QueryExposure(context.Background(), []int{22}, 1)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, 2, resp.Results[0].HostCount)
+		assert.Equal(t, []string{"1.1.1.1"}, resp.Results[0].Hosts)
+	})
+}
+
+func TestNonPublicIPPattern(t *testing.T) {
+	pattern := regexp.MustCompile(nonPublicIPPattern)
+	for _, ip := range []string{
+		"10.1.2.3", "172.16.0.1", "172.31.255.255", "192.168.1.9", "127.0.0.1", "169.254.1.1",
+		"0.0.0.0", "224.0.0.1", "239.255.255.250", "100.64.0.1", "100.127.255.255",
+		"192.0.2.10", "198.51.100.4", "203.0.113.5", "::ffff:10.0.0.1",
+		"::", "::1", "fe80::1", "FE80::1", "fd12:3456::1", "fc00::1", "ff02::1", "2001:db8::5", "2001:DB8:1::7",
+	} {
+		assert.True(t, pattern.MatchString(ip), "%s is not public", ip)
+	}
+	for _, ip := range []string{
+		"8.8.8.8", "1.1.1.1", "100.63.255.255", "100.128.0.1", "172.32.0.1", "192.0.3.1", "198.51.101.1",
+		"203.0.114.1", "101.64.0.1", "::ffff:8.8.8.8", "2606:4700:4700::1111", "2001:4860::8888", "fc::1",
+	} {
+		assert.False(t, pattern.MatchString(ip), "%s is public", ip)
+	}
+}
+
+func TestGraphQueryExecutor_QueryUnidentifiedServices(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	seedTestData(t, db) // nginx, openssh and redis all have a product and CPE
+
+	for _, query := range []string{
+		`CREATE banner:dropbear SET hash = "dropbear", sample = "SSH-2.0-dropbear_2022.83";`,
+		`CREATE banner:acme_ftp SET hash = "acme_ftp", sample = "220 acme FTP ready";`,
+		`CREATE service:unknown_ssh_1 SET name = "ssh", product = "", cpe = [];`,
+		`CREATE service:unknown_ssh_2 SET name = "ssh", cpe = [];`,
+		`CREATE service:unknown_ssh_3 SET name = "ssh";`,
+		`CREATE service:unknown_ftp SET name = "ftp", product = "", cpe = [];`,
+		`CREATE service:unknown_bare SET name = "unknown";`,
+		// A product without a CPE is identified enough to leave out
+		`CREATE service:product_only SET name = "http", product = "acme-httpd", cpe = [];`,
+		`RELATE service:unknown_ssh_1->EVIDENCED_BY->banner:dropbear SET evidence_type = "banner";`,
+		`RELATE service:unknown_ssh_2->EVIDENCED_BY->banner:dropbear SET evidence_type = "banner";`,
+		`RELATE service:unknown_ssh_3->EVIDENCED_BY->banner:dropbear SET evidence_type = "banner";`,
+		`RELATE service:unknown_ftp->EVIDENCED_BY->banner:acme_ftp SET evidence_type = "banner";`,
+	} {
+		_, err := surrealdb.Query[interface{}](context.Background(), db, query, nil)
+		require.NoError(t, err)
+	}
+	defer func() {
+		_, _ = surrealdb.Query[interface{}](context.Background(), db, "DELETE banner; DELETE EVIDENCED_BY;", nil)
+	}()
+
+	executor := NewGraphQueryExecutor(db, zaptest.NewLogger(t))
+
+	t.Run("groups unidentified services by banner", func(t *testing.T) {
+		resp, err := executor.QueryUnidentifiedServices(context.Background(), 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultAggregateLimit, resp.Limit)
+		assert.Equal(t, []models.UnidentifiedBanner{
+			{Banner: "SSH-2.0-dropbear_2022.83", ServiceCount: 3},
+			{Banner: "", ServiceCount: 1},
+			{Banner: "220 acme FTP ready", ServiceCount: 1},
+		}, resp.Results)
+	})
+
+	t.Run("limit keeps the most common banners", func(t *testing.T) {
+		resp, err := executor.QueryUnidentifiedServices(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "SSH-2.0-dropbear_2022.83", resp.Results[0].Banner)
+	})
+}
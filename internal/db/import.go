@@ -0,0 +1,351 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	surrealmodels "github.com/surrealdb/surrealdb.go/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultImportBatchSize is how many records GraphImporter writes per query
+const DefaultImportBatchSize = 500
+
+// MaxImportLineBytes bounds a single line of an import; longer lines fail it
+const MaxImportLineBytes = 16 << 20
+
+// importBatchQuery upserts a batch of records prepared by importRow
+// A record that does not exist yet is created with its full data. One that does
+// has its other fields replaced but keeps its observation window monotonic, as
+// ingest does: first_seen only moves backward and last_seen only forward, so
+// importing an old backup over a live graph never shrinks it. An edge whose
+// endpoints are already joined under another id, in a table with a unique
+// index over its endpoints such as RUNS, is merged into that edge the same way.
+const importBatchQuery = `
+	FOR $record IN $records {
+		LET $first_seen = $record.first_seen;
+		LET $last_seen = $record.last_seen;
+		IF record::exists($record.id) {
+			UPDATE $record.id MERGE $record.fields;
+			IF $first_seen IS NOT NONE {
+				UPDATE $record.id SET first_seen = IF first_seen IS NONE OR $first_seen < first_seen THEN $first_seen ELSE first_seen END;
+			};
+			IF $last_seen IS NOT NONE {
+				UPDATE $record.id SET last_seen = IF last_seen IS NONE OR $last_seen > last_seen THEN $last_seen ELSE last_seen END;
+			};
+		} ELSE IF $record.in IS NONE {
+			CREATE $record.id CONTENT $record.data;
+		} ELSE {
+			LET $edge = (RELATE $record.in->$record.id->$record.out CONTENT $record.data ON DUPLICATE KEY UPDATE {
+				first_seen: IF $first_seen IS NOT NONE AND (first_seen IS NONE OR $first_seen < first_seen) THEN $first_seen ELSE first_seen END,
+				last_seen: IF $last_seen IS NOT NONE AND (last_seen IS NONE OR $last_seen > last_seen) THEN $last_seen ELSE last_seen END
+			});
+			UPDATE $edge.id MERGE $record.fields;
+		};
+	};
+`
+
+// importDatetimeFields are the fields of each graph table the schema declares as
+// datetimes. Export writes datetimes as RFC 3339 strings, and only these fields
+// are converted back, so a string that merely looks like a date stays a string.
+var importDatetimeFields = map[string][]string{
+	"host":         {"first_seen", "last_seen", "last_scanned_at", "asn_enriched_at", "geo_enriched_at"},
+	"port":         {"first_seen", "last_seen"},
+	"service":      {"first_seen", "last_seen", "cpe_updated_at"},
+	"banner":       {"first_seen"},
+	"tls_cert":     {"first_seen", "not_before", "not_after"},
+	"vuln":         {"first_seen", "last_updated"},
+	"HAS":          {"first_seen", "last_seen"},
+	"RUNS":         {"first_seen", "last_seen"},
+	"EVIDENCED_BY": {"first_seen"},
+	"AFFECTED_BY":  {"first_detected", "last_confirmed"},
+	"OBSERVED_AT":  {"ts"},
+}
+
+// isDatetimeField reports whether the schema declares field of table a datetime
+func isDatetimeField(table, field string) bool {
+	return slices.Contains(importDatetimeFields[table], field)
+}
+
+// GraphImporter loads an export written by GraphExporter back into the graph
+// Records are read and written in batches, so memory stays bounded by one batch
+// whatever the size of the export. Importing the same export twice leaves the
+// graph as it was after the first time.
+type GraphImporter struct {
+	writer    ImportWriter
+	logger    *zap.Logger
+	batchSize int
+}
+
+// ImportWriter writes batches of prepared import records
+type ImportWriter interface {
+	// UpsertBatch upserts records the way importBatchQuery does
+	UpsertBatch(ctx context.Context, records []map[string]interface{}) error
+}
+
+// NewGraphImporter creates a graph importer with the default batch size
+func NewGraphImporter(db *surrealdb.DB, logger *zap.Logger) *GraphImporter {
+	return NewGraphImporterWithWriter(&surrealImportWriter{db: db}, logger)
+}
+
+// NewGraphImporterWithWriter creates a graph importer on a custom writer (useful for testing)
+func NewGraphImporterWithWriter(writer ImportWriter, logger *zap.Logger) *GraphImporter {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &GraphImporter{
+		writer:    writer,
+		logger:    logger,
+		batchSize: DefaultImportBatchSize,
+	}
+}
+
+// Import reads JSON lines of models.ExportRecord from r and upserts them
+// Malformed lines are skipped and reported in the response, like malformed scan
+// lines at ingest. It stops at the first read or database error, returning the
+// counts written until then; the import can be repeated safely.
+func (i *GraphImporter) Import(ctx context.Context, r io.Reader) (models.ImportResponse, error) {
+	var (
+		resp    models.ImportResponse
+		batch   []map[string]interface{}
+		pending models.ImportResponse
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := i.writer.UpsertBatch(ctx, batch); err != nil {
+			return err
+		}
+		resp.Nodes += pending.Nodes
+		resp.Edges += pending.Edges
+		batch, pending = batch[:0], models.ImportResponse{}
+		return nil
+	}
+
+	skip := func(line int, reason error) {
+		resp.Skipped++
+		if len(resp.Errors) < models.MaxImportErrors {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("line %d: %v", line, reason))
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxImportLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		record, err := decodeImportRecord(raw)
+		if err != nil {
+			skip(line, err)
+			continue
+		}
+		row, err := importRow(record)
+		if err != nil {
+			skip(line, err)
+			continue
+		}
+
+		batch = append(batch, row)
+		if record.Kind == models.ExportKindEdge {
+			pending.Edges++
+		} else {
+			pending.Nodes++
+		}
+		if len(batch) == i.batchSize {
+			if err := flush(); err != nil {
+				return resp, fmt.Errorf("failed to write records before line %d: %w", line+1, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return resp, fmt.Errorf("failed to read line %d: %w", line+1, err)
+	}
+	if err := flush(); err != nil {
+		return resp, fmt.Errorf("failed to write records: %w", err)
+	}
+
+	i.logger.Debug("imported graph records",
+		zap.Int("nodes", resp.Nodes),
+		zap.Int("edges", resp.Edges),
+		zap.Int("skipped", resp.Skipped))
+
+	return resp, nil
+}
+
+// decodeImportRecord parses and validates one line of an export
+// Numbers are kept exact so integer fields stay integers.
+func decodeImportRecord(raw []byte) (models.ExportRecord, error) {
+	var record models.ExportRecord
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		return record, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := record.Validate(); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+// importRow prepares a record for importBatchQuery: its ids as record ids, its
+// data with JSON values converted back to database types, and the fields to
+// merge into an existing record, which leave out the observation window
+func importRow(record models.ExportRecord) (map[string]interface{}, error) {
+	id, err := parseRecordID(record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]interface{}, len(record.Data))
+	fields := make(map[string]interface{}, len(record.Data))
+	for field, value := range record.Data {
+		switch field {
+		case "id", "in", "out":
+			return nil, fmt.Errorf("data must not set %s", field)
+		}
+		if isDatetimeField(record.Table, field) {
+			if data[field], err = importDatetime(value); err != nil {
+				return nil, fmt.Errorf("field %s: %w", field, err)
+			}
+		} else {
+			data[field] = importValue(value)
+		}
+		if field != "first_seen" && field != "last_seen" {
+			fields[field] = data[field]
+		}
+	}
+
+	row := map[string]interface{}{
+		"id":     id,
+		"data":   data,
+		"fields": fields,
+	}
+	for _, field := range []string{"first_seen", "last_seen"} {
+		if seen, ok := data[field].(time.Time); ok {
+			row[field] = seen
+		}
+	}
+
+	if record.Kind == models.ExportKindEdge {
+		if row["in"], err = parseRecordID(record.In); err != nil {
+			return nil, err
+		}
+		if row["out"], err = parseRecordID(record.Out); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// importDatetime converts a datetime field back from the RFC 3339 string
+// Export wrote; null stays null, for optional datetimes
+func importDatetime(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid datetime %q", v)
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("datetime must be an RFC 3339 string, got %T", value)
+	}
+}
+
+// importValue converts a decoded JSON value back to what the database stored:
+// integral numbers to integers and other numbers to floats. Strings stay
+// strings; datetime fields go through importDatetime instead.
+func importValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = importValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = importValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// parseRecordID reverses recordIDString for the ids Export writes: table:key,
+// with the key in ⟨⟩ when it needs escaping. An unescaped numeric key is an
+// integer id.
+func parseRecordID(id string) (*surrealmodels.RecordID, error) {
+	table, key, found := strings.Cut(id, ":")
+	if !found || table == "" || key == "" {
+		return nil, fmt.Errorf("invalid record id %q", id)
+	}
+
+	if strings.HasPrefix(key, "⟨") && strings.HasSuffix(key, "⟩") {
+		key = strings.TrimSuffix(strings.TrimPrefix(key, "⟨"), "⟩")
+		key = strings.NewReplacer(`\⟩`, "⟩", `\\`, `\`).Replace(key)
+		rid := surrealmodels.NewRecordID(table, key)
+		return &rid, nil
+	}
+	if n, err := strconv.ParseInt(key, 10, 64); err == nil {
+		rid := surrealmodels.NewRecordID(table, n)
+		return &rid, nil
+	}
+	for _, ch := range key {
+		if !(ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z') {
+			return nil, fmt.Errorf("unsupported record id %q", id)
+		}
+	}
+	rid := surrealmodels.NewRecordID(table, key)
+	return &rid, nil
+}
+
+// surrealImportWriter writes import batches to SurrealDB
+type surrealImportWriter struct {
+	db *surrealdb.DB
+}
+
+// UpsertBatch upserts a batch of prepared records
+func (w *surrealImportWriter) UpsertBatch(ctx context.Context, records []map[string]interface{}) error {
+	result, err := surrealdb.Query[interface{}](ctx, w.db, importBatchQuery, map[string]interface{}{
+		"records": records,
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		for _, r := range *result {
+			if r.Error != nil {
+				return fmt.Errorf("query error: %w", r.Error)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	surrealmodels "github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// memoryImportWriter writes into graph the way importBatchQuery writes into the database
+type memoryImportWriter struct {
+	graph   map[string][]map[string]interface{}
+	batches int
+}
+
+func (w *memoryImportWriter) UpsertBatch(ctx context.Context, records []map[string]interface{}) error {
+	w.batches++
+	for _, record := range records {
+		id := record["id"].(*surrealmodels.RecordID)
+
+		var existing map[string]interface{}
+		for _, row := range w.graph[id.Table] {
+			if recordIDString(row["id"]) == id.String() {
+				existing = row
+			}
+		}
+		if existing == nil {
+			row := map[string]interface{}{"id": *id}
+			if in, ok := record["in"].(*surrealmodels.RecordID); ok {
+				row["in"], row["out"] = *in, *record["out"].(*surrealmodels.RecordID)
+			}
+			for field, value := range record["data"].(map[string]interface{}) {
+				row[field] = value
+			}
+			w.graph[id.Table] = append(w.graph[id.Table], row)
+			continue
+		}
+
+		for field, value := range record["fields"].(map[string]interface{}) {
+			existing[field] = value
+		}
+		if first, ok := record["first_seen"].(time.Time); ok {
+			if current, ok := existing["first_seen"].(time.Time); !ok || first.Before(current) {
+				existing["first_seen"] = first
+			}
+		}
+		if last, ok := record["last_seen"].(time.Time); ok {
+			if current, ok := existing["last_seen"].(time.Time); !ok || last.After(current) {
+				existing["last_seen"] = last
+			}
+		}
+	}
+	return nil
+}
+
+// memoryImporter returns an importer writing into graph, and its batch count
+func memoryImporter(graph map[string][]map[string]interface{}, batchSize int) (*GraphImporter, *int) {
+	w := &memoryImportWriter{graph: graph}
+	i := NewGraphImporterWithWriter(w, nil)
+	i.batchSize = batchSize
+	return i, &w.batches
+}
+
+// exportJSONL exports every graph table of graph as JSON lines
+func exportJSONL(t *testing.T, graph map[string][]map[string]interface{}) string {
+	t.Helper()
+
+	e, _ := stubExporter(graph, 2)
+	tables, err := models.ResolveExportTables(nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	_, err = e.Export(context.Background(), tables, func(record models.ExportRecord) error {
+		return encoder.Encode(record)
+	})
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func TestGraphImporter_RoundTrip(t *testing.T) {
+	seen := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := map[string][]map[string]interface{}{
+		"host": {
+			{"id": rid("host", "192_0_2_1"), "ip": "192.0.2.1", "first_seen": seen, "last_seen": seen.Add(time.Hour)},
+			{"id": rid("host", "2001_db8__1"), "ip": "2001:db8::1", "first_seen": seen, "last_seen": seen},
+		},
+		"port": {
+			{"id": rid("port", "port_22_tcp"), "number": 22, "protocol": "tcp"},
+		},
+		"service": {
+			{"id": rid("service", "ssh"), "name": "ssh", "cpe": []interface{}{"cpe:2.3:a:openbsd:openssh:9.6:*:*:*:*:*:*:*"}, "confidence": 0.25},
+		},
+		"HAS": {
+			{"id": rid("HAS", "e1"), "in": rid("host", "192_0_2_1"), "out": rid("port", "port_22_tcp"), "first_seen": seen},
+			{"id": rid("HAS", "e2"), "in": rid("host", "2001_db8__1"), "out": rid("port", "port_22_tcp"), "first_seen": seen},
+		},
+		"RUNS": {
+			{"id": rid("RUNS", "e3"), "in": rid("port", "port_22_tcp"), "out": rid("service", "ssh")},
+		},
+	}
+	dump := exportJSONL(t, source)
+
+	// Import into an empty graph
+	restored := map[string][]map[string]interface{}{}
+	importer, batches := memoryImporter(restored, 3)
+	resp, err := importer.Import(context.Background(), strings.NewReader(dump))
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportResponse{Nodes: 4, Edges: 3}, resp)
+	assert.Equal(t, 3, *batches)
+
+	assert.Equal(t, dump, exportJSONL(t, restored), "the restored graph exports identically")
+
+	// Importing again changes nothing
+	resp, err = importer.Import(context.Background(), strings.NewReader(dump))
+	require.NoError(t, err)
+	assert.Equal(t, 7, resp.Nodes+resp.Edges)
+	assert.Equal(t, dump, exportJSONL(t, restored))
+}
+
+func TestGraphImporter_KeepsObservationWindow(t *testing.T) {
+	seen := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	graph := map[string][]map[string]interface{}{
+		"host": {{"id": rid("host", "192_0_2_1"), "ip": "192.0.2.1", "first_seen": seen, "last_seen": seen.Add(time.Hour)}},
+	}
+	importer, _ := memoryImporter(graph, 10)
+
+	// An older backup widens the window backward but not forward
+	older := `{"table":"host","kind":"node","id":"host:⟨192_0_2_1⟩","data":{"ip":"192.0.2.1","asn":64500,"first_seen":"2026-02-01T00:00:00Z","last_seen":"2026-02-02T00:00:00Z"}}`
+	_, err := importer.Import(context.Background(), strings.NewReader(older))
+	require.NoError(t, err)
+
+	host := graph["host"][0]
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), host["first_seen"])
+	assert.Equal(t, seen.Add(time.Hour), host["last_seen"])
+	assert.Equal(t, int64(64500), host["asn"], "other fields are replaced")
+}
+
+func TestGraphImporter_SkipsMalformedLines(t *testing.T) {
+	input := strings.Join([]string{
+		`{"table":"host","kind":"node","id":"host:a","data":{"ip":"192.0.2.1"}}`,
+		`not json`,
+		``,
+		`{"table":"job","kind":"node","id":"job:a","data":{}}`,
+		`{"table":"host","kind":"edge","id":"host:b","data":{}}`,
+		`{"table":"host","kind":"node","id":"port:b","data":{}}`,
+		`{"table":"HAS","kind":"edge","id":"HAS:e1","in":"host:a","data":{}}`,
+		`{"table":"host","kind":"node","id":"host:c","data":{"id":"host:d"}}`,
+		`{"table":"HAS","kind":"edge","id":"HAS:e2","in":"host:a","out":"port:port_22_tcp","data":{}}`,
+	}, "\n")
+
+	graph := map[string][]map[string]interface{}{}
+	importer, _ := memoryImporter(graph, 10)
+	resp, err := importer.Import(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, resp.Nodes)
+	assert.Equal(t, 1, resp.Edges)
+	assert.Equal(t, 6, resp.Skipped)
+	require.Len(t, resp.Errors, 6)
+	assert.True(t, strings.HasPrefix(resp.Errors[0], "line 2: invalid JSON"), resp.Errors[0])
+	assert.Contains(t, resp.Errors[1], `line 4: unknown table "job"`)
+	assert.Contains(t, resp.Errors[5], "line 8: data must not set id")
+}
+
+// failingImportWriter fails its failOn-th batch
+type failingImportWriter struct {
+	failOn int
+	calls  int
+}
+
+func (w *failingImportWriter) UpsertBatch(ctx context.Context, records []map[string]interface{}) error {
+	w.calls++
+	if w.calls == w.failOn {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func TestGraphImporter_StopsOnWriteError(t *testing.T) {
+	importer := NewGraphImporterWithWriter(&failingImportWriter{failOn: 2}, nil)
+	importer.batchSize = 1
+
+	input := `{"table":"host","kind":"node","id":"host:a","data":{}}
+{"table":"host","kind":"node","id":"host:b","data":{}}
+{"table":"host","kind":"node","id":"host:c","data":{}}`
+	resp, err := importer.Import(context.Background(), strings.NewReader(input))
+
+	assert.ErrorContains(t, err, "connection reset")
+	assert.Equal(t, 1, resp.Nodes, "only the batch that was written is counted")
+}
+
+func TestParseRecordID(t *testing.T) {
+	for _, id := range []surrealmodels.RecordID{
+		rid("host", "abc"),
+		rid("host", "192_0_2_1"),
+		rid("port", "port_22_tcp"),
+		rid("service", "a⟩b\\c"),
+		surrealmodels.NewRecordID("HAS", int64(42)),
+	} {
+		parsed, err := parseRecordID(recordIDString(id))
+		require.NoError(t, err, id.String())
+		assert.Equal(t, id.String(), parsed.String())
+		assert.Equal(t, id.ID, parsed.ID)
+	}
+
+	for _, bad := range []string{"host", "host:", ":abc", "host:a-b", "host:[1,2]"} {
+		_, err := parseRecordID(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestImportValue(t *testing.T) {
+	var data map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(`{"n":22,"f":0.25,"t":"2026-03-01T12:00:00Z","s":"nginx","l":["2026-03-01T12:00:00.5Z",1]}`))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&data))
+
+	converted := importValue(data).(map[string]interface{})
+	assert.Equal(t, int64(22), converted["n"])
+	assert.Equal(t, 0.25, converted["f"])
+	assert.Equal(t, "2026-03-01T12:00:00Z", converted["t"], "strings are never guessed to be datetimes")
+	assert.Equal(t, "nginx", converted["s"])
+	assert.Equal(t, []interface{}{"2026-03-01T12:00:00.5Z", int64(1)}, converted["l"])
+}
+
+func TestImportRow_DatetimeFields(t *testing.T) {
+	record, err := decodeImportRecord([]byte(`{"table":"service","kind":"node","id":"service:s1",` +
+		`"data":{"name":"http","version":"2026-03-01T12:00:00Z","first_seen":"2026-03-01T12:00:00Z","cpe_updated_at":null}}`))
+	require.NoError(t, err)
+
+	row, err := importRow(record)
+	require.NoError(t, err)
+	data := row["data"].(map[string]interface{})
+	assert.Equal(t, "2026-03-01T12:00:00Z", data["version"], "a field the schema types as a string stays a string")
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), data["first_seen"])
+	assert.Equal(t, data["first_seen"], row["first_seen"])
+	assert.Nil(t, data["cpe_updated_at"])
+
+	record.Data["last_seen"] = "yesterday"
+	_, err = importRow(record)
+	assert.ErrorContains(t, err, "last_seen")
+}
+
+func TestImportDatetimeFields_MatchSchema(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+
+	declared := regexp.MustCompile(`DEFINE FIELD IF NOT EXISTS (\w+) ON TABLE (\w+) TYPE (?:option<)?datetime\b`)
+	want := map[string][]string{}
+	for _, migration := range migrations {
+		for _, match := range declared.FindAllStringSubmatch(migration.SQL, -1) {
+			if models.IsExportTable(match[2]) {
+				want[match[2]] = append(want[match[2]], match[1])
+			}
+		}
+	}
+
+	require.Len(t, importDatetimeFields, len(want))
+	for table, fields := range want {
+		assert.ElementsMatch(t, fields, importDatetimeFields[table], table)
+	}
+}
+
+func TestGraphImporter_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	wipe := `DELETE HAS; DELETE host; DELETE port;`
+	_, err := surrealdb.Query[interface{}](ctx, db, wipe+`
+		CREATE host:⟨192_0_2_1⟩ SET ip = '192.0.2.1', first_seen = d'2026-03-01T00:00:00Z', last_seen = d'2026-03-02T00:00:00Z';
+		CREATE host:⟨192_0_2_2⟩ SET ip = '192.0.2.2';
+		CREATE port:port_22_tcp SET number = 22, protocol = 'tcp';
+		RELATE host:⟨192_0_2_1⟩->HAS->port:port_22_tcp;
+		RELATE host:⟨192_0_2_2⟩->HAS->port:port_22_tcp;
+	`, nil)
+	require.NoError(t, err)
+
+	export := func() string {
+		e := NewGraphExporter(db, nil)
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		_, err := e.Export(ctx, []string{"host", "port", "HAS"}, func(record models.ExportRecord) error {
+			return encoder.Encode(record)
+		})
+		require.NoError(t, err)
+		return buf.String()
+	}
+	dump := export()
+
+	_, err = surrealdb.Query[interface{}](ctx, db, wipe, nil)
+	require.NoError(t, err)
+
+	importer := NewGraphImporter(db, nil)
+	importer.batchSize = 2
+	resp, err := importer.Import(ctx, strings.NewReader(dump))
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportResponse{Nodes: 3, Edges: 2}, resp)
+	assert.Equal(t, dump, export())
+
+	// A second import is a no-op
+	_, err = importer.Import(ctx, strings.NewReader(dump))
+	require.NoError(t, err)
+	assert.Equal(t, dump, export())
+}
+
+func TestGraphImporter_Integration_MergesEdgeUnderAnotherID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	// RUNS allows one edge per port and service; the live graph already has it under another id
+	_, err := surrealdb.Query[interface{}](ctx, db, `
+		DELETE RUNS; DELETE port; DELETE service;
+		CREATE port:port_22_tcp SET number = 22, protocol = 'tcp';
+		CREATE service:ssh SET name = 'ssh';
+		RELATE port:port_22_tcp->RUNS:live->service:ssh SET first_seen = d'2026-03-02T00:00:00Z', last_seen = d'2026-03-02T00:00:00Z';
+	`, nil)
+	require.NoError(t, err)
+
+	dump := `{"table":"RUNS","kind":"edge","id":"RUNS:backup","in":"port:port_22_tcp","out":"service:ssh","data":{"first_seen":"2026-03-01T00:00:00Z","last_seen":"2026-03-01T00:00:00Z"}}`
+	resp, err := NewGraphImporter(db, nil).Import(ctx, strings.NewReader(dump))
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Edges)
+
+	windows, err := surrealdb.Query[[]bool](ctx, db,
+		`SELECT VALUE first_seen = d'2026-03-01T00:00:00Z' AND last_seen = d'2026-03-02T00:00:00Z' FROM RUNS;`, nil)
+	require.NoError(t, err)
+	require.Len(t, (*windows)[0].Result, 1, "the backup edge is merged into the live one")
+	assert.True(t, (*windows)[0].Result[0], "the merged edge keeps the wider observation window")
+}
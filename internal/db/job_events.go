@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// DefaultJobEventLimit is the default number of events returned by Since
+const DefaultJobEventLimit = 500
+
+// JobEventStore persists job state-transition events
+// The workflow service publishes to it and the API server polls it, which
+// bridges the two processes without a separate message broker.
+type JobEventStore struct {
+	db     *surrealdb.DB
+	logger *zap.Logger
+}
+
+// NewJobEventStore creates a new job event store
+func NewJobEventStore(db *surrealdb.DB, logger *zap.Logger) *JobEventStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &JobEventStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// jobEventRow is a job_event record; the event ID is stored as event_id
+// because SurrealDB reserves id for the record ID
+type jobEventRow struct {
+	EventID    string    `json:"event_id"`
+	JobID      string    `json:"job_id"`
+	ScannerKey string    `json:"scanner_key"`
+	State      string    `json:"state"`
+	Error      *string   `json:"error,omitempty"`
+	HostCount  int       `json:"host_count"`
+	PortCount  int       `json:"port_count"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// toEvent converts a stored row to a models.JobEvent
+func (r jobEventRow) toEvent() models.JobEvent {
+	event := models.JobEvent{
+		ID:         r.EventID,
+		JobID:      r.JobID,
+		ScannerKey: r.ScannerKey,
+		State:      models.JobState(r.State),
+		HostCount:  r.HostCount,
+		PortCount:  r.PortCount,
+		Timestamp:  r.Timestamp,
+	}
+	if r.Error != nil {
+		event.Error = *r.Error
+	}
+	return event
+}
+
+// Publish stores an event, assigning a time-ordered ID and timestamp if unset
+func (s *JobEventStore) Publish(ctx context.Context, event models.JobEvent) error {
+	if event.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate job event ID: %w", err)
+		}
+		event.ID = id.String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	var errorMsg *string
+	if event.Error != "" {
+		errorMsg = &event.Error
+	}
+
+	query := `
+		CREATE type::thing('job_event', $event_id) CONTENT {
+			event_id: $event_id,
+			job_id: $job_id,
+			scanner_key: $scanner_key,
+			state: $state,
+			error: $error,
+			host_count: $host_count,
+			port_count: $port_count,
+			timestamp: $timestamp
+		};
+	`
+	result, err := surrealdb.Query[interface{}](ctx, s.db, query, map[string]interface{}{
+		"event_id":    event.ID,
+		"job_id":      event.JobID,
+		"scanner_key": event.ScannerKey,
+		"state":       event.State.String(),
+		"error":       errorMsg,
+		"host_count":  event.HostCount,
+		"port_count":  event.PortCount,
+		"timestamp":   event.Timestamp,
+	})
+	if err != nil {
+		s.logger.Error("failed to publish job event",
+			zap.Error(err),
+			zap.String("job_id", event.JobID),
+			zap.String("state", event.State.String()))
+		return fmt.Errorf("failed to publish job event: %w", err)
+	}
+	if result != nil && len(*result) > 0 && (*result)[0].Error != nil {
+		return fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+
+	return nil
+}
+
+// Since returns up to limit events published after afterID, oldest first
+func (s *JobEventStore) Since(ctx context.Context, afterID string, limit int) ([]models.JobEvent, error) {
+	if limit < 1 {
+		limit = DefaultJobEventLimit
+	}
+
+	query := `
+		SELECT event_id, job_id, scanner_key, state, error, host_count, port_count, timestamp
+		FROM job_event
+		WHERE event_id > $after
+		ORDER BY event_id
+		LIMIT $limit
+	`
+	return s.query(ctx, query, map[string]interface{}{
+		"after": afterID,
+		"limit": limit,
+	})
+}
+
+// LatestID returns the ID of the most recent event, or "" if there are none
+func (s *JobEventStore) LatestID(ctx context.Context) (string, error) {
+	query := `
+		SELECT event_id, job_id, scanner_key, state, error, host_count, port_count, timestamp
+		FROM job_event
+		ORDER BY event_id DESC
+		LIMIT 1
+	`
+	events, err := s.query(ctx, query, nil)
+	if err != nil || len(events) == 0 {
+		return "", err
+	}
+	return events[0].ID, nil
+}
+
+// query runs a job_event SELECT and converts the rows to events
+func (s *JobEventStore) query(ctx context.Context, query string, params map[string]interface{}) ([]models.JobEvent, error) {
+	result, err := surrealdb.Query[[]jobEventRow](ctx, s.db, query, params)
+	if err != nil {
+		s.logger.Error("failed to query job events",
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to query job events: %w", err)
+	}
+
+	events := make([]models.JobEvent, 0)
+	if result != nil && len(*result) > 0 {
+		if (*result)[0].Error != nil {
+			return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+		}
+		for _, row := range (*result)[0].Result {
+			events = append(events, row.toEvent())
+		}
+	}
+
+	return events, nil
+}
+
+// publishJobTransition records a state change made by this package
+// Failures are logged rather than returned: the job write has already succeeded.
+func publishJobTransition(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, job *models.Job) {
+	event := models.JobEvent{
+		JobID:      job.ID,
+		ScannerKey: job.ScannerKey,
+		State:      job.State,
+		Timestamp:  job.UpdatedAt,
+	}
+	if err := NewJobEventStore(db, logger).Publish(ctx, event); err != nil {
+		logger.Warn("failed to publish job event",
+			zap.Error(err),
+			zap.String("job_id", job.ID))
+	}
+}
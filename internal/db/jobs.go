@@ -38,6 +38,7 @@ func CreateJob(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, scanne
 	query := `CREATE job CONTENT {
 		id: $id,
 		scanner_key: $scanner_key,
+		scanner_id: $scanner_id,
 		state: $state,
 		created_at: $created_at,
 		updated_at: $updated_at,
@@ -48,6 +49,7 @@ func CreateJob(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, scanne
 	result, err := surrealdb.Query[map[string]interface{}](ctx, db, query, map[string]interface{}{
 		"id":          job.ID,
 		"scanner_key": job.ScannerKey,
+		"scanner_id":  models.ScannerID(job.ScannerKey),
 		"state":       job.State.String(),
 		"created_at":  job.CreatedAt,
 		"updated_at":  job.UpdatedAt,
@@ -73,6 +75,8 @@ func CreateJob(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, scanne
 		zap.String("scanner_key", maskPublicKey(scannerKey)),
 		zap.String("state", job.State.String()))
 
+	publishJobTransition(ctx, db, logger, job)
+
 	return job, nil
 }
 
@@ -144,7 +148,7 @@ func UpdateJobState(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, j
 			zap.String("job_id", jobID),
 			zap.String("current_state", job.State.String()),
 			zap.String("new_state", newState.String()))
-		return fmt.Errorf("invalid state transition from %s to %s", job.State, newState)
+		return fmt.Errorf("%w from %s to %s", models.ErrInvalidTransition, job.State, newState)
 	}
 
 	now := time.Now().UTC()
@@ -160,7 +164,7 @@ func UpdateJobState(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, j
 	}
 
 	// Add completed_at for terminal states
-	if newState == models.JobStateCompleted || newState == models.JobStateFailed {
+	if newState.IsTerminal() {
 		query += `, completed_at = $completed_at`
 		params["completed_at"] = now
 	}
@@ -199,6 +203,76 @@ func UpdateJobState(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, j
 	return nil
 }
 
+// CancelJob moves a pending or processing job to the cancelled state
+// Returns nil if the job doesn't exist, and an error wrapping models.ErrInvalidTransition
+// if the job has already finished. Cancelling an already-cancelled job is a no-op.
+// The running workflow notices the cancellation between its durable steps.
+func CancelJob(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, jobID string) (*models.Job, error) {
+	job, err := GetJob(ctx, db, logger, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job for cancellation: %w", err)
+	}
+	if job == nil {
+		return nil, nil
+	}
+
+	if job.State == models.JobStateCancelled {
+		return job, nil
+	}
+
+	previousState := job.State
+	if err := job.TransitionTo(models.JobStateCancelled); err != nil {
+		logger.Warn("job cannot be cancelled",
+			zap.String("job_id", jobID),
+			zap.String("current_state", previousState.String()))
+		return nil, err
+	}
+
+	// Only cancel if the job hasn't finished since it was read
+	query := `UPDATE job SET
+		state = $state,
+		updated_at = $updated_at,
+		completed_at = $completed_at
+		WHERE id = $id AND state IN $cancellable`
+
+	result, err := surrealdb.Query[[]map[string]interface{}](ctx, db, query, map[string]interface{}{
+		"id":           jobID,
+		"state":        models.JobStateCancelled.String(),
+		"updated_at":   job.UpdatedAt,
+		"completed_at": *job.CompletedAt,
+		"cancellable":  []string{models.JobStatePending.String(), models.JobStateProcessing.String()},
+	})
+	if err != nil {
+		logger.Error("failed to cancel job",
+			zap.Error(err),
+			zap.String("job_id", jobID))
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	if result == nil || len(*result) == 0 {
+		return nil, fmt.Errorf("failed to cancel job: empty result")
+	}
+	if (*result)[0].Error != nil {
+		logger.Error("query returned error",
+			zap.Error((*result)[0].Error),
+			zap.String("job_id", jobID))
+		return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+
+	// No row updated means the job reached a terminal state concurrently
+	if len((*result)[0].Result) == 0 {
+		return nil, fmt.Errorf("%w: job %s finished before it could be cancelled", models.ErrInvalidTransition, jobID)
+	}
+
+	logger.Info("job cancelled",
+		zap.String("job_id", jobID),
+		zap.String("previous_state", previousState.String()))
+
+	publishJobTransition(ctx, db, logger, job)
+
+	return job, nil
+}
+
 // ListJobs retrieves a paginated list of jobs based on filters
 func ListJobs(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, req models.JobListRequest) (*models.JobListResponse, error) {
 	// Validate the request
@@ -273,8 +347,11 @@ func ListJobs(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, req mod
 			for _, jobData := range queryResult.Result {
 				job, err := parseJobResult(jobData)
 				if err != nil {
-					logger.Warn("failed to parse job in list",
-						zap.Error(err))
+					// Skip corrupt rows (e.g. an unknown state) rather than failing the whole page
+					logger.Warn("skipping job with invalid data in list",
+						zap.Error(err),
+						zap.Any("id", jobData["id"]),
+						zap.Any("state", jobData["state"]))
 					continue
 				}
 				jobs = append(jobs, *job)
@@ -332,11 +409,15 @@ func parseJobResult(data map[string]interface{}) (*models.Job, error) {
 		return nil, fmt.Errorf("missing or invalid scanner_key field")
 	}
 
-	if state, ok := data["state"].(string); ok {
-		job.State = models.JobState(state)
-	} else {
+	stateStr, ok := data["state"].(string)
+	if !ok {
 		return nil, fmt.Errorf("missing or invalid state field")
 	}
+	state, err := models.ParseJobState(stateStr)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", job.ID, err)
+	}
+	job.State = state
 
 	// Parse timestamps
 	if createdAt, err := parseTimeField(data, "created_at"); err == nil {
@@ -363,6 +444,32 @@ func parseJobResult(data map[string]interface{}) (*models.Job, error) {
 		job.PortCount = portCount
 	}
 
+	// Parse optional progress and per-step timings recorded by the ingest workflow
+	if progress, ok := data["progress"].(map[string]interface{}); ok {
+		done, _ := getIntField(progress, "hosts_done")
+		total, _ := getIntField(progress, "hosts_total")
+		job.Progress = &models.JobProgress{HostsDone: done, HostsTotal: total}
+	}
+	if steps, ok := data["step_durations_ms"].(map[string]interface{}); ok && len(steps) > 0 {
+		job.StepDurations = make(map[string]int64, len(steps))
+		for step := range steps {
+			if ms, ok := getIntField(steps, step); ok {
+				job.StepDurations[step] = int64(ms)
+			}
+		}
+	}
+
+	// Parse the optional completion callback outcome
+	if callbackState, ok := data["callback_state"].(string); ok {
+		job.CallbackState = callbackState
+	}
+	if attempts, ok := getIntField(data, "callback_attempts"); ok {
+		job.CallbackAttempts = attempts
+	}
+	if callbackErr, ok := data["callback_error"].(string); ok && callbackErr != "" {
+		job.CallbackError = &callbackErr
+	}
+
 	return job, nil
 }
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJobStateIsValid(t *testing.T) {
@@ -34,6 +35,11 @@ func TestJobStateIsValid(t *testing.T) {
 			state:    models.JobStateFailed,
 			expected: true,
 		},
+		{
+			name:     "cancelled is valid",
+			state:    models.JobStateCancelled,
+			expected: true,
+		},
 		{
 			name:     "invalid state",
 			state:    models.JobState("invalid"),
@@ -49,6 +55,86 @@ func TestJobStateIsValid(t *testing.T) {
 	}
 }
 
+func TestParseJobState(t *testing.T) {
+	for _, valid := range []string{"pending", "processing", "completed", "failed"} {
+		t.Run(valid, func(t *testing.T) {
+			state, err := models.ParseJobState(valid)
+			require.NoError(t, err)
+			assert.Equal(t, models.JobState(valid), state)
+		})
+	}
+
+	for _, invalid := range []string{"processng", "", "PENDING", "done"} {
+		t.Run("rejects "+invalid, func(t *testing.T) {
+			state, err := models.ParseJobState(invalid)
+			assert.ErrorIs(t, err, models.ErrInvalidJobState)
+			assert.Empty(t, state)
+		})
+	}
+}
+
+func TestParseJobResult_State(t *testing.T) {
+	row := func(state interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":          "job:abc",
+			"scanner_key": "scanner-key",
+			"state":       state,
+		}
+	}
+
+	job, err := parseJobResult(row("processing"))
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStateProcessing, job.State)
+
+	// A corrupt row must be rejected rather than producing a job in an unknown state
+	_, err = parseJobResult(row("processng"))
+	assert.ErrorIs(t, err, models.ErrInvalidJobState)
+	assert.Contains(t, err.Error(), "job:abc")
+
+	_, err = parseJobResult(row(42))
+	assert.Error(t, err)
+}
+
+func TestParseJobResult_ProgressAndTimings(t *testing.T) {
+	// Counts arrive from SurrealDB as unsigned integers
+	job, err := parseJobResult(map[string]interface{}{
+		"id":          "job:abc",
+		"scanner_key": "scanner-key",
+		"state":       "completed",
+		"progress": map[string]interface{}{
+			"hosts_done":  uint64(250),
+			"hosts_total": uint64(250),
+		},
+		"step_durations_ms": map[string]interface{}{
+			models.JobStepParse:   uint64(12),
+			models.JobStepPersist: uint64(3400),
+		},
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, job.Progress)
+	assert.Equal(t, models.JobProgress{HostsDone: 250, HostsTotal: 250}, *job.Progress)
+	assert.Equal(t, 100.0, job.Progress.Percent())
+	assert.Equal(t, map[string]int64{models.JobStepParse: 12, models.JobStepPersist: 3400}, job.StepDurations)
+
+	// Jobs that haven't started processing carry neither
+	job, err = parseJobResult(map[string]interface{}{
+		"id":          "job:def",
+		"scanner_key": "scanner-key",
+		"state":       "pending",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, job.Progress)
+	assert.Nil(t, job.StepDurations)
+}
+
+func TestJobProgressPercent(t *testing.T) {
+	assert.Equal(t, 0.0, models.JobProgress{HostsDone: 0, HostsTotal: 200}.Percent())
+	assert.Equal(t, 50.0, models.JobProgress{HostsDone: 100, HostsTotal: 200}.Percent())
+	assert.Equal(t, 100.0, models.JobProgress{HostsDone: 200, HostsTotal: 200}.Percent())
+	assert.Equal(t, 100.0, models.JobProgress{}.Percent())
+}
+
 func TestJobCanTransition(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -86,6 +172,36 @@ func TestJobCanTransition(t *testing.T) {
 			newState:     models.JobStateProcessing,
 			expected:     false,
 		},
+		{
+			name:         "pending to cancelled - valid",
+			currentState: models.JobStatePending,
+			newState:     models.JobStateCancelled,
+			expected:     true,
+		},
+		{
+			name:         "processing to cancelled - valid",
+			currentState: models.JobStateProcessing,
+			newState:     models.JobStateCancelled,
+			expected:     true,
+		},
+		{
+			name:         "completed to cancelled - invalid",
+			currentState: models.JobStateCompleted,
+			newState:     models.JobStateCancelled,
+			expected:     false,
+		},
+		{
+			name:         "failed to cancelled - invalid",
+			currentState: models.JobStateFailed,
+			newState:     models.JobStateCancelled,
+			expected:     false,
+		},
+		{
+			name:         "cancelled to processing - invalid",
+			currentState: models.JobStateCancelled,
+			newState:     models.JobStateProcessing,
+			expected:     false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -104,6 +220,21 @@ func TestJobCanTransition(t *testing.T) {
 	}
 }
 
+func TestJobTransitionTo_Cancelled(t *testing.T) {
+	job := &models.Job{ID: "test-job-id", State: models.JobStateProcessing}
+
+	require.NoError(t, job.TransitionTo(models.JobStateCancelled))
+	assert.Equal(t, models.JobStateCancelled, job.State)
+	assert.True(t, job.State.IsTerminal())
+	assert.NotNil(t, job.CompletedAt, "cancelled is a terminal state")
+
+	// Cancelling a completed job is rejected
+	completed := &models.Job{ID: "test-job-id", State: models.JobStateCompleted}
+	err := completed.TransitionTo(models.JobStateCancelled)
+	assert.ErrorIs(t, err, models.ErrInvalidTransition)
+	assert.Equal(t, models.JobStateCompleted, completed.State)
+}
+
 func TestMaskPublicKey(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// schemaFS holds the baseline schema and the numbered migrations that follow it
+//
+//go:embed schema/schema.surql schema/migrations/*.surql
+var schemaFS embed.FS
+
+// Migration is one versioned step of the graph schema
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded schema migrations in version order
+// Version 1 is schema/schema.surql; later versions are schema/migrations/NNNN_name.surql.
+func Migrations() ([]Migration, error) {
+	baseline, err := schemaFS.ReadFile("schema/schema.surql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline schema: %w", err)
+	}
+	migrations := []Migration{{Version: 1, Name: "baseline", SQL: string(baseline)}}
+
+	entries, err := schemaFS.ReadDir("schema/migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	seen := map[int]string{1: "schema.surql"}
+	for _, entry := range entries {
+		version, name, err := parseMigrationFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration %s reuses version %d of %s", entry.Name(), version, other)
+		}
+		seen[version] = entry.Name()
+
+		sql, err := schemaFS.ReadFile(path.Join("schema/migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(sql)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parseMigrationFilename splits "0002_affected_by_indexes.surql" into its version and name
+func parseMigrationFilename(filename string) (int, string, error) {
+	prefix, name, ok := strings.Cut(strings.TrimSuffix(filename, ".surql"), "_")
+	if !ok || name == "" {
+		return 0, "", fmt.Errorf("migration %s must be named NNNN_name.surql", filename)
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version < 2 {
+		return 0, "", fmt.Errorf("migration %s must start with a version of 2 or more", filename)
+	}
+	return version, name, nil
+}
+
+// Migrator applies the embedded schema migrations a database has not seen yet
+// Applied versions are recorded in the schema_migration table, so running it
+// again only applies migrations added since the last run.
+type Migrator struct {
+	store  SchemaStore
+	logger *zap.Logger
+
+	// namespacedHostIDs is set once migration 10 is seen applied
+	namespacedHostIDs atomic.Bool
+}
+
+// SchemaStore reads and records the migrations a database has applied
+type SchemaStore interface {
+	// AppliedVersions returns the versions recorded as applied
+	AppliedVersions(ctx context.Context) (map[int]bool, error)
+	// Apply runs a migration and records its version
+	Apply(ctx context.Context, migration Migration) error
+}
+
+// NewMigrator creates a migrator for the embedded migrations on the database
+func NewMigrator(db *surrealdb.DB, logger *zap.Logger) *Migrator {
+	return NewMigratorWithStore(&surrealSchemaStore{db: db}, logger)
+}
+
+// NewMigratorWithStore creates a migrator on a custom store (useful for testing)
+func NewMigratorWithStore(store SchemaStore, logger *zap.Logger) *Migrator {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Migrator{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Migrate applies every pending migration in version order, stopping at the
+// first failure. With dryRun it only reports what would be applied.
+func (m *Migrator) Migrate(ctx context.Context, dryRun bool) (models.MigrateResponse, error) {
+	resp := models.MigrateResponse{
+		Applied: []models.MigrationInfo{},
+		DryRun:  dryRun,
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return resp, err
+	}
+	resp.LatestVersion = migrations[len(migrations)-1].Version
+
+	applied, err := m.store.AppliedVersions(ctx)
+	if err != nil {
+		return resp, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	for version := range applied {
+		resp.CurrentVersion = max(resp.CurrentVersion, version)
+	}
+
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		info := models.MigrationInfo{Version: migration.Version, Name: migration.Name}
+		if dryRun {
+			resp.Applied = append(resp.Applied, info)
+			continue
+		}
+
+		if err := m.store.Apply(ctx, migration); err != nil {
+			return resp, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		resp.Applied = append(resp.Applied, info)
+		resp.CurrentVersion = max(resp.CurrentVersion, migration.Version)
+
+		m.logger.Info("applied schema migration",
+			zap.Int("version", migration.Version),
+			zap.String("name", migration.Name))
+	}
+
+	return resp, nil
+}
+
+// AppliedVersions returns the versions of the migrations the database has applied
+func (m *Migrator) AppliedVersions(ctx context.Context) (map[int]bool, error) {
+	applied, err := m.store.AppliedVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// HostIDScheme returns the host ID scheme of the applied schema, reading the
+// applied migrations on every call until migration 10 has rewritten the hosts.
+// Migrations are never reverted, so from then on it is namespaced without a query.
+func (m *Migrator) HostIDScheme(ctx context.Context) (models.HostIDScheme, error) {
+	if m.namespacedHostIDs.Load() {
+		return models.HostIDSchemeNamespaced, nil
+	}
+
+	applied, err := m.AppliedVersions(ctx)
+	if err != nil {
+		return "", err
+	}
+	scheme := models.HostIDSchemeForSchema(applied)
+	if scheme == models.HostIDSchemeNamespaced {
+		m.namespacedHostIDs.Store(true)
+	}
+	return scheme, nil
+}
+
+// surrealSchemaStore records applied migrations in the schema_migration table
+type surrealSchemaStore struct {
+	db *surrealdb.DB
+}
+
+// AppliedVersions reads the versions recorded in schema_migration
+func (s *surrealSchemaStore) AppliedVersions(ctx context.Context) (map[int]bool, error) {
+	result, err := surrealdb.Query[[]int](ctx, s.db, `
+		DEFINE TABLE IF NOT EXISTS schema_migration SCHEMALESS;
+		SELECT VALUE version FROM schema_migration;
+	`, nil)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(*result) < 2 {
+		return nil, fmt.Errorf("unexpected result for applied migrations")
+	}
+
+	applied := make(map[int]bool)
+	for _, version := range (*result)[1].Result {
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+// Apply runs a migration and records its version in one transaction, so a
+// failed migration leaves no partial schema and can be retried
+func (s *surrealSchemaStore) Apply(ctx context.Context, migration Migration) error {
+	query := "BEGIN TRANSACTION;\n" + migration.SQL + `
+		CREATE type::thing('schema_migration', $version) SET version = $version, name = $name, applied_at = time::now();
+		COMMIT TRANSACTION;`
+
+	result, err := surrealdb.Query[interface{}](ctx, s.db, query, map[string]interface{}{
+		"version": migration.Version,
+		"name":    migration.Name,
+	})
+	if err != nil {
+		return err
+	}
+	if result != nil {
+		for _, statement := range *result {
+			if statement.Error != nil {
+				return statement.Error
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// fakeSchemaStore records applied migrations in memory, like the schema_migration table
+type fakeSchemaStore struct {
+	applied  map[int]bool
+	executed []string // SQL of every migration run, in order
+	failOn   int      // version whose apply fails, if non-zero
+	reads    int      // calls to AppliedVersions
+}
+
+func (s *fakeSchemaStore) AppliedVersions(ctx context.Context) (map[int]bool, error) {
+	s.reads++
+	applied := make(map[int]bool, len(s.applied))
+	for version := range s.applied {
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+func (s *fakeSchemaStore) Apply(ctx context.Context, migration Migration) error {
+	if migration.Version == s.failOn {
+		return errors.New("statement failed")
+	}
+	s.executed = append(s.executed, migration.SQL)
+	s.applied[migration.Version] = true
+	return nil
+}
+
+// embeddedMigrations returns the embedded migrations, failing the test if they don't parse
+func embeddedMigrations(t *testing.T) []Migration {
+	t.Helper()
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	return migrations
+}
+
+// newTestMigrator creates a migrator for the embedded migrations backed by store
+func newTestMigrator(t *testing.T, store *fakeSchemaStore) *Migrator {
+	t.Helper()
+	return NewMigratorWithStore(store, zap.NewNop())
+}
+
+func TestMigrations_Embedded(t *testing.T) {
+	migrations := embeddedMigrations(t)
+
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "baseline", migrations[0].Name)
+	for i := 1; i < len(migrations); i++ {
+		assert.Greater(t, migrations[i].Version, migrations[i-1].Version, "migrations should be in version order")
+	}
+
+	// Every definition must be re-runnable over a database that already has it
+	define := regexp.MustCompile(`(?m)^DEFINE (TABLE|FIELD|INDEX|ANALYZER) (.*)$`)
+	for _, migration := range migrations {
+		for _, match := range define.FindAllStringSubmatch(migration.SQL, -1) {
+			if !strings.HasPrefix(match[2], "IF NOT EXISTS ") {
+				t.Errorf("migration %d: %q is missing IF NOT EXISTS", migration.Version, match[0])
+			}
+		}
+	}
+
+	var all strings.Builder
+	for _, migration := range migrations {
+		all.WriteString(migration.SQL)
+	}
+	for _, want := range []string{
+		"DEFINE INDEX IF NOT EXISTS idx_host_ip ON TABLE host COLUMNS ip UNIQUE",
+		"DEFINE INDEX IF NOT EXISTS idx_vuln_cve ON TABLE vuln COLUMNS cve_id UNIQUE",
+		"DEFINE INDEX IF NOT EXISTS idx_vuln_doc_embedding ON TABLE vuln_doc COLUMNS embedding MTREE DIMENSION 1536 DIST COSINE",
+		"DEFINE ANALYZER IF NOT EXISTS vuln_analyzer",
+		"DEFINE INDEX IF NOT EXISTS idx_affected_by_last_confirmed ON TABLE AFFECTED_BY",
+		"DEFINE INDEX IF NOT EXISTS idx_runs_port_service ON TABLE RUNS COLUMNS in, out UNIQUE",
+		"DEFINE TABLE IF NOT EXISTS audit SCHEMAFULL",
+	} {
+		assert.Contains(t, all.String(), want)
+	}
+}
+
+func TestParseMigrationFilename(t *testing.T) {
+	version, name, err := parseMigrationFilename("0002_affected_by_indexes.surql")
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	assert.Equal(t, "affected_by_indexes", name)
+
+	for _, bad := range []string{"0002.surql", "abc_name.surql", "0001_clashes_with_baseline.surql", "0003_.surql"} {
+		_, _, err := parseMigrationFilename(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestMigrator_MigrateTwiceIsNoop(t *testing.T) {
+	store := &fakeSchemaStore{applied: map[int]bool{}}
+	m := newTestMigrator(t, store)
+	migrations := embeddedMigrations(t)
+	latest := migrations[len(migrations)-1].Version
+
+	first, err := m.Migrate(context.Background(), false)
+	require.NoError(t, err)
+	assert.Len(t, first.Applied, len(migrations))
+	assert.Equal(t, 1, first.Applied[0].Version)
+	assert.Equal(t, latest, first.CurrentVersion)
+	assert.Equal(t, latest, first.LatestVersion)
+	assert.Contains(t, store.executed[0], "idx_host_ip")
+
+	second, err := m.Migrate(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, second.Applied)
+	assert.Equal(t, latest, second.CurrentVersion)
+	assert.Len(t, store.executed, len(migrations), "second run should apply nothing")
+}
+
+func TestMigrator_AppliesOnlyNewMigrations(t *testing.T) {
+	store := &fakeSchemaStore{applied: map[int]bool{1: true}}
+	m := newTestMigrator(t, store)
+	migrations := embeddedMigrations(t)
+
+	resp, err := m.Migrate(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, resp.Applied, len(migrations)-1)
+	assert.Equal(t, 2, resp.Applied[0].Version)
+	assert.NotContains(t, strings.Join(store.executed, "\n"), "idx_host_ip", "the baseline should not run again")
+}
+
+func TestMigrator_DryRun(t *testing.T) {
+	store := &fakeSchemaStore{applied: map[int]bool{}}
+	m := newTestMigrator(t, store)
+	migrations := embeddedMigrations(t)
+
+	resp, err := m.Migrate(context.Background(), true)
+	require.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.Len(t, resp.Applied, len(migrations))
+	assert.Zero(t, resp.CurrentVersion)
+	assert.Empty(t, store.executed)
+}
+
+func TestMigrator_StopsAtFailure(t *testing.T) {
+	store := &fakeSchemaStore{applied: map[int]bool{}, failOn: 2}
+	m := newTestMigrator(t, store)
+
+	resp, err := m.Migrate(context.Background(), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration 2")
+	require.Len(t, resp.Applied, 1)
+	assert.Equal(t, 1, resp.CurrentVersion)
+	assert.False(t, store.applied[2])
+}
+
+// embeddedMigration returns an embedded migration by version
+func embeddedMigration(t *testing.T, version int) Migration {
+	t.Helper()
+	for _, migration := range embeddedMigrations(t) {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	t.Fatalf("no migration %d", version)
+	return Migration{}
+}
+
+func TestMigrations_NamespacedHostIDs(t *testing.T) {
+	migration := embeddedMigration(t, 10)
+
+	// The rewrite must produce the IDs models.HostRecordID derives
+	for _, prefix := range []string{models.HostIDPrefixIPv4, models.HostIDPrefixIPv6, models.HostIDPrefixRaw} {
+		assert.Contains(t, migration.SQL, `"`+prefix+`"`)
+	}
+	for _, edge := range []string{"HAS", "IN_CITY", "IN_ASN", "IN_CLOUD_REGION"} {
+		assert.Contains(t, migration.SQL, "RELATE $new->"+edge+"->", "%s edges should be carried over", edge)
+	}
+}
+
+func TestMigrator_HostIDSchemeFollowsSchema(t *testing.T) {
+	assert.Equal(t, "namespaced_host_ids", embeddedMigration(t, models.NamespacedHostIDsVersion).Name)
+
+	// A database short of the host ID rewrite keeps its legacy IDs
+	store := &fakeSchemaStore{applied: map[int]bool{1: true, 2: true, 3: true}}
+	m := newTestMigrator(t, store)
+	scheme, err := m.HostIDScheme(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.HostIDSchemeLegacy, scheme)
+
+	// A running service picks up the rewrite without a restart
+	_, err = m.Migrate(context.Background(), false)
+	require.NoError(t, err)
+	scheme, err = m.HostIDScheme(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.HostIDSchemeNamespaced, scheme)
+
+	// and stops reading the schema once it has seen it
+	reads := store.reads
+	scheme, err = m.HostIDScheme(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, models.HostIDSchemeNamespaced, scheme)
+	assert.Equal(t, reads, store.reads)
+}
+
+func TestMigrations_NamespacedHostIDs_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	// Hosts under legacy IDs, one of each family, plus one that isn't an address
+	_, err := surrealdb.Query[interface{}](ctx, db, `
+		DELETE host; DELETE port; DELETE asn; DELETE city; DELETE schema_migration;
+		CREATE host:⟨8_8_8_8⟩ SET ip = '8.8.8.8', asn = 15169;
+		CREATE host:⟨2001-db8--1⟩ SET ip = '2001:db8::1';
+		CREATE host:not_an_ip SET ip = 'not-an-ip';
+		CREATE port:port_53_udp SET number = 53, protocol = 'udp';
+		CREATE asn:asn15169 SET number = 15169;
+		CREATE city:migrate_city SET name = 'Testville';
+		RELATE host:⟨8_8_8_8⟩->HAS->port:port_53_udp SET discovered_by = 'nmap';
+		RELATE host:⟨8_8_8_8⟩->IN_ASN->asn:asn15169;
+		RELATE host:⟨8_8_8_8⟩->IN_CITY->city:migrate_city SET accuracy_radius_km = 10;
+	`, nil)
+	require.NoError(t, err)
+
+	store := &surrealSchemaStore{db: db}
+	require.NoError(t, store.Apply(ctx, embeddedMigration(t, 10)))
+
+	hostKey := func(ip string) string {
+		res, err := surrealdb.Query[[]string](ctx, db, "SELECT VALUE <string> record::id(id) FROM host WHERE ip = $ip;", map[string]interface{}{"ip": ip})
+		require.NoError(t, err)
+		require.Len(t, (*res)[0].Result, 1, ip)
+		return (*res)[0].Result[0]
+	}
+	assert.Equal(t, "ip4_8_8_8_8", hostKey("8.8.8.8"))
+	assert.Equal(t, "ip6_2001-db8--1", hostKey("2001:db8::1"))
+	assert.Equal(t, "not_an_ip", hostKey("not-an-ip"), "IDs that aren't addresses are left alone")
+
+	// Lookups by the IDs the workflows now derive land on the migrated hosts
+	count := func(query string, vars map[string]interface{}) int {
+		res, err := surrealdb.Query[[]interface{}](ctx, db, query, vars)
+		require.NoError(t, err)
+		return len((*res)[0].Result)
+	}
+	for _, ip := range []string{"8.8.8.8", "2001:db8::1"} {
+		assert.Equal(t, 1, count("SELECT id FROM type::thing('host', $id);", map[string]interface{}{"id": models.HostRecordID(ip)}), ip)
+	}
+	assert.Zero(t, count("SELECT id FROM host:⟨8_8_8_8⟩;", nil))
+
+	// Fields and edges come along
+	assert.Equal(t, 1, count("SELECT id FROM host:ip4_8_8_8_8 WHERE asn = 15169;", nil))
+	assert.Equal(t, 1, count("SELECT id FROM HAS WHERE in = host:ip4_8_8_8_8 AND out = port:port_53_udp AND discovered_by = 'nmap';", nil))
+	assert.Equal(t, 1, count("SELECT id FROM IN_ASN WHERE in = host:ip4_8_8_8_8;", nil))
+	assert.Equal(t, 1, count("SELECT id FROM IN_CITY WHERE in = host:ip4_8_8_8_8 AND accuracy_radius_km = 10;", nil))
+	assert.Equal(t, 1, count("SELECT id FROM host WHERE ->HAS->port.number CONTAINS 53;", nil))
+}
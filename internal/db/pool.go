@@ -0,0 +1,590 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/surrealdb/surrealdb.go"
+	"github.com/surrealdb/surrealdb.go/pkg/connection"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/gorillaws"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/http"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHealthCheckInterval is how often the pool pings SurrealDB
+	DefaultHealthCheckInterval = 10 * time.Second
+	// DefaultHealthCheckTimeout bounds a single health-check ping
+	DefaultHealthCheckTimeout = 5 * time.Second
+	// DefaultReconnectInitialBackoff is the delay before the second reconnect attempt
+	DefaultReconnectInitialBackoff = 500 * time.Millisecond
+	// DefaultReconnectMaxBackoff caps the delay between reconnect attempts
+	DefaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// ErrPoolClosed is returned by queries issued after the pool has been closed
+var ErrPoolClosed = errors.New("surrealdb pool is closed")
+
+// Dialer creates a new, unconnected SurrealDB connection
+type Dialer func(ctx context.Context) (connection.Connection, error)
+
+// PoolConfig configures a Pool
+type PoolConfig struct {
+	URL       string // ws://, wss://, http:// or https:// endpoint
+	Username  string
+	Password  string
+	Namespace string
+	Database  string
+
+	// HealthCheckInterval is how often the connection is pinged (DefaultHealthCheckInterval if zero)
+	HealthCheckInterval time.Duration
+	// InitialBackoff and MaxBackoff bound the exponential delay between reconnect attempts
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Dial overrides how connections are created; by default URL is dialed
+	Dial Dialer
+}
+
+// Pool manages the SurrealDB connection shared by the API and workflow services
+// SurrealDB multiplexes requests over one WebSocket, so the pool holds a single
+// connection; when it drops, the pool redials with backoff and replays the
+// sign-in and namespace/database selection before serving the next query.
+// Callers keep using the *surrealdb.DB returned by DB across reconnects.
+type Pool struct {
+	db     *surrealdb.DB
+	conn   *reconnectingConn
+	logger *zap.Logger
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewPool connects to SurrealDB, signs in, selects the namespace and database,
+// and starts the background health check
+func NewPool(ctx context.Context, cfg PoolConfig, logger *zap.Logger) (*Pool, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultReconnectInitialBackoff
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = DefaultReconnectMaxBackoff
+		if cfg.MaxBackoff < cfg.InitialBackoff {
+			cfg.MaxBackoff = cfg.InitialBackoff
+		}
+	}
+	if cfg.Dial == nil {
+		dial, err := urlDialer(cfg.URL)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Dial = dial
+	}
+
+	conn := &reconnectingConn{
+		dial:           cfg.Dial,
+		initialBackoff: cfg.InitialBackoff,
+		maxBackoff:     cfg.MaxBackoff,
+		logger:         logger,
+		closed:         make(chan struct{}),
+	}
+
+	client, err := surrealdb.FromConnection(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SurrealDB: %w", err)
+	}
+
+	if _, err := client.SignIn(ctx, surrealdb.Auth{
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}); err != nil {
+		client.Close(ctx)
+		return nil, fmt.Errorf("failed to authenticate with SurrealDB: %w", err)
+	}
+
+	if err := client.Use(ctx, cfg.Namespace, cfg.Database); err != nil {
+		client.Close(ctx)
+		return nil, fmt.Errorf("failed to use namespace/database: %w", err)
+	}
+
+	p := &Pool{
+		db:     client,
+		conn:   conn,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.healthCheckLoop(cfg.HealthCheckInterval)
+
+	return p, nil
+}
+
+// DB returns the client handlers and workflows query through
+func (p *Pool) DB() *surrealdb.DB {
+	return p.db
+}
+
+// Ping checks the connection, reconnecting first if it is known to be down
+func (p *Pool) Ping(ctx context.Context) error {
+	_, err := p.conn.Send(ctx, "ping")
+	return err
+}
+
+// Reconnects returns how many times the pool has re-established the connection
+func (p *Pool) Reconnects() int {
+	return p.conn.reconnectCount()
+}
+
+// Close stops the health check and closes the connection
+func (p *Pool) Close(ctx context.Context) error {
+	var err error
+	p.once.Do(func() {
+		close(p.stop)
+		<-p.done
+		err = p.db.Close(ctx)
+	})
+	return err
+}
+
+// healthCheckLoop pings the connection so drops are repaired before a request hits them
+func (p *Pool) healthCheckLoop(interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultHealthCheckTimeout)
+			err := p.Ping(ctx)
+			cancel()
+			if err == nil {
+				continue
+			}
+
+			p.logger.Warn("SurrealDB health check failed",
+				zap.Error(err))
+
+			// The ping may have failed on a connection that has not noticed it is
+			// gone yet; force a redial, bounded by the pool's lifetime
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-p.stop:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			if err := p.conn.reconnect(ctx, nil); err != nil && !errors.Is(err, context.Canceled) {
+				p.logger.Error("failed to reconnect to SurrealDB",
+					zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// urlDialer returns a Dialer for the transport named by the URL scheme
+func urlDialer(rawURL string) (Dialer, error) {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SurrealDB URL: %w", err)
+	}
+
+	conf := connection.NewConfig(u)
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid connection config: %w", err)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss":
+		return func(ctx context.Context) (connection.Connection, error) {
+			return gorillaws.New(conf), nil
+		}, nil
+	case "http", "https":
+		return func(ctx context.Context) (connection.Connection, error) {
+			return http.New(conf), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SurrealDB URL scheme %q", u.Scheme)
+	}
+}
+
+// closer is implemented by connections that can report they have been disconnected
+type closer interface {
+	IsClosed() bool
+}
+
+// reconnectingConn is a connection.Connection that redials its inner connection
+// when it drops and restores the session on the new one
+type reconnectingConn struct {
+	// Connection is the first dialed connection; it is kept only to supply
+	// GetUnmarshaler, whose return type lives in an internal driver package.
+	// Every other method goes through current.
+	connection.Connection
+
+	dial           Dialer
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	logger         *zap.Logger
+
+	mu         sync.RWMutex
+	current    connection.Connection
+	auth       any
+	token      string
+	namespace  string
+	database   string
+	reconnects int
+
+	// reconnectMu serializes redials so concurrent failures trigger one reconnect
+	reconnectMu sync.Mutex
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// Connect dials the initial connection
+func (c *reconnectingConn) Connect(ctx context.Context) error {
+	conn, err := c.dialOnce(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.Connection = conn
+	c.current = conn
+	c.mu.Unlock()
+	return nil
+}
+
+// Close closes the current connection; no further reconnects are attempted
+func (c *reconnectingConn) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	c.mu.RLock()
+	conn := c.current
+	c.mu.RUnlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.Close(ctx)
+}
+
+// Send issues a request, redialing first if the connection is known to be down
+// A request that fails because the connection dropped mid-flight is not
+// retried, since it may already have been applied; the next request reconnects.
+func (c *reconnectingConn) Send(ctx context.Context, method string, params ...any) (*connection.RPCResponse[cbor.RawMessage], error) {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := conn.Send(ctx, method, params...)
+	if err != nil && isDisconnect(conn, err) {
+		c.logger.Warn("SurrealDB connection dropped",
+			zap.String("method", method),
+			zap.Error(err))
+		c.markBroken(conn)
+	}
+	return res, err
+}
+
+// Use selects the namespace and database and remembers them for reconnects
+func (c *reconnectingConn) Use(ctx context.Context, namespace string, database string) error {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.Use(ctx, namespace, database); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.namespace, c.database = namespace, database
+	c.mu.Unlock()
+	return nil
+}
+
+// SignIn authenticates and remembers the credentials for reconnects
+func (c *reconnectingConn) SignIn(ctx context.Context, authData any) (string, error) {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return "", err
+	}
+	token, err := conn.SignIn(ctx, authData)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.auth, c.token = authData, ""
+	c.mu.Unlock()
+	return token, nil
+}
+
+// SignUp creates a record user; the returned token is remembered for reconnects
+func (c *reconnectingConn) SignUp(ctx context.Context, authData any) (string, error) {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return "", err
+	}
+	token, err := conn.SignUp(ctx, authData)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.auth, c.token = nil, token
+	c.mu.Unlock()
+	return token, nil
+}
+
+// Authenticate authenticates with a token and remembers it for reconnects
+func (c *reconnectingConn) Authenticate(ctx context.Context, token string) error {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.Authenticate(ctx, token); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.auth, c.token = nil, token
+	c.mu.Unlock()
+	return nil
+}
+
+// Invalidate drops the session authentication
+func (c *reconnectingConn) Invalidate(ctx context.Context) error {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.Invalidate(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.auth, c.token = nil, ""
+	c.mu.Unlock()
+	return nil
+}
+
+// Let sets a session variable on the current connection
+// Session variables are not restored after a reconnect.
+func (c *reconnectingConn) Let(ctx context.Context, key string, value any) error {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Let(ctx, key, value)
+}
+
+// Unset removes a session variable from the current connection
+func (c *reconnectingConn) Unset(ctx context.Context, key string) error {
+	conn, err := c.live(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Unset(ctx, key)
+}
+
+// LiveNotifications returns the notification channel for a live query on the
+// current connection; live queries do not survive a reconnect
+func (c *reconnectingConn) LiveNotifications(id string) (chan connection.Notification, error) {
+	c.mu.RLock()
+	conn := c.current
+	c.mu.RUnlock()
+	if conn == nil {
+		return nil, ErrPoolClosed
+	}
+	return conn.LiveNotifications(id)
+}
+
+// CloseLiveNotifications closes the notification channel for a live query
+func (c *reconnectingConn) CloseLiveNotifications(id string) error {
+	c.mu.RLock()
+	conn := c.current
+	c.mu.RUnlock()
+	if conn == nil {
+		return ErrPoolClosed
+	}
+	return conn.CloseLiveNotifications(id)
+}
+
+// live returns a usable connection, reconnecting first if the current one is down
+func (c *reconnectingConn) live(ctx context.Context) (connection.Connection, error) {
+	select {
+	case <-c.closed:
+		return nil, ErrPoolClosed
+	default:
+	}
+
+	c.mu.RLock()
+	conn := c.current
+	c.mu.RUnlock()
+
+	if conn != nil && !isClosed(conn) {
+		return conn, nil
+	}
+	if err := c.reconnect(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current, nil
+}
+
+// markBroken drops conn so the next request redials
+func (c *reconnectingConn) markBroken(conn connection.Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == conn {
+		c.current = nil
+	}
+}
+
+// reconnect replaces broken with a freshly dialed connection and restores the
+// session on it. If another caller already replaced broken, it returns
+// immediately; a nil broken forces a redial of whatever is current.
+func (c *reconnectingConn) reconnect(ctx context.Context, broken connection.Connection) error {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+
+	c.mu.RLock()
+	current := c.current
+	c.mu.RUnlock()
+	if broken != nil && current != broken && current != nil && !isClosed(current) {
+		return nil
+	}
+
+	backoff := c.initialBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.closed:
+			return ErrPoolClosed
+		default:
+		}
+
+		conn, err := c.dialOnce(ctx)
+		if err == nil {
+			err = c.restore(ctx, conn)
+			if err != nil {
+				conn.Close(ctx)
+			}
+		}
+		if err == nil {
+			c.mu.Lock()
+			old := c.current
+			c.current = conn
+			c.reconnects++
+			c.mu.Unlock()
+
+			if old != nil {
+				old.Close(ctx)
+			}
+			c.logger.Info("reconnected to SurrealDB",
+				zap.Int("attempt", attempt))
+			return nil
+		}
+
+		c.logger.Warn("SurrealDB reconnect attempt failed",
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("reconnect to SurrealDB abandoned after %d attempts: %w", attempt, ctx.Err())
+		case <-c.closed:
+			return ErrPoolClosed
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+// restore re-runs sign-in and namespace/database selection on a new connection
+func (c *reconnectingConn) restore(ctx context.Context, conn connection.Connection) error {
+	c.mu.RLock()
+	auth, token := c.auth, c.token
+	namespace, database := c.namespace, c.database
+	c.mu.RUnlock()
+
+	switch {
+	case auth != nil:
+		if _, err := conn.SignIn(ctx, auth); err != nil {
+			return fmt.Errorf("failed to sign in: %w", err)
+		}
+	case token != "":
+		if err := conn.Authenticate(ctx, token); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if namespace != "" || database != "" {
+		if err := conn.Use(ctx, namespace, database); err != nil {
+			return fmt.Errorf("failed to use namespace/database: %w", err)
+		}
+	}
+	return nil
+}
+
+// dialOnce creates and connects a single connection
+func (c *reconnectingConn) dialOnce(ctx context.Context) (connection.Connection, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// reconnectCount returns the number of successful reconnects
+func (c *reconnectingConn) reconnectCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.reconnects
+}
+
+// isClosed reports whether conn knows it has been disconnected
+func isClosed(conn connection.Connection) bool {
+	cl, ok := conn.(closer)
+	return ok && cl.IsClosed()
+}
+
+// isDisconnect reports whether err from conn means the connection is gone,
+// as opposed to a query or timeout error on a healthy connection
+func isDisconnect(conn connection.Connection, err error) bool {
+	if isClosed(conn) {
+		return true
+	}
+	var netErr *net.OpError
+	return errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, io.EOF) ||
+		errors.As(err, &netErr)
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"github.com/surrealdb/surrealdb.go/pkg/connection"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/gorillaws"
+)
+
+// fakeConn is an in-memory SurrealDB connection that can be dropped on demand
+type fakeConn struct {
+	// Connection supplies GetUnmarshaler; it is never connected
+	connection.Connection
+
+	marshal func(any) ([]byte, error)
+	closed  atomic.Bool
+	// dropOnSend simulates the socket dying while a request is in flight
+	dropOnSend atomic.Bool
+
+	mu      sync.Mutex
+	signIns []any
+	uses    [][2]string
+	queries int
+}
+
+func (f *fakeConn) Connect(ctx context.Context) error { return nil }
+
+func (f *fakeConn) Close(ctx context.Context) error {
+	f.closed.Store(true)
+	return nil
+}
+
+func (f *fakeConn) IsClosed() bool { return f.closed.Load() }
+
+func (f *fakeConn) Send(ctx context.Context, method string, params ...any) (*connection.RPCResponse[cbor.RawMessage], error) {
+	if f.dropOnSend.Load() {
+		f.closed.Store(true)
+	}
+	if f.closed.Load() {
+		return nil, net.ErrClosed
+	}
+
+	var result any = "pong"
+	if method == "query" {
+		f.mu.Lock()
+		f.queries++
+		f.mu.Unlock()
+		result = []map[string]any{{
+			"status": "OK",
+			"time":   "1ms",
+			"result": []map[string]any{{"ip": "192.0.2.1"}},
+		}}
+	}
+
+	data, err := f.marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	raw := cbor.RawMessage(data)
+	return &connection.RPCResponse[cbor.RawMessage]{Result: &raw}, nil
+}
+
+func (f *fakeConn) SignIn(ctx context.Context, authData any) (string, error) {
+	if f.closed.Load() {
+		return "", net.ErrClosed
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signIns = append(f.signIns, authData)
+	return "token", nil
+}
+
+func (f *fakeConn) Use(ctx context.Context, namespace, database string) error {
+	if f.closed.Load() {
+		return net.ErrClosed
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uses = append(f.uses, [2]string{namespace, database})
+	return nil
+}
+
+// fakeDialer hands out fakeConns, failing the next failNext dials
+type fakeDialer struct {
+	conf *connection.Config
+
+	mu       sync.Mutex
+	conns    []*fakeConn
+	dials    int
+	failNext int
+}
+
+func newFakeDialer(t testing.TB) *fakeDialer {
+	u, err := url.Parse("ws://localhost:8000")
+	require.NoError(t, err)
+	return &fakeDialer{conf: connection.NewConfig(u)}
+}
+
+func (d *fakeDialer) Dial(ctx context.Context) (connection.Connection, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dials++
+	if d.failNext > 0 {
+		d.failNext--
+		return nil, errors.New("connection refused")
+	}
+
+	conn := &fakeConn{
+		Connection: gorillaws.New(d.conf),
+		marshal:    d.conf.Marshaler.Marshal,
+	}
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+func (d *fakeDialer) conn(i int) *fakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns[i]
+}
+
+func (d *fakeDialer) dialCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dials
+}
+
+func newTestPool(t *testing.T, dialer *fakeDialer, interval time.Duration) *Pool {
+	pool, err := NewPool(context.Background(), PoolConfig{
+		Username:            "root",
+		Password:            "secret",
+		Namespace:           "spectra",
+		Database:            "intel_mesh",
+		HealthCheckInterval: interval,
+		InitialBackoff:      time.Millisecond,
+		MaxBackoff:          5 * time.Millisecond,
+		Dial:                dialer.Dial,
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close(context.Background()) })
+	return pool
+}
+
+// queryHost runs a query through the same surface handlers use
+func queryHost(pool *Pool) error {
+	_, err := surrealdb.Query[[]map[string]any](context.Background(), pool.DB(), "SELECT * FROM host", nil)
+	return err
+}
+
+func TestPool_ReconnectsAfterDrop(t *testing.T) {
+	dialer := newFakeDialer(t)
+	pool := newTestPool(t, dialer, time.Hour)
+
+	require.NoError(t, queryHost(pool))
+
+	// Simulate SurrealDB restarting underneath the service
+	dialer.conn(0).closed.Store(true)
+
+	require.NoError(t, queryHost(pool))
+	assert.Equal(t, 1, pool.Reconnects())
+
+	replacement := dialer.conn(1)
+	assert.Equal(t, []any{surrealdb.Auth{Username: "root", Password: "secret"}}, replacement.signIns)
+	assert.Equal(t, [][2]string{{"spectra", "intel_mesh"}}, replacement.uses)
+	assert.Equal(t, 1, replacement.queries)
+}
+
+func TestPool_ReconnectBacksOffUntilServerReturns(t *testing.T) {
+	dialer := newFakeDialer(t)
+	pool := newTestPool(t, dialer, time.Hour)
+
+	dialer.mu.Lock()
+	dialer.failNext = 3
+	dialer.mu.Unlock()
+	dialer.conn(0).closed.Store(true)
+
+	require.NoError(t, queryHost(pool))
+	assert.Equal(t, 5, dialer.dialCount(), "initial dial, three refused, one success")
+	assert.Equal(t, 1, pool.Reconnects())
+}
+
+func TestPool_DropMidRequestIsNotRetried(t *testing.T) {
+	dialer := newFakeDialer(t)
+	pool := newTestPool(t, dialer, time.Hour)
+
+	dialer.conn(0).dropOnSend.Store(true)
+
+	// The request may have reached the server, so the error surfaces...
+	err := queryHost(pool)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	// ...and the next request runs on a fresh connection
+	require.NoError(t, queryHost(pool))
+	assert.Equal(t, 1, pool.Reconnects())
+}
+
+func TestPool_HealthCheckReconnectsIdleConnection(t *testing.T) {
+	dialer := newFakeDialer(t)
+	pool := newTestPool(t, dialer, 5*time.Millisecond)
+
+	dialer.conn(0).closed.Store(true)
+
+	require.Eventually(t, func() bool {
+		return pool.Reconnects() >= 1
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, pool.Ping(context.Background()))
+}
+
+func TestPool_ClosedPoolRejectsQueries(t *testing.T) {
+	dialer := newFakeDialer(t)
+	pool := newTestPool(t, dialer, time.Hour)
+
+	require.NoError(t, pool.Close(context.Background()))
+
+	err := queryHost(pool)
+	assert.ErrorIs(t, err, ErrPoolClosed)
+	assert.Equal(t, 1, dialer.dialCount())
+}
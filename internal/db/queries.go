@@ -85,8 +85,16 @@ func QueryHost(ctx context.Context, db *surrealdb.DB, logger *zap.Logger, ip str
 	return response, nil
 }
 
+// portSourcesProjection selects the tool that found each of a host's ports, from
+// the host's own HAS edges; parseHostQueryResult matches them to ports by number
+// and protocol
+const portSourcesProjection = `(SELECT out.number AS number, out.protocol AS protocol, discovered_by FROM $parent->HAS) AS port_sources`
+
 // buildHostQuery constructs the SurrealDB query based on depth
+// Depths beyond DepthMaximum build the DepthMaximum query.
 func buildHostQuery(ip string, depth int) string {
+	depth = models.ClampDepth(depth)
+
 	// Base query - always get host
 	query := `SELECT * FROM host WHERE ip = $ip`
 
@@ -94,7 +102,8 @@ func buildHostQuery(ip string, depth int) string {
 	if depth >= 1 {
 		// Depth 1: Include ports
 		query = `SELECT *,
-			->HAS->port.* AS ports
+			->HAS->port.* AS ports,
+			` + portSourcesProjection + `
 		FROM host WHERE ip = $ip`
 	}
 
@@ -102,6 +111,7 @@ func buildHostQuery(ip string, depth int) string {
 		// Depth 2: Include ports and services
 		query = `SELECT *,
 			->HAS->port.* AS ports,
+			` + portSourcesProjection + `,
 			->HAS->port->RUNS->service.* AS services
 		FROM host WHERE ip = $ip`
 	}
@@ -110,6 +120,7 @@ func buildHostQuery(ip string, depth int) string {
 		// Depth 3: Include ports, services, and vulnerabilities
 		query = `SELECT *,
 			->HAS->port.* AS ports,
+			` + portSourcesProjection + `,
 			->HAS->port->RUNS->service.* AS services,
 			->HAS->port->RUNS->service->AFFECTED_BY->vuln.* AS vulns
 		FROM host WHERE ip = $ip`
@@ -119,6 +130,7 @@ func buildHostQuery(ip string, depth int) string {
 		// Depth 4+: Include extended relationships (geographic, ASN)
 		query = `SELECT *,
 			->HAS->port.* AS ports,
+			` + portSourcesProjection + `,
 			->HAS->port->RUNS->service.* AS services,
 			->HAS->port->RUNS->service->AFFECTED_BY->vuln.* AS vulns,
 			->IN_CITY->city.* AS city_detail,
@@ -177,6 +189,9 @@ func parseHostQueryResult(result map[string]interface{}, depth int, logger *zap.
 		if ports, ok := hostData["ports"].([]interface{}); ok {
 			response.Ports = parsePorts(ports, depth, logger)
 		}
+		if sources, ok := hostData["port_sources"].([]interface{}); ok {
+			applyPortSources(response.Ports, sources)
+		}
 	}
 
 	if depth >= 2 {
@@ -230,6 +245,34 @@ func parsePorts(portsData []interface{}, depth int, logger *zap.Logger) []models
 	return ports
 }
 
+// applyPortSources sets DiscoveredBy on each port from the port_sources rows of the
+// host query; ports ingested before sources were recorded are left empty
+func applyPortSources(ports []models.PortDetail, sourcesData []interface{}) {
+	type portKey struct {
+		number   int
+		protocol string
+	}
+
+	sources := make(map[portKey]string, len(sourcesData))
+	for _, item := range sourcesData {
+		sourceMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		number, ok := getIntField(sourceMap, "number")
+		if !ok {
+			continue
+		}
+		if source := getStringField(sourceMap, "discovered_by"); source != "" {
+			sources[portKey{number, getStringField(sourceMap, "protocol")}] = source
+		}
+	}
+
+	for i := range ports {
+		ports[i].DiscoveredBy = sources[portKey{ports[i].Number, ports[i].Protocol}]
+	}
+}
+
 // parseServices extracts service information from query result
 func parseServices(servicesData []interface{}, depth int, logger *zap.Logger) []models.ServiceDetail {
 	services := make([]models.ServiceDetail, 0, len(servicesData))
@@ -318,6 +361,8 @@ func getIntField(data map[string]interface{}, key string) (int, bool) {
 		return val, true
 	case int64:
 		return int(val), true
+	case uint64:
+		return int(val), true
 	case float64:
 		return int(val), true
 	}
@@ -27,7 +27,8 @@ func TestBuildHostQuery(t *testing.T) {
 			ip:    "1.2.3.4",
 			depth: 1,
 			expectedQuery: `SELECT *,
-			->HAS->port.* AS ports
+			->HAS->port.* AS ports,
+			(SELECT out.number AS number, out.protocol AS protocol, discovered_by FROM $parent->HAS) AS port_sources
 		FROM host WHERE ip = $ip LIMIT 1;`,
 		},
 		{
@@ -36,6 +37,7 @@ func TestBuildHostQuery(t *testing.T) {
 			depth: 2,
 			expectedQuery: `SELECT *,
 			->HAS->port.* AS ports,
+			(SELECT out.number AS number, out.protocol AS protocol, discovered_by FROM $parent->HAS) AS port_sources,
 			->HAS->port->RUNS->service.* AS services
 		FROM host WHERE ip = $ip LIMIT 1;`,
 		},
@@ -45,6 +47,7 @@ func TestBuildHostQuery(t *testing.T) {
 			depth: 3,
 			expectedQuery: `SELECT *,
 			->HAS->port.* AS ports,
+			(SELECT out.number AS number, out.protocol AS protocol, discovered_by FROM $parent->HAS) AS port_sources,
 			->HAS->port->RUNS->service.* AS services,
 			->HAS->port->RUNS->service->AFFECTED_BY->vuln.* AS vulns
 		FROM host WHERE ip = $ip LIMIT 1;`,
@@ -55,6 +58,7 @@ func TestBuildHostQuery(t *testing.T) {
 			depth: 4,
 			expectedQuery: `SELECT *,
 			->HAS->port.* AS ports,
+			(SELECT out.number AS number, out.protocol AS protocol, discovered_by FROM $parent->HAS) AS port_sources,
 			->HAS->port->RUNS->service.* AS services,
 			->HAS->port->RUNS->service->AFFECTED_BY->vuln.* AS vulns,
 			->IN_CITY->city.* AS city_detail,
@@ -67,6 +71,7 @@ func TestBuildHostQuery(t *testing.T) {
 			depth: 5,
 			expectedQuery: `SELECT *,
 			->HAS->port.* AS ports,
+			(SELECT out.number AS number, out.protocol AS protocol, discovered_by FROM $parent->HAS) AS port_sources,
 			->HAS->port->RUNS->service.* AS services,
 			->HAS->port->RUNS->service->AFFECTED_BY->vuln.* AS vulns,
 			->IN_CITY->city.* AS city_detail,
@@ -84,6 +89,7 @@ func TestBuildHostQuery(t *testing.T) {
 			// Check depth-specific clauses
 			if tt.depth >= 1 {
 				assert.Contains(t, query, "->HAS->port.* AS ports")
+				assert.Contains(t, query, "discovered_by FROM $parent->HAS) AS port_sources")
 			}
 			if tt.depth >= 2 {
 				assert.Contains(t, query, "->HAS->port->RUNS->service.* AS services")
@@ -99,6 +105,10 @@ func TestBuildHostQuery(t *testing.T) {
 	}
 }
 
+func TestBuildHostQuery_ClampsDepth(t *testing.T) {
+	assert.Equal(t, buildHostQuery("1.2.3.4", int(models.DepthMaximum)), buildHostQuery("1.2.3.4", 99))
+}
+
 func TestGetStringField(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -346,6 +356,28 @@ func TestParsePorts(t *testing.T) {
 	}
 }
 
+func TestApplyPortSources(t *testing.T) {
+	ports := []models.PortDetail{
+		{Number: 22, Protocol: "tcp"},
+		{Number: 53, Protocol: "udp"},
+		{Number: 53, Protocol: "tcp"},
+		{Number: 8080, Protocol: "tcp"},
+	}
+
+	applyPortSources(ports, []interface{}{
+		map[string]interface{}{"number": 22, "protocol": "tcp", "discovered_by": "nmap"},
+		map[string]interface{}{"number": 53, "protocol": "udp", "discovered_by": "masscan"},
+		map[string]interface{}{"number": 53, "protocol": "tcp", "discovered_by": "unknown"},
+		map[string]interface{}{"number": 8080, "protocol": "tcp"}, // ingested before sources were recorded
+		"not-a-map",
+	})
+
+	assert.Equal(t, "nmap", ports[0].DiscoveredBy)
+	assert.Equal(t, "masscan", ports[1].DiscoveredBy)
+	assert.Equal(t, "unknown", ports[2].DiscoveredBy)
+	assert.Empty(t, ports[3].DiscoveredBy)
+}
+
 func TestParseServices(t *testing.T) {
 	logger := zap.NewNop()
 
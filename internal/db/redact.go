@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidRedactTarget is returned for an IP or CIDR that does not parse
+var ErrInvalidRedactTarget = errors.New("invalid redaction target")
+
+// hostEdgeTables are the relations leaving a host node
+var hostEdgeTables = []string{
+	"HAS", "IN_CITY", "HOST_IN_REGION", "HOST_IN_COUNTRY", "IN_ASN", "IN_CLOUD_REGION",
+}
+
+// portEdgeTables are the relations leaving a port node
+var portEdgeTables = []string{"RUNS", "IS_COMMON"}
+
+// HostRedactor removes hosts from the graph, for GDPR requests or out-of-scope
+// targets ingested by mistake. A host goes with all of its edges, including the
+// RUNS edges recording its services; ports are shared between hosts and are only
+// removed once no host has them. Services, cities and ASNs are shared too and are
+// left in place.
+type HostRedactor struct {
+	store  RedactStore
+	logger *zap.Logger
+}
+
+// RedactStore runs the statements behind a redaction
+type RedactStore interface {
+	// Exec runs a statement and returns how many records it touched
+	Exec(ctx context.Context, query string, vars map[string]interface{}) (int, error)
+	// SelectValues runs a SELECT VALUE query returning strings
+	SelectValues(ctx context.Context, query string, vars map[string]interface{}) ([]string, error)
+}
+
+// NewHostRedactor creates a host redactor on the database
+func NewHostRedactor(db *surrealdb.DB, logger *zap.Logger) *HostRedactor {
+	return NewHostRedactorWithStore(&surrealRedactStore{db: db}, logger)
+}
+
+// NewHostRedactorWithStore creates a host redactor on a custom store (useful for testing)
+func NewHostRedactorWithStore(store RedactStore, logger *zap.Logger) *HostRedactor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &HostRedactor{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// DeleteHost removes the host with the given IP, its edges and any of its
+// ports no other host has
+func (r *HostRedactor) DeleteHost(ctx context.Context, ip string) (models.RedactResponse, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return models.RedactResponse{}, fmt.Errorf("%w: IP %q", ErrInvalidRedactTarget, ip)
+	}
+	return r.deleteHosts(ctx, []string{addr.String()})
+}
+
+// DeleteNetwork removes every host inside cidr, as DeleteHost does
+func (r *HostRedactor) DeleteNetwork(ctx context.Context, cidr string) (models.RedactResponse, error) {
+	network, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return models.RedactResponse{}, fmt.Errorf("%w: CIDR %q", ErrInvalidRedactTarget, cidr)
+	}
+	network = network.Masked()
+
+	// Narrow the scan with the network's leading octets, then match exactly
+	query := "SELECT VALUE ip FROM host"
+	vars := map[string]interface{}{}
+	if prefix := ipv4TextPrefix(network); prefix != "" {
+		query += " WHERE string::starts_with(ip, $prefix)"
+		vars["prefix"] = prefix
+	}
+	candidates, err := r.store.SelectValues(ctx, query+";", vars)
+	if err != nil {
+		return models.RedactResponse{}, fmt.Errorf("failed to list hosts in %s: %w", network, err)
+	}
+
+	var ips []string
+	for _, ip := range candidates {
+		if addr, err := netip.ParseAddr(ip); err == nil && network.Contains(addr) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return models.RedactResponse{}, nil
+	}
+	return r.deleteHosts(ctx, ips)
+}
+
+// deleteHosts removes the hosts with the given IPs, then the ports they leave orphaned
+func (r *HostRedactor) deleteHosts(ctx context.Context, ips []string) (models.RedactResponse, error) {
+	var result models.RedactResponse
+	vars := map[string]interface{}{"ips": ips}
+
+	// Note the hosts' ports before their HAS edges go
+	ports, err := r.store.SelectValues(ctx, `SELECT VALUE type::string(out) FROM HAS WHERE in.ip IN $ips;`, vars)
+	if err != nil {
+		return result, fmt.Errorf("failed to list ports of redacted hosts: %w", err)
+	}
+
+	for _, table := range hostEdgeTables {
+		removed, err := r.store.Exec(ctx, fmt.Sprintf(`DELETE %s WHERE in.ip IN $ips RETURN BEFORE;`, table), vars)
+		if err != nil {
+			return result, fmt.Errorf("failed to delete %s edges of redacted hosts: %w", table, err)
+		}
+		result.Edges += removed
+	}
+
+	// A shared port outlives the host, but the services seen on it for this host don't
+	removed, err := r.store.Exec(ctx, `DELETE RUNS WHERE host_ip IN $ips RETURN BEFORE;`, vars)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete RUNS edges of redacted hosts: %w", err)
+	}
+	result.Edges += removed
+
+	removed, err = r.store.Exec(ctx, `DELETE host WHERE ip IN $ips RETURN BEFORE;`, vars)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete redacted hosts: %w", err)
+	}
+	result.Hosts = removed
+
+	if len(ports) > 0 {
+		orphans, err := r.store.SelectValues(ctx, `
+			SELECT VALUE type::string(id) FROM port
+			WHERE type::string(id) IN $ports AND count(<-HAS) = 0;
+		`, map[string]interface{}{"ports": ports})
+		if err != nil {
+			return result, fmt.Errorf("failed to find orphaned ports: %w", err)
+		}
+
+		if len(orphans) > 0 {
+			orphanVars := map[string]interface{}{"ports": orphans}
+			for _, table := range portEdgeTables {
+				removed, err := r.store.Exec(ctx, fmt.Sprintf(`DELETE %s WHERE type::string(in) IN $ports RETURN BEFORE;`, table), orphanVars)
+				if err != nil {
+					return result, fmt.Errorf("failed to delete %s edges of orphaned ports: %w", table, err)
+				}
+				result.Edges += removed
+			}
+
+			removed, err := r.store.Exec(ctx, `DELETE port WHERE type::string(id) IN $ports RETURN BEFORE;`, orphanVars)
+			if err != nil {
+				return result, fmt.Errorf("failed to delete orphaned ports: %w", err)
+			}
+			result.Ports = removed
+		}
+	}
+
+	r.logger.Info("redacted hosts",
+		zap.Int("hosts", result.Hosts),
+		zap.Int("ports", result.Ports),
+		zap.Int("edges", result.Edges))
+
+	return result, nil
+}
+
+// ipv4TextPrefix returns the dotted text every IPv4 address in network starts
+// with, e.g. "10.1." for 10.1.0.0/16 or 10.1.128.0/17, or "" when there is none
+func ipv4TextPrefix(network netip.Prefix) string {
+	if !network.Addr().Is4() || network.Bits() < 8 {
+		return ""
+	}
+	if network.Bits() == 32 {
+		// A single address; a trailing dot would not match it
+		return network.Addr().String()
+	}
+
+	octets := network.Addr().As4()
+	parts := make([]string, network.Bits()/8)
+	for i := range parts {
+		parts[i] = strconv.Itoa(int(octets[i]))
+	}
+	return strings.Join(parts, ".") + "."
+}
+
+// surrealRedactStore runs redactions against SurrealDB
+type surrealRedactStore struct {
+	db *surrealdb.DB
+}
+
+// Exec runs a statement that returns the records it touched
+func (s *surrealRedactStore) Exec(ctx context.Context, query string, vars map[string]interface{}) (int, error) {
+	result, err := surrealdb.Query[[]map[string]interface{}](ctx, s.db, query, vars)
+	if err != nil {
+		return 0, err
+	}
+	if result == nil || len(*result) == 0 {
+		return 0, nil
+	}
+	return len((*result)[0].Result), nil
+}
+
+// SelectValues runs a SELECT VALUE query returning strings
+func (s *surrealRedactStore) SelectValues(ctx context.Context, query string, vars map[string]interface{}) ([]string, error) {
+	result, err := surrealdb.Query[[]string](ctx, s.db, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(*result) == 0 {
+		return nil, nil
+	}
+	return (*result)[0].Result, nil
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap/zaptest"
+)
+
+// stubRedactStore records statements and answers them from selects (by query
+// fragment) and counts (by DELETE target)
+type stubRedactStore struct {
+	selects    map[string][]string
+	counts     map[string]int
+	statements []string
+	deleted    []string // IPs of the last DELETE host
+}
+
+func (s *stubRedactStore) Exec(ctx context.Context, query string, vars map[string]interface{}) (int, error) {
+	s.statements = append(s.statements, strings.TrimSpace(query))
+	if strings.HasPrefix(query, "DELETE host") {
+		s.deleted = vars["ips"].([]string)
+	}
+	return s.counts[strings.Fields(query)[1]], nil
+}
+
+func (s *stubRedactStore) SelectValues(ctx context.Context, query string, vars map[string]interface{}) ([]string, error) {
+	s.statements = append(s.statements, strings.TrimSpace(query))
+	for fragment, values := range s.selects {
+		if strings.Contains(query, fragment) {
+			return values, nil
+		}
+	}
+	return nil, nil
+}
+
+// stubRedactor returns a redactor on a stubRedactStore
+func stubRedactor(selects map[string][]string, counts map[string]int) (*HostRedactor, *stubRedactStore) {
+	store := &stubRedactStore{selects: selects, counts: counts}
+	return NewHostRedactorWithStore(store, nil), store
+}
+
+func TestHostRedactor_DeleteHost(t *testing.T) {
+	r, store := stubRedactor(map[string][]string{
+		"FROM HAS":  {"port:port_22_tcp", "port:port_443_tcp"},
+		"FROM port": {"port:port_443_tcp"},
+	}, map[string]int{"HAS": 2, "IN_CITY": 1, "IN_ASN": 1, "host": 1, "RUNS": 1, "port": 1})
+
+	result, err := r.DeleteHost(context.Background(), "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RedactResponse{Hosts: 1, Ports: 1, Edges: 6}, result)
+
+	// Edges go before the host, and orphaned ports are found after both
+	var order []string
+	for _, statement := range store.statements {
+		order = append(order, strings.Join(strings.Fields(statement)[:2], " "))
+	}
+	assert.Equal(t, []string{
+		"SELECT VALUE", "DELETE HAS", "DELETE IN_CITY", "DELETE HOST_IN_REGION", "DELETE HOST_IN_COUNTRY",
+		"DELETE IN_ASN", "DELETE IN_CLOUD_REGION", "DELETE RUNS",
+		"DELETE host", "SELECT VALUE", "DELETE RUNS", "DELETE IS_COMMON", "DELETE port",
+	}, order)
+}
+
+func TestHostRedactor_DeleteHostKeepsSharedPorts(t *testing.T) {
+	r, store := stubRedactor(map[string][]string{
+		"FROM HAS": {"port:port_22_tcp"},
+	}, map[string]int{"HAS": 1, "host": 1})
+
+	result, err := r.DeleteHost(context.Background(), "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RedactResponse{Hosts: 1, Edges: 1}, result)
+	for _, statement := range store.statements {
+		assert.NotContains(t, statement, "DELETE port")
+	}
+}
+
+func TestHostRedactor_DeleteNetwork(t *testing.T) {
+	r, store := stubRedactor(map[string][]string{
+		"FROM host": {"10.1.2.3", "10.1.200.9", "10.10.0.1"},
+	}, map[string]int{"host": 2})
+
+	result, err := r.DeleteNetwork(context.Background(), "10.1.0.0/16")
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Hosts)
+	assert.Equal(t, []string{"10.1.2.3", "10.1.200.9"}, store.deleted, "10.10.0.1 shares the text prefix but not the network")
+}
+
+func TestHostRedactor_DeleteNetworkNoHosts(t *testing.T) {
+	r, store := stubRedactor(nil, nil)
+
+	result, err := r.DeleteNetwork(context.Background(), "198.51.100.0/24")
+	require.NoError(t, err)
+	assert.Equal(t, models.RedactResponse{}, result)
+	assert.Len(t, store.statements, 1, "nothing is deleted when no host is in the network")
+}
+
+func TestHostRedactor_InvalidTarget(t *testing.T) {
+	r := NewHostRedactor(nil, nil)
+
+	_, err := r.DeleteHost(context.Background(), "not-an-ip")
+	assert.True(t, errors.Is(err, ErrInvalidRedactTarget))
+
+	_, err = r.DeleteNetwork(context.Background(), "10.0.0.0/33")
+	assert.True(t, errors.Is(err, ErrInvalidRedactTarget))
+}
+
+func TestIPv4TextPrefix(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.0/8":     "10.",
+		"10.1.0.0/16":    "10.1.",
+		"10.1.128.0/17":  "10.1.",
+		"192.0.2.0/24":   "192.0.2.",
+		"192.0.2.7/32":   "192.0.2.7",
+		"0.0.0.0/0":      "",
+		"2001:db8::/32":  "",
+		"192.0.2.128/25": "192.0.2.",
+		"172.16.0.0/12":  "172.",
+	}
+	for cidr, want := range tests {
+		assert.Equal(t, want, ipv4TextPrefix(netip.MustParsePrefix(cidr)), cidr)
+	}
+}
+
+func TestHostRedactor_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	// Two hosts share port 22 and each runs a service on it; only the redacted
+	// one has port 443 and its service
+	_, err := surrealdb.Query[interface{}](ctx, db, `
+		DELETE host; DELETE port; DELETE service; DELETE asn; DELETE city;
+		CREATE host:redact_a SET ip = '192.0.2.1';
+		CREATE host:redact_b SET ip = '192.0.2.2';
+		CREATE port:port_22_tcp SET number = 22, protocol = 'tcp';
+		CREATE port:port_443_tcp SET number = 443, protocol = 'tcp';
+		CREATE service:redact_https SET name = 'https';
+		CREATE service:redact_ssh SET name = 'ssh';
+		CREATE asn:asn64500 SET number = 64500;
+		CREATE city:redact_city SET name = 'Testville';
+		RELATE host:redact_a->HAS->port:port_22_tcp;
+		RELATE host:redact_a->HAS->port:port_443_tcp;
+		RELATE host:redact_b->HAS->port:port_22_tcp;
+		RELATE host:redact_a->IN_ASN->asn:asn64500;
+		RELATE host:redact_a->IN_CITY->city:redact_city;
+		RELATE port:port_443_tcp->RUNS->service:redact_https SET host_ip = '192.0.2.1';
+		RELATE port:port_22_tcp->RUNS->service:redact_ssh SET host_ip = '192.0.2.1';
+		RELATE port:port_22_tcp->RUNS->service:redact_ssh SET host_ip = '192.0.2.2';
+	`, nil)
+	require.NoError(t, err)
+
+	r := NewHostRedactor(db, zaptest.NewLogger(t))
+	result, err := r.DeleteHost(ctx, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RedactResponse{Hosts: 1, Ports: 1, Edges: 6}, result)
+
+	count := func(query string) int {
+		res, err := surrealdb.Query[[]interface{}](ctx, db, query, nil)
+		require.NoError(t, err)
+		return len((*res)[0].Result)
+	}
+	assert.Zero(t, count("SELECT id FROM host WHERE ip = '192.0.2.1';"))
+	assert.Zero(t, count("SELECT id FROM port:port_443_tcp;"), "orphaned port is removed")
+	assert.Zero(t, count("SELECT id FROM HAS WHERE in = host:redact_a;"))
+	assert.Zero(t, count("SELECT id FROM IN_ASN WHERE in = host:redact_a;"))
+	assert.Zero(t, count("SELECT id FROM IN_CITY WHERE in = host:redact_a;"))
+	assert.Zero(t, count("SELECT id FROM RUNS WHERE in = port:port_443_tcp;"))
+
+	assert.Equal(t, 1, count("SELECT id FROM host WHERE ip = '192.0.2.2';"))
+	assert.Equal(t, 1, count("SELECT id FROM HAS WHERE out = port:port_22_tcp;"), "shared port keeps its other host")
+	assert.Equal(t, 1, count("SELECT id FROM RUNS WHERE in = port:port_22_tcp;"), "and only that host's service")
+	assert.Equal(t, 1, count("SELECT id FROM RUNS WHERE host_ip = '192.0.2.2';"))
+	assert.Equal(t, 1, count("SELECT id FROM asn:asn64500;"), "shared nodes stay")
+	assert.Equal(t, 1, count("SELECT id FROM service:redact_https;"))
+
+	// Redacting the rest of the network removes the remaining host and port
+	result, err = r.DeleteNetwork(ctx, "192.0.2.0/24")
+	require.NoError(t, err)
+	assert.Equal(t, models.RedactResponse{Hosts: 1, Ports: 1, Edges: 2}, result)
+	assert.Zero(t, count("SELECT id FROM host;"))
+	assert.Zero(t, count("SELECT id FROM port;"))
+}
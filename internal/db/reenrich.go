@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// ReenrichStore selects the hosts and services a bulk re-enrichment covers
+type ReenrichStore struct {
+	db     *surrealdb.DB
+	logger *zap.Logger
+}
+
+// NewReenrichStore creates a new re-enrichment store
+func NewReenrichStore(db *surrealdb.DB, logger *zap.Logger) *ReenrichStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ReenrichStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// HostIPs returns up to limit IPs of hosts matching the request's host filters, in IP order
+func (s *ReenrichStore) HostIPs(ctx context.Context, req models.ReenrichRequest, limit int) ([]string, error) {
+	query, params := buildReenrichHostQuery(req, limit)
+
+	type hostRow struct {
+		IP string `json:"ip"`
+	}
+	result, err := surrealdb.Query[[]hostRow](ctx, s.db, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hosts for re-enrichment: %w", err)
+	}
+
+	var ips []string
+	if result != nil && len(*result) > 0 {
+		for _, row := range (*result)[0].Result {
+			ips = append(ips, row.IP)
+		}
+	}
+
+	s.logger.Debug("selected hosts for re-enrichment",
+		zap.Int("hosts", len(ips)))
+
+	return ips, nil
+}
+
+// Services returns up to limit services matching the request's service filters,
+// on hosts matching its host filters, in ID order
+func (s *ReenrichStore) Services(ctx context.Context, req models.ReenrichRequest, limit int) ([]models.ReenrichService, error) {
+	query, params := buildReenrichServiceQuery(req, limit)
+
+	result, err := surrealdb.Query[[]models.ReenrichService](ctx, s.db, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query services for re-enrichment: %w", err)
+	}
+
+	var services []models.ReenrichService
+	if result != nil && len(*result) > 0 {
+		services = (*result)[0].Result
+	}
+
+	s.logger.Debug("selected services for re-enrichment",
+		zap.Int("services", len(services)))
+
+	return services, nil
+}
+
+// buildReenrichHostQuery builds the host selection for a re-enrichment request
+func buildReenrichHostQuery(req models.ReenrichRequest, limit int) (string, map[string]interface{}) {
+	query := "SELECT ip FROM host WHERE 1=1"
+	params := map[string]interface{}{"limit": limit}
+
+	if req.ASN > 0 {
+		query += " AND asn = $asn"
+		params["asn"] = req.ASN
+	}
+	if req.Country != "" {
+		query += " AND country = $country"
+		params["country"] = req.Country
+	}
+
+	return query + " ORDER BY ip LIMIT $limit;", params
+}
+
+// buildReenrichServiceQuery builds the service selection for a re-enrichment request
+// Host filters are applied through the host -> HAS -> port -> RUNS -> service path.
+func buildReenrichServiceQuery(req models.ReenrichRequest, limit int) (string, map[string]interface{}) {
+	query := "SELECT type::string(id) AS id, name, product, version FROM service WHERE 1=1"
+	params := map[string]interface{}{"limit": limit}
+
+	if req.MissingCPE {
+		query += " AND (cpe IS NONE OR array::len(cpe) = 0)"
+	}
+	if req.StaleBefore != nil {
+		// Services enriched before cpe_updated_at was recorded fall back to last_seen
+		query += " AND (cpe_updated_at < $stale_before OR (cpe_updated_at IS NONE AND last_seen < $stale_before))"
+		params["stale_before"] = req.StaleBefore.UTC()
+	}
+	if req.ASN > 0 {
+		query += " AND $asn IN <-RUNS<-port<-HAS<-host.asn"
+		params["asn"] = req.ASN
+	}
+	if req.Country != "" {
+		query += " AND $country IN <-RUNS<-port<-HAS<-host.country"
+		params["country"] = req.Country
+	}
+
+	return query + " ORDER BY id LIMIT $limit;", params
+}
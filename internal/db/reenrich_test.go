@@ -0,0 +1,74 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildReenrichHostQuery(t *testing.T) {
+	query, params := buildReenrichHostQuery(models.ReenrichRequest{ASN: 64500, Country: "US"}, 50)
+
+	assert.Equal(t, "SELECT ip FROM host WHERE 1=1 AND asn = $asn AND country = $country ORDER BY ip LIMIT $limit;", query)
+	assert.Equal(t, map[string]interface{}{"asn": 64500, "country": "US", "limit": 50}, params)
+
+	query, params = buildReenrichHostQuery(models.ReenrichRequest{Country: "DE"}, 10)
+	assert.NotContains(t, query, "$asn")
+	assert.NotContains(t, params, "asn")
+}
+
+func TestBuildReenrichServiceQuery(t *testing.T) {
+	stale := time.Date(2026, 1, 1, 0, 0, 0, 0, time.FixedZone("EST", -5*3600))
+
+	tests := []struct {
+		name         string
+		req          models.ReenrichRequest
+		wantClauses  []string
+		wantParams   []string
+		absentParams []string
+	}{
+		{
+			name:         "missing cpe",
+			req:          models.ReenrichRequest{MissingCPE: true},
+			wantClauses:  []string{"cpe IS NONE OR array::len(cpe) = 0"},
+			absentParams: []string{"stale_before", "asn", "country"},
+		},
+		{
+			name:         "stale",
+			req:          models.ReenrichRequest{StaleBefore: &stale},
+			wantClauses:  []string{"cpe_updated_at < $stale_before", "last_seen < $stale_before"},
+			wantParams:   []string{"stale_before"},
+			absentParams: []string{"asn", "country"},
+		},
+		{
+			name:        "scoped to hosts",
+			req:         models.ReenrichRequest{MissingCPE: true, ASN: 64500, Country: "US"},
+			wantClauses: []string{"$asn IN <-RUNS<-port<-HAS<-host.asn", "$country IN <-RUNS<-port<-HAS<-host.country"},
+			wantParams:  []string{"asn", "country"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, params := buildReenrichServiceQuery(tt.req, 25)
+
+			assert.Contains(t, query, "SELECT type::string(id) AS id, name, product, version FROM service")
+			assert.Contains(t, query, "LIMIT $limit")
+			assert.Equal(t, 25, params["limit"])
+			for _, clause := range tt.wantClauses {
+				assert.Contains(t, query, clause)
+			}
+			for _, key := range tt.wantParams {
+				assert.Contains(t, params, key)
+			}
+			for _, key := range tt.absentParams {
+				assert.NotContains(t, params, key)
+			}
+		})
+	}
+
+	_, params := buildReenrichServiceQuery(models.ReenrichRequest{StaleBefore: &stale}, 1)
+	assert.Equal(t, time.UTC, params["stale_before"].(time.Time).Location())
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// scannerStatsQuery aggregates the jobs of one scanner in the database
+// Jobs are found through the indexed scanner_id, the ScannerID stamped on each
+// job at creation, so neither the lookup nor the totals read other scanners' jobs.
+const scannerStatsQuery = `
+	SELECT
+		count() AS jobs,
+		count(state = 'completed') AS completed,
+		count(state = 'failed') AS failed,
+		count(state = 'cancelled') AS cancelled,
+		math::sum(host_count ?? 0) AS hosts,
+		math::sum(port_count ?? 0) AS ports,
+		time::min(created_at) AS first_contributed,
+		time::max(created_at) AS last_contributed
+	FROM job
+	WHERE scanner_id = $scanner_id
+	GROUP ALL;
+`
+
+// ScannerStatsStore aggregates jobs into per-scanner contribution stats
+type ScannerStatsStore struct {
+	querier ScannerStatsQuerier
+	logger  *zap.Logger
+}
+
+// ScannerStatsQuerier runs scanner stats queries
+type ScannerStatsQuerier interface {
+	QueryStats(ctx context.Context, query string, vars map[string]interface{}) ([]models.ScannerStats, error)
+}
+
+// NewScannerStatsStore creates a new scanner stats store
+func NewScannerStatsStore(db *surrealdb.DB, logger *zap.Logger) *ScannerStatsStore {
+	return NewScannerStatsStoreWithQuerier(&surrealScannerStatsQuerier{db: db}, logger)
+}
+
+// NewScannerStatsStoreWithQuerier creates a scanner stats store on a custom querier (useful for testing)
+func NewScannerStatsStoreWithQuerier(querier ScannerStatsQuerier, logger *zap.Logger) *ScannerStatsStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ScannerStatsStore{
+		querier: querier,
+		logger:  logger,
+	}
+}
+
+// Stats returns the contribution stats of the scanner with the given
+// ScannerID, or nil if it has no jobs
+func (s *ScannerStatsStore) Stats(ctx context.Context, scannerID string) (*models.ScannerStats, error) {
+	rows, err := s.querier.QueryStats(ctx, scannerStatsQuery, map[string]interface{}{
+		"scanner_id": scannerID,
+	})
+	if err != nil {
+		s.logger.Error("failed to query scanner stats",
+			zap.Error(err),
+			zap.String("scanner_id", scannerID))
+		return nil, fmt.Errorf("failed to query scanner stats: %w", err)
+	}
+	if len(rows) == 0 || rows[0].Jobs == 0 {
+		return nil, nil
+	}
+
+	stats := rows[0]
+	stats.ScannerID = scannerID
+	if stats.FirstContributed != nil {
+		first := stats.FirstContributed.UTC()
+		stats.FirstContributed = &first
+	}
+	if stats.LastContributed != nil {
+		last := stats.LastContributed.UTC()
+		stats.LastContributed = &last
+	}
+
+	// Jobs still pending or processing, or cancelled, say nothing about success
+	if finished := stats.Completed + stats.Failed; finished > 0 {
+		rate := float64(stats.Completed) / float64(finished)
+		stats.SuccessRate = &rate
+	}
+	return &stats, nil
+}
+
+// surrealScannerStatsQuerier runs scanner stats queries against SurrealDB
+type surrealScannerStatsQuerier struct {
+	db *surrealdb.DB
+}
+
+// QueryStats runs a stats query against the database
+func (q *surrealScannerStatsQuerier) QueryStats(ctx context.Context, query string, vars map[string]interface{}) ([]models.ScannerStats, error) {
+	result, err := surrealdb.Query[[]models.ScannerStats](ctx, q.db, query, vars)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(*result) == 0 {
+		return nil, nil
+	}
+	if (*result)[0].Error != nil {
+		return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+	return (*result)[0].Result, nil
+}
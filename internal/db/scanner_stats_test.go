@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap/zaptest"
+)
+
+// capturingStatsQuerier captures queries and answers them with rows
+type capturingStatsQuerier struct {
+	rows    []models.ScannerStats
+	err     error
+	queries []string
+	vars    []map[string]interface{}
+}
+
+func (q *capturingStatsQuerier) QueryStats(ctx context.Context, query string, vars map[string]interface{}) ([]models.ScannerStats, error) {
+	q.queries = append(q.queries, query)
+	q.vars = append(q.vars, vars)
+	return q.rows, q.err
+}
+
+// stubStatsStore returns a store whose queries are captured and answered with rows
+func stubStatsStore(rows []models.ScannerStats, err error) (*ScannerStatsStore, *[]string, *[]map[string]interface{}) {
+	q := &capturingStatsQuerier{rows: rows, err: err}
+	return NewScannerStatsStoreWithQuerier(q, nil), &q.queries, &q.vars
+}
+
+func TestScannerStatsStore_Stats(t *testing.T) {
+	first := time.Date(2026, 5, 1, 2, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	last := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	store, queries, vars := stubStatsStore([]models.ScannerStats{{
+		Jobs: 4, Completed: 2, Failed: 1, Hosts: 15, Ports: 32,
+		FirstContributed: &first, LastContributed: &last,
+	}}, nil)
+
+	alice, err := store.Stats(context.Background(), models.ScannerID("alice"))
+	require.NoError(t, err)
+	require.NotNil(t, alice)
+
+	require.Len(t, *queries, 1)
+	assert.Contains(t, (*queries)[0], "WHERE scanner_id = $scanner_id", "jobs are found through the indexed hash")
+	assert.Contains(t, (*queries)[0], "GROUP ALL", "the database aggregates")
+	assert.Equal(t, models.ScannerID("alice"), (*vars)[0]["scanner_id"])
+
+	assert.Equal(t, models.ScannerID("alice"), alice.ScannerID)
+	assert.Equal(t, 4, alice.Jobs)
+	assert.Equal(t, 15, alice.Hosts)
+	assert.Equal(t, 32, alice.Ports)
+	require.NotNil(t, alice.SuccessRate)
+	assert.InDelta(t, 2.0/3.0, *alice.SuccessRate, 1e-9, "pending jobs do not count")
+	assert.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), *alice.FirstContributed)
+	assert.Equal(t, last, *alice.LastContributed)
+}
+
+func TestScannerStatsStore_NoJobs(t *testing.T) {
+	for _, rows := range [][]models.ScannerStats{nil, {{Jobs: 0}}} {
+		store, _, _ := stubStatsStore(rows, nil)
+		stats, err := store.Stats(context.Background(), models.ScannerID("carol"))
+		require.NoError(t, err)
+		assert.Nil(t, stats)
+	}
+
+	// Nothing finished yet: no success rate
+	store, _, _ := stubStatsStore([]models.ScannerStats{{Jobs: 1}}, nil)
+	stats, err := store.Stats(context.Background(), models.ScannerID("alice"))
+	require.NoError(t, err)
+	assert.Nil(t, stats.SuccessRate)
+}
+
+func TestScannerStatsStore_QueryError(t *testing.T) {
+	store, _, _ := stubStatsStore(nil, errors.New("connection refused"))
+
+	_, err := store.Stats(context.Background(), models.ScannerID("alice"))
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestScannerStatsStore_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	_, err := surrealdb.Query[interface{}](ctx, db, `DELETE job;`, nil)
+	require.NoError(t, err)
+
+	for _, seed := range []struct {
+		key   string
+		state models.JobState
+		hosts int
+	}{
+		{"alice", models.JobStateCompleted, 10},
+		{"alice", models.JobStateFailed, 0},
+		{"alice", models.JobStateCompleted, 5},
+		{"bob", models.JobStateCompleted, 100},
+	} {
+		job, err := CreateJob(ctx, db, logger, seed.key)
+		require.NoError(t, err)
+		require.NoError(t, UpdateJobState(ctx, db, logger, job.ID, models.JobStateProcessing, nil))
+		require.NoError(t, UpdateJobState(ctx, db, logger, job.ID, seed.state, nil))
+		_, err = surrealdb.Query[interface{}](ctx, db, `UPDATE type::thing('job', $id) SET host_count = $hosts;`, map[string]interface{}{
+			"id":    job.ID,
+			"hosts": seed.hosts,
+		})
+		require.NoError(t, err)
+	}
+
+	store := NewScannerStatsStore(db, logger)
+	alice, err := store.Stats(ctx, models.ScannerID("alice"))
+	require.NoError(t, err)
+	require.NotNil(t, alice)
+	assert.Equal(t, 3, alice.Jobs)
+	assert.Equal(t, 2, alice.Completed)
+	assert.Equal(t, 1, alice.Failed)
+	assert.Equal(t, 15, alice.Hosts)
+	require.NotNil(t, alice.FirstContributed)
+
+	bob, err := store.Stats(ctx, models.ScannerID("bob"))
+	require.NoError(t, err)
+	require.NotNil(t, bob)
+	assert.Equal(t, 1, bob.Jobs)
+	assert.Equal(t, 100, bob.Hosts)
+}
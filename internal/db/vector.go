@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spectra-red/recon/internal/models"
@@ -58,6 +59,8 @@ type VulnDocResult struct {
 	Title         string    `json:"title"`
 	Summary       string    `json:"summary"`
 	CVSS          float64   `json:"cvss"`
+	EPSS          float64   `json:"epss"`
+	KEV           bool      `json:"kev"`
 	CPE           []string  `json:"cpe"`
 	PublishedDate time.Time `json:"published_date"`
 	Score         float64   `json:"score"` // Similarity score
@@ -74,8 +77,8 @@ func (c *VectorSearchClient) VectorSearch(ctx context.Context, params VectorSear
 	if params.K < 1 {
 		params.K = models.DefaultK
 	}
-	if params.K > models.MaxK {
-		params.K = models.MaxK
+	if params.K > models.MaxSearchWindow {
+		params.K = models.MaxSearchWindow
 	}
 
 	startTime := time.Now()
@@ -95,6 +98,8 @@ func (c *VectorSearchClient) VectorSearch(ctx context.Context, params VectorSear
 			title,
 			summary,
 			cvss,
+			epss ?? 0.0 AS epss,
+			(SELECT VALUE kev_flag FROM vuln WHERE cve_id = $parent.cve_id LIMIT 1)[0] ?? false AS kev,
 			cpe,
 			published_date,
 			vector::similarity::cosine(embedding, $query_embedding) AS score
@@ -145,18 +150,7 @@ func (c *VectorSearchClient) VectorSearch(ctx context.Context, params VectorSear
 	}
 
 	// Convert to model results
-	results := make([]models.VulnResult, 0, len(filtered))
-	for _, r := range filtered {
-		results = append(results, models.VulnResult{
-			CVEID:         r.CVEID,
-			Title:         r.Title,
-			Summary:       r.Summary,
-			CVSS:          r.CVSS,
-			CPE:           r.CPE,
-			PublishedDate: r.PublishedDate.Format(time.RFC3339),
-			Score:         r.Score,
-		})
-	}
+	results := toVulnResults(filtered)
 
 	c.logger.Info("vector search completed",
 		zap.Int("results", len(results)),
@@ -179,36 +173,104 @@ func (c *VectorSearchClient) VectorSearchWithMinScore(ctx context.Context, embed
 	})
 }
 
-// CreateVectorSearchClient creates and initializes a vector search client with database connection
-func CreateVectorSearchClient(ctx context.Context, logger *zap.Logger) (*VectorSearchClient, error) {
-	// Create database connection
-	db, err := surrealdb.New("ws://localhost:8000/rpc")
+// KeywordSearchParams holds parameters for keyword search
+type KeywordSearchParams struct {
+	// Query is the keyword or identifier to match (case-insensitive)
+	Query string
+
+	// K is the number of results to return
+	K int
+}
+
+// KeywordSearch matches vulnerability documents whose CVE ID equals the query or
+// whose title or summary contains it. Exact CVE ID matches are ranked first, then
+// by CVSS. Keyword matches carry no similarity score.
+func (c *VectorSearchClient) KeywordSearch(ctx context.Context, params KeywordSearchParams) ([]models.VulnResult, error) {
+	term := strings.ToLower(strings.TrimSpace(params.Query))
+	if term == "" {
+		return nil, ErrNoResults
+	}
+
+	// Validate K
+	if params.K < 1 {
+		params.K = models.DefaultK
+	}
+	if params.K > models.MaxSearchWindow {
+		params.K = models.MaxSearchWindow
+	}
+
+	startTime := time.Now()
+
+	query := `
+		SELECT
+			meta::id(id) AS id,
+			cve_id,
+			title,
+			summary,
+			cvss,
+			epss ?? 0.0 AS epss,
+			(SELECT VALUE kev_flag FROM vuln WHERE cve_id = $parent.cve_id LIMIT 1)[0] ?? false AS kev,
+			cpe,
+			published_date,
+			string::lowercase(cve_id) = $term AS exact
+		FROM vuln_doc
+		WHERE string::lowercase(cve_id) = $term
+			OR string::contains(string::lowercase(title ?? ""), $term)
+			OR string::contains(string::lowercase(summary ?? ""), $term)
+		ORDER BY exact DESC, cvss DESC
+		LIMIT $k
+	`
+
+	result, err := surrealdb.Query[[]VulnDocResult](ctx, c.db, query, map[string]interface{}{
+		"term": term,
+		"k":    params.K,
+	})
 	if err != nil {
+		c.logger.Error("keyword search query failed",
+			zap.Error(err),
+			zap.Duration("elapsed", time.Since(startTime)))
 		return nil, fmt.Errorf("%w: %v", ErrDatabaseUnavailable, err)
 	}
 
-	// Sign in
-	if _, err := db.SignIn(ctx, map[string]interface{}{
-		"user": "root",
-		"pass": "root",
-	}); err != nil {
-		db.Close(ctx)
-		return nil, fmt.Errorf("%w: authentication failed", ErrDatabaseUnavailable)
+	if result == nil || len(*result) == 0 {
+		return nil, ErrNoResults
 	}
 
-	// Use namespace and database
-	if err := db.Use(ctx, "spectra", "intel"); err != nil {
-		db.Close(ctx)
-		return nil, fmt.Errorf("%w: failed to use database", ErrDatabaseUnavailable)
+	queryResult := (*result)[0]
+	if queryResult.Error != nil {
+		c.logger.Error("keyword query returned error",
+			zap.String("error", queryResult.Error.Error()))
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseUnavailable, queryResult.Error)
 	}
 
-	return NewVectorSearchClient(db, logger), nil
+	results := toVulnResults(queryResult.Result)
+
+	c.logger.Info("keyword search completed",
+		zap.Int("results", len(results)),
+		zap.Duration("elapsed", time.Since(startTime)))
+
+	if len(results) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return results, nil
 }
 
-// Close closes the database connection
-func (c *VectorSearchClient) Close(ctx context.Context) error {
-	if c.db != nil {
-		return c.db.Close(ctx)
+// toVulnResults converts database documents to API results
+func toVulnResults(docs []VulnDocResult) []models.VulnResult {
+	results := make([]models.VulnResult, 0, len(docs))
+	for _, r := range docs {
+		results = append(results, models.VulnResult{
+			CVEID:         r.CVEID,
+			Title:         r.Title,
+			Summary:       r.Summary,
+			CVSS:          r.CVSS,
+			EPSS:          r.EPSS,
+			KEV:           r.KEV,
+			CPE:           r.CPE,
+			PublishedDate: r.PublishedDate.Format(time.RFC3339),
+			Score:         r.Score,
+		})
 	}
-	return nil
+	return results
 }
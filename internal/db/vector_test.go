@@ -7,6 +7,8 @@ import (
 	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -313,17 +315,30 @@ func setupTestDatabase(t *testing.T) (*VectorSearchClient, func()) {
 	ctx := context.Background()
 	logger := zaptest.NewLogger(t)
 
-	client, err := CreateVectorSearchClient(ctx, logger)
+	conn, err := surrealdb.New("ws://localhost:8000/rpc")
 	if err != nil {
 		t.Skipf("skipping test: database not available: %v", err)
 		return nil, nil
 	}
+	if _, err := conn.SignIn(ctx, map[string]interface{}{
+		"user": "root",
+		"pass": "root",
+	}); err != nil {
+		conn.Close(ctx)
+		t.Skipf("skipping test: database authentication failed: %v", err)
+		return nil, nil
+	}
+	if err := conn.Use(ctx, "spectra", "intel"); err != nil {
+		conn.Close(ctx)
+		t.Skipf("skipping test: failed to use database: %v", err)
+		return nil, nil
+	}
 
 	cleanup := func() {
-		client.Close(ctx)
+		conn.Close(ctx)
 	}
 
-	return client, cleanup
+	return NewVectorSearchClient(conn, logger), cleanup
 }
 
 func TestVectorSearch_Integration(t *testing.T) {
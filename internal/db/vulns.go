@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
+)
+
+// VulnStore reads vulnerability nodes and their documents
+type VulnStore struct {
+	db     *surrealdb.DB
+	logger *zap.Logger
+}
+
+// NewVulnStore creates a new vulnerability store
+func NewVulnStore(db *surrealdb.DB, logger *zap.Logger) *VulnStore {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &VulnStore{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// vulnDetailRow is a vuln node joined with its vuln_doc and affected host count
+type vulnDetailRow struct {
+	CVEID         string      `json:"cve_id"`
+	CVSS          float64     `json:"cvss"`
+	CVSSVector    string      `json:"cvss_vector"`
+	Severity      string      `json:"severity"`
+	KEVFlag       bool        `json:"kev_flag"`
+	FirstSeen     time.Time   `json:"first_seen"`
+	LastUpdated   time.Time   `json:"last_updated"`
+	AffectedHosts int         `json:"affected_hosts"`
+	Doc           *vulnDocRow `json:"doc"`
+}
+
+// vulnDocRow holds the vuln_doc fields returned in a CVE's detail
+type vulnDocRow struct {
+	Title         string     `json:"title"`
+	Summary       string     `json:"summary"`
+	EPSS          float64    `json:"epss"`
+	CPE           []string   `json:"cpe"`
+	ExploitRefs   []string   `json:"exploit_refs"`
+	PublishedDate *time.Time `json:"published_date"`
+	LastModified  *time.Time `json:"last_modified"`
+}
+
+// GetVulnDetail returns the full detail of a CVE, or nil if there is no vuln node for it
+// Affected hosts are counted through service<-RUNS<-port<-HAS<-host, once per host.
+func (s *VulnStore) GetVulnDetail(ctx context.Context, cveID string) (*models.VulnDetailResponse, error) {
+	query := `
+		SELECT
+			cve_id,
+			cvss,
+			cvss_vector,
+			severity,
+			kev_flag,
+			first_seen,
+			last_updated,
+			array::len(array::distinct(<-AFFECTED_BY<-service<-RUNS<-port<-HAS<-host)) AS affected_hosts,
+			(SELECT title, summary, epss, cpe, exploit_refs, published_date, last_modified
+				FROM ONLY type::thing('vuln_doc', $cve_id)) AS doc
+		FROM type::thing('vuln', $cve_id);
+	`
+	result, err := surrealdb.Query[[]vulnDetailRow](ctx, s.db, query, map[string]interface{}{
+		"cve_id": cveID,
+	})
+	if err != nil {
+		s.logger.Error("failed to get vuln detail",
+			zap.Error(err),
+			zap.String("cve_id", cveID))
+		return nil, fmt.Errorf("failed to get vuln detail: %w", err)
+	}
+
+	if result == nil || len(*result) == 0 {
+		return nil, nil
+	}
+	if (*result)[0].Error != nil {
+		return nil, fmt.Errorf("query error: %w", (*result)[0].Error)
+	}
+	if len((*result)[0].Result) == 0 {
+		return nil, nil
+	}
+
+	return (*result)[0].Result[0].detail(), nil
+}
+
+// detail converts a joined row to the API response
+func (r vulnDetailRow) detail() *models.VulnDetailResponse {
+	detail := &models.VulnDetailResponse{
+		CVEID:         r.CVEID,
+		CVSS:          r.CVSS,
+		CVSSVector:    r.CVSSVector,
+		Severity:      r.Severity,
+		KEVFlag:       r.KEVFlag,
+		AffectedHosts: r.AffectedHosts,
+		FirstSeen:     r.FirstSeen,
+		LastUpdated:   r.LastUpdated,
+	}
+	if r.Doc != nil {
+		detail.Title = r.Doc.Title
+		detail.Summary = r.Doc.Summary
+		detail.EPSS = r.Doc.EPSS
+		detail.CPEs = r.Doc.CPE
+		detail.References = r.Doc.ExploitRefs
+		detail.PublishedDate = r.Doc.PublishedDate
+		detail.LastModified = r.Doc.LastModified
+	}
+	return detail
+}
@@ -2,40 +2,123 @@ package enrichment
 
 import (
 	"bufio"
+	"container/list"
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/spectra-red/recon/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ASNInfo represents ASN information for an IP address
 type ASNInfo struct {
-	Number  int    `json:"asn"`
-	Org     string `json:"org"`
+	Number  int    `json:"asn"`               // Primary origin ASN
+	Org     string `json:"org"`               // AS name of the primary origin
 	Country string `json:"country"`
+	Origins []int  `json:"origins,omitempty"` // All origin ASNs, set only for multi-origin prefixes
+	Prefix  string `json:"prefix,omitempty"`  // Announced BGP prefix covering the IP, e.g. 8.8.8.0/24
+}
+
+// OriginASNs returns every origin ASN for the IP, primary first
+func (a *ASNInfo) OriginASNs() []int {
+	if len(a.Origins) > 0 {
+		return a.Origins
+	}
+	return []int{a.Number}
+}
+
+// mergeASNInfo combines two answers for the same IP, keeping the first as primary
+// and adding any origins from the second that are not already present
+func mergeASNInfo(existing, next *ASNInfo) *ASNInfo {
+	if existing == nil {
+		return next
+	}
+
+	merged := *existing
+	origins := append([]int(nil), existing.OriginASNs()...)
+	for _, asn := range next.OriginASNs() {
+		if !containsASN(origins, asn) {
+			origins = append(origins, asn)
+		}
+	}
+	if len(origins) > 1 {
+		merged.Origins = origins
+	}
+
+	return &merged
+}
+
+// containsASN checks if an ASN is already in a list
+func containsASN(asns []int, asn int) bool {
+	for _, a := range asns {
+		if a == asn {
+			return true
+		}
+	}
+	return false
 }
 
 // ASNClient provides ASN lookup capabilities
 type ASNClient interface {
 	LookupASN(ctx context.Context, ip string) (*ASNInfo, error)
 	LookupBatch(ctx context.Context, ips []string) (map[string]*ASNInfo, error)
+	LookupBatchWithOptions(ctx context.Context, ips []string, opts ASNLookupOptions) (map[string]*ASNInfo, error)
+}
+
+// ASNLookupOptions adjusts how a lookup uses the cache
+type ASNLookupOptions struct {
+	// ForceRefresh skips the cache read so the lookup always reaches Team Cymru;
+	// the fresh result is still cached
+	ForceRefresh bool
 }
 
 // TeamCymruClient implements ASN lookups via Team Cymru's whois service
 // https://www.team-cymru.com/ip-asn-mapping
 type TeamCymruClient struct {
 	cache      map[string]*cacheEntry
+	cacheLRU   *list.List // IPs, most recently used at the front
 	cacheMu    sync.RWMutex
 	cacheTTL   time.Duration
+	cacheMax   int
+	counters   cacheCounters
 	rateLimit  *rateLimiter
+	whoisAddr  string
+	dialer     *net.Dialer
+	breaker    *CircuitBreaker
 }
 
 type cacheEntry struct {
 	info      *ASNInfo
 	timestamp time.Time
+	elem      *list.Element // position in cacheLRU
+}
+
+const (
+	// DefaultASNCacheMaxEntries caps the ASN cache when TeamCymruConfig leaves it unset
+	DefaultASNCacheMaxEntries = 100000
+	// DefaultWhoisDialTimeout bounds connecting to the whois server, so a
+	// blackholed SYN fails fast even when the caller's context has no deadline
+	DefaultWhoisDialTimeout = 5 * time.Second
+
+	// Team Cymru bulk whois endpoint
+	teamCymruWhoisAddr = "whois.cymru.com:43"
+	// Keep-alive probe interval for whois connections
+	whoisKeepAlive = 15 * time.Second
+)
+
+// TeamCymruConfig configures a TeamCymruClient
+type TeamCymruConfig struct {
+	RateLimit       int           // Max requests per minute (default 100)
+	CacheTTL        time.Duration // How long to cache results (default 24 hours)
+	CacheMaxEntries int           // Cached IPs kept before LRU eviction (defaults to DefaultASNCacheMaxEntries)
+	DialTimeout     time.Duration // Connect timeout for the whois server (defaults to DefaultWhoisDialTimeout)
+	Breaker         BreakerConfig // Circuit breaker around whois lookups
 }
 
 type rateLimiter struct {
@@ -50,6 +133,22 @@ type rateLimiter struct {
 // rateLimit: max requests per minute (default 100)
 // cacheTTL: how long to cache results (default 24 hours)
 func NewTeamCymruClient(rateLimit int, cacheTTL time.Duration) *TeamCymruClient {
+	return NewTeamCymruClientWithConfig(TeamCymruConfig{RateLimit: rateLimit, CacheTTL: cacheTTL})
+}
+
+// NewTeamCymruClientWithConfig creates a new ASN client using Team Cymru from config
+// The cache is bounded by CacheMaxEntries independently of its TTL.
+func NewTeamCymruClientWithConfig(cfg TeamCymruConfig) *TeamCymruClient {
+	rateLimit := cfg.RateLimit
+	cacheTTL := cfg.CacheTTL
+	cacheMax := cfg.CacheMaxEntries
+	if cacheMax <= 0 {
+		cacheMax = DefaultASNCacheMaxEntries
+	}
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = DefaultWhoisDialTimeout
+	}
 	if rateLimit <= 0 {
 		rateLimit = 100 // Default 100 req/min
 	}
@@ -59,7 +158,15 @@ func NewTeamCymruClient(rateLimit int, cacheTTL time.Duration) *TeamCymruClient
 
 	return &TeamCymruClient{
 		cache:    make(map[string]*cacheEntry),
+		cacheLRU: list.New(),
 		cacheTTL: cacheTTL,
+		cacheMax: cacheMax,
+		whoisAddr: teamCymruWhoisAddr,
+		dialer: &net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: whoisKeepAlive,
+		},
+		breaker: NewCircuitBreaker(cfg.Breaker),
 		rateLimit: &rateLimiter{
 			tokens:    rateLimit,
 			maxTokens: rateLimit,
@@ -71,18 +178,33 @@ func NewTeamCymruClient(rateLimit int, cacheTTL time.Duration) *TeamCymruClient
 
 // LookupASN performs an ASN lookup for a single IP address
 func (c *TeamCymruClient) LookupASN(ctx context.Context, ip string) (*ASNInfo, error) {
+	return c.LookupASNWithOptions(ctx, ip, ASNLookupOptions{})
+}
+
+// LookupASNWithOptions performs an ASN lookup for a single IP address
+func (c *TeamCymruClient) LookupASNWithOptions(ctx context.Context, ip string, opts ASNLookupOptions) (*ASNInfo, error) {
 	// Check cache first
-	if info := c.checkCache(ip); info != nil {
-		return info, nil
+	if !opts.ForceRefresh {
+		if info := c.checkCache(ip); info != nil {
+			return info, nil
+		}
+	}
+
+	// Fail fast while Team Cymru is down
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
 	}
 
 	// Wait for rate limit token
 	if err := c.rateLimit.wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait failed: %w", err)
+		err = fmt.Errorf("%w: rate limit wait failed: %w", ErrRateLimited, err)
+		c.breaker.Record(err)
+		return nil, err
 	}
 
 	// Perform lookup
 	info, err := c.lookupTeamCymru(ctx, ip)
+	c.breaker.Record(err)
 	if err != nil {
 		return nil, err
 	}
@@ -96,12 +218,19 @@ func (c *TeamCymruClient) LookupASN(ctx context.Context, ip string) (*ASNInfo, e
 // LookupBatch performs ASN lookups for multiple IP addresses
 // This is more efficient than calling LookupASN multiple times
 func (c *TeamCymruClient) LookupBatch(ctx context.Context, ips []string) (map[string]*ASNInfo, error) {
+	return c.LookupBatchWithOptions(ctx, ips, ASNLookupOptions{})
+}
+
+// LookupBatchWithOptions performs ASN lookups for multiple IP addresses
+func (c *TeamCymruClient) LookupBatchWithOptions(ctx context.Context, ips []string, opts ASNLookupOptions) (map[string]*ASNInfo, error) {
 	results := make(map[string]*ASNInfo)
 	var missing []string
 
 	// Check cache for all IPs
 	for _, ip := range ips {
-		if info := c.checkCache(ip); info != nil {
+		if opts.ForceRefresh {
+			missing = append(missing, ip)
+		} else if info := c.checkCache(ip); info != nil {
 			results[ip] = info
 		} else {
 			missing = append(missing, ip)
@@ -124,13 +253,25 @@ func (c *TeamCymruClient) LookupBatch(ctx context.Context, ips []string) (map[st
 
 		batch := missing[i:end]
 
+		// Fail fast while Team Cymru is down
+		if err := c.breaker.Allow(); err != nil {
+			return results, err
+		}
+
 		// Wait for rate limit token
 		if err := c.rateLimit.wait(ctx); err != nil {
-			return results, fmt.Errorf("rate limit wait failed: %w", err)
+			err = fmt.Errorf("%w: rate limit wait failed: %w", ErrRateLimited, err)
+			c.breaker.Record(err)
+			return results, err
 		}
 
 		// Perform batch lookup
-		batchResults, err := c.lookupTeamCymruBatch(ctx, batch)
+		batchCtx, span := tracing.Start(ctx, "enrichment.asn.LookupBatch",
+			attribute.Int("ips", len(batch)))
+		batchResults, err := c.lookupTeamCymruBatch(batchCtx, batch)
+		span.SetAttributes(attribute.Int("results", len(batchResults)))
+		tracing.End(span, err)
+		c.breaker.Record(err)
 		if err != nil {
 			return results, fmt.Errorf("batch lookup failed: %w", err)
 		}
@@ -148,10 +289,9 @@ func (c *TeamCymruClient) LookupBatch(ctx context.Context, ips []string) (map[st
 // lookupTeamCymru performs a single ASN lookup via Team Cymru whois
 func (c *TeamCymruClient) lookupTeamCymru(ctx context.Context, ip string) (*ASNInfo, error) {
 	// Connect to Team Cymru whois server
-	var dialer net.Dialer
-	conn, err := dialer.DialContext(ctx, "tcp", "whois.cymru.com:43")
+	conn, err := c.dialWhois(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Team Cymru: %w", err)
+		return nil, err
 	}
 	defer conn.Close()
 
@@ -162,45 +302,39 @@ func (c *TeamCymruClient) lookupTeamCymru(ctx context.Context, ip string) (*ASNI
 	// Send query: " -v <ip>" for verbose output
 	query := fmt.Sprintf(" -v %s\n", ip)
 	if _, err := conn.Write([]byte(query)); err != nil {
-		return nil, fmt.Errorf("failed to write query: %w", err)
+		return nil, fmt.Errorf("%w: failed to write query: %w", ErrUpstreamUnavailable, err)
 	}
 
-	// Read response
-	scanner := bufio.NewScanner(conn)
-	var responseLine string
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Skip comment lines and headers
-		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "AS") || strings.TrimSpace(line) == "" {
-			continue
-		}
-		responseLine = line
-		break
+	// Read response; multi-origin prefixes can answer with several lines
+	results, err := c.parseTeamCymruBatch(conn)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	info, ok := results[ip]
+	if !ok {
+		return nil, fmt.Errorf("%w: no ASN data for IP %s", ErrNotFound, ip)
 	}
 
-	if responseLine == "" {
-		return nil, fmt.Errorf("no ASN data found for IP %s", ip)
-	}
+	return info, nil
+}
 
-	// Parse response
-	// Format: ASN | IP | BGP Prefix | CC | Registry | Allocated | AS Name
-	// Example: 15169 | 8.8.8.8 | 8.8.8.0/24 | US | arin | 1992-12-01 | GOOGLE, US
-	return c.parseTeamCymruResponse(responseLine)
+// dialWhois connects to the whois server, giving up after the dialer's
+// timeout or when ctx is done, whichever comes first
+func (c *TeamCymruClient) dialWhois(ctx context.Context) (net.Conn, error) {
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.whoisAddr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to connect to Team Cymru: %w", ErrUpstreamUnavailable, err)
+	}
+	return conn, nil
 }
 
 // lookupTeamCymruBatch performs batch ASN lookup via Team Cymru
 func (c *TeamCymruClient) lookupTeamCymruBatch(ctx context.Context, ips []string) (map[string]*ASNInfo, error) {
-	results := make(map[string]*ASNInfo)
-
 	// Connect to Team Cymru whois server
-	var dialer net.Dialer
-	conn, err := dialer.DialContext(ctx, "tcp", "whois.cymru.com:43")
+	conn, err := c.dialWhois(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Team Cymru: %w", err)
+		return nil, err
 	}
 	defer conn.Close()
 
@@ -210,23 +344,31 @@ func (c *TeamCymruClient) lookupTeamCymruBatch(ctx context.Context, ips []string
 
 	// Send "begin" marker
 	if _, err := conn.Write([]byte("begin\n")); err != nil {
-		return nil, fmt.Errorf("failed to write begin marker: %w", err)
+		return nil, fmt.Errorf("%w: failed to write begin marker: %w", ErrUpstreamUnavailable, err)
 	}
 
 	// Send all IPs with -v flag for verbose
 	for _, ip := range ips {
 		if _, err := conn.Write([]byte(fmt.Sprintf(" -v %s\n", ip))); err != nil {
-			return nil, fmt.Errorf("failed to write IP %s: %w", ip, err)
+			return nil, fmt.Errorf("%w: failed to write IP %s: %w", ErrUpstreamUnavailable, ip, err)
 		}
 	}
 
 	// Send "end" marker
 	if _, err := conn.Write([]byte("end\n")); err != nil {
-		return nil, fmt.Errorf("failed to write end marker: %w", err)
+		return nil, fmt.Errorf("%w: failed to write end marker: %w", ErrUpstreamUnavailable, err)
 	}
 
 	// Read responses
-	scanner := bufio.NewScanner(conn)
+	return c.parseTeamCymruBatch(conn)
+}
+
+// parseTeamCymruBatch reads Team Cymru response lines and groups them by IP
+// An IP that appears on several lines (multi-origin prefix) gets all of its origin ASNs.
+func (c *TeamCymruClient) parseTeamCymruBatch(r io.Reader) (map[string]*ASNInfo, error) {
+	results := make(map[string]*ASNInfo)
+
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 		// Skip comment lines, headers, and empty lines
@@ -246,12 +388,12 @@ func (c *TeamCymruClient) lookupTeamCymruBatch(ctx context.Context, ips []string
 		fields := strings.Split(line, "|")
 		if len(fields) >= 2 {
 			ip := strings.TrimSpace(fields[1])
-			results[ip] = info
+			results[ip] = mergeASNInfo(results[ip], info)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("%w: failed to read response: %w", ErrUpstreamUnavailable, err)
 	}
 
 	return results, nil
@@ -260,58 +402,108 @@ func (c *TeamCymruClient) lookupTeamCymruBatch(ctx context.Context, ips []string
 // parseTeamCymruResponse parses a Team Cymru response line
 // Format: ASN | IP | BGP Prefix | CC | Registry | Allocated | AS Name
 // Example: 15169 | 8.8.8.8 | 8.8.8.0/24 | US | arin | 1992-12-01 | GOOGLE, US
+// Multi-origin prefixes list several space-separated ASNs in the first field; the AS name
+// belongs to the first.
 func (c *TeamCymruClient) parseTeamCymruResponse(line string) (*ASNInfo, error) {
 	fields := strings.Split(line, "|")
 	if len(fields) < 7 {
 		return nil, fmt.Errorf("invalid response format: %s", line)
 	}
 
-	// Parse ASN
+	// Parse ASNs
 	asnStr := strings.TrimSpace(fields[0])
-	asn, err := strconv.Atoi(asnStr)
-	if err != nil {
+	var origins []int
+	for _, field := range strings.Fields(asnStr) {
+		asn, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ASN number: %s", asnStr)
+		}
+		if !containsASN(origins, asn) {
+			origins = append(origins, asn)
+		}
+	}
+	if len(origins) == 0 {
 		return nil, fmt.Errorf("invalid ASN number: %s", asnStr)
 	}
 
-	// Extract country code
+	// Extract BGP prefix and country code
+	prefix := strings.TrimSpace(fields[2])
 	country := strings.TrimSpace(fields[3])
 
 	// Extract AS name (organization)
 	org := strings.TrimSpace(fields[6])
 
-	return &ASNInfo{
-		Number:  asn,
+	info := &ASNInfo{
+		Number:  origins[0],
 		Org:     org,
 		Country: country,
-	}, nil
+		Prefix:  prefix,
+	}
+	if len(origins) > 1 {
+		info.Origins = origins
+	}
+
+	return info, nil
 }
 
 // checkCache checks if an IP is in the cache and not expired
+// A hit marks the IP as most recently used, so it takes the write lock.
 func (c *TeamCymruClient) checkCache(ip string) *ASNInfo {
-	c.cacheMu.RLock()
-	defer c.cacheMu.RUnlock()
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
 
 	entry, exists := c.cache[ip]
 	if !exists {
+		c.counters.misses.Add(1)
 		return nil
 	}
 
 	// Check if entry is expired
 	if time.Since(entry.timestamp) > c.cacheTTL {
+		c.counters.misses.Add(1)
 		return nil
 	}
 
+	c.cacheLRU.MoveToFront(entry.elem)
+	c.counters.hits.Add(1)
 	return entry.info
 }
 
-// setCache stores an ASN info in the cache
+// setCache stores an ASN info in the cache, evicting the least recently used
+// IP when a new one would exceed the size cap
 func (c *TeamCymruClient) setCache(ip string, info *ASNInfo) {
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
+	if entry, exists := c.cache[ip]; exists {
+		entry.info = info
+		entry.timestamp = time.Now()
+		c.cacheLRU.MoveToFront(entry.elem)
+		return
+	}
+
+	for len(c.cache) >= c.cacheMax {
+		oldest := c.cacheLRU.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeCacheEntry(oldest.Value.(string))
+		c.counters.evictions.Add(1)
+	}
+
 	c.cache[ip] = &cacheEntry{
 		info:      info,
 		timestamp: time.Now(),
+		elem:      c.cacheLRU.PushFront(ip),
+	}
+}
+
+// removeCacheEntry deletes an IP from the cache and its LRU list
+// The caller must hold cacheMu.
+func (c *TeamCymruClient) removeCacheEntry(ip string) {
+	if entry, exists := c.cache[ip]; exists {
+		c.cacheLRU.Remove(entry.elem)
+		delete(c.cache, ip)
 	}
 }
 
@@ -332,6 +524,26 @@ func (c *TeamCymruClient) GetCacheStats() (size int, oldestEntry time.Time) {
 	return size, oldestEntry
 }
 
+// Stats returns the cache's size, oldest entry, and lookup counters
+func (c *TeamCymruClient) Stats() CacheStats {
+	c.cacheMu.RLock()
+	stats := CacheStats{Size: len(c.cache)}
+	for _, entry := range c.cache {
+		if stats.OldestEntry.IsZero() || entry.timestamp.Before(stats.OldestEntry) {
+			stats.OldestEntry = entry.timestamp
+		}
+	}
+	c.cacheMu.RUnlock()
+
+	c.counters.snapshot(&stats)
+	return stats
+}
+
+// BreakerStats returns the state of the circuit breaker around whois lookups
+func (c *TeamCymruClient) BreakerStats() BreakerStats {
+	return c.breaker.Stats()
+}
+
 // ClearExpiredCache removes expired entries from the cache
 func (c *TeamCymruClient) ClearExpiredCache() int {
 	c.cacheMu.Lock()
@@ -342,10 +554,11 @@ func (c *TeamCymruClient) ClearExpiredCache() int {
 
 	for ip, entry := range c.cache {
 		if now.Sub(entry.timestamp) > c.cacheTTL {
-			delete(c.cache, ip)
+			c.removeCacheEntry(ip)
 			removed++
 		}
 	}
+	c.counters.evictions.Add(uint64(removed))
 
 	return removed
 }
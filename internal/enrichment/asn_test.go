@@ -1,7 +1,13 @@
 package enrichment
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -59,6 +65,7 @@ func TestTeamCymruClient_parseTeamCymruResponse(t *testing.T) {
 				Number:  15169,
 				Org:     "GOOGLE, US",
 				Country: "US",
+				Prefix:  "8.8.8.0/24",
 			},
 			wantErr: false,
 		},
@@ -69,6 +76,7 @@ func TestTeamCymruClient_parseTeamCymruResponse(t *testing.T) {
 				Number:  13335,
 				Org:     "CLOUDFLARENET, US",
 				Country: "US",
+				Prefix:  "1.1.1.0/24",
 			},
 			wantErr: false,
 		},
@@ -98,10 +106,56 @@ func TestTeamCymruClient_parseTeamCymruResponse(t *testing.T) {
 			assert.Equal(t, tt.want.Number, got.Number)
 			assert.Equal(t, tt.want.Org, got.Org)
 			assert.Equal(t, tt.want.Country, got.Country)
+			assert.Equal(t, tt.want.Prefix, got.Prefix)
 		})
 	}
 }
 
+func TestTeamCymruClient_parseTeamCymruResponse_MultiOrigin(t *testing.T) {
+	client := NewTeamCymruClient(100, 24*time.Hour)
+
+	got, err := client.parseTeamCymruResponse("23028 3356 | 216.90.108.31 | 216.90.108.0/24 | US | arin | 1998-09-25 | TEAM-CYMRU, US")
+	require.NoError(t, err)
+
+	assert.Equal(t, 23028, got.Number, "primary origin is the first ASN")
+	assert.Equal(t, "TEAM-CYMRU, US", got.Org)
+	assert.Equal(t, []int{23028, 3356}, got.Origins)
+	assert.Equal(t, []int{23028, 3356}, got.OriginASNs())
+
+	single, err := client.parseTeamCymruResponse("15169 | 8.8.8.8 | 8.8.8.0/24 | US | arin | 1992-12-01 | GOOGLE, US")
+	require.NoError(t, err)
+	assert.Nil(t, single.Origins, "single-origin answers leave Origins unset")
+	assert.Equal(t, []int{15169}, single.OriginASNs())
+}
+
+func TestTeamCymruClient_parseTeamCymruBatch(t *testing.T) {
+	client := NewTeamCymruClient(100, 24*time.Hour)
+
+	response := strings.Join([]string{
+		"Bulk mode; whois.cymru.com [2026-10-16 12:00:00 +0000]",
+		"AS      | IP               | BGP Prefix          | CC | Registry | Allocated  | AS Name",
+		"15169   | 8.8.8.8          | 8.8.8.0/24          | US | arin     | 1992-12-01 | GOOGLE, US",
+		"64500   | 192.0.2.10       | 192.0.2.0/24        | US | arin     | 2001-01-01 | EXAMPLE-A, US",
+		"64501   | 192.0.2.10       | 192.0.2.0/24        | US | arin     | 2001-01-01 | EXAMPLE-B, US",
+		"64500 64502 | 192.0.2.10   | 192.0.2.0/24        | US | arin     | 2001-01-01 | EXAMPLE-A, US",
+		"garbage line",
+		"",
+	}, "\n")
+
+	results, err := client.parseTeamCymruBatch(strings.NewReader(response))
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	google := results["8.8.8.8"]
+	assert.Equal(t, 15169, google.Number)
+	assert.Equal(t, []int{15169}, google.OriginASNs())
+
+	multi := results["192.0.2.10"]
+	assert.Equal(t, 64500, multi.Number, "first line stays primary")
+	assert.Equal(t, "EXAMPLE-A, US", multi.Org)
+	assert.Equal(t, []int{64500, 64501, 64502}, multi.Origins, "every origin appears once, in order")
+}
+
 func TestTeamCymruClient_Cache(t *testing.T) {
 	client := NewTeamCymruClient(100, 100*time.Millisecond)
 
@@ -309,3 +363,257 @@ func BenchmarkTeamCymruClient_checkCache(b *testing.B) {
 		_ = client.checkCache("8.8.8.8")
 	}
 }
+
+func TestTeamCymruClient_Stats(t *testing.T) {
+	client := NewTeamCymruClient(100, 50*time.Millisecond)
+
+	stats := client.Stats()
+	assert.Zero(t, stats.Size)
+	assert.True(t, stats.OldestEntry.IsZero())
+
+	client.setCache("8.8.8.8", &ASNInfo{Number: 15169, Org: "GOOGLE", Country: "US"})
+
+	// Two hits and one miss on a missing IP
+	assert.NotNil(t, client.checkCache("8.8.8.8"))
+	assert.NotNil(t, client.checkCache("8.8.8.8"))
+	assert.Nil(t, client.checkCache("1.1.1.1"))
+
+	stats = client.Stats()
+	assert.Equal(t, 1, stats.Size)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Zero(t, stats.Evictions)
+	assert.False(t, stats.OldestEntry.IsZero())
+	assert.InDelta(t, 2.0/3.0, stats.HitRatio(), 1e-9)
+
+	// An expired entry is a miss, and clearing it counts as an eviction
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, client.checkCache("8.8.8.8"))
+	assert.Equal(t, 1, client.ClearExpiredCache())
+
+	stats = client.Stats()
+	assert.Zero(t, stats.Size)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+}
+
+func TestTeamCymruClient_CacheLRUEviction(t *testing.T) {
+	client := NewTeamCymruClientWithConfig(TeamCymruConfig{CacheMaxEntries: 2})
+
+	client.setCache("8.8.8.8", &ASNInfo{Number: 15169})
+	client.setCache("1.1.1.1", &ASNInfo{Number: 13335})
+	client.setCache("9.9.9.9", &ASNInfo{Number: 19281})
+
+	// The first IP inserted is the least recently used
+	assert.Nil(t, client.checkCache("8.8.8.8"))
+	assert.NotNil(t, client.checkCache("1.1.1.1"))
+	assert.NotNil(t, client.checkCache("9.9.9.9"))
+
+	size, _ := client.GetCacheStats()
+	assert.Equal(t, 2, size)
+	assert.Equal(t, uint64(1), client.Stats().Evictions)
+}
+
+func TestTeamCymruClient_CacheKeepsFrequentlyAccessedIPs(t *testing.T) {
+	client := NewTeamCymruClientWithConfig(TeamCymruConfig{CacheMaxEntries: 3})
+
+	client.setCache("8.8.8.8", &ASNInfo{Number: 15169})
+	for i := 1; i <= 10; i++ {
+		ip := "10.0.0." + strconv.Itoa(i)
+		client.setCache(ip, &ASNInfo{Number: 64512})
+
+		// Reading the hot IP between inserts keeps it out of the eviction path
+		require.NotNil(t, client.checkCache("8.8.8.8"), "hot IP evicted after inserting %s", ip)
+	}
+
+	size, _ := client.GetCacheStats()
+	assert.Equal(t, 3, size)
+	assert.NotNil(t, client.checkCache("10.0.0.10"))
+	assert.Nil(t, client.checkCache("10.0.0.1"))
+
+	// Refreshing an existing IP does not evict anything
+	evictions := client.Stats().Evictions
+	client.setCache("10.0.0.10", &ASNInfo{Number: 64513})
+	assert.Equal(t, evictions, client.Stats().Evictions)
+}
+
+func TestTeamCymruClient_ClearExpiredCacheWithLRU(t *testing.T) {
+	client := NewTeamCymruClientWithConfig(TeamCymruConfig{CacheTTL: 50 * time.Millisecond, CacheMaxEntries: 2})
+
+	client.setCache("8.8.8.8", &ASNInfo{Number: 15169})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, client.ClearExpiredCache())
+
+	// Expired entries leave the LRU list too, so the cap still holds two fresh IPs
+	client.setCache("1.1.1.1", &ASNInfo{Number: 13335})
+	client.setCache("9.9.9.9", &ASNInfo{Number: 19281})
+	assert.NotNil(t, client.checkCache("1.1.1.1"))
+	assert.NotNil(t, client.checkCache("9.9.9.9"))
+}
+
+func TestTeamCymruClient_DialTimeout(t *testing.T) {
+	client := NewTeamCymruClientWithConfig(TeamCymruConfig{DialTimeout: 200 * time.Millisecond})
+	assert.Equal(t, 200*time.Millisecond, client.dialer.Timeout)
+	assert.Equal(t, whoisKeepAlive, client.dialer.KeepAlive)
+
+	// TEST-NET-1 is never routed, so SYNs go unanswered (or fail fast without a route)
+	client.whoisAddr = "192.0.2.1:43"
+
+	for name, lookup := range map[string]func() error{
+		"single": func() error {
+			_, err := client.lookupTeamCymru(context.Background(), "8.8.8.8")
+			return err
+		},
+		"batch": func() error {
+			_, err := client.lookupTeamCymruBatch(context.Background(), []string{"8.8.8.8"})
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			err := lookup()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to connect to Team Cymru")
+			assert.Less(t, time.Since(start), 2*time.Second, "dial was not bounded by the connect timeout")
+		})
+	}
+}
+
+func TestNewTeamCymruClient_DefaultDialTimeout(t *testing.T) {
+	client := NewTeamCymruClient(0, 0)
+	assert.Equal(t, DefaultWhoisDialTimeout, client.dialer.Timeout)
+	assert.Equal(t, teamCymruWhoisAddr, client.whoisAddr)
+}
+
+// newTestWhoisServer answers every whois connection with a Team Cymru line for each
+// queried IP and counts the connections. A bulk query ends at "end"; a single query
+// is answered and the connection closed.
+func newTestWhoisServer(t *testing.T, asn int) (string, *int32) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	var connections int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&connections, 1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				bulk := false
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					switch line := strings.TrimSpace(scanner.Text()); line {
+					case "begin":
+						bulk = true
+					case "end":
+						return
+					default:
+						ip := strings.TrimSpace(strings.TrimPrefix(line, "-v"))
+						fmt.Fprintf(conn, "%d | %s | %s/32 | US | arin | 2000-01-01 | TEST, US\n", asn, ip, ip)
+						if !bulk {
+							return
+						}
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String(), &connections
+}
+
+func TestTeamCymruClient_ForceRefresh(t *testing.T) {
+	addr, connections := newTestWhoisServer(t, 64500)
+	client := NewTeamCymruClient(1000, time.Hour)
+	client.whoisAddr = addr
+
+	// Warm the cache with stale data
+	client.setCache("192.0.2.10", &ASNInfo{Number: 64499})
+
+	info, err := client.LookupASN(context.Background(), "192.0.2.10")
+	require.NoError(t, err)
+	assert.Equal(t, 64499, info.Number, "unforced lookup should be served from cache")
+	assert.Equal(t, int32(0), atomic.LoadInt32(connections))
+
+	t.Run("single lookup", func(t *testing.T) {
+		info, err := client.LookupASNWithOptions(context.Background(), "192.0.2.10", ASNLookupOptions{ForceRefresh: true})
+		require.NoError(t, err)
+		assert.Equal(t, 64500, info.Number)
+		assert.Equal(t, int32(1), atomic.LoadInt32(connections))
+
+		// The fresh result replaces the cached one
+		assert.Equal(t, 64500, client.checkCache("192.0.2.10").Number)
+	})
+
+	t.Run("batch lookup", func(t *testing.T) {
+		client.setCache("192.0.2.10", &ASNInfo{Number: 64499})
+		client.setCache("192.0.2.11", &ASNInfo{Number: 64499})
+		before := atomic.LoadInt32(connections)
+
+		results, err := client.LookupBatchWithOptions(context.Background(), []string{"192.0.2.10", "192.0.2.11"}, ASNLookupOptions{ForceRefresh: true})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, 64500, results["192.0.2.11"].Number)
+		assert.Equal(t, before+1, atomic.LoadInt32(connections))
+		assert.Equal(t, 64500, client.checkCache("192.0.2.11").Number)
+	})
+}
+
+func TestTeamCymruClient_ErrorTypes(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		client := NewTeamCymruClientWithConfig(TeamCymruConfig{DialTimeout: 200 * time.Millisecond})
+		client.whoisAddr = "192.0.2.1:43"
+
+		_, err := client.LookupASN(context.Background(), "8.8.8.8")
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+		assert.True(t, IsRetryable(err))
+
+		_, err = client.LookupBatch(context.Background(), []string{"8.8.8.8"})
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		// The server hangs up without answering
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+
+		client := NewTeamCymruClient(0, 0)
+		client.whoisAddr = listener.Addr().String()
+
+		_, err = client.LookupASN(context.Background(), "8.8.8.8")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.False(t, IsRetryable(err))
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		client := NewTeamCymruClient(1, 0)
+		client.rateLimit.tokens = 0
+		client.rateLimit.lastRefill = time.Now()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.LookupASN(ctx, "8.8.8.8")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = client.LookupBatch(ctx, []string{"8.8.8.8"})
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+}
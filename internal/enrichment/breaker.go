@@ -0,0 +1,157 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults for the upstream circuit breakers
+const (
+	// DefaultBreakerFailureThreshold is how many consecutive upstream failures open the breaker
+	DefaultBreakerFailureThreshold = 5
+	// DefaultBreakerOpenDuration is how long an open breaker fails fast before probing
+	DefaultBreakerOpenDuration = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without calling the upstream while its breaker is open
+// It wraps ErrUpstreamUnavailable, so callers treat it as retryable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrUpstreamUnavailable)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails every call fast
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe through to test for recovery
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerConfig configures a CircuitBreaker
+// Zero fields use the defaults above.
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the breaker
+	OpenDuration     time.Duration // Time spent open before a probe is let through
+}
+
+// BreakerStats reports a circuit breaker's state
+type BreakerStats struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	Opens               uint64       `json:"opens"`               // times the breaker has opened
+	Rejected            uint64       `json:"rejected"`            // calls failed fast while open
+	OpenedAt            time.Time    `json:"opened_at,omitempty"` // zero unless open or half-open
+}
+
+// CircuitBreaker stops calling an upstream after repeated failures
+// Once FailureThreshold consecutive calls fail with ErrUpstreamUnavailable the
+// breaker opens and calls fail fast with ErrCircuitOpen. After OpenDuration one
+// probe call is let through: success closes the breaker, failure reopens it.
+// Rate limiting and cancellation say nothing about the upstream and leave the
+// breaker as it is; other errors, such as ErrNotFound, mean the upstream
+// answered and count as successes.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	opens    uint64
+	rejected uint64
+
+	// now returns the current time; tests replace it to skip the open duration
+	now func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = DefaultBreakerOpenDuration
+	}
+	return &CircuitBreaker{
+		config: config,
+		state:  BreakerClosed,
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether a call may go to the upstream, returning ErrCircuitOpen
+// if not. Every allowed call must be followed by Record with its result.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.config.OpenDuration {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		// Only one probe at a time
+		if b.probing {
+			b.rejected++
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record updates the breaker with the result of an allowed call
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probe := b.state == BreakerHalfOpen
+	b.probing = false
+
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited):
+		return
+	case errors.Is(err, ErrUpstreamUnavailable):
+		b.failures++
+		if probe || b.failures >= b.config.FailureThreshold {
+			b.open()
+		}
+	default:
+		b.failures = 0
+		b.state = BreakerClosed
+		b.openedAt = time.Time{}
+	}
+}
+
+// open trips the breaker; callers hold b.mu
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.opens++
+}
+
+// Stats returns the breaker's current state and counters
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BreakerStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Opens:               b.opens,
+		Rejected:            b.rejected,
+		OpenedAt:            b.openedAt,
+	}
+}
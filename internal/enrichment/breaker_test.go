@@ -0,0 +1,192 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+var errTestUpstreamDown = fmt.Errorf("%w: connection refused", ErrUpstreamUnavailable)
+
+// newTestBreaker returns a breaker whose clock is advanced by the returned func
+func newTestBreaker(threshold int, openFor time.Duration) (*CircuitBreaker, func(time.Duration)) {
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: threshold, OpenDuration: openFor})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestNewCircuitBreaker_Defaults(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerConfig{})
+	assert.Equal(t, DefaultBreakerFailureThreshold, breaker.config.FailureThreshold)
+	assert.Equal(t, DefaultBreakerOpenDuration, breaker.config.OpenDuration)
+	assert.Equal(t, BreakerClosed, breaker.Stats().State)
+}
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	breaker, _ := newTestBreaker(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, breaker.Allow())
+		breaker.Record(errTestUpstreamDown)
+	}
+
+	stats := breaker.Stats()
+	assert.Equal(t, BreakerOpen, stats.State)
+	assert.Equal(t, uint64(1), stats.Opens)
+
+	err := breaker.Allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.Equal(t, uint64(1), breaker.Stats().Rejected)
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	breaker, _ := newTestBreaker(3, time.Minute)
+
+	for _, err := range []error{errTestUpstreamDown, errTestUpstreamDown, nil, errTestUpstreamDown, errTestUpstreamDown} {
+		require.NoError(t, breaker.Allow())
+		breaker.Record(err)
+	}
+	assert.Equal(t, BreakerClosed, breaker.Stats().State)
+	assert.Equal(t, 2, breaker.Stats().ConsecutiveFailures)
+
+	// The upstream answered, so not found counts as a success
+	breaker.Record(fmt.Errorf("%w: no ASN data", ErrNotFound))
+	assert.Zero(t, breaker.Stats().ConsecutiveFailures)
+}
+
+func TestCircuitBreaker_IgnoresRateLimitAndCancel(t *testing.T) {
+	breaker, _ := newTestBreaker(2, time.Minute)
+
+	breaker.Record(errTestUpstreamDown)
+	breaker.Record(fmt.Errorf("%w: rate limit wait failed", ErrRateLimited))
+	breaker.Record(fmt.Errorf("%w: %w", ErrUpstreamUnavailable, context.Canceled))
+
+	stats := breaker.Stats()
+	assert.Equal(t, BreakerClosed, stats.State)
+	assert.Equal(t, 1, stats.ConsecutiveFailures)
+}
+
+func TestCircuitBreaker_ProbeClosesBreaker(t *testing.T) {
+	breaker, advance := newTestBreaker(1, time.Minute)
+
+	require.NoError(t, breaker.Allow())
+	breaker.Record(errTestUpstreamDown)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	advance(time.Minute)
+
+	// One probe goes through; other calls still fail fast while it runs
+	require.NoError(t, breaker.Allow())
+	assert.Equal(t, BreakerHalfOpen, breaker.Stats().State)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	breaker.Record(nil)
+	stats := breaker.Stats()
+	assert.Equal(t, BreakerClosed, stats.State)
+	assert.True(t, stats.OpenedAt.IsZero())
+	assert.NoError(t, breaker.Allow())
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	breaker, advance := newTestBreaker(1, time.Minute)
+
+	require.NoError(t, breaker.Allow())
+	breaker.Record(errTestUpstreamDown)
+	advance(time.Minute)
+
+	require.NoError(t, breaker.Allow())
+	breaker.Record(errTestUpstreamDown)
+
+	stats := breaker.Stats()
+	assert.Equal(t, BreakerOpen, stats.State)
+	assert.Equal(t, uint64(2), stats.Opens)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	// A cancelled probe leaves the breaker half-open for the next caller
+	advance(time.Minute)
+	require.NoError(t, breaker.Allow())
+	breaker.Record(context.Canceled)
+	assert.Equal(t, BreakerHalfOpen, breaker.Stats().State)
+	assert.NoError(t, breaker.Allow())
+}
+
+func TestNVDClient_CircuitBreaker(t *testing.T) {
+	var requests, down int32 = 0, 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"totalResults":0,"vulnerabilities":[]}`)
+	}))
+	defer server.Close()
+
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+	breaker, advance := newTestBreaker(2, time.Minute)
+	client.breaker = breaker
+
+	cpe := "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*"
+	for i := 0; i < 2; i++ {
+		_, err := client.QueryByCPE(context.Background(), cpe)
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	}
+	assert.Equal(t, BreakerOpen, client.BreakerStats().State)
+
+	// Open: fail fast without calling NVD
+	_, err := client.QueryByCPE(context.Background(), cpe)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// NVD recovers and the probe closes the breaker
+	atomic.StoreInt32(&down, 0)
+	advance(time.Minute)
+	_, err = client.QueryByCPE(context.Background(), cpe)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Equal(t, BreakerClosed, client.BreakerStats().State)
+}
+
+func TestTeamCymruClient_CircuitBreaker(t *testing.T) {
+	// Nothing listens on a closed listener's port, so dials are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := listener.Addr().String()
+	listener.Close()
+
+	client := NewTeamCymruClient(0, 0)
+	breaker, advance := newTestBreaker(2, time.Minute)
+	client.breaker = breaker
+	client.whoisAddr = closedAddr
+
+	for i := 0; i < 2; i++ {
+		_, err := client.LookupASN(context.Background(), "8.8.8.8")
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	assert.Equal(t, BreakerOpen, client.BreakerStats().State)
+
+	_, err = client.LookupBatch(context.Background(), []string{"8.8.8.8", "1.1.1.1"})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// Team Cymru recovers and the probe closes the breaker
+	addr, _ := newTestWhoisServer(t, 15169)
+	client.whoisAddr = addr
+	advance(time.Minute)
+
+	info, err := client.LookupASN(context.Background(), "8.8.8.8")
+	require.NoError(t, err)
+	assert.Equal(t, 15169, info.Number)
+	assert.Equal(t, BreakerClosed, client.BreakerStats().State)
+}
@@ -0,0 +1,39 @@
+package enrichment
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats reports how effective an enrichment cache is
+// Hits and misses count lookups; an expired entry counts as a miss.
+type CacheStats struct {
+	Size        int       `json:"size"`
+	Hits        uint64    `json:"hits"`
+	Misses      uint64    `json:"misses"`
+	Evictions   uint64    `json:"evictions"`              // expired or cleared entries removed
+	OldestEntry time.Time `json:"oldest_entry,omitempty"` // zero when the cache is empty
+}
+
+// HitRatio returns the fraction of lookups served from the cache, or 0 before any lookup
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// cacheCounters tracks lookups and evictions without holding the cache lock
+type cacheCounters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// snapshot fills in the counter fields of stats
+func (c *cacheCounters) snapshot(stats *CacheStats) {
+	stats.Hits = c.hits.Load()
+	stats.Misses = c.misses.Load()
+	stats.Evictions = c.evictions.Load()
+}
@@ -13,15 +13,39 @@ type CPEIdentifier struct {
 	Product string `json:"product"`
 	Version string `json:"version"`
 	CPE     string `json:"cpe"` // Full CPE 2.3 string
+
+	// FromBanner is set when product and version were parsed from the raw banner
+	// or HTTP body rather than taken from the service fingerprint
+	FromBanner bool `json:"from_banner,omitempty"`
+}
+
+// Match confidence by CPE specificity
+const (
+	ConfidenceVersioned = 1.0  // CPE pins the exact fingerprinted version
+	ConfidenceBanner    = 0.75 // CPE pins a version parsed from the service banner
+	ConfidenceWildcard  = 0.5  // CPE matches any version of the product
+)
+
+// CPEMatchConfidence returns how much a CVE matched through this CPE can be trusted
+func CPEMatchConfidence(cpe CPEIdentifier) float64 {
+	switch {
+	case cpe.Version == "" || cpe.Version == "*":
+		return ConfidenceWildcard
+	case cpe.FromBanner:
+		return ConfidenceBanner
+	default:
+		return ConfidenceVersioned
+	}
 }
 
 // ServiceInfo represents service data for CPE generation
 type ServiceInfo struct {
-	ID       string `json:"id"`        // Service record ID
-	Name     string `json:"name"`      // Service name (http, ssh, etc.)
-	Product  string `json:"product"`   // Product name (nginx, openssh, etc.)
-	Version  string `json:"version"`   // Version string
-	Banner   string `json:"banner"`    // Raw banner text
+	ID       string `json:"id"`                  // Service record ID
+	Name     string `json:"name"`                // Service name (http, ssh, etc.)
+	Product  string `json:"product"`             // Product name (nginx, openssh, etc.)
+	Version  string `json:"version"`             // Version string
+	Banner   string `json:"banner"`              // Raw banner text
+	HTTPBody string `json:"http_body,omitempty"` // Start of the HTTP response body, for web application fingerprints
 }
 
 // BannerPattern represents a regex pattern for parsing service banners
@@ -165,6 +189,43 @@ var bannerPatterns = []BannerPattern{
 	},
 }
 
+// MaxHTTPBodyScanBytes bounds how much of an HTTP body is searched for fingerprints
+// Generator tags and version footers sit near the top or bottom of small pages;
+// scanning only the start keeps a huge body from stalling CPE generation.
+const MaxHTTPBodyScanBytes = 64 * 1024
+
+// Web application fingerprints found in HTML bodies; each captures the version
+var bodyPatterns = []BannerPattern{
+	// <meta name="generator"> tags
+	{
+		Regex:   regexp.MustCompile(`(?i)<meta\s+name=["']generator["']\s+content=["']WordPress\s+([\d.]+)`),
+		Vendor:  "wordpress",
+		Product: "wordpress",
+	},
+	{
+		Regex:   regexp.MustCompile(`(?i)<meta\s+name=["']generator["']\s+content=["']Drupal\s+([\d.]+)`),
+		Vendor:  "drupal",
+		Product: "drupal",
+	},
+	{
+		Regex:   regexp.MustCompile(`(?i)<meta\s+name=["']generator["']\s+content=["']MediaWiki\s+([\d.]+)`),
+		Vendor:  "mediawiki",
+		Product: "mediawiki",
+	},
+
+	// Atlassian page footers
+	{
+		Regex:   regexp.MustCompile(`(?s)Atlassian Jira.{0,200}?\(v([\d.]+)`),
+		Vendor:  "atlassian",
+		Product: "jira",
+	},
+	{
+		Regex:   regexp.MustCompile(`Atlassian Confluence</a>\s*<span\s+id=["']footer-build-information["']>([\d.]+)`),
+		Vendor:  "atlassian",
+		Product: "confluence",
+	},
+}
+
 // ProductVendorMap provides vendor mapping for products when not in banner
 var ProductVendorMap = map[string]string{
 	"nginx":      "nginx",
@@ -207,17 +268,47 @@ func ParseBanner(banner string) (product string, version string, vendor string)
 	return "", "", ""
 }
 
+// ParseHTTPBody returns a CPE for each web application fingerprinted in an HTTP
+// body. Only the first MaxHTTPBodyScanBytes are searched.
+func ParseHTTPBody(body string) []CPEIdentifier {
+	if len(body) > MaxHTTPBodyScanBytes {
+		body = body[:MaxHTTPBodyScanBytes]
+	}
+
+	var cpes []CPEIdentifier
+	for _, pattern := range bodyPatterns {
+		matches := pattern.Regex.FindStringSubmatch(body)
+		if len(matches) < 2 {
+			continue
+		}
+		version := strings.TrimRight(matches[1], ".")
+		cpe := formatCPE23(pattern.Vendor, pattern.Product, version)
+		if containsCPE(cpes, cpe) {
+			continue
+		}
+		cpes = append(cpes, CPEIdentifier{
+			Vendor:     pattern.Vendor,
+			Product:    pattern.Product,
+			Version:    version,
+			CPE:        cpe,
+			FromBanner: true,
+		})
+	}
+
+	return cpes
+}
+
 // GenerateCPE creates a CPE 2.3 identifier from service information
 func GenerateCPE(service ServiceInfo) []CPEIdentifier {
 	var cpes []CPEIdentifier
 
 	// Strategy 1: Use existing product/version from service record
 	if service.Product != "" && service.Version != "" {
-		vendor := normalizeVendor(service.Product)
-		cpe := formatCPE23(vendor, service.Product, service.Version)
+		vendor, product := resolveVendorProduct(service.Product)
+		cpe := formatCPE23(vendor, product, service.Version)
 		cpes = append(cpes, CPEIdentifier{
 			Vendor:  vendor,
-			Product: service.Product,
+			Product: product,
 			Version: service.Version,
 			CPE:     cpe,
 		})
@@ -231,23 +322,34 @@ func GenerateCPE(service ServiceInfo) []CPEIdentifier {
 			// Only add if different from strategy 1
 			if !containsCPE(cpes, cpe) {
 				cpes = append(cpes, CPEIdentifier{
-					Vendor:  vendor,
-					Product: product,
-					Version: version,
-					CPE:     cpe,
+					Vendor:     vendor,
+					Product:    product,
+					Version:    version,
+					CPE:        cpe,
+					FromBanner: true,
 				})
 			}
 		}
 	}
 
+	// Strategy 2b: Fingerprint web applications in the HTTP body, which are
+	// parsed like banners but identify the application rather than the server
+	if service.HTTPBody != "" {
+		for _, cpe := range ParseHTTPBody(service.HTTPBody) {
+			if !containsCPE(cpes, cpe.CPE) {
+				cpes = append(cpes, cpe)
+			}
+		}
+	}
+
 	// Strategy 3: Generate fuzzy CPE without version (for broader matching)
 	if service.Product != "" && service.Version == "" {
-		vendor := normalizeVendor(service.Product)
-		cpe := formatCPE23(vendor, service.Product, "*")
+		vendor, product := resolveVendorProduct(service.Product)
+		cpe := formatCPE23(vendor, product, "*")
 		if !containsCPE(cpes, cpe) {
 			cpes = append(cpes, CPEIdentifier{
 				Vendor:  vendor,
-				Product: service.Product,
+				Product: product,
 				Version: "*",
 				CPE:     cpe,
 			})
@@ -271,13 +373,30 @@ func GenerateCPEBatch(services []ServiceInfo) map[string][]CPEIdentifier {
 	return result
 }
 
+// resolveVendorProduct determines the vendor and product names to use in a CPE
+// Configured vendor overrides win, then the CPE dictionary if one is installed,
+// then the built-in vendor guess with the product name unchanged.
+func resolveVendorProduct(product string) (string, string) {
+	if vendor, exists := vendorOverride(strings.ToLower(strings.TrimSpace(product))); exists {
+		return vendor, product
+	}
+
+	if dict := currentCPEDictionary(); dict != nil {
+		if match, ok := dict.Resolve(product); ok {
+			return match.Vendor, match.Product
+		}
+	}
+
+	return normalizeVendor(product), product
+}
+
 // normalizeVendor attempts to determine the vendor from the product name
 func normalizeVendor(product string) string {
 	// Normalize product name (lowercase, remove special chars)
 	normalized := strings.ToLower(strings.TrimSpace(product))
 
-	// Check product-to-vendor map
-	if vendor, exists := ProductVendorMap[normalized]; exists {
+	// Check loaded overrides, then the built-in product-to-vendor map
+	if vendor, exists := lookupVendor(normalized); exists {
 		return vendor
 	}
 
@@ -297,6 +416,67 @@ func formatCPE23(vendor, product, version string) string {
 	return fmt.Sprintf("cpe:2.3:a:%s:%s:%s:*:*:*:*:*:*:*", vendor, product, version)
 }
 
+// cpe23Components is the number of colon-separated components in a CPE 2.3 formatted string
+const cpe23Components = 13
+
+// ValidCPE23 reports whether s is a well-formed CPE 2.3 formatted string worth querying NVD with
+// It checks the 13-component structure, the part (a, o or h), and that no component is empty.
+// Vendor and product must be concrete values, since a wildcard would match every product.
+func ValidCPE23(s string) bool {
+	components := splitCPE23(s)
+	if len(components) != cpe23Components {
+		return false
+	}
+
+	if components[0] != "cpe" || components[1] != "2.3" {
+		return false
+	}
+
+	switch components[2] {
+	case "a", "o", "h":
+	default:
+		return false
+	}
+
+	for _, component := range components[3:] {
+		if component == "" {
+			return false
+		}
+	}
+
+	vendor, product := components[3], components[4]
+	if vendor == "*" || vendor == "-" || product == "*" || product == "-" {
+		return false
+	}
+
+	return true
+}
+
+// splitCPE23 splits a CPE 2.3 string on colons, keeping backslash-escaped colons inside a component
+func splitCPE23(s string) []string {
+	components := []string{}
+	var current strings.Builder
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			current.WriteRune(r)
+			escaped = true
+		case r == ':':
+			components = append(components, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	return append(components, current.String())
+}
+
 // normalizeCPEComponent normalizes a CPE component according to CPE 2.3 spec
 func normalizeCPEComponent(s string) string {
 	if s == "" || s == "*" {
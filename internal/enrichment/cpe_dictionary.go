@@ -0,0 +1,221 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MinProductSimilarity is the lowest normalized similarity at which a product name snaps
+// to a dictionary product
+const MinProductSimilarity = 0.8
+
+// CPEDictionary holds canonical vendor:product pairs from the NVD CPE dictionary
+// GenerateCPE consults it, once installed with UseCPEDictionary, to replace guessed
+// vendor and product names with the ones NVD uses.
+type CPEDictionary struct {
+	products  []dictionaryProduct            // Distinct products, in load order
+	byProduct map[string][]dictionaryProduct // Exact lookup by normalized product
+}
+
+// dictionaryProduct is a canonical vendor:product pair
+type dictionaryProduct struct {
+	Vendor  string
+	Product string
+	key     string // Product with separators removed, for similarity scoring
+}
+
+// activeCPEDictionary is the dictionary GenerateCPE uses; nil disables dictionary matching
+var (
+	activeCPEDictionaryMu sync.RWMutex
+	activeCPEDictionary   *CPEDictionary
+)
+
+// UseCPEDictionary installs dict for CPE generation; pass nil to disable dictionary matching
+func UseCPEDictionary(dict *CPEDictionary) {
+	activeCPEDictionaryMu.Lock()
+	defer activeCPEDictionaryMu.Unlock()
+	activeCPEDictionary = dict
+}
+
+// currentCPEDictionary returns the installed dictionary, or nil
+func currentCPEDictionary() *CPEDictionary {
+	activeCPEDictionaryMu.RLock()
+	defer activeCPEDictionaryMu.RUnlock()
+	return activeCPEDictionary
+}
+
+// NewCPEDictionary builds a dictionary from CPE 2.3 names
+// Invalid names are skipped; versions and other attributes are ignored.
+func NewCPEDictionary(cpeNames []string) *CPEDictionary {
+	dict := &CPEDictionary{
+		byProduct: make(map[string][]dictionaryProduct),
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range cpeNames {
+		if !ValidCPE23(name) {
+			continue
+		}
+		components := splitCPE23(name)
+		vendor, product := components[3], components[4]
+
+		if seen[vendor+":"+product] {
+			continue
+		}
+		seen[vendor+":"+product] = true
+
+		entry := dictionaryProduct{Vendor: vendor, Product: product, key: similarityKey(product)}
+		dict.products = append(dict.products, entry)
+		dict.byProduct[product] = append(dict.byProduct[product], entry)
+	}
+
+	return dict
+}
+
+// LoadCPEDictionary loads the NVD CPE dictionary from the official XML feed (.xml)
+// or a CPE API 2.0 products response (.json)
+func LoadCPEDictionary(path string) (*CPEDictionary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CPE dictionary: %w", err)
+	}
+
+	var names []string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		var feed struct {
+			Items []struct {
+				CPE23 struct {
+					Name string `xml:"name,attr"`
+				} `xml:"cpe23-item"`
+			} `xml:"cpe-item"`
+		}
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return nil, fmt.Errorf("failed to parse CPE dictionary %s: %w", path, err)
+		}
+		for _, item := range feed.Items {
+			names = append(names, item.CPE23.Name)
+		}
+	case ".json":
+		var resp struct {
+			Products []struct {
+				CPE struct {
+					CPEName    string `json:"cpeName"`
+					Deprecated bool   `json:"deprecated"`
+				} `json:"cpe"`
+			} `json:"products"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse CPE dictionary %s: %w", path, err)
+		}
+		for _, product := range resp.Products {
+			if !product.CPE.Deprecated {
+				names = append(names, product.CPE.CPEName)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CPE dictionary format %q (use .xml or .json)", filepath.Ext(path))
+	}
+
+	dict := NewCPEDictionary(names)
+	if dict.Len() == 0 {
+		return nil, fmt.Errorf("CPE dictionary %s contains no valid CPE 2.3 names", path)
+	}
+
+	return dict, nil
+}
+
+// Len returns the number of distinct vendor:product pairs in the dictionary
+func (d *CPEDictionary) Len() int {
+	return len(d.products)
+}
+
+// Resolve snaps a product name to the closest canonical vendor:product pair
+// An exact product match wins; otherwise the most similar product at or above
+// MinProductSimilarity is used, preferring the earliest loaded on ties.
+// When several vendors ship the same product, the one matching the guessed vendor is preferred.
+func (d *CPEDictionary) Resolve(product string) (CPEIdentifier, bool) {
+	normalized := normalizeCPEComponent(product)
+	if normalized == "" || normalized == "*" {
+		return CPEIdentifier{}, false
+	}
+
+	if candidates, ok := d.byProduct[normalized]; ok {
+		return pickVendor(candidates, normalizeVendor(product)), true
+	}
+
+	key := similarityKey(normalized)
+	best := -1
+	bestScore := 0.0
+	for i, entry := range d.products {
+		score := similarity(key, entry.key)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 || bestScore < MinProductSimilarity {
+		return CPEIdentifier{}, false
+	}
+
+	return pickVendor(d.byProduct[d.products[best].Product], normalizeVendor(product)), true
+}
+
+// pickVendor chooses among pairs sharing a product, preferring the guessed vendor
+func pickVendor(candidates []dictionaryProduct, guessedVendor string) CPEIdentifier {
+	chosen := candidates[0]
+	for _, candidate := range candidates {
+		if candidate.Vendor == guessedVendor {
+			chosen = candidate
+			break
+		}
+	}
+	return CPEIdentifier{Vendor: chosen.Vendor, Product: chosen.Product}
+}
+
+// similarityKey strips CPE separators so "http-server", "http_server" and "httpserver" compare equal
+func similarityKey(s string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "", "\\", "").Replace(strings.ToLower(s))
+}
+
+// similarity returns 1 minus the Levenshtein distance over the longer length, in [0, 1]
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein computes the edit distance between two strings
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
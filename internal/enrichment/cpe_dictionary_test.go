@@ -0,0 +1,172 @@
+package enrichment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testDictionaryCPEs = []string{
+	"cpe:2.3:a:jenkins:jenkins:2.401:*:*:*:*:*:*:*",
+	"cpe:2.3:a:jenkins:jenkins:2.402:*:*:*:*:*:*:*",
+	"cpe:2.3:a:apache:http_server:2.4.57:*:*:*:*:*:*:*",
+	"cpe:2.3:a:grafana:grafana:10.0.0:*:*:*:*:*:*:*",
+	"cpe:2.3:a:elastic:elasticsearch:8.8.0:*:*:*:*:*:*:*",
+	"cpe:2.3:a:f5:nginx:1.25.0:*:*:*:*:*:*:*",
+	"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*",
+	"cpe:2.3:a::broken:1.0:*:*:*:*:*:*:*",
+}
+
+// useTestDictionary installs a dictionary for the duration of a test
+func useTestDictionary(t *testing.T, dict *CPEDictionary) {
+	t.Helper()
+	UseCPEDictionary(dict)
+	t.Cleanup(func() { UseCPEDictionary(nil) })
+}
+
+func TestNewCPEDictionary(t *testing.T) {
+	dict := NewCPEDictionary(testDictionaryCPEs)
+
+	// Versions collapse to one pair per vendor:product; the invalid name is skipped
+	if dict.Len() != 6 {
+		t.Errorf("Len() = %d, want 6", dict.Len())
+	}
+}
+
+func TestCPEDictionary_Resolve(t *testing.T) {
+	dict := NewCPEDictionary(testDictionaryCPEs)
+
+	tests := []struct {
+		name        string
+		product     string
+		wantVendor  string
+		wantProduct string
+		wantOK      bool
+	}{
+		{"exact", "jenkins", "jenkins", "jenkins", true},
+		{"case and spacing", "HTTP Server", "apache", "http_server", true},
+		{"separator variant", "http-server", "apache", "http_server", true},
+		{"misspelling", "grafanna", "grafana", "grafana", true},
+		{"suffix variant", "elastic-search", "elastic", "elasticsearch", true},
+		{"shared product prefers guessed vendor", "nginx", "nginx", "nginx", true},
+		{"too different", "postgres", "", "", false},
+		{"empty", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := dict.Resolve(tt.product)
+			if ok != tt.wantOK {
+				t.Fatalf("Resolve(%q) ok = %v, want %v", tt.product, ok, tt.wantOK)
+			}
+			if got.Vendor != tt.wantVendor || got.Product != tt.wantProduct {
+				t.Errorf("Resolve(%q) = %s:%s, want %s:%s", tt.product, got.Vendor, got.Product, tt.wantVendor, tt.wantProduct)
+			}
+		})
+	}
+}
+
+func TestGenerateCPE_UsesDictionary(t *testing.T) {
+	service := ServiceInfo{Product: "Grafanna", Version: "10.0.0"}
+
+	// Opt-in: without a dictionary the guessed names are used unchanged
+	cpes := GenerateCPE(service)
+	if len(cpes) == 0 || cpes[0].CPE != "cpe:2.3:a:grafanna:grafanna:10.0.0:*:*:*:*:*:*:*" {
+		t.Errorf("GenerateCPE() without dictionary = %+v", cpes)
+	}
+
+	useTestDictionary(t, NewCPEDictionary(testDictionaryCPEs))
+
+	cpes = GenerateCPE(service)
+	if len(cpes) == 0 || cpes[0].CPE != "cpe:2.3:a:grafana:grafana:10.0.0:*:*:*:*:*:*:*" {
+		t.Errorf("GenerateCPE() with dictionary = %+v, want canonical grafana CPE", cpes)
+	}
+
+	// Unversioned services snap too
+	cpes = GenerateCPE(ServiceInfo{Product: "http-server"})
+	if len(cpes) == 0 || cpes[0].CPE != "cpe:2.3:a:apache:http_server:*:*:*:*:*:*:*:*" {
+		t.Errorf("GenerateCPE() unversioned with dictionary = %+v", cpes)
+	}
+}
+
+func TestGenerateCPE_VendorOverrideBeatsDictionary(t *testing.T) {
+	resetVendorOverrides(t)
+	useTestDictionary(t, NewCPEDictionary(testDictionaryCPEs))
+
+	if _, err := LoadVendorMap(writeVendorMap(t, "vendors.yaml", "jenkins: cloudbees\n")); err != nil {
+		t.Fatalf("LoadVendorMap() error = %v", err)
+	}
+
+	cpes := GenerateCPE(ServiceInfo{Product: "jenkins", Version: "2.401"})
+	if len(cpes) == 0 || cpes[0].Vendor != "cloudbees" {
+		t.Errorf("GenerateCPE() = %+v, want the configured vendor override", cpes)
+	}
+}
+
+func TestLoadCPEDictionary(t *testing.T) {
+	dir := t.TempDir()
+
+	xmlPath := filepath.Join(dir, "official-cpe-dictionary_v2.3.xml")
+	xmlFeed := `<?xml version="1.0" encoding="UTF-8"?>
+<cpe-list xmlns="http://cpe.mitre.org/dictionary/2.0" xmlns:cpe-23="http://scap.nist.gov/schema/cpe-extension/2.3">
+  <cpe-item name="cpe:/a:jenkins:jenkins:2.401">
+    <title xml:lang="en-US">Jenkins 2.401</title>
+    <cpe-23:cpe23-item name="cpe:2.3:a:jenkins:jenkins:2.401:*:*:*:*:*:*:*"/>
+  </cpe-item>
+  <cpe-item name="cpe:/a:grafana:grafana:10.0.0">
+    <cpe-23:cpe23-item name="cpe:2.3:a:grafana:grafana:10.0.0:*:*:*:*:*:*:*"/>
+  </cpe-item>
+</cpe-list>`
+	if err := os.WriteFile(xmlPath, []byte(xmlFeed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonPath := filepath.Join(dir, "cpes.json")
+	jsonFeed := `{"products": [
+		{"cpe": {"cpeName": "cpe:2.3:a:jenkins:jenkins:2.401:*:*:*:*:*:*:*", "deprecated": false}},
+		{"cpe": {"cpeName": "cpe:2.3:a:old:jenkins_legacy:1.0:*:*:*:*:*:*:*", "deprecated": true}}
+	]}`
+	if err := os.WriteFile(jsonPath, []byte(jsonFeed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantLen int
+		wantErr bool
+	}{
+		{"official XML feed", xmlPath, 2, false},
+		{"CPE API JSON skips deprecated", jsonPath, 1, false},
+		{"missing file", filepath.Join(dir, "missing.xml"), 0, true},
+		{"unsupported extension", writeVendorMap(t, "cpes.txt", "cpe:2.3:a:x:y:1:*:*:*:*:*:*:*"), 0, true},
+		{"no valid names", writeVendorMap(t, "empty.json", `{"products": []}`), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dict, err := LoadCPEDictionary(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCPEDictionary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && dict.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", dict.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if got := similarity("grafana", "grafana"); got != 1 {
+		t.Errorf("similarity(identical) = %v, want 1", got)
+	}
+	if got := similarity("grafanna", "grafana"); got < MinProductSimilarity {
+		t.Errorf("similarity(grafanna, grafana) = %v, want >= %v", got, MinProductSimilarity)
+	}
+	if got := similarity("postgres", "nginx"); got >= MinProductSimilarity {
+		t.Errorf("similarity(postgres, nginx) = %v, want < %v", got, MinProductSimilarity)
+	}
+	if got := levenshtein("kitten", "sitting"); got != 3 {
+		t.Errorf("levenshtein(kitten, sitting) = %d, want 3", got)
+	}
+}
@@ -1,6 +1,7 @@
 package enrichment
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -234,6 +235,47 @@ func TestFormatCPE23(t *testing.T) {
 	}
 }
 
+func TestValidCPE23(t *testing.T) {
+	tests := []struct {
+		name string
+		cpe  string
+		want bool
+	}{
+		{"versioned application", "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*", true},
+		{"wildcard version", "cpe:2.3:a:mysql:mysql:*:*:*:*:*:*:*:*", true},
+		{"operating system", "cpe:2.3:o:linux:linux_kernel:6.1:*:*:*:*:*:*:*", true},
+		{"hardware", "cpe:2.3:h:cisco:asa_5505:-:*:*:*:*:*:*:*", true},
+		{"escaped colon in product", `cpe:2.3:a:vendor:prod\:uct:1.0:*:*:*:*:*:*:*`, true},
+		{"empty string", "", false},
+		{"empty product", "cpe:2.3:a:nginx::1.24.0:*:*:*:*:*:*:*", false},
+		{"empty vendor", "cpe:2.3:a::nginx:1.24.0:*:*:*:*:*:*:*", false},
+		{"wildcard product", "cpe:2.3:a:nginx:*:1.24.0:*:*:*:*:*:*:*", false},
+		{"wildcard vendor", "cpe:2.3:a:*:nginx:1.24.0:*:*:*:*:*:*:*", false},
+		{"too few components", "cpe:2.3:a:nginx:nginx:1.24.0", false},
+		{"too many components", "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*:*", false},
+		{"empty trailing component", "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:", false},
+		{"wrong prefix", "xpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*", false},
+		{"CPE 2.2 URI", "cpe:/a:nginx:nginx:1.24.0", false},
+		{"unknown part", "cpe:2.3:x:nginx:nginx:1.24.0:*:*:*:*:*:*:*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidCPE23(tt.cpe); got != tt.want {
+				t.Errorf("ValidCPE23(%q) = %v, want %v", tt.cpe, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidCPE23_GeneratedFromGarbage(t *testing.T) {
+	// Normalization strips every character, leaving an empty product
+	cpe := formatCPE23("acme", "!!!", "1.0")
+	if ValidCPE23(cpe) {
+		t.Errorf("ValidCPE23(%q) = true, want false for a CPE with an empty product", cpe)
+	}
+}
+
 func TestNormalizeCPEComponent(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -442,3 +484,95 @@ func TestGenerateCPEBatch(t *testing.T) {
 		t.Error("GenerateCPEBatch() should not include svc3 (no data)")
 	}
 }
+
+func TestParseHTTPBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantCPE []string
+	}{
+		{
+			name:    "WordPress generator tag",
+			body:    `<html><head><meta name="generator" content="WordPress 6.4.2" /></head></html>`,
+			wantCPE: []string{"cpe:2.3:a:wordpress:wordpress:6.4.2:*:*:*:*:*:*:*"},
+		},
+		{
+			name:    "Drupal generator tag",
+			body:    `<meta name="Generator" content="Drupal 10 (https://www.drupal.org)" />`,
+			wantCPE: []string{"cpe:2.3:a:drupal:drupal:10:*:*:*:*:*:*:*"},
+		},
+		{
+			name:    "MediaWiki generator tag",
+			body:    `<meta name='generator' content='MediaWiki 1.39.5'/>`,
+			wantCPE: []string{"cpe:2.3:a:mediawiki:mediawiki:1.39.5:*:*:*:*:*:*:*"},
+		},
+		{
+			name:    "Jira footer",
+			body:    `<li>Atlassian Jira <a href="https://www.atlassian.com/software/jira">Project Management Software</a> (v9.4.5#940005-sha1:abc)</li>`,
+			wantCPE: []string{"cpe:2.3:a:atlassian:jira:9.4.5:*:*:*:*:*:*:*"},
+		},
+		{
+			name:    "Confluence footer",
+			body:    `Powered by <a href="https://www.atlassian.com/software/confluence">Atlassian Confluence</a> <span id='footer-build-information'>7.19.0</span>`,
+			wantCPE: []string{"cpe:2.3:a:atlassian:confluence:7.19.0:*:*:*:*:*:*:*"},
+		},
+		{
+			name: "No fingerprint",
+			body: `<html><body>Hello</body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpes := ParseHTTPBody(tt.body)
+			if len(cpes) != len(tt.wantCPE) {
+				t.Fatalf("ParseHTTPBody() returned %d CPEs, want %d", len(cpes), len(tt.wantCPE))
+			}
+			for i, cpe := range cpes {
+				if cpe.CPE != tt.wantCPE[i] {
+					t.Errorf("ParseHTTPBody() CPE = %v, want %v", cpe.CPE, tt.wantCPE[i])
+				}
+				if !cpe.FromBanner {
+					t.Errorf("ParseHTTPBody() CPE %v should be marked as parsed", cpe.CPE)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHTTPBody_ScansOnlyTheStart(t *testing.T) {
+	tag := `<meta name="generator" content="WordPress 6.4.2" />`
+	body := strings.Repeat(" ", MaxHTTPBodyScanBytes) + tag
+
+	if cpes := ParseHTTPBody(body); len(cpes) != 0 {
+		t.Errorf("ParseHTTPBody() found %v past the scan limit", cpes)
+	}
+	if cpes := ParseHTTPBody(tag + body); len(cpes) != 1 {
+		t.Errorf("ParseHTTPBody() returned %d CPEs for a tag at the start, want 1", len(cpes))
+	}
+}
+
+func TestGenerateCPE_HTTPBody(t *testing.T) {
+	service := ServiceInfo{
+		Name:     "http",
+		Product:  "wordpress",
+		Version:  "6.4.2",
+		Banner:   "nginx/1.24.0",
+		HTTPBody: `<meta name="generator" content="WordPress 6.4.2" />`,
+	}
+
+	cpes := GenerateCPE(service)
+
+	want := []string{
+		"cpe:2.3:a:wordpress:wordpress:6.4.2:*:*:*:*:*:*:*",
+		"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*",
+	}
+	if len(cpes) != len(want) {
+		t.Fatalf("GenerateCPE() returned %d CPEs, want %d (body CPE deduped against the fingerprint): %v", len(cpes), len(want), cpes)
+	}
+	for i, cpe := range cpes {
+		if cpe.CPE != want[i] {
+			t.Errorf("GenerateCPE()[%d] = %v, want %v", i, cpe.CPE, want[i])
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package enrichment
+
+import "errors"
+
+// Errors returned by the enrichment clients, wrapped with detail about the
+// failed call. Callers match them with errors.Is to decide whether to retry,
+// dead-letter or skip a lookup.
+var (
+	// ErrRateLimited indicates the upstream throttled the request or the
+	// client's rate limiter gave up waiting for a token
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrUpstreamUnavailable indicates the upstream could not be reached or
+	// failed to answer the request
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+
+	// ErrNotFound indicates the upstream has no data for the requested key
+	ErrNotFound = errors.New("not found")
+)
+
+// IsRetryable reports whether err is a transient upstream failure worth retrying
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUpstreamUnavailable)
+}
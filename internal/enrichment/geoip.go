@@ -1,6 +1,7 @@
 package enrichment
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -20,6 +21,10 @@ type GeoIPInfo struct {
 	CountryCC string  `json:"country_cc"` // ISO 3166-1 alpha-2
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
+
+	// AccuracyRadiusKm is MaxMind's radius around the coordinates that contains
+	// the address; 0 when the database does not report one
+	AccuracyRadiusKm int `json:"accuracy_radius_km,omitempty"`
 }
 
 // GeoIPClient provides GeoIP lookup functionality with local MMDB files and API fallback
@@ -30,8 +35,14 @@ type GeoIPClient struct {
 	httpClient *http.Client
 	apiKey     string // Optional API key for fallback service
 	apiURL     string // Optional API URL for fallback
+
+	// concurrency is the number of workers LookupBatch fans out to
+	concurrency int
 }
 
+// DefaultGeoIPConcurrency is the number of LookupBatch workers used when none is configured
+const DefaultGeoIPConcurrency = 10
+
 // GeoIPConfig configures the GeoIP client
 type GeoIPConfig struct {
 	// Path to MaxMind GeoLite2 City MMDB file
@@ -40,6 +51,11 @@ type GeoIPConfig struct {
 	// Optional API fallback configuration
 	APIKey string // ipinfo.io API key
 	APIURL string // Default: https://ipinfo.io
+
+	// Concurrency is the number of parallel lookups in LookupBatch; MMDB reads are
+	// goroutine-safe, so this spreads a batch across cores. 1 looks IPs up serially.
+	// Default: DefaultGeoIPConcurrency
+	Concurrency int
 }
 
 // NewGeoIPClient creates a new GeoIP lookup client
@@ -50,8 +66,13 @@ func NewGeoIPClient(config GeoIPConfig) (*GeoIPClient, error) {
 		httpClient: &http.Client{
 			Timeout: 5 * time.Second,
 		},
-		apiKey: config.APIKey,
-		apiURL: config.APIURL,
+		apiKey:      config.APIKey,
+		apiURL:      config.APIURL,
+		concurrency: config.Concurrency,
+	}
+
+	if client.concurrency <= 0 {
+		client.concurrency = DefaultGeoIPConcurrency
 	}
 
 	// Set default API URL if not provided
@@ -114,12 +135,14 @@ func (c *GeoIPClient) Lookup(ipStr string) (*GeoIPInfo, error) {
 	hasMMDB := c.db != nil
 	c.mu.RUnlock()
 
+	var mmdbErr error
 	if hasMMDB {
 		info, err := c.lookupMMDB(ip)
 		if err == nil {
 			return info, nil
 		}
 		// If MMDB lookup fails, fall through to API
+		mmdbErr = err
 	}
 
 	// Fallback to API if MMDB is unavailable or lookup failed
@@ -127,7 +150,12 @@ func (c *GeoIPClient) Lookup(ipStr string) (*GeoIPInfo, error) {
 		return c.lookupAPI(ipStr)
 	}
 
-	return nil, fmt.Errorf("no GeoIP data source available (MMDB failed and no API key configured)")
+	// The MMDB answered but has no record for the address
+	if errors.Is(mmdbErr, ErrNotFound) {
+		return nil, mmdbErr
+	}
+
+	return nil, fmt.Errorf("%w: no GeoIP data source available (MMDB failed and no API key configured)", ErrUpstreamUnavailable)
 }
 
 // lookupMMDB performs a lookup using the local MMDB file
@@ -136,19 +164,25 @@ func (c *GeoIPClient) lookupMMDB(ip net.IP) (*GeoIPInfo, error) {
 	defer c.mu.RUnlock()
 
 	if c.db == nil {
-		return nil, fmt.Errorf("MMDB database not initialized")
+		return nil, fmt.Errorf("%w: MMDB database not initialized", ErrUpstreamUnavailable)
 	}
 
 	record, err := c.db.City(ip)
 	if err != nil {
-		return nil, fmt.Errorf("MMDB lookup failed: %w", err)
+		return nil, fmt.Errorf("%w: MMDB lookup failed: %w", ErrUpstreamUnavailable, err)
+	}
+
+	// Addresses missing from the database decode to an empty record
+	if record.Country.IsoCode == "" && record.Location.Latitude == 0 && record.Location.Longitude == 0 {
+		return nil, fmt.Errorf("%w: no GeoIP record for IP %s", ErrNotFound, ip)
 	}
 
 	info := &GeoIPInfo{
-		IP:        ip.String(),
-		Latitude:  record.Location.Latitude,
-		Longitude: record.Location.Longitude,
-		CountryCC: record.Country.IsoCode,
+		IP:               ip.String(),
+		Latitude:         record.Location.Latitude,
+		Longitude:        record.Location.Longitude,
+		AccuracyRadiusKm: int(record.Location.AccuracyRadius),
+		CountryCC:        record.Country.IsoCode,
 	}
 
 	// Extract city name (prefer English)
@@ -199,42 +233,66 @@ func (c *GeoIPClient) lookupAPI(ipStr string) (*GeoIPInfo, error) {
 	// Note: This is a simplified implementation
 	// In production, you would parse the JSON response from ipinfo.io
 	// For now, return an error to encourage using MMDB
-	return nil, fmt.Errorf("API fallback not fully implemented - please provide MMDB file")
+	return nil, fmt.Errorf("%w: API fallback not fully implemented - please provide MMDB file", ErrUpstreamUnavailable)
 }
 
 // LookupBatch performs GeoIP lookups for multiple IP addresses
 // Returns a map of IP -> GeoIPInfo
-// Skips IPs that fail lookup without returning error
+// Lookups run on the client's configured number of workers. IPs that are invalid
+// or fail lookup are skipped without returning an error. If every lookup fails,
+// the error wraps the first lookup failure.
 func (c *GeoIPClient) LookupBatch(ips []string) (map[string]*GeoIPInfo, error) {
 	results := make(map[string]*GeoIPInfo)
+	var firstErr error
 	var mu sync.Mutex
 
-	// Use a worker pool for batch processing
-	const maxWorkers = 10
-	semaphore := make(chan struct{}, maxWorkers)
-	var wg sync.WaitGroup
-
-	for _, ipStr := range ips {
-		wg.Add(1)
-		go func(ip string) {
-			defer wg.Done()
-
-			semaphore <- struct{}{}        // Acquire
-			defer func() { <-semaphore }() // Release
+	lookup := func(ip string) {
+		if net.ParseIP(ip) == nil {
+			return
+		}
+		info, err := c.Lookup(ip)
+		mu.Lock()
+		if err == nil && info != nil {
+			results[ip] = info
+		} else if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		// Silently skip failed lookups in batch mode
+	}
 
-			info, err := c.Lookup(ip)
-			if err == nil && info != nil {
-				mu.Lock()
-				results[ip] = info
-				mu.Unlock()
-			}
-			// Silently skip failed lookups in batch mode
-		}(ipStr)
+	workers := c.concurrency
+	if workers > len(ips) {
+		workers = len(ips)
 	}
 
-	wg.Wait()
+	if workers <= 1 {
+		for _, ip := range ips {
+			lookup(ip)
+		}
+	} else {
+		jobs := make(chan string)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ip := range jobs {
+					lookup(ip)
+				}
+			}()
+		}
+		for _, ip := range ips {
+			jobs <- ip
+		}
+		close(jobs)
+		wg.Wait()
+	}
 
 	if len(results) == 0 {
+		if firstErr != nil {
+			return nil, fmt.Errorf("no successful GeoIP lookups from %d IPs: %w", len(ips), firstErr)
+		}
 		return nil, fmt.Errorf("no successful GeoIP lookups from %d IPs", len(ips))
 	}
 
@@ -1,8 +1,10 @@
 package enrichment
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -203,6 +205,43 @@ func TestGeoIPClient_LookupBatch(t *testing.T) {
 	})
 }
 
+// TestGeoIPClient_Concurrency tests the LookupBatch worker count configuration
+func TestGeoIPClient_Concurrency(t *testing.T) {
+	client, _ := NewGeoIPClient(GeoIPConfig{})
+	assert.Equal(t, DefaultGeoIPConcurrency, client.concurrency)
+
+	client, _ = NewGeoIPClient(GeoIPConfig{Concurrency: 4})
+	assert.Equal(t, 4, client.concurrency)
+}
+
+// TestGeoIPClient_LookupBatch_ConcurrencyLevels checks every concurrency level
+// returns the same results, skipping invalid IPs
+func TestGeoIPClient_LookupBatch_ConcurrencyLevels(t *testing.T) {
+	mmdbPath := getTestMMDBPath()
+	if mmdbPath == "" {
+		t.Skip("No GeoIP MMDB file available for testing (set GEOIP_MMDB_PATH environment variable)")
+	}
+
+	ips := append(benchmarkIPs(200), "invalid", "not-an-ip")
+
+	var serial map[string]*GeoIPInfo
+	for _, concurrency := range []int{1, 4, 16} {
+		client, err := NewGeoIPClient(GeoIPConfig{MMDBPath: mmdbPath, Concurrency: concurrency})
+		require.NoError(t, err)
+
+		results, err := client.LookupBatch(ips)
+		client.Close()
+		require.NoError(t, err)
+		assert.NotContains(t, results, "invalid")
+
+		if serial == nil {
+			serial = results
+			continue
+		}
+		assert.Equal(t, serial, results, "concurrency=%d", concurrency)
+	}
+}
+
 // TestValidateMMDB tests MMDB file validation
 func TestValidateMMDB(t *testing.T) {
 	mmdbPath := getTestMMDBPath()
@@ -300,3 +339,75 @@ func getTestMMDBPath() string {
 
 	return ""
 }
+
+// benchmarkIPs returns n public IPv4 addresses spread across the address space
+func benchmarkIPs(n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("%d.%d.%d.%d", 1+i%223, (i*7)%256, (i*13)%256, 1+i%254)
+	}
+	return ips
+}
+
+// BenchmarkGeoIPClient_LookupBatch compares LookupBatch concurrency levels on a 1000-IP batch
+func BenchmarkGeoIPClient_LookupBatch(b *testing.B) {
+	mmdbPath := getTestMMDBPath()
+	if mmdbPath == "" {
+		b.Skip("No GeoIP MMDB file available for benchmarking (set GEOIP_MMDB_PATH environment variable)")
+	}
+
+	ips := benchmarkIPs(1000)
+	for _, concurrency := range []int{1, 2, 4, 8, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			client, err := NewGeoIPClient(GeoIPConfig{MMDBPath: mmdbPath, Concurrency: concurrency})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer client.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.LookupBatch(ips); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGeoIPClient_ErrorTypes(t *testing.T) {
+	t.Run("no data source", func(t *testing.T) {
+		client, _ := NewGeoIPClient(GeoIPConfig{})
+		defer client.Close()
+
+		_, err := client.Lookup("8.8.8.8")
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+
+		_, err = client.LookupBatch([]string{"8.8.8.8", "1.1.1.1"})
+		assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	})
+
+	t.Run("invalid IP", func(t *testing.T) {
+		client, _ := NewGeoIPClient(GeoIPConfig{})
+		defer client.Close()
+
+		_, err := client.Lookup("not-an-ip")
+		require.Error(t, err)
+		assert.False(t, IsRetryable(err))
+		assert.NotErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("not in database", func(t *testing.T) {
+		mmdbPath := getTestMMDBPath()
+		if mmdbPath == "" {
+			t.Skip("No GeoIP MMDB file available for testing (set GEOIP_MMDB_PATH environment variable)")
+		}
+
+		client, err := NewGeoIPClient(GeoIPConfig{MMDBPath: mmdbPath})
+		require.NoError(t, err)
+		defer client.Close()
+
+		_, err = client.Lookup("10.0.0.1")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
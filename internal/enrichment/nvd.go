@@ -1,14 +1,22 @@
 package enrichment
 
 import (
+	"container/list"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/spectra-red/recon/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/time/rate"
 )
 
@@ -23,27 +31,144 @@ const (
 	// Request timeout
 	nvdRequestTimeout = 30 * time.Second
 
-	// Cache TTL
+	// Cache TTL, unless NVDConfig.CacheTTLBySeverity overrides it
 	nvdCacheTTL = 24 * time.Hour
+
+	// Concurrent lookups in QueryByCPEBatch; the rate limiter still bounds the request rate
+	nvdBatchWorkers = 8
 )
 
+const (
+	// DefaultNVDCacheMaxEntries caps the NVD response cache when NVDConfig leaves it unset
+	DefaultNVDCacheMaxEntries = 10000
+
+	// NVDMaxResultsPerPage is the largest page the NVD CVE API serves
+	NVDMaxResultsPerPage = 2000
+	// DefaultNVDResultsPerPage is the page size requested when NVDQueryOptions leaves it unset
+	DefaultNVDResultsPerPage = NVDMaxResultsPerPage
+	// DefaultNVDMaxResults bounds the CVEs fetched per CPE when NVDQueryOptions leaves it unset
+	DefaultNVDMaxResults = 2000
+)
+
+// NVDQueryOptions bounds the paging done for a single CPE lookup
+type NVDQueryOptions struct {
+	MaxResults     int // Keep at most this many CVEs, the newest
+	ResultsPerPage int // Page size, capped at NVDMaxResultsPerPage
+
+	// ForceRefresh skips the cache read so the lookup always reaches NVD;
+	// the fresh result is still cached
+	ForceRefresh bool
+}
+
+// withDefaults fills unset options from defaults and caps the page size
+func (o NVDQueryOptions) withDefaults(defaults NVDQueryOptions) NVDQueryOptions {
+	if o.MaxResults <= 0 {
+		o.MaxResults = defaults.MaxResults
+	}
+	if o.ResultsPerPage <= 0 {
+		o.ResultsPerPage = defaults.ResultsPerPage
+	}
+	if o.ResultsPerPage > NVDMaxResultsPerPage {
+		o.ResultsPerPage = NVDMaxResultsPerPage
+	}
+	return o
+}
+
 // NVDClient provides methods for querying the NVD API
 type NVDClient struct {
 	httpClient *http.Client
+	baseURL    string
 	apiKey     string
 	limiter    *rate.Limiter
 	cache      *NVDCache
+	breaker    *CircuitBreaker
+	workers    int
+	query      NVDQueryOptions // defaults for QueryByCPE
 }
 
 // NVDCache stores cached NVD responses
+// It is safe for concurrent use by the batch workers. Once maxEntries is
+// reached, inserting a new key evicts the least recently used entry.
 type NVDCache struct {
-	entries map[string]*CacheEntry
+	mu         sync.Mutex
+	entries    map[string]*CacheEntry
+	lru        *list.List // keys, most recently used at the front
+	maxEntries int        // 0 means unbounded
+	counters   cacheCounters
+
+	// severityTTLs replace the TTL given to Set for entries whose most severe CVE
+	// has that severity; they are applied on read, so changing them affects entries
+	// already cached
+	severityTTLs map[Severity]time.Duration
+}
+
+// NewNVDCache creates a cache holding at most maxEntries responses
+// A non-positive maxEntries leaves the cache unbounded.
+func NewNVDCache(maxEntries int) *NVDCache {
+	return NewNVDCacheWithTTLs(maxEntries, nil)
+}
+
+// NewNVDCacheWithTTLs creates a cache like NewNVDCache whose entries expire after
+// the TTL for the most severe CVE they contain, e.g. sooner for CRITICAL CVEs whose
+// exploit status changes fast. Severities without a positive TTL keep the TTL given to Set.
+func NewNVDCacheWithTTLs(maxEntries int, severityTTLs map[Severity]time.Duration) *NVDCache {
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+	ttls := make(map[Severity]time.Duration, len(severityTTLs))
+	for severity, ttl := range severityTTLs {
+		if ttl > 0 {
+			ttls[severity] = ttl
+		}
+	}
+	return &NVDCache{
+		entries:      make(map[string]*CacheEntry),
+		lru:          list.New(),
+		maxEntries:   maxEntries,
+		severityTTLs: ttls,
+	}
+}
+
+// BatchQueryError reports the CPEs whose lookups failed in QueryByCPEBatch
+type BatchQueryError struct {
+	Failed map[string]error // Lookup error per CPE
+	Total  int              // Number of CPEs in the batch
+}
+
+// Error summarizes the failed lookups in CPE order
+func (e *BatchQueryError) Error() string {
+	cpes := make([]string, 0, len(e.Failed))
+	for cpe := range e.Failed {
+		cpes = append(cpes, cpe)
+	}
+	sort.Strings(cpes)
+
+	parts := make([]string, 0, len(cpes))
+	for _, cpe := range cpes {
+		parts = append(parts, fmt.Sprintf("%s: %v", cpe, e.Failed[cpe]))
+	}
+	return fmt.Sprintf("%d of %d CPE lookups failed: %s", len(e.Failed), e.Total, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the per-CPE errors so callers can match them with errors.Is
+func (e *BatchQueryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
 // CacheEntry represents a cached NVD response
 type CacheEntry struct {
 	Data      []CVEItem
-	ExpiresAt time.Time
+	StoredAt  time.Time
+	ExpiresAt time.Time // from the TTL given to Set; a severity TTL may end the entry sooner or later
+
+	// MaxSeverity is the most severe CVE in Data, SeverityUnknown when it has none
+	MaxSeverity Severity
+
+	elem *list.Element // position in the cache's LRU list
 }
 
 // CVEItem represents a CVE from the NVD API
@@ -51,7 +176,8 @@ type CVEItem struct {
 	CVEID       string    `json:"cve_id"`
 	Description string    `json:"description"`
 	CVSS        float64   `json:"cvss"`
-	Severity    string    `json:"severity"` // CRITICAL, HIGH, MEDIUM, LOW
+	CVSSVector  string    `json:"cvss_vector,omitempty"` // e.g. CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+	Severity    string    `json:"severity"`              // CRITICAL, HIGH, MEDIUM, LOW
 	Published   time.Time `json:"published"`
 	Modified    time.Time `json:"modified"`
 	CPEs        []string  `json:"cpes"`
@@ -60,10 +186,11 @@ type CVEItem struct {
 
 // VulnMatch represents a vulnerability matched to a service
 type VulnMatch struct {
-	ServiceID string  `json:"service_id"`
-	CVE       string  `json:"cve"`
-	CVSS      float64 `json:"cvss"`
-	Severity  string  `json:"severity"`
+	ServiceID  string  `json:"service_id"`
+	CVE        string  `json:"cve"`
+	CVSS       float64 `json:"cvss"`
+	Severity   string  `json:"severity"`
+	Confidence float64 `json:"confidence"` // How specific the matching CPE was (see CPEMatchConfidence)
 }
 
 // NVDResponse represents the NVD API response structure
@@ -85,17 +212,20 @@ type NVDResponse struct {
 					CVSSData struct {
 						BaseScore    float64 `json:"baseScore"`
 						BaseSeverity string  `json:"baseSeverity"`
+						VectorString string  `json:"vectorString"`
 					} `json:"cvssData"`
 				} `json:"cvssMetricV31"`
 				CVSSMetricV30 []struct {
 					CVSSData struct {
 						BaseScore    float64 `json:"baseScore"`
 						BaseSeverity string  `json:"baseSeverity"`
+						VectorString string  `json:"vectorString"`
 					} `json:"cvssData"`
 				} `json:"cvssMetricV30"`
 				CVSSMetricV2 []struct {
 					CVSSData struct {
-						BaseScore float64 `json:"baseScore"`
+						BaseScore    float64 `json:"baseScore"`
+						VectorString string  `json:"vectorString"`
 					} `json:"cvssData"`
 					BaseSeverity string `json:"baseSeverity"`
 				} `json:"cvssMetricV2"`
@@ -115,8 +245,32 @@ type NVDResponse struct {
 	} `json:"vulnerabilities"`
 }
 
+// NVDConfig configures an NVDClient
+type NVDConfig struct {
+	APIKey          string        // Optional API key for the higher rate limit
+	CacheMaxEntries int           // Cached responses kept before LRU eviction (defaults to DefaultNVDCacheMaxEntries)
+	MaxResults      int           // CVEs fetched per CPE (defaults to DefaultNVDMaxResults)
+	ResultsPerPage  int           // NVD page size (defaults to DefaultNVDResultsPerPage, capped at NVDMaxResultsPerPage)
+	Breaker         BreakerConfig // Circuit breaker around NVD API calls
+
+	// CacheTTLBySeverity overrides the 24h cache TTL for responses whose most severe
+	// CVE has the severity (see ParseSeverityTTLs)
+	CacheTTLBySeverity map[Severity]time.Duration
+}
+
 // NewNVDClient creates a new NVD API client
 func NewNVDClient(apiKey string) *NVDClient {
+	return NewNVDClientWithConfig(NVDConfig{APIKey: apiKey})
+}
+
+// NewNVDClientWithConfig creates a new NVD API client from config
+func NewNVDClientWithConfig(cfg NVDConfig) *NVDClient {
+	apiKey := cfg.APIKey
+	cacheMaxEntries := cfg.CacheMaxEntries
+	if cacheMaxEntries <= 0 {
+		cacheMaxEntries = DefaultNVDCacheMaxEntries
+	}
+
 	// Determine rate limit based on API key presence
 	rateLimit := nvdRateLimitPublic
 	if apiKey != "" {
@@ -130,40 +284,172 @@ func NewNVDClient(apiKey string) *NVDClient {
 		httpClient: &http.Client{
 			Timeout: nvdRequestTimeout,
 		},
+		baseURL: nvdBaseURL,
 		apiKey:  apiKey,
 		limiter: limiter,
-		cache: &NVDCache{
-			entries: make(map[string]*CacheEntry),
-		},
+		cache:   NewNVDCacheWithTTLs(cacheMaxEntries, cfg.CacheTTLBySeverity),
+		breaker: NewCircuitBreaker(cfg.Breaker),
+		workers: nvdBatchWorkers,
+		query: NVDQueryOptions{MaxResults: cfg.MaxResults, ResultsPerPage: cfg.ResultsPerPage}.withDefaults(NVDQueryOptions{
+			MaxResults:     DefaultNVDMaxResults,
+			ResultsPerPage: DefaultNVDResultsPerPage,
+		}),
 	}
 }
 
-// QueryByCPE queries the NVD API for vulnerabilities matching a CPE identifier
+// CacheStats returns the statistics of the client's response cache
+func (c *NVDClient) CacheStats() CacheStats {
+	return c.cache.Stats()
+}
+
+// BreakerStats returns the state of the circuit breaker around NVD API calls
+func (c *NVDClient) BreakerStats() BreakerStats {
+	return c.breaker.Stats()
+}
+
+// QueryByCPE queries the NVD API for vulnerabilities matching a CPE identifier,
+// paging with the client's default options
 func (c *NVDClient) QueryByCPE(ctx context.Context, cpe string) ([]CVEItem, error) {
+	return c.QueryByCPEWithOptions(ctx, cpe, NVDQueryOptions{})
+}
+
+// QueryByCPEWithOptions queries the NVD API for vulnerabilities matching a CPE
+// identifier in pages of opts.ResultsPerPage, returning the newest opts.MaxResults
+// CVEs, or all of them when NVD has fewer. Unset options use the client's defaults.
+func (c *NVDClient) QueryByCPEWithOptions(ctx context.Context, cpe string, opts NVDQueryOptions) ([]CVEItem, error) {
+	opts = opts.withDefaults(c.query)
+
+	// The page size only changes how many requests are made, so lookups that
+	// differ only in it share a cache entry
+	cacheKey := cpe
+	if opts.MaxResults != c.query.MaxResults {
+		cacheKey = fmt.Sprintf("%s|max=%d", cpe, opts.MaxResults)
+	}
+
 	// Check cache first
-	if cached, ok := c.cache.Get(cpe); ok {
-		return cached, nil
+	if !opts.ForceRefresh {
+		if cached, ok := c.cache.Get(cacheKey); ok {
+			return cached, nil
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, "enrichment.nvd.QueryByCPE",
+		attribute.String("cpe", cpe),
+		attribute.Int("max_results", opts.MaxResults))
+	items, err := c.fetchCPEPages(ctx, cpe, opts)
+	span.SetAttributes(attribute.Int("cves", len(items)))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
 	}
 
+	// Cache the result
+	c.cache.Set(cacheKey, items, nvdCacheTTL)
+
+	return items, nil
+}
+
+// fetchCPEPages requests the newest opts.MaxResults vulnerabilities matching a CPE.
+// NVD returns matches oldest first, so once the first page reports more than
+// MaxResults in total, paging skips ahead to the last MaxResults. A total that is
+// missing, or smaller than the first page, is treated as unknown: every page is
+// read until a short one, keeping the newest MaxResults.
+func (c *NVDClient) fetchCPEPages(ctx context.Context, cpe string, opts NVDQueryOptions) ([]CVEItem, error) {
+	pageSize := min(opts.ResultsPerPage, opts.MaxResults)
+	page, total, err := c.fetchCPE(ctx, cpe, 0, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	// A short page is the last one, even if totalResults is missing
+	if len(page) < pageSize {
+		return page, nil
+	}
+
+	if total < len(page) {
+		return c.fetchCPEPagesUnknownTotal(ctx, cpe, opts, page)
+	}
+
+	// Keep whatever of the first page falls in the tail, then read the rest of it
+	tail := max(total-opts.MaxResults, 0)
+	items := []CVEItem{}
+	startIndex := tail
+	if tail < len(page) {
+		items = append(items, page[tail:]...)
+		startIndex = len(page)
+	}
+	for startIndex < total {
+		pageSize := min(opts.ResultsPerPage, total-startIndex)
+		page, _, err := c.fetchCPE(ctx, cpe, startIndex, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		startIndex += len(page)
+
+		if len(page) < pageSize {
+			break
+		}
+	}
+	return items, nil
+}
+
+// fetchCPEPagesUnknownTotal continues a lookup whose total NVD did not report from
+// the full first page, reading pages until a short one and keeping the newest
+// opts.MaxResults
+func (c *NVDClient) fetchCPEPagesUnknownTotal(ctx context.Context, cpe string, opts NVDQueryOptions, first []CVEItem) ([]CVEItem, error) {
+	items := first
+	for startIndex := len(first); ; {
+		page, _, err := c.fetchCPE(ctx, cpe, startIndex, opts.ResultsPerPage)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if len(items) > opts.MaxResults {
+			items = slices.Clone(items[len(items)-opts.MaxResults:])
+		}
+		startIndex += len(page)
+
+		if len(page) < opts.ResultsPerPage {
+			return items, nil
+		}
+	}
+}
+
+// fetchCPE requests one page of the vulnerabilities matching a CPE from the NVD API
+// and returns it with NVD's total result count
+func (c *NVDClient) fetchCPE(ctx context.Context, cpe string, startIndex, resultsPerPage int) ([]CVEItem, int, error) {
+	// Fail fast while NVD is down
+	if err := c.breaker.Allow(); err != nil {
+		return nil, 0, err
+	}
+	items, total, err := c.fetchCPEPage(ctx, cpe, startIndex, resultsPerPage)
+	c.breaker.Record(err)
+	return items, total, err
+}
+
+// fetchCPEPage performs the rate-limited NVD request for fetchCPE
+func (c *NVDClient) fetchCPEPage(ctx context.Context, cpe string, startIndex, resultsPerPage int) ([]CVEItem, int, error) {
 	// Wait for rate limiter
 	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %w", err)
+		return nil, 0, fmt.Errorf("%w: rate limiter error: %w", ErrRateLimited, err)
 	}
 
 	// Build request URL
-	reqURL, err := url.Parse(nvdBaseURL)
+	reqURL, err := url.Parse(c.baseURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid base URL: %w", err)
+		return nil, 0, fmt.Errorf("invalid base URL: %w", err)
 	}
 
 	query := reqURL.Query()
 	query.Set("cpeName", cpe)
+	query.Set("startIndex", strconv.Itoa(startIndex))
+	query.Set("resultsPerPage", strconv.Itoa(resultsPerPage))
 	reqURL.RawQuery = query.Encode()
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add API key if available
@@ -174,43 +460,94 @@ func (c *NVDClient) QueryByCPE(ctx context.Context, cpe string) ([]CVEItem, erro
 	// Execute request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, 0, fmt.Errorf("%w: HTTP request failed: %w", ErrUpstreamUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("NVD API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, 0, statusError(resp.StatusCode, body)
 	}
 
 	// Parse response
 	var nvdResp NVDResponse
 	if err := json.NewDecoder(resp.Body).Decode(&nvdResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	// Convert to CVEItems
-	items := c.convertResponse(nvdResp)
-
-	// Cache the result
-	c.cache.Set(cpe, items, nvdCacheTTL)
+	return c.convertResponse(nvdResp), nvdResp.TotalResults, nil
+}
 
-	return items, nil
+// statusError builds the error for a non-200 NVD response, wrapping the sentinel
+// that matches its status code. NVD answers 403 as well as 429 when a client
+// exceeds its rate limit.
+func statusError(code int, body []byte) error {
+	err := fmt.Errorf("NVD API returned status %d: %s", code, string(body))
+	switch {
+	case code == http.StatusTooManyRequests || code == http.StatusForbidden:
+		return fmt.Errorf("%w: %v", ErrRateLimited, err)
+	case code == http.StatusNotFound:
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	case code >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %v", ErrUpstreamUnavailable, err)
+	default:
+		return err
+	}
 }
 
-// QueryByCPEBatch queries NVD for multiple CPEs with rate limiting
+// QueryByCPEBatch queries NVD for multiple CPEs concurrently
+// Lookups run on a bounded worker pool that shares the client's rate limiter, so the
+// batch goes as fast as the NVD limit allows. Successful lookups are always returned;
+// if any lookup failed, the error is a *BatchQueryError listing the failed CPEs.
 func (c *NVDClient) QueryByCPEBatch(ctx context.Context, cpes []string) (map[string][]CVEItem, error) {
-	results := make(map[string][]CVEItem)
+	return c.QueryByCPEBatchWithOptions(ctx, cpes, NVDQueryOptions{})
+}
+
+// QueryByCPEBatchWithOptions queries NVD for multiple CPEs concurrently, applying opts to each lookup
+func (c *NVDClient) QueryByCPEBatchWithOptions(ctx context.Context, cpes []string, opts NVDQueryOptions) (map[string][]CVEItem, error) {
+	results := make(map[string][]CVEItem, len(cpes))
+	failed := make(map[string]error)
+
+	workers := c.workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(cpes) {
+		workers = len(cpes)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cpe := range jobs {
+				items, err := c.QueryByCPEWithOptions(ctx, cpe, opts)
+
+				mu.Lock()
+				if err != nil {
+					failed[cpe] = err
+				} else {
+					results[cpe] = items
+				}
+				mu.Unlock()
+			}
+		}()
+	}
 
 	for _, cpe := range cpes {
-		items, err := c.QueryByCPE(ctx, cpe)
-		if err != nil {
-			// Log error but continue with other CPEs
-			// In production, you might want to return partial results + errors
-			continue
-		}
-		results[cpe] = items
+		jobs <- cpe
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return results, &BatchQueryError{Failed: failed, Total: len(cpes)}
 	}
 
 	return results, nil
@@ -232,19 +569,32 @@ func (c *NVDClient) convertResponse(resp NVDResponse) []CVEItem {
 			}
 		}
 
-		// Extract CVSS score and severity (prefer v3.1, then v3.0, then v2)
+		// Extract CVSS score, vector and severity (prefer v3.1, then v3.0, then v2)
 		cvss := 0.0
+		vector := ""
 		severity := "UNKNOWN"
+		cvssVersion := ""
 
 		if len(cve.Metrics.CVSSMetricV31) > 0 {
 			cvss = cve.Metrics.CVSSMetricV31[0].CVSSData.BaseScore
+			vector = cve.Metrics.CVSSMetricV31[0].CVSSData.VectorString
 			severity = cve.Metrics.CVSSMetricV31[0].CVSSData.BaseSeverity
+			cvssVersion = CVSSVersion31
 		} else if len(cve.Metrics.CVSSMetricV30) > 0 {
 			cvss = cve.Metrics.CVSSMetricV30[0].CVSSData.BaseScore
+			vector = cve.Metrics.CVSSMetricV30[0].CVSSData.VectorString
 			severity = cve.Metrics.CVSSMetricV30[0].CVSSData.BaseSeverity
+			cvssVersion = CVSSVersion30
 		} else if len(cve.Metrics.CVSSMetricV2) > 0 {
 			cvss = cve.Metrics.CVSSMetricV2[0].CVSSData.BaseScore
+			vector = cve.Metrics.CVSSMetricV2[0].CVSSData.VectorString
 			severity = cve.Metrics.CVSSMetricV2[0].BaseSeverity
+			cvssVersion = CVSSVersion2
+		}
+
+		// Derive the severity from the score when the metric omits it
+		if cvssVersion != "" && strings.TrimSpace(severity) == "" {
+			severity = SeverityFromCVSS(cvss, cvssVersion)
 		}
 
 		// Extract CPEs
@@ -273,6 +623,7 @@ func (c *NVDClient) convertResponse(resp NVDResponse) []CVEItem {
 			CVEID:       cve.ID,
 			Description: description,
 			CVSS:        cvss,
+			CVSSVector:  vector,
 			Severity:    severity,
 			Published:   published,
 			Modified:    modified,
@@ -285,32 +636,129 @@ func (c *NVDClient) convertResponse(resp NVDResponse) []CVEItem {
 }
 
 // Get retrieves a cached entry if it exists and is not expired
+// A hit marks the entry as most recently used.
 func (c *NVDCache) Get(key string) ([]CVEItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	entry, exists := c.entries[key]
 	if !exists {
+		c.counters.misses.Add(1)
 		return nil, false
 	}
 
 	// Check if expired
-	if time.Now().After(entry.ExpiresAt) {
-		delete(c.entries, key)
+	if time.Now().After(c.expiresAt(entry)) {
+		c.remove(key, entry)
+		c.counters.misses.Add(1)
+		c.counters.evictions.Add(1)
 		return nil, false
 	}
 
+	c.order().MoveToFront(entry.elem)
+	c.counters.hits.Add(1)
 	return entry.Data, true
 }
 
-// Set stores a cache entry with TTL
+// Set stores a cache entry with TTL, evicting the least recently used entry
+// when a new key would exceed the size cap
 func (c *NVDCache) Set(key string, data []CVEItem, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	severity := maxSeverity(data)
+	if entry, exists := c.entries[key]; exists {
+		entry.Data = data
+		entry.StoredAt = now
+		entry.ExpiresAt = now.Add(ttl)
+		entry.MaxSeverity = severity
+		c.order().MoveToFront(entry.elem)
+		return
+	}
+
+	if c.maxEntries > 0 {
+		for len(c.entries) >= c.maxEntries {
+			oldest := c.order().Back()
+			if oldest == nil {
+				break
+			}
+			oldestKey := oldest.Value.(string)
+			c.remove(oldestKey, c.entries[oldestKey])
+			c.counters.evictions.Add(1)
+		}
+	}
+
 	c.entries[key] = &CacheEntry{
-		Data:      data,
-		ExpiresAt: time.Now().Add(ttl),
+		Data:        data,
+		StoredAt:    now,
+		ExpiresAt:   now.Add(ttl),
+		MaxSeverity: severity,
+		elem:        c.order().PushFront(key),
 	}
 }
 
+// expiresAt returns when an entry expires under the TTL for its most severe CVE
+// The caller must hold c.mu.
+func (c *NVDCache) expiresAt(entry *CacheEntry) time.Time {
+	if ttl, ok := c.severityTTLs[entry.MaxSeverity]; ok {
+		return entry.StoredAt.Add(ttl)
+	}
+	return entry.ExpiresAt
+}
+
+// maxSeverity returns the most severe rating among items, ignoring unrated ones
+func maxSeverity(items []CVEItem) Severity {
+	highest := SeverityUnknown
+	for _, item := range items {
+		if severity, err := ParseSeverity(item.Severity); err == nil && severity > highest {
+			highest = severity
+		}
+	}
+	return highest
+}
+
 // Clear removes all cache entries
 func (c *NVDCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters.evictions.Add(uint64(len(c.entries)))
 	c.entries = make(map[string]*CacheEntry)
+	c.lru = list.New()
+}
+
+// order returns the LRU list, creating it for a zero-value cache
+// The caller must hold c.mu.
+func (c *NVDCache) order() *list.List {
+	if c.lru == nil {
+		c.lru = list.New()
+	}
+	return c.lru
+}
+
+// remove deletes an entry from the map and the LRU list
+// The caller must hold c.mu.
+func (c *NVDCache) remove(key string, entry *CacheEntry) {
+	delete(c.entries, key)
+	if entry != nil && entry.elem != nil {
+		c.order().Remove(entry.elem)
+	}
+}
+
+// Stats returns the cache's size, oldest entry, and lookup counters
+func (c *NVDCache) Stats() CacheStats {
+	c.mu.Lock()
+	stats := CacheStats{Size: len(c.entries)}
+	for _, entry := range c.entries {
+		if stats.OldestEntry.IsZero() || entry.StoredAt.Before(stats.OldestEntry) {
+			stats.OldestEntry = entry.StoredAt
+		}
+	}
+	c.mu.Unlock()
+
+	c.counters.snapshot(&stats)
+	return stats
 }
 
 // MatchServicesToCVEs matches services to vulnerabilities based on CPE
@@ -322,10 +770,11 @@ func MatchServicesToCVEs(serviceCPEs map[string][]CPEIdentifier, cvesByCPE map[s
 			if cves, exists := cvesByCPE[cpe.CPE]; exists {
 				for _, cve := range cves {
 					matches = append(matches, VulnMatch{
-						ServiceID: serviceID,
-						CVE:       cve.CVEID,
-						CVSS:      cve.CVSS,
-						Severity:  cve.Severity,
+						ServiceID:  serviceID,
+						CVE:        cve.CVEID,
+						CVSS:       cve.CVSS,
+						Severity:   cve.Severity,
+						Confidence: CPEMatchConfidence(cpe),
 					})
 				}
 			}
@@ -337,10 +786,20 @@ func MatchServicesToCVEs(serviceCPEs map[string][]CPEIdentifier, cvesByCPE map[s
 
 // FilterHighSeverity filters vulnerability matches to only include HIGH and CRITICAL
 func FilterHighSeverity(matches []VulnMatch) []VulnMatch {
+	return FilterBySeverity(matches, SeverityHigh)
+}
+
+// FilterBySeverity filters vulnerability matches to those rated at or above min
+// Matches with a missing or unrecognised severity are dropped.
+func FilterBySeverity(matches []VulnMatch, min Severity) []VulnMatch {
 	filtered := []VulnMatch{}
 
 	for _, match := range matches {
-		if match.Severity == "HIGH" || match.Severity == "CRITICAL" {
+		severity, err := ParseSeverity(match.Severity)
+		if err != nil {
+			continue
+		}
+		if severity >= min {
 			filtered = append(filtered, match)
 		}
 	}
@@ -348,16 +807,23 @@ func FilterHighSeverity(matches []VulnMatch) []VulnMatch {
 	return filtered
 }
 
-// DeduplicateMatches removes duplicate vulnerability matches
+// DeduplicateMatches removes duplicate vulnerability matches per (service, CVE)
+// When a service matched the same CVE through several CPE variants, the highest-confidence
+// match is kept, in the position of the first match seen.
 func DeduplicateMatches(matches []VulnMatch) []VulnMatch {
-	seen := make(map[string]bool)
+	index := make(map[string]int)
 	deduplicated := []VulnMatch{}
 
 	for _, match := range matches {
 		key := fmt.Sprintf("%s:%s", match.ServiceID, match.CVE)
-		if !seen[key] {
-			seen[key] = true
+		i, seen := index[key]
+		if !seen {
+			index[key] = len(deduplicated)
 			deduplicated = append(deduplicated, match)
+			continue
+		}
+		if match.Confidence > deduplicated[i].Confidence {
+			deduplicated[i] = match
 		}
 	}
 
@@ -2,8 +2,20 @@ package enrichment
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 func TestNewNVDClient(t *testing.T) {
@@ -114,6 +126,169 @@ func TestNVDCache(t *testing.T) {
 	})
 }
 
+func TestNVDCache_LRUEviction(t *testing.T) {
+	cache := NewNVDCache(2)
+	testData := []CVEItem{{CVEID: "CVE-2023-1234"}}
+
+	t.Run("Inserting beyond the cap evicts the least recently used entry", func(t *testing.T) {
+		cache.Set("a", testData, 1*time.Hour)
+		cache.Set("b", testData, 1*time.Hour)
+		cache.Set("c", testData, 1*time.Hour)
+
+		if _, ok := cache.Get("a"); ok {
+			t.Error("Get(a) returned true, want it evicted")
+		}
+		for _, key := range []string{"b", "c"} {
+			if _, ok := cache.Get(key); !ok {
+				t.Errorf("Get(%s) returned false, want it cached", key)
+			}
+		}
+		if stats := cache.Stats(); stats.Size != 2 || stats.Evictions != 1 {
+			t.Errorf("Size = %d, Evictions = %d, want 2, 1", stats.Size, stats.Evictions)
+		}
+	})
+
+	t.Run("A recently read entry survives", func(t *testing.T) {
+		cache.Clear()
+		cache.Set("a", testData, 1*time.Hour)
+		cache.Set("b", testData, 1*time.Hour)
+
+		// Reading a makes b the least recently used
+		cache.Get("a")
+		cache.Set("c", testData, 1*time.Hour)
+
+		if _, ok := cache.Get("a"); !ok {
+			t.Error("Get(a) returned false, want it kept after being read")
+		}
+		if _, ok := cache.Get("b"); ok {
+			t.Error("Get(b) returned true, want it evicted")
+		}
+	})
+
+	t.Run("Overwriting a key does not evict", func(t *testing.T) {
+		cache.Clear()
+		cache.Set("a", testData, 1*time.Hour)
+		cache.Set("b", testData, 1*time.Hour)
+		cache.Set("a", testData, 1*time.Hour)
+
+		if stats := cache.Stats(); stats.Size != 2 {
+			t.Errorf("Size = %d, want 2", stats.Size)
+		}
+		if _, ok := cache.Get("b"); !ok {
+			t.Error("Get(b) returned false after overwriting a")
+		}
+	})
+
+	t.Run("TTL is still honored", func(t *testing.T) {
+		cache.Clear()
+		cache.Set("short", testData, 1*time.Nanosecond)
+		time.Sleep(10 * time.Millisecond)
+
+		if _, ok := cache.Get("short"); ok {
+			t.Error("Get() returned true for expired entry")
+		}
+		if stats := cache.Stats(); stats.Size != 0 {
+			t.Errorf("Size = %d, want 0", stats.Size)
+		}
+	})
+}
+
+func TestNVDCache_Stats(t *testing.T) {
+	cache := &NVDCache{
+		entries: make(map[string]*CacheEntry),
+	}
+	testData := []CVEItem{{CVEID: "CVE-2023-1234"}}
+
+	cache.Set("hit", testData, 1*time.Hour)
+	cache.Set("expire", testData, 1*time.Nanosecond)
+	time.Sleep(10 * time.Millisecond)
+
+	cache.Get("hit")
+	cache.Get("hit")
+	cache.Get("missing")
+	cache.Get("expire") // expired: a miss that evicts the entry
+
+	stats := cache.Stats()
+	if stats.Size != 1 {
+		t.Errorf("Size = %d, want 1", stats.Size)
+	}
+	if stats.Hits != 2 {
+		t.Errorf("Hits = %d, want 2", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("Misses = %d, want 2", stats.Misses)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1", stats.Evictions)
+	}
+	if stats.OldestEntry.IsZero() {
+		t.Error("OldestEntry is zero for a non-empty cache")
+	}
+
+	cache.Clear()
+	stats = cache.Stats()
+	if stats.Size != 0 || stats.Evictions != 2 {
+		t.Errorf("after Clear() Size = %d, Evictions = %d, want 0, 2", stats.Size, stats.Evictions)
+	}
+	if !stats.OldestEntry.IsZero() {
+		t.Error("OldestEntry is not zero for an empty cache")
+	}
+}
+
+func TestNVDCache_SeverityTTLs(t *testing.T) {
+	cache := NewNVDCacheWithTTLs(0, map[Severity]time.Duration{
+		SeverityCritical: 20 * time.Millisecond,
+		SeverityLow:      time.Hour,
+	})
+
+	critical := []CVEItem{
+		{CVEID: "CVE-2023-0001", Severity: "LOW"},
+		{CVEID: "CVE-2023-0002", Severity: "CRITICAL"},
+	}
+	low := []CVEItem{{CVEID: "CVE-2023-0003", Severity: "LOW"}}
+	unrated := []CVEItem{{CVEID: "CVE-2023-0004"}}
+
+	cache.Set("critical", critical, nvdCacheTTL)
+	cache.Set("low", low, nvdCacheTTL)
+	cache.Set("unrated", unrated, nvdCacheTTL)
+	cache.Set("empty", []CVEItem{}, nvdCacheTTL)
+
+	if got := cache.entries["critical"].MaxSeverity; got != SeverityCritical {
+		t.Errorf("MaxSeverity of a CRITICAL-containing entry = %s, want CRITICAL", got)
+	}
+	if got := cache.entries["empty"].MaxSeverity; got != SeverityUnknown {
+		t.Errorf("MaxSeverity of an empty entry = %s, want UNKNOWN", got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// The critical-containing entry expires under its shorter TTL
+	if _, ok := cache.Get("critical"); ok {
+		t.Error("Get() returned a critical-containing entry past its severity TTL")
+	}
+	// The low-only entry is still served
+	if _, ok := cache.Get("low"); !ok {
+		t.Error("Get() missed a low-only entry within its severity TTL")
+	}
+	// Severities without an override keep the TTL given to Set
+	for _, key := range []string{"unrated", "empty"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Get(%q) missed an entry within the default TTL", key)
+		}
+	}
+}
+
+func TestNewNVDClientWithConfig_CacheTTLBySeverity(t *testing.T) {
+	client := NewNVDClientWithConfig(NVDConfig{
+		CacheTTLBySeverity: map[Severity]time.Duration{SeverityHigh: 6 * time.Hour, SeverityLow: 0},
+	})
+
+	want := map[Severity]time.Duration{SeverityHigh: 6 * time.Hour}
+	if !reflect.DeepEqual(client.cache.severityTTLs, want) {
+		t.Errorf("severityTTLs = %v, want %v (non-positive TTLs dropped)", client.cache.severityTTLs, want)
+	}
+}
+
 func TestMatchServicesToCVEs(t *testing.T) {
 	serviceCPEs := map[string][]CPEIdentifier{
 		"service1": {
@@ -212,6 +387,308 @@ func TestFilterHighSeverity(t *testing.T) {
 	}
 }
 
+func TestDeduplicateMatches_KeepsHighestConfidence(t *testing.T) {
+	matches := []VulnMatch{
+		{ServiceID: "s1", CVE: "CVE-1", CVSS: 9.8, Severity: "CRITICAL", Confidence: ConfidenceWildcard},
+		{ServiceID: "s2", CVE: "CVE-1", CVSS: 9.8, Severity: "CRITICAL", Confidence: ConfidenceVersioned},
+		{ServiceID: "s1", CVE: "CVE-1", CVSS: 9.8, Severity: "CRITICAL", Confidence: ConfidenceVersioned},
+		{ServiceID: "s1", CVE: "CVE-2", CVSS: 7.5, Severity: "HIGH", Confidence: ConfidenceVersioned},
+		{ServiceID: "s1", CVE: "CVE-2", CVSS: 7.5, Severity: "HIGH", Confidence: ConfidenceWildcard},
+	}
+
+	deduplicated := DeduplicateMatches(matches)
+
+	want := []struct {
+		serviceID  string
+		cve        string
+		confidence float64
+	}{
+		{"s1", "CVE-1", ConfidenceVersioned}, // Upgraded in place from the wildcard match
+		{"s2", "CVE-1", ConfidenceVersioned},
+		{"s1", "CVE-2", ConfidenceVersioned}, // Lower-confidence duplicate ignored
+	}
+
+	if len(deduplicated) != len(want) {
+		t.Fatalf("DeduplicateMatches() returned %d matches, want %d", len(deduplicated), len(want))
+	}
+	for i, w := range want {
+		got := deduplicated[i]
+		if got.ServiceID != w.serviceID || got.CVE != w.cve || got.Confidence != w.confidence {
+			t.Errorf("DeduplicateMatches()[%d] = %s/%s (%.1f), want %s/%s (%.1f)",
+				i, got.ServiceID, got.CVE, got.Confidence, w.serviceID, w.cve, w.confidence)
+		}
+	}
+}
+
+func TestMatchServicesToCVEs_Confidence(t *testing.T) {
+	versioned := CPEIdentifier{Vendor: "nginx", Product: "nginx", Version: "1.24.0", CPE: "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"}
+	wildcard := CPEIdentifier{Vendor: "nginx", Product: "nginx", Version: "*", CPE: "cpe:2.3:a:nginx:nginx:*:*:*:*:*:*:*:*"}
+
+	serviceCPEs := map[string][]CPEIdentifier{
+		"service1": {wildcard, versioned},
+	}
+	cvesByCPE := map[string][]CVEItem{
+		versioned.CPE: {{CVEID: "CVE-2023-1001", CVSS: 7.5, Severity: "HIGH"}},
+		wildcard.CPE:  {{CVEID: "CVE-2023-1001", CVSS: 7.5, Severity: "HIGH"}},
+	}
+
+	deduplicated := DeduplicateMatches(MatchServicesToCVEs(serviceCPEs, cvesByCPE))
+
+	if len(deduplicated) != 1 {
+		t.Fatalf("expected 1 match after dedup, got %d", len(deduplicated))
+	}
+	if deduplicated[0].Confidence != ConfidenceVersioned {
+		t.Errorf("Confidence = %.1f, want %.1f", deduplicated[0].Confidence, ConfidenceVersioned)
+	}
+}
+
+func TestCPEMatchConfidence(t *testing.T) {
+	tests := []struct {
+		name string
+		cpe  CPEIdentifier
+		want float64
+	}{
+		{"exact version", CPEIdentifier{Product: "nginx", Version: "1.24.0"}, ConfidenceVersioned},
+		{"banner version", CPEIdentifier{Product: "openssh", Version: "9.0p1", FromBanner: true}, ConfidenceBanner},
+		{"wildcard version", CPEIdentifier{Product: "nginx", Version: "*"}, ConfidenceWildcard},
+		{"missing version", CPEIdentifier{Product: "nginx"}, ConfidenceWildcard},
+		{"wildcard from banner", CPEIdentifier{Product: "nginx", Version: "*", FromBanner: true}, ConfidenceWildcard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CPEMatchConfidence(tt.cpe); got != tt.want {
+				t.Errorf("CPEMatchConfidence() = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchServicesToCVEs_BannerConfidence(t *testing.T) {
+	serviceCPEs := GenerateCPEBatch([]ServiceInfo{
+		{ID: "fingerprinted", Product: "nginx", Version: "1.24.0"},
+		{ID: "banner", Banner: "nginx/1.24.0"},
+		{ID: "unversioned", Product: "nginx"},
+	})
+	cpe := "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"
+	cvesByCPE := map[string][]CVEItem{
+		cpe:                                     {{CVEID: "CVE-2023-1001", CVSS: 7.5, Severity: "HIGH"}},
+		"cpe:2.3:a:nginx:nginx:*:*:*:*:*:*:*:*": {{CVEID: "CVE-2023-1001", CVSS: 7.5, Severity: "HIGH"}},
+	}
+
+	confidence := map[string]float64{}
+	for _, match := range MatchServicesToCVEs(serviceCPEs, cvesByCPE) {
+		confidence[match.ServiceID] = match.Confidence
+	}
+
+	want := map[string]float64{
+		"fingerprinted": ConfidenceVersioned,
+		"banner":        ConfidenceBanner,
+		"unversioned":   ConfidenceWildcard,
+	}
+	for serviceID, wantConfidence := range want {
+		if confidence[serviceID] != wantConfidence {
+			t.Errorf("%s confidence = %.2f, want %.2f", serviceID, confidence[serviceID], wantConfidence)
+		}
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	matches := []VulnMatch{
+		{ServiceID: "s1", CVE: "CVE-1", CVSS: 9.8, Severity: "CRITICAL"},
+		{ServiceID: "s2", CVE: "CVE-2", CVSS: 7.5, Severity: "HIGH"},
+		{ServiceID: "s3", CVE: "CVE-3", CVSS: 5.3, Severity: "MEDIUM"},
+		{ServiceID: "s4", CVE: "CVE-4", CVSS: 3.1, Severity: "LOW"},
+		{ServiceID: "s5", CVE: "CVE-5", CVSS: 0.0, Severity: ""},
+	}
+
+	tests := []struct {
+		name     string
+		min      Severity
+		wantCVEs []string
+	}{
+		{"low", SeverityLow, []string{"CVE-1", "CVE-2", "CVE-3", "CVE-4"}},
+		{"medium", SeverityMedium, []string{"CVE-1", "CVE-2", "CVE-3"}},
+		{"high", SeverityHigh, []string{"CVE-1", "CVE-2"}},
+		{"critical", SeverityCritical, []string{"CVE-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := FilterBySeverity(matches, tt.min)
+
+			if len(filtered) != len(tt.wantCVEs) {
+				t.Fatalf("FilterBySeverity(%s) returned %d matches, want %d", tt.min, len(filtered), len(tt.wantCVEs))
+			}
+			for i, match := range filtered {
+				if match.CVE != tt.wantCVEs[i] {
+					t.Errorf("FilterBySeverity(%s)[%d] = %s, want %s", tt.min, i, match.CVE, tt.wantCVEs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Severity
+		wantErr bool
+	}{
+		{"LOW", SeverityLow, false},
+		{"medium", SeverityMedium, false},
+		{" High ", SeverityHigh, false},
+		{"CRITICAL", SeverityCritical, false},
+		{"NONE", SeverityUnknown, true},
+		{"", SeverityUnknown, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSeverity(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeverity(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSeverity(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	if !(SeverityLow < SeverityMedium && SeverityMedium < SeverityHigh && SeverityHigh < SeverityCritical) {
+		t.Error("severities are not ordered LOW < MEDIUM < HIGH < CRITICAL")
+	}
+}
+
+func TestParseSeverityTTLs(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[Severity]time.Duration
+		wantErr bool
+	}{
+		{"", map[Severity]time.Duration{}, false},
+		{"CRITICAL=1h", map[Severity]time.Duration{SeverityCritical: time.Hour}, false},
+		{" critical=1h , LOW=72h ", map[Severity]time.Duration{SeverityCritical: time.Hour, SeverityLow: 72 * time.Hour}, false},
+		{"CRITICAL", nil, true},
+		{"SEVERE=1h", nil, true},
+		{"HIGH=soon", nil, true},
+		{"HIGH=0s", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSeverityTTLs(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeverityTTLs(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSeverityTTLs(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverityFromCVSS(t *testing.T) {
+	tests := []struct {
+		score   float64
+		version string
+		want    string
+	}{
+		// CVSS v2: LOW 0.0-3.9, MEDIUM 4.0-6.9, HIGH 7.0-10.0
+		{0.0, CVSSVersion2, "LOW"},
+		{3.9, CVSSVersion2, "LOW"},
+		{4.0, CVSSVersion2, "MEDIUM"},
+		{6.9, CVSSVersion2, "MEDIUM"},
+		{7.0, CVSSVersion2, "HIGH"},
+		{9.0, CVSSVersion2, "HIGH"},
+		{10.0, CVSSVersion2, "HIGH"},
+		{7.5, "2", "HIGH"},
+
+		// CVSS v3: NONE 0.0, LOW 0.1-3.9, MEDIUM 4.0-6.9, HIGH 7.0-8.9, CRITICAL 9.0-10.0
+		{0.0, CVSSVersion31, "NONE"},
+		{0.1, CVSSVersion31, "LOW"},
+		{3.9, CVSSVersion31, "LOW"},
+		{4.0, CVSSVersion31, "MEDIUM"},
+		{6.9, CVSSVersion31, "MEDIUM"},
+		{7.0, CVSSVersion31, "HIGH"},
+		{8.9, CVSSVersion31, "HIGH"},
+		{9.0, CVSSVersion31, "CRITICAL"},
+		{10.0, CVSSVersion31, "CRITICAL"},
+		{9.0, CVSSVersion30, "CRITICAL"},
+		{9.0, "", "CRITICAL"},
+
+		// Out of range
+		{-1, CVSSVersion31, "UNKNOWN"},
+		{10.1, CVSSVersion2, "UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("v%s_%.1f", tt.version, tt.score), func(t *testing.T) {
+			if got := SeverityFromCVSS(tt.score, tt.version); got != tt.want {
+				t.Errorf("SeverityFromCVSS(%.1f, %q) = %s, want %s", tt.score, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertResponse_DerivesMissingSeverity(t *testing.T) {
+	client := NewNVDClient("")
+
+	var resp NVDResponse
+	err := json.Unmarshal([]byte(`{"vulnerabilities":[
+		{"cve":{"id":"CVE-2010-0001","metrics":{"cvssMetricV2":[{"cvssData":{"baseScore":7.5}}]}}},
+		{"cve":{"id":"CVE-2010-0002","metrics":{"cvssMetricV2":[{"cvssData":{"baseScore":9.3},"baseSeverity":"HIGH"}]}}},
+		{"cve":{"id":"CVE-2023-0003","metrics":{"cvssMetricV31":[{"cvssData":{"baseScore":9.8}}]}}},
+		{"cve":{"id":"CVE-2023-0004"}}
+	]}`), &resp)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := map[string]string{
+		"CVE-2010-0001": "HIGH",     // derived from the v2 bands
+		"CVE-2010-0002": "HIGH",     // NVD's severity is kept
+		"CVE-2023-0003": "CRITICAL", // derived from the v3 bands
+		"CVE-2023-0004": "UNKNOWN",  // no metrics at all
+	}
+	for _, item := range client.convertResponse(resp) {
+		if item.Severity != want[item.CVEID] {
+			t.Errorf("%s severity = %s, want %s", item.CVEID, item.Severity, want[item.CVEID])
+		}
+	}
+}
+
+func TestConvertResponse_CapturesCVSSVector(t *testing.T) {
+	client := NewNVDClient("")
+
+	var resp NVDResponse
+	err := json.Unmarshal([]byte(`{"vulnerabilities":[
+		{"cve":{"id":"CVE-2021-44228","metrics":{
+			"cvssMetricV31":[{"cvssData":{"version":"3.1","vectorString":"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H","baseScore":10.0,"baseSeverity":"CRITICAL"}}],
+			"cvssMetricV2":[{"cvssData":{"version":"2.0","vectorString":"AV:N/AC:M/Au:N/C:C/I:C/A:C","baseScore":9.3},"baseSeverity":"HIGH"}]}}},
+		{"cve":{"id":"CVE-2010-0001","metrics":{"cvssMetricV2":[{"cvssData":{"vectorString":"AV:N/AC:L/Au:N/C:P/I:P/A:P","baseScore":7.5}}]}}},
+		{"cve":{"id":"CVE-2023-0004"}}
+	]}`), &resp)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := map[string]string{
+		"CVE-2021-44228": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", // the v3.1 vector wins, like its score
+		"CVE-2010-0001":  "AV:N/AC:L/Au:N/C:P/I:P/A:P",
+		"CVE-2023-0004":  "", // not scored yet
+	}
+	items := client.convertResponse(resp)
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for _, item := range items {
+		if item.CVSSVector != want[item.CVEID] {
+			t.Errorf("%s vector = %q, want %q", item.CVEID, item.CVSSVector, want[item.CVEID])
+		}
+	}
+}
+
 func TestDeduplicateMatches(t *testing.T) {
 	matches := []VulnMatch{
 		{ServiceID: "s1", CVE: "CVE-1", CVSS: 9.8, Severity: "CRITICAL"},
@@ -260,17 +737,20 @@ func TestConvertResponse(t *testing.T) {
 						CVSSData struct {
 							BaseScore    float64 `json:"baseScore"`
 							BaseSeverity string  `json:"baseSeverity"`
+							VectorString string  `json:"vectorString"`
 						} `json:"cvssData"`
 					} `json:"cvssMetricV31"`
 					CVSSMetricV30 []struct {
 						CVSSData struct {
 							BaseScore    float64 `json:"baseScore"`
 							BaseSeverity string  `json:"baseSeverity"`
+							VectorString string  `json:"vectorString"`
 						} `json:"cvssData"`
 					} `json:"cvssMetricV30"`
 					CVSSMetricV2 []struct {
 						CVSSData struct {
-							BaseScore float64 `json:"baseScore"`
+							BaseScore    float64 `json:"baseScore"`
+							VectorString string  `json:"vectorString"`
 						} `json:"cvssData"`
 						BaseSeverity string `json:"baseSeverity"`
 					} `json:"cvssMetricV2"`
@@ -302,17 +782,20 @@ func TestConvertResponse(t *testing.T) {
 							CVSSData struct {
 								BaseScore    float64 `json:"baseScore"`
 								BaseSeverity string  `json:"baseSeverity"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 						} `json:"cvssMetricV31"`
 						CVSSMetricV30 []struct {
 							CVSSData struct {
 								BaseScore    float64 `json:"baseScore"`
 								BaseSeverity string  `json:"baseSeverity"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 						} `json:"cvssMetricV30"`
 						CVSSMetricV2 []struct {
 							CVSSData struct {
-								BaseScore float64 `json:"baseScore"`
+								BaseScore    float64 `json:"baseScore"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 							BaseSeverity string `json:"baseSeverity"`
 						} `json:"cvssMetricV2"`
@@ -343,17 +826,20 @@ func TestConvertResponse(t *testing.T) {
 							CVSSData struct {
 								BaseScore    float64 `json:"baseScore"`
 								BaseSeverity string  `json:"baseSeverity"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 						} `json:"cvssMetricV31"`
 						CVSSMetricV30 []struct {
 							CVSSData struct {
 								BaseScore    float64 `json:"baseScore"`
 								BaseSeverity string  `json:"baseSeverity"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 						} `json:"cvssMetricV30"`
 						CVSSMetricV2 []struct {
 							CVSSData struct {
-								BaseScore float64 `json:"baseScore"`
+								BaseScore    float64 `json:"baseScore"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 							BaseSeverity string `json:"baseSeverity"`
 						} `json:"cvssMetricV2"`
@@ -362,12 +848,14 @@ func TestConvertResponse(t *testing.T) {
 							CVSSData struct {
 								BaseScore    float64 `json:"baseScore"`
 								BaseSeverity string  `json:"baseSeverity"`
+								VectorString string  `json:"vectorString"`
 							} `json:"cvssData"`
 						}{
 							{
 								CVSSData: struct {
 									BaseScore    float64 `json:"baseScore"`
 									BaseSeverity string  `json:"baseSeverity"`
+									VectorString string  `json:"vectorString"`
 								}{
 									BaseScore:    9.8,
 									BaseSeverity: "CRITICAL",
@@ -447,6 +935,141 @@ func TestConvertResponse(t *testing.T) {
 	}
 }
 
+// newTestNVDServer serves one CVE per CPE after an optional delay, failing the CPEs in fail
+func newTestNVDServer(t testing.TB, delay time.Duration, fail map[string]bool) (*httptest.Server, *sync.Map, *int32) {
+	t.Helper()
+
+	var queried sync.Map
+	var inFlight, maxInFlight int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+
+		cpe := r.URL.Query().Get("cpeName")
+		queried.Store(cpe, true)
+		time.Sleep(delay)
+
+		if fail[cpe] {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"vulnerabilities":[{"cve":{"id":"CVE-%s"}}]}`, cpe[len(cpe)-1:])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &queried, &maxInFlight
+}
+
+func newTestNVDClient(baseURL string, limiter *rate.Limiter) *NVDClient {
+	client := NewNVDClient("")
+	client.baseURL = baseURL
+	client.limiter = limiter
+	return client
+}
+
+func testCPEs(n int) []string {
+	cpes := make([]string, n)
+	for i := range cpes {
+		cpes[i] = fmt.Sprintf("cpe:2.3:a:vendor:product%d:1.0:*:*:*:*:*:*:%d", i, i%10)
+	}
+	return cpes
+}
+
+func TestQueryByCPEBatch_QueriesAllCPEsConcurrently(t *testing.T) {
+	server, queried, maxInFlight := newTestNVDServer(t, 20*time.Millisecond, nil)
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+
+	cpes := testCPEs(20)
+	results, err := client.QueryByCPEBatch(context.Background(), cpes)
+	if err != nil {
+		t.Fatalf("QueryByCPEBatch() error = %v", err)
+	}
+
+	if len(results) != len(cpes) {
+		t.Errorf("QueryByCPEBatch() returned %d results, want %d", len(results), len(cpes))
+	}
+	for _, cpe := range cpes {
+		if _, ok := queried.Load(cpe); !ok {
+			t.Errorf("CPE %s was never queried", cpe)
+		}
+		if len(results[cpe]) != 1 {
+			t.Errorf("results[%s] has %d CVEs, want 1", cpe, len(results[cpe]))
+		}
+	}
+
+	if got := atomic.LoadInt32(maxInFlight); got < 2 || got > nvdBatchWorkers {
+		t.Errorf("max concurrent requests = %d, want between 2 and %d", got, nvdBatchWorkers)
+	}
+}
+
+func TestQueryByCPEBatch_RespectsLimiter(t *testing.T) {
+	server, _, _ := newTestNVDServer(t, 0, nil)
+
+	// One request every 25ms with no burst beyond the first
+	interval := 25 * time.Millisecond
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Every(interval), 1))
+
+	cpes := testCPEs(6)
+	start := time.Now()
+	results, err := client.QueryByCPEBatch(context.Background(), cpes)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("QueryByCPEBatch() error = %v", err)
+	}
+	if len(results) != len(cpes) {
+		t.Errorf("QueryByCPEBatch() returned %d results, want %d", len(results), len(cpes))
+	}
+
+	// The first request uses the burst token; each later one waits a full interval
+	if minElapsed := time.Duration(len(cpes)-1) * interval; elapsed < minElapsed {
+		t.Errorf("batch finished in %v, limiter should have held it to at least %v", elapsed, minElapsed)
+	}
+}
+
+func TestQueryByCPEBatch_PartialFailure(t *testing.T) {
+	cpes := testCPEs(4)
+	server, _, _ := newTestNVDServer(t, 0, map[string]bool{cpes[1]: true, cpes[3]: true})
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+
+	results, err := client.QueryByCPEBatch(context.Background(), cpes)
+
+	var batchErr *BatchQueryError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("QueryByCPEBatch() error = %v, want *BatchQueryError", err)
+	}
+	if len(batchErr.Failed) != 2 || batchErr.Total != 4 {
+		t.Errorf("BatchQueryError = %d of %d failed, want 2 of 4", len(batchErr.Failed), batchErr.Total)
+	}
+	if _, ok := batchErr.Failed[cpes[1]]; !ok {
+		t.Errorf("BatchQueryError missing failed CPE %s", cpes[1])
+	}
+	if len(results) != 2 {
+		t.Errorf("QueryByCPEBatch() returned %d results, want 2 successful lookups", len(results))
+	}
+}
+
+func BenchmarkQueryByCPEBatch(b *testing.B) {
+	server, _, _ := newTestNVDServer(b, 5*time.Millisecond, nil)
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+	cpes := testCPEs(50)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.cache.Clear()
+		if _, err := client.QueryByCPEBatch(context.Background(), cpes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // Note: We skip testing actual NVD API calls to avoid rate limiting and external dependencies
 // In production, you would use mocks or record/replay HTTP interactions
 func TestQueryByCPE_Integration(t *testing.T) {
@@ -480,3 +1103,302 @@ func TestQueryByCPE_Integration(t *testing.T) {
 		t.Errorf("Cached result length = %d, want %d", len(cachedItems), len(items))
 	}
 }
+
+// newPagedNVDServer serves total CVEs for any CPE, oldest first as NVD does, honoring
+// startIndex and resultsPerPage, and records the page sizes requested
+func newPagedNVDServer(t testing.TB, total int) (*httptest.Server, *[]int) {
+	t.Helper()
+	return newPagedNVDServerReportingTotal(t, total, true)
+}
+
+// newPagedNVDServerReportingTotal is newPagedNVDServer, leaving totalResults out of
+// its responses unless reportTotal is set
+func newPagedNVDServerReportingTotal(t testing.TB, total int, reportTotal bool) (*httptest.Server, *[]int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	pages := []int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("resultsPerPage"))
+
+		mu.Lock()
+		pages = append(pages, perPage)
+		mu.Unlock()
+
+		vulns := []string{}
+		for i := start; i < total && i < start+perPage; i++ {
+			vulns = append(vulns, fmt.Sprintf(`{"cve":{"id":"CVE-2024-%04d"}}`, i))
+		}
+		if !reportTotal {
+			fmt.Fprintf(w, `{"resultsPerPage":%d,"startIndex":%d,"vulnerabilities":[%s]}`,
+				len(vulns), start, strings.Join(vulns, ","))
+			return
+		}
+		fmt.Fprintf(w, `{"resultsPerPage":%d,"startIndex":%d,"totalResults":%d,"vulnerabilities":[%s]}`,
+			len(vulns), start, total, strings.Join(vulns, ","))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &pages
+}
+
+func TestQueryByCPEWithOptions_Paging(t *testing.T) {
+	const cpe = "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*"
+
+	tests := []struct {
+		name        string
+		total       int
+		reportTotal bool
+		opts        NVDQueryOptions
+		wantCVEs    int
+		wantPages   []int
+	}{
+		{
+			name:        "keeps the newest max results",
+			total:       500,
+			reportTotal: true,
+			opts:        NVDQueryOptions{MaxResults: 200, ResultsPerPage: 75},
+			wantCVEs:    200,
+			wantPages:   []int{75, 75, 75, 50},
+		},
+		{
+			name:        "stops at the last page",
+			total:       120,
+			reportTotal: true,
+			opts:        NVDQueryOptions{MaxResults: 1000, ResultsPerPage: 50},
+			wantCVEs:    120,
+			wantPages:   []int{50, 50, 20},
+		},
+		{
+			name:        "page size capped at the NVD ceiling",
+			total:       3000,
+			reportTotal: true,
+			opts:        NVDQueryOptions{MaxResults: 2500, ResultsPerPage: 5000},
+			wantCVEs:    2500,
+			wantPages:   []int{NVDMaxResultsPerPage, 1000},
+		},
+		{
+			name:        "no results",
+			total:       0,
+			reportTotal: true,
+			opts:        NVDQueryOptions{MaxResults: 100, ResultsPerPage: 50},
+			wantCVEs:    0,
+			wantPages:   []int{50},
+		},
+		{
+			name:      "missing total reads every page",
+			total:     120,
+			opts:      NVDQueryOptions{MaxResults: 100, ResultsPerPage: 50},
+			wantCVEs:  100,
+			wantPages: []int{50, 50, 50},
+		},
+		{
+			name:      "missing total on a single short page",
+			total:     30,
+			opts:      NVDQueryOptions{MaxResults: 100, ResultsPerPage: 50},
+			wantCVEs:  30,
+			wantPages: []int{50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, pages := newPagedNVDServerReportingTotal(t, tt.total, tt.reportTotal)
+			client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+
+			items, err := client.QueryByCPEWithOptions(context.Background(), cpe, tt.opts)
+			if err != nil {
+				t.Fatalf("QueryByCPEWithOptions() error = %v", err)
+			}
+			if len(items) != tt.wantCVEs {
+				t.Errorf("got %d CVEs, want %d", len(items), tt.wantCVEs)
+			}
+			if !reflect.DeepEqual(*pages, tt.wantPages) {
+				t.Errorf("requested page sizes %v, want %v", *pages, tt.wantPages)
+			}
+			// The newest CVEs are kept, in order
+			for i, item := range items {
+				if want := fmt.Sprintf("CVE-2024-%04d", tt.total-tt.wantCVEs+i); item.CVEID != want {
+					t.Fatalf("CVE %d = %s, want %s", i, item.CVEID, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryByCPE_UsesClientDefaults(t *testing.T) {
+	server, pages := newPagedNVDServer(t, 50)
+	client := NewNVDClientWithConfig(NVDConfig{MaxResults: 30, ResultsPerPage: 20})
+	client.baseURL = server.URL
+	client.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	items, err := client.QueryByCPE(context.Background(), "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*")
+	if err != nil {
+		t.Fatalf("QueryByCPE() error = %v", err)
+	}
+	if len(items) != 30 {
+		t.Errorf("got %d CVEs, want 30", len(items))
+	}
+	if !reflect.DeepEqual(*pages, []int{20, 20, 10}) {
+		t.Errorf("requested page sizes %v, want [20 20 10]", *pages)
+	}
+
+	// A larger max is cached separately from the default lookup
+	items, err = client.QueryByCPEWithOptions(context.Background(), "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*", NVDQueryOptions{MaxResults: 50})
+	if err != nil {
+		t.Fatalf("QueryByCPEWithOptions() error = %v", err)
+	}
+	if len(items) != 50 {
+		t.Errorf("got %d CVEs with MaxResults 50, want 50", len(items))
+	}
+}
+
+func TestNewNVDClientWithConfig_QueryDefaults(t *testing.T) {
+	client := NewNVDClient("")
+	if client.query.MaxResults != DefaultNVDMaxResults || client.query.ResultsPerPage != DefaultNVDResultsPerPage {
+		t.Errorf("default query options = %+v", client.query)
+	}
+
+	client = NewNVDClientWithConfig(NVDConfig{ResultsPerPage: 10000})
+	if client.query.ResultsPerPage != NVDMaxResultsPerPage {
+		t.Errorf("ResultsPerPage = %d, want capped at %d", client.query.ResultsPerPage, NVDMaxResultsPerPage)
+	}
+}
+
+func TestQueryByCPE_ForceRefresh(t *testing.T) {
+	const cpe = "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*"
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"totalResults":1,"vulnerabilities":[{"cve":{"id":"CVE-2024-0002"}}]}`)
+	}))
+	defer server.Close()
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+
+	// Warm the cache with stale data
+	client.cache.Set(cpe, []CVEItem{{CVEID: "CVE-2024-0001"}}, time.Hour)
+
+	items, err := client.QueryByCPE(context.Background(), cpe)
+	if err != nil {
+		t.Fatalf("QueryByCPE() error = %v", err)
+	}
+	if items[0].CVEID != "CVE-2024-0001" || atomic.LoadInt32(&requests) != 0 {
+		t.Fatalf("unforced lookup was not served from cache")
+	}
+
+	results, err := client.QueryByCPEBatchWithOptions(context.Background(), []string{cpe}, NVDQueryOptions{ForceRefresh: true})
+	if err != nil {
+		t.Fatalf("QueryByCPEBatchWithOptions() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("forced lookup made %d requests, want 1", got)
+	}
+	if len(results[cpe]) != 1 || results[cpe][0].CVEID != "CVE-2024-0002" {
+		t.Errorf("forced lookup returned %+v, want the fresh CVE", results[cpe])
+	}
+
+	// The fresh result is written back to the cache
+	cached, ok := client.cache.Get(cpe)
+	if !ok || cached[0].CVEID != "CVE-2024-0002" {
+		t.Errorf("cache holds %+v after forced lookup, want the fresh CVE", cached)
+	}
+}
+
+func TestQueryByCPE_ErrorTypes(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusForbidden, ErrRateLimited},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusServiceUnavailable, ErrUpstreamUnavailable},
+		{http.StatusInternalServerError, ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+			_, err := client.QueryByCPE(context.Background(), "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("QueryByCPE() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("bad request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+		_, err := client.QueryByCPE(context.Background(), "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*")
+		if err == nil || IsRetryable(err) || errors.Is(err, ErrNotFound) {
+			t.Errorf("QueryByCPE() error = %v, want a non-sentinel error", err)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+		_, err := client.QueryByCPE(context.Background(), "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*")
+		if !errors.Is(err, ErrUpstreamUnavailable) {
+			t.Errorf("QueryByCPE() error = %v, want %v", err, ErrUpstreamUnavailable)
+		}
+	})
+
+	t.Run("limiter", func(t *testing.T) {
+		// The only token is spent, so the next one can't arrive before the deadline
+		limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+		limiter.Allow()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		client := newTestNVDClient("http://127.0.0.1:0", limiter)
+		_, err := client.QueryByCPE(ctx, "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*")
+		if !errors.Is(err, ErrRateLimited) {
+			t.Errorf("QueryByCPE() error = %v, want %v", err, ErrRateLimited)
+		}
+	})
+}
+
+func TestQueryByCPEBatch_ErrorTypes(t *testing.T) {
+	cpes := testCPEs(3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cpeName") == cpes[0] {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newTestNVDClient(server.URL, rate.NewLimiter(rate.Inf, 1))
+	_, err := client.QueryByCPEBatch(context.Background(), cpes)
+
+	var batchErr *BatchQueryError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("QueryByCPEBatch() error = %v, want *BatchQueryError", err)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("batch error does not match %v", ErrRateLimited)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("batch error does not match %v", ErrNotFound)
+	}
+	if errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("batch error unexpectedly matches %v", ErrUpstreamUnavailable)
+	}
+}
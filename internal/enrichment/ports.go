@@ -0,0 +1,176 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfidencePortGuess is the confidence of a service named only from its port
+// number, below every CPE match confidence: nothing on the wire confirmed it.
+const ConfidencePortGuess = 0.25
+
+// portKey identifies a transport port, e.g. {22, "tcp"}
+type portKey struct {
+	number   int
+	protocol string
+}
+
+// DefaultPortServices maps well-known ports to their IANA service names
+// It covers the ports scanners most often report; ports without an entry are
+// not guessed.
+var DefaultPortServices = map[portKey]string{
+	{20, "tcp"}:    "ftp-data",
+	{21, "tcp"}:    "ftp",
+	{22, "tcp"}:    "ssh",
+	{23, "tcp"}:    "telnet",
+	{25, "tcp"}:    "smtp",
+	{53, "tcp"}:    "domain",
+	{53, "udp"}:    "domain",
+	{67, "udp"}:    "bootps",
+	{69, "udp"}:    "tftp",
+	{80, "tcp"}:    "http",
+	{88, "tcp"}:    "kerberos",
+	{110, "tcp"}:   "pop3",
+	{111, "tcp"}:   "sunrpc",
+	{111, "udp"}:   "sunrpc",
+	{123, "udp"}:   "ntp",
+	{135, "tcp"}:   "msrpc",
+	{137, "udp"}:   "netbios-ns",
+	{139, "tcp"}:   "netbios-ssn",
+	{143, "tcp"}:   "imap",
+	{161, "udp"}:   "snmp",
+	{179, "tcp"}:   "bgp",
+	{389, "tcp"}:   "ldap",
+	{443, "tcp"}:   "https",
+	{443, "udp"}:   "https",
+	{445, "tcp"}:   "microsoft-ds",
+	{465, "tcp"}:   "submissions",
+	{500, "udp"}:   "isakmp",
+	{514, "udp"}:   "syslog",
+	{587, "tcp"}:   "submission",
+	{631, "tcp"}:   "ipp",
+	{636, "tcp"}:   "ldaps",
+	{873, "tcp"}:   "rsync",
+	{993, "tcp"}:   "imaps",
+	{995, "tcp"}:   "pop3s",
+	{1080, "tcp"}:  "socks",
+	{1194, "udp"}:  "openvpn",
+	{1433, "tcp"}:  "ms-sql-s",
+	{1723, "tcp"}:  "pptp",
+	{1883, "tcp"}:  "mqtt",
+	{1900, "udp"}:  "ssdp",
+	{2049, "tcp"}:  "nfs",
+	{2375, "tcp"}:  "docker",
+	{2376, "tcp"}:  "docker-s",
+	{3306, "tcp"}:  "mysql",
+	{3389, "tcp"}:  "ms-wbt-server",
+	{5060, "udp"}:  "sip",
+	{5060, "tcp"}:  "sip",
+	{5353, "udp"}:  "mdns",
+	{5432, "tcp"}:  "postgresql",
+	{5671, "tcp"}:  "amqps",
+	{5672, "tcp"}:  "amqp",
+	{5900, "tcp"}:  "rfb",
+	{5984, "tcp"}:  "couchdb",
+	{6379, "tcp"}:  "redis",
+	{8080, "tcp"}:  "http-alt",
+	{8883, "tcp"}:  "secure-mqtt",
+	{11211, "tcp"}: "memcache",
+	{27017, "tcp"}: "mongodb",
+}
+
+// portServiceOverrides holds port-to-service mappings loaded from config
+// Entries take precedence over DefaultPortServices.
+var (
+	portServiceOverridesMu sync.RWMutex
+	portServiceOverrides   = map[portKey]string{}
+)
+
+// GuessServiceName returns the service conventionally run on a port, or ""
+// when the port has no well-known service. An empty protocol means tcp.
+func GuessServiceName(port int, protocol string) string {
+	key := portKey{number: port, protocol: normalizeProtocol(protocol)}
+
+	portServiceOverridesMu.RLock()
+	name, exists := portServiceOverrides[key]
+	portServiceOverridesMu.RUnlock()
+	if exists {
+		return name
+	}
+
+	return DefaultPortServices[key]
+}
+
+// LoadPortServiceMap merges a port-to-service mapping file into the lookup
+// The file is a flat YAML (.yaml, .yml) or JSON (.json) object of "port/protocol"
+// (or a bare port, meaning tcp) to service name, e.g. {"8443/tcp": "https"}.
+// Loaded entries override built-ins and earlier loads; an empty name stops a
+// port from being guessed. Returns the number of mappings loaded.
+func LoadPortServiceMap(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read port service map: %w", err)
+	}
+
+	mapping := map[string]string{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &mapping)
+	case ".json":
+		err = json.Unmarshal(data, &mapping)
+	default:
+		return 0, fmt.Errorf("unsupported port service map format %q (use .yaml, .yml or .json)", filepath.Ext(path))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse port service map %s: %w", path, err)
+	}
+
+	// Validate every entry before merging, so a bad file leaves the lookup unchanged
+	normalized := make(map[portKey]string, len(mapping))
+	for port, name := range mapping {
+		key, err := parsePortKey(port)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port service map entry %q: %w", port, err)
+		}
+		normalized[key] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	portServiceOverridesMu.Lock()
+	defer portServiceOverridesMu.Unlock()
+
+	for key, name := range normalized {
+		portServiceOverrides[key] = name
+	}
+
+	return len(normalized), nil
+}
+
+// parsePortKey parses "443/tcp", "53/udp" or a bare "443" (tcp)
+func parsePortKey(s string) (portKey, error) {
+	number, protocol, _ := strings.Cut(strings.TrimSpace(s), "/")
+	port, err := strconv.Atoi(number)
+	if err != nil || port < 1 || port > 65535 {
+		return portKey{}, fmt.Errorf("port must be between 1 and 65535")
+	}
+	protocol = normalizeProtocol(protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return portKey{}, fmt.Errorf("protocol must be tcp or udp")
+	}
+	return portKey{number: port, protocol: protocol}, nil
+}
+
+// normalizeProtocol lowercases a transport protocol, defaulting to tcp
+func normalizeProtocol(protocol string) string {
+	protocol = strings.ToLower(strings.TrimSpace(protocol))
+	if protocol == "" {
+		return "tcp"
+	}
+	return protocol
+}
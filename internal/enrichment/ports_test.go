@@ -0,0 +1,96 @@
+package enrichment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// resetPortServiceOverrides clears loaded port mappings after a test
+func resetPortServiceOverrides(t *testing.T) {
+	t.Cleanup(func() {
+		portServiceOverridesMu.Lock()
+		portServiceOverrides = map[portKey]string{}
+		portServiceOverridesMu.Unlock()
+	})
+}
+
+func TestGuessServiceName(t *testing.T) {
+	tests := []struct {
+		port     int
+		protocol string
+		want     string
+	}{
+		{22, "tcp", "ssh"},
+		{443, "tcp", "https"},
+		{3306, "tcp", "mysql"},
+		{80, "", "http"},
+		{53, "UDP", "domain"},
+		{161, "udp", "snmp"},
+		{22, "udp", ""},
+		{31337, "tcp", ""},
+		{0, "tcp", ""},
+	}
+
+	for _, tt := range tests {
+		if got := GuessServiceName(tt.port, tt.protocol); got != tt.want {
+			t.Errorf("GuessServiceName(%d, %q) = %q, want %q", tt.port, tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPortServiceMap(t *testing.T) {
+	resetPortServiceOverrides(t)
+
+	path := filepath.Join(t.TempDir(), "ports.yaml")
+	content := "8443/tcp: HTTPS\n9000: minio\n\"22\": \"\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write port map: %v", err)
+	}
+
+	loaded, err := LoadPortServiceMap(path)
+	if err != nil {
+		t.Fatalf("LoadPortServiceMap() error = %v", err)
+	}
+	if loaded != 3 {
+		t.Errorf("LoadPortServiceMap() loaded %d mappings, want 3", loaded)
+	}
+
+	if got := GuessServiceName(8443, "tcp"); got != "https" {
+		t.Errorf("GuessServiceName(8443) = %q, want https", got)
+	}
+	if got := GuessServiceName(9000, "tcp"); got != "minio" {
+		t.Errorf("GuessServiceName(9000) = %q, want minio (a bare port means tcp)", got)
+	}
+	if got := GuessServiceName(22, "tcp"); got != "" {
+		t.Errorf("GuessServiceName(22) = %q, want empty (an empty name disables the guess)", got)
+	}
+	if got := GuessServiceName(443, "tcp"); got != "https" {
+		t.Errorf("GuessServiceName(443) = %q, want the built-in https", got)
+	}
+}
+
+func TestLoadPortServiceMap_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad port":     `{"http": "http"}`,
+		"out of range": `{"70000/tcp": "x"}`,
+		"bad protocol": `{"80/sctp": "http"}`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			resetPortServiceOverrides(t)
+
+			path := filepath.Join(t.TempDir(), "ports.json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("failed to write port map: %v", err)
+			}
+			if _, err := LoadPortServiceMap(path); err == nil {
+				t.Error("LoadPortServiceMap() error = nil, want an error")
+			}
+			if got := GuessServiceName(80, "tcp"); got != "http" {
+				t.Errorf("a rejected file changed the lookup: GuessServiceName(80) = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package enrichment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Severity is an ordered CVSS severity rating, so thresholds can be compared with <
+type Severity int
+
+// Severity levels in ascending order; SeverityUnknown sorts below every rated severity
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// CVSS versions accepted by SeverityFromCVSS
+const (
+	CVSSVersion2  = "2.0"
+	CVSSVersion30 = "3.0"
+	CVSSVersion31 = "3.1"
+)
+
+// DefaultMinSeverity is the threshold used when none is configured
+const DefaultMinSeverity = SeverityHigh
+
+// String returns the NVD spelling of the severity (LOW, MEDIUM, HIGH, CRITICAL)
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "LOW"
+	case SeverityMedium:
+		return "MEDIUM"
+	case SeverityHigh:
+		return "HIGH"
+	case SeverityCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseSeverity parses an NVD severity string, ignoring case
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "LOW":
+		return SeverityLow, nil
+	case "MEDIUM":
+		return SeverityMedium, nil
+	case "HIGH":
+		return SeverityHigh, nil
+	case "CRITICAL":
+		return SeverityCritical, nil
+	default:
+		return SeverityUnknown, fmt.Errorf("invalid severity: %q (must be one of: LOW, MEDIUM, HIGH, CRITICAL)", s)
+	}
+}
+
+// ParseSeverityTTLs parses a comma-separated list of SEVERITY=duration pairs, e.g.
+// "CRITICAL=1h,HIGH=6h", into per-severity cache TTLs. An empty string yields none.
+func ParseSeverityTTLs(s string) (map[Severity]time.Duration, error) {
+	ttls := make(map[Severity]time.Duration)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid severity TTL %q: expected SEVERITY=duration", pair)
+		}
+		severity, err := ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid TTL for %s: %q (must be a positive duration such as 6h)", severity, value)
+		}
+		ttls[severity] = ttl
+	}
+	return ttls, nil
+}
+
+// SeverityFromCVSS derives the NVD severity string for a base score when the
+// metric doesn't carry one, as CVSS v2 metrics often don't.
+// v2 bands: LOW 0.0-3.9, MEDIUM 4.0-6.9, HIGH 7.0-10.0.
+// v3 bands: NONE 0.0, LOW 0.1-3.9, MEDIUM 4.0-6.9, HIGH 7.0-8.9, CRITICAL 9.0-10.0.
+// Versions starting with "2" use the v2 bands and anything else the v3 bands;
+// scores outside 0-10 are UNKNOWN.
+func SeverityFromCVSS(score float64, version string) string {
+	if score < 0 || score > 10 {
+		return SeverityUnknown.String()
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(version), "2") {
+		switch {
+		case score >= 7.0:
+			return SeverityHigh.String()
+		case score >= 4.0:
+			return SeverityMedium.String()
+		default:
+			return SeverityLow.String()
+		}
+	}
+
+	switch {
+	case score >= 9.0:
+		return SeverityCritical.String()
+	case score >= 7.0:
+		return SeverityHigh.String()
+	case score >= 4.0:
+		return SeverityMedium.String()
+	case score > 0:
+		return SeverityLow.String()
+	default:
+		return "NONE"
+	}
+}
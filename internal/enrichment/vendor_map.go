@@ -0,0 +1,82 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// vendorOverrides holds product-to-vendor mappings loaded from config
+// Entries take precedence over ProductVendorMap, which stays the built-in default.
+var (
+	vendorOverridesMu sync.RWMutex
+	vendorOverrides   = map[string]string{}
+)
+
+// LoadVendorMap merges a product-to-vendor mapping file into the vendor lookup
+// The file is a flat YAML (.yaml, .yml) or JSON (.json) object of product name to NVD vendor,
+// e.g. {"acme-gateway": "acme_corp"}. Loaded entries override built-ins and earlier loads.
+// Returns the number of mappings loaded.
+func LoadVendorMap(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read vendor map: %w", err)
+	}
+
+	mapping := map[string]string{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &mapping)
+	case ".json":
+		err = json.Unmarshal(data, &mapping)
+	default:
+		return 0, fmt.Errorf("unsupported vendor map format %q (use .yaml, .yml or .json)", filepath.Ext(path))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse vendor map %s: %w", path, err)
+	}
+
+	// Validate every entry before merging, so a bad file leaves the lookup unchanged
+	normalized := make(map[string]string, len(mapping))
+	for product, vendor := range mapping {
+		product = strings.ToLower(strings.TrimSpace(product))
+		vendor = strings.TrimSpace(vendor)
+		if product == "" || vendor == "" {
+			return 0, fmt.Errorf("invalid vendor map entry %q: %q (product and vendor are required)", product, vendor)
+		}
+		normalized[product] = vendor
+	}
+
+	vendorOverridesMu.Lock()
+	defer vendorOverridesMu.Unlock()
+
+	for product, vendor := range normalized {
+		vendorOverrides[product] = vendor
+	}
+
+	return len(normalized), nil
+}
+
+// lookupVendor returns the vendor for a normalized product name, preferring loaded overrides
+func lookupVendor(product string) (string, bool) {
+	if vendor, exists := vendorOverride(product); exists {
+		return vendor, true
+	}
+
+	vendor, exists := ProductVendorMap[product]
+	return vendor, exists
+}
+
+// vendorOverride returns the loaded override for a normalized product name, if any
+func vendorOverride(product string) (string, bool) {
+	vendorOverridesMu.RLock()
+	defer vendorOverridesMu.RUnlock()
+
+	vendor, exists := vendorOverrides[product]
+	return vendor, exists
+}
@@ -0,0 +1,107 @@
+package enrichment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// resetVendorOverrides clears loaded vendor mappings after a test
+func resetVendorOverrides(t *testing.T) {
+	t.Cleanup(func() {
+		vendorOverridesMu.Lock()
+		vendorOverrides = map[string]string{}
+		vendorOverridesMu.Unlock()
+	})
+}
+
+func writeVendorMap(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write vendor map: %v", err)
+	}
+	return path
+}
+
+func TestLoadVendorMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "yaml",
+			file:    "vendors.yaml",
+			content: "acme-gateway: acme_corp\nopenssh: openssh_project\n",
+		},
+		{
+			name:    "json",
+			file:    "vendors.json",
+			content: `{"ACME-Gateway": "acme_corp", "openssh": "openssh_project"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVendorOverrides(t)
+
+			loaded, err := LoadVendorMap(writeVendorMap(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("LoadVendorMap() error = %v", err)
+			}
+			if loaded != 2 {
+				t.Errorf("LoadVendorMap() loaded %d mappings, want 2", loaded)
+			}
+
+			// New product picks up the configured vendor
+			if got := normalizeVendor("acme-gateway"); got != "acme_corp" {
+				t.Errorf("normalizeVendor(acme-gateway) = %q, want acme_corp", got)
+			}
+			// Override beats the built-in mapping
+			if got := normalizeVendor("OpenSSH"); got != "openssh_project" {
+				t.Errorf("normalizeVendor(OpenSSH) = %q, want openssh_project", got)
+			}
+			// Built-ins that weren't overridden still apply
+			if got := normalizeVendor("tomcat"); got != "apache" {
+				t.Errorf("normalizeVendor(tomcat) = %q, want apache", got)
+			}
+			// Unknown products fall back to the product name
+			if got := normalizeVendor("unknownd"); got != "unknownd" {
+				t.Errorf("normalizeVendor(unknownd) = %q, want unknownd", got)
+			}
+
+			cpes := GenerateCPE(ServiceInfo{Product: "acme-gateway", Version: "2.1"})
+			if len(cpes) == 0 || cpes[0].Vendor != "acme_corp" {
+				t.Errorf("GenerateCPE() = %+v, want vendor acme_corp", cpes)
+			}
+		})
+	}
+}
+
+func TestLoadVendorMap_Errors(t *testing.T) {
+	resetVendorOverrides(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.yaml")},
+		{"unsupported extension", writeVendorMap(t, "vendors.txt", "acme: acme_corp")},
+		{"malformed yaml", writeVendorMap(t, "vendors.yaml", "acme: [unclosed")},
+		{"empty vendor", writeVendorMap(t, "vendors.json", `{"acme-gateway": "acme_corp", "other": ""}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadVendorMap(tt.path); err == nil {
+				t.Error("LoadVendorMap() expected error, got nil")
+			}
+		})
+	}
+
+	// A rejected file must not partially apply
+	if got := normalizeVendor("acme-gateway"); got != "acme-gateway" {
+		t.Errorf("normalizeVendor(acme-gateway) = %q after failed loads, want the product name", got)
+	}
+}
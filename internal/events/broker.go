@@ -0,0 +1,263 @@
+// Package events fans job state-transition events out to live subscribers.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spectra-red/recon/internal/models"
+	"go.uber.org/zap"
+)
+
+// DefaultSubscriberBuffer is how many events a subscriber may fall behind by
+// before it is disconnected
+const DefaultSubscriberBuffer = 64
+
+// DefaultPollInterval is how often Poll checks the source for new events
+const DefaultPollInterval = time.Second
+
+// pollBatchSize bounds how many events Poll reads per query
+const pollBatchSize = 500
+
+// PollOverlap is how far behind its cursor Poll re-reads. Event IDs are UUIDv7s
+// minted by more than one process, so an event can commit after one with a later
+// ID has been published; re-reading the overlap (and skipping events already
+// published) still delivers it, provided it commits within the overlap.
+const PollOverlap = 10 * time.Second
+
+// Source reads persisted job events in ID order
+type Source interface {
+	Since(ctx context.Context, afterID string, limit int) ([]models.JobEvent, error)
+	LatestID(ctx context.Context) (string, error)
+}
+
+// Broker fans published job events out to subscribers
+// Publishing never blocks: a subscriber whose buffer is full is disconnected,
+// and is expected to reconnect and resume from the last event ID it received.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	buffer int
+	logger *zap.Logger
+}
+
+// Subscription receives events published after it was created
+// C is closed when the subscription is closed or falls too far behind.
+type Subscription struct {
+	C <-chan models.JobEvent
+
+	ch     chan models.JobEvent
+	broker *Broker
+}
+
+// NewBroker creates a broker whose subscribers buffer up to buffer events
+func NewBroker(buffer int, logger *zap.Logger) *Broker {
+	if buffer <= 0 {
+		buffer = DefaultSubscriberBuffer
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Broker{
+		subs:   make(map[*Subscription]struct{}),
+		buffer: buffer,
+		logger: logger,
+	}
+}
+
+// Subscribe registers a new subscriber
+func (b *Broker) Subscribe() *Subscription {
+	ch := make(chan models.JobEvent, b.buffer)
+	sub := &Subscription{C: ch, ch: ch, broker: b}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Close unregisters the subscription; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.remove(s)
+}
+
+// Subscribers returns the number of active subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Publish delivers an event to every subscriber
+func (b *Broker) Publish(event models.JobEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			b.logger.Warn("dropping lagging job event subscriber",
+				zap.String("event_id", event.ID))
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Watch calls handle for every event published until ctx is cancelled
+// If handle falls too far behind, the subscription is re-established and
+// resync is called, since events may have been missed in between.
+func (b *Broker) Watch(ctx context.Context, handle func(models.JobEvent), resync func()) {
+	for {
+		sub := b.Subscribe()
+		dropped := b.consume(ctx, sub, handle)
+		sub.Close()
+		if !dropped {
+			return
+		}
+		if resync != nil {
+			resync()
+		}
+	}
+}
+
+// consume hands sub's events to handle until ctx is cancelled or the broker
+// drops sub, reporting whether it was dropped
+func (b *Broker) consume(ctx context.Context, sub *Subscription, handle func(models.JobEvent)) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return true
+			}
+			handle(event)
+		}
+	}
+}
+
+// remove unregisters sub and closes its channel if it is still registered
+func (b *Broker) remove(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Poll publishes events from source as they appear until ctx is cancelled
+// Only events newer than the latest one at startup are published; clients
+// that need older events replay them from the source directly.
+func (b *Broker) Poll(ctx context.Context, source Source, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	cursor, started := "", false
+	seen := make(map[string]time.Time)
+	for {
+		if !started {
+			latest, err := source.LatestID(ctx)
+			if err != nil {
+				b.logger.Warn("failed to read latest job event", zap.Error(err))
+			} else {
+				cursor, started = latest, true
+				markSeen(seen, latest)
+			}
+		}
+		if started {
+			cursor = b.drain(ctx, source, cursor, seen)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain publishes every event not yet seen from PollOverlap before cursor onwards,
+// and returns the new cursor. seen holds the IDs already published inside the
+// overlap; older ones are forgotten, since no query reaches back to them.
+func (b *Broker) drain(ctx context.Context, source Source, cursor string, seen map[string]time.Time) string {
+	after := rewindEventID(cursor, PollOverlap)
+	for {
+		events, err := source.Since(ctx, after, pollBatchSize)
+		if err != nil {
+			b.logger.Warn("failed to poll job events",
+				zap.Error(err),
+				zap.String("after", after))
+			return cursor
+		}
+		for _, event := range events {
+			if _, ok := seen[event.ID]; !ok {
+				b.Publish(event)
+				markSeen(seen, event.ID)
+			}
+			if event.ID > cursor {
+				cursor = event.ID
+			}
+		}
+		if len(events) < pollBatchSize {
+			break
+		}
+		after = events[len(events)-1].ID
+	}
+
+	horizon := eventIDTime(cursor).Add(-PollOverlap)
+	for id, at := range seen {
+		if at.Before(horizon) {
+			delete(seen, id)
+		}
+	}
+	return cursor
+}
+
+// markSeen records a published event ID with its time; IDs that aren't UUIDv7s
+// are read without an overlap, so they never need to be remembered
+func markSeen(seen map[string]time.Time, id string) {
+	if at := eventIDTime(id); !at.IsZero() {
+		seen[id] = at
+	}
+}
+
+// eventIDTime returns the time embedded in a UUIDv7 event ID, or the zero time
+// for an ID that isn't one
+func eventIDTime(id string) time.Time {
+	parsed, err := uuid.Parse(id)
+	if err != nil || parsed.Version() != 7 {
+		return time.Time{}
+	}
+	sec, nsec := parsed.Time().UnixTime()
+	return time.Unix(sec, nsec)
+}
+
+// rewindEventID returns an ID that sorts before every UUIDv7 minted from by
+// before the time in id onwards. IDs that aren't UUIDv7s are returned
+// unchanged, so such sources are read without an overlap.
+func rewindEventID(id string, by time.Duration) string {
+	at := eventIDTime(id)
+	if at.IsZero() {
+		return id
+	}
+	ms := at.Add(-by).UnixMilli()
+	if ms < 0 {
+		return ""
+	}
+
+	var rewound uuid.UUID
+	for i := 5; i >= 0; i-- {
+		rewound[i] = byte(ms)
+		ms >>= 8
+	}
+	return rewound.String()
+}
@@ -0,0 +1,235 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource is an in-memory Source
+type fakeSource struct {
+	mu     sync.Mutex
+	events []models.JobEvent
+}
+
+func (s *fakeSource) add(event models.JobEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+func (s *fakeSource) Since(ctx context.Context, afterID string, limit int) ([]models.JobEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]models.JobEvent, 0)
+	for _, event := range s.events {
+		if event.ID > afterID && len(out) < limit {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeSource) LatestID(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) == 0 {
+		return "", nil
+	}
+	return s.events[len(s.events)-1].ID, nil
+}
+
+func receive(t *testing.T, sub *Subscription) models.JobEvent {
+	t.Helper()
+	select {
+	case event, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return models.JobEvent{}
+	}
+}
+
+func TestBroker_PublishReachesAllSubscribers(t *testing.T) {
+	broker := NewBroker(0, nil)
+	first := broker.Subscribe()
+	defer first.Close()
+	second := broker.Subscribe()
+	defer second.Close()
+
+	broker.Publish(models.JobEvent{ID: "01", JobID: "job-1", State: models.JobStateProcessing})
+
+	assert.Equal(t, "job-1", receive(t, first).JobID)
+	assert.Equal(t, "job-1", receive(t, second).JobID)
+}
+
+func TestBroker_CloseUnsubscribes(t *testing.T) {
+	broker := NewBroker(0, nil)
+	sub := broker.Subscribe()
+	require.Equal(t, 1, broker.Subscribers())
+
+	sub.Close()
+	sub.Close()
+
+	assert.Equal(t, 0, broker.Subscribers())
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	// Publishing after close must not panic
+	broker.Publish(models.JobEvent{ID: "01"})
+}
+
+func TestBroker_DropsLaggingSubscriber(t *testing.T) {
+	broker := NewBroker(2, nil)
+	slow := broker.Subscribe()
+	defer slow.Close()
+
+	for i := 0; i < 3; i++ {
+		broker.Publish(models.JobEvent{ID: fmt.Sprintf("%02d", i)})
+	}
+
+	assert.Equal(t, 0, broker.Subscribers())
+	assert.Equal(t, "00", receive(t, slow).ID)
+	assert.Equal(t, "01", receive(t, slow).ID)
+	_, ok := <-slow.C
+	assert.False(t, ok, "lagging subscriber should be closed")
+}
+
+func TestBroker_PollPublishesNewEvents(t *testing.T) {
+	source := &fakeSource{}
+	source.add(models.JobEvent{ID: "01", JobID: "old"})
+
+	broker := NewBroker(0, nil)
+	sub := broker.Subscribe()
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Poll(ctx, source, 10*time.Millisecond)
+
+	// Give Poll a chance to read the starting cursor before the new event lands
+	time.Sleep(50 * time.Millisecond)
+	source.add(models.JobEvent{ID: "02", JobID: "new", State: models.JobStateCompleted})
+
+	event := receive(t, sub)
+	assert.Equal(t, "02", event.ID)
+	assert.Equal(t, "new", event.JobID)
+	assert.Equal(t, models.JobStateCompleted, event.State)
+}
+
+// uuidv7 builds a UUIDv7 event ID minted at ms milliseconds past the epoch
+func uuidv7(ms int64, seq byte) string {
+	var id uuid.UUID
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+	id[6], id[8], id[15] = 0x70, 0x80, seq
+	return id.String()
+}
+
+func TestBroker_DrainDeliversLateCommittedEvents(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	first, late, last := uuidv7(base, 1), uuidv7(base+1000, 2), uuidv7(base+2000, 3)
+
+	source := &fakeSource{}
+	source.add(models.JobEvent{ID: first})
+	source.add(models.JobEvent{ID: last})
+
+	broker := NewBroker(0, nil)
+	sub := broker.Subscribe()
+	defer sub.Close()
+
+	seen := map[string]time.Time{}
+	markSeen(seen, first)
+	cursor := broker.drain(context.Background(), source, first, seen)
+	assert.Equal(t, last, cursor)
+	assert.Equal(t, last, receive(t, sub).ID)
+
+	// An event minted before the cursor but committed after it was read is still
+	// delivered, once, because each poll re-reads the overlap
+	source.add(models.JobEvent{ID: late})
+	cursor = broker.drain(context.Background(), source, cursor, seen)
+	assert.Equal(t, last, cursor)
+	assert.Equal(t, late, receive(t, sub).ID)
+
+	broker.drain(context.Background(), source, cursor, seen)
+	select {
+	case event := <-sub.C:
+		t.Fatalf("event %s was published twice", event.ID)
+	default:
+	}
+}
+
+func TestRewindEventID(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := uuidv7(base.UnixMilli(), 9)
+
+	rewound := rewindEventID(id, PollOverlap)
+	assert.Less(t, rewound, uuidv7(base.Add(-PollOverlap).UnixMilli(), 0))
+	assert.Greater(t, rewound, uuidv7(base.Add(-PollOverlap).UnixMilli()-1, 255))
+	assert.Equal(t, base, eventIDTime(id).UTC())
+
+	// Non-UUIDv7 IDs are read without an overlap
+	assert.Equal(t, "02", rewindEventID("02", PollOverlap))
+}
+
+func TestBroker_WatchResubscribesAfterDrop(t *testing.T) {
+	broker := NewBroker(1, nil)
+
+	handled := make(chan string, 10)
+	release := make(chan struct{})
+	resynced := make(chan struct{}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		broker.Watch(ctx, func(event models.JobEvent) {
+			<-release
+			handled <- event.ID
+		}, func() { resynced <- struct{}{} })
+	}()
+	require.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, 5*time.Millisecond)
+
+	// The handler is stuck on the first event, so the third overflows the buffer
+	for i := 0; i < 3; i++ {
+		broker.Publish(models.JobEvent{ID: fmt.Sprintf("%02d", i)})
+	}
+	close(release)
+
+	select {
+	case <-resynced:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watcher was not resynced after being dropped")
+	}
+
+	require.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, 5*time.Millisecond)
+	broker.Publish(models.JobEvent{ID: "03"})
+
+	var ids []string
+	require.Eventually(t, func() bool {
+		for {
+			select {
+			case id := <-handled:
+				ids = append(ids, id)
+			default:
+				return len(ids) > 0 && ids[len(ids)-1] == "03"
+			}
+		}
+	}, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Equal(t, 0, broker.Subscribers())
+}
@@ -0,0 +1,126 @@
+// Package logging builds the zap loggers used by the services and the CLI.
+// Level and encoding come from LOG_LEVEL and LOG_FORMAT for the services and
+// from --log-level for the CLI; the default is production JSON at info.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log output formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Defaults used when LOG_LEVEL or LOG_FORMAT is unset
+const (
+	DefaultLevel  = "info"
+	DefaultFormat = FormatJSON
+)
+
+// New builds a logger writing to stderr at level (debug, info, warn, error)
+// in format (json or console)
+func New(level, format string) (*zap.Logger, error) {
+	return newLogger(level, format, os.Stderr)
+}
+
+// FromEnv builds the logger configured by LOG_LEVEL and LOG_FORMAT
+// An invalid value is replaced with its default and logged as a warning
+// rather than stopping the service from starting.
+func FromEnv() (*zap.Logger, error) {
+	return fromEnv(os.Stderr)
+}
+
+// fromEnv is FromEnv writing to w
+func fromEnv(w io.Writer) (*zap.Logger, error) {
+	level := getEnv("LOG_LEVEL", DefaultLevel)
+	format := getEnv("LOG_FORMAT", DefaultFormat)
+
+	var warnings []zap.Field
+	if _, err := parseLevel(level); err != nil {
+		warnings = append(warnings, zap.String("LOG_LEVEL", level))
+		level = DefaultLevel
+	}
+	if _, err := encoderFor(format); err != nil {
+		warnings = append(warnings, zap.String("LOG_FORMAT", format))
+		format = DefaultFormat
+	}
+
+	logger, err := newLogger(level, format, w)
+	if err != nil {
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		logger.Warn("invalid logging configuration, using defaults",
+			append(warnings,
+				zap.String("default_level", DefaultLevel),
+				zap.String("default_format", DefaultFormat))...)
+	}
+	return logger, nil
+}
+
+// newLogger builds a logger at level in format writing to w
+// It matches zap.NewProduction: sampled, with callers, and stack traces on errors.
+func newLogger(level, format string, w io.Writer) (*zap.Logger, error) {
+	lvl, err := parseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := encoderFor(format)
+	if err != nil {
+		return nil, err
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(w)), lvl)
+	core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
+
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
+
+// parseLevel parses a log level name, ignoring case
+func parseLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn", "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("invalid log level %q (must be one of: debug, info, warn, error)", level)
+	}
+}
+
+// encoderFor returns the encoder for a log format, ignoring case
+// JSON uses zap's production field names; console is human-readable with
+// ISO8601 timestamps.
+func encoderFor(format string) (zapcore.Encoder, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), nil
+	case FormatConsole:
+		cfg := zap.NewDevelopmentEncoderConfig()
+		cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewConsoleEncoder(cfg), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (must be json or console)", format)
+	}
+}
+
+// getEnv returns the value of an environment variable or a default
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
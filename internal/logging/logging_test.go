@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger_LevelsAndFormats(t *testing.T) {
+	levels := []string{"debug", "info", "warn", "error"}
+
+	for _, format := range []string{FormatJSON, FormatConsole} {
+		for i, level := range levels {
+			t.Run(level+"/"+format, func(t *testing.T) {
+				var buf bytes.Buffer
+				logger, err := newLogger(level, format, &buf)
+				require.NoError(t, err)
+
+				logger.Debug("debug message")
+				logger.Info("info message")
+				logger.Warn("warn message")
+				logger.Error("error message")
+				require.NoError(t, logger.Sync())
+
+				out := buf.String()
+				// Messages at or above the configured level are written, lower ones dropped
+				for j, l := range levels {
+					if j >= i {
+						assert.Contains(t, out, l+" message")
+					} else {
+						assert.NotContains(t, out, l+" message")
+					}
+				}
+
+				lines := strings.Split(strings.TrimSpace(out), "\n")
+				require.NotEmpty(t, lines)
+				var entry map[string]interface{}
+				if format == FormatJSON {
+					require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry), "json format writes one object per line")
+					assert.Equal(t, levels[i], entry["level"])
+				} else {
+					assert.Error(t, json.Unmarshal([]byte(lines[0]), &entry), "console format is not JSON")
+					assert.Contains(t, lines[0], strings.ToUpper(levels[i]))
+				}
+			})
+		}
+	}
+}
+
+func TestNew_RejectsInvalidSettings(t *testing.T) {
+	_, err := New("verbose", FormatJSON)
+	assert.ErrorContains(t, err, `invalid log level "verbose"`)
+
+	_, err = New("info", "xml")
+	assert.ErrorContains(t, err, `invalid log format "xml"`)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Run("defaults to json at info", func(t *testing.T) {
+		t.Setenv("LOG_LEVEL", "")
+		t.Setenv("LOG_FORMAT", "")
+
+		var buf bytes.Buffer
+		logger, err := fromEnv(&buf)
+		require.NoError(t, err)
+		logger.Debug("hidden")
+		logger.Info("shown")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "shown", entry["msg"])
+	})
+
+	t.Run("invalid values fall back with a warning", func(t *testing.T) {
+		t.Setenv("LOG_LEVEL", "loud")
+		t.Setenv("LOG_FORMAT", "CONSOLE")
+
+		var buf bytes.Buffer
+		logger, err := fromEnv(&buf)
+		require.NoError(t, err)
+		logger.Debug("hidden")
+
+		out := buf.String()
+		assert.Contains(t, out, "WARN")
+		assert.Contains(t, out, "invalid logging configuration")
+		assert.Contains(t, out, "loud")
+		assert.NotContains(t, out, "hidden")
+	})
+}
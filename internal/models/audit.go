@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// Audited actions
+const (
+	AuditActionIngest   = "ingest"
+	AuditActionRedact   = "redact"
+	AuditActionReenrich = "reenrich"
+	AuditActionExport   = "export"
+	AuditActionImport   = "import"
+	AuditActionMigrate  = "migrate"
+	AuditActionRequeue  = "requeue"
+)
+
+// Actors recorded for actions taken through the admin API: AuditActorAdmin when
+// the request presented the admin token, AuditActorAnonymous when the endpoint
+// does not require it
+const (
+	AuditActorAdmin     = "admin"
+	AuditActorAnonymous = "anonymous"
+)
+
+// DefaultAuditLimit and MaxAuditLimit bound the page size of an audit listing
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// ScannerActor returns the audit actor for a scanner key: "scanner:" and its
+// ScannerID. Entries can be matched to a known key without the audit trail
+// holding any key itself.
+func ScannerActor(scannerKey string) string {
+	return "scanner:" + ScannerID(scannerKey)
+}
+
+// AuditEntry records who ingested a scan or performed an admin action
+type AuditEntry struct {
+	Action    string                 `json:"action"`               // One of the AuditAction constants
+	Actor     string                 `json:"actor"`                // ScannerActor of the submitting key, AuditActorAdmin or AuditActorAnonymous
+	SourceIP  string                 `json:"source_ip,omitempty"`  // Address the request came from
+	JobID     string                 `json:"job_id,omitempty"`     // Job created by an ingest
+	HostCount int                    `json:"host_count,omitempty"` // Hosts in the scan or removed by a redaction
+	Details   map[string]interface{} `json:"details,omitempty"`    // Action-specific counts and filters
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Since  time.Time // entries created at or after
+	Until  time.Time // entries created before
+	Actor  string
+	Action string
+	Limit  int
+}
+
+// AuditListResponse represents the response for listing audit entries
+type AuditListResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+}
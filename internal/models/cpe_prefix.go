@@ -0,0 +1,159 @@
+package models
+
+import "strings"
+
+// cpe23Header starts every CPE 2.3 formatted string
+const cpe23Header = "cpe:2.3:"
+
+// cpe23Components is the number of components after the header: part, vendor,
+// product, version, update, edition, language, sw_edition, target_sw, target_hw, other
+const cpe23Components = 11
+
+// MaxCPEPrefixScan caps the services read for a prefix with a * component. The
+// database can only narrow such a prefix to the components before the wildcard,
+// so the rest of the match runs over at most this many candidates.
+const MaxCPEPrefixScan = 5000
+
+// CPEPattern matches CPE 2.3 strings by their leading components
+// Components are compared whole, so cpe:2.3:a:apache matches apache's products but
+// not apache_software's. A * component matches any value, and a * ending the last
+// component matches values starting with the rest of it (cpe:2.3:a:apache:http*).
+type CPEPattern struct {
+	// Prefix is what every matching CPE starts with: the components before the
+	// first wildcard, colon-terminated unless the last one is a partial value
+	Prefix string
+	// Components are the components given, lower-cased, with trailing wildcards dropped
+	Components []string
+}
+
+// CPE prefix validation errors
+var (
+	ErrInvalidCPEPrefix = &ValidationError{Field: "prefix", Rule: RuleFormat, Message: "prefix must be a CPE 2.3 prefix such as cpe:2.3:a:apache:*"}
+	ErrBroadCPEPrefix   = &ValidationError{Field: "prefix", Rule: RuleRequired, Message: "prefix must name at least the CPE part (a, o or h)"}
+	ErrInvalidCPEPart   = &ValidationError{Field: "prefix", Rule: RuleEnum, Message: "CPE part must be a, o or h"}
+)
+
+// ParseCPEPrefix parses a CPE 2.3 prefix such as cpe:2.3:a:apache:* or
+// cpe:2.3:a:*:log4j, ignoring case and trailing * components. The part must be
+// given, so every prefix narrows the lookup past the header.
+func ParseCPEPrefix(prefix string) (CPEPattern, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if !strings.HasPrefix(prefix, cpe23Header) {
+		return CPEPattern{}, ErrInvalidCPEPrefix
+	}
+
+	components := splitCPE(strings.TrimPrefix(prefix, cpe23Header))
+	for len(components) > 0 && (components[len(components)-1] == "*" || components[len(components)-1] == "") {
+		components = components[:len(components)-1]
+	}
+	if len(components) == 0 || components[0] == "*" {
+		return CPEPattern{}, ErrBroadCPEPrefix
+	}
+	if len(components) > cpe23Components {
+		return CPEPattern{}, ErrInvalidCPEPrefix
+	}
+
+	for i, component := range components {
+		value := component
+		if i == len(components)-1 {
+			value, _ = partialValue(component)
+		}
+		// Wildcards stand alone except at the end of the last component
+		if value == "" || (value != "*" && strings.Contains(strings.ReplaceAll(value, `\*`, ""), "*")) {
+			return CPEPattern{}, ErrInvalidCPEPrefix
+		}
+	}
+	if part := components[0]; part != "a" && part != "o" && part != "h" {
+		return CPEPattern{}, ErrInvalidCPEPart
+	}
+
+	literal := cpe23Header
+	for i, component := range components {
+		if component == "*" {
+			break
+		}
+		if value, partial := partialValue(component); partial && i == len(components)-1 {
+			literal += value
+			break
+		}
+		literal += component + ":"
+	}
+
+	return CPEPattern{Prefix: literal, Components: components}, nil
+}
+
+// Literal reports whether the pattern has no * component, so that every CPE
+// starting with Prefix matches it
+func (p CPEPattern) Literal() bool {
+	for _, component := range p.Components {
+		if component == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether a CPE 2.3 string matches the pattern
+func (p CPEPattern) Matches(cpe string) bool {
+	cpe = strings.ToLower(cpe)
+	if !strings.HasPrefix(cpe, cpe23Header) {
+		return false
+	}
+
+	values := splitCPE(strings.TrimPrefix(cpe, cpe23Header))
+	for i, want := range p.Components {
+		if want == "*" {
+			continue
+		}
+		if i >= len(values) {
+			return false
+		}
+		if value, partial := partialValue(want); partial && i == len(p.Components)-1 {
+			if !strings.HasPrefix(values[i], value) {
+				return false
+			}
+			continue
+		}
+		if values[i] != want {
+			return false
+		}
+	}
+	return true
+}
+
+// partialValue returns a component without its trailing * and whether it had one
+func partialValue(component string) (string, bool) {
+	if component != "*" && strings.HasSuffix(component, "*") && !strings.HasSuffix(component, `\*`) {
+		return strings.TrimSuffix(component, "*"), true
+	}
+	return component, false
+}
+
+// splitCPE splits CPE components on colons that aren't escaped with a backslash
+func splitCPE(s string) []string {
+	var components []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case ':':
+			components = append(components, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(components, s[start:])
+}
+
+// CPEPrefixResponse lists the services with a CPE matching a prefix, the hosts
+// running them and the CVEs the services are linked to. Truncated is set when a
+// prefix with a * component read MaxCPEPrefixScan candidates before limit
+// services matched, so more matches may exist.
+type CPEPrefixResponse struct {
+	Prefix    string            `json:"prefix"`
+	Services  []CPEServiceMatch `json:"services"`
+	Hosts     []string          `json:"hosts"` // IPs running any listed service, in address order
+	Limit     int               `json:"limit"`
+	Truncated bool              `json:"truncated,omitempty"`
+	QueryTime float64           `json:"query_time_ms"`
+}
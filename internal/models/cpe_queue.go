@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// CPEQueueEntry is a service waiting in the cpe_queue table for CPE enrichment
+type CPEQueueEntry struct {
+	ServiceID  string    `json:"service_id"` // Service record ID, e.g. service:<fingerprint>
+	Name       string    `json:"name"`
+	Product    string    `json:"product"`
+	Version    string    `json:"version"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	Attempts   int       `json:"attempts"` // Times the entry has been dequeued
+}
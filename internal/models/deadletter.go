@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Enrichment stages that can dead-letter an item
+const (
+	DeadLetterStageASN = "asn"
+	DeadLetterStageGeo = "geo"
+)
+
+// IsValidDeadLetterStage checks if the stage is one of the known enrichment stages
+func IsValidDeadLetterStage(stage string) bool {
+	switch stage {
+	case DeadLetterStageASN, DeadLetterStageGeo:
+		return true
+	default:
+		return false
+	}
+}
+
+// DeadLetter records an IP that permanently failed an enrichment stage so it can be revisited
+type DeadLetter struct {
+	IP          string    `json:"ip"`
+	Stage       string    `json:"stage"`        // Enrichment stage that gave up (asn, geo)
+	Reason      string    `json:"reason"`       // Why the item was dead-lettered
+	Attempts    int       `json:"attempts"`     // Number of workflow runs that failed the item
+	LastAttempt time.Time `json:"last_attempt"` // When the item last failed
+}
+
+// DeadLetterListResponse represents the response for listing dead letters
+type DeadLetterListResponse struct {
+	DeadLetters []DeadLetter `json:"dead_letters"`
+	Total       int          `json:"total"`
+	Limit       int          `json:"limit"`
+}
+
+// RequeueDeadLetterRequest identifies a dead letter to clear and re-submit for enrichment
+type RequeueDeadLetterRequest struct {
+	IP    string `json:"ip"`
+	Stage string `json:"stage"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"net/netip"
+	"time"
+)
+
+// DiffRequest asks what changed in a network between two scan snapshots
+// From and To are the start times of the two scans: the earlier snapshot holds
+// what was seen from From up to To, the later one what has been seen since To.
+// Hosts, ports and services are placed in the snapshots by their first_seen and
+// last_seen timestamps.
+type DiffRequest struct {
+	// Scope: exactly one of CIDR or ASN
+	CIDR string `json:"cidr,omitempty"`
+	ASN  *int   `json:"asn,omitempty"`
+
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Limit caps the hosts listed per change category; the summary counts them all
+	Limit int `json:"limit,omitempty"` // Default: 100, Max: 1000
+}
+
+// Validate validates the DiffRequest and applies the limit default
+func (r *DiffRequest) Validate() error {
+	switch {
+	case r.CIDR == "" && r.ASN == nil:
+		return ErrMissingDiffScope
+	case r.CIDR != "" && r.ASN != nil:
+		return ErrAmbiguousDiffScope
+	case r.CIDR != "":
+		if _, err := netip.ParsePrefix(r.CIDR); err != nil {
+			return ErrInvalidDiffCIDR
+		}
+	}
+
+	if r.From.IsZero() {
+		return ErrMissingDiffFrom
+	}
+	if r.To.IsZero() {
+		return ErrMissingDiffTo
+	}
+	if !r.From.Before(r.To) {
+		return ErrInvalidDiffRange
+	}
+
+	if r.Limit <= 0 {
+		r.Limit = DefaultLimit
+	}
+	if r.Limit > MaxLimit {
+		r.Limit = MaxLimit
+	}
+	return nil
+}
+
+// Diff validation errors
+var (
+	ErrMissingDiffScope   = &ValidationError{Field: "scope", Rule: RuleRequired, Message: "one of cidr or asn is required"}
+	ErrAmbiguousDiffScope = &ValidationError{Field: "scope", Rule: RuleEnum, Message: "only one of cidr or asn may be set"}
+	ErrInvalidDiffCIDR    = &ValidationError{Field: "cidr", Rule: RuleFormat, Message: "cidr must be a network such as 192.0.2.0/24"}
+	ErrMissingDiffFrom    = &ValidationError{Field: "from", Rule: RuleRequired, Message: "from is required"}
+	ErrMissingDiffTo      = &ValidationError{Field: "to", Rule: RuleRequired, Message: "to is required"}
+	ErrInvalidDiffRange   = &ValidationError{Field: "to", Rule: RuleMin, Message: "to must be after from"}
+)
+
+// DiffService is a service found on a port
+type DiffService struct {
+	Name    string `json:"name,omitempty"`
+	Product string `json:"product,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// DiffPort is an open port that appeared or disappeared, with the services on it
+type DiffPort struct {
+	Number   int           `json:"number"`
+	Protocol string        `json:"protocol"`
+	Services []DiffService `json:"services,omitempty"`
+}
+
+// ServiceChange is a port open in both snapshots whose services differ, e.g. after
+// an upgrade; Before or After is empty when the port had no identified service
+type ServiceChange struct {
+	Number   int           `json:"number"`
+	Protocol string        `json:"protocol"`
+	Before   []DiffService `json:"before"`
+	After    []DiffService `json:"after"`
+}
+
+// HostDiff is what changed on one host between the snapshots
+type HostDiff struct {
+	IP              string          `json:"ip"`
+	AddedPorts      []DiffPort      `json:"added_ports,omitempty"`
+	RemovedPorts    []DiffPort      `json:"removed_ports,omitempty"`
+	ChangedServices []ServiceChange `json:"changed_services,omitempty"`
+	// NewCVEs affect the host's services in the later snapshot but none in the earlier one
+	NewCVEs []string `json:"new_cves,omitempty"`
+}
+
+// DiffSummary counts the changes in every category, including hosts past the limit
+type DiffSummary struct {
+	HostsAdded      int `json:"hosts_added"`
+	HostsRemoved    int `json:"hosts_removed"`
+	HostsChanged    int `json:"hosts_changed"`
+	PortsAdded      int `json:"ports_added"`
+	PortsRemoved    int `json:"ports_removed"`
+	ServicesChanged int `json:"services_changed"`
+	NewCVEs         int `json:"new_cves"`
+}
+
+// DiffResponse lists the hosts that changed between two scan snapshots, by IP
+type DiffResponse struct {
+	CIDR    string      `json:"cidr,omitempty"`
+	ASN     *int        `json:"asn,omitempty"`
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	Summary DiffSummary `json:"summary"`
+	// Added hosts were first seen in the later snapshot
+	Added []HostDiff `json:"added"`
+	// Removed hosts were seen in the earlier snapshot but not since
+	Removed []HostDiff `json:"removed"`
+	// Changed hosts are in both snapshots with different ports, services or CVEs
+	Changed   []HostDiff `json:"changed"`
+	Limit     int        `json:"limit"`
+	QueryTime float64    `json:"query_time_ms"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Export record kinds
+const (
+	ExportKindNode = "node"
+	ExportKindEdge = "edge"
+)
+
+// Trailers of an export response. The status is sent before the export can
+// fail, so a client that did not get a complete export sees X-Export-Error
+// set, or no trailers at all if the connection dropped.
+const (
+	ExportRecordsTrailer = "X-Export-Records"
+	ExportErrorTrailer   = "X-Export-Error"
+)
+
+// ExportNodeTables are the graph's node tables, in the order they are exported
+// Vector documents (vuln_doc) are derived from vulns and are not exported.
+var ExportNodeTables = []string{
+	"host", "port", "service", "banner", "tls_cert", "vuln",
+	"city", "region", "country", "asn", "cloud_region", "common_port",
+}
+
+// ExportEdgeTables are the graph's relation tables, exported after every node
+// table so a reader can create both ends of an edge before the edge itself
+var ExportEdgeTables = []string{
+	"HAS", "RUNS", "EVIDENCED_BY", "AFFECTED_BY", "OBSERVED_AT",
+	"IN_CITY", "IN_REGION", "IN_COUNTRY", "HOST_IN_REGION", "HOST_IN_COUNTRY",
+	"IN_ASN", "IN_CLOUD_REGION", "IS_COMMON",
+}
+
+// ExportRecord is one line of a graph export: a node, or an edge between two nodes
+type ExportRecord struct {
+	Table string                 `json:"table"`
+	Kind  string                 `json:"kind"`          // ExportKindNode or ExportKindEdge
+	ID    string                 `json:"id"`            // Record id, e.g. host:abc123
+	In    string                 `json:"in,omitempty"`  // Edge source record id
+	Out   string                 `json:"out,omitempty"` // Edge target record id
+	Data  map[string]interface{} `json:"data"`          // Every other field of the record
+}
+
+// ResolveExportTables returns the tables an export covers, nodes before edges
+// An empty selection means every node and edge table; unknown names are an error.
+func ResolveExportTables(selected []string) ([]string, error) {
+	all := append(append([]string{}, ExportNodeTables...), ExportEdgeTables...)
+
+	want := make(map[string]bool, len(selected))
+	for _, table := range selected {
+		table = strings.TrimSpace(table)
+		if table == "" {
+			continue
+		}
+		if !IsExportTable(table) {
+			return nil, fmt.Errorf("unknown table %q (must be one of: %s, %s)", table,
+				strings.Join(ExportNodeTables, ", "), strings.Join(ExportEdgeTables, ", "))
+		}
+		want[table] = true
+	}
+
+	if len(want) == 0 {
+		return all, nil
+	}
+
+	var tables []string
+	for _, table := range all {
+		if want[table] {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// IsExportTable reports whether table is a node or edge table of the graph
+func IsExportTable(table string) bool {
+	return ExportKind(table) != ""
+}
+
+// ExportKind returns ExportKindNode or ExportKindEdge for a graph table, or ""
+func ExportKind(table string) string {
+	for _, node := range ExportNodeTables {
+		if node == table {
+			return ExportKindNode
+		}
+	}
+	for _, edge := range ExportEdgeTables {
+		if edge == table {
+			return ExportKindEdge
+		}
+	}
+	return ""
+}
+
+// Validate checks that a record read back from an export has the shape Export
+// writes: a graph table, the kind of that table, an id in that table, and in
+// and out on edges only
+func (r ExportRecord) Validate() error {
+	kind := ExportKind(r.Table)
+	if kind == "" {
+		return fmt.Errorf("unknown table %q", r.Table)
+	}
+	if r.Kind != kind {
+		return fmt.Errorf("table %s holds %s records, not %q", r.Table, kind, r.Kind)
+	}
+	if !strings.HasPrefix(r.ID, r.Table+":") || len(r.ID) == len(r.Table)+1 {
+		return fmt.Errorf("id %q is not a record of table %s", r.ID, r.Table)
+	}
+
+	if kind == ExportKindNode {
+		if r.In != "" || r.Out != "" {
+			return fmt.Errorf("node %s has in or out set", r.ID)
+		}
+		return nil
+	}
+	for _, end := range []string{r.In, r.Out} {
+		table, _, found := strings.Cut(end, ":")
+		if !found || ExportKind(table) != ExportKindNode {
+			return fmt.Errorf("edge %s needs in and out node ids, got %q", r.ID, end)
+		}
+	}
+	return nil
+}
+
+// MaxImportErrors caps how many skipped lines an import reports individually
+const MaxImportErrors = 20
+
+// ImportResponse reports what an import of an export wrote
+type ImportResponse struct {
+	Nodes   int      `json:"nodes"`            // Node records upserted
+	Edges   int      `json:"edges"`            // Edge records upserted
+	Skipped int      `json:"skipped"`          // Malformed lines left out
+	Errors  []string `json:"errors,omitempty"` // Why lines were skipped, the first MaxImportErrors of them
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RiskyPorts names the management and data-store services the exposure report looks
+// for by default: exposed to the internet, each is a common way in
+var RiskyPorts = map[int]string{
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	445:   "smb",
+	2375:  "docker",
+	3306:  "mysql",
+	3389:  "rdp",
+	5432:  "postgresql",
+	5900:  "vnc",
+	6379:  "redis",
+	9200:  "elasticsearch",
+	11211: "memcached",
+	27017: "mongodb",
+}
+
+// DefaultRiskyPorts returns the port numbers of RiskyPorts in ascending order
+func DefaultRiskyPorts() []int {
+	ports := make([]int, 0, len(RiskyPorts))
+	for port := range RiskyPorts {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// ParsePortList parses a comma-separated list of port numbers such as "22,3389,6379",
+// dropping duplicates and returning them in ascending order
+func ParsePortList(list string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		port, err := strconv.Atoi(field)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q: must be an integer between 1 and 65535", field)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// ExposureGroup lists the public hosts exposing one risky port
+type ExposureGroup struct {
+	Port      int      `json:"port"`
+	Service   string   `json:"service,omitempty"` // Name from RiskyPorts, if the port is one of them
+	HostCount int      `json:"host_count"`
+	Hosts     []string `json:"hosts"` // IPs, at most the response limit
+}
+
+// ExposureResponse is the exposure report: public hosts grouped by the risky port they
+// expose, the port with most hosts first
+type ExposureResponse struct {
+	Results   []ExposureGroup `json:"results"`
+	Ports     []int           `json:"ports"` // Risky ports that were looked for
+	Limit     int             `json:"limit"` // Most hosts listed per port
+	QueryTime float64         `json:"query_time_ms"`
+}
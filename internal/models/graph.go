@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"net"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
 
 // GraphQueryType represents the type of graph query to perform
 type GraphQueryType string
@@ -10,11 +16,36 @@ const (
 	QueryByLocation GraphQueryType = "by_location"
 	QueryByVuln     GraphQueryType = "by_vuln"
 	QueryByService  GraphQueryType = "by_service"
+	QueryByKEV      GraphQueryType = "by_kev"
+	QueryRelated    GraphQueryType = "related"
+)
+
+// RelationKind represents a way two hosts can be related in the graph
+type RelationKind string
+
+const (
+	RelationASN     RelationKind = "asn"     // Hosts in the same autonomous system
+	RelationSubnet  RelationKind = "subnet"  // Hosts in the same /24 network
+	RelationService RelationKind = "service" // Hosts running the same service product
+	RelationVuln    RelationKind = "vuln"    // Hosts affected by the same vulnerability
 )
 
+// AllRelationKinds lists every supported relation kind, used when a related query omits relations
+var AllRelationKinds = []RelationKind{RelationASN, RelationSubnet, RelationService, RelationVuln}
+
+// IsValid checks if the relation kind is one of the supported values
+func (k RelationKind) IsValid() bool {
+	switch k {
+	case RelationASN, RelationSubnet, RelationService, RelationVuln:
+		return true
+	default:
+		return false
+	}
+}
+
 // GraphQueryRequest represents the request for a graph traversal query
 type GraphQueryRequest struct {
-	QueryType GraphQueryType `json:"query_type" validate:"required,oneof=by_asn by_location by_vuln by_service"`
+	QueryType GraphQueryType `json:"query_type" validate:"required,oneof=by_asn by_location by_vuln by_service by_kev related"`
 
 	// ASN query parameters
 	ASN *int `json:"asn,omitempty"`
@@ -30,10 +61,30 @@ type GraphQueryRequest struct {
 	// Service query parameters
 	Product string `json:"product,omitempty"`
 	Service string `json:"service,omitempty"`
+	// VersionConstraint narrows a service query to matching versions, e.g. "<1.25.0"
+	// or ">= 1.18, < 1.25"; services with unparseable versions never match
+	VersionConstraint string `json:"version_constraint,omitempty"`
+
+	// Related query parameters
+	SeedIP    string         `json:"seed_ip,omitempty"`
+	Relations []RelationKind `json:"relations,omitempty"` // Default: all relation kinds
 
 	// Pagination parameters
 	Limit  int `json:"limit,omitempty"`  // Default: 100, Max: 1000
 	Offset int `json:"offset,omitempty"` // Default: 0
+	// Stable orders every query type by host id instead of its own ordering (recency,
+	// CVSS, relation score), so pages of an export neither overlap nor skip hosts that
+	// change between requests. Every match is sorted before LIMIT/START is applied,
+	// which costs more on large result sets, and the most relevant hosts no longer
+	// come first.
+	Stable bool `json:"stable,omitempty"`
+
+	// Time window, applied to every query type: only hosts first seen at or after
+	// FirstSeenAfter and last seen at or after LastSeenAfter and before LastSeenBefore
+	// match. Unset bounds are open, so FirstSeenAfter alone finds new exposures.
+	FirstSeenAfter *time.Time `json:"first_seen_after,omitempty"`
+	LastSeenAfter  *time.Time `json:"last_seen_after,omitempty"`
+	LastSeenBefore *time.Time `json:"last_seen_before,omitempty"`
 }
 
 // GraphQueryResponse represents the response from a graph traversal query
@@ -55,6 +106,16 @@ type HostResult struct {
 	Services  []Service `json:"services,omitempty"`
 	LastSeen  time.Time `json:"last_seen"`
 	FirstSeen time.Time `json:"first_seen,omitempty"`
+
+	// Related query fields (only set for related queries)
+	Score     float64        `json:"score,omitempty"`
+	Relations []RelationKind `json:"relations,omitempty"`
+
+	// KEV query fields (only set for by_kev queries)
+	MaxCVSS float64 `json:"max_cvss,omitempty"` // Highest CVSS among the host's KEV-listed CVEs
+
+	// Source names the mesh that returned the host (only set by federated queries)
+	Source string `json:"source,omitempty"`
 }
 
 // Port represents a port on a host
@@ -103,10 +164,50 @@ func (r *GraphQueryRequest) Validate() error {
 		if r.Product == "" && r.Service == "" {
 			return ErrMissingService
 		}
+		if r.VersionConstraint != "" {
+			if _, err := version.NewConstraint(r.VersionConstraint); err != nil {
+				return ErrInvalidVersionConstraint
+			}
+		}
+	case QueryByKEV:
+		// No parameters: matches every host affected by a KEV-listed CVE
+	case QueryRelated:
+		if r.SeedIP == "" {
+			return ErrMissingSeedIP
+		}
+		if net.ParseIP(r.SeedIP) == nil {
+			return ErrInvalidSeedIP
+		}
+		requested := r.Relations
+		if len(requested) == 0 {
+			requested = AllRelationKinds
+		}
+		// Build a fresh slice so callers can't alias AllRelationKinds, and score
+		// each kind once however many times the request names it
+		relations := make([]RelationKind, 0, len(requested))
+		for _, kind := range requested {
+			if !kind.IsValid() {
+				return ErrInvalidRelation
+			}
+			if !slices.Contains(relations, kind) {
+				relations = append(relations, kind)
+			}
+		}
+		r.Relations = relations
 	default:
 		return ErrInvalidQueryType
 	}
 
+	// Validate the time window; a host can't be last seen before it was first seen
+	if r.LastSeenBefore != nil {
+		if r.LastSeenAfter != nil && !r.LastSeenAfter.Before(*r.LastSeenBefore) {
+			return ErrInvalidLastSeenRange
+		}
+		if r.FirstSeenAfter != nil && !r.FirstSeenAfter.Before(*r.LastSeenBefore) {
+			return ErrInvalidFirstSeenRange
+		}
+	}
+
 	// Validate and set pagination defaults
 	if r.Limit <= 0 {
 		r.Limit = DefaultLimit
@@ -128,20 +229,98 @@ const (
 )
 
 // ValidationError represents a validation error
+// Rule names the constraint that failed (e.g. required, max_length) so clients
+// can map errors to form inputs.
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
 }
 
+// Validation rule names
+const (
+	RuleRequired  = "required"
+	RuleEnum      = "enum"
+	RuleFormat    = "format"
+	RuleMin       = "min"
+	RuleMax       = "max"
+	RuleMaxLength = "max_length"
+)
+
 func (e *ValidationError) Error() string {
 	return e.Field + ": " + e.Message
 }
 
 // Validation errors
 var (
-	ErrInvalidQueryType = &ValidationError{Field: "query_type", Message: "invalid query type"}
-	ErrMissingASN       = &ValidationError{Field: "asn", Message: "asn is required for by_asn queries"}
-	ErrMissingLocation  = &ValidationError{Field: "location", Message: "at least one of city, region, or country is required"}
-	ErrMissingCVE       = &ValidationError{Field: "cve", Message: "cve is required for by_vuln queries"}
-	ErrMissingService   = &ValidationError{Field: "service", Message: "product or service is required for by_service queries"}
+	ErrInvalidQueryType         = &ValidationError{Field: "query_type", Rule: RuleEnum, Message: "invalid query type"}
+	ErrMissingASN               = &ValidationError{Field: "asn", Rule: RuleRequired, Message: "asn is required for by_asn queries"}
+	ErrMissingLocation          = &ValidationError{Field: "location", Rule: RuleRequired, Message: "at least one of city, region, or country is required"}
+	ErrMissingCVE               = &ValidationError{Field: "cve", Rule: RuleRequired, Message: "cve is required for by_vuln queries"}
+	ErrMissingService           = &ValidationError{Field: "service", Rule: RuleRequired, Message: "product or service is required for by_service queries"}
+	ErrInvalidVersionConstraint = &ValidationError{Field: "version_constraint", Rule: RuleFormat, Message: "version_constraint must be a version constraint such as <1.25.0"}
+	ErrMissingSeedIP            = &ValidationError{Field: "seed_ip", Rule: RuleRequired, Message: "seed_ip is required for related queries"}
+	ErrInvalidSeedIP            = &ValidationError{Field: "seed_ip", Rule: RuleFormat, Message: "seed_ip must be a valid IP address"}
+	ErrInvalidRelation          = &ValidationError{Field: "relations", Rule: RuleEnum, Message: "relations must be one of asn, subnet, service, vuln"}
+	ErrInvalidLastSeenRange     = &ValidationError{Field: "last_seen_before", Rule: RuleMin, Message: "last_seen_before must be after last_seen_after"}
+	ErrInvalidFirstSeenRange    = &ValidationError{Field: "first_seen_after", Rule: RuleMax, Message: "first_seen_after must be before last_seen_before"}
 )
+
+// ASNAggregate represents host and port counts for a single ASN
+type ASNAggregate struct {
+	ASN       int    `json:"asn"`
+	Org       string `json:"org,omitempty"`
+	HostCount int    `json:"host_count"`
+	PortCount int    `json:"port_count"`
+}
+
+// ASNAggregateResponse represents the response from an ASN aggregation query
+type ASNAggregateResponse struct {
+	Results   []ASNAggregate `json:"results"`
+	Limit     int            `json:"limit"`
+	QueryTime float64        `json:"query_time_ms"`
+}
+
+// DefaultAggregateLimit is the default number of groups returned by aggregate queries
+const DefaultAggregateLimit = 20
+
+// CPEVulnLink is a vulnerability a service is linked to through AFFECTED_BY
+type CPEVulnLink struct {
+	CVEID      string  `json:"cve_id"`
+	CVSS       float64 `json:"cvss"`
+	Severity   string  `json:"severity,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// CPEServiceMatch is a service whose cpe list contains the looked-up CPE
+type CPEServiceMatch struct {
+	ServiceID string        `json:"service_id"`
+	Name      string        `json:"name,omitempty"`
+	Product   string        `json:"product,omitempty"`
+	Version   string        `json:"version,omitempty"`
+	CPEs      []string      `json:"cpe"`
+	Hosts     []string      `json:"hosts"` // IPs of hosts running the service
+	Vulns     []CPEVulnLink `json:"vulnerabilities"`
+}
+
+// CPELookupResponse lists the services a CPE was assigned to and the CVEs they matched
+type CPELookupResponse struct {
+	CPE       string            `json:"cpe"`
+	Services  []CPEServiceMatch `json:"services"`
+	Limit     int               `json:"limit"`
+	QueryTime float64           `json:"query_time_ms"`
+}
+
+// UnidentifiedBanner groups services that banner parsing left without a product or
+// CPE by their raw banner. Services with no banner evidence are not listed.
+type UnidentifiedBanner struct {
+	Banner       string `json:"banner"`
+	ServiceCount int    `json:"service_count"`
+}
+
+// UnidentifiedServicesResponse lists raw banners of unidentified services, most common first
+type UnidentifiedServicesResponse struct {
+	Results   []UnidentifiedBanner `json:"results"`
+	Limit     int                  `json:"limit"`
+	QueryTime float64              `json:"query_time_ms"`
+}
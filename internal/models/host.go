@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Host record ID namespaces: each prefixes the encoded address (or input) so IDs
+// derived from IPs can't collide with each other across families, or with any ID
+// derived from something else
+const (
+	HostIDPrefixIPv4 = "ip4_" // ip4_8_8_8_8: dots become underscores
+	HostIDPrefixIPv6 = "ip6_" // ip6_2001-db8--1: colons become hyphens
+	HostIDPrefixRaw  = "raw_" // raw_ followed by the hex of an input that isn't an IP
+)
+
+// HostIDScheme selects how host record IDs are derived from IPs (see RecordID)
+type HostIDScheme string
+
+const (
+	// HostIDSchemeNamespaced prefixes the address family (ip4_8_8_8_8), for
+	// databases whose host IDs migration 10 has rewritten
+	HostIDSchemeNamespaced HostIDScheme = "namespaced"
+	// HostIDSchemeLegacy encodes the address alone (8_8_8_8), for databases whose
+	// host IDs migration 10 has not rewritten yet
+	HostIDSchemeLegacy HostIDScheme = "legacy"
+)
+
+// NamespacedHostIDsVersion is the schema migration that rewrites legacy host IDs
+// to the namespaced scheme
+const NamespacedHostIDsVersion = 10
+
+// HostIDSchemeForSchema returns the scheme matching a database's applied schema
+// migrations: namespaced once migration 10 has rewritten its hosts, legacy before,
+// since a namespaced host written beside its legacy twin would fail the unique
+// index on host.ip
+func HostIDSchemeForSchema(applied map[int]bool) HostIDScheme {
+	if applied[NamespacedHostIDsVersion] {
+		return HostIDSchemeNamespaced
+	}
+	return HostIDSchemeLegacy
+}
+
+// ParseHostIDScheme parses a host ID scheme name, ignoring case
+func ParseHostIDScheme(s string) (HostIDScheme, error) {
+	switch scheme := HostIDScheme(strings.ToLower(strings.TrimSpace(s))); scheme {
+	case HostIDSchemeNamespaced, HostIDSchemeLegacy:
+		return scheme, nil
+	default:
+		return "", fmt.Errorf("invalid host ID scheme: %q (must be namespaced or legacy)", s)
+	}
+}
+
+// legacyHostRecordIDEncoder maps the separators of both address families onto
+// characters that are safe in a SurrealDB record ID: IPv4 dots become
+// underscores and IPv6 colons become hyphens.
+var (
+	legacyHostRecordIDEncoder = strings.NewReplacer(".", "_", ":", "-")
+	legacyHostRecordIDDecoder = strings.NewReplacer("_", ".", "-", ":")
+)
+
+// HostRecordID returns the host record ID (without the "host:" table prefix)
+// for an IP address under the namespaced scheme, e.g. "ip4_8_8_8_8" or
+// "ip6_2001-4860-4860--8888". Workflows derive host IDs through
+// HostIDScheme.RecordID so ingest and enrichment land on the same node. Addresses are canonicalized first, so
+// equivalent spellings of an IPv6 address, or an IPv4-mapped address and its
+// IPv4 form, share an ID. An input that isn't an IP is hex-encoded under its own
+// namespace, so it can't take an address's ID and still decodes exactly.
+func HostRecordID(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return HostIDPrefixRaw + hex.EncodeToString([]byte(ip))
+	case parsed.To4() != nil:
+		return HostIDPrefixIPv4 + strings.ReplaceAll(parsed.String(), ".", "_")
+	default:
+		return HostIDPrefixIPv6 + strings.ReplaceAll(parsed.String(), ":", "-")
+	}
+}
+
+// RecordID returns the host record ID for an IP under the scheme: HostRecordID
+// when namespaced, the canonical address with its separators encoded when legacy
+func (s HostIDScheme) RecordID(ip string) string {
+	if s != HostIDSchemeLegacy {
+		return HostRecordID(ip)
+	}
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	return legacyHostRecordIDEncoder.Replace(ip)
+}
+
+// IPFromHostRecordID reverses HostRecordID, returning the canonical IP address.
+// A leading "host:" table prefix is ignored. Legacy IDs without a namespace
+// decode too, so hosts not yet migrated still resolve.
+func IPFromHostRecordID(id string) string {
+	id = strings.TrimPrefix(id, "host:")
+	switch {
+	case strings.HasPrefix(id, HostIDPrefixIPv4):
+		return strings.ReplaceAll(strings.TrimPrefix(id, HostIDPrefixIPv4), "_", ".")
+	case strings.HasPrefix(id, HostIDPrefixIPv6):
+		return strings.ReplaceAll(strings.TrimPrefix(id, HostIDPrefixIPv6), "-", ":")
+	case strings.HasPrefix(id, HostIDPrefixRaw):
+		if raw, err := hex.DecodeString(strings.TrimPrefix(id, HostIDPrefixRaw)); err == nil {
+			return string(raw)
+		}
+		return id
+	default:
+		return legacyHostRecordIDDecoder.Replace(id)
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostIDScheme_RecordID(t *testing.T) {
+	assert.Equal(t, "8_8_8_8", HostIDSchemeLegacy.RecordID("8.8.8.8"))
+	assert.Equal(t, "2001-4860-4860--8888", HostIDSchemeLegacy.RecordID("2001:4860:4860:0:0:0:0:8888"))
+	assert.Equal(t, "8_8_8_8", HostIDSchemeLegacy.RecordID("::ffff:8.8.8.8"))
+	assert.Equal(t, HostRecordID("8.8.8.8"), HostIDSchemeNamespaced.RecordID("8.8.8.8"))
+
+	// Legacy IDs decode too, so unmigrated hosts still resolve
+	assert.Equal(t, "8.8.8.8", IPFromHostRecordID("host:8_8_8_8"))
+	assert.Equal(t, "2001:4860:4860::8888", IPFromHostRecordID("2001-4860-4860--8888"))
+}
+
+func TestHostIDScheme_SchemesNeverShareAnID(t *testing.T) {
+	// A namespaced host can't land on the record of a legacy one, so writes
+	// under the wrong scheme fail on idx_host_ip rather than merging silently
+	inputs := []string{"8.8.8.8", "10.0.0.1", "2001:db8::1", "::1", "scanme.example"}
+	legacy := make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		legacy[HostIDSchemeLegacy.RecordID(input)] = input
+	}
+
+	for _, input := range inputs {
+		id := HostIDSchemeNamespaced.RecordID(input)
+		other, clash := legacy[id]
+		assert.False(t, clash, "namespaced %q takes the legacy ID of %q: %s", input, other, id)
+		assert.Equal(t, input, IPFromHostRecordID(id))
+	}
+}
+
+func TestHostIDSchemeForSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied map[int]bool
+		want    HostIDScheme
+	}{
+		{name: "empty database", applied: nil, want: HostIDSchemeLegacy},
+		{name: "before the rewrite", applied: map[int]bool{1: true, 2: true, 9: true}, want: HostIDSchemeLegacy},
+		{name: "rewritten", applied: map[int]bool{1: true, 9: true, 10: true}, want: HostIDSchemeNamespaced},
+		{name: "later migrations", applied: map[int]bool{1: true, 10: true, 15: true}, want: HostIDSchemeNamespaced},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HostIDSchemeForSchema(tt.applied))
+		})
+	}
+}
+
+func TestParseHostIDScheme(t *testing.T) {
+	for input, want := range map[string]HostIDScheme{
+		"namespaced": HostIDSchemeNamespaced,
+		" Legacy ":   HostIDSchemeLegacy,
+		"NAMESPACED": HostIDSchemeNamespaced,
+	} {
+		scheme, err := ParseHostIDScheme(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, scheme, input)
+	}
+
+	_, err := ParseHostIDScheme("hashed")
+	assert.Error(t, err)
+	_, err = ParseHostIDScheme("")
+	assert.Error(t, err, "an unset scheme is left to the schema, not parsed")
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -13,18 +16,46 @@ const (
 	JobStateProcessing JobState = "processing"
 	JobStateCompleted  JobState = "completed"
 	JobStateFailed     JobState = "failed"
+	JobStateCancelled  JobState = "cancelled"
 )
 
 // IsValid checks if the job state is one of the allowed values
 func (s JobState) IsValid() bool {
 	switch s {
-	case JobStatePending, JobStateProcessing, JobStateCompleted, JobStateFailed:
+	case JobStatePending, JobStateProcessing, JobStateCompleted, JobStateFailed, JobStateCancelled:
 		return true
 	default:
 		return false
 	}
 }
 
+// IsTerminal reports whether the job state is final (completed, failed or cancelled)
+func (s JobState) IsTerminal() bool {
+	switch s {
+	case JobStateCompleted, JobStateFailed, JobStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	// ErrInvalidJobState indicates a string is not one of the known job states
+	ErrInvalidJobState = errors.New("invalid job state")
+
+	// ErrInvalidTransition indicates a job cannot move from its current state to the requested one
+	ErrInvalidTransition = errors.New("invalid state transition")
+)
+
+// ParseJobState converts a string into a JobState, rejecting unknown values
+func ParseJobState(s string) (JobState, error) {
+	state := JobState(s)
+	if !state.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidJobState, s)
+	}
+	return state, nil
+}
+
 // String returns the string representation of the JobState
 func (s JobState) String() string {
 	return string(s)
@@ -41,6 +72,38 @@ type Job struct {
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
 	HostCount    int        `json:"host_count"`    // Number of hosts processed
 	PortCount    int        `json:"port_count"`    // Number of ports processed
+	Progress      *JobProgress     `json:"progress,omitempty"`          // Hosts persisted so far while processing
+	StepDurations map[string]int64 `json:"step_durations_ms,omitempty"` // Milliseconds spent in each completed workflow step
+
+	// Completion callback outcome, only set when the job was submitted with a callback URL
+	CallbackState    string  `json:"callback_state,omitempty"`    // delivered or failed
+	CallbackAttempts int     `json:"callback_attempts,omitempty"` // Delivery attempts made
+	CallbackError    *string `json:"callback_error,omitempty"`    // Last delivery error if failed
+}
+
+// Workflow step names recorded in Job.StepDurations
+const (
+	JobStepParse   = "parse"
+	JobStepPersist = "persist"
+	JobStepEnrich  = "enrich" // queueing the scan's services for enrichment
+)
+
+// JobProgress tracks how many of a job's hosts have been persisted
+type JobProgress struct {
+	HostsDone  int `json:"hosts_done"`
+	HostsTotal int `json:"hosts_total"`
+}
+
+// Percent returns the completed fraction as a percentage (0-100)
+// A job with no hosts is reported as complete
+func (p JobProgress) Percent() float64 {
+	if p.HostsTotal <= 0 {
+		return 100
+	}
+	if p.HostsDone >= p.HostsTotal {
+		return 100
+	}
+	return float64(p.HostsDone) / float64(p.HostsTotal) * 100
 }
 
 // JobStateTransition defines allowed state transitions
@@ -54,12 +117,14 @@ var AllowedTransitions = map[JobStateTransition]bool{
 	// From pending
 	{JobStatePending, JobStateProcessing}: true,
 	{JobStatePending, JobStateFailed}:     true,
+	{JobStatePending, JobStateCancelled}:  true,
 
 	// From processing
 	{JobStateProcessing, JobStateCompleted}: true,
 	{JobStateProcessing, JobStateFailed}:    true,
+	{JobStateProcessing, JobStateCancelled}: true,
 
-	// Terminal states (completed/failed) cannot transition further
+	// Terminal states (completed/failed/cancelled) cannot transition further
 	// This is enforced by the absence of transitions from these states
 }
 
@@ -84,14 +149,14 @@ func (j *Job) CanTransition(newState JobState) bool {
 // Returns an error if the transition is not allowed
 func (j *Job) TransitionTo(newState JobState) error {
 	if !j.CanTransition(newState) {
-		return fmt.Errorf("invalid state transition from %s to %s", j.State, newState)
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, j.State, newState)
 	}
 
 	j.State = newState
 	j.UpdatedAt = time.Now().UTC()
 
 	// Set completed_at for terminal states
-	if newState == JobStateCompleted || newState == JobStateFailed {
+	if newState.IsTerminal() {
 		now := time.Now().UTC()
 		j.CompletedAt = &now
 	}
@@ -105,30 +170,80 @@ func (j *Job) SetError(errMsg string) error {
 	return j.TransitionTo(JobStateFailed)
 }
 
+// ScanSourceUnknown is recorded for scans submitted without naming the tool that produced them
+const ScanSourceUnknown = "unknown"
+
+// scanSourcePattern is what a scan source may look like: a short tool name such as
+// naabu, nmap, masscan or a custom scanner's name
+var scanSourcePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+// NormalizeScanSource lowercases and trims the name of the tool that produced a scan,
+// returning ScanSourceUnknown when it is empty and an error when it isn't a plausible
+// tool name
+func NormalizeScanSource(source string) (string, error) {
+	source = strings.ToLower(strings.TrimSpace(source))
+	if source == "" {
+		return ScanSourceUnknown, nil
+	}
+	if !scanSourcePattern.MatchString(source) {
+		return "", fmt.Errorf("invalid scan source %q: must be up to 32 letters, digits, '.', '_' or '-'", source)
+	}
+	return source, nil
+}
+
 // IngestWorkflowRequest represents the request to the ingest workflow
 type IngestWorkflowRequest struct {
-	JobID      string `json:"job_id"`
-	ScannerKey string `json:"scanner_key"`
-	ScanData   []byte `json:"scan_data"` // Raw JSON scan data
+	JobID       string `json:"job_id"`
+	ScannerKey  string `json:"scanner_key"`
+	ScanData    []byte `json:"scan_data"`              // Raw JSON scan data
+	CallbackURL string `json:"callback_url,omitempty"` // Notified with a JobCallbackPayload when the job finishes
+	Source      string `json:"source,omitempty"`       // Tool that produced the scan, e.g. naabu; empty is recorded as ScanSourceUnknown
+}
+
+// JobCallbackPayload is POSTed to a job's callback URL once the ingest
+// workflow reaches a terminal state
+type JobCallbackPayload struct {
+	JobID        string   `json:"job_id"`
+	State        JobState `json:"state"`
+	HostCount    int      `json:"host_count"`
+	PortCount    int      `json:"port_count"`
+	SkippedHosts int      `json:"skipped_hosts"`
+	Error        string   `json:"error,omitempty"` // Set when the job failed
 }
 
+// Callback delivery outcomes recorded in Job.CallbackState
+const (
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
 // IngestWorkflowResponse represents the response from the ingest workflow
 type IngestWorkflowResponse struct {
 	JobID     string `json:"job_id"`
 	State     JobState `json:"state"`
 	HostCount int    `json:"host_count"`
 	PortCount int    `json:"port_count"`
+	SkippedHosts int `json:"skipped_hosts"` // Private/reserved hosts dropped during parsing
 }
 
 // ScanData represents the parsed scan data structure (Naabu format)
 type ScanData struct {
-	Hosts []ScanHost `json:"hosts"`
+	Hosts        []ScanHost `json:"hosts"`
+	SkippedHosts int        `json:"skipped_hosts,omitempty"` // Hosts rejected as private/reserved
+	// Source is the tool that produced the scan, stamped on every HAS edge it writes
+	// as discovered_by
+	Source string `json:"source,omitempty"`
 }
 
 // ScanHost represents a scanned host with its ports
 type ScanHost struct {
 	IP    string     `json:"ip"`
 	Ports []ScanPort `json:"ports"`
+
+	// Observation window from the scanner's per-result timestamps; zero when
+	// the scanner didn't report any, in which case the ingest time is used
+	FirstSeen time.Time `json:"first_seen,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty"`
 }
 
 // ScanPort represents a scanned port
@@ -136,6 +251,29 @@ type ScanPort struct {
 	Number   int    `json:"number"`
 	Protocol string `json:"protocol"` // tcp, udp
 	State    string `json:"state"`    // open, closed, filtered
+
+	// Service detected on the port, if the scanner identified one
+	Service *ScanService `json:"service,omitempty"`
+}
+
+// MaxBannerSample is the most of a scanned banner that is stored
+const MaxBannerSample = 2048
+
+// ScanService is a service identified on a scanned port
+// Services with the same name, product and version share one service node.
+type ScanService struct {
+	Name    string `json:"name"`              // e.g. http, ssh
+	Product string `json:"product,omitempty"` // e.g. nginx, openssh
+	Version string `json:"version,omitempty"` // e.g. 1.24.0
+
+	// Banner is the raw banner the scanner read from the port, if it reported one;
+	// ingest stores it as the service's evidence, capped at MaxBannerSample bytes
+	Banner string `json:"banner,omitempty"`
+
+	// Guessed marks a service named from the port number alone, when the scan
+	// identified nothing on the port; it is stored with low confidence. It is
+	// serialized so it survives the workflow's journaled steps.
+	Guessed bool `json:"guessed,omitempty"`
 }
 
 // JobListRequest represents the parameters for listing jobs
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// JobEventType is the SSE event name for job state transitions
+const JobEventType = "job_state"
+
+// JobEvent records a job entering a new state
+// IDs are time-ordered (UUID v7), so clients can resume a stream after the last ID they saw.
+type JobEvent struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"job_id"`
+	ScannerKey string    `json:"scanner_key"`
+	State      JobState  `json:"state"`
+	Error      string    `json:"error,omitempty"` // Set when the job failed
+	HostCount  int       `json:"host_count,omitempty"`
+	PortCount  int       `json:"port_count,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
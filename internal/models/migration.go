@@ -0,0 +1,20 @@
+package models
+
+// MigrationInfo identifies one schema migration
+type MigrationInfo struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// MigrateRequest asks the API to bring the graph schema up to date
+type MigrateRequest struct {
+	DryRun bool `json:"dry_run,omitempty"` // report pending migrations without applying them
+}
+
+// MigrateResponse reports the outcome of a schema migration run
+type MigrateResponse struct {
+	CurrentVersion int             `json:"current_version"` // highest version applied once the run finished
+	LatestVersion  int             `json:"latest_version"`  // highest version the server knows about
+	Applied        []MigrationInfo `json:"applied"`         // migrations applied by this run, or pending ones on a dry run
+	DryRun         bool            `json:"dry_run,omitempty"`
+}
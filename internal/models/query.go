@@ -17,6 +17,18 @@ type HostQueryResponse struct {
 	Ports       []PortDetail    `json:"ports,omitempty"`
 	Services    []ServiceDetail `json:"services,omitempty"`
 	Vulns       []VulnDetail    `json:"vulnerabilities,omitempty"`
+	VulnSummary *VulnSummary    `json:"vuln_summary,omitempty"` // set when the query depth includes vulnerabilities
+	Source      string          `json:"source,omitempty"`       // mesh that answered, set by federated queries
+}
+
+// VulnSummary counts a host's vulnerabilities by severity
+// Vulnerabilities with no severity or CVSS are counted in neither bucket.
+type VulnSummary struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	KEV      int `json:"kev"` // listed in the CISA KEV catalog, whatever their severity
 }
 
 // PortDetail represents a port with its relationships
@@ -27,6 +39,10 @@ type PortDetail struct {
 	FirstSeen time.Time       `json:"first_seen"`
 	LastSeen  time.Time       `json:"last_seen"`
 	Services  []ServiceDetail `json:"services,omitempty"`
+
+	// DiscoveredBy is the tool whose scan first found the port on this host, e.g.
+	// naabu, or "unknown" when the scan didn't say
+	DiscoveredBy string `json:"discovered_by,omitempty"`
 }
 
 // ServiceDetail represents a service with its metadata
@@ -72,6 +88,17 @@ func ValidateDepth(depth int) bool {
 	return depth >= 0 && depth <= int(DepthMaximum)
 }
 
+// ClampDepth limits depth to [0, DepthMaximum]
+func ClampDepth(depth int) int {
+	if depth < 0 {
+		return 0
+	}
+	if depth > int(DepthMaximum) {
+		return int(DepthMaximum)
+	}
+	return depth
+}
+
 // DefaultDepth returns the default query depth
 func DefaultDepth() QueryDepth {
 	return DepthWithServices
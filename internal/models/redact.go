@@ -0,0 +1,15 @@
+package models
+
+// RedactRequest names the hosts to remove from the graph
+// Exactly one of IP and CIDR is set.
+type RedactRequest struct {
+	IP   string `json:"ip,omitempty"`   // A single host
+	CIDR string `json:"cidr,omitempty"` // Every host inside this network
+}
+
+// RedactResponse counts the records a redaction removed
+type RedactResponse struct {
+	Hosts int `json:"hosts"`
+	Ports int `json:"ports"` // ports left without any host once the hosts were removed
+	Edges int `json:"edges"` // HAS, geo, IN_ASN and IN_CLOUD_REGION edges of the hosts, plus the removed ports' edges
+}
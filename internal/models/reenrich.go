@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Limits on how much a single re-enrichment request may queue
+const (
+	// DefaultReenrichLimit caps the hosts and the services queued when a request sets no limit
+	DefaultReenrichLimit = 1000
+	// MaxReenrichLimit is the largest limit a re-enrichment request may set
+	MaxReenrichLimit = 100000
+)
+
+// ReenrichRequest selects hosts and services to run through enrichment again
+// With only host filters (ASN, Country) the matching hosts are queued for ASN
+// and GeoIP enrichment. With a service filter (MissingCPE, StaleBefore) the
+// matching services on those hosts are queued for CPE enrichment instead.
+type ReenrichRequest struct {
+	ASN         int        `json:"asn,omitempty"`          // Hosts announced by this ASN
+	Country     string     `json:"country,omitempty"`      // Hosts located in this country
+	MissingCPE  bool       `json:"missing_cpe,omitempty"`  // Services with no CPE identifiers
+	StaleBefore *time.Time `json:"stale_before,omitempty"` // Services whose CPEs were last updated before this time
+	Limit       int        `json:"limit,omitempty"`        // Maximum hosts and maximum services to queue
+}
+
+// HasHostFilter reports whether the request selects hosts
+func (r ReenrichRequest) HasHostFilter() bool {
+	return r.ASN > 0 || r.Country != ""
+}
+
+// HasServiceFilter reports whether the request selects services
+func (r ReenrichRequest) HasServiceFilter() bool {
+	return r.MissingCPE || r.StaleBefore != nil
+}
+
+// ReenrichService is a service selected for CPE re-enrichment
+type ReenrichService struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Product string `json:"product"`
+	Version string `json:"version"`
+}
+
+// ReenrichResponse reports what a re-enrichment request queued
+type ReenrichResponse struct {
+	HostsQueued    int `json:"hosts_queued"`
+	ServicesQueued int `json:"services_queued"`
+	Batches        int `json:"batches"` // Workflow invocations sent
+}
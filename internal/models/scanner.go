@@ -0,0 +1,42 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// ScannerID returns the public identifier of a scanner key: the hex SHA-256 of
+// the key. Stats and audit entries name scanners by it, so neither URLs nor
+// logs carry the key itself.
+func ScannerID(scannerKey string) string {
+	sum := sha256.Sum256([]byte(scannerKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeScannerID lowercases id and reports whether it has the form ScannerID returns
+func NormalizeScannerID(id string) (string, bool) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if len(id) != sha256.Size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// ScannerStats summarizes what one scanner contributed, aggregated over its jobs
+type ScannerStats struct {
+	ScannerID        string     `json:"scanner_id"`                  // ScannerID of the key
+	Jobs             int        `json:"jobs"`                        // Jobs in any state
+	Completed        int        `json:"completed"`                   // Jobs that completed
+	Failed           int        `json:"failed"`                      // Jobs that failed
+	Cancelled        int        `json:"cancelled"`                   // Jobs that were cancelled
+	Hosts            int        `json:"hosts"`                       // Hosts processed, summed over jobs (a host in two scans counts twice)
+	Ports            int        `json:"ports"`                       // Ports processed, summed over jobs
+	SuccessRate      *float64   `json:"success_rate,omitempty"`      // Completed / (completed + failed); absent before any finished
+	FirstContributed *time.Time `json:"first_contributed,omitempty"` // Creation time of the oldest job
+	LastContributed  *time.Time `json:"last_contributed,omitempty"`  // Creation time of the newest job
+}
@@ -7,6 +7,42 @@ type SimilarRequest struct {
 
 	// K is the number of results to return (optional, default 10)
 	K *int `json:"k,omitempty"`
+
+	// Mode selects the search strategy: vector (default), keyword, or hybrid
+	Mode SearchMode `json:"mode,omitempty"`
+
+	// Rerank blends the similarity score with CVSS, EPSS, KEV status and
+	// recency to produce FinalScore, and orders results by it (optional).
+	// Every page reranks the same MaxSearchWindow most similar results.
+	Rerank bool `json:"rerank,omitempty"`
+
+	// Offset skips this many results; pass a previous response's NextOffset
+	// to fetch the following page (optional, default 0)
+	Offset int `json:"offset,omitempty"`
+
+	// MinScore drops results whose similarity score is below it (optional, 0.0 to 1.0)
+	MinScore float64 `json:"min_score,omitempty"`
+}
+
+// SearchMode selects how /v1/query/similar finds matching documents
+type SearchMode string
+
+const (
+	// SearchModeVector ranks documents by embedding similarity
+	SearchModeVector SearchMode = "vector"
+	// SearchModeKeyword matches CVE IDs and title/summary text
+	SearchModeKeyword SearchMode = "keyword"
+	// SearchModeHybrid fuses vector and keyword results with reciprocal-rank fusion
+	SearchModeHybrid SearchMode = "hybrid"
+)
+
+// IsValid reports whether the mode is a known search mode
+func (m SearchMode) IsValid() bool {
+	switch m {
+	case SearchModeVector, SearchModeKeyword, SearchModeHybrid:
+		return true
+	}
+	return false
 }
 
 // SimilarResponse represents the response from a similarity search
@@ -20,6 +56,21 @@ type SimilarResponse struct {
 	// Count is the number of results returned
 	Count int `json:"count"`
 
+	// Offset is the number of results skipped before this page
+	Offset int `json:"offset"`
+
+	// HasMore reports whether further results follow this page
+	HasMore bool `json:"has_more"`
+
+	// NextOffset is the offset of the following page, set only when HasMore is true
+	NextOffset *int `json:"next_offset,omitempty"`
+
+	// MinScore echoes the request's score floor
+	MinScore float64 `json:"min_score,omitempty"`
+
+	// BelowMinScore counts the fetched results dropped for scoring under MinScore
+	BelowMinScore int `json:"below_min_score,omitempty"`
+
 	// Timestamp is when the search was performed
 	Timestamp string `json:"timestamp"`
 }
@@ -41,11 +92,20 @@ type VulnResult struct {
 	// CPE is the list of affected CPEs
 	CPE []string `json:"cpe,omitempty"`
 
+	// EPSS is the exploit prediction score (0.0 to 1.0)
+	EPSS float64 `json:"epss,omitempty"`
+
+	// KEV indicates the vulnerability is in the CISA Known Exploited Vulnerabilities catalog
+	KEV bool `json:"kev,omitempty"`
+
 	// PublishedDate is when the vulnerability was published
 	PublishedDate string `json:"published_date,omitempty"`
 
 	// Score is the similarity score (0.0 to 1.0, higher is more similar)
 	Score float64 `json:"score"`
+
+	// FinalScore is the reranked score (0.0 to 1.0), set only when reranking is requested
+	FinalScore float64 `json:"final_score,omitempty"`
 }
 
 // ErrorResponse represents an error response from the API
@@ -59,17 +119,25 @@ type ErrorResponse struct {
 	// Details provides additional error context
 	Details string `json:"details,omitempty"`
 
+	// Errors lists field-level validation failures
+	Errors []ValidationError `json:"errors,omitempty"`
+
 	// Timestamp is when the error occurred
 	Timestamp string `json:"timestamp"`
 }
 
-// Validate validates a SimilarRequest
+// Validate validates a SimilarRequest against the default limits
 func (r *SimilarRequest) Validate() error {
+	return r.ValidateWithLimits(MaxK, MaxQueryLength)
+}
+
+// ValidateWithLimits validates a SimilarRequest against the given maximum K and query length
+func (r *SimilarRequest) ValidateWithLimits(maxK, maxQueryLength int) error {
 	if r.Query == "" {
 		return ErrEmptyQuery
 	}
 
-	if len(r.Query) > MaxQueryLength {
+	if len(r.Query) > maxQueryLength {
 		return ErrQueryTooLong
 	}
 
@@ -78,14 +146,37 @@ func (r *SimilarRequest) Validate() error {
 		if *r.K < 1 {
 			return ErrInvalidK
 		}
-		if *r.K > MaxK {
+		if *r.K > maxK {
 			return ErrKTooLarge
 		}
 	}
 
+	if r.Mode != "" && !r.Mode.IsValid() {
+		return ErrInvalidMode
+	}
+
+	if r.Offset < 0 {
+		return ErrInvalidOffset
+	}
+	if r.Offset > MaxOffset {
+		return ErrOffsetTooLarge
+	}
+
+	if r.MinScore < 0 || r.MinScore > 1 {
+		return ErrInvalidMinScore
+	}
+
 	return nil
 }
 
+// GetMode returns the search mode or the default if not set
+func (r *SimilarRequest) GetMode() SearchMode {
+	if r.Mode == "" {
+		return SearchModeVector
+	}
+	return r.Mode
+}
+
 // GetK returns the K value or the default if not set
 func (r *SimilarRequest) GetK() int {
 	if r.K == nil {
@@ -94,29 +185,58 @@ func (r *SimilarRequest) GetK() int {
 	return *r.K
 }
 
+// SearchWindow returns how many results to ask the search backend for: every
+// result up to the end of the requested page, plus one to tell whether more follow.
+// A reranked search always asks for MaxSearchWindow, since reranking a window
+// that grew with the offset would reorder it differently for every page.
+func (r *SimilarRequest) SearchWindow() int {
+	if r.Rerank {
+		return MaxSearchWindow
+	}
+	return min(r.Offset+r.GetK()+1, MaxSearchWindow)
+}
+
 // Constants for validation and defaults
 const (
 	// DefaultK is the default number of results to return
 	DefaultK = 10
 
-	// MaxK is the maximum number of results allowed
+	// MaxK is the default maximum number of results allowed
 	MaxK = 50
 
-	// MaxQueryLength is the maximum query string length
+	// MaxQueryLength is the default maximum query string length
 	MaxQueryLength = 500
+
+	// MaxOffset is the largest offset a similarity search may page to
+	MaxOffset = 200
+
+	// MaxSearchWindow bounds how many results one search asks the backend for
+	MaxSearchWindow = 500
 )
 
 // Error types for validation
 var (
 	// ErrEmptyQuery indicates the query is empty
-	ErrEmptyQuery = &ValidationError{Field: "query", Message: "query cannot be empty"}
+	ErrEmptyQuery = &ValidationError{Field: "query", Rule: RuleRequired, Message: "query cannot be empty"}
 
 	// ErrQueryTooLong indicates the query exceeds max length
-	ErrQueryTooLong = &ValidationError{Field: "query", Message: "query exceeds maximum length"}
+	ErrQueryTooLong = &ValidationError{Field: "query", Rule: RuleMaxLength, Message: "query exceeds maximum length"}
 
 	// ErrInvalidK indicates K is invalid
-	ErrInvalidK = &ValidationError{Field: "k", Message: "k must be greater than 0"}
+	ErrInvalidK = &ValidationError{Field: "k", Rule: RuleMin, Message: "k must be greater than 0"}
 
 	// ErrKTooLarge indicates K exceeds maximum
-	ErrKTooLarge = &ValidationError{Field: "k", Message: "k exceeds maximum allowed value"}
+	ErrKTooLarge = &ValidationError{Field: "k", Rule: RuleMax, Message: "k exceeds maximum allowed value"}
+
+	// ErrInvalidMode indicates the search mode is unknown
+	ErrInvalidMode = &ValidationError{Field: "mode", Rule: RuleEnum, Message: "mode must be one of vector, keyword, hybrid"}
+
+	// ErrInvalidOffset indicates the offset is negative
+	ErrInvalidOffset = &ValidationError{Field: "offset", Rule: RuleMin, Message: "offset cannot be negative"}
+
+	// ErrOffsetTooLarge indicates the offset exceeds MaxOffset
+	ErrOffsetTooLarge = &ValidationError{Field: "offset", Rule: RuleMax, Message: "offset exceeds maximum allowed value"}
+
+	// ErrInvalidMinScore indicates the score floor is outside 0.0 to 1.0
+	ErrInvalidMinScore = &ValidationError{Field: "min_score", Rule: RuleMax, Message: "min_score must be between 0.0 and 1.0"}
 )
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cvePattern matches a CVE identifier such as CVE-2021-44228
+var cvePattern = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+
+// NormalizeCVEID upper-cases and trims a CVE identifier, reporting whether it is well formed
+func NormalizeCVEID(cve string) (string, bool) {
+	normalized := strings.ToUpper(strings.TrimSpace(cve))
+	return normalized, cvePattern.MatchString(normalized)
+}
+
+// VulnDetailResponse is the full detail of a single CVE, combining the vuln node,
+// its vuln_doc and the number of hosts it affects
+type VulnDetailResponse struct {
+	CVEID         string     `json:"cve_id"`
+	Title         string     `json:"title,omitempty"`
+	Summary       string     `json:"summary,omitempty"`
+	CVSS          float64    `json:"cvss"`
+	CVSSVector    string     `json:"cvss_vector,omitempty"`
+	Severity      string     `json:"severity,omitempty"`
+	EPSS          float64    `json:"epss"`
+	KEVFlag       bool       `json:"kev_flag"`
+	CPEs          []string   `json:"cpe,omitempty"`
+	References    []string   `json:"references,omitempty"`
+	AffectedHosts int        `json:"affected_hosts"`
+	PublishedDate *time.Time `json:"published_date,omitempty"`
+	LastModified  *time.Time `json:"last_modified,omitempty"`
+	FirstSeen     time.Time  `json:"first_seen"`
+	LastUpdated   time.Time  `json:"last_updated"`
+}
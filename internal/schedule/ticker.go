@@ -0,0 +1,89 @@
+// Package schedule provides tickers for periodic background work.
+// Jittered tickers keep replicas started together from running their
+// cleanup at the same moment.
+package schedule
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// DefaultJitter spreads background ticks by up to 10% either side of their interval
+const DefaultJitter = 0.1
+
+// Ticker delivers ticks at intervals spread randomly around a mean interval
+// Like time.Ticker, it drops ticks for a slow receiver. With zero jitter every
+// interval is exactly the mean.
+type Ticker struct {
+	C <-chan time.Time
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewTicker starts a ticker whose intervals are drawn uniformly from
+// [interval*(1-jitter), interval*(1+jitter)]. Jitter is clamped to [0, 1).
+// The interval must be positive.
+func NewTicker(interval time.Duration, jitter float64) *Ticker {
+	if interval <= 0 {
+		panic("schedule: non-positive interval for NewTicker")
+	}
+
+	c := make(chan time.Time, 1)
+	t := &Ticker{C: c, stop: make(chan struct{}), done: make(chan struct{})}
+	go t.run(c, interval, jitter)
+	return t
+}
+
+// Stop turns off the ticker; no more ticks are sent after it returns
+func (t *Ticker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+	<-t.done
+}
+
+// run sends a tick after each jittered interval until Stop is called
+func (t *Ticker) run(c chan<- time.Time, interval time.Duration, jitter float64) {
+	defer close(t.done)
+
+	timer := time.NewTimer(Jittered(interval, jitter))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-timer.C:
+			select {
+			case c <- now:
+			default:
+			}
+			timer.Reset(Jittered(interval, jitter))
+		}
+	}
+}
+
+// Jittered returns interval scaled by a random factor in [1-jitter, 1+jitter]
+// Jitter is clamped to [0, 1), so the result is always positive for a positive
+// interval and averages to interval.
+func Jittered(interval time.Duration, jitter float64) time.Duration {
+	jitter = clampJitter(jitter)
+	if jitter == 0 {
+		return interval
+	}
+	factor := 1 + jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(interval) * factor)
+}
+
+// clampJitter limits jitter to [0, 1)
+func clampJitter(jitter float64) float64 {
+	switch {
+	case jitter <= 0:
+		return 0
+	case jitter >= 1:
+		return 0.99
+	default:
+		return jitter
+	}
+}
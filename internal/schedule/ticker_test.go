@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJittered_StaysWithinBounds(t *testing.T) {
+	const interval = time.Minute
+	const jitter = 0.2
+	min := time.Duration(float64(interval) * (1 - jitter))
+	max := time.Duration(float64(interval) * (1 + jitter))
+
+	var total time.Duration
+	const samples = 10000
+	for i := 0; i < samples; i++ {
+		d := Jittered(interval, jitter)
+		assert.GreaterOrEqual(t, d, min)
+		assert.LessOrEqual(t, d, max)
+		total += d
+	}
+
+	// The configured interval stays the mean
+	mean := total / samples
+	assert.InDelta(t, float64(interval), float64(mean), float64(interval)/50)
+}
+
+func TestJittered_ZeroJitterIsDeterministic(t *testing.T) {
+	for _, jitter := range []float64{0, -0.5} {
+		assert.Equal(t, time.Second, Jittered(time.Second, jitter))
+	}
+}
+
+func TestJittered_ClampsJitter(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		d := Jittered(time.Second, 5)
+		assert.Positive(t, d)
+		assert.Less(t, d, 2*time.Second)
+	}
+}
+
+func TestTicker_IntervalsWithinBounds(t *testing.T) {
+	const interval = 40 * time.Millisecond
+	const jitter = 0.5
+	min := time.Duration(float64(interval) * (1 - jitter))
+	max := time.Duration(float64(interval) * (1 + jitter))
+	// Timers never fire early, but may fire late on a loaded machine
+	const slack = 30 * time.Millisecond
+
+	ticker := NewTicker(interval, jitter)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for i := 0; i < 5; i++ {
+		tick := <-ticker.C
+		gap := tick.Sub(last)
+		assert.GreaterOrEqual(t, gap, min, "tick %d came early", i)
+		assert.LessOrEqual(t, gap, max+slack, "tick %d came late", i)
+		last = tick
+	}
+}
+
+func TestTicker_Stop(t *testing.T) {
+	ticker := NewTicker(10*time.Millisecond, 0)
+	<-ticker.C
+	ticker.Stop()
+	ticker.Stop() // idempotent
+
+	// Drain a tick sent before Stop, then nothing more arrives
+	select {
+	case <-ticker.C:
+	default:
+	}
+	select {
+	case <-ticker.C:
+		t.Fatal("tick after Stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrCallbacksDisabled is returned when a callback is requested but no hosts are allowlisted
+	ErrCallbacksDisabled = errors.New("callbacks are not enabled on this server")
+	// ErrInvalidCallbackURL is returned when a callback URL can't be parsed or isn't absolute
+	ErrInvalidCallbackURL = errors.New("invalid callback URL")
+	// ErrCallbackScheme is returned for callback URLs that don't use https
+	ErrCallbackScheme = errors.New("callback URL must use https")
+	// ErrCallbackHostNotAllowed is returned for callback hosts outside the allowlist
+	ErrCallbackHostNotAllowed = errors.New("callback host is not allowlisted")
+)
+
+// Allowlist restricts which hosts job callbacks may be delivered to, so a
+// submitter can't point the workflow service at internal addresses (SSRF).
+// Entries are exact hostnames or IPs ("hooks.example.com", "203.0.113.7"), or
+// a leading-dot suffix (".example.com") that matches any subdomain.
+type Allowlist struct {
+	hosts    map[string]bool
+	suffixes []string
+}
+
+// NewAllowlist builds an allowlist from host entries; blank entries are ignored
+func NewAllowlist(entries []string) *Allowlist {
+	a := &Allowlist{hosts: make(map[string]bool)}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "" || entry == ".":
+			continue
+		case strings.HasPrefix(entry, "."):
+			a.suffixes = append(a.suffixes, entry)
+		default:
+			a.hosts[entry] = true
+		}
+	}
+	return a
+}
+
+// ParseAllowlist builds an allowlist from a comma-separated list of host entries
+func ParseAllowlist(value string) *Allowlist {
+	return NewAllowlist(strings.Split(value, ","))
+}
+
+// Enabled reports whether any host is allowlisted
+func (a *Allowlist) Enabled() bool {
+	return a != nil && (len(a.hosts) > 0 || len(a.suffixes) > 0)
+}
+
+// Validate checks that rawURL is an absolute https URL without credentials
+// whose host is allowlisted
+func (a *Allowlist) Validate(rawURL string) error {
+	if !a.Enabled() {
+		return ErrCallbacksDisabled
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return ErrInvalidCallbackURL
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials are not allowed", ErrInvalidCallbackURL)
+	}
+	if u.Scheme != "https" {
+		return ErrCallbackScheme
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if a.allows(host) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrCallbackHostNotAllowed, host)
+}
+
+// allows reports whether a normalized hostname matches an entry
+func (a *Allowlist) allows(host string) bool {
+	if host == "" {
+		return false
+	}
+	if a.hosts[host] {
+		return true
+	}
+	for _, suffix := range a.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
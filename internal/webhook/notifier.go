@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers set on every callback request
+const (
+	// TimestampHeader carries the Unix time the payload was signed at
+	TimestampHeader = "X-Spectra-Timestamp"
+	// SignatureHeader carries "sha256=" + hex HMAC-SHA256 of "<timestamp>.<body>"
+	SignatureHeader = "X-Spectra-Signature"
+)
+
+// DefaultTimeout bounds a single delivery attempt
+const DefaultTimeout = 10 * time.Second
+
+// DeliveryError describes a failed delivery attempt
+type DeliveryError struct {
+	StatusCode int   // HTTP status from the receiver; 0 if no response was received
+	Err        error // Underlying transport or validation error, if any
+}
+
+func (e *DeliveryError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("callback returned HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("callback delivery failed: %v", e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether a later attempt could succeed: transport errors,
+// timeouts, rate limiting and server errors are retried; other client errors
+// and rejected URLs are not
+func (e *DeliveryError) Retryable() bool {
+	switch {
+	case e.StatusCode == 0:
+		return e.Err != nil && !isValidationError(e.Err)
+	case e.StatusCode == http.StatusRequestTimeout, e.StatusCode == http.StatusTooManyRequests:
+		return true
+	default:
+		return e.StatusCode >= 500
+	}
+}
+
+// Notifier delivers signed JSON payloads to allowlisted callback URLs
+type Notifier struct {
+	allowlist *Allowlist
+	secret    []byte
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewNotifier creates a notifier that signs payloads with secret. A nil client
+// uses one with DefaultTimeout. Redirects are never followed, since a receiver
+// could otherwise bounce the request to a host outside the allowlist.
+func NewNotifier(allowlist *Allowlist, secret []byte, client *http.Client) *Notifier {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Notifier{
+		allowlist: allowlist,
+		secret:    secret,
+		client:    &noRedirects,
+		now:       time.Now,
+	}
+}
+
+// Allowlist returns the hosts the notifier may deliver to
+func (n *Notifier) Allowlist() *Allowlist {
+	return n.allowlist
+}
+
+// Deliver makes a single signed POST of payload to callbackURL. Any non-2xx
+// response is returned as a *DeliveryError.
+func (n *Notifier) Deliver(ctx context.Context, callbackURL string, payload interface{}) error {
+	if err := n.allowlist.Validate(callbackURL); err != nil {
+		return &DeliveryError{Err: err}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal callback payload: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(n.now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return &DeliveryError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(n.secret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return &DeliveryError{Err: err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &DeliveryError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the signature header value for a callback body
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a callback signature in constant time; receivers should also
+// reject stale timestamps to prevent replays
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// isValidationError reports whether err came from the allowlist check
+func isValidationError(err error) bool {
+	for _, target := range []error{ErrCallbacksDisabled, ErrInvalidCallbackURL, ErrCallbackScheme, ErrCallbackHostNotAllowed} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestNotifier returns a notifier allowlisting the TLS test server's host
+func newTestNotifier(t *testing.T, server *httptest.Server, secret string) *Notifier {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	notifier := NewNotifier(NewAllowlist([]string{u.Hostname()}), []byte(secret), server.Client())
+	notifier.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return notifier
+}
+
+func TestNotifier_DeliverSignsPayload(t *testing.T) {
+	var gotBody []byte
+	var gotHeaders http.Header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, server, "s3cret")
+	err := notifier.Deliver(context.Background(), server.URL+"/hook", map[string]string{"job_id": "job-1"})
+	require.NoError(t, err)
+
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal(gotBody, &payload))
+	assert.Equal(t, "job-1", payload["job_id"])
+	assert.Equal(t, "application/json", gotHeaders.Get("Content-Type"))
+	assert.Equal(t, "1700000000", gotHeaders.Get(TimestampHeader))
+	assert.True(t, Verify([]byte("s3cret"), gotHeaders.Get(TimestampHeader), gotBody, gotHeaders.Get(SignatureHeader)))
+	assert.False(t, Verify([]byte("wrong"), gotHeaders.Get(TimestampHeader), gotBody, gotHeaders.Get(SignatureHeader)))
+}
+
+func TestNotifier_DeliverRejectsNonAllowlistedURL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not be sent")
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(NewAllowlist([]string{"hooks.example.com"}), []byte("s3cret"), server.Client())
+	err := notifier.Deliver(context.Background(), server.URL, map[string]string{})
+
+	var deliveryErr *DeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	assert.ErrorIs(t, err, ErrCallbackHostNotAllowed)
+	assert.False(t, deliveryErr.Retryable())
+}
+
+func TestNotifier_DeliverDoesNotFollowRedirects(t *testing.T) {
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("redirect should not be followed")
+	}))
+	defer internal.Close()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, server, "s3cret")
+	err := notifier.Deliver(context.Background(), server.URL, map[string]string{})
+
+	var deliveryErr *DeliveryError
+	require.ErrorAs(t, err, &deliveryErr)
+	assert.Equal(t, http.StatusTemporaryRedirect, deliveryErr.StatusCode)
+	assert.False(t, deliveryErr.Retryable())
+}
+
+func TestDeliveryError_Retryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *DeliveryError
+		want bool
+	}{
+		{name: "server error", err: &DeliveryError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "rate limited", err: &DeliveryError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "request timeout", err: &DeliveryError{StatusCode: http.StatusRequestTimeout}, want: true},
+		{name: "client error", err: &DeliveryError{StatusCode: http.StatusNotFound}, want: false},
+		{name: "transport error", err: &DeliveryError{Err: context.DeadlineExceeded}, want: true},
+		{name: "rejected URL", err: &DeliveryError{Err: ErrCallbackScheme}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.err.Retryable())
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryPolicy bounds callback delivery attempts with exponential backoff
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts, including the first
+	BaseDelay   time.Duration // Delay before the second attempt; doubles after each failure
+	MaxDelay    time.Duration // Upper bound on a single delay
+}
+
+// DefaultRetryPolicy makes 5 attempts over roughly 15 seconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// Delay returns the wait after the given failed attempt (1-based)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Retry runs attempt until it succeeds, fails with an error that isn't a
+// retryable *DeliveryError, or the policy's attempts are exhausted, calling
+// sleep between attempts. Callers inside a durable workflow pass journaled
+// attempt and sleep functions. It returns the number of attempts made and the
+// last error.
+func Retry(policy RetryPolicy, attempt func(n int) error, sleep func(time.Duration) error) (int, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for n := 1; n <= maxAttempts; n++ {
+		if err = attempt(n); err == nil {
+			return n, nil
+		}
+
+		var deliveryErr *DeliveryError
+		if !errors.As(err, &deliveryErr) || !deliveryErr.Retryable() {
+			return n, err
+		}
+		if n == maxAttempts {
+			return n, err
+		}
+		if sleepErr := sleep(policy.Delay(n)); sleepErr != nil {
+			return n, sleepErr
+		}
+	}
+	return maxAttempts, err
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 6, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 2*time.Second, policy.Delay(2))
+	assert.Equal(t, 4*time.Second, policy.Delay(3))
+	assert.Equal(t, 5*time.Second, policy.Delay(4))
+	assert.Equal(t, 5*time.Second, policy.Delay(10))
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, MaxDelay: time.Minute}
+	serverErr := &DeliveryError{StatusCode: http.StatusServiceUnavailable}
+
+	t.Run("succeeds after retryable failures", func(t *testing.T) {
+		var slept []time.Duration
+		attempts, err := Retry(policy, func(n int) error {
+			if n < 3 {
+				return serverErr
+			}
+			return nil
+		}, func(d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, slept)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		attempts, err := Retry(policy, func(int) error { return serverErr }, func(time.Duration) error { return nil })
+		assert.ErrorIs(t, err, serverErr)
+		assert.Equal(t, 4, attempts)
+	})
+
+	t.Run("stops on non-retryable failure", func(t *testing.T) {
+		attempts, err := Retry(policy, func(int) error {
+			return &DeliveryError{StatusCode: http.StatusBadRequest}
+		}, func(time.Duration) error { return nil })
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("stops when sleep fails", func(t *testing.T) {
+		sleepErr := errors.New("suspended")
+		attempts, err := Retry(policy, func(int) error { return serverErr }, func(time.Duration) error { return sleepErr })
+		assert.ErrorIs(t, err, sleepErr)
+		assert.Equal(t, 1, attempts)
+	})
+}
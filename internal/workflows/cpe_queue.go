@@ -0,0 +1,212 @@
+package workflows
+
+import (
+	"context"
+	"time"
+
+	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/schedule"
+	"go.uber.org/zap"
+)
+
+// Defaults for the CPE queue worker
+const (
+	// DefaultCPEQueueChunkSize is how many services are dequeued and enriched together
+	DefaultCPEQueueChunkSize = 50
+	// DefaultCPEQueueInterval is how often the worker looks for queued services
+	DefaultCPEQueueInterval = time.Minute
+)
+
+// CPEQueue holds services awaiting CPE enrichment
+// Dequeue claims entries rather than removing them: entries that are never
+// acknowledged are handed out again once their claim expires.
+type CPEQueue interface {
+	Enqueue(ctx context.Context, entries []models.CPEQueueEntry) error
+	Dequeue(ctx context.Context, limit int) ([]models.CPEQueueEntry, error)
+	Ack(ctx context.Context, serviceIDs []string) error
+}
+
+// ServiceEnricher runs CPE enrichment for a batch of services, as
+// EnrichCPEWorkflow does
+type ServiceEnricher interface {
+	EnrichServices(ctx context.Context, services []enrichment.ServiceInfo) (EnrichCPEResponse, error)
+}
+
+// CPEQueueWorkerConfig configures a CPEQueueWorker
+// Zero or out-of-range fields use the defaults above.
+type CPEQueueWorkerConfig struct {
+	ChunkSize int
+	Interval  time.Duration
+	Jitter    float64 // fraction of Interval each run is spread by, in [0, 1)
+}
+
+// CPEQueueWorker drains the CPE queue in bounded chunks on a schedule
+// Each chunk goes through CPE enrichment with the shared NVD client, so its
+// rate limit paces the worker; a chunk is acknowledged only once enriched, so
+// enrichment resumes where it left off after a failure or restart.
+type CPEQueueWorker struct {
+	queue    CPEQueue
+	enricher ServiceEnricher
+	logger   *zap.Logger
+	config   CPEQueueWorkerConfig
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCPEQueueWorker creates a worker that enriches queued services with enricher,
+// normally the EnrichCPEWorkflow
+func NewCPEQueueWorker(queue CPEQueue, enricher ServiceEnricher, logger *zap.Logger, config CPEQueueWorkerConfig) *CPEQueueWorker {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultCPEQueueChunkSize
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultCPEQueueInterval
+	}
+	if config.Jitter < 0 || config.Jitter >= 1 {
+		config.Jitter = 0
+	}
+
+	return &CPEQueueWorker{
+		queue:    queue,
+		enricher: enricher,
+		logger:   logger,
+		config:   config,
+	}
+}
+
+// Config returns the worker's effective configuration
+func (w *CPEQueueWorker) Config() CPEQueueWorkerConfig {
+	return w.config
+}
+
+// ProcessChunk dequeues up to ChunkSize services, enriches them and acknowledges
+// them, returning how many were processed. A failed enrichment leaves the chunk
+// unacknowledged so it is dequeued again once its claim expires.
+func (w *CPEQueueWorker) ProcessChunk(ctx context.Context) (int, error) {
+	entries, err := w.queue.Dequeue(ctx, w.config.ChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	services := make([]enrichment.ServiceInfo, len(entries))
+	serviceIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		services[i] = enrichment.ServiceInfo{
+			ID:      entry.ServiceID,
+			Name:    entry.Name,
+			Product: entry.Product,
+			Version: entry.Version,
+		}
+		serviceIDs[i] = entry.ServiceID
+	}
+
+	resp, err := w.enricher.EnrichServices(ctx, services)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.queue.Ack(ctx, serviceIDs); err != nil {
+		// The chunk is enriched; it will be enriched again, harmlessly, after its claim expires
+		return len(entries), err
+	}
+
+	w.logger.Info("enriched queued services",
+		zap.Int("services", resp.ServicesProcessed),
+		zap.Int("cpes", resp.CPEsGenerated),
+		zap.Int("vulns", resp.VulnsFound),
+		zap.Int("relationships", resp.RelationshipsCreated))
+
+	return len(entries), nil
+}
+
+// Drain processes chunks until the queue has no claimable entries left, a chunk
+// fails, or ctx is done, returning how many services were processed
+func (w *CPEQueueWorker) Drain(ctx context.Context) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		processed, err := w.ProcessChunk(ctx)
+		total += processed
+		if err != nil {
+			return total, err
+		}
+		if processed < w.config.ChunkSize {
+			return total, nil
+		}
+	}
+	return total, ctx.Err()
+}
+
+// Start drains the queue about every Interval until Stop is called
+func (w *CPEQueueWorker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go w.loop(ctx)
+}
+
+// Stop ends the worker, abandoning a chunk in progress; its services are
+// dequeued again once their claim expires
+func (w *CPEQueueWorker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+	w.cancel = nil
+}
+
+// loop drains the queue on every tick
+func (w *CPEQueueWorker) loop(ctx context.Context) {
+	defer close(w.done)
+
+	ticker := schedule.NewTicker(w.config.Interval, w.config.Jitter)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			processed, err := w.Drain(ctx)
+			if err != nil && ctx.Err() == nil {
+				w.logger.Warn("CPE queue drain failed",
+					zap.Error(err),
+					zap.Int("processed", processed))
+			}
+		}
+	}
+}
+
+// cpeQueueEntries returns one queue entry per distinct service in hosts that
+// CPE enrichment can work with, i.e. one naming both a product and a version
+func cpeQueueEntries(hosts []models.ScanHost) []models.CPEQueueEntry {
+	entries := []models.CPEQueueEntry{}
+	seen := make(map[string]bool)
+	for _, host := range hosts {
+		for _, port := range host.Ports {
+			svc := port.Service
+			if svc == nil || svc.Product == "" || svc.Version == "" {
+				continue
+			}
+			serviceID := "service:" + serviceRecordID(*svc)
+			if seen[serviceID] {
+				continue
+			}
+			seen[serviceID] = true
+			entries = append(entries, models.CPEQueueEntry{
+				ServiceID: serviceID,
+				Name:      svc.Name,
+				Product:   svc.Product,
+				Version:   svc.Version,
+			})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,235 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCPEQueue is an in-memory CPEQueue with the same claim semantics as the
+// cpe_queue table: entries are keyed by service and a dequeue claims them until
+// the lease expires
+type memoryCPEQueue struct {
+	mu      sync.Mutex
+	now     time.Time
+	lease   time.Duration
+	entries map[string]*memoryCPEQueueEntry
+	order   int
+	limits  []int
+}
+
+type memoryCPEQueueEntry struct {
+	entry        models.CPEQueueEntry
+	order        int
+	claimedUntil time.Time
+}
+
+func newMemoryCPEQueue() *memoryCPEQueue {
+	return &memoryCPEQueue{
+		now:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		lease:   time.Minute,
+		entries: make(map[string]*memoryCPEQueueEntry),
+	}
+}
+
+func (q *memoryCPEQueue) Enqueue(ctx context.Context, entries []models.CPEQueueEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, entry := range entries {
+		if _, queued := q.entries[entry.ServiceID]; queued {
+			continue
+		}
+		q.order++
+		q.entries[entry.ServiceID] = &memoryCPEQueueEntry{entry: entry, order: q.order}
+	}
+	return nil
+}
+
+func (q *memoryCPEQueue) Dequeue(ctx context.Context, limit int) ([]models.CPEQueueEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limits = append(q.limits, limit)
+
+	var claimable []*memoryCPEQueueEntry
+	for _, e := range q.entries {
+		if !e.claimedUntil.After(q.now) {
+			claimable = append(claimable, e)
+		}
+	}
+	sort.Slice(claimable, func(i, j int) bool { return claimable[i].order < claimable[j].order })
+
+	var entries []models.CPEQueueEntry
+	for _, e := range claimable[:min(limit, len(claimable))] {
+		e.claimedUntil = q.now.Add(q.lease)
+		e.entry.Attempts++
+		entries = append(entries, e.entry)
+	}
+	return entries, nil
+}
+
+func (q *memoryCPEQueue) Ack(ctx context.Context, serviceIDs []string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, id := range serviceIDs {
+		delete(q.entries, id)
+	}
+	return nil
+}
+
+func (q *memoryCPEQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+func (q *memoryCPEQueue) advance(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.now = q.now.Add(d)
+}
+
+// queueEntries returns n distinct queue entries
+func queueEntries(n int) []models.CPEQueueEntry {
+	entries := make([]models.CPEQueueEntry, n)
+	for i := range entries {
+		entries[i] = models.CPEQueueEntry{
+			ServiceID: "service:svc" + string(rune('a'+i)),
+			Name:      "http",
+			Product:   "nginx",
+			Version:   "1.18." + string(rune('0'+i%10)),
+		}
+	}
+	return entries
+}
+
+// recordingEnricher records the chunks it is asked to enrich
+type recordingEnricher struct {
+	chunks [][]string
+	err    error
+}
+
+func (r *recordingEnricher) EnrichServices(ctx context.Context, services []enrichment.ServiceInfo) (EnrichCPEResponse, error) {
+	ids := make([]string, len(services))
+	for i, svc := range services {
+		ids[i] = svc.ID
+	}
+	r.chunks = append(r.chunks, ids)
+	if r.err != nil {
+		return EnrichCPEResponse{}, r.err
+	}
+	return EnrichCPEResponse{ServicesProcessed: len(services)}, nil
+}
+
+func newTestCPEQueueWorker(queue CPEQueue, enricher *recordingEnricher, chunkSize int) *CPEQueueWorker {
+	return NewCPEQueueWorker(queue, enricher, nil, CPEQueueWorkerConfig{ChunkSize: chunkSize})
+}
+
+func TestNewCPEQueueWorker_Defaults(t *testing.T) {
+	w := NewCPEQueueWorker(newMemoryCPEQueue(), nil, nil, CPEQueueWorkerConfig{Jitter: 2})
+
+	assert.Equal(t, DefaultCPEQueueChunkSize, w.Config().ChunkSize)
+	assert.Equal(t, DefaultCPEQueueInterval, w.Config().Interval)
+	assert.Zero(t, w.Config().Jitter)
+}
+
+func TestCPEQueue_EnqueueIsIdempotent(t *testing.T) {
+	queue := newMemoryCPEQueue()
+	entries := queueEntries(3)
+
+	require.NoError(t, queue.Enqueue(context.Background(), entries))
+	require.NoError(t, queue.Enqueue(context.Background(), entries[:2]))
+
+	assert.Equal(t, 3, queue.len(), "re-enqueueing a queued service adds no entry")
+}
+
+func TestCPEQueueWorker_ProcessChunkIsBounded(t *testing.T) {
+	queue := newMemoryCPEQueue()
+	require.NoError(t, queue.Enqueue(context.Background(), queueEntries(5)))
+	enricher := &recordingEnricher{}
+	w := newTestCPEQueueWorker(queue, enricher, 2)
+
+	processed, err := w.ProcessChunk(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, processed)
+	assert.Equal(t, []int{2}, queue.limits)
+	assert.Equal(t, [][]string{{"service:svca", "service:svcb"}}, enricher.chunks, "oldest entries first")
+	assert.Equal(t, 3, queue.len(), "only the enriched chunk is acknowledged")
+}
+
+func TestCPEQueueWorker_DrainProcessesEveryChunk(t *testing.T) {
+	queue := newMemoryCPEQueue()
+	require.NoError(t, queue.Enqueue(context.Background(), queueEntries(5)))
+	enricher := &recordingEnricher{}
+	w := newTestCPEQueueWorker(queue, enricher, 2)
+
+	processed, err := w.Drain(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, processed)
+	assert.Len(t, enricher.chunks, 3)
+	assert.Zero(t, queue.len())
+
+	processed, err = w.ProcessChunk(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, processed, "an empty queue enriches nothing")
+	assert.Len(t, enricher.chunks, 3)
+}
+
+func TestCPEQueueWorker_FailedChunkIsReprocessed(t *testing.T) {
+	queue := newMemoryCPEQueue()
+	require.NoError(t, queue.Enqueue(context.Background(), queueEntries(2)))
+	enricher := &recordingEnricher{err: errors.New("nvd unavailable")}
+	w := newTestCPEQueueWorker(queue, enricher, 10)
+
+	_, err := w.ProcessChunk(context.Background())
+	require.ErrorContains(t, err, "nvd unavailable")
+	assert.Equal(t, 2, queue.len(), "a failed chunk stays queued")
+
+	// Claimed entries aren't handed out again while the lease holds
+	processed, err := w.ProcessChunk(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+
+	queue.advance(queue.lease)
+	enricher.err = nil
+	processed, err = w.ProcessChunk(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, processed)
+	assert.Equal(t, enricher.chunks[0], enricher.chunks[1], "the same services are enriched again")
+	assert.Zero(t, queue.len())
+}
+
+func TestCPEQueueEntries(t *testing.T) {
+	nginx := &models.ScanService{Name: "http", Product: "nginx", Version: "1.18.0"}
+	hosts := []models.ScanHost{
+		{IP: "192.0.2.1", Ports: []models.ScanPort{
+			{Number: 80, Protocol: "tcp", Service: nginx},
+			{Number: 22, Protocol: "tcp", Service: &models.ScanService{Name: "ssh", Product: "openssh"}},
+			{Number: 443, Protocol: "tcp"},
+		}},
+		{IP: "192.0.2.2", Ports: []models.ScanPort{
+			{Number: 8080, Protocol: "tcp", Service: nginx},
+		}},
+	}
+
+	entries := cpeQueueEntries(hosts)
+
+	require.Len(t, entries, 1, "one entry per service with a product and version")
+	assert.Equal(t, models.CPEQueueEntry{
+		ServiceID: "service:" + serviceRecordID(*nginx),
+		Name:      "http",
+		Product:   "nginx",
+		Version:   "1.18.0",
+	}, entries[0])
+	assert.Empty(t, cpeQueueEntries(nil))
+}
@@ -0,0 +1,100 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	restate "github.com/restatedev/sdk-go"
+	"go.uber.org/zap"
+)
+
+// DeadLetterRecorder records enrichment items that exhausted their retries
+type DeadLetterRecorder interface {
+	Record(ctx context.Context, ip, stage, reason string) error
+}
+
+// missingIPs returns the requested IPs that have no entry in results, in request order
+func missingIPs[T any](ips []string, results map[string]T) []string {
+	missing := make([]string, 0)
+	for _, ip := range ips {
+		if _, ok := results[ip]; !ok {
+			missing = append(missing, ip)
+		}
+	}
+	return missing
+}
+
+// Failed dead-letter writes are retried after a durable sleep, up to deadLetterAttempts times
+const (
+	deadLetterAttempts   = 3
+	deadLetterRetryDelay = 5 * time.Second
+)
+
+// DeadLetterResult is the outcome of a durable dead-letter write step
+type DeadLetterResult struct {
+	Recorded int      `json:"recorded"`
+	Failed   []string `json:"failed,omitempty"` // IPs whose write failed
+	Error    string   `json:"error,omitempty"`  // Why the writes failed
+}
+
+// recordDeadLetters writes a dead letter for each failed IP
+// A failed write doesn't stop the others: the IPs whose write failed are returned
+// in Failed, with the joined errors, so the caller can retry just those.
+func recordDeadLetters(ctx context.Context, recorder DeadLetterRecorder, ips []string, stage, reason string) DeadLetterResult {
+	var result DeadLetterResult
+	if recorder == nil {
+		return result
+	}
+
+	var errs []error
+	for _, ip := range ips {
+		if err := recorder.Record(ctx, ip, stage, reason); err != nil {
+			result.Failed = append(result.Failed, ip)
+			errs = append(errs, fmt.Errorf("%s: %w", ip, err))
+			continue
+		}
+		result.Recorded++
+	}
+	if err := errors.Join(errs...); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// deadLetterIPs durably records a dead letter for each IP and returns how many were
+// recorded. Writes that fail are retried after a durable sleep; the IPs still
+// unrecorded after deadLetterAttempts are logged, since a missing dead letter must
+// not fail the enrichment of the other IPs.
+func deadLetterIPs(ctx restate.Context, logger *zap.Logger, recorder DeadLetterRecorder, ips []string, stage, reason string) int {
+	recorded := 0
+	pending := ips
+	var lastErr string
+	for attempt := 1; attempt <= deadLetterAttempts && len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			if err := restate.Sleep(ctx, deadLetterRetryDelay); err != nil {
+				break
+			}
+		}
+
+		result, err := restate.Run(ctx, func(ctx restate.RunContext) (DeadLetterResult, error) {
+			return recordDeadLetters(ctx, recorder, pending, stage, reason), nil
+		}, restate.WithName(fmt.Sprintf("record-dead-letters-%d", attempt)))
+		if err != nil {
+			lastErr = err.Error()
+			break
+		}
+		recorded += result.Recorded
+		pending = result.Failed
+		lastErr = result.Error
+	}
+
+	if len(pending) > 0 {
+		logger.Error("failed to record dead letters",
+			zap.String("stage", stage),
+			zap.Strings("ips", pending),
+			zap.String("error", lastErr))
+	}
+	return recorded
+}
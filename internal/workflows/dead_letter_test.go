@@ -0,0 +1,70 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeadLetterRecorder records dead letters in memory
+type fakeDeadLetterRecorder struct {
+	records map[string]string
+	failIP  string
+}
+
+func (f *fakeDeadLetterRecorder) Record(ctx context.Context, ip, stage, reason string) error {
+	if ip == f.failIP {
+		return errors.New("write failed")
+	}
+	if f.records == nil {
+		f.records = make(map[string]string)
+	}
+	f.records[stage+":"+ip] = reason
+	return nil
+}
+
+func TestMissingIPs(t *testing.T) {
+	results := map[string]int{"1.1.1.1": 1, "3.3.3.3": 3}
+
+	assert.Equal(t, []string{"2.2.2.2", "4.4.4.4"}, missingIPs([]string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"}, results))
+	assert.Empty(t, missingIPs([]string{"1.1.1.1"}, results))
+}
+
+func TestRecordDeadLetters_ForcedFailure(t *testing.T) {
+	// The ASN client drops one IP, as a lookup that exhausted its retries does
+	client := &mockASNClient{
+		lookupBatchFunc: func(ctx context.Context, ips []string) (map[string]*enrichment.ASNInfo, error) {
+			return map[string]*enrichment.ASNInfo{
+				"8.8.8.8": {Number: 15169},
+			}, nil
+		},
+	}
+
+	ips := []string{"8.8.8.8", "192.0.2.1"}
+	results, err := client.LookupBatch(context.Background(), ips)
+	require.NoError(t, err)
+
+	recorder := &fakeDeadLetterRecorder{}
+	result := recordDeadLetters(context.Background(), recorder, missingIPs(ips, results), models.DeadLetterStageASN, "no ASN data returned after retries")
+
+	assert.Equal(t, DeadLetterResult{Recorded: 1}, result)
+	assert.Equal(t, map[string]string{"asn:192.0.2.1": "no ASN data returned after retries"}, recorder.records)
+}
+
+func TestRecordDeadLetters_ReportsFailedWrites(t *testing.T) {
+	recorder := &fakeDeadLetterRecorder{failIP: "192.0.2.1"}
+
+	result := recordDeadLetters(context.Background(), recorder, []string{"192.0.2.1", "192.0.2.2"}, models.DeadLetterStageGeo, "no GeoIP data for address")
+
+	// The failed write doesn't stop the others, and is reported for a retry
+	assert.Equal(t, 1, result.Recorded)
+	assert.Equal(t, []string{"192.0.2.1"}, result.Failed)
+	assert.Contains(t, result.Error, "192.0.2.1: write failed")
+	assert.Contains(t, recorder.records, "geo:192.0.2.2")
+	assert.Equal(t, DeadLetterResult{}, recordDeadLetters(context.Background(), nil, []string{"192.0.2.3"}, models.DeadLetterStageGeo, "x"))
+}
@@ -0,0 +1,34 @@
+package workflows
+
+import (
+	"sort"
+)
+
+// Mutation operations reported in a dry-run preview
+const (
+	MutationCreate = "create"
+	MutationUpdate = "update"
+	MutationRelate = "relate"
+)
+
+// PlannedMutation describes a write an enrichment workflow would make to SurrealDB
+type PlannedMutation struct {
+	Op     string `json:"op"`     // create, update or relate
+	Target string `json:"target"` // Record ID, or "from->EDGE->to" for relations
+}
+
+// sortMutations orders a preview by operation and target, so map-derived plans are stable
+func sortMutations(mutations []PlannedMutation) []PlannedMutation {
+	sort.Slice(mutations, func(i, j int) bool {
+		if mutations[i].Op != mutations[j].Op {
+			return mutations[i].Op < mutations[j].Op
+		}
+		return mutations[i].Target < mutations[j].Target
+	})
+	return mutations
+}
+
+// relationTarget formats an edge for a preview
+func relationTarget(from, edge, to string) string {
+	return from + "->" + edge + "->" + to
+}
@@ -3,25 +3,88 @@ package workflows
 import (
 	"context"
 	"fmt"
-	"strings"
+	"net/netip"
+	"sort"
 	"time"
 
 	restate "github.com/restatedev/sdk-go"
+	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
 	"github.com/surrealdb/surrealdb.go"
+	"go.uber.org/zap"
 )
 
 // EnrichASNWorkflow handles ASN enrichment for IP addresses
 type EnrichASNWorkflow struct {
-	db        *surrealdb.DB
-	asnClient enrichment.ASNClient
+	db          *surrealdb.DB
+	asnClient   enrichment.ASNClient
+	deadLetters DeadLetterRecorder
+	logger      *zap.Logger
+
+	// coalescePrefixes reuses one lookup for every IP inside the BGP prefix it returned
+	coalescePrefixes bool
+
+	// freshFor is how long a host's ASN enrichment is trusted before it is looked up again
+	freshFor time.Duration
+	// enrichedAt loads asn_enriched_at
+	enrichedAt EnrichedAtLoader
+
+	// hostIDSchemes reports the host ID scheme of the applied schema
+	hostIDSchemes HostIDSchemeSource
+}
+
+// EnrichASNConfig configures an EnrichASNWorkflow
+type EnrichASNConfig struct {
+	// CoalescePrefixes looks up one IP per /24 (IPv4) or /48 (IPv6) first and
+	// assigns its answer to the other IPs inside the announced BGP prefix, so a
+	// block of sequential hosts costs one upstream lookup. Hosts in a more
+	// specific announcement inside that prefix would get the covering prefix's
+	// ASN, so runs that need exact answers disable it (or set ExactLookups).
+	CoalescePrefixes bool
+
+	// FreshFor skips hosts ASN-enriched more recently than this unless the
+	// request sets ForceRefresh; defaults to DefaultEnrichmentFreshness
+	FreshFor time.Duration
+
+	// Logger reports enrichment problems that don't fail the run; nil discards them
+	Logger *zap.Logger
+
+	// HostIDSchemes is asked for the host ID scheme by every step that writes
+	// hosts, e.g. a db.Migrator; nil uses the namespaced scheme
+	HostIDSchemes HostIDSchemeSource
+
+	// EnrichedAt reports when hosts were last enriched; nil reads the host table
+	EnrichedAt EnrichedAtLoader
 }
 
 // NewEnrichASNWorkflow creates a new EnrichASNWorkflow instance
-func NewEnrichASNWorkflow(db *surrealdb.DB, asnClient enrichment.ASNClient) *EnrichASNWorkflow {
+// Every IP is looked up individually.
+func NewEnrichASNWorkflow(dbClient *surrealdb.DB, asnClient enrichment.ASNClient) *EnrichASNWorkflow {
+	return NewEnrichASNWorkflowWithConfig(dbClient, asnClient, EnrichASNConfig{})
+}
+
+// NewEnrichASNWorkflowWithConfig creates an EnrichASNWorkflow with the given configuration
+func NewEnrichASNWorkflowWithConfig(dbClient *surrealdb.DB, asnClient enrichment.ASNClient, config EnrichASNConfig) *EnrichASNWorkflow {
+	freshFor := config.FreshFor
+	if freshFor <= 0 {
+		freshFor = DefaultEnrichmentFreshness
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	return &EnrichASNWorkflow{
-		db:        db,
-		asnClient: asnClient,
+		db:               dbClient,
+		asnClient:        asnClient,
+		deadLetters:      db.NewDeadLetterStore(dbClient, logger),
+		logger:           logger,
+		coalescePrefixes: config.CoalescePrefixes,
+		freshFor:         freshFor,
+		enrichedAt:       enrichedAtOrDefault(config.EnrichedAt, dbClient),
+		hostIDSchemes:    hostIDSchemesOrDefault(config.HostIDSchemes),
 	}
 }
 
@@ -34,15 +97,18 @@ func (w *EnrichASNWorkflow) ServiceName() string {
 type EnrichASNRequest struct {
 	IPs       []string `json:"ips"`        // IP addresses to enrich (batch)
 	JobID     string   `json:"job_id"`     // Optional job ID for tracking
-	ForceRefresh bool  `json:"force_refresh"` // Force re-lookup even if cached
+	ForceRefresh bool  `json:"force_refresh"` // Re-lookup hosts that already have ASN data, bypassing the lookup cache
+	ExactLookups bool  `json:"exact_lookups"` // Look up every IP even when prefix coalescing is enabled
 }
 
 // EnrichASNResponse represents the response from ASN enrichment
 type EnrichASNResponse struct {
 	TotalIPs      int                       `json:"total_ips"`
 	EnrichedIPs   int                       `json:"enriched_ips"`
-	CachedIPs     int                       `json:"cached_ips"`
+	SkippedIPs    int                       `json:"skipped_ips"`             // IPs enriched within the freshness window and not looked up again
+	CachedIPs     int                       `json:"cached_ips"`              // Deprecated: same as SkippedIPs
 	FailedIPs     int                       `json:"failed_ips"`
+	CoalescedIPs  int                       `json:"coalesced_ips,omitempty"` // IPs answered from another IP's prefix without an upstream lookup
 	FailedIPsList []string                  `json:"failed_ips_list,omitempty"`
 	ASNData       map[string]*enrichment.ASNInfo `json:"asn_data"`
 }
@@ -73,52 +139,71 @@ func (w *EnrichASNWorkflow) Run(ctx restate.Context, req EnrichASNRequest) (Enri
 		FailedIPsList: make([]string, 0),
 	}
 
-	// Step 1: Check which IPs need enrichment (filter already enriched hosts)
+	// Step 1: Skip hosts enriched within the freshness window
 	ipsToEnrich, err := restate.Run[[]string](ctx, func(ctx restate.RunContext) ([]string, error) {
 		if req.ForceRefresh {
 			// Force refresh all IPs
 			return req.IPs, nil
 		}
-		return w.filterIPsNeedingEnrichment(req.IPs)
+		return w.filterIPsNeedingEnrichment(ctx, req.IPs, time.Now()), nil
 	})
 	if err != nil {
 		return response, fmt.Errorf("failed to filter IPs: %w", err)
 	}
+	response.SkippedIPs = len(req.IPs) - len(ipsToEnrich)
+	response.CachedIPs = response.SkippedIPs
 
 	// If no IPs need enrichment, return early
 	if len(ipsToEnrich) == 0 {
-		response.CachedIPs = len(req.IPs)
 		return response, nil
 	}
 
 	// Step 2: Lookup ASN data (external API call - durable)
-	asnLookupResults, err := restate.Run[map[string]*enrichment.ASNInfo](ctx, func(ctx restate.RunContext) (map[string]*enrichment.ASNInfo, error) {
-		// Use background context for external API call (not the Restate context)
-		apiCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	lookup, err := restate.Run[asnLookupResult](ctx, func(ctx restate.RunContext) (asnLookupResult, error) {
+		// Bound the external call, and cancel it if the invocation is cancelled
+		apiCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 		defer cancel()
 
-		return w.asnClient.LookupBatch(apiCtx, ipsToEnrich)
+		opts := enrichment.ASNLookupOptions{ForceRefresh: req.ForceRefresh}
+		var result asnLookupResult
+		var err error
+		if w.coalescePrefixes && !req.ExactLookups {
+			result, err = lookupCoalesced(apiCtx, w.asnClient, ipsToEnrich, opts)
+		} else {
+			result.ASNData, err = w.asnClient.LookupBatchWithOptions(apiCtx, ipsToEnrich, opts)
+		}
+		if err != nil && !enrichment.IsRetryable(err) {
+			// Retrying won't help; fail the step instead of looping on it
+			return result, restate.TerminalError(err)
+		}
+		return result, err
 	})
 	if err != nil {
 		return response, fmt.Errorf("failed to lookup ASN data: %w", err)
 	}
+	asnLookupResults := lookup.ASNData
 
 	// Track results
 	response.ASNData = asnLookupResults
+	response.CoalescedIPs = lookup.Coalesced
 	response.EnrichedIPs = len(asnLookupResults)
-	response.CachedIPs = response.TotalIPs - len(ipsToEnrich)
 	response.FailedIPs = len(ipsToEnrich) - len(asnLookupResults)
 
 	// Identify failed IPs
-	for _, ip := range ipsToEnrich {
-		if _, ok := asnLookupResults[ip]; !ok {
-			response.FailedIPsList = append(response.FailedIPsList, ip)
-		}
+	response.FailedIPsList = missingIPs(ipsToEnrich, asnLookupResults)
+
+	// Dead-letter IPs the ASN client gave up on so they can be revisited and requeued
+	if len(response.FailedIPsList) > 0 {
+		deadLetterIPs(ctx, w.logger, w.deadLetters, response.FailedIPsList, models.DeadLetterStageASN, "no ASN data returned after retries")
 	}
 
 	// Step 3: Update SurrealDB host records with ASN data
 	_, err = restate.Run[int](ctx, func(ctx restate.RunContext) (int, error) {
-		return w.updateHostASNData(asnLookupResults)
+		scheme, err := w.hostIDSchemes.HostIDScheme(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return w.updateHostASNData(scheme, asnLookupResults)
 	})
 	if err != nil {
 		return response, fmt.Errorf("failed to update host ASN data: %w", err)
@@ -126,7 +211,11 @@ func (w *EnrichASNWorkflow) Run(ctx restate.Context, req EnrichASNRequest) (Enri
 
 	// Step 4: Create or update ASN nodes and edges
 	_, err = restate.Run[int](ctx, func(ctx restate.RunContext) (int, error) {
-		return w.upsertASNNodesAndEdges(asnLookupResults)
+		scheme, err := w.hostIDSchemes.HostIDScheme(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return w.upsertASNNodesAndEdges(scheme, asnLookupResults)
 	})
 	if err != nil {
 		return response, fmt.Errorf("failed to upsert ASN nodes: %w", err)
@@ -135,55 +224,120 @@ func (w *EnrichASNWorkflow) Run(ctx restate.Context, req EnrichASNRequest) (Enri
 	return response, nil
 }
 
-// filterIPsNeedingEnrichment queries the database to find IPs that don't have ASN data
-func (w *EnrichASNWorkflow) filterIPsNeedingEnrichment(ips []string) ([]string, error) {
-	ctx := context.Background()
-	var ipsToEnrich []string
+// asnLookupResult is the outcome of the durable ASN lookup step
+type asnLookupResult struct {
+	ASNData   map[string]*enrichment.ASNInfo `json:"asn_data"`
+	Coalesced int                            `json:"coalesced"` // IPs answered from another IP's prefix
+}
 
-	// Query each IP to check if it has ASN data
+// lookupCoalesced looks up ASN data for ips with as few upstream lookups as
+// possible. The first IP of each /24 (IPv4) or /48 (IPv6) block is looked up
+// first; every other IP inside a BGP prefix one of those answers announced
+// gets a copy of that answer, and only the IPs left uncovered are looked up.
+// IPs that don't parse are passed through to the client unchanged.
+func lookupCoalesced(ctx context.Context, client enrichment.ASNClient, ips []string, opts enrichment.ASNLookupOptions) (asnLookupResult, error) {
+	result := asnLookupResult{ASNData: make(map[string]*enrichment.ASNInfo, len(ips))}
+
+	addrs := make(map[string]netip.Addr, len(ips))
+	blocks := make(map[netip.Prefix]bool)
+	var first, rest []string
 	for _, ip := range ips {
-		query := `SELECT asn FROM type::thing('host', $host_id) LIMIT 1;`
-		result, err := surrealdb.Query[[]map[string]interface{}](ctx, w.db, query, map[string]interface{}{
-			"host_id": strings.ReplaceAll(ip, ".", "_"),
-		})
-
-		// If query fails or host doesn't exist, add to enrich list
-		if err != nil || result == nil || len(*result) == 0 {
-			ipsToEnrich = append(ipsToEnrich, ip)
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			first = append(first, ip)
 			continue
 		}
+		addr = addr.Unmap()
+		addrs[ip] = addr
 
-		// Check if ASN field is set
-		hosts := (*result)[0].Result
-		if len(hosts) == 0 {
-			ipsToEnrich = append(ipsToEnrich, ip)
+		bits := 24
+		if addr.Is6() {
+			bits = 48
+		}
+		block, _ := addr.Prefix(bits)
+		if blocks[block] {
+			rest = append(rest, ip)
 			continue
 		}
+		blocks[block] = true
+		first = append(first, ip)
+	}
+
+	answers, err := client.LookupBatchWithOptions(ctx, first, opts)
+	for ip, info := range answers {
+		result.ASNData[ip] = info
+	}
+	if err != nil || len(rest) == 0 {
+		return result, err
+	}
+
+	// Prefixes announced for the IPs looked up so far, with the answer to share
+	type announced struct {
+		prefix netip.Prefix
+		info   *enrichment.ASNInfo
+	}
+	var prefixes []announced
+	for _, info := range answers {
+		if prefix, err := netip.ParsePrefix(info.Prefix); err == nil {
+			prefixes = append(prefixes, announced{prefix: prefix.Masked(), info: info})
+		}
+	}
 
-		// Check if asn field exists and is not nil/zero
-		host := hosts[0]
-		asnValue, hasASN := host["asn"]
-		if !hasASN || asnValue == nil || asnValue == 0 {
-			ipsToEnrich = append(ipsToEnrich, ip)
+	var uncovered []string
+	for _, ip := range rest {
+		covered := false
+		for _, p := range prefixes {
+			if p.prefix.Contains(addrs[ip]) {
+				shared := *p.info
+				result.ASNData[ip] = &shared
+				result.Coalesced++
+				covered = true
+				break
+			}
 		}
+		if !covered {
+			uncovered = append(uncovered, ip)
+		}
+	}
+
+	if len(uncovered) == 0 {
+		return result, nil
+	}
+	answers, err = client.LookupBatchWithOptions(ctx, uncovered, opts)
+	for ip, info := range answers {
+		result.ASNData[ip] = info
 	}
+	return result, err
+}
 
-	return ipsToEnrich, nil
+// filterIPsNeedingEnrichment returns the IPs whose ASN enrichment is missing or
+// older than the freshness window. If the timestamps can't be loaded every IP
+// is enriched.
+func (w *EnrichASNWorkflow) filterIPsNeedingEnrichment(ctx context.Context, ips []string, now time.Time) []string {
+	enrichedAt, err := w.enrichedAt.EnrichedAt(ctx, ips, hostASNEnrichedAt)
+	if err != nil {
+		return ips
+	}
+	stale, _ := staleIPs(ips, enrichedAt, now, w.freshFor)
+	return stale
 }
 
-// updateHostASNData updates host records in SurrealDB with ASN information
-func (w *EnrichASNWorkflow) updateHostASNData(asnData map[string]*enrichment.ASNInfo) (int, error) {
+// updateHostASNData updates host records in SurrealDB with ASN information,
+// addressing hosts by their record ID under scheme
+func (w *EnrichASNWorkflow) updateHostASNData(scheme models.HostIDScheme, asnData map[string]*enrichment.ASNInfo) (int, error) {
 	ctx := context.Background()
 	updated := 0
+	now := time.Now().UTC()
 
 	for ip, info := range asnData {
-		hostID := strings.ReplaceAll(ip, ".", "_")
+		hostID := scheme.RecordID(ip)
 
-		// Update host with ASN data
+		// Update host with ASN data and when it was looked up
 		updateQuery := `
 			UPDATE type::thing('host', $host_id) MERGE {
 				asn: $asn,
-				country: $country
+				country: $country,
+				asn_enriched_at: $now
 			};
 		`
 
@@ -191,6 +345,7 @@ func (w *EnrichASNWorkflow) updateHostASNData(asnData map[string]*enrichment.ASN
 			"host_id": hostID,
 			"asn":     info.Number,
 			"country": info.Country,
+			"now":     now,
 		})
 
 		if err != nil {
@@ -205,21 +360,18 @@ func (w *EnrichASNWorkflow) updateHostASNData(asnData map[string]*enrichment.ASN
 }
 
 // upsertASNNodesAndEdges creates ASN nodes and IN_ASN edges in the graph
-func (w *EnrichASNWorkflow) upsertASNNodesAndEdges(asnData map[string]*enrichment.ASNInfo) (int, error) {
+// Multi-origin IPs get an IN_ASN edge to every origin ASN. Hosts are addressed
+// by their record ID under scheme.
+func (w *EnrichASNWorkflow) upsertASNNodesAndEdges(scheme models.HostIDScheme, asnData map[string]*enrichment.ASNInfo) (int, error) {
 	ctx := context.Background()
 	created := 0
 
 	// Group by ASN to avoid duplicate upserts
-	asnMap := make(map[int]*enrichment.ASNInfo)
-	hostsByASN := make(map[int][]string)
-
-	for ip, info := range asnData {
-		asnMap[info.Number] = info
-		hostsByASN[info.Number] = append(hostsByASN[info.Number], ip)
-	}
+	asnMap, hostsByASN := groupHostsByOrigin(asnData)
 
 	// Upsert ASN nodes
 	for asnNum, info := range asnMap {
+		// Secondary origins have no AS name in the lookup, so keep any org already stored
 		upsertASNQuery := `
 			LET $asn_id = type::thing('asn', $asn_number);
 			CREATE $asn_id CONTENT {
@@ -227,7 +379,7 @@ func (w *EnrichASNWorkflow) upsertASNNodesAndEdges(asnData map[string]*enrichmen
 				org: $org,
 				country: $country
 			} ON DUPLICATE KEY UPDATE {
-				org: $org,
+				org: IF $org != "" THEN $org ELSE org END,
 				country: $country
 			};
 		`
@@ -244,7 +396,7 @@ func (w *EnrichASNWorkflow) upsertASNNodesAndEdges(asnData map[string]*enrichmen
 
 		// Create IN_ASN edges for all hosts in this ASN
 		for _, ip := range hostsByASN[asnNum] {
-			hostID := strings.ReplaceAll(ip, ".", "_")
+			hostID := scheme.RecordID(ip)
 
 			relateQuery := `
 				LET $host_id = type::thing('host', $host_encoded);
@@ -267,3 +419,31 @@ func (w *EnrichASNWorkflow) upsertASNNodesAndEdges(asnData map[string]*enrichmen
 
 	return created, nil
 }
+
+// groupHostsByOrigin groups IPs under every origin ASN they resolve to
+// Node data for an ASN comes from an IP where it is the primary origin, when there is one,
+// since only the primary origin carries an AS name.
+func groupHostsByOrigin(asnData map[string]*enrichment.ASNInfo) (map[int]enrichment.ASNInfo, map[int][]string) {
+	asnMap := make(map[int]enrichment.ASNInfo)
+	hostsByASN := make(map[int][]string)
+
+	for ip, info := range asnData {
+		for _, asn := range info.OriginASNs() {
+			hostsByASN[asn] = append(hostsByASN[asn], ip)
+
+			node := enrichment.ASNInfo{Number: asn, Country: info.Country}
+			if asn == info.Number {
+				node.Org = info.Org
+			}
+			if existing, ok := asnMap[asn]; !ok || (existing.Org == "" && node.Org != "") {
+				asnMap[asn] = node
+			}
+		}
+	}
+
+	for asn := range hostsByASN {
+		sort.Strings(hostsByASN[asn])
+	}
+
+	return asnMap, hostsByASN
+}
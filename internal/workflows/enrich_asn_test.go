@@ -3,10 +3,13 @@ package workflows
 import (
 	"context"
 	"errors"
-	"strings"
+	"fmt"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +18,9 @@ import (
 type mockASNClient struct {
 	lookupFunc      func(ctx context.Context, ip string) (*enrichment.ASNInfo, error)
 	lookupBatchFunc func(ctx context.Context, ips []string) (map[string]*enrichment.ASNInfo, error)
+
+	// lastOptions records the options of the last LookupBatchWithOptions call
+	lastOptions enrichment.ASNLookupOptions
 }
 
 func (m *mockASNClient) LookupASN(ctx context.Context, ip string) (*enrichment.ASNInfo, error) {
@@ -31,6 +37,11 @@ func (m *mockASNClient) LookupBatch(ctx context.Context, ips []string) (map[stri
 	return nil, errors.New("not implemented")
 }
 
+func (m *mockASNClient) LookupBatchWithOptions(ctx context.Context, ips []string, opts enrichment.ASNLookupOptions) (map[string]*enrichment.ASNInfo, error) {
+	m.lastOptions = opts
+	return m.LookupBatch(ctx, ips)
+}
+
 func TestEnrichASNWorkflow_ServiceName(t *testing.T) {
 	workflow := &EnrichASNWorkflow{}
 	assert.Equal(t, "EnrichASNWorkflow", workflow.ServiceName())
@@ -246,28 +257,93 @@ func TestIPEncoding(t *testing.T) {
 		name     string
 		ip       string
 		expected string
+		decoded  string
 	}{
 		{
 			name:     "standard IPv4",
 			ip:       "8.8.8.8",
-			expected: "8_8_8_8",
+			expected: "ip4_8_8_8_8",
+			decoded:  "8.8.8.8",
 		},
 		{
 			name:     "private IPv4",
 			ip:       "192.168.1.1",
-			expected: "192_168_1_1",
+			expected: "ip4_192_168_1_1",
+			decoded:  "192.168.1.1",
+		},
+		{
+			name:     "IPv6",
+			ip:       "2001:4860:4860::8888",
+			expected: "ip6_2001-4860-4860--8888",
+			decoded:  "2001:4860:4860::8888",
+		},
+		{
+			name:     "IPv6 non-canonical spelling",
+			ip:       "2001:4860:4860:0000:0000:0000:0000:8888",
+			expected: "ip6_2001-4860-4860--8888",
+			decoded:  "2001:4860:4860::8888",
+		},
+		{
+			name:     "IPv4-mapped IPv6",
+			ip:       "::ffff:8.8.8.8",
+			expected: "ip4_8_8_8_8",
+			decoded:  "8.8.8.8",
+		},
+		{
+			name:     "not an IP",
+			ip:       "scanme.example",
+			expected: "raw_7363616e6d652e6578616d706c65",
+			decoded:  "scanme.example",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// This mimics the encoding logic used in the workflow
-			encoded := strings.ReplaceAll(tt.ip, ".", "_")
+			encoded := models.HostRecordID(tt.ip)
 			assert.Equal(t, tt.expected, encoded)
+			assert.Equal(t, tt.decoded, models.IPFromHostRecordID(encoded))
+			assert.Equal(t, tt.decoded, models.IPFromHostRecordID("host:"+encoded))
 		})
 	}
 }
 
+func TestIPEncoding_NoCollisions(t *testing.T) {
+	// The old scheme only replaced dots, so IPv6 addresses kept their colons
+	// and distinct families could never be told apart on decode. Inputs that
+	// aren't IPs, including ones spelled like an encoded address, must not take
+	// an address's ID either.
+	inputs := []string{
+		"8.8.8.8", "8.8.4.4", "2001:db8::1", "2001:db8::1:0", "2001:db8:0:1::", "::1",
+		"8_8_8_8", "ip4_8_8_8_8", "2001-db8--1", "ip6_2001-db8--1", "raw_", "",
+	}
+
+	seen := make(map[string]string)
+	for _, input := range inputs {
+		id := models.HostRecordID(input)
+		assert.NotContains(t, id, ":")
+		assert.NotContains(t, id, ".")
+		assert.Equal(t, input, models.IPFromHostRecordID(id), "%q should decode back", input)
+		if other, ok := seen[id]; ok {
+			t.Fatalf("%q and %q both encode to %s", input, other, id)
+		}
+		seen[id] = input
+	}
+
+	// Every IPv4 address in a /16 and an IPv6 /112 gets its own ID
+	for i := 0; i < 1<<16; i++ {
+		for _, ip := range []string{
+			netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}).String(),
+			fmt.Sprintf("2001:db8:ffff::%x", i),
+		} {
+			id := models.HostRecordID(ip)
+			if other, ok := seen[id]; ok {
+				t.Fatalf("%s and %s both encode to %s", ip, other, id)
+			}
+			seen[id] = ip
+		}
+	}
+}
+
 // Benchmark tests
 func BenchmarkEnrichASNRequest_Creation(b *testing.B) {
 	ips := make([]string, 100)
@@ -303,3 +379,139 @@ func BenchmarkEnrichASNResponse_Creation(b *testing.B) {
 		}
 	}
 }
+
+func TestGroupHostsByOrigin(t *testing.T) {
+	asnData := map[string]*enrichment.ASNInfo{
+		"8.8.8.8":    {Number: 15169, Org: "GOOGLE, US", Country: "US"},
+		"192.0.2.10": {Number: 64500, Org: "EXAMPLE-A, US", Country: "US", Origins: []int{64500, 64501}},
+		"192.0.2.20": {Number: 64501, Org: "EXAMPLE-B, US", Country: "US"},
+	}
+
+	nodes, hostsByASN := groupHostsByOrigin(asnData)
+
+	// One IN_ASN edge per origin: the multi-origin IP is linked to both ASNs
+	assert.Equal(t, []string{"8.8.8.8"}, hostsByASN[15169])
+	assert.Equal(t, []string{"192.0.2.10"}, hostsByASN[64500])
+	assert.Equal(t, []string{"192.0.2.10", "192.0.2.20"}, hostsByASN[64501])
+
+	assert.Len(t, nodes, 3)
+	assert.Equal(t, "EXAMPLE-A, US", nodes[64500].Org)
+	// The secondary origin takes its name from the IP where it is primary
+	assert.Equal(t, "EXAMPLE-B, US", nodes[64501].Org)
+}
+
+func TestGroupHostsByOrigin_SecondaryOnly(t *testing.T) {
+	asnData := map[string]*enrichment.ASNInfo{
+		"192.0.2.10": {Number: 64500, Org: "EXAMPLE-A, US", Country: "US", Origins: []int{64500, 64502}},
+	}
+
+	nodes, hostsByASN := groupHostsByOrigin(asnData)
+
+	assert.Equal(t, []string{"192.0.2.10"}, hostsByASN[64502])
+	assert.Equal(t, 64502, nodes[64502].Number)
+	assert.Empty(t, nodes[64502].Org, "no AS name is known for a secondary-only origin")
+}
+
+// countingASNClient answers from a fixed table of prefixes and records every batch it is asked for
+func countingASNClient(prefixes map[string]enrichment.ASNInfo) (*mockASNClient, *[][]string) {
+	var calls [][]string
+	client := &mockASNClient{
+		lookupBatchFunc: func(ctx context.Context, ips []string) (map[string]*enrichment.ASNInfo, error) {
+			calls = append(calls, append([]string(nil), ips...))
+			results := make(map[string]*enrichment.ASNInfo)
+			for _, ip := range ips {
+				addr, err := netip.ParseAddr(ip)
+				if err != nil {
+					continue
+				}
+				for prefix, info := range prefixes {
+					if netip.MustParsePrefix(prefix).Contains(addr) {
+						answer := info
+						answer.Prefix = prefix
+						results[ip] = &answer
+						break
+					}
+				}
+			}
+			return results, nil
+		},
+	}
+	return client, &calls
+}
+
+func TestLookupCoalesced_SlashTwentyEight(t *testing.T) {
+	client, calls := countingASNClient(map[string]enrichment.ASNInfo{
+		"203.0.113.0/24": {Number: 64496, Org: "EXAMPLE, US", Country: "US"},
+	})
+
+	var ips []string
+	for i := 15; i >= 0; i-- {
+		ips = append(ips, fmt.Sprintf("203.0.113.%d", i))
+	}
+
+	result, err := lookupCoalesced(context.Background(), client, ips, enrichment.ASNLookupOptions{})
+	require.NoError(t, err)
+
+	assert.Len(t, *calls, 1, "one upstream call covers the whole /28")
+	assert.Len(t, (*calls)[0], 1)
+	assert.Len(t, result.ASNData, 16)
+	assert.Equal(t, 15, result.Coalesced)
+
+	// Every host still gets its own IN_ASN edge
+	_, hostsByASN := groupHostsByOrigin(result.ASNData)
+	assert.Len(t, hostsByASN[64496], 16)
+}
+
+func TestLookupCoalesced_UncoveredIPsAreLookedUp(t *testing.T) {
+	client, calls := countingASNClient(map[string]enrichment.ASNInfo{
+		"198.51.100.0/30":  {Number: 64500, Country: "US"},
+		"198.51.100.64/26": {Number: 64501, Country: "DE"},
+		"2001:db8::/32":    {Number: 64502, Country: "NL"},
+	})
+
+	ips := []string{"198.51.100.1", "198.51.100.2", "198.51.100.70", "198.51.100.71", "2001:db8::1", "2001:db8::2", "not-an-ip"}
+
+	result, err := lookupCoalesced(context.Background(), client, ips, enrichment.ASNLookupOptions{ForceRefresh: true})
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 2)
+	assert.ElementsMatch(t, []string{"198.51.100.1", "2001:db8::1", "not-an-ip"}, (*calls)[0])
+	assert.ElementsMatch(t, []string{"198.51.100.70", "198.51.100.71"}, (*calls)[1],
+		"IPs outside the first answer's /30 need their own lookup")
+	assert.True(t, client.lastOptions.ForceRefresh, "options are passed through")
+
+	assert.Equal(t, 2, result.Coalesced)
+	assert.Equal(t, 64500, result.ASNData["198.51.100.2"].Number)
+	assert.Equal(t, 64501, result.ASNData["198.51.100.71"].Number)
+	assert.Equal(t, 64502, result.ASNData["2001:db8::2"].Number)
+	assert.NotContains(t, result.ASNData, "not-an-ip")
+
+	// Coalesced answers are copies, not shared pointers
+	result.ASNData["198.51.100.2"].Org = "changed"
+	assert.Empty(t, result.ASNData["198.51.100.1"].Org)
+}
+
+func TestEnrichASNWorkflow_SkipsFreshHosts(t *testing.T) {
+	now := time.Now()
+	loader := &fakeEnrichedAt{times: map[string]time.Time{
+		"1.1.1.1": now.Add(-time.Hour),     // enriched an hour ago
+		"8.8.8.8": now.Add(-7 * time.Hour), // older than the window
+	}}
+	workflow := NewEnrichASNWorkflowWithConfig(nil, &mockASNClient{}, EnrichASNConfig{FreshFor: 6 * time.Hour, EnrichedAt: loader})
+
+	ips := workflow.filterIPsNeedingEnrichment(context.Background(), []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}, now)
+	assert.Equal(t, []string{"8.8.8.8", "9.9.9.9"}, ips)
+	assert.Equal(t, "asn_enriched_at", loader.field)
+
+	// Without timestamps every IP is enriched
+	workflow = NewEnrichASNWorkflowWithConfig(nil, &mockASNClient{}, EnrichASNConfig{
+		FreshFor:   6 * time.Hour,
+		EnrichedAt: &fakeEnrichedAt{err: errors.New("database unavailable")},
+	})
+	assert.Equal(t, []string{"1.1.1.1", "8.8.8.8"}, workflow.filterIPsNeedingEnrichment(context.Background(), []string{"1.1.1.1", "8.8.8.8"}, now))
+}
+
+func TestNewEnrichASNWorkflow_DefaultFreshness(t *testing.T) {
+	workflow := NewEnrichASNWorkflow(nil, &mockASNClient{})
+	assert.Equal(t, DefaultEnrichmentFreshness, workflow.freshFor)
+}
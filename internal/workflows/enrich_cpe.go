@@ -2,25 +2,53 @@ package workflows
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	restate "github.com/restatedev/sdk-go"
+	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/enrichment"
 	"github.com/surrealdb/surrealdb.go"
 )
 
 // EnrichCPEWorkflow handles CPE matching and vulnerability correlation
 type EnrichCPEWorkflow struct {
-	db        *surrealdb.DB
-	nvdClient *enrichment.NVDClient
+	db          *surrealdb.DB
+	nvdClient   *enrichment.NVDClient
+	minSeverity enrichment.Severity
 }
 
-// NewEnrichCPEWorkflow creates a new EnrichCPEWorkflow instance
+// EnrichCPEConfig configures the CPE enrichment workflow
+type EnrichCPEConfig struct {
+	NVDAPIKey   string              // Optional NVD API key for the higher rate limit
+	MinSeverity enrichment.Severity // Lowest severity that gets an AFFECTED_BY edge (defaults to HIGH)
+	// NVDClient is shared with the caller, e.g. to report its cache stats;
+	// when nil a client is built from NVDAPIKey
+	NVDClient *enrichment.NVDClient
+}
+
+// NewEnrichCPEWorkflow creates a new EnrichCPEWorkflow instance with the default severity threshold
 func NewEnrichCPEWorkflow(db *surrealdb.DB, nvdAPIKey string) *EnrichCPEWorkflow {
+	return NewEnrichCPEWorkflowWithConfig(db, EnrichCPEConfig{NVDAPIKey: nvdAPIKey})
+}
+
+// NewEnrichCPEWorkflowWithConfig creates a new EnrichCPEWorkflow instance from config
+func NewEnrichCPEWorkflowWithConfig(db *surrealdb.DB, cfg EnrichCPEConfig) *EnrichCPEWorkflow {
+	minSeverity := cfg.MinSeverity
+	if minSeverity == enrichment.SeverityUnknown {
+		minSeverity = enrichment.DefaultMinSeverity
+	}
+
+	nvdClient := cfg.NVDClient
+	if nvdClient == nil {
+		nvdClient = enrichment.NewNVDClient(cfg.NVDAPIKey)
+	}
+
 	return &EnrichCPEWorkflow{
-		db:        db,
-		nvdClient: enrichment.NewNVDClient(nvdAPIKey),
+		db:          db,
+		nvdClient:   nvdClient,
+		minSeverity: minSeverity,
 	}
 }
 
@@ -33,15 +61,19 @@ func (w *EnrichCPEWorkflow) ServiceName() string {
 type EnrichCPERequest struct {
 	Services []enrichment.ServiceInfo `json:"services"` // Services to enrich
 	BatchID  string                   `json:"batch_id"` // Optional batch identifier for tracking
+	DryRun   bool                     `json:"dry_run"`  // Plan the enrichment without writing to SurrealDB
+	// ForceRefresh bypasses the NVD response cache, e.g. when NVD just published new data
+	ForceRefresh bool `json:"force_refresh,omitempty"`
 }
 
 // EnrichCPEResponse represents the response from the CPE enrichment workflow
 type EnrichCPEResponse struct {
-	BatchID            string `json:"batch_id"`
-	ServicesProcessed  int    `json:"services_processed"`
-	CPEsGenerated      int    `json:"cpes_generated"`
-	VulnsFound         int    `json:"vulns_found"`
-	RelationshipsCreated int  `json:"relationships_created"`
+	BatchID              string            `json:"batch_id"`
+	ServicesProcessed    int               `json:"services_processed"`
+	CPEsGenerated        int               `json:"cpes_generated"`
+	VulnsFound           int               `json:"vulns_found"`
+	RelationshipsCreated int               `json:"relationships_created"`
+	Preview              []PlannedMutation `json:"preview,omitempty"` // Planned writes, set only for dry runs
 }
 
 // Run executes the CPE enrichment workflow with durable steps
@@ -56,38 +88,22 @@ func (w *EnrichCPEWorkflow) Run(ctx restate.Context, req EnrichCPERequest) (Enri
 		return EnrichCPEResponse{}, fmt.Errorf("failed to generate CPEs: %w", err)
 	}
 
-	cpeCount := 0
-	for _, cpes := range serviceCPEs {
-		cpeCount += len(cpes)
-	}
+	cpeCount := countCPEs(serviceCPEs)
 
 	// Step 2: Query NVD for vulnerabilities (with rate limiting)
-	// We collect all unique CPE strings
-	uniqueCPEs := make(map[string]bool)
-	for _, cpes := range serviceCPEs {
-		for _, cpe := range cpes {
-			uniqueCPEs[cpe.CPE] = true
-		}
-	}
-
-	cpeList := make([]string, 0, len(uniqueCPEs))
-	for cpe := range uniqueCPEs {
-		cpeList = append(cpeList, cpe)
-	}
+	// We collect all unique CPE strings, skipping malformed ones that would waste a rate-limited request
+	cpeList := queryableCPEs(serviceCPEs)
 
 	cvesByCPE, err := restate.Run[map[string][]enrichment.CVEItem](ctx, func(ctx restate.RunContext) (map[string][]enrichment.CVEItem, error) {
-		return w.nvdClient.QueryByCPEBatch(context.Background(), cpeList)
+		return w.queryNVD(context.Background(), cpeList, req.ForceRefresh)
 	})
 	if err != nil {
 		return EnrichCPEResponse{}, fmt.Errorf("failed to query NVD: %w", err)
 	}
 
-	// Step 3: Match services to CVEs
+	// Step 3: Match services to CVEs at or above the severity threshold
 	matches, err := restate.Run[[]enrichment.VulnMatch](ctx, func(ctx restate.RunContext) ([]enrichment.VulnMatch, error) {
-		allMatches := enrichment.MatchServicesToCVEs(serviceCPEs, cvesByCPE)
-		// Deduplicate matches
-		deduped := enrichment.DeduplicateMatches(allMatches)
-		return deduped, nil
+		return w.matchCVEs(serviceCPEs, cvesByCPE), nil
 	})
 	if err != nil {
 		return EnrichCPEResponse{}, fmt.Errorf("failed to match CVEs: %w", err)
@@ -95,7 +111,7 @@ func (w *EnrichCPEWorkflow) Run(ctx restate.Context, req EnrichCPERequest) (Enri
 
 	// Step 4: Create vulnerability nodes in SurrealDB
 	vulnCount, err := restate.Run[int](ctx, func(ctx restate.RunContext) (int, error) {
-		return w.createVulnNodes(cvesByCPE)
+		return w.createVulnNodes(cvesByCPE, req.DryRun)
 	})
 	if err != nil {
 		return EnrichCPEResponse{}, fmt.Errorf("failed to create vulnerability nodes: %w", err)
@@ -103,7 +119,7 @@ func (w *EnrichCPEWorkflow) Run(ctx restate.Context, req EnrichCPERequest) (Enri
 
 	// Step 5: Update service records with CPE identifiers
 	_, err = restate.Run[int](ctx, func(ctx restate.RunContext) (int, error) {
-		return w.updateServiceCPEs(serviceCPEs)
+		return w.updateServiceCPEs(serviceCPEs, req.DryRun)
 	})
 	if err != nil {
 		return EnrichCPEResponse{}, fmt.Errorf("failed to update service CPEs: %w", err)
@@ -111,24 +127,146 @@ func (w *EnrichCPEWorkflow) Run(ctx restate.Context, req EnrichCPERequest) (Enri
 
 	// Step 6: Create AFFECTED_BY relationships
 	relationshipsCreated, err := restate.Run[int](ctx, func(ctx restate.RunContext) (int, error) {
-		return w.createAffectedByRelationships(matches)
+		return w.createAffectedByRelationships(matches, req.DryRun)
 	})
 	if err != nil {
 		return EnrichCPEResponse{}, fmt.Errorf("failed to create relationships: %w", err)
 	}
 
-	return EnrichCPEResponse{
+	resp := EnrichCPEResponse{
 		BatchID:              req.BatchID,
 		ServicesProcessed:    len(req.Services),
 		CPEsGenerated:        cpeCount,
 		VulnsFound:           vulnCount,
 		RelationshipsCreated: relationshipsCreated,
+	}
+	if req.DryRun {
+		// Built from journaled step results, so the preview is stable across replays
+		resp.Preview = previewCPEMutations(serviceCPEs, cvesByCPE, matches)
+	}
+
+	return resp, nil
+}
+
+// EnrichServices enriches services directly rather than as durable Restate steps,
+// as the CPE queue worker does. Every write is an idempotent upsert, so a batch
+// that fails part way through can safely be enriched again from the start.
+func (w *EnrichCPEWorkflow) EnrichServices(ctx context.Context, services []enrichment.ServiceInfo) (EnrichCPEResponse, error) {
+	serviceCPEs := enrichment.GenerateCPEBatch(services)
+
+	cvesByCPE, err := w.queryNVD(ctx, queryableCPEs(serviceCPEs), false)
+	if err != nil {
+		return EnrichCPEResponse{}, fmt.Errorf("failed to query NVD: %w", err)
+	}
+	matches := w.matchCVEs(serviceCPEs, cvesByCPE)
+
+	vulnCount, err := w.createVulnNodes(cvesByCPE, false)
+	if err != nil {
+		return EnrichCPEResponse{}, fmt.Errorf("failed to create vulnerability nodes: %w", err)
+	}
+	if _, err := w.updateServiceCPEs(serviceCPEs, false); err != nil {
+		return EnrichCPEResponse{}, fmt.Errorf("failed to update service CPEs: %w", err)
+	}
+	relationshipsCreated, err := w.createAffectedByRelationships(matches, false)
+	if err != nil {
+		return EnrichCPEResponse{}, fmt.Errorf("failed to create relationships: %w", err)
+	}
+
+	return EnrichCPEResponse{
+		ServicesProcessed:    len(services),
+		CPEsGenerated:        countCPEs(serviceCPEs),
+		VulnsFound:           vulnCount,
+		RelationshipsCreated: relationshipsCreated,
 	}, nil
 }
 
+// countCPEs returns the number of CPEs generated across all services
+func countCPEs(serviceCPEs map[string][]enrichment.CPEIdentifier) int {
+	count := 0
+	for _, cpes := range serviceCPEs {
+		count += len(cpes)
+	}
+	return count
+}
+
+// queryableCPEs returns each well-formed CPE string once
+func queryableCPEs(serviceCPEs map[string][]enrichment.CPEIdentifier) []string {
+	uniqueCPEs := make(map[string]bool)
+	for _, cpes := range serviceCPEs {
+		for _, cpe := range cpes {
+			if enrichment.ValidCPE23(cpe.CPE) {
+				uniqueCPEs[cpe.CPE] = true
+			}
+		}
+	}
+
+	cpeList := make([]string, 0, len(uniqueCPEs))
+	for cpe := range uniqueCPEs {
+		cpeList = append(cpeList, cpe)
+	}
+	return cpeList
+}
+
+// queryNVD looks up vulnerabilities for each CPE under the client's rate limit
+func (w *EnrichCPEWorkflow) queryNVD(ctx context.Context, cpeList []string, forceRefresh bool) (map[string][]enrichment.CVEItem, error) {
+	results, err := w.nvdClient.QueryByCPEBatchWithOptions(ctx, cpeList, enrichment.NVDQueryOptions{
+		ForceRefresh: forceRefresh,
+	})
+	var batchErr *enrichment.BatchQueryError
+	if errors.As(err, &batchErr) && (len(results) > 0 || !enrichment.IsRetryable(err)) {
+		// Keep partial results; failed CPEs are picked up by the next enrichment run.
+		// CPEs NVD has no data for are skipped rather than retried.
+		return results, nil
+	}
+	return results, err
+}
+
+// matchCVEs matches services to CVEs at or above the severity threshold
+func (w *EnrichCPEWorkflow) matchCVEs(serviceCPEs map[string][]enrichment.CPEIdentifier, cvesByCPE map[string][]enrichment.CVEItem) []enrichment.VulnMatch {
+	allMatches := enrichment.MatchServicesToCVEs(serviceCPEs, cvesByCPE)
+	// Deduplicate matches
+	deduped := enrichment.DeduplicateMatches(allMatches)
+	return enrichment.FilterBySeverity(deduped, w.minSeverity)
+}
+
+// previewCPEMutations lists the writes the CPE workflow makes for the given step results
+func previewCPEMutations(serviceCPEs map[string][]enrichment.CPEIdentifier, cvesByCPE map[string][]enrichment.CVEItem, matches []enrichment.VulnMatch) []PlannedMutation {
+	mutations := []PlannedMutation{}
+
+	for _, cveID := range uniqueCVEIDs(cvesByCPE) {
+		mutations = append(mutations, PlannedMutation{Op: MutationCreate, Target: "vuln:" + cveID})
+	}
+	for serviceID := range serviceCPEs {
+		mutations = append(mutations, PlannedMutation{Op: MutationUpdate, Target: serviceID})
+	}
+	for _, match := range matches {
+		mutations = append(mutations, PlannedMutation{
+			Op:     MutationRelate,
+			Target: relationTarget(match.ServiceID, "AFFECTED_BY", "vuln:"+match.CVE),
+		})
+	}
+
+	return sortMutations(mutations)
+}
+
+// uniqueCVEIDs returns each CVE ID once, since the same CVE may appear in multiple CPE results
+func uniqueCVEIDs(cvesByCPE map[string][]enrichment.CVEItem) []string {
+	seen := make(map[string]bool)
+	ids := []string{}
+	for _, cves := range cvesByCPE {
+		for _, cve := range cves {
+			if !seen[cve.CVEID] {
+				seen[cve.CVEID] = true
+				ids = append(ids, cve.CVEID)
+			}
+		}
+	}
+	return ids
+}
+
 // createVulnNodes creates vulnerability nodes in SurrealDB
-// Returns the count of vulnerabilities created
-func (w *EnrichCPEWorkflow) createVulnNodes(cvesByCPE map[string][]enrichment.CVEItem) (int, error) {
+// Returns the count of vulnerabilities created, or that would be created when dryRun is set
+func (w *EnrichCPEWorkflow) createVulnNodes(cvesByCPE map[string][]enrichment.CVEItem, dryRun bool) (int, error) {
 	ctx := context.Background()
 	now := time.Now().UTC()
 	count := 0
@@ -141,36 +279,48 @@ func (w *EnrichCPEWorkflow) createVulnNodes(cvesByCPE map[string][]enrichment.CV
 		}
 	}
 
+	if dryRun {
+		return len(uniqueCVEs), nil
+	}
+
+	// Queue every vuln and vuln_doc node and write them in batched queries
+	batch := db.NewBatchExecutor(w.db, db.DefaultBatchSize, nil)
+	vulnStmts := make([]int, 0, len(uniqueCVEs))
+	vulnIDs := make(map[int]string, len(uniqueCVEs))
+
 	for _, cve := range uniqueCVEs {
-		// Create vuln node (idempotent upsert)
+		// Create vuln node (idempotent upsert); an unscored CVE has no vector
 		query := `
 			LET $vuln_id = type::thing('vuln', $cve_id);
 			CREATE $vuln_id CONTENT {
 				cve_id: $cve_id,
 				cvss: $cvss,
+				cvss_vector: $cvss_vector OR NONE,
 				severity: $severity,
 				kev_flag: false,
 				first_seen: $now,
 				last_updated: $now
 			} ON DUPLICATE KEY UPDATE {
 				cvss: $cvss,
+				cvss_vector: $cvss_vector OR NONE,
 				severity: $severity,
 				last_updated: $now
 			};
 		`
 
-		_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
-			"cve_id":   cve.CVEID,
-			"cvss":     cve.CVSS,
-			"severity": cve.Severity,
-			"now":      now,
+		idx := batch.Add(query, map[string]interface{}{
+			"cve_id":      cve.CVEID,
+			"cvss":        cve.CVSS,
+			"cvss_vector": cve.CVSSVector,
+			"severity":    cve.Severity,
+			"now":         now,
 		})
-
-		if err != nil {
-			return count, fmt.Errorf("failed to create vuln node %s: %w", cve.CVEID, err)
-		}
+		vulnStmts = append(vulnStmts, idx)
+		vulnIDs[idx] = cve.CVEID
 
 		// Create vuln_doc node for RAG (if description exists)
+		// Its result is ignored: vuln_doc is for RAG, not critical for basic
+		// vulnerability tracking
 		if cve.Description != "" {
 			docQuery := `
 				LET $doc_id = type::thing('vuln_doc', $cve_id);
@@ -197,7 +347,7 @@ func (w *EnrichCPEWorkflow) createVulnNodes(cvesByCPE map[string][]enrichment.CV
 			// Use CVE ID as title if not available
 			title := cve.CVEID
 
-			_, err := surrealdb.Query[interface{}](ctx, w.db, docQuery, map[string]interface{}{
+			batch.Add(docQuery, map[string]interface{}{
 				"cve_id":    cve.CVEID,
 				"title":     title,
 				"summary":   cve.Description,
@@ -207,25 +357,33 @@ func (w *EnrichCPEWorkflow) createVulnNodes(cvesByCPE map[string][]enrichment.CV
 				"published": cve.Published,
 				"modified":  cve.Modified,
 			})
+		}
+	}
 
-			if err != nil {
-				// Log error but don't fail the entire process
-				// vuln_doc is for RAG, not critical for basic vulnerability tracking
-				continue
+	errs := batch.Flush(ctx)
+	var firstErr error
+	for _, idx := range vulnStmts {
+		if errs[idx] != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to create vuln node %s: %w", vulnIDs[idx], errs[idx])
 			}
+			continue
 		}
-
 		count++
 	}
 
-	return count, nil
+	return count, firstErr
 }
 
 // updateServiceCPEs updates service records with generated CPE identifiers
-func (w *EnrichCPEWorkflow) updateServiceCPEs(serviceCPEs map[string][]enrichment.CPEIdentifier) (int, error) {
+func (w *EnrichCPEWorkflow) updateServiceCPEs(serviceCPEs map[string][]enrichment.CPEIdentifier, dryRun bool) (int, error) {
 	ctx := context.Background()
 	count := 0
 
+	if dryRun {
+		return len(serviceCPEs), nil
+	}
+
 	for serviceID, cpes := range serviceCPEs {
 		// Extract CPE strings
 		cpeStrings := make([]string, len(cpes))
@@ -237,6 +395,7 @@ func (w *EnrichCPEWorkflow) updateServiceCPEs(serviceCPEs map[string][]enrichmen
 		query := `
 			UPDATE $service_id MERGE {
 				cpe: $cpe,
+				cpe_updated_at: $now,
 				last_seen: $now
 			};
 		`
@@ -258,76 +417,70 @@ func (w *EnrichCPEWorkflow) updateServiceCPEs(serviceCPEs map[string][]enrichmen
 }
 
 // createAffectedByRelationships creates AFFECTED_BY edges between services and vulnerabilities
-func (w *EnrichCPEWorkflow) createAffectedByRelationships(matches []enrichment.VulnMatch) (int, error) {
+func (w *EnrichCPEWorkflow) createAffectedByRelationships(matches []enrichment.VulnMatch, dryRun bool) (int, error) {
 	ctx := context.Background()
 	now := time.Now().UTC()
 	count := 0
 
+	if dryRun {
+		return len(matches), nil
+	}
+
+	// Queue every edge and write them in batched queries
+	batch := db.NewBatchExecutor(w.db, db.DefaultBatchSize, nil)
 	for _, match := range matches {
 		// Create AFFECTED_BY relationship (idempotent)
 		query := `
 			LET $service_id = $sid;
 			LET $vuln_id = type::thing('vuln', $cve_id);
 			RELATE $service_id->AFFECTED_BY->$vuln_id CONTENT {
-				confidence: 1.0,
+				confidence: $confidence,
 				first_detected: $now,
 				last_confirmed: $now
 			} ON DUPLICATE KEY UPDATE {
+				confidence: $confidence,
 				last_confirmed: $now
 			};
 		`
 
-		_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
-			"sid":    match.ServiceID,
-			"cve_id": match.CVE,
-			"now":    now,
+		batch.Add(query, map[string]interface{}{
+			"sid":        match.ServiceID,
+			"cve_id":     match.CVE,
+			"confidence": match.Confidence,
+			"now":        now,
 		})
+	}
 
+	// Statement indices follow matches, since one edge is queued per match
+	var firstErr error
+	for i, err := range batch.Flush(ctx) {
 		if err != nil {
-			return count, fmt.Errorf("failed to create AFFECTED_BY edge %s->%s: %w", match.ServiceID, match.CVE, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to create AFFECTED_BY edge %s->%s: %w", matches[i].ServiceID, matches[i].CVE, err)
+			}
+			continue
 		}
-
 		count++
 	}
 
-	return count, nil
+	return count, firstErr
 }
 
-// GetServicesByFilter retrieves services from SurrealDB for batch processing
-func GetServicesByFilter(db *surrealdb.DB, filter ServiceFilter) ([]enrichment.ServiceInfo, error) {
-	ctx := context.Background()
-
-	// Build query based on filter
-	query := `
-		SELECT
-			id,
-			name,
-			product,
-			version,
-			meta::tb(id) as table_name
-		FROM service
-		WHERE 1=1
-	`
-
-	params := make(map[string]interface{})
+// defaultServiceLimit is the batch size used when ServiceFilter.Limit is not set
+const defaultServiceLimit = 100
 
-	// Add filters
-	if filter.MinLastSeen != nil {
-		query += " AND last_seen >= $min_last_seen"
-		params["min_last_seen"] = filter.MinLastSeen
-	}
+// ServicePage is one batch of services and the cursor for the next batch
+type ServicePage struct {
+	Services   []enrichment.ServiceInfo `json:"services"`
+	NextCursor string                   `json:"next_cursor,omitempty"` // Empty when there are no more services
+}
 
-	if filter.OnlyMissingCPE {
-		query += " AND (cpe IS NONE OR array::len(cpe) = 0)"
-	}
+// GetServicesByFilter retrieves one batch of services from SurrealDB for batch processing
+// Pass the returned NextCursor as filter.Cursor to fetch the following batch.
+func GetServicesByFilter(db *surrealdb.DB, filter ServiceFilter) (*ServicePage, error) {
+	ctx := context.Background()
 
-	// Add limit
-	if filter.Limit > 0 {
-		query += " LIMIT $limit"
-		params["limit"] = filter.Limit
-	} else {
-		query += " LIMIT 100" // Default limit
-	}
+	query, params := buildServiceFilterQuery(filter)
 
 	// Execute query
 	type ServiceRow struct {
@@ -357,12 +510,109 @@ func GetServicesByFilter(db *surrealdb.DB, filter ServiceFilter) ([]enrichment.S
 		}
 	}
 
-	return services, nil
+	return newServicePage(services, filter), nil
+}
+
+// WalkServicesByFilter fetches every service matching filter in batches of filter.Limit,
+// calling visit once per batch until the cursor is exhausted or visit returns an error
+func WalkServicesByFilter(db *surrealdb.DB, filter ServiceFilter, visit func([]enrichment.ServiceInfo) error) error {
+	return walkServicePages(func(f ServiceFilter) (*ServicePage, error) {
+		return GetServicesByFilter(db, f)
+	}, filter, visit)
+}
+
+// walkServicePages drives a cursor loop over fetch, starting from filter.Cursor
+func walkServicePages(fetch func(ServiceFilter) (*ServicePage, error), filter ServiceFilter, visit func([]enrichment.ServiceInfo) error) error {
+	for {
+		page, err := fetch(filter)
+		if err != nil {
+			return err
+		}
+		if len(page.Services) > 0 {
+			if err := visit(page.Services); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// newServicePage wraps a batch of services, setting NextCursor when the batch was full
+// A full batch may be followed by more services; a short one is the last.
+func newServicePage(services []enrichment.ServiceInfo, filter ServiceFilter) *ServicePage {
+	page := &ServicePage{Services: services}
+	if len(services) > 0 && len(services) >= serviceLimit(filter) {
+		page.NextCursor = services[len(services)-1].ID
+	}
+	return page
+}
+
+// serviceLimit returns the batch size for a filter
+func serviceLimit(filter ServiceFilter) int {
+	if filter.Limit > 0 {
+		return filter.Limit
+	}
+	return defaultServiceLimit
+}
+
+// buildServiceFilterQuery builds the service selection query and its parameters for a filter
+func buildServiceFilterQuery(filter ServiceFilter) (string, map[string]interface{}) {
+	query := `
+		SELECT
+			id,
+			name,
+			product,
+			version,
+			meta::tb(id) as table_name
+		FROM service
+		WHERE 1=1
+	`
+
+	params := make(map[string]interface{})
+
+	// Add filters
+	if filter.MinLastSeen != nil {
+		query += " AND last_seen >= $min_last_seen"
+		params["min_last_seen"] = filter.MinLastSeen
+	}
+
+	if filter.OnlyMissingCPE {
+		query += " AND (cpe IS NONE OR array::len(cpe) = 0)"
+	}
+
+	if filter.CPEOlderThan != nil {
+		// Services enriched before cpe_updated_at was recorded fall back to last_seen
+		query += " AND (cpe_updated_at < $cpe_older_than OR (cpe_updated_at IS NONE AND last_seen < $cpe_older_than))"
+		params["cpe_older_than"] = filter.CPEOlderThan
+	}
+
+	// Keyset pagination: resume after the last service ID of the previous batch
+	if filter.Cursor != "" {
+		query += " AND id > <record> $cursor"
+		params["cursor"] = filter.Cursor
+	}
+
+	query += " ORDER BY id"
+
+	// Add limit
+	if filter.Limit > 0 {
+		query += " LIMIT $limit"
+		params["limit"] = filter.Limit
+	} else {
+		query += fmt.Sprintf(" LIMIT %d", defaultServiceLimit)
+	}
+
+	return query, params
 }
 
 // ServiceFilter defines filters for retrieving services
 type ServiceFilter struct {
 	MinLastSeen    *time.Time // Only services seen after this time
 	OnlyMissingCPE bool       // Only services without CPE identifiers
-	Limit          int        // Maximum number of services to retrieve
+	CPEOlderThan   *time.Time // Only services whose CPEs were last updated before this time (stale enrichment)
+	Limit          int        // Maximum number of services to retrieve per batch
+	Cursor         string     // Service ID to resume after, from ServicePage.NextCursor
 }
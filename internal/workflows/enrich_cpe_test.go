@@ -1,7 +1,11 @@
 package workflows
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spectra-red/recon/internal/enrichment"
 )
@@ -37,6 +41,31 @@ func TestNewEnrichCPEWorkflow(t *testing.T) {
 	}
 }
 
+func TestNewEnrichCPEWorkflowWithConfig_MinSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		min  enrichment.Severity
+		want enrichment.Severity
+	}{
+		{"defaults to high", enrichment.SeverityUnknown, enrichment.SeverityHigh},
+		{"medium", enrichment.SeverityMedium, enrichment.SeverityMedium},
+		{"critical", enrichment.SeverityCritical, enrichment.SeverityCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow := NewEnrichCPEWorkflowWithConfig(nil, EnrichCPEConfig{MinSeverity: tt.min})
+			if workflow.minSeverity != tt.want {
+				t.Errorf("minSeverity = %s, want %s", workflow.minSeverity, tt.want)
+			}
+		})
+	}
+
+	if got := NewEnrichCPEWorkflow(nil, "").minSeverity; got != enrichment.DefaultMinSeverity {
+		t.Errorf("NewEnrichCPEWorkflow() minSeverity = %s, want %s", got, enrichment.DefaultMinSeverity)
+	}
+}
+
 func TestEnrichCPERequest_Validation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -97,6 +126,212 @@ func TestServiceFilter(t *testing.T) {
 	}
 }
 
+func TestBuildServiceFilterQuery(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	lastSeenClause := "last_seen >= $min_last_seen"
+	missingCPEClause := "(cpe IS NONE OR array::len(cpe) = 0)"
+	staleCPEClause := "(cpe_updated_at < $cpe_older_than OR (cpe_updated_at IS NONE AND last_seen < $cpe_older_than))"
+
+	tests := []struct {
+		name        string
+		filter      ServiceFilter
+		wantClauses []string
+		wantParams  []string
+		wantLimit   string
+	}{
+		{
+			name:      "no filters",
+			filter:    ServiceFilter{},
+			wantLimit: "LIMIT 100",
+		},
+		{
+			name:        "min last seen",
+			filter:      ServiceFilter{MinLastSeen: &since},
+			wantClauses: []string{lastSeenClause},
+			wantParams:  []string{"min_last_seen"},
+			wantLimit:   "LIMIT 100",
+		},
+		{
+			name:        "only missing CPE",
+			filter:      ServiceFilter{OnlyMissingCPE: true, Limit: 10},
+			wantClauses: []string{missingCPEClause},
+			wantParams:  []string{"limit"},
+			wantLimit:   "LIMIT $limit",
+		},
+		{
+			name:        "CPE older than",
+			filter:      ServiceFilter{CPEOlderThan: &cutoff},
+			wantClauses: []string{staleCPEClause},
+			wantParams:  []string{"cpe_older_than"},
+			wantLimit:   "LIMIT 100",
+		},
+		{
+			name:        "min last seen and CPE older than",
+			filter:      ServiceFilter{MinLastSeen: &since, CPEOlderThan: &cutoff, Limit: 25},
+			wantClauses: []string{lastSeenClause, staleCPEClause},
+			wantParams:  []string{"min_last_seen", "cpe_older_than", "limit"},
+			wantLimit:   "LIMIT $limit",
+		},
+		{
+			name:        "all filters",
+			filter:      ServiceFilter{MinLastSeen: &since, OnlyMissingCPE: true, CPEOlderThan: &cutoff, Limit: 5},
+			wantClauses: []string{lastSeenClause, missingCPEClause, staleCPEClause},
+			wantParams:  []string{"min_last_seen", "cpe_older_than", "limit"},
+			wantLimit:   "LIMIT $limit",
+		},
+	}
+
+	allClauses := []string{lastSeenClause, missingCPEClause, staleCPEClause}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, params := buildServiceFilterQuery(tt.filter)
+
+			for _, clause := range allClauses {
+				want := false
+				for _, c := range tt.wantClauses {
+					if c == clause {
+						want = true
+					}
+				}
+				if got := strings.Contains(query, " AND "+clause); got != want {
+					t.Errorf("query contains %q = %v, want %v\nquery: %s", clause, got, want, query)
+				}
+			}
+
+			if !strings.HasSuffix(query, tt.wantLimit) {
+				t.Errorf("query should end with %q, got: %s", tt.wantLimit, query)
+			}
+
+			if len(params) != len(tt.wantParams) {
+				t.Errorf("params = %v, want keys %v", params, tt.wantParams)
+			}
+			for _, key := range tt.wantParams {
+				if _, ok := params[key]; !ok {
+					t.Errorf("params missing %q", key)
+				}
+			}
+			if tt.filter.CPEOlderThan != nil && params["cpe_older_than"] != tt.filter.CPEOlderThan {
+				t.Errorf("cpe_older_than = %v, want %v", params["cpe_older_than"], tt.filter.CPEOlderThan)
+			}
+		})
+	}
+}
+
+func TestBuildServiceFilterQuery_Cursor(t *testing.T) {
+	query, params := buildServiceFilterQuery(ServiceFilter{OnlyMissingCPE: true, Cursor: "service:abc", Limit: 10})
+
+	if !strings.Contains(query, " AND id > <record> $cursor ORDER BY id LIMIT $limit") {
+		t.Errorf("query should page by id after the cursor, got: %s", query)
+	}
+	if params["cursor"] != "service:abc" {
+		t.Errorf("cursor param = %v, want service:abc", params["cursor"])
+	}
+
+	query, params = buildServiceFilterQuery(ServiceFilter{})
+	if strings.Contains(query, "$cursor") {
+		t.Errorf("query without a cursor should not filter on it, got: %s", query)
+	}
+	if _, ok := params["cursor"]; ok {
+		t.Error("params should not include a cursor")
+	}
+	if !strings.HasSuffix(query, " ORDER BY id LIMIT 100") {
+		t.Errorf("query should order by id before the default limit, got: %s", query)
+	}
+}
+
+func TestWalkServicePages_CoversAllServicesOnce(t *testing.T) {
+	// Seed services with IDs in key order, as SurrealDB returns them for ORDER BY id
+	seeded := make([]enrichment.ServiceInfo, 0, 23)
+	for i := 0; i < 23; i++ {
+		seeded = append(seeded, enrichment.ServiceInfo{ID: fmt.Sprintf("service:%03d", i)})
+	}
+
+	// fetch applies the same keyset predicate as buildServiceFilterQuery
+	fetch := func(filter ServiceFilter) (*ServicePage, error) {
+		batch := []enrichment.ServiceInfo{}
+		for _, svc := range seeded {
+			if filter.Cursor != "" && svc.ID <= filter.Cursor {
+				continue
+			}
+			if len(batch) == serviceLimit(filter) {
+				break
+			}
+			batch = append(batch, svc)
+		}
+		return newServicePage(batch, filter), nil
+	}
+
+	for _, limit := range []int{1, 5, 23, 50} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			seen := make(map[string]int)
+			batches := 0
+
+			err := walkServicePages(fetch, ServiceFilter{Limit: limit}, func(services []enrichment.ServiceInfo) error {
+				batches++
+				if len(services) > limit {
+					t.Errorf("batch of %d services exceeds limit %d", len(services), limit)
+				}
+				for _, svc := range services {
+					seen[svc.ID]++
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("walkServicePages() error = %v", err)
+			}
+
+			if len(seen) != len(seeded) {
+				t.Errorf("walked %d distinct services, want %d", len(seen), len(seeded))
+			}
+			for id, count := range seen {
+				if count != 1 {
+					t.Errorf("service %s visited %d times, want once", id, count)
+				}
+			}
+			if wantBatches := (len(seeded) + limit - 1) / limit; batches != wantBatches {
+				t.Errorf("walked %d batches, want %d", batches, wantBatches)
+			}
+		})
+	}
+}
+
+func TestWalkServicePages_StopsOnError(t *testing.T) {
+	calls := 0
+	fetch := func(filter ServiceFilter) (*ServicePage, error) {
+		calls++
+		return &ServicePage{Services: []enrichment.ServiceInfo{{ID: "service:a"}}, NextCursor: "service:a"}, nil
+	}
+
+	wantErr := errors.New("visit failed")
+	err := walkServicePages(fetch, ServiceFilter{Limit: 1}, func([]enrichment.ServiceInfo) error {
+		return wantErr
+	})
+
+	if !errors.Is(err, wantErr) {
+		t.Errorf("walkServicePages() error = %v, want %v", err, wantErr)
+	}
+	if calls != 1 {
+		t.Errorf("fetch called %d times, want 1", calls)
+	}
+}
+
+func TestNewServicePage_NextCursor(t *testing.T) {
+	services := []enrichment.ServiceInfo{{ID: "service:a"}, {ID: "service:b"}}
+
+	if page := newServicePage(services, ServiceFilter{Limit: 2}); page.NextCursor != "service:b" {
+		t.Errorf("full page NextCursor = %q, want service:b", page.NextCursor)
+	}
+	if page := newServicePage(services, ServiceFilter{Limit: 3}); page.NextCursor != "" {
+		t.Errorf("short page NextCursor = %q, want empty", page.NextCursor)
+	}
+	if page := newServicePage(nil, ServiceFilter{}); page.NextCursor != "" {
+		t.Errorf("empty page NextCursor = %q, want empty", page.NextCursor)
+	}
+}
+
 // Note: Full integration tests require a real SurrealDB instance and Restate runtime
 // These would be implemented in a separate integration test suite
 // Here we test the basic structure and logic
@@ -298,3 +533,51 @@ func TestEnrichCPEWorkflow_Integration(t *testing.T) {
 
 	t.Skip("Integration test not yet implemented")
 }
+
+func TestEnrichCPEWorkflow_DryRun(t *testing.T) {
+	// A nil DB makes any attempted write fail the test
+	workflow := NewEnrichCPEWorkflow(nil, "")
+
+	serviceCPEs := map[string][]enrichment.CPEIdentifier{
+		"service:nginx": {{Vendor: "nginx", Product: "nginx", Version: "1.24.0", CPE: "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"}},
+	}
+	cvesByCPE := map[string][]enrichment.CVEItem{
+		"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*": {
+			{CVEID: "CVE-2023-1001", CVSS: 7.5, Severity: "HIGH", Description: "test"},
+			{CVEID: "CVE-2023-1002", CVSS: 9.8, Severity: "CRITICAL"},
+		},
+	}
+	matches := enrichment.DeduplicateMatches(enrichment.MatchServicesToCVEs(serviceCPEs, cvesByCPE))
+
+	vulnCount, err := workflow.createVulnNodes(cvesByCPE, true)
+	if err != nil || vulnCount != 2 {
+		t.Errorf("createVulnNodes(dryRun) = %d, %v; want 2, nil", vulnCount, err)
+	}
+
+	serviceCount, err := workflow.updateServiceCPEs(serviceCPEs, true)
+	if err != nil || serviceCount != 1 {
+		t.Errorf("updateServiceCPEs(dryRun) = %d, %v; want 1, nil", serviceCount, err)
+	}
+
+	relCount, err := workflow.createAffectedByRelationships(matches, true)
+	if err != nil || relCount != 2 {
+		t.Errorf("createAffectedByRelationships(dryRun) = %d, %v; want 2, nil", relCount, err)
+	}
+
+	preview := previewCPEMutations(serviceCPEs, cvesByCPE, matches)
+	want := []PlannedMutation{
+		{Op: MutationCreate, Target: "vuln:CVE-2023-1001"},
+		{Op: MutationCreate, Target: "vuln:CVE-2023-1002"},
+		{Op: MutationRelate, Target: "service:nginx->AFFECTED_BY->vuln:CVE-2023-1001"},
+		{Op: MutationRelate, Target: "service:nginx->AFFECTED_BY->vuln:CVE-2023-1002"},
+		{Op: MutationUpdate, Target: "service:nginx"},
+	}
+	if len(preview) != len(want) {
+		t.Fatalf("previewCPEMutations() returned %d mutations, want %d: %v", len(preview), len(want), preview)
+	}
+	for i := range want {
+		if preview[i] != want[i] {
+			t.Errorf("previewCPEMutations()[%d] = %v, want %v", i, preview[i], want[i])
+		}
+	}
+}
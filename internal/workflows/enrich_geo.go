@@ -2,33 +2,89 @@ package workflows
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	restate "github.com/restatedev/sdk-go"
+	"github.com/spectra-red/recon/internal/db"
 	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
 	"github.com/surrealdb/surrealdb.go"
 	"go.uber.org/zap"
 )
 
 // EnrichGeoWorkflow handles GeoIP enrichment for IP addresses
 type EnrichGeoWorkflow struct {
-	db        *surrealdb.DB
-	geoClient *enrichment.GeoIPClient
-	logger    *zap.Logger
+	db          *surrealdb.DB
+	geoClient   *enrichment.GeoIPClient
+	deadLetters DeadLetterRecorder
+	logger      *zap.Logger
+
+	// maxCityAccuracyKm is the largest accuracy radius that still gets a city
+	maxCityAccuracyKm int
+
+	// freshFor is how long a host's GeoIP enrichment is trusted before it is looked up again
+	freshFor time.Duration
+	// enrichedAt loads geo_enriched_at
+	enrichedAt EnrichedAtLoader
+
+	// hostIDSchemes reports the host ID scheme of the applied schema
+	hostIDSchemes HostIDSchemeSource
+}
+
+// DefaultMaxCityAccuracyKm is the largest GeoIP accuracy radius trusted for a city
+const DefaultMaxCityAccuracyKm = 100
+
+// EnrichGeoConfig configures an EnrichGeoWorkflow
+type EnrichGeoConfig struct {
+	// MaxCityAccuracyKm drops the city (keeping region and country) from results
+	// whose accuracy radius is wider than this; defaults to DefaultMaxCityAccuracyKm
+	MaxCityAccuracyKm int
+
+	// FreshFor skips hosts GeoIP-enriched more recently than this unless the
+	// request sets ForceRefresh; defaults to DefaultEnrichmentFreshness
+	FreshFor time.Duration
+
+	// HostIDSchemes is asked for the host ID scheme by every step that writes
+	// hosts, e.g. a db.Migrator; nil uses the namespaced scheme
+	HostIDSchemes HostIDSchemeSource
+
+	// EnrichedAt reports when hosts were last enriched; nil reads the host table
+	EnrichedAt EnrichedAtLoader
 }
 
 // NewEnrichGeoWorkflow creates a new GeoIP enrichment workflow
-func NewEnrichGeoWorkflow(db *surrealdb.DB, geoClient *enrichment.GeoIPClient, logger *zap.Logger) *EnrichGeoWorkflow {
+func NewEnrichGeoWorkflow(dbClient *surrealdb.DB, geoClient *enrichment.GeoIPClient, logger *zap.Logger) *EnrichGeoWorkflow {
+	return NewEnrichGeoWorkflowWithConfig(dbClient, geoClient, logger, EnrichGeoConfig{})
+}
+
+// NewEnrichGeoWorkflowWithConfig creates a new GeoIP enrichment workflow from config
+func NewEnrichGeoWorkflowWithConfig(dbClient *surrealdb.DB, geoClient *enrichment.GeoIPClient, logger *zap.Logger, cfg EnrichGeoConfig) *EnrichGeoWorkflow {
 	if logger == nil {
 		logger, _ = zap.NewProduction()
 	}
 
+	maxCityAccuracyKm := cfg.MaxCityAccuracyKm
+	if maxCityAccuracyKm <= 0 {
+		maxCityAccuracyKm = DefaultMaxCityAccuracyKm
+	}
+
+	freshFor := cfg.FreshFor
+	if freshFor <= 0 {
+		freshFor = DefaultEnrichmentFreshness
+	}
+
 	return &EnrichGeoWorkflow{
-		db:        db,
-		geoClient: geoClient,
-		logger:    logger,
+		db:                dbClient,
+		geoClient:         geoClient,
+		deadLetters:       db.NewDeadLetterStore(dbClient, logger),
+		logger:            logger,
+		maxCityAccuracyKm: maxCityAccuracyKm,
+		freshFor:          freshFor,
+		enrichedAt:        enrichedAtOrDefault(cfg.EnrichedAt, dbClient),
+		hostIDSchemes:     hostIDSchemesOrDefault(cfg.HostIDSchemes),
 	}
 }
 
@@ -39,14 +95,18 @@ func (w *EnrichGeoWorkflow) ServiceName() string {
 
 // EnrichGeoRequest represents the request to enrich IPs with geographic data
 type EnrichGeoRequest struct {
-	IPs []string `json:"ips"` // Batch of IP addresses to enrich
+	IPs          []string `json:"ips"`           // Batch of IP addresses to enrich
+	DryRun       bool     `json:"dry_run"`       // Plan the enrichment without writing to SurrealDB
+	ForceRefresh bool     `json:"force_refresh"` // Look up hosts even if they were enriched within the freshness window
 }
 
 // EnrichGeoResponse represents the response from the enrichment workflow
 type EnrichGeoResponse struct {
-	Enriched int      `json:"enriched"` // Number of IPs successfully enriched
-	Failed   int      `json:"failed"`   // Number of IPs that failed enrichment
-	Errors   []string `json:"errors,omitempty"`
+	Enriched int               `json:"enriched"` // Number of IPs successfully enriched
+	Failed   int               `json:"failed"`   // Number of IPs that failed enrichment
+	Skipped  int               `json:"skipped"`  // Number of IPs enriched within the freshness window
+	Errors   []string          `json:"errors,omitempty"`
+	Preview  []PlannedMutation `json:"preview,omitempty"` // Planned writes, set only for dry runs
 }
 
 // GeoNodeResult holds the result of creating geographic nodes
@@ -58,9 +118,11 @@ type GeoNodeResult struct {
 
 // RelationshipResult holds the result of creating geographic relationships
 type RelationshipResult struct {
-	HostCityLinks   int
-	CityRegionLinks int
+	HostCityLinks      int
+	CityRegionLinks    int
 	RegionCountryLinks int
+	HostRegionLinks    int // Hosts without a city, placed in their region
+	HostCountryLinks   int // Hosts without a city or region, placed in their country
 }
 
 // Run executes the GeoIP enrichment workflow with durable steps
@@ -72,69 +134,216 @@ func (w *EnrichGeoWorkflow) Run(ctx restate.Context, req EnrichGeoRequest) (Enri
 	w.logger.Info("starting GeoIP enrichment workflow",
 		zap.Int("ip_count", len(req.IPs)))
 
+	// Skip hosts enriched within the freshness window
+	ips, err := restate.Run(ctx, func(ctx restate.RunContext) ([]string, error) {
+		if req.ForceRefresh {
+			return req.IPs, nil
+		}
+		return w.filterIPsNeedingEnrichment(ctx, req.IPs, time.Now()), nil
+	})
+	if err != nil {
+		return EnrichGeoResponse{
+			Failed: len(req.IPs),
+			Errors: []string{fmt.Sprintf("Failed to filter IPs: %v", err)},
+		}, err
+	}
+	skipped := len(req.IPs) - len(ips)
+	if len(ips) == 0 {
+		w.logger.Info("all IPs enriched recently, nothing to do",
+			zap.Int("skipped", skipped))
+		return EnrichGeoResponse{Skipped: skipped}, nil
+	}
+
 	// Step 1: Lookup GeoIP data for all IPs
 	geoData, err := restate.Run(ctx, func(ctx restate.RunContext) (map[string]*enrichment.GeoIPInfo, error) {
-		return w.lookupGeoIP(req.IPs)
+		return w.lookupGeoIP(ips)
 	})
 	if err != nil {
 		w.logger.Error("GeoIP lookup failed",
 			zap.Error(err),
-			zap.Int("ip_count", len(req.IPs)))
+			zap.Int("ip_count", len(ips)))
 		return EnrichGeoResponse{
-			Failed: len(req.IPs),
-			Errors: []string{fmt.Sprintf("GeoIP lookup failed: %v", err)},
+			Failed:  len(ips),
+			Skipped: skipped,
+			Errors:  []string{fmt.Sprintf("GeoIP lookup failed: %v", err)},
 		}, err
 	}
 
 	w.logger.Info("GeoIP lookup completed",
 		zap.Int("successful", len(geoData)),
-		zap.Int("failed", len(req.IPs)-len(geoData)))
+		zap.Int("failed", len(ips)-len(geoData)),
+		zap.Int("skipped", skipped))
+
+	// Imprecise results only place the host in a region and country
+	geoData, dropped := dropImpreciseCities(geoData, w.maxCityAccuracyKm)
+	if dropped > 0 {
+		w.logger.Info("dropped cities from low-accuracy GeoIP results",
+			zap.Int("count", dropped),
+			zap.Int("max_accuracy_km", w.maxCityAccuracyKm))
+	}
+
+	// Dead-letter IPs with no GeoIP data so they can be revisited and requeued
+	if failed := missingIPs(ips, geoData); len(failed) > 0 && !req.DryRun {
+		deadLetterIPs(ctx, w.logger, w.deadLetters, failed, models.DeadLetterStageGeo, "no GeoIP data for address")
+	}
 
 	// Step 2: Create geographic nodes (city, region, country)
 	_, err = restate.Run(ctx, func(ctx restate.RunContext) (GeoNodeResult, error) {
-		return w.createGeoNodes(geoData)
+		return w.createGeoNodes(geoData, req.DryRun)
 	})
 	if err != nil {
 		w.logger.Error("failed to create geographic nodes", zap.Error(err))
 		return EnrichGeoResponse{
-			Failed: len(req.IPs),
-			Errors: []string{fmt.Sprintf("Failed to create geographic nodes: %v", err)},
+			Failed:  len(ips),
+			Skipped: skipped,
+			Errors:  []string{fmt.Sprintf("Failed to create geographic nodes: %v", err)},
 		}, err
 	}
 
 	// Step 3: Create geographic relationships
 	_, err = restate.Run(ctx, func(ctx restate.RunContext) (RelationshipResult, error) {
-		return w.createGeoRelationships(geoData)
+		scheme, err := w.hostIDSchemes.HostIDScheme(ctx)
+		if err != nil {
+			return RelationshipResult{}, err
+		}
+		return w.createGeoRelationships(scheme, geoData, req.DryRun)
 	})
 	if err != nil {
 		w.logger.Error("failed to create geographic relationships", zap.Error(err))
 		return EnrichGeoResponse{
-			Failed: len(req.IPs),
-			Errors: []string{fmt.Sprintf("Failed to create geographic relationships: %v", err)},
+			Failed:  len(ips),
+			Skipped: skipped,
+			Errors:  []string{fmt.Sprintf("Failed to create geographic relationships: %v", err)},
 		}, err
 	}
 
 	// Step 4: Update host records with geographic data
 	_, err = restate.Run(ctx, func(ctx restate.RunContext) (restate.Void, error) {
-		return restate.Void{}, w.updateHostRecords(geoData)
+		scheme, err := w.hostIDSchemes.HostIDScheme(ctx)
+		if err != nil {
+			return restate.Void{}, err
+		}
+		return restate.Void{}, w.updateHostRecords(scheme, geoData, req.DryRun)
 	})
 	if err != nil {
 		w.logger.Error("failed to update host records", zap.Error(err))
 		return EnrichGeoResponse{
 			Enriched: len(geoData),
-			Failed:   len(req.IPs) - len(geoData),
+			Failed:   len(ips) - len(geoData),
+			Skipped:  skipped,
 			Errors:   []string{fmt.Sprintf("Failed to update host records: %v", err)},
 		}, err
 	}
 
 	w.logger.Info("GeoIP enrichment workflow completed",
 		zap.Int("enriched", len(geoData)),
-		zap.Int("failed", len(req.IPs)-len(geoData)))
+		zap.Int("failed", len(ips)-len(geoData)),
+		zap.Bool("dry_run", req.DryRun))
 
-	return EnrichGeoResponse{
+	resp := EnrichGeoResponse{
 		Enriched: len(geoData),
-		Failed:   len(req.IPs) - len(geoData),
-	}, nil
+		Failed:   len(ips) - len(geoData),
+		Skipped:  skipped,
+	}
+	if req.DryRun {
+		// Built from the journaled lookup result and scheme, so the preview is stable across replays
+		scheme, err := restate.Run(ctx, func(ctx restate.RunContext) (models.HostIDScheme, error) {
+			return w.hostIDSchemes.HostIDScheme(ctx)
+		})
+		if err != nil {
+			return resp, fmt.Errorf("failed to read the host ID scheme: %w", err)
+		}
+		resp.Preview = previewGeoMutations(scheme, geoData)
+	}
+
+	return resp, nil
+}
+
+// dropImpreciseCities returns geoData with the city cleared from every result whose
+// accuracy radius is wider than maxAccuracyKm, and how many were cleared.
+// Results without a reported radius keep their city. The input map is not modified.
+func dropImpreciseCities(geoData map[string]*enrichment.GeoIPInfo, maxAccuracyKm int) (map[string]*enrichment.GeoIPInfo, int) {
+	filtered := make(map[string]*enrichment.GeoIPInfo, len(geoData))
+	dropped := 0
+	for ip, info := range geoData {
+		if info.City != "" && info.AccuracyRadiusKm > maxAccuracyKm {
+			imprecise := *info
+			imprecise.City = ""
+			info = &imprecise
+			dropped++
+		}
+		filtered[ip] = info
+	}
+	return filtered, dropped
+}
+
+// geoRegionID builds the region record ID for a GeoIP result
+func geoRegionID(info *enrichment.GeoIPInfo) string {
+	return strings.ReplaceAll(fmt.Sprintf("%s:%s", info.CountryCC, info.Region), ":", "_")
+}
+
+// geoFallbackEdge returns the relation placing a host whose result has no city:
+// HOST_IN_REGION when the region is known, HOST_IN_COUNTRY when only the country
+// is, or "" when the host has a city or nothing is known
+func geoFallbackEdge(info *enrichment.GeoIPInfo) string {
+	switch {
+	case info.City != "":
+		return ""
+	case info.Region != "":
+		return "HOST_IN_REGION"
+	case info.CountryCC != "":
+		return "HOST_IN_COUNTRY"
+	default:
+		return ""
+	}
+}
+
+// geoCityID builds the city record ID for a GeoIP result
+func geoCityID(info *enrichment.GeoIPInfo) string {
+	return strings.ReplaceAll(fmt.Sprintf("%s:%s:%s", info.CountryCC, info.Region, info.City), ":", "_")
+}
+
+// previewGeoMutations lists the writes the GeoIP workflow makes for the given
+// lookup results, with hosts under their record ID in scheme
+func previewGeoMutations(scheme models.HostIDScheme, geoData map[string]*enrichment.GeoIPInfo) []PlannedMutation {
+	unique := make(map[PlannedMutation]bool)
+
+	for ip, info := range geoData {
+		hostID := "host:" + scheme.RecordID(ip)
+		countryID := "country:" + info.CountryCC
+		regionID := "region:" + geoRegionID(info)
+		cityID := "city:" + geoCityID(info)
+
+		if info.CountryCC != "" {
+			unique[PlannedMutation{Op: MutationCreate, Target: countryID}] = true
+		}
+		if info.Region != "" {
+			unique[PlannedMutation{Op: MutationCreate, Target: regionID}] = true
+		}
+		if info.City != "" {
+			unique[PlannedMutation{Op: MutationCreate, Target: cityID}] = true
+			unique[PlannedMutation{Op: MutationRelate, Target: relationTarget(hostID, "IN_CITY", cityID)}] = true
+		}
+		if info.City != "" && info.Region != "" {
+			unique[PlannedMutation{Op: MutationRelate, Target: relationTarget(cityID, "IN_REGION", regionID)}] = true
+		}
+		switch geoFallbackEdge(info) {
+		case "HOST_IN_REGION":
+			unique[PlannedMutation{Op: MutationRelate, Target: relationTarget(hostID, "HOST_IN_REGION", regionID)}] = true
+		case "HOST_IN_COUNTRY":
+			unique[PlannedMutation{Op: MutationRelate, Target: relationTarget(hostID, "HOST_IN_COUNTRY", countryID)}] = true
+		}
+		if info.Region != "" && info.CountryCC != "" {
+			unique[PlannedMutation{Op: MutationRelate, Target: relationTarget(regionID, "IN_COUNTRY", countryID)}] = true
+		}
+		unique[PlannedMutation{Op: MutationUpdate, Target: hostID}] = true
+	}
+
+	mutations := make([]PlannedMutation, 0, len(unique))
+	for mutation := range unique {
+		mutations = append(mutations, mutation)
+	}
+	return sortMutations(mutations)
 }
 
 // lookupGeoIP performs batch GeoIP lookup using the GeoIP client
@@ -146,6 +355,10 @@ func (w *EnrichGeoWorkflow) lookupGeoIP(ips []string) (map[string]*enrichment.Ge
 	w.logger.Info("performing GeoIP lookup", zap.Int("ip_count", len(ips)))
 
 	results, err := w.geoClient.LookupBatch(ips)
+	if errors.Is(err, enrichment.ErrNotFound) {
+		// No address is in the database; the caller dead-letters them rather than retrying
+		return map[string]*enrichment.GeoIPInfo{}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("batch GeoIP lookup failed: %w", err)
 	}
@@ -158,8 +371,8 @@ func (w *EnrichGeoWorkflow) lookupGeoIP(ips []string) (map[string]*enrichment.Ge
 }
 
 // createGeoNodes creates city, region, and country nodes in SurrealDB
-// Uses idempotent upserts with ON DUPLICATE KEY
-func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPInfo) (GeoNodeResult, error) {
+// Uses idempotent upserts with ON DUPLICATE KEY, sent as batched queries; with dryRun set it only counts the nodes
+func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPInfo, dryRun bool) (GeoNodeResult, error) {
 	ctx := context.Background()
 	result := GeoNodeResult{}
 
@@ -186,9 +399,24 @@ func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPI
 	w.logger.Info("creating geographic nodes",
 		zap.Int("countries", len(countries)),
 		zap.Int("regions", len(regions)),
-		zap.Int("cities", len(cities)))
+		zap.Int("cities", len(cities)),
+		zap.Bool("dry_run", dryRun))
+
+	if dryRun {
+		return GeoNodeResult{
+			CountriesCreated: len(countries),
+			RegionsCreated:   len(regions),
+			CitiesCreated:    len(cities),
+		}, nil
+	}
 
-	// Create country nodes
+	// Queue every node and write them in as few round-trips as possible
+	batch := db.NewBatchExecutor(w.db, db.DefaultBatchSize, w.logger)
+	countryStmts := make(map[int]string, len(countries))
+	regionStmts := make(map[int]string, len(regions))
+	cityStmts := make(map[int]string, len(cities))
+
+	// Queue country nodes
 	for cc, info := range countries {
 		query := `
 			LET $country_id = type::thing('country', $cc);
@@ -199,20 +427,14 @@ func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPI
 				name: $name
 			};
 		`
-		_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
+		idx := batch.Add(query, map[string]interface{}{
 			"cc":   cc,
 			"name": info.Country,
 		})
-		if err != nil {
-			w.logger.Error("failed to create country node",
-				zap.String("country", cc),
-				zap.Error(err))
-			continue
-		}
-		result.CountriesCreated++
+		countryStmts[idx] = cc
 	}
 
-	// Create region nodes
+	// Queue region nodes
 	for regionKey, info := range regions {
 		// Generate a safe region ID
 		regionID := strings.ReplaceAll(regionKey, ":", "_")
@@ -227,22 +449,16 @@ func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPI
 				name: $name
 			};
 		`
-		_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
+		idx := batch.Add(query, map[string]interface{}{
 			"region_id": regionID,
 			"name":      info.Region,
 			"cc":        info.CountryCC,
 			"code":      "", // Region code not available from MaxMind
 		})
-		if err != nil {
-			w.logger.Error("failed to create region node",
-				zap.String("region", regionKey),
-				zap.Error(err))
-			continue
-		}
-		result.RegionsCreated++
+		regionStmts[idx] = regionKey
 	}
 
-	// Create city nodes
+	// Queue city nodes
 	for cityKey, info := range cities {
 		// Generate a safe city ID
 		cityID := strings.ReplaceAll(cityKey, ":", "_")
@@ -260,17 +476,40 @@ func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPI
 				lon: $lon
 			};
 		`
-		_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
+		idx := batch.Add(query, map[string]interface{}{
 			"city_id": cityID,
 			"name":    info.City,
 			"cc":      info.CountryCC,
 			"lat":     info.Latitude,
 			"lon":     info.Longitude,
 		})
-		if err != nil {
+		cityStmts[idx] = cityKey
+	}
+
+	errs := batch.Flush(ctx)
+	for idx, cc := range countryStmts {
+		if errs[idx] != nil {
+			w.logger.Error("failed to create country node",
+				zap.String("country", cc),
+				zap.Error(errs[idx]))
+			continue
+		}
+		result.CountriesCreated++
+	}
+	for idx, regionKey := range regionStmts {
+		if errs[idx] != nil {
+			w.logger.Error("failed to create region node",
+				zap.String("region", regionKey),
+				zap.Error(errs[idx]))
+			continue
+		}
+		result.RegionsCreated++
+	}
+	for idx, cityKey := range cityStmts {
+		if errs[idx] != nil {
 			w.logger.Error("failed to create city node",
 				zap.String("city", cityKey),
-				zap.Error(err))
+				zap.Error(errs[idx]))
 			continue
 		}
 		result.CitiesCreated++
@@ -286,113 +525,228 @@ func (w *EnrichGeoWorkflow) createGeoNodes(geoData map[string]*enrichment.GeoIPI
 
 // createGeoRelationships creates LOCATED_IN relationships between geographic entities
 // host -> IN_CITY -> city -> IN_REGION -> region -> IN_COUNTRY -> country
-func (w *EnrichGeoWorkflow) createGeoRelationships(geoData map[string]*enrichment.GeoIPInfo) (RelationshipResult, error) {
+// A host without a city is placed with HOST_IN_REGION, or HOST_IN_COUNTRY when
+// its region is unknown too; either edge is replaced on every enrichment and
+// dropped once the host has a city. Hosts are addressed by their record ID under scheme.
+func (w *EnrichGeoWorkflow) createGeoRelationships(scheme models.HostIDScheme, geoData map[string]*enrichment.GeoIPInfo, dryRun bool) (RelationshipResult, error) {
 	ctx := context.Background()
 	result := RelationshipResult{}
 
+	if dryRun {
+		return countGeoRelationships(geoData), nil
+	}
+
+	// geoEdge remembers which relationship a queued statement creates
+	type geoEdge struct {
+		kind   string
+		fields []zap.Field
+	}
+	batch := db.NewBatchExecutor(w.db, db.DefaultBatchSize, w.logger)
+	edges := make(map[int]geoEdge)
+
 	for ip, info := range geoData {
-		// Create host -> IN_CITY -> city relationship
+		// Queue host -> IN_CITY -> city relationship
 		if info.City != "" {
-			cityID := strings.ReplaceAll(fmt.Sprintf("%s:%s:%s", info.CountryCC, info.Region, info.City), ":", "_")
-			hostID := strings.ReplaceAll(ip, ".", "_")
+			cityID := geoCityID(info)
+			hostID := scheme.RecordID(ip)
 
 			query := `
 				LET $host_id = type::thing('host', $host_id);
 				LET $city_id = type::thing('city', $city_id);
-				RELATE $host_id->IN_CITY->$city_id;
+				DELETE $host_id->HOST_IN_REGION;
+				DELETE $host_id->HOST_IN_COUNTRY;
+				RELATE $host_id->IN_CITY->$city_id SET accuracy_radius_km = $accuracy_radius_km;
+			`
+			idx := batch.Add(query, map[string]interface{}{
+				"host_id":            hostID,
+				"city_id":            cityID,
+				"accuracy_radius_km": info.AccuracyRadiusKm,
+			})
+			edges[idx] = geoEdge{"host->city", []zap.Field{
+				zap.String("ip", ip),
+				zap.String("city", info.City),
+			}}
+		}
+
+		// Queue host -> HOST_IN_REGION -> region or host -> HOST_IN_COUNTRY -> country
+		// relationship for a host without a city
+		switch geoFallbackEdge(info) {
+		case "HOST_IN_REGION":
+			query := `
+				LET $host_id = type::thing('host', $host_id);
+				LET $region_id = type::thing('region', $region_id);
+				DELETE $host_id->HOST_IN_REGION;
+				DELETE $host_id->HOST_IN_COUNTRY;
+				RELATE $host_id->HOST_IN_REGION->$region_id SET accuracy_radius_km = $accuracy_radius_km;
+			`
+			idx := batch.Add(query, map[string]interface{}{
+				"host_id":            scheme.RecordID(ip),
+				"region_id":          geoRegionID(info),
+				"accuracy_radius_km": info.AccuracyRadiusKm,
+			})
+			edges[idx] = geoEdge{"host->region", []zap.Field{
+				zap.String("ip", ip),
+				zap.String("region", info.Region),
+			}}
+		case "HOST_IN_COUNTRY":
+			query := `
+				LET $host_id = type::thing('host', $host_id);
+				LET $country_id = type::thing('country', $cc);
+				DELETE $host_id->HOST_IN_REGION;
+				DELETE $host_id->HOST_IN_COUNTRY;
+				RELATE $host_id->HOST_IN_COUNTRY->$country_id SET accuracy_radius_km = $accuracy_radius_km;
 			`
-			_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
-				"host_id": hostID,
-				"city_id": cityID,
+			idx := batch.Add(query, map[string]interface{}{
+				"host_id":            scheme.RecordID(ip),
+				"cc":                 info.CountryCC,
+				"accuracy_radius_km": info.AccuracyRadiusKm,
 			})
-			if err != nil {
-				w.logger.Error("failed to create host->city relationship",
-					zap.String("ip", ip),
-					zap.String("city", info.City),
-					zap.Error(err))
-			} else {
-				result.HostCityLinks++
-			}
+			edges[idx] = geoEdge{"host->country", []zap.Field{
+				zap.String("ip", ip),
+				zap.String("country", info.CountryCC),
+			}}
 		}
 
-		// Create city -> IN_REGION -> region relationship
+		// Queue city -> IN_REGION -> region relationship
 		if info.City != "" && info.Region != "" {
-			cityID := strings.ReplaceAll(fmt.Sprintf("%s:%s:%s", info.CountryCC, info.Region, info.City), ":", "_")
-			regionID := strings.ReplaceAll(fmt.Sprintf("%s:%s", info.CountryCC, info.Region), ":", "_")
+			cityID := geoCityID(info)
+			regionID := geoRegionID(info)
 
 			query := `
 				LET $city_id = type::thing('city', $city_id);
 				LET $region_id = type::thing('region', $region_id);
 				RELATE $city_id->IN_REGION->$region_id;
 			`
-			_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
+			idx := batch.Add(query, map[string]interface{}{
 				"city_id":   cityID,
 				"region_id": regionID,
 			})
-			if err != nil {
-				w.logger.Error("failed to create city->region relationship",
-					zap.String("city", info.City),
-					zap.String("region", info.Region),
-					zap.Error(err))
-			} else {
-				result.CityRegionLinks++
-			}
+			edges[idx] = geoEdge{"city->region", []zap.Field{
+				zap.String("city", info.City),
+				zap.String("region", info.Region),
+			}}
 		}
 
-		// Create region -> IN_COUNTRY -> country relationship
+		// Queue region -> IN_COUNTRY -> country relationship
 		if info.Region != "" && info.CountryCC != "" {
-			regionID := strings.ReplaceAll(fmt.Sprintf("%s:%s", info.CountryCC, info.Region), ":", "_")
+			regionID := geoRegionID(info)
 
 			query := `
 				LET $region_id = type::thing('region', $region_id);
 				LET $country_id = type::thing('country', $cc);
 				RELATE $region_id->IN_COUNTRY->$country_id;
 			`
-			_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
+			idx := batch.Add(query, map[string]interface{}{
 				"region_id": regionID,
 				"cc":        info.CountryCC,
 			})
-			if err != nil {
-				w.logger.Error("failed to create region->country relationship",
-					zap.String("region", info.Region),
-					zap.String("country", info.CountryCC),
-					zap.Error(err))
-			} else {
-				result.RegionCountryLinks++
-			}
+			edges[idx] = geoEdge{"region->country", []zap.Field{
+				zap.String("region", info.Region),
+				zap.String("country", info.CountryCC),
+			}}
+		}
+	}
+
+	errs := batch.Flush(ctx)
+	for idx, edge := range edges {
+		if errs[idx] != nil {
+			w.logger.Error("failed to create "+edge.kind+" relationship",
+				append(edge.fields, zap.Error(errs[idx]))...)
+			continue
+		}
+		switch edge.kind {
+		case "host->city":
+			result.HostCityLinks++
+		case "city->region":
+			result.CityRegionLinks++
+		case "region->country":
+			result.RegionCountryLinks++
+		case "host->region":
+			result.HostRegionLinks++
+		case "host->country":
+			result.HostCountryLinks++
 		}
 	}
 
 	w.logger.Info("geographic relationships created",
 		zap.Int("host_city", result.HostCityLinks),
 		zap.Int("city_region", result.CityRegionLinks),
-		zap.Int("region_country", result.RegionCountryLinks))
+		zap.Int("region_country", result.RegionCountryLinks),
+		zap.Int("host_region", result.HostRegionLinks),
+		zap.Int("host_country", result.HostCountryLinks))
 
 	return result, nil
 }
 
-// updateHostRecords updates host records with city, region, and country fields
-func (w *EnrichGeoWorkflow) updateHostRecords(geoData map[string]*enrichment.GeoIPInfo) error {
+// countGeoRelationships counts the relationships createGeoRelationships would create
+func countGeoRelationships(geoData map[string]*enrichment.GeoIPInfo) RelationshipResult {
+	result := RelationshipResult{}
+	for _, info := range geoData {
+		if info.City != "" {
+			result.HostCityLinks++
+		}
+		if info.City != "" && info.Region != "" {
+			result.CityRegionLinks++
+		}
+		if info.Region != "" && info.CountryCC != "" {
+			result.RegionCountryLinks++
+		}
+		switch geoFallbackEdge(info) {
+		case "HOST_IN_REGION":
+			result.HostRegionLinks++
+		case "HOST_IN_COUNTRY":
+			result.HostCountryLinks++
+		}
+	}
+	return result
+}
+
+// filterIPsNeedingEnrichment returns the IPs whose GeoIP enrichment is missing
+// or older than the freshness window. If the timestamps can't be loaded every
+// IP is enriched.
+func (w *EnrichGeoWorkflow) filterIPsNeedingEnrichment(ctx context.Context, ips []string, now time.Time) []string {
+	enrichedAt, err := w.enrichedAt.EnrichedAt(ctx, ips, hostGeoEnrichedAt)
+	if err != nil {
+		w.logger.Warn("failed to load GeoIP enrichment times, enriching every IP",
+			zap.Error(err))
+		return ips
+	}
+	stale, _ := staleIPs(ips, enrichedAt, now, w.freshFor)
+	return stale
+}
+
+// updateHostRecords updates host records, addressed by their record ID under
+// scheme, with city, region, and country fields
+func (w *EnrichGeoWorkflow) updateHostRecords(scheme models.HostIDScheme, geoData map[string]*enrichment.GeoIPInfo, dryRun bool) error {
 	ctx := context.Background()
 	now := time.Now().UTC()
 
-	for ip, info := range geoData {
-		hostID := strings.ReplaceAll(ip, ".", "_")
+	if dryRun {
+		return nil
+	}
 
-		query := `
-			UPDATE type::thing('host', $host_id) MERGE {
-				city: $city,
-				region: $region,
-				country: $country,
-				last_seen: $now
-			};
-		`
+	for ip, info := range geoData {
+		hostID := scheme.RecordID(ip)
+
+		// Enrichment counts as an observation, but must not move last_seen
+		// backwards past a newer ingest or first_seen forwards
+		query := fmt.Sprintf(`
+			UPDATE type::thing('host', $host_id) SET
+				city = $city,
+				region = $region,
+				country = $country,
+				geo_enriched_at = $now,
+				first_seen = %s,
+				last_seen = %s;
+		`, firstSeenBackward, lastSeenForward)
 		_, err := surrealdb.Query[interface{}](ctx, w.db, query, map[string]interface{}{
-			"host_id": hostID,
-			"city":    info.City,
-			"region":  info.Region,
-			"country": info.Country,
-			"now":     now,
+			"host_id":    hostID,
+			"city":       info.City,
+			"region":     info.Region,
+			"country":    info.Country,
+			"now":        now,
+			"first_seen": now,
+			"last_seen":  now,
 		})
 		if err != nil {
 			w.logger.Error("failed to update host record",
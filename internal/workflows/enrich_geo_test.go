@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/spectra-red/recon/internal/enrichment"
+	"github.com/spectra-red/recon/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/surrealdb/surrealdb.go"
@@ -86,17 +87,17 @@ func TestEnrichGeoWorkflow_Integration(t *testing.T) {
 		assert.NotEmpty(t, geoData)
 
 		// Test creating geographic nodes
-		nodeResult, err := workflow.createGeoNodes(geoData)
+		nodeResult, err := workflow.createGeoNodes(geoData, false)
 		require.NoError(t, err)
 		assert.Greater(t, nodeResult.CountriesCreated, 0)
 
 		// Test creating relationships
-		relResult, err := workflow.createGeoRelationships(geoData)
+		relResult, err := workflow.createGeoRelationships(models.HostIDSchemeNamespaced, geoData, false)
 		require.NoError(t, err)
 		assert.Greater(t, relResult.HostCityLinks, 0)
 
 		// Test updating host records
-		err = workflow.updateHostRecords(geoData)
+		err = workflow.updateHostRecords(models.HostIDSchemeNamespaced, geoData, false)
 		require.NoError(t, err)
 
 		// Verify host records were updated
@@ -187,7 +188,7 @@ func TestEnrichGeoWorkflow_CreateGeoNodes(t *testing.T) {
 		},
 	}
 
-	result, err := workflow.createGeoNodes(geoData)
+	result, err := workflow.createGeoNodes(geoData, false)
 	require.NoError(t, err)
 
 	// Should create 1 country (US), 1 region (California), 2 cities
@@ -233,18 +234,18 @@ func TestEnrichGeoWorkflow_CreateGeoRelationships(t *testing.T) {
 		};
 	`
 	_, err = surrealdb.Query[interface{}](ctx, db, createHostQuery, map[string]interface{}{
-		"host_id": "8_8_8_8",
+		"host_id": models.HostRecordID("8.8.8.8"),
 		"ip":      "8.8.8.8",
 		"now":     time.Now().UTC(),
 	})
 	require.NoError(t, err)
 
 	// Create geographic nodes
-	_, err = workflow.createGeoNodes(geoData)
+	_, err = workflow.createGeoNodes(geoData, false)
 	require.NoError(t, err)
 
 	// Create relationships
-	result, err := workflow.createGeoRelationships(geoData)
+	result, err := workflow.createGeoRelationships(models.HostIDSchemeNamespaced, geoData, false)
 	require.NoError(t, err)
 
 	assert.Equal(t, 1, result.HostCityLinks)
@@ -276,7 +277,7 @@ func TestEnrichGeoWorkflow_UpdateHostRecords(t *testing.T) {
 		};
 	`
 	_, err = surrealdb.Query[interface{}](ctx, db, createHostQuery, map[string]interface{}{
-		"host_id": "8_8_8_8",
+		"host_id": models.HostRecordID("8.8.8.8"),
 		"ip":      "8.8.8.8",
 		"now":     time.Now().UTC(),
 	})
@@ -293,18 +294,70 @@ func TestEnrichGeoWorkflow_UpdateHostRecords(t *testing.T) {
 		},
 	}
 
-	err = workflow.updateHostRecords(geoData)
+	err = workflow.updateHostRecords(models.HostIDSchemeNamespaced, geoData, false)
 	require.NoError(t, err)
 
 	// Verify update
 	verifyQuery := `SELECT * FROM type::thing('host', $host_id);`
 	result, err := surrealdb.Query[[]interface{}](ctx, db, verifyQuery, map[string]interface{}{
-		"host_id": "8_8_8_8",
+		"host_id": models.HostRecordID("8.8.8.8"),
 	})
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
 
+// TestEnrichGeoWorkflow_UpdateHostRecordsKeepsNewerLastSeen checks an
+// enrichment run never moves a host's last_seen backwards
+func TestEnrichGeoWorkflow_UpdateHostRecordsKeepsNewerLastSeen(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	workflow := NewEnrichGeoWorkflow(db, nil, zap.NewNop())
+
+	// An ingest stamped ahead of the enrichment run (e.g. a scanner result that
+	// landed while the lookup was in flight)
+	ctx := context.Background()
+	firstSeen := time.Now().UTC().Add(-time.Hour)
+	lastSeen := time.Now().UTC().Add(time.Hour)
+	_, err = surrealdb.Query[interface{}](ctx, db, `
+		CREATE type::thing('host', $host_id) CONTENT {
+			ip: $ip,
+			first_seen: $first_seen,
+			last_seen: $last_seen
+		};
+	`, map[string]interface{}{
+		"host_id":    models.HostRecordID("8.8.8.8"),
+		"ip":         "8.8.8.8",
+		"first_seen": firstSeen,
+		"last_seen":  lastSeen,
+	})
+	require.NoError(t, err)
+
+	geoData := map[string]*enrichment.GeoIPInfo{
+		"8.8.8.8": {IP: "8.8.8.8", City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US"},
+	}
+	require.NoError(t, workflow.updateHostRecords(models.HostIDSchemeNamespaced, geoData, false))
+
+	result, err := surrealdb.Query[[]models.HostResult](ctx, db,
+		`SELECT ip, city, first_seen, last_seen FROM type::thing('host', $host_id);`,
+		map[string]interface{}{"host_id": models.HostRecordID("8.8.8.8")})
+	require.NoError(t, err)
+	require.NotEmpty(t, *result)
+	require.Len(t, (*result)[0].Result, 1)
+
+	host := (*result)[0].Result[0]
+	assert.Equal(t, "Mountain View", host.City)
+	assert.WithinDuration(t, firstSeen, host.FirstSeen, time.Millisecond)
+	assert.WithinDuration(t, lastSeen, host.LastSeen, time.Millisecond)
+}
+
 // setupTestDB creates a test SurrealDB connection
 func setupTestDB(t *testing.T) (*surrealdb.DB, error) {
 	surrealURL := os.Getenv("SURREALDB_URL")
@@ -366,3 +419,138 @@ func getTestMMDBPath() string {
 
 	return ""
 }
+
+// TestEnrichGeoWorkflow_DryRun verifies dry runs count the plan without touching the database
+func TestEnrichGeoWorkflow_DryRun(t *testing.T) {
+	// A nil DB makes any attempted write fail the test
+	workflow := NewEnrichGeoWorkflow(nil, nil, zap.NewNop())
+
+	geoData := map[string]*enrichment.GeoIPInfo{
+		"8.8.8.8": {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US"},
+		"8.8.4.4": {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US"},
+		"1.1.1.1": {Country: "Australia", CountryCC: "AU"},
+	}
+
+	nodes, err := workflow.createGeoNodes(geoData, true)
+	require.NoError(t, err)
+	assert.Equal(t, GeoNodeResult{CountriesCreated: 2, RegionsCreated: 1, CitiesCreated: 1}, nodes)
+
+	rels, err := workflow.createGeoRelationships(models.HostIDSchemeNamespaced, geoData, true)
+	require.NoError(t, err)
+	assert.Equal(t, RelationshipResult{HostCityLinks: 2, CityRegionLinks: 2, RegionCountryLinks: 2, HostCountryLinks: 1}, rels)
+
+	require.NoError(t, workflow.updateHostRecords(models.HostIDSchemeNamespaced, geoData, true))
+
+	preview := previewGeoMutations(models.HostIDSchemeNamespaced, geoData)
+	assert.Contains(t, preview, PlannedMutation{Op: MutationCreate, Target: "country:AU"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationCreate, Target: "city:US_California_Mountain View"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "host:ip4_8_8_8_8->IN_CITY->city:US_California_Mountain View"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "region:US_California->IN_COUNTRY->country:US"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "host:ip4_1_1_1_1->HOST_IN_COUNTRY->country:AU"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationUpdate, Target: "host:ip4_1_1_1_1"})
+	// 2 countries, 1 region, 1 city, 2 host->city, 1 city->region, 1 region->country, 1 host->country, 3 host updates
+	assert.Len(t, preview, 12)
+}
+
+func TestPreviewGeoMutations_IPv6(t *testing.T) {
+	geoData := map[string]*enrichment.GeoIPInfo{
+		"2001:4860:4860::8888": {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US"},
+		"8.8.8.8":              {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US"},
+	}
+
+	preview := previewGeoMutations(models.HostIDSchemeNamespaced, geoData)
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "host:ip6_2001-4860-4860--8888->IN_CITY->city:US_California_Mountain View"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "host:ip4_8_8_8_8->IN_CITY->city:US_California_Mountain View"})
+
+	// Every host update decodes back to one of the looked-up addresses
+	var hosts []string
+	for _, mutation := range preview {
+		if mutation.Op == MutationUpdate {
+			hosts = append(hosts, models.IPFromHostRecordID(mutation.Target))
+		}
+	}
+	assert.ElementsMatch(t, []string{"2001:4860:4860::8888", "8.8.8.8"}, hosts)
+}
+
+func TestDropImpreciseCities(t *testing.T) {
+	geoData := map[string]*enrichment.GeoIPInfo{
+		"8.8.8.8": {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US", AccuracyRadiusKm: 1},
+		"8.8.4.4": {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US", AccuracyRadiusKm: 1000},
+		"1.1.1.1": {City: "Sydney", Region: "New South Wales", Country: "Australia", CountryCC: "AU"},
+	}
+
+	filtered, dropped := dropImpreciseCities(geoData, DefaultMaxCityAccuracyKm)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, "Mountain View", filtered["8.8.8.8"].City, "accurate results keep their city")
+	assert.Equal(t, "Sydney", filtered["1.1.1.1"].City, "results without a radius keep their city")
+
+	imprecise := filtered["8.8.4.4"]
+	assert.Empty(t, imprecise.City)
+	assert.Equal(t, "California", imprecise.Region)
+	assert.Equal(t, "US", imprecise.CountryCC)
+	assert.Equal(t, "Mountain View", geoData["8.8.4.4"].City, "the lookup results are not modified")
+}
+
+// TestEnrichGeoWorkflow_LowAccuracySkipsCityEdge checks a low-accuracy result falls
+// back to a host->region edge without a host->city edge
+func TestEnrichGeoWorkflow_LowAccuracySkipsCityEdge(t *testing.T) {
+	workflow := NewEnrichGeoWorkflowWithConfig(nil, nil, zap.NewNop(), EnrichGeoConfig{MaxCityAccuracyKm: 50})
+	assert.Equal(t, 50, workflow.maxCityAccuracyKm)
+
+	geoData, _ := dropImpreciseCities(map[string]*enrichment.GeoIPInfo{
+		"8.8.4.4": {City: "Mountain View", Region: "California", Country: "United States", CountryCC: "US", AccuracyRadiusKm: 500},
+	}, workflow.maxCityAccuracyKm)
+
+	nodes, err := workflow.createGeoNodes(geoData, true)
+	require.NoError(t, err)
+	assert.Equal(t, GeoNodeResult{CountriesCreated: 1, RegionsCreated: 1}, nodes)
+
+	rels, err := workflow.createGeoRelationships(models.HostIDSchemeNamespaced, geoData, true)
+	require.NoError(t, err)
+	assert.Equal(t, RelationshipResult{RegionCountryLinks: 1, HostRegionLinks: 1}, rels)
+
+	preview := previewGeoMutations(models.HostIDSchemeNamespaced, geoData)
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "host:ip4_8_8_4_4->HOST_IN_REGION->region:US_California"})
+	assert.Contains(t, preview, PlannedMutation{Op: MutationRelate, Target: "region:US_California->IN_COUNTRY->country:US"})
+	for _, mutation := range preview {
+		assert.NotContains(t, mutation.Target, "IN_CITY")
+		assert.NotContains(t, mutation.Target, "city:")
+	}
+}
+
+// TestEnrichGeoWorkflow_LowAccuracyWithoutRegionGetsCountry checks a low-accuracy
+// result with no region still places the host in its country
+func TestEnrichGeoWorkflow_LowAccuracyWithoutRegionGetsCountry(t *testing.T) {
+	workflow := NewEnrichGeoWorkflowWithConfig(nil, nil, zap.NewNop(), EnrichGeoConfig{MaxCityAccuracyKm: 50})
+
+	geoData, dropped := dropImpreciseCities(map[string]*enrichment.GeoIPInfo{
+		"1.1.1.1": {City: "Sydney", Country: "Australia", CountryCC: "AU", AccuracyRadiusKm: 1000},
+	}, workflow.maxCityAccuracyKm)
+	assert.Equal(t, 1, dropped)
+
+	rels, err := workflow.createGeoRelationships(models.HostIDSchemeNamespaced, geoData, true)
+	require.NoError(t, err)
+	assert.Equal(t, RelationshipResult{HostCountryLinks: 1}, rels)
+
+	assert.Contains(t, previewGeoMutations(models.HostIDSchemeNamespaced, geoData),
+		PlannedMutation{Op: MutationRelate, Target: "host:ip4_1_1_1_1->HOST_IN_COUNTRY->country:AU"})
+}
+
+func TestNewEnrichGeoWorkflow_DefaultAccuracy(t *testing.T) {
+	workflow := NewEnrichGeoWorkflow(nil, nil, zap.NewNop())
+	assert.Equal(t, DefaultMaxCityAccuracyKm, workflow.maxCityAccuracyKm)
+}
+
+func TestEnrichGeoWorkflow_SkipsFreshHosts(t *testing.T) {
+	now := time.Now()
+	loader := &fakeEnrichedAt{times: map[string]time.Time{
+		"1.1.1.1": now.Add(-10 * time.Minute), // recently enriched
+		"8.8.8.8": now.Add(-2 * time.Hour),    // stale
+	}}
+	workflow := NewEnrichGeoWorkflowWithConfig(nil, nil, zap.NewNop(), EnrichGeoConfig{FreshFor: time.Hour, EnrichedAt: loader})
+	assert.Equal(t, time.Hour, workflow.freshFor)
+
+	ips := workflow.filterIPsNeedingEnrichment(context.Background(), []string{"1.1.1.1", "8.8.8.8"}, now)
+	assert.Equal(t, []string{"8.8.8.8"}, ips)
+	assert.Equal(t, "geo_enriched_at", loader.field)
+}
@@ -0,0 +1,85 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go"
+)
+
+// DefaultEnrichmentFreshness is how long an ASN or GeoIP enrichment is trusted
+// before the host is looked up again
+const DefaultEnrichmentFreshness = 24 * time.Hour
+
+// Host fields recording when each enrichment last succeeded
+const (
+	hostASNEnrichedAt = "asn_enriched_at"
+	hostGeoEnrichedAt = "geo_enriched_at"
+)
+
+// EnrichedAtLoader reports when hosts were last enriched
+type EnrichedAtLoader interface {
+	// EnrichedAt returns when each of ips was last enriched, as recorded in the
+	// given host field; IPs never enriched are left out
+	EnrichedAt(ctx context.Context, ips []string, field string) (map[string]time.Time, error)
+}
+
+// hostEnrichedAt reads enrichment timestamps from the host table
+type hostEnrichedAt struct {
+	db *surrealdb.DB
+}
+
+// EnrichedAt loads the timestamps with queryEnrichedAt
+func (h hostEnrichedAt) EnrichedAt(ctx context.Context, ips []string, field string) (map[string]time.Time, error) {
+	return queryEnrichedAt(ctx, h.db, ips, field)
+}
+
+// enrichedAtOrDefault returns loader, or one reading the host table when it is nil
+func enrichedAtOrDefault(loader EnrichedAtLoader, dbClient *surrealdb.DB) EnrichedAtLoader {
+	if loader == nil {
+		return hostEnrichedAt{db: dbClient}
+	}
+	return loader
+}
+
+// staleIPs returns the IPs not enriched since now minus freshFor, in their
+// original order, and how many were skipped as fresh
+func staleIPs(ips []string, enrichedAt map[string]time.Time, now time.Time, freshFor time.Duration) ([]string, int) {
+	cutoff := now.Add(-freshFor)
+	stale := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if at, ok := enrichedAt[ip]; ok && at.After(cutoff) {
+			continue
+		}
+		stale = append(stale, ip)
+	}
+	return stale, len(ips) - len(stale)
+}
+
+// queryEnrichedAt loads the enrichment timestamp in field for every host in ips
+func queryEnrichedAt(ctx context.Context, dbClient *surrealdb.DB, ips []string, field string) (map[string]time.Time, error) {
+	if field != hostASNEnrichedAt && field != hostGeoEnrichedAt {
+		return nil, fmt.Errorf("unknown enrichment field %q", field)
+	}
+
+	query := fmt.Sprintf(`SELECT ip, %s AS enriched_at FROM host WHERE ip IN $ips AND %s != NONE;`, field, field)
+	result, err := surrealdb.Query[[]struct {
+		IP         string    `json:"ip"`
+		EnrichedAt time.Time `json:"enriched_at"`
+	}](ctx, dbClient, query, map[string]interface{}{
+		"ips": ips,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", field, err)
+	}
+
+	enrichedAt := make(map[string]time.Time)
+	if result == nil || len(*result) == 0 {
+		return enrichedAt, nil
+	}
+	for _, host := range (*result)[0].Result {
+		enrichedAt[host.IP] = host.EnrichedAt
+	}
+	return enrichedAt, nil
+}
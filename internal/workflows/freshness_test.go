@@ -0,0 +1,36 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEnrichedAt serves enrichment timestamps from memory and records the field asked for
+type fakeEnrichedAt struct {
+	times map[string]time.Time
+	err   error
+	field string
+}
+
+func (f *fakeEnrichedAt) EnrichedAt(ctx context.Context, ips []string, field string) (map[string]time.Time, error) {
+	f.field = field
+	return f.times, f.err
+}
+
+func TestStaleIPs(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	enrichedAt := map[string]time.Time{
+		"1.1.1.1": now.Add(-time.Hour),      // recently enriched
+		"8.8.8.8": now.Add(-48 * time.Hour), // stale
+		"9.9.9.9": now.Add(-24 * time.Hour), // exactly at the cutoff
+	}
+
+	stale, skipped := staleIPs([]string{"8.8.8.8", "1.1.1.1", "9.9.9.9", "4.4.4.4"}, enrichedAt, now, 24*time.Hour)
+
+	assert.Equal(t, []string{"8.8.8.8", "9.9.9.9", "4.4.4.4"}, stale, "stale and never-enriched IPs are kept in order")
+	assert.Equal(t, 1, skipped)
+}
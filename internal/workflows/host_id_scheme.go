@@ -0,0 +1,31 @@
+package workflows
+
+import (
+	"context"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// HostIDSchemeSource reports the host ID scheme host writes must use. It is asked
+// inside every step that writes hosts, so a db.Migrator, which answers from the
+// applied schema, moves running workflows onto namespaced IDs once migration 10 runs.
+type HostIDSchemeSource interface {
+	HostIDScheme(ctx context.Context) (models.HostIDScheme, error)
+}
+
+// FixedHostIDScheme is a HostIDSchemeSource that always reports one scheme, for
+// deployments that pin HOST_ID_SCHEME
+type FixedHostIDScheme models.HostIDScheme
+
+// HostIDScheme returns the fixed scheme
+func (s FixedHostIDScheme) HostIDScheme(ctx context.Context) (models.HostIDScheme, error) {
+	return models.HostIDScheme(s), nil
+}
+
+// hostIDSchemesOrDefault returns source, or the namespaced scheme when it is nil
+func hostIDSchemesOrDefault(source HostIDSchemeSource) HostIDSchemeSource {
+	if source == nil {
+		return FixedHostIDScheme(models.HostIDSchemeNamespaced)
+	}
+	return source
+}
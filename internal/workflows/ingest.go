@@ -2,25 +2,114 @@ package workflows
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
 	restate "github.com/restatedev/sdk-go"
+	"github.com/spectra-red/recon/internal/db"
+	"github.com/spectra-red/recon/internal/enrichment"
 	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/webhook"
 	"github.com/surrealdb/surrealdb.go"
 )
 
 // IngestWorkflow handles the durable scan ingestion workflow
 type IngestWorkflow struct {
 	db *surrealdb.DB
+
+	// rejectPrivateIPs drops private, loopback, link-local and other reserved
+	// addresses during parsing. Internal deployments leave this off.
+	rejectPrivateIPs bool
+
+	// notifier delivers completion callbacks; nil rejects every callback URL
+	notifier      *webhook.Notifier
+	callbackRetry webhook.RetryPolicy
+
+	// events receives a job event for every state transition
+	events JobEventPublisher
+
+	// guessServiceNames names services on ports the scan identified nothing on,
+	// from the port number alone (see enrichment.GuessServiceName)
+	guessServiceNames bool
+
+	// cpeQueue receives the scan's services for CPE enrichment; nil skips them
+	cpeQueue CPEQueue
+
+	// hostIDSchemes reports the host ID scheme of the applied schema
+	hostIDSchemes HostIDSchemeSource
+
+	// hosts writes each scanned host to the graph
+	hosts HostPersister
+}
+
+// HostPersister writes one scanned host to the graph
+type HostPersister interface {
+	// PersistHost upserts the host with its ports and HAS edges, stamping the
+	// edges with the scan's source, and returns the number of ports written
+	PersistHost(ctx context.Context, scheme models.HostIDScheme, host models.ScanHost, source string, now time.Time) (int, error)
+}
+
+// IngestConfig configures an IngestWorkflow
+type IngestConfig struct {
+	// RejectPrivateIPs drops private and reserved hosts during parsing
+	RejectPrivateIPs bool
+	// Notifier delivers job completion callbacks; nil disables them
+	Notifier *webhook.Notifier
+	// CallbackRetry bounds callback delivery attempts; zero uses webhook.DefaultRetryPolicy
+	CallbackRetry webhook.RetryPolicy
+	// Events receives job state transitions; nil uses the job_event table
+	Events JobEventPublisher
+	// GuessServiceNames tags ports that arrive without a service with the
+	// service conventionally run on them, stored with low confidence
+	GuessServiceNames bool
+	// CPEQueue receives the services each scan wrote, for the CPE queue worker
+	// to enrich; nil leaves them unenriched
+	CPEQueue CPEQueue
+	// HostIDSchemes is asked for the host ID scheme on every persist, e.g. a
+	// db.Migrator; nil uses the namespaced scheme
+	HostIDSchemes HostIDSchemeSource
+	// Hosts writes each scanned host; nil writes them to the database
+	Hosts HostPersister
 }
 
 // NewIngestWorkflow creates a new IngestWorkflow instance
-func NewIngestWorkflow(db *surrealdb.DB) *IngestWorkflow {
+func NewIngestWorkflow(db *surrealdb.DB, rejectPrivateIPs bool) *IngestWorkflow {
+	return NewIngestWorkflowWithConfig(db, IngestConfig{RejectPrivateIPs: rejectPrivateIPs})
+}
+
+// NewIngestWorkflowWithConfig creates a new IngestWorkflow with the given configuration
+func NewIngestWorkflowWithConfig(dbClient *surrealdb.DB, config IngestConfig) *IngestWorkflow {
+	callbackRetry := config.CallbackRetry
+	if callbackRetry.MaxAttempts <= 0 {
+		callbackRetry = webhook.DefaultRetryPolicy
+	}
+
+	events := config.Events
+	if events == nil {
+		events = db.NewJobEventStore(dbClient, nil)
+	}
+
+	hosts := config.Hosts
+	if hosts == nil {
+		hosts = &graphHostPersister{db: dbClient}
+	}
+
 	return &IngestWorkflow{
-		db: db,
+		db:               dbClient,
+		rejectPrivateIPs: config.RejectPrivateIPs,
+		notifier:         config.Notifier,
+		callbackRetry:    callbackRetry,
+		events:           events,
+
+		guessServiceNames: config.GuessServiceNames,
+		cpeQueue:          config.CPEQueue,
+		hostIDSchemes:     hostIDSchemesOrDefault(config.HostIDSchemes),
+		hosts:             hosts,
 	}
 }
 
@@ -29,15 +118,42 @@ func (w *IngestWorkflow) ServiceName() string {
 	return "IngestWorkflow"
 }
 
+// progressInterval is how many hosts are persisted between job progress updates
+const progressInterval = 100
+
+// ParseResult holds the parsed scan data and how long parsing took
+type ParseResult struct {
+	ScanData   *models.ScanData
+	DurationMS int64
+}
+
 // PersistResult holds the result of persisting scan data
 type PersistResult struct {
-	Hosts int
-	Ports int
+	Hosts      int
+	Ports      int
+	DurationMS int64
 }
 
 // Run executes the ingest workflow with durable steps
-// This workflow is idempotent and can be safely retried
+// This workflow is idempotent and can be safely retried.
+// Cancellation is cooperative: the job state is checked between durable steps and the
+// workflow stops without further writes once the job has been cancelled.
+// When the request carries a callback URL, a final durable step notifies it of
+// the outcome; delivery failures are recorded on the job but never fail it.
 func (w *IngestWorkflow) Run(ctx restate.Context, req models.IngestWorkflowRequest) (models.IngestWorkflowResponse, error) {
+	resp, err := w.ingest(ctx, req)
+	if req.CallbackURL != "" {
+		w.notifyCompletion(ctx, req.JobID, req.CallbackURL, completionPayload(resp, err))
+	}
+	return resp, err
+}
+
+// ingest runs the durable ingest steps and returns the job's final state
+func (w *IngestWorkflow) ingest(ctx restate.Context, req models.IngestWorkflowRequest) (models.IngestWorkflowResponse, error) {
+	if w.checkCancelled(ctx, req.JobID) {
+		return cancelledResponse(req.JobID), nil
+	}
+
 	// Step 1: Update job state to "processing"
 	_, err := restate.Run[string](ctx, func(ctx restate.RunContext) (string, error) {
 		return "", w.updateJobState(req.JobID, models.JobStateProcessing, "", req.ScannerKey)
@@ -51,9 +167,18 @@ func (w *IngestWorkflow) Run(ctx restate.Context, req models.IngestWorkflowReque
 		}, fmt.Errorf("failed to update job to processing: %w", err)
 	}
 
+	if w.checkCancelled(ctx, req.JobID) {
+		return cancelledResponse(req.JobID), nil
+	}
+
 	// Step 2: Parse and validate scan data
-	scanData, err := restate.Run[*models.ScanData](ctx, func(ctx restate.RunContext) (*models.ScanData, error) {
-		return w.parseScanData(req.ScanData)
+	parseResult, err := restate.Run[ParseResult](ctx, func(ctx restate.RunContext) (ParseResult, error) {
+		start := time.Now()
+		scanData, err := w.parseScanData(req.ScanData)
+		if scanData != nil {
+			scanData.Source = scanSource(req.Source)
+		}
+		return ParseResult{ScanData: scanData, DurationMS: time.Since(start).Milliseconds()}, err
 	})
 	if err != nil {
 		_ = w.updateJobState(req.JobID, models.JobStateFailed, fmt.Sprintf("Failed to parse scan data: %v", err), req.ScannerKey)
@@ -63,10 +188,21 @@ func (w *IngestWorkflow) Run(ctx restate.Context, req models.IngestWorkflowReque
 		}, fmt.Errorf("failed to parse scan data: %w", err)
 	}
 
+	scanData := parseResult.ScanData
+	hostsTotal := distinctHostCount(scanData.Hosts)
+
+	// Record the parse timing and the total number of hosts to persist
+	w.recordProgress(ctx, req.JobID, models.JobProgress{HostsTotal: hostsTotal}, models.JobStepParse, parseResult.DurationMS)
+
+	if w.checkCancelled(ctx, req.JobID) {
+		return cancelledResponse(req.JobID), nil
+	}
+
 	// Step 3: Persist scan results to SurrealDB
 	persistResult, err := restate.Run[PersistResult](ctx, func(ctx restate.RunContext) (PersistResult, error) {
+		start := time.Now()
 		hosts, ports, err := w.persistScanData(req.JobID, scanData, req.ScannerKey)
-		return PersistResult{Hosts: hosts, Ports: ports}, err
+		return PersistResult{Hosts: hosts, Ports: ports, DurationMS: time.Since(start).Milliseconds()}, err
 	})
 	if err != nil {
 		_ = w.updateJobState(req.JobID, models.JobStateFailed, fmt.Sprintf("Failed to persist scan data: %v", err), req.ScannerKey)
@@ -76,29 +212,241 @@ func (w *IngestWorkflow) Run(ctx restate.Context, req models.IngestWorkflowReque
 		}, fmt.Errorf("failed to persist scan data: %w", err)
 	}
 
-	// Step 4: Update job state to "completed"
-	_, err = restate.Run[string](ctx, func(ctx restate.RunContext) (string, error) {
-		return "", w.updateJobStateWithCounts(req.JobID, models.JobStateCompleted, "", req.ScannerKey, persistResult.Hosts, persistResult.Ports)
+	w.recordProgress(ctx, req.JobID, models.JobProgress{HostsDone: persistResult.Hosts, HostsTotal: hostsTotal}, models.JobStepPersist, persistResult.DurationMS)
+
+	if durationMS, ok := w.enqueueCPEEnrichment(ctx, scanData.Hosts); ok {
+		w.recordProgress(ctx, req.JobID, models.JobProgress{HostsDone: persistResult.Hosts, HostsTotal: hostsTotal}, models.JobStepEnrich, durationMS)
+	}
+
+	if w.checkCancelled(ctx, req.JobID) {
+		return cancelledResponse(req.JobID), nil
+	}
+
+	// Step 4: Update job state to "completed", unless a cancel landed after the last check
+	completed, err := restate.Run[bool](ctx, func(ctx restate.RunContext) (bool, error) {
+		return w.updateJobStateWithCounts(req.JobID, models.JobStateCompleted, "", req.ScannerKey, persistResult.Hosts, persistResult.Ports)
 	})
+	if err == nil && !completed {
+		return cancelledResponse(req.JobID), nil
+	}
 	if err != nil {
 		// Even if we fail to update to completed, the data is persisted
 		// This is a non-critical error, so we log it but don't fail the workflow
 		return models.IngestWorkflowResponse{
-			JobID:     req.JobID,
-			State:     models.JobStateCompleted, // Data was persisted successfully
-			HostCount: persistResult.Hosts,
-			PortCount: persistResult.Ports,
+			JobID:        req.JobID,
+			State:        models.JobStateCompleted, // Data was persisted successfully
+			HostCount:    persistResult.Hosts,
+			PortCount:    persistResult.Ports,
+			SkippedHosts: scanData.SkippedHosts,
 		}, nil
 	}
 
 	return models.IngestWorkflowResponse{
-		JobID:     req.JobID,
-		State:     models.JobStateCompleted,
-		HostCount: persistResult.Hosts,
-		PortCount: persistResult.Ports,
+		JobID:        req.JobID,
+		State:        models.JobStateCompleted,
+		HostCount:    persistResult.Hosts,
+		PortCount:    persistResult.Ports,
+		SkippedHosts: scanData.SkippedHosts,
 	}, nil
 }
 
+// completionPayload builds the callback body for a finished ingest
+func completionPayload(resp models.IngestWorkflowResponse, err error) models.JobCallbackPayload {
+	payload := models.JobCallbackPayload{
+		JobID:        resp.JobID,
+		State:        resp.State,
+		HostCount:    resp.HostCount,
+		PortCount:    resp.PortCount,
+		SkippedHosts: resp.SkippedHosts,
+	}
+	if err != nil && resp.State == models.JobStateFailed {
+		payload.Error = err.Error()
+	}
+	return payload
+}
+
+// callbackAttempt is the journaled outcome of one callback delivery attempt
+type callbackAttempt struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Retryable  bool   `json:"retryable,omitempty"`
+}
+
+// err rebuilds the attempt's error for webhook.Retry: retryable failures come
+// back as a *webhook.DeliveryError, anything else stops the retries
+func (a callbackAttempt) err() error {
+	switch {
+	case a.Error == "":
+		return nil
+	case a.Retryable:
+		return &webhook.DeliveryError{StatusCode: a.StatusCode, Err: errors.New(a.Error)}
+	default:
+		return errors.New(a.Error)
+	}
+}
+
+// deliverCallback makes a single delivery attempt
+func (w *IngestWorkflow) deliverCallback(ctx context.Context, callbackURL string, payload models.JobCallbackPayload) callbackAttempt {
+	if w.notifier == nil {
+		return callbackAttempt{Error: webhook.ErrCallbacksDisabled.Error()}
+	}
+
+	err := w.notifier.Deliver(ctx, callbackURL, payload)
+	if err == nil {
+		return callbackAttempt{}
+	}
+
+	attempt := callbackAttempt{Error: err.Error()}
+	var deliveryErr *webhook.DeliveryError
+	if errors.As(err, &deliveryErr) {
+		attempt.StatusCode = deliveryErr.StatusCode
+		attempt.Retryable = deliveryErr.Retryable()
+	}
+	return attempt
+}
+
+// notifyCompletion delivers the completion payload with durable retries:
+// each attempt is a journaled step and the backoff between them a durable
+// sleep, so a restart resumes where delivery left off. The outcome is recorded
+// on the job on a best-effort basis.
+func (w *IngestWorkflow) notifyCompletion(ctx restate.Context, jobID, callbackURL string, payload models.JobCallbackPayload) {
+	attempts, err := webhook.Retry(w.callbackRetry,
+		func(n int) error {
+			outcome, err := restate.Run(ctx, func(ctx restate.RunContext) (callbackAttempt, error) {
+				return w.deliverCallback(ctx, callbackURL, payload), nil
+			}, restate.WithName(fmt.Sprintf("callback-attempt-%d", n)))
+			if err != nil {
+				return err
+			}
+			return outcome.err()
+		},
+		func(d time.Duration) error {
+			return restate.Sleep(ctx, d)
+		})
+
+	_, _ = restate.Run(ctx, func(ctx restate.RunContext) (restate.Void, error) {
+		// Best effort: a failed write must not fail a finished job
+		_ = w.updateJobProgress(jobID, callbackUpdate(attempts, err))
+		return restate.Void{}, nil
+	}, restate.WithName("record-callback"))
+}
+
+// callbackUpdate builds the job fields recording a callback delivery outcome
+func callbackUpdate(attempts int, err error) map[string]interface{} {
+	update := map[string]interface{}{
+		"callback_state":    models.CallbackDelivered,
+		"callback_attempts": attempts,
+	}
+	if err != nil {
+		update["callback_state"] = models.CallbackFailed
+		update["callback_error"] = err.Error()
+	}
+	return update
+}
+
+// checkCancelled durably records whether the job has been cancelled
+// A failed lookup is treated as not cancelled so a transient error doesn't abort the ingest
+func (w *IngestWorkflow) checkCancelled(ctx restate.Context, jobID string) bool {
+	cancelled, err := restate.Run[bool](ctx, func(ctx restate.RunContext) (bool, error) {
+		state, err := w.getJobState(jobID)
+		if err != nil {
+			return false, nil
+		}
+		return state == models.JobStateCancelled, nil
+	})
+	return err == nil && cancelled
+}
+
+// enqueueCPEEnrichment durably queues the scan's services for CPE enrichment and
+// returns how long that took, or false when no queue is configured
+// Enrichment is not part of the ingest, so a failed enqueue doesn't fail the workflow;
+// the services can still be picked up by a backfill.
+func (w *IngestWorkflow) enqueueCPEEnrichment(ctx restate.Context, hosts []models.ScanHost) (int64, bool) {
+	if w.cpeQueue == nil {
+		return 0, false
+	}
+	durationMS, err := restate.Run(ctx, func(ctx restate.RunContext) (int64, error) {
+		start := time.Now()
+		_ = w.cpeQueue.Enqueue(ctx, cpeQueueEntries(hosts))
+		return time.Since(start).Milliseconds(), nil
+	}, restate.WithName("enqueue-cpe-enrichment"))
+	return durationMS, err == nil
+}
+
+// recordProgress durably records job progress and the duration of a completed step
+// Progress is informational, so a failed write doesn't fail the workflow
+func (w *IngestWorkflow) recordProgress(ctx restate.Context, jobID string, progress models.JobProgress, step string, durationMS int64) {
+	_, _ = restate.Run[string](ctx, func(ctx restate.RunContext) (string, error) {
+		_ = w.updateJobProgress(jobID, progressUpdate(progress, step, durationMS))
+		return "", nil
+	})
+}
+
+// progressUpdate builds the job fields to merge for a progress update
+// step may be empty when only the host progress changed
+func progressUpdate(progress models.JobProgress, step string, durationMS int64) map[string]interface{} {
+	data := map[string]interface{}{
+		"progress": map[string]interface{}{
+			"hosts_done":  progress.HostsDone,
+			"hosts_total": progress.HostsTotal,
+		},
+	}
+	if step != "" {
+		data["step_durations_ms"] = map[string]interface{}{
+			step: durationMS,
+		}
+	}
+	return data
+}
+
+// progressDue reports whether progress should be written after persisting done of total hosts
+func progressDue(done, total int) bool {
+	return done > 0 && (done == total || done%progressInterval == 0)
+}
+
+// updateJobProgress merges progress fields into the job record
+func (w *IngestWorkflow) updateJobProgress(jobID string, data map[string]interface{}) error {
+	data["updated_at"] = time.Now().UTC()
+
+	updateQuery := `UPDATE type::thing('job', $job_id) MERGE $data;`
+	_, err := surrealdb.Query[interface{}](context.Background(), w.db, updateQuery, map[string]interface{}{
+		"job_id": jobID,
+		"data":   data,
+	})
+	return err
+}
+
+// cancelledResponse builds the workflow response for a job cancelled mid-flight
+func cancelledResponse(jobID string) models.IngestWorkflowResponse {
+	return models.IngestWorkflowResponse{
+		JobID: jobID,
+		State: models.JobStateCancelled,
+	}
+}
+
+// getJobState reads the current state of a job from SurrealDB
+// Returns an empty state if the job doesn't exist yet
+func (w *IngestWorkflow) getJobState(jobID string) (models.JobState, error) {
+	query := `SELECT VALUE state FROM type::thing('job', $job_id);`
+	result, err := surrealdb.Query[[]string](context.Background(), w.db, query, map[string]interface{}{
+		"job_id": jobID,
+	})
+	if err != nil {
+		return "", err
+	}
+	if result == nil || len(*result) == 0 {
+		return "", nil
+	}
+	if (*result)[0].Error != nil {
+		return "", (*result)[0].Error
+	}
+	states := (*result)[0].Result
+	if len(states) == 0 {
+		return "", nil
+	}
+	return models.ParseJobState(states[0])
+}
+
 // updateJobState updates the job state in SurrealDB
 func (w *IngestWorkflow) updateJobState(jobID string, state models.JobState, errorMsg string, scannerKey string) error {
 	ctx := context.Background()
@@ -128,6 +476,7 @@ func (w *IngestWorkflow) updateJobState(jobID string, state models.JobState, err
 				id: $job_id,
 				state: $state,
 				scanner_key: $scanner_key,
+				scanner_id: $scanner_id,
 				error_message: $error_message,
 				created_at: $now,
 				updated_at: $now,
@@ -139,9 +488,13 @@ func (w *IngestWorkflow) updateJobState(jobID string, state models.JobState, err
 			"job_id":        jobID,
 			"state":         string(state),
 			"scanner_key":   scannerKey,
+			"scanner_id":    models.ScannerID(scannerKey),
 			"error_message": errorPtr,
 			"now":           now,
 		})
+		if err == nil {
+			w.publishTransition(ctx, jobID, state, errorMsg, scannerKey, 0, 0, now)
+		}
 		return err
 	}
 
@@ -153,7 +506,7 @@ func (w *IngestWorkflow) updateJobState(jobID string, state models.JobState, err
 	if errorMsg != "" {
 		updateData["error_message"] = errorPtr
 	}
-	if state == models.JobStateCompleted || state == models.JobStateFailed {
+	if state.IsTerminal() {
 		updateData["completed_at"] = now
 	}
 
@@ -162,12 +515,17 @@ func (w *IngestWorkflow) updateJobState(jobID string, state models.JobState, err
 		"job_id": jobID,
 		"data":   updateData,
 	})
+	if err == nil {
+		w.publishTransition(ctx, jobID, state, errorMsg, scannerKey, 0, 0, now)
+	}
 
 	return err
 }
 
 // updateJobStateWithCounts updates the job state with host and port counts
-func (w *IngestWorkflow) updateJobStateWithCounts(jobID string, state models.JobState, errorMsg string, scannerKey string, hostCount, portCount int) error {
+// A cancelled job is left alone, so a cancel that races the final transition wins;
+// the returned bool reports whether the job was updated.
+func (w *IngestWorkflow) updateJobStateWithCounts(jobID string, state models.JobState, errorMsg string, scannerKey string, hostCount, portCount int) (bool, error) {
 	ctx := context.Background()
 	now := time.Now().UTC()
 
@@ -185,17 +543,46 @@ func (w *IngestWorkflow) updateJobStateWithCounts(jobID string, state models.Job
 	if errorMsg != "" {
 		updateData["error_message"] = errorPtr
 	}
-	if state == models.JobStateCompleted || state == models.JobStateFailed {
+	if state.IsTerminal() {
 		updateData["completed_at"] = now
 	}
 
-	updateQuery := `UPDATE type::thing('job', $job_id) MERGE $data;`
-	_, err := surrealdb.Query[interface{}](ctx, w.db, updateQuery, map[string]interface{}{
-		"job_id": jobID,
-		"data":   updateData,
+	updateQuery := `UPDATE type::thing('job', $job_id) MERGE $data WHERE state != $cancelled;`
+	result, err := surrealdb.Query[[]map[string]interface{}](ctx, w.db, updateQuery, map[string]interface{}{
+		"job_id":    jobID,
+		"data":      updateData,
+		"cancelled": models.JobStateCancelled.String(),
 	})
+	if err != nil {
+		return false, err
+	}
+	if result == nil || len(*result) == 0 {
+		return false, fmt.Errorf("failed to update job %s: empty result", jobID)
+	}
+	if (*result)[0].Error != nil {
+		return false, (*result)[0].Error
+	}
 
-	return err
+	// No row updated means the job was cancelled
+	if len((*result)[0].Result) == 0 {
+		return false, nil
+	}
+
+	w.publishTransition(ctx, jobID, state, errorMsg, scannerKey, hostCount, portCount, now)
+	return true, nil
+}
+
+// publishTransition publishes a job event for a state change that was just written
+func (w *IngestWorkflow) publishTransition(ctx context.Context, jobID string, state models.JobState, errorMsg, scannerKey string, hostCount, portCount int, at time.Time) {
+	publishJobEvent(ctx, w.events, models.JobEvent{
+		JobID:      jobID,
+		ScannerKey: scannerKey,
+		State:      state,
+		Error:      errorMsg,
+		HostCount:  hostCount,
+		PortCount:  portCount,
+		Timestamp:  at,
+	})
 }
 
 // parseScanData parses and validates scan data from Naabu JSON format
@@ -204,9 +591,14 @@ func (w *IngestWorkflow) parseScanData(rawData []byte) (*models.ScanData, error)
 	// Example:
 	// {"host":"1.2.3.4","port":80,"protocol":"tcp"}
 	// {"host":"1.2.3.4","port":443,"protocol":"tcp"}
+	// Scanners that fingerprint services may add a service object:
+	// {"host":"1.2.3.4","port":80,"protocol":"tcp","service":{"name":"http","product":"nginx","version":"1.24.0"}}
+	// and the raw banner they read:
+	// {"host":"1.2.3.4","port":22,"protocol":"tcp","service":{"name":"ssh","banner":"SSH-2.0-dropbear_2022.83"}}
 
 	lines := strings.Split(string(rawData), "\n")
 	hostMap := make(map[string]*models.ScanHost)
+	skipped := make(map[string]struct{})
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -215,9 +607,12 @@ func (w *IngestWorkflow) parseScanData(rawData []byte) (*models.ScanData, error)
 		}
 
 		var naabuEntry struct {
-			Host     string `json:"host"`
-			Port     int    `json:"port"`
-			Protocol string `json:"protocol"`
+			Host      string `json:"host"`
+			Port      int    `json:"port"`
+			Protocol  string `json:"protocol"`
+			Timestamp string `json:"timestamp"`
+
+			Service *models.ScanService `json:"service"`
 		}
 
 		if err := json.Unmarshal([]byte(line), &naabuEntry); err != nil {
@@ -230,6 +625,14 @@ func (w *IngestWorkflow) parseScanData(rawData []byte) (*models.ScanData, error)
 			continue
 		}
 
+		// Drop un-routable hosts when running as a public mesh
+		if w.rejectPrivateIPs {
+			if ip := net.ParseIP(naabuEntry.Host); ip != nil && isReservedIP(ip) {
+				skipped[naabuEntry.Host] = struct{}{}
+				continue
+			}
+		}
+
 		// Default protocol to tcp if not specified
 		if naabuEntry.Protocol == "" {
 			naabuEntry.Protocol = "tcp"
@@ -245,11 +648,29 @@ func (w *IngestWorkflow) parseScanData(rawData []byte) (*models.ScanData, error)
 			hostMap[naabuEntry.Host] = host
 		}
 
+		service := normalizeScanService(naabuEntry.Service)
+		if service == nil && w.guessServiceNames {
+			service = guessScanService(naabuEntry.Port, naabuEntry.Protocol)
+		}
+
 		host.Ports = append(host.Ports, models.ScanPort{
 			Number:   naabuEntry.Port,
 			Protocol: naabuEntry.Protocol,
 			State:    "open", // Naabu only reports open ports
+			Service:  service,
 		})
+
+		// Widen the host's observation window; a missing or malformed
+		// timestamp just leaves it to fall back to the ingest time
+		if seen, err := time.Parse(time.RFC3339Nano, naabuEntry.Timestamp); err == nil {
+			seen = seen.UTC()
+			if host.FirstSeen.IsZero() || seen.Before(host.FirstSeen) {
+				host.FirstSeen = seen
+			}
+			if seen.After(host.LastSeen) {
+				host.LastSeen = seen
+			}
+		}
 	}
 
 	// Convert map to slice
@@ -259,15 +680,72 @@ func (w *IngestWorkflow) parseScanData(rawData []byte) (*models.ScanData, error)
 	}
 
 	if len(hosts) == 0 {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("no valid hosts found in scan data (%d private/reserved hosts rejected)", len(skipped))
+		}
 		return nil, fmt.Errorf("no valid hosts found in scan data")
 	}
 
 	return &models.ScanData{
-		Hosts: hosts,
+		Hosts:        hosts,
+		SkippedHosts: len(skipped),
 	}, nil
 }
 
+// scanSource returns the source to record for a scan submitted as source
+// The API validates sources before they reach the workflow, so one that still
+// fails validation is recorded as unknown rather than failing the ingest.
+func scanSource(source string) string {
+	normalized, err := models.NormalizeScanSource(source)
+	if err != nil {
+		return models.ScanSourceUnknown
+	}
+	return normalized
+}
+
+// isReservedIP reports whether ip is not publicly routable: RFC 1918 / RFC 4193
+// private ranges, loopback, link-local, multicast, or the unspecified address
+func isReservedIP(ip net.IP) bool {
+	return ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// SurrealQL expressions that keep a record's observation window monotonic:
+// last_seen only moves forward and first_seen only moves backward, so an
+// out-of-order ingest or a later enrichment update can never shrink it.
+// They read the candidate times from $first_seen and $last_seen.
+const (
+	firstSeenBackward = `IF first_seen IS NONE OR $first_seen < first_seen THEN $first_seen ELSE first_seen END`
+	lastSeenForward   = `IF last_seen IS NONE OR $last_seen > last_seen THEN $last_seen ELSE last_seen END`
+)
+
+// discoveredByKnown keeps the tool that first found a port on a host, replacing it
+// with $source only when it was never recorded or recorded as unknown
+const discoveredByKnown = `IF discovered_by IS NONE OR discovered_by = "unknown" THEN $source ELSE discovered_by END`
+
+// seenWindow returns the first and last observation times to record for a
+// scanned host. Hosts without scanner timestamps were seen at now; timestamps
+// ahead of now are clamped so a skewed scanner clock can't push last_seen into
+// the future.
+func seenWindow(host models.ScanHost, now time.Time) (time.Time, time.Time) {
+	first, last := host.FirstSeen, host.LastSeen
+	if last.IsZero() || last.After(now) {
+		last = now
+	}
+	if first.IsZero() || first.After(last) {
+		first = last
+	}
+	return first, last
+}
+
 // persistScanData persists scan data to SurrealDB
+// Hosts repeated in the scan are merged first, so each is upserted exactly once
+// and hostCount is the number of distinct hosts.
 // Returns (hostCount, portCount, error)
 func (w *IngestWorkflow) persistScanData(jobID string, scanData *models.ScanData, scannerKey string) (int, int, error) {
 	ctx := context.Background()
@@ -275,82 +753,333 @@ func (w *IngestWorkflow) persistScanData(jobID string, scanData *models.ScanData
 	portCount := 0
 	now := time.Now().UTC()
 
-	for _, host := range scanData.Hosts {
-		// Upsert host node
-		upsertHostQuery := `
-			LET $host_id = type::thing('host', $ip_encoded);
-			CREATE $host_id CONTENT {
-				ip: $ip,
-				last_seen: $now,
-				last_scanned_at: $now,
-				first_seen: $now
-			} ON DUPLICATE KEY UPDATE {
-				last_seen: $now,
-				last_scanned_at: $now
-			};
-		`
-		_, err := surrealdb.Query[interface{}](ctx, w.db, upsertHostQuery, map[string]interface{}{
-			"ip_encoded": strings.ReplaceAll(host.IP, ".", "_"),
-			"ip":         host.IP,
-			"now":        now,
-		})
+	source := scanSource(scanData.Source)
+
+	// Read per persist so a migration applied while the service runs takes effect
+	scheme, err := w.hostIDSchemes.HostIDScheme(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read the host ID scheme: %w", err)
+	}
 
+	hosts := mergeScanHosts(scanData.Hosts)
+	for _, host := range hosts {
+		ports, err := w.hosts.PersistHost(ctx, scheme, host, source, now)
+		portCount += ports
 		if err != nil {
-			return hostCount, portCount, fmt.Errorf("failed to upsert host %s: %w", host.IP, err)
+			return hostCount, portCount, err
 		}
 		hostCount++
 
-		// Upsert ports and create HAS edges
-		for _, port := range host.Ports {
-			portID := fmt.Sprintf("port_%d_%s", port.Number, port.Protocol)
-
-			// Upsert port
-			upsertPortQuery := `
-				LET $port_id = type::thing('port', $port_encoded);
-				CREATE $port_id CONTENT {
-					number: $number,
-					protocol: $protocol,
-					last_seen: $now,
-					first_seen: $now
-				} ON DUPLICATE KEY UPDATE {
-					last_seen: $now
-				};
-			`
-			_, err := surrealdb.Query[interface{}](ctx, w.db, upsertPortQuery, map[string]interface{}{
-				"port_encoded": portID,
-				"number":       port.Number,
-				"protocol":     port.Protocol,
-				"now":          now,
-			})
+		if progressDue(hostCount, len(hosts)) {
+			// Best effort: progress is informational
+			_ = w.updateJobProgress(jobID, progressUpdate(models.JobProgress{HostsDone: hostCount, HostsTotal: len(hosts)}, "", 0))
+		}
+	}
 
-			if err != nil {
-				return hostCount, portCount, fmt.Errorf("failed to upsert port %d: %w", port.Number, err)
-			}
+	return hostCount, portCount, nil
+}
+
+// mergeScanHosts combines hosts that appear more than once into a single entry,
+// in order of first appearance. Their ports are unioned (one per number and
+// protocol, keeping the first service identified on it, or failing that the
+// first guessed one) and their observation
+// windows widened to cover every record.
+func mergeScanHosts(hosts []models.ScanHost) []models.ScanHost {
+	merged := make([]models.ScanHost, 0, len(hosts))
+	index := make(map[string]int, len(hosts))
+	seenPorts := make(map[string]map[models.ScanPort]int, len(hosts))
 
-			// Create HAS edge (host -> port)
-			relateQuery := `
-				LET $host_id = type::thing('host', $host_encoded);
-				LET $port_id = type::thing('port', $port_encoded);
-				RELATE $host_id->HAS->$port_id CONTENT {
-					first_seen: $now,
-					last_seen: $now
-				} ON DUPLICATE KEY UPDATE {
-					last_seen: $now
-				};
-			`
-			_, err = surrealdb.Query[interface{}](ctx, w.db, relateQuery, map[string]interface{}{
-				"host_encoded": strings.ReplaceAll(host.IP, ".", "_"),
-				"port_encoded": portID,
-				"now":          now,
+	for _, host := range hosts {
+		i, exists := index[host.IP]
+		if !exists {
+			i = len(merged)
+			index[host.IP] = i
+			seenPorts[host.IP] = make(map[models.ScanPort]int)
+			merged = append(merged, models.ScanHost{
+				IP:        host.IP,
+				Ports:     []models.ScanPort{},
+				FirstSeen: host.FirstSeen,
+				LastSeen:  host.LastSeen,
 			})
+		} else {
+			target := &merged[i]
+			if !host.FirstSeen.IsZero() && (target.FirstSeen.IsZero() || host.FirstSeen.Before(target.FirstSeen)) {
+				target.FirstSeen = host.FirstSeen
+			}
+			if host.LastSeen.After(target.LastSeen) {
+				target.LastSeen = host.LastSeen
+			}
+		}
 
-			if err != nil {
-				return hostCount, portCount, fmt.Errorf("failed to create HAS edge: %w", err)
+		for _, port := range host.Ports {
+			key := models.ScanPort{Number: port.Number, Protocol: port.Protocol}
+			if p, seen := seenPorts[host.IP][key]; seen {
+				if current := merged[i].Ports[p].Service; current == nil || (current.Guessed && port.Service != nil && !port.Service.Guessed) {
+					merged[i].Ports[p].Service = port.Service
+				}
+				continue
 			}
+			seenPorts[host.IP][key] = len(merged[i].Ports)
+			merged[i].Ports = append(merged[i].Ports, port)
+		}
+	}
+
+	return merged
+}
+
+// distinctHostCount returns the number of distinct IPs among hosts
+func distinctHostCount(hosts []models.ScanHost) int {
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		seen[host.IP] = true
+	}
+	return len(seen)
+}
+
+// graphHostPersister writes scanned hosts to the graph in SurrealDB
+type graphHostPersister struct {
+	db *surrealdb.DB
+}
+
+// PersistHost upserts a host node, its ports and the HAS edges between them,
+// plus the service identified on each port and its RUNS edge.
+// A new HAS edge records source as discovered_by; an existing one keeps the tool
+// that first found the port, unless that was unknown.
+// The host's record ID follows scheme. Returns the number of ports written.
+func (p *graphHostPersister) PersistHost(ctx context.Context, scheme models.HostIDScheme, host models.ScanHost, source string, now time.Time) (int, error) {
+	portCount := 0
+	hostID := scheme.RecordID(host.IP)
+	firstSeen, lastSeen := seenWindow(host, now)
 
-			portCount++
+	// Upsert host node
+	upsertHostQuery := fmt.Sprintf(`
+		LET $host_id = type::thing('host', $ip_encoded);
+		CREATE $host_id CONTENT {
+			ip: $ip,
+			last_seen: $last_seen,
+			last_scanned_at: $now,
+			first_seen: $first_seen
+		} ON DUPLICATE KEY UPDATE {
+			first_seen: %s,
+			last_seen: %s,
+			last_scanned_at: $now
+		};
+	`, firstSeenBackward, lastSeenForward)
+	_, err := surrealdb.Query[interface{}](ctx, p.db, upsertHostQuery, map[string]interface{}{
+		"ip_encoded": hostID,
+		"ip":         host.IP,
+		"first_seen": firstSeen,
+		"last_seen":  lastSeen,
+		"now":        now,
+	})
+
+	if err != nil {
+		return portCount, fmt.Errorf("failed to upsert host %s: %w", host.IP, err)
+	}
+
+	// Upsert ports and create HAS edges
+	for _, port := range host.Ports {
+		portID := fmt.Sprintf("port_%d_%s", port.Number, port.Protocol)
+
+		// Upsert port
+		upsertPortQuery := fmt.Sprintf(`
+			LET $port_id = type::thing('port', $port_encoded);
+			CREATE $port_id CONTENT {
+				number: $number,
+				protocol: $protocol,
+				last_seen: $last_seen,
+				first_seen: $first_seen
+			} ON DUPLICATE KEY UPDATE {
+				first_seen: %s,
+				last_seen: %s
+			};
+		`, firstSeenBackward, lastSeenForward)
+		_, err := surrealdb.Query[interface{}](ctx, p.db, upsertPortQuery, map[string]interface{}{
+			"port_encoded": portID,
+			"number":       port.Number,
+			"protocol":     port.Protocol,
+			"first_seen":   firstSeen,
+			"last_seen":    lastSeen,
+		})
+
+		if err != nil {
+			return portCount, fmt.Errorf("failed to upsert port %d: %w", port.Number, err)
 		}
+
+		// Create HAS edge (host -> port)
+		relateQuery := fmt.Sprintf(`
+			LET $host_id = type::thing('host', $host_encoded);
+			LET $port_id = type::thing('port', $port_encoded);
+			RELATE $host_id->HAS->$port_id CONTENT {
+				first_seen: $first_seen,
+				last_seen: $last_seen,
+				discovered_by: $source
+			} ON DUPLICATE KEY UPDATE {
+				first_seen: %s,
+				last_seen: %s,
+				discovered_by: %s
+			};
+		`, firstSeenBackward, lastSeenForward, discoveredByKnown)
+		_, err = surrealdb.Query[interface{}](ctx, p.db, relateQuery, map[string]interface{}{
+			"host_encoded": hostID,
+			"port_encoded": portID,
+			"first_seen":   firstSeen,
+			"last_seen":    lastSeen,
+			"source":       source,
+		})
+
+		if err != nil {
+			return portCount, fmt.Errorf("failed to create HAS edge: %w", err)
+		}
+
+		if port.Service != nil {
+			if err := p.upsertService(ctx, host.IP, portID, *port.Service, firstSeen, lastSeen); err != nil {
+				return portCount, err
+			}
+		}
+
+		portCount++
 	}
 
-	return hostCount, portCount, nil
+	return portCount, nil
+}
+
+// normalizeScanService trims a scanned service's fields and caps its banner,
+// returning nil when it names neither a service nor a product
+func normalizeScanService(svc *models.ScanService) *models.ScanService {
+	if svc == nil {
+		return nil
+	}
+	normalized := models.ScanService{
+		Name:    strings.TrimSpace(svc.Name),
+		Product: strings.TrimSpace(svc.Product),
+		Version: strings.TrimSpace(svc.Version),
+		Banner:  bannerSample(svc.Banner),
+	}
+	if normalized.Name == "" && normalized.Product == "" {
+		return nil
+	}
+	return &normalized
+}
+
+// bannerSample trims a raw banner and truncates it to models.MaxBannerSample
+// bytes without splitting a UTF-8 sequence
+func bannerSample(banner string) string {
+	banner = strings.TrimSpace(banner)
+	if len(banner) <= models.MaxBannerSample {
+		return banner
+	}
+	return strings.ToValidUTF8(banner[:models.MaxBannerSample], "")
+}
+
+// bannerRecordID returns the banner record id for a banner sample: its SHA-256,
+// so every service reporting the same banner shares one banner node
+func bannerRecordID(banner string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(banner)))
+}
+
+// guessScanService returns the service conventionally run on a port, marked as
+// guessed, or nil when the port has no well-known service
+func guessScanService(port int, protocol string) *models.ScanService {
+	name := enrichment.GuessServiceName(port, protocol)
+	if name == "" {
+		return nil
+	}
+	return &models.ScanService{Name: name, Guessed: true}
+}
+
+// serviceRecordID returns the service record id for a scanned service: its
+// fingerprint, so every port running the same name, product and version
+// converges on one service node
+func serviceRecordID(svc models.ScanService) string {
+	return enrichment.GenerateServiceFingerprint(svc.Name, svc.Product, svc.Version)
+}
+
+// upsertService upserts the service node for svc and the RUNS edge from the
+// port to it. The service is keyed by its fingerprint, so repeated ingests and
+// other hosts running the same service reuse the existing node. Port nodes are
+// shared between hosts, so the edge carries the host's IP and each host keeps
+// its own edge to the service. A guessed
+// service is stored with enrichment.ConfidencePortGuess; once a scan reports
+// the same service, the node is raised to full confidence and stays there.
+// A reported banner is stored as a banner node the service is EVIDENCED_BY.
+func (p *graphHostPersister) upsertService(ctx context.Context, hostIP, portID string, svc models.ScanService, firstSeen, lastSeen time.Time) error {
+	fingerprint := serviceRecordID(svc)
+
+	query := fmt.Sprintf(`
+		LET $service_id = type::thing('service', $fingerprint);
+		LET $port_id = type::thing('port', $port_encoded);
+		CREATE $service_id CONTENT {
+			name: $name,
+			product: $product,
+			version: $version,
+			cpe: [],
+			fingerprint: $fingerprint,
+			confidence: $confidence,
+			first_seen: $first_seen,
+			last_seen: $last_seen
+		} ON DUPLICATE KEY UPDATE {
+			confidence: math::max([confidence ?? 1.0, $confidence]),
+			first_seen: %s,
+			last_seen: %s
+		};
+		RELATE $port_id->RUNS->$service_id CONTENT {
+			host_ip: $host_ip,
+			first_seen: $first_seen,
+			last_seen: $last_seen
+		} ON DUPLICATE KEY UPDATE {
+			first_seen: %s,
+			last_seen: %s
+		};
+	`, firstSeenBackward, lastSeenForward, firstSeenBackward, lastSeenForward)
+	_, err := surrealdb.Query[interface{}](ctx, p.db, query, map[string]interface{}{
+		"fingerprint":  fingerprint,
+		"host_ip":      hostIP,
+		"port_encoded": portID,
+		"name":         svc.Name,
+		"product":      svc.Product,
+		"version":      svc.Version,
+		"confidence":   serviceConfidence(svc),
+		"first_seen":   firstSeen,
+		"last_seen":    lastSeen,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert service %s on port %s: %w", fingerprint, portID, err)
+	}
+
+	if svc.Banner == "" {
+		return nil
+	}
+
+	query = fmt.Sprintf(`
+		LET $service_id = type::thing('service', $fingerprint);
+		LET $banner_id = type::thing('banner', $hash);
+		UPSERT $banner_id SET hash = $hash, sample = $sample;
+		RELATE $service_id->EVIDENCED_BY->$banner_id CONTENT {
+			evidence_type: 'banner',
+			first_seen: $first_seen
+		} ON DUPLICATE KEY UPDATE {
+			first_seen: %s
+		};
+	`, firstSeenBackward)
+	_, err = surrealdb.Query[interface{}](ctx, p.db, query, map[string]interface{}{
+		"fingerprint": fingerprint,
+		"hash":        bannerRecordID(svc.Banner),
+		"sample":      svc.Banner,
+		"first_seen":  firstSeen,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record banner of service %s: %w", fingerprint, err)
+	}
+
+	return nil
+}
+
+// serviceConfidence returns how far a scanned service can be trusted: fully
+// when the scan reported it, ConfidencePortGuess when it was guessed
+func serviceConfidence(svc models.ScanService) float64 {
+	if svc.Guessed {
+		return enrichment.ConfidencePortGuess
+	}
+	return 1.0
 }
@@ -1,11 +1,25 @@
 package workflows
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/spectra-red/recon/internal/enrichment"
 	"github.com/spectra-red/recon/internal/models"
+	"github.com/spectra-red/recon/internal/webhook"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/surrealdb/surrealdb.go"
 )
 
 func TestParseScanData_ValidNaabuOutput(t *testing.T) {
@@ -108,6 +122,573 @@ func TestParseScanData_DefaultProtocol(t *testing.T) {
 	assert.Equal(t, "tcp", result.Hosts[0].Ports[0].Protocol, "Should default to tcp")
 }
 
+func TestParseScanData_ObservationWindow(t *testing.T) {
+	workflow := &IngestWorkflow{}
+
+	// Results arrive out of order; the window spans the earliest and latest
+	naabuOutput := `{"host":"192.168.1.1","port":443,"timestamp":"2024-03-01T12:00:00Z"}
+{"host":"192.168.1.1","port":80,"timestamp":"2024-03-01T10:00:00+02:00"}
+{"host":"192.168.1.1","port":22,"timestamp":"2024-03-01T11:00:00Z"}
+{"host":"192.168.1.2","port":22}
+{"host":"192.168.1.3","port":22,"timestamp":"yesterday"}`
+
+	result, err := workflow.parseScanData([]byte(naabuOutput))
+	require.NoError(t, err)
+	require.Len(t, result.Hosts, 3)
+
+	hosts := make(map[string]models.ScanHost)
+	for _, host := range result.Hosts {
+		hosts[host.IP] = host
+	}
+
+	assert.Equal(t, time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), hosts["192.168.1.1"].FirstSeen)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), hosts["192.168.1.1"].LastSeen)
+
+	// Missing and malformed timestamps keep the line but leave the window empty
+	assert.True(t, hosts["192.168.1.2"].LastSeen.IsZero())
+	assert.Len(t, hosts["192.168.1.3"].Ports, 1)
+	assert.True(t, hosts["192.168.1.3"].FirstSeen.IsZero())
+}
+
+func TestSeenWindow(t *testing.T) {
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	earlier := now.Add(-48 * time.Hour)
+	later := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		host      models.ScanHost
+		wantFirst time.Time
+		wantLast  time.Time
+	}{
+		{
+			name:      "no scanner timestamps",
+			host:      models.ScanHost{},
+			wantFirst: now,
+			wantLast:  now,
+		},
+		{
+			name:      "scanner window",
+			host:      models.ScanHost{FirstSeen: earlier, LastSeen: later},
+			wantFirst: earlier,
+			wantLast:  later,
+		},
+		{
+			name:      "future timestamps are clamped",
+			host:      models.ScanHost{FirstSeen: now.Add(time.Hour), LastSeen: now.Add(2 * time.Hour)},
+			wantFirst: now,
+			wantLast:  now,
+		},
+		{
+			name:      "window ending in the future keeps its start",
+			host:      models.ScanHost{FirstSeen: earlier, LastSeen: now.Add(time.Hour)},
+			wantFirst: earlier,
+			wantLast:  now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last := seenWindow(tt.host, now)
+			assert.Equal(t, tt.wantFirst, first)
+			assert.Equal(t, tt.wantLast, last)
+		})
+	}
+}
+
+// TestPersistScanData_OutOfOrderIngest persists a newer scan and then an older
+// one for the same host and checks the observation window only ever widens
+func TestPersistScanData_OutOfOrderIngest(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	workflow := NewIngestWorkflow(db, false)
+
+	newer := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	older := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	oldest := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	scan := func(first, last time.Time) *models.ScanData {
+		return &models.ScanData{Hosts: []models.ScanHost{{
+			IP:        "8.8.8.8",
+			Ports:     []models.ScanPort{{Number: 53, Protocol: "udp", State: "open"}},
+			FirstSeen: first,
+			LastSeen:  last,
+		}}}
+	}
+
+	_, _, err = workflow.persistScanData("job-newer", scan(newer, newer), "scanner")
+	require.NoError(t, err)
+	_, _, err = workflow.persistScanData("job-older", scan(oldest, older), "scanner")
+	require.NoError(t, err)
+
+	result, err := surrealdb.Query[[]models.HostResult](context.Background(), db,
+		`SELECT ip, first_seen, last_seen FROM type::thing('host', $host_id);`,
+		map[string]interface{}{"host_id": models.HostRecordID("8.8.8.8")})
+	require.NoError(t, err)
+	require.NotEmpty(t, *result)
+	require.Len(t, (*result)[0].Result, 1)
+
+	host := (*result)[0].Result[0]
+	assert.True(t, host.FirstSeen.Equal(oldest), "first_seen should move back to the older scan")
+	assert.True(t, host.LastSeen.Equal(newer), "last_seen should not move back to the older scan")
+}
+
+// TestPersistScanData_RepeatedHostUpsertedOnce persists a scan listing one IP
+// across several records and checks the host is upserted exactly once
+func TestPersistScanData_RepeatedHostUpsertedOnce(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	recorder := &recordingHosts{next: &graphHostPersister{db: db}}
+	workflow := NewIngestWorkflowWithConfig(db, IngestConfig{Hosts: recorder})
+
+	scanData := &models.ScanData{Hosts: []models.ScanHost{
+		{IP: "9.9.9.9", Ports: []models.ScanPort{{Number: 53, Protocol: "udp", State: "open"}}},
+		{IP: "9.9.9.9", Ports: []models.ScanPort{{Number: 443, Protocol: "tcp", State: "open"}}},
+		{IP: "9.9.9.9", Ports: []models.ScanPort{{Number: 53, Protocol: "udp", State: "open"}}},
+	}}
+
+	hosts, ports, err := workflow.persistScanData("job-repeated", scanData, "scanner")
+	require.NoError(t, err)
+	assert.Equal(t, 1, hosts)
+	assert.Equal(t, 2, ports)
+	assert.Equal(t, []string{"9.9.9.9"}, recorder.ips, "one upsert per IP")
+}
+
+// TestPersistScanData_IdenticalServicesShareNode persists two hosts running the
+// same nginx build and checks both reach a single service node
+func TestPersistScanData_IdenticalServicesShareNode(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	workflow := NewIngestWorkflow(db, false)
+
+	nginx := &models.ScanService{Name: "http", Product: "nginx", Version: "1.24.0"}
+	scanData := &models.ScanData{Hosts: []models.ScanHost{
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 80, Protocol: "tcp", State: "open", Service: nginx}}},
+		{IP: "8.8.8.8", Ports: []models.ScanPort{{Number: 8080, Protocol: "tcp", State: "open", Service: nginx}}},
+	}}
+
+	_, _, err = workflow.persistScanData("job-services", scanData, "scanner")
+	require.NoError(t, err)
+	// A second ingest of the same scan must not add nodes or edges
+	_, _, err = workflow.persistScanData("job-services-again", scanData, "scanner")
+	require.NoError(t, err)
+
+	services, err := surrealdb.Query[[]map[string]interface{}](context.Background(), db,
+		`SELECT id, product, version FROM service;`, nil)
+	require.NoError(t, err)
+	require.Len(t, (*services)[0].Result, 1, "identical services should share one node")
+
+	edges, err := surrealdb.Query[[]map[string]interface{}](context.Background(), db,
+		`SELECT id FROM RUNS;`, nil)
+	require.NoError(t, err)
+	assert.Len(t, (*edges)[0].Result, 2, "one RUNS edge per port")
+
+	for _, ip := range []string{"1.1.1.1", "8.8.8.8"} {
+		reached, err := surrealdb.Query[[][]string](context.Background(), db,
+			`SELECT VALUE array::distinct(->HAS->port->RUNS->service.fingerprint) FROM type::thing('host', $host_id);`,
+			map[string]interface{}{"host_id": models.HostRecordID(ip)})
+		require.NoError(t, err)
+		require.Len(t, (*reached)[0].Result, 1)
+		assert.Equal(t, []string{serviceRecordID(*nginx)}, (*reached)[0].Result[0], ip)
+	}
+}
+
+// TestPersistScanData_StoresBanners checks a reported banner becomes the
+// service's EVIDENCED_BY evidence, once however often it is ingested
+func TestPersistScanData_StoresBanners(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	workflow := NewIngestWorkflow(db, false)
+
+	dropbear := &models.ScanService{Name: "ssh", Banner: "SSH-2.0-dropbear_2022.83"}
+	scanData := &models.ScanData{Hosts: []models.ScanHost{
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open", Service: dropbear}}},
+		{IP: "8.8.8.8", Ports: []models.ScanPort{{Number: 2222, Protocol: "tcp", State: "open", Service: dropbear}}},
+	}}
+
+	_, _, err = workflow.persistScanData("job-banners", scanData, "scanner")
+	require.NoError(t, err)
+	_, _, err = workflow.persistScanData("job-banners-again", scanData, "scanner")
+	require.NoError(t, err)
+
+	samples, err := surrealdb.Query[[][]string](context.Background(), db,
+		`SELECT VALUE ->EVIDENCED_BY->banner.sample FROM type::thing('service', $fingerprint);`,
+		map[string]interface{}{"fingerprint": serviceRecordID(*dropbear)})
+	require.NoError(t, err)
+	require.Len(t, (*samples)[0].Result, 1)
+	assert.Equal(t, []string{dropbear.Banner}, (*samples)[0].Result[0], "one EVIDENCED_BY edge per service and banner")
+}
+
+// TestPersistScanData_StampsSource records persisted hosts and checks the scan's source
+// reaches every host, defaulting to unknown
+func TestPersistScanData_StampsSource(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "named tool", source: "nmap", want: "nmap"},
+		{name: "normalized", source: " Masscan ", want: "masscan"},
+		{name: "absent", source: "", want: models.ScanSourceUnknown},
+		{name: "invalid", source: "not a tool!", want: models.ScanSourceUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts := &recordingHosts{}
+			workflow := NewIngestWorkflowWithConfig(db, IngestConfig{Hosts: hosts})
+
+			scanData := &models.ScanData{
+				Hosts: []models.ScanHost{
+					{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 53, Protocol: "udp", State: "open"}}},
+					{IP: "8.8.8.8", Ports: []models.ScanPort{{Number: 53, Protocol: "udp", State: "open"}}},
+				},
+				Source: tt.source,
+			}
+
+			_, _, err := workflow.persistScanData("job-source", scanData, "scanner")
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.want, tt.want}, hosts.sources)
+		})
+	}
+}
+
+// recordingHosts records each persisted host, then hands it to next when set
+type recordingHosts struct {
+	next      HostPersister
+	ips       []string
+	sources   []string
+	recordIDs []string
+}
+
+func (r *recordingHosts) PersistHost(ctx context.Context, scheme models.HostIDScheme, host models.ScanHost, source string, now time.Time) (int, error) {
+	r.ips = append(r.ips, host.IP)
+	r.sources = append(r.sources, source)
+	r.recordIDs = append(r.recordIDs, scheme.RecordID(host.IP))
+	if r.next == nil {
+		return len(host.Ports), nil
+	}
+	return r.next.PersistHost(ctx, scheme, host, source, now)
+}
+
+// switchingSchemes is a HostIDSchemeSource whose answer changes when the schema is migrated
+type switchingSchemes struct {
+	scheme models.HostIDScheme
+	err    error
+}
+
+func (s *switchingSchemes) HostIDScheme(ctx context.Context) (models.HostIDScheme, error) {
+	return s.scheme, s.err
+}
+
+// TestPersistScanData_FollowsHostIDScheme checks every persist asks for the scheme,
+// so a running workflow moves to namespaced IDs once the schema is migrated
+func TestPersistScanData_FollowsHostIDScheme(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	// Progress updates still need the job table
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	schemes := &switchingSchemes{scheme: models.HostIDSchemeLegacy}
+	recorder := &recordingHosts{}
+	workflow := NewIngestWorkflowWithConfig(db, IngestConfig{HostIDSchemes: schemes, Hosts: recorder})
+	scanData := &models.ScanData{Hosts: []models.ScanHost{{IP: "8.8.8.8"}}}
+
+	_, _, err = workflow.persistScanData("job-scheme", scanData, "scanner")
+	require.NoError(t, err)
+	schemes.scheme = models.HostIDSchemeNamespaced
+	_, _, err = workflow.persistScanData("job-scheme", scanData, "scanner")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"8_8_8_8", "ip4_8_8_8_8"}, recorder.recordIDs)
+
+	// Without a scheme nothing is written
+	schemes.err = errors.New("schema unavailable")
+	hosts, _, err := workflow.persistScanData("job-scheme", scanData, "scanner")
+	assert.Error(t, err)
+	assert.Zero(t, hosts)
+	assert.Len(t, recorder.recordIDs, 2)
+}
+
+// TestPersistScanData_DiscoveredBy persists the same port from two tools and checks
+// the HAS edge records the first, then that a named tool replaces an unknown one
+func TestPersistScanData_DiscoveredBy(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	workflow := NewIngestWorkflow(db, false)
+
+	scan := func(ip, source string) *models.ScanData {
+		return &models.ScanData{
+			Hosts:  []models.ScanHost{{IP: ip, Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open"}}}},
+			Source: source,
+		}
+	}
+	discoveredBy := func(ip string) string {
+		result, err := surrealdb.Query[[]string](context.Background(), db,
+			`SELECT VALUE discovered_by FROM HAS WHERE in = type::thing('host', $host_id);`,
+			map[string]interface{}{"host_id": models.HostRecordID(ip)})
+		require.NoError(t, err)
+		require.Len(t, (*result)[0].Result, 1)
+		return (*result)[0].Result[0]
+	}
+
+	_, _, err = workflow.persistScanData("job-naabu", scan("1.1.1.1", "naabu"), "scanner")
+	require.NoError(t, err)
+	_, _, err = workflow.persistScanData("job-nmap", scan("1.1.1.1", "nmap"), "scanner")
+	require.NoError(t, err)
+	assert.Equal(t, "naabu", discoveredBy("1.1.1.1"), "the first tool to find the port is kept")
+
+	_, _, err = workflow.persistScanData("job-untagged", scan("8.8.8.8", ""), "scanner")
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanSourceUnknown, discoveredBy("8.8.8.8"))
+	_, _, err = workflow.persistScanData("job-masscan", scan("8.8.8.8", "masscan"), "scanner")
+	require.NoError(t, err)
+	assert.Equal(t, "masscan", discoveredBy("8.8.8.8"), "a named tool replaces unknown")
+}
+
+func TestNormalizeScanSource(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{source: "naabu", want: "naabu"},
+		{source: "  NMAP ", want: "nmap"},
+		{source: "my-scanner_v2.1", want: "my-scanner_v2.1"},
+		{source: "", want: models.ScanSourceUnknown},
+		{source: "   ", want: models.ScanSourceUnknown},
+		{source: "two words", wantErr: true},
+		{source: "-leading-dash", wantErr: true},
+		{source: "a-very-long-scanner-name-beyond-32-chars", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := models.NormalizeScanSource(tt.source)
+		if tt.wantErr {
+			assert.Error(t, err, tt.source)
+			continue
+		}
+		require.NoError(t, err, tt.source)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestServiceRecordID(t *testing.T) {
+	nginx := serviceRecordID(models.ScanService{Name: "http", Product: "nginx", Version: "1.24.0"})
+
+	assert.Equal(t, nginx, serviceRecordID(models.ScanService{Name: "HTTP", Product: "Nginx", Version: "1.24.0"}),
+		"fingerprints ignore case")
+	assert.NotEqual(t, nginx, serviceRecordID(models.ScanService{Name: "http", Product: "nginx", Version: "1.25.0"}),
+		"other versions are other services")
+	assert.NotEqual(t, nginx, serviceRecordID(models.ScanService{Name: "http", Product: "apache", Version: "1.24.0"}))
+}
+
+func TestParseScanData_Services(t *testing.T) {
+	workflow := &IngestWorkflow{}
+
+	result, err := workflow.parseScanData([]byte(`{"host":"1.1.1.1","port":80,"protocol":"tcp","service":{"name":"http","product":" nginx ","version":"1.24.0"}}
+{"host":"1.1.1.1","port":22,"protocol":"tcp","service":{"name":"","product":""}}
+{"host":"8.8.8.8","port":80,"protocol":"tcp","service":{"name":"http","product":"nginx","version":"1.24.0"}}`))
+	require.NoError(t, err)
+
+	services := map[string]*models.ScanService{}
+	for _, host := range result.Hosts {
+		for _, port := range host.Ports {
+			services[fmt.Sprintf("%s:%d", host.IP, port.Number)] = port.Service
+		}
+	}
+
+	nginx := &models.ScanService{Name: "http", Product: "nginx", Version: "1.24.0"}
+	assert.Equal(t, nginx, services["1.1.1.1:80"], "service fields are trimmed")
+	assert.Nil(t, services["1.1.1.1:22"], "a service with no name or product is dropped")
+	assert.Equal(t, nginx, services["8.8.8.8:80"])
+	assert.Equal(t, serviceRecordID(*services["1.1.1.1:80"]), serviceRecordID(*services["8.8.8.8:80"]),
+		"identical services on different hosts share a record id")
+}
+
+func TestParseScanData_Banners(t *testing.T) {
+	workflow := &IngestWorkflow{}
+
+	long := strings.Repeat("x", models.MaxBannerSample-1) + "é"
+	result, err := workflow.parseScanData([]byte(`{"host":"1.1.1.1","port":22,"protocol":"tcp","service":{"name":"ssh","banner":" SSH-2.0-dropbear_2022.83\r\n"}}
+{"host":"1.1.1.1","port":80,"protocol":"tcp","service":{"name":"http","banner":"` + long + `"}}`))
+	require.NoError(t, err)
+	require.Len(t, result.Hosts, 1)
+
+	banners := map[int]string{}
+	for _, port := range result.Hosts[0].Ports {
+		banners[port.Number] = port.Service.Banner
+	}
+	assert.Equal(t, "SSH-2.0-dropbear_2022.83", banners[22], "banners are trimmed")
+	assert.Equal(t, strings.Repeat("x", models.MaxBannerSample-1), banners[80],
+		"banners are capped without splitting a character")
+
+	assert.Equal(t, serviceRecordID(models.ScanService{Name: "ssh"}),
+		serviceRecordID(*result.Hosts[0].Ports[0].Service), "the banner is evidence, not part of the service identity")
+	assert.Equal(t, bannerRecordID("SSH-2.0-dropbear_2022.83"), bannerRecordID(banners[22]))
+	assert.NotEqual(t, bannerRecordID(banners[22]), bannerRecordID(banners[80]))
+}
+
+func TestMergeScanHosts_KeepsIdentifiedService(t *testing.T) {
+	ssh := &models.ScanService{Name: "ssh", Product: "openssh", Version: "9.6"}
+
+	merged := mergeScanHosts([]models.ScanHost{
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open"}}},
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open", Service: ssh}}},
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open", Service: &models.ScanService{Name: "ssh"}}}},
+	})
+
+	require.Len(t, merged, 1)
+	require.Len(t, merged[0].Ports, 1)
+	assert.Equal(t, ssh, merged[0].Ports[0].Service, "the first identified service is kept")
+}
+
+func TestParseScanData_GuessServiceNames(t *testing.T) {
+	scan := []byte(`{"host":"1.1.1.1","port":22,"protocol":"tcp"}
+{"host":"1.1.1.1","port":3306,"protocol":"tcp"}
+{"host":"1.1.1.1","port":31337,"protocol":"tcp"}
+{"host":"1.1.1.1","port":443,"protocol":"tcp","service":{"name":"http","product":"nginx"}}`)
+
+	services := func(workflow *IngestWorkflow) map[int]*models.ScanService {
+		result, err := workflow.parseScanData(scan)
+		require.NoError(t, err)
+		require.Len(t, result.Hosts, 1)
+		byPort := map[int]*models.ScanService{}
+		for _, port := range result.Hosts[0].Ports {
+			byPort[port.Number] = port.Service
+		}
+		return byPort
+	}
+
+	off := services(&IngestWorkflow{})
+	assert.Nil(t, off[22], "guessing is off by default")
+
+	on := services(&IngestWorkflow{guessServiceNames: true})
+	assert.Equal(t, &models.ScanService{Name: "ssh", Guessed: true}, on[22])
+	assert.Equal(t, &models.ScanService{Name: "mysql", Guessed: true}, on[3306])
+	assert.Nil(t, on[31337], "ports without a well-known service stay unnamed")
+	assert.Equal(t, &models.ScanService{Name: "http", Product: "nginx"}, on[443], "reported services are never replaced by a guess")
+
+	assert.Equal(t, enrichment.ConfidencePortGuess, serviceConfidence(*on[22]))
+	assert.Equal(t, 1.0, serviceConfidence(*on[443]))
+}
+
+func TestParseScanData_GuessSurvivesJSONRoundTrip(t *testing.T) {
+	workflow := &IngestWorkflow{guessServiceNames: true}
+	parsed, err := workflow.parseScanData([]byte(`{"host":"1.1.1.1","port":22,"protocol":"tcp"}
+{"host":"1.1.1.1","port":443,"protocol":"tcp","service":{"name":"http","product":"nginx"}}`))
+	require.NoError(t, err)
+
+	// restate.Run journals the parsed scan as JSON before persistScanData sees it
+	encoded, err := json.Marshal(parsed)
+	require.NoError(t, err)
+	var decoded models.ScanData
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+
+	assert.Equal(t, parsed.Hosts, decoded.Hosts)
+	require.Len(t, decoded.Hosts, 1)
+	confidence := map[int]float64{}
+	for _, port := range decoded.Hosts[0].Ports {
+		confidence[port.Number] = serviceConfidence(*port.Service)
+	}
+	assert.Equal(t, enrichment.ConfidencePortGuess, confidence[22])
+	assert.Equal(t, 1.0, confidence[443])
+}
+
+func TestMergeScanHosts_PrefersReportedServiceOverGuess(t *testing.T) {
+	reported := &models.ScanService{Name: "ssh", Product: "openssh"}
+
+	merged := mergeScanHosts([]models.ScanHost{
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open", Service: &models.ScanService{Name: "ssh", Guessed: true}}}},
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 22, Protocol: "tcp", State: "open", Service: reported}}},
+	})
+
+	require.Len(t, merged[0].Ports, 1)
+	assert.Equal(t, reported, merged[0].Ports[0].Service)
+}
+
+func TestMergeScanHosts(t *testing.T) {
+	early := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	middle := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	merged := mergeScanHosts([]models.ScanHost{
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 80, Protocol: "tcp", State: "open"}}, FirstSeen: middle, LastSeen: middle},
+		{IP: "8.8.8.8", Ports: []models.ScanPort{{Number: 53, Protocol: "udp", State: "open"}}},
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 443, Protocol: "tcp", State: "open"}}, FirstSeen: early, LastSeen: middle},
+		{IP: "1.1.1.1", Ports: []models.ScanPort{{Number: 80, Protocol: "tcp", State: "open"}, {Number: 80, Protocol: "udp", State: "open"}}, LastSeen: late},
+	})
+
+	require.Len(t, merged, 2)
+	assert.Equal(t, "1.1.1.1", merged[0].IP, "hosts keep their first-appearance order")
+	assert.Equal(t, "8.8.8.8", merged[1].IP)
+
+	assert.Equal(t, []models.ScanPort{
+		{Number: 80, Protocol: "tcp", State: "open"},
+		{Number: 443, Protocol: "tcp", State: "open"},
+		{Number: 80, Protocol: "udp", State: "open"},
+	}, merged[0].Ports)
+	assert.True(t, merged[0].FirstSeen.Equal(early), "first_seen should widen to the earliest record")
+	assert.True(t, merged[0].LastSeen.Equal(late), "last_seen should widen to the latest record")
+
+	assert.Equal(t, 2, distinctHostCount([]models.ScanHost{{IP: "1.1.1.1"}, {IP: "8.8.8.8"}, {IP: "1.1.1.1"}}))
+}
+
 func TestParseScanData_UDPProtocol(t *testing.T) {
 	workflow := &IngestWorkflow{}
 
@@ -141,6 +722,82 @@ func TestParseScanData_LargeDataset(t *testing.T) {
 	assert.GreaterOrEqual(t, len(result.Hosts), 1)
 }
 
+func TestParseScanData_RejectPrivateIPs(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+	}{
+		{name: "RFC1918 10/8", host: "10.1.2.3"},
+		{name: "RFC1918 172.16/12", host: "172.20.0.5"},
+		{name: "RFC1918 192.168/16", host: "192.168.1.1"},
+		{name: "IPv6 unique local", host: "fd00::1"},
+		{name: "IPv4 loopback", host: "127.0.0.1"},
+		{name: "IPv6 loopback", host: "::1"},
+		{name: "IPv4 link-local", host: "169.254.10.20"},
+		{name: "IPv6 link-local", host: "fe80::1"},
+		{name: "IPv4 multicast", host: "224.0.0.251"},
+		{name: "IPv4 unspecified", host: "0.0.0.0"},
+		{name: "IPv6 unspecified", host: "::"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow := &IngestWorkflow{rejectPrivateIPs: true}
+
+			naabuOutput := fmt.Sprintf(`{"host":"%s","port":80,"protocol":"tcp"}
+{"host":"8.8.8.8","port":53,"protocol":"udp"}`, tt.host)
+
+			result, err := workflow.parseScanData([]byte(naabuOutput))
+
+			assert.NoError(t, err)
+			assert.NotNil(t, result)
+			assert.Len(t, result.Hosts, 1)
+			assert.Equal(t, "8.8.8.8", result.Hosts[0].IP)
+			assert.Equal(t, 1, result.SkippedHosts)
+		})
+	}
+}
+
+func TestParseScanData_RejectPrivateIPs_CountsUniqueHosts(t *testing.T) {
+	workflow := &IngestWorkflow{rejectPrivateIPs: true}
+
+	naabuOutput := `{"host":"192.168.1.1","port":22,"protocol":"tcp"}
+{"host":"192.168.1.1","port":80,"protocol":"tcp"}
+{"host":"10.0.0.1","port":443,"protocol":"tcp"}
+{"host":"1.1.1.1","port":443,"protocol":"tcp"}`
+
+	result, err := workflow.parseScanData([]byte(naabuOutput))
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Hosts, 1)
+	assert.Equal(t, 2, result.SkippedHosts)
+}
+
+func TestParseScanData_RejectPrivateIPs_AllRejected(t *testing.T) {
+	workflow := &IngestWorkflow{rejectPrivateIPs: true}
+
+	naabuOutput := `{"host":"192.168.1.1","port":22,"protocol":"tcp"}`
+
+	result, err := workflow.parseScanData([]byte(naabuOutput))
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "1 private/reserved hosts rejected")
+}
+
+func TestParseScanData_PermissiveByDefault(t *testing.T) {
+	workflow := &IngestWorkflow{}
+
+	naabuOutput := `{"host":"192.168.1.1","port":22,"protocol":"tcp"}
+{"host":"127.0.0.1","port":80,"protocol":"tcp"}`
+
+	result, err := workflow.parseScanData([]byte(naabuOutput))
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Hosts, 2)
+	assert.Equal(t, 0, result.SkippedHosts)
+}
+
 func TestJobStateTransitions(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -278,3 +935,205 @@ func TestIngestWorkflowRequest_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestProgressDue(t *testing.T) {
+	// Progress advances every progressInterval hosts and always on the last host
+	var reported []int
+	for done := 1; done <= 250; done++ {
+		if progressDue(done, 250) {
+			reported = append(reported, done)
+		}
+	}
+	assert.Equal(t, []int{100, 200, 250}, reported)
+
+	// Small jobs report once, on completion
+	assert.False(t, progressDue(0, 3))
+	assert.False(t, progressDue(2, 3))
+	assert.True(t, progressDue(3, 3))
+}
+
+func TestProgressUpdate(t *testing.T) {
+	// Completing a step records its duration alongside the progress
+	data := progressUpdate(models.JobProgress{HostsDone: 40, HostsTotal: 40}, models.JobStepPersist, 1234)
+	assert.Equal(t, map[string]interface{}{"hosts_done": 40, "hosts_total": 40}, data["progress"])
+	assert.Equal(t, map[string]interface{}{models.JobStepPersist: int64(1234)}, data["step_durations_ms"])
+
+	// Intermediate progress leaves step timings untouched
+	data = progressUpdate(models.JobProgress{HostsDone: 100, HostsTotal: 250}, "", 0)
+	assert.Equal(t, map[string]interface{}{"hosts_done": 100, "hosts_total": 250}, data["progress"])
+	assert.NotContains(t, data, "step_durations_ms")
+}
+
+func TestHostRecordID_IngestAndEnrichmentAgree(t *testing.T) {
+	workflow := NewIngestWorkflow(nil, false)
+	rawData := []byte(`{"host":"8.8.8.8","port":53,"protocol":"udp"}
+{"host":"2001:4860:4860::8888","port":443,"protocol":"tcp"}
+{"host":"2001:db8:0:0:0:0:0:1","port":22,"protocol":"tcp"}`)
+
+	scanData, err := workflow.parseScanData(rawData)
+	require.NoError(t, err)
+
+	// Host node IDs as persistScanData derives them from parsed scan data
+	ingestIDs := make([]string, 0, len(scanData.Hosts))
+	geoData := make(map[string]*enrichment.GeoIPInfo)
+	for _, host := range scanData.Hosts {
+		ingestIDs = append(ingestIDs, "host:"+models.HostRecordID(host.IP))
+		geoData[host.IP] = &enrichment.GeoIPInfo{CountryCC: "US"}
+	}
+
+	// Host node IDs the GeoIP workflow would update for the same addresses
+	var enrichIDs []string
+	for _, mutation := range previewGeoMutations(models.HostIDSchemeNamespaced, geoData) {
+		if mutation.Op == MutationUpdate {
+			enrichIDs = append(enrichIDs, mutation.Target)
+		}
+	}
+
+	assert.ElementsMatch(t, ingestIDs, enrichIDs)
+	assert.ElementsMatch(t, []string{"host:ip4_8_8_8_8", "host:ip6_2001-4860-4860--8888", "host:ip6_2001-db8--1"}, ingestIDs)
+}
+
+// newCallbackServer starts a TLS callback receiver that fails the first
+// failures requests with status and records every payload it is sent
+func newCallbackServer(t *testing.T, failures, status int) (*httptest.Server, *[]models.JobCallbackPayload) {
+	t.Helper()
+	var received []models.JobCallbackPayload
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.True(t, webhook.Verify([]byte("s3cret"), r.Header.Get(webhook.TimestampHeader), body, r.Header.Get(webhook.SignatureHeader)))
+
+		var payload models.JobCallbackPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		received = append(received, payload)
+
+		if len(received) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+// newCallbackWorkflow returns an ingest workflow whose notifier trusts server
+func newCallbackWorkflow(t *testing.T, server *httptest.Server) *IngestWorkflow {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	notifier := webhook.NewNotifier(webhook.NewAllowlist([]string{u.Hostname()}), []byte("s3cret"), server.Client())
+	return NewIngestWorkflowWithConfig(nil, IngestConfig{Notifier: notifier})
+}
+
+// deliverWithRetry mirrors notifyCompletion without the Restate journal
+func deliverWithRetry(w *IngestWorkflow, callbackURL string, payload models.JobCallbackPayload) (int, error) {
+	return webhook.Retry(w.callbackRetry, func(int) error {
+		return w.deliverCallback(context.Background(), callbackURL, payload).err()
+	}, func(time.Duration) error { return nil })
+}
+
+func TestIngestCallback_Delivered(t *testing.T) {
+	server, received := newCallbackServer(t, 0, 0)
+	workflow := newCallbackWorkflow(t, server)
+
+	payload := completionPayload(models.IngestWorkflowResponse{
+		JobID:        "job-1",
+		State:        models.JobStateCompleted,
+		HostCount:    3,
+		PortCount:    7,
+		SkippedHosts: 1,
+	}, nil)
+
+	attempts, err := deliverWithRetry(workflow, server.URL+"/hook", payload)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	require.Len(t, *received, 1)
+	assert.Equal(t, payload, (*received)[0])
+	assert.Equal(t, map[string]interface{}{"callback_state": models.CallbackDelivered, "callback_attempts": 1}, callbackUpdate(attempts, err))
+}
+
+func TestIngestCallback_RetriesServerErrors(t *testing.T) {
+	server, received := newCallbackServer(t, 2, http.StatusServiceUnavailable)
+	workflow := newCallbackWorkflow(t, server)
+
+	attempts, err := deliverWithRetry(workflow, server.URL, models.JobCallbackPayload{JobID: "job-1", State: models.JobStateCompleted})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Len(t, *received, 3)
+}
+
+func TestIngestCallback_FailureIsRecorded(t *testing.T) {
+	t.Run("retries exhausted", func(t *testing.T) {
+		server, received := newCallbackServer(t, 100, http.StatusBadGateway)
+		workflow := newCallbackWorkflow(t, server)
+
+		attempts, err := deliverWithRetry(workflow, server.URL, models.JobCallbackPayload{JobID: "job-1"})
+		require.Error(t, err)
+		assert.Equal(t, webhook.DefaultRetryPolicy.MaxAttempts, attempts)
+		assert.Len(t, *received, webhook.DefaultRetryPolicy.MaxAttempts)
+
+		update := callbackUpdate(attempts, err)
+		assert.Equal(t, models.CallbackFailed, update["callback_state"])
+		assert.Contains(t, update["callback_error"], "HTTP 502")
+	})
+
+	t.Run("client error is not retried", func(t *testing.T) {
+		server, received := newCallbackServer(t, 100, http.StatusGone)
+		workflow := newCallbackWorkflow(t, server)
+
+		attempts, err := deliverWithRetry(workflow, server.URL, models.JobCallbackPayload{JobID: "job-1"})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Len(t, *received, 1)
+	})
+
+	t.Run("callbacks not configured", func(t *testing.T) {
+		workflow := NewIngestWorkflow(nil, false)
+
+		attempts, err := deliverWithRetry(workflow, "https://hooks.example.com/", models.JobCallbackPayload{JobID: "job-1"})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Contains(t, err.Error(), "not enabled")
+	})
+}
+
+func TestCompletionPayload(t *testing.T) {
+	failed := completionPayload(models.IngestWorkflowResponse{JobID: "job-1", State: models.JobStateFailed}, fmt.Errorf("failed to parse scan data: bad input"))
+	assert.Equal(t, models.JobStateFailed, failed.State)
+	assert.Equal(t, "failed to parse scan data: bad input", failed.Error)
+
+	cancelled := completionPayload(cancelledResponse("job-2"), nil)
+	assert.Equal(t, models.JobStateCancelled, cancelled.State)
+	assert.Empty(t, cancelled.Error)
+}
+
+func TestUpdateJobStateWithCounts_CancelledJobStaysCancelled(t *testing.T) {
+	if os.Getenv("SKIP_INTEGRATION") != "" {
+		t.Skip("Skipping integration test")
+	}
+
+	db, err := setupTestDB(t)
+	if err != nil {
+		t.Skipf("SurrealDB not available: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	workflow := NewIngestWorkflow(db, false)
+	require.NoError(t, workflow.updateJobState("job-running", models.JobStateProcessing, "", "scanner"))
+	require.NoError(t, workflow.updateJobState("job-cancelled", models.JobStateProcessing, "", "scanner"))
+	require.NoError(t, workflow.updateJobState("job-cancelled", models.JobStateCancelled, "", "scanner"))
+
+	completed, err := workflow.updateJobStateWithCounts("job-running", models.JobStateCompleted, "", "scanner", 1, 2)
+	require.NoError(t, err)
+	assert.True(t, completed)
+
+	// A cancel that lands after the workflow's last check is not overwritten
+	completed, err = workflow.updateJobStateWithCounts("job-cancelled", models.JobStateCompleted, "", "scanner", 1, 2)
+	require.NoError(t, err)
+	assert.False(t, completed)
+
+	state, err := workflow.getJobState("job-cancelled")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStateCancelled, state)
+}
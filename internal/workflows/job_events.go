@@ -0,0 +1,22 @@
+package workflows
+
+import (
+	"context"
+
+	"github.com/spectra-red/recon/internal/models"
+)
+
+// JobEventPublisher publishes job state transitions for live subscribers
+type JobEventPublisher interface {
+	Publish(ctx context.Context, event models.JobEvent) error
+}
+
+// publishJobEvent publishes a state transition on a best-effort basis
+// Events are advisory: a failed publish never fails the state change, and a
+// retried step may publish the same transition twice.
+func publishJobEvent(ctx context.Context, publisher JobEventPublisher, event models.JobEvent) {
+	if publisher == nil {
+		return
+	}
+	_ = publisher.Publish(ctx, event)
+}